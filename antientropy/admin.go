@@ -0,0 +1,55 @@
+package antientropy
+
+import (
+	"encoding/json"
+
+	"github.com/pkg/errors"
+	"github.com/unpackdev/fdb/config"
+	"github.com/unpackdev/fdb/db"
+	"github.com/unpackdev/fdb/messages"
+)
+
+// RepairRequest is the JSON payload of a messages.AdminRepair request.
+type RepairRequest struct {
+	// Database is the database to repair.
+	Database string `json:"database"`
+
+	// Peer is the node serving the other replica.
+	Peer config.ReplicationPeer `json:"peer"`
+
+	// Direction decides which replica wins in divergent ranges, empty for pull.
+	Direction config.RepairDirection `json:"direction"`
+}
+
+// RepairResponse is the JSON answer to a messages.AdminRepair request.
+type RepairResponse struct {
+	// ID identifies the started run in the repair status.
+	ID uint64 `json:"id"`
+}
+
+// RegisterAdminOps exposes on-demand repairs and their status through the admin handlers of
+// the manager's transports. messages.AdminRepair starts a repair in the background and answers
+// with the ID of the run, messages.AdminRepairStatus returns the JSON encoded Status.
+//
+// Parameters:
+//
+//	manager (*db.Manager): The manager dispatching admin requests.
+//	service (*Service): The anti-entropy service running the repairs.
+func RegisterAdminOps(manager *db.Manager, service *Service) {
+	manager.RegisterAdminOp(messages.AdminRepair, func(req *messages.AdminRequest) ([]byte, error) {
+		var repair RepairRequest
+		if err := json.Unmarshal(req.Payload, &repair); err != nil {
+			return nil, errors.Wrap(err, "failed to decode repair request")
+		}
+
+		id, err := service.Run(repair.Database, repair.Peer, repair.Direction)
+		if err != nil {
+			return nil, err
+		}
+		return json.Marshal(RepairResponse{ID: id})
+	})
+
+	manager.RegisterAdminOp(messages.AdminRepairStatus, func(_ *messages.AdminRequest) ([]byte, error) {
+		return json.Marshal(service.Status())
+	})
+}
@@ -0,0 +1,23 @@
+// Package antientropy detects and repairs divergence between replicas of a database, such as
+// writes missed over UDP or a replica restored from an old backup.
+//
+// Every database keeps a Merkle tree over its key space (see db.Db.MerkleHashes): the keys are
+// split into db.MerkleLeaves ranges by their leading bits, each leaf hashes the pairs of its
+// range and each inner node the hashes of its children. The write path only marks the leaves of
+// the keys it commits as changed; they are rehashed when the tree is next read.
+//
+// A repair compares the trees of two replicas from the root down, descending only into the
+// subtrees whose hashes differ, streams the keys of the divergent leaves from the peer and
+// repairs the non-authoritative replica: the local one when pulling, the peer when pushing.
+// Identical replicas are compared with a single request.
+//
+// Repairs run on a schedule when enabled in the configuration, against configured peers or
+// random gossip members serving the database, and on demand through the admin API
+// (`fdb repair --peer`).
+//
+// Example usage:
+//
+//	service := antientropy.NewService(manager, cnf.AntiEntropy, membershipNode)
+//	service.Start(ctx)
+//	id, err := service.Run("fdb", config.ReplicationPeer{Transport: types.TCPTransportType, Addr: "10.0.0.2:5011"}, config.RepairPull)
+package antientropy
@@ -0,0 +1,276 @@
+package antientropy
+
+import (
+	"bytes"
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/unpackdev/fdb/config"
+	"github.com/unpackdev/fdb/db"
+	"github.com/unpackdev/fdb/messages"
+	"github.com/unpackdev/fdb/remote"
+	"go.uber.org/zap"
+)
+
+const (
+	// levelStep is the number of tree levels descended per round trip. Each divergent node is
+	// compared through its 2^levelStep descendants, which saves round trips at the cost of
+	// comparing a few identical nodes.
+	levelStep = 4
+
+	// leafBatch is the number of divergent leaves whose keys are compared at once.
+	leafBatch = 16
+)
+
+// Result describes a single repair run of a database against a peer.
+type Result struct {
+	// ID identifies the run among the runs of the service.
+	ID uint64 `json:"id"`
+
+	// Database is the repaired database.
+	Database string `json:"database"`
+
+	// Peer is the transport address of the peer.
+	Peer string `json:"peer"`
+
+	// Direction tells which replica won in the divergent ranges.
+	Direction config.RepairDirection `json:"direction"`
+
+	// StartedAt is when the run started.
+	StartedAt time.Time `json:"startedAt"`
+
+	// Duration is how long the run took, zero while it is running.
+	Duration time.Duration `json:"duration"`
+
+	// Running reports whether the run is still in progress.
+	Running bool `json:"running"`
+
+	// DivergentLeaves is the number of Merkle tree leaves (key ranges) that differed.
+	DivergentLeaves int `json:"divergentLeaves"`
+
+	// Written is the number of keys written to the repaired replica.
+	Written int `json:"written"`
+
+	// Deleted is the number of keys removed from the repaired replica.
+	Deleted int `json:"deleted"`
+
+	// Error is the error that stopped the run, if any.
+	Error string `json:"error,omitempty"`
+}
+
+// Repair compares a local database with the same database on a peer and repairs the key ranges
+// in which they differ. The Merkle trees of both replicas are compared top-down, descending only
+// into divergent subtrees, then the keys of the divergent leaves are streamed from the peer and
+// compared with the local ones. In pull direction the local database is made to match the peer,
+// in push direction the peer is made to match the local database; keys missing on the
+// authoritative side are removed from the other one.
+//
+// Writes made while the repair runs may be reported as divergent, and a repair racing them may
+// overwrite the newer value. Repair replicas, or run it while writes are quiet.
+//
+// Example usage:
+//
+//	result, err := antientropy.Repair(ctx, local, peer, "fdb", config.RepairPull)
+//	if err != nil {
+//	    log.Fatalf("Failed to repair: %v", err)
+//	}
+//	log.Printf("Repaired %d ranges", result.DivergentLeaves)
+//
+// Parameters:
+//
+//	ctx (context.Context): Cancels the repair.
+//	local (*db.Db): The local replica.
+//	peer (*remote.Client): The node serving the other replica.
+//	database (string): The database name on the peer.
+//	direction (config.RepairDirection): Which replica is authoritative.
+//
+// Returns:
+//
+//	Result: The divergence found and the keys repaired, also when the repair failed midway.
+//	error: Returns an error if the trees cannot be compared or a repair fails.
+func Repair(ctx context.Context, local *db.Db, peer *remote.Client, database string, direction config.RepairDirection) (Result, error) {
+	result := Result{
+		Database:  database,
+		Peer:      peer.Addr(),
+		Direction: direction.GetOrDefault(),
+		StartedAt: time.Now(),
+	}
+
+	leaves, err := divergentLeaves(ctx, local, peer, database)
+	if err != nil {
+		return result, err
+	}
+	result.DivergentLeaves = len(leaves)
+
+	for start := 0; start < len(leaves); start += leafBatch {
+		end := start + leafBatch
+		if end > len(leaves) {
+			end = len(leaves)
+		}
+		if err := repairLeaves(ctx, local, peer, database, leaves[start:end], &result); err != nil {
+			return result, err
+		}
+	}
+
+	zap.L().Info(
+		"Anti-entropy repair completed",
+		zap.String("database", database),
+		zap.String("peer", result.Peer),
+		zap.String("direction", string(result.Direction)),
+		zap.Int("divergent_leaves", result.DivergentLeaves),
+		zap.Int("written", result.Written),
+		zap.Int("deleted", result.Deleted),
+	)
+	return result, nil
+}
+
+// divergentLeaves walks both trees from the root and returns the leaves that differ, in
+// ascending order.
+func divergentLeaves(ctx context.Context, local *db.Db, peer *remote.Client, database string) ([]uint32, error) {
+	divergent := []uint32{0}
+	level := 0
+	for {
+		differing, err := compareNodes(ctx, local, peer, database, level, divergent)
+		if err != nil {
+			return nil, err
+		}
+		if len(differing) == 0 || level == db.MerkleDepth {
+			return differing, nil
+		}
+
+		next := level + levelStep
+		if next > db.MerkleDepth {
+			next = db.MerkleDepth
+		}
+		fanout := uint32(1) << (next - level)
+
+		divergent = make([]uint32, 0, len(differing)*int(fanout))
+		for _, node := range differing {
+			for child := uint32(0); child < fanout; child++ {
+				divergent = append(divergent, node*fanout+child)
+			}
+		}
+		level = next
+	}
+}
+
+// compareNodes returns the given nodes of a tree level whose hashes differ between the replicas.
+func compareNodes(ctx context.Context, local *db.Db, peer *remote.Client, database string, level int, nodes []uint32) ([]uint32, error) {
+	var differing []uint32
+	for start := 0; start < len(nodes); start += messages.MaxTreeIndices {
+		end := start + messages.MaxTreeIndices
+		if end > len(nodes) {
+			end = len(nodes)
+		}
+		batch := nodes[start:end]
+
+		localHashes, err := local.MerkleHashes(level, batch)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to read local merkle tree")
+		}
+		peerHashes, err := peer.TreeHashes(ctx, database, level, batch)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to read merkle tree of %s", peer.Addr())
+		}
+
+		for i, node := range batch {
+			if localHashes[i] != peerHashes[i] {
+				differing = append(differing, node)
+			}
+		}
+	}
+	return differing, nil
+}
+
+// repairLeaves compares the keys of divergent leaves and repairs the non-authoritative replica.
+func repairLeaves(ctx context.Context, local *db.Db, peer *remote.Client, database string, leaves []uint32, result *Result) error {
+	localPairs := make(map[string][]byte)
+	err := local.MerkleRange(leaves, func(key, value []byte) error {
+		localPairs[string(key)] = append([]byte(nil), value...)
+		return nil
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to read local keys")
+	}
+
+	peerPairs := make(map[string][]byte)
+	err = peer.TreeRange(ctx, database, leaves, func(key, value []byte) error {
+		peerPairs[string(key)] = append([]byte(nil), value...)
+		return nil
+	})
+	if err != nil {
+		return errors.Wrapf(err, "failed to read keys of %s", peer.Addr())
+	}
+
+	source, target := peerPairs, localPairs
+	if result.Direction == config.RepairPush {
+		source, target = localPairs, peerPairs
+	}
+
+	var set []db.KeyValue
+	var deleted [][]byte
+	for key, value := range source {
+		if current, ok := target[key]; !ok || !bytes.Equal(current, value) {
+			set = append(set, db.KeyValue{Key: []byte(key), Value: value})
+		}
+	}
+	for key := range target {
+		if _, ok := source[key]; !ok {
+			deleted = append(deleted, []byte(key))
+		}
+	}
+
+	if result.Direction == config.RepairPush {
+		return pushRepair(ctx, peer, database, set, deleted, result)
+	}
+
+	if err := local.Repair(set, deleted); err != nil {
+		return errors.Wrap(err, "failed to repair local keys")
+	}
+	result.Written += len(set)
+	result.Deleted += len(deleted)
+	return nil
+}
+
+// pushRepair writes and deletes keys on the peer through its transport.
+func pushRepair(ctx context.Context, peer *remote.Client, database string, set []db.KeyValue, deleted [][]byte, result *Result) error {
+	for _, pair := range set {
+		key, ok := requestKey(pair.Key)
+		if !ok {
+			zap.L().Warn("Skipping repair of key not addressable by transports", zap.Binary("key", pair.Key))
+			continue
+		}
+		if len(pair.Value) == 0 {
+			zap.L().Warn("Skipping repair of empty value, transports cannot write it", zap.Binary("key", pair.Key))
+			continue
+		}
+		if err := peer.Set(ctx, database, key, pair.Value); err != nil {
+			return errors.Wrapf(err, "failed to repair key %x on %s", pair.Key, peer.Addr())
+		}
+		result.Written++
+	}
+
+	for _, raw := range deleted {
+		key, ok := requestKey(raw)
+		if !ok {
+			zap.L().Warn("Skipping repair of key not addressable by transports", zap.Binary("key", raw))
+			continue
+		}
+		if err := peer.Delete(ctx, database, key); err != nil {
+			return errors.Wrapf(err, "failed to repair key %x on %s", raw, peer.Addr())
+		}
+		result.Deleted++
+	}
+	return nil
+}
+
+// requestKey converts a stored key into the fixed-size key of transport requests.
+func requestKey(raw []byte) ([32]byte, bool) {
+	var key [32]byte
+	if len(raw) != len(key) {
+		return key, false
+	}
+	copy(key[:], raw)
+	return key, true
+}
@@ -0,0 +1,97 @@
+package antientropy_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/unpackdev/fdb/antientropy"
+	"github.com/unpackdev/fdb/config"
+	"github.com/unpackdev/fdb/db"
+	fdbErrors "github.com/unpackdev/fdb/errors"
+	"github.com/unpackdev/fdb/fdbtest"
+	"github.com/unpackdev/fdb/remote"
+	"github.com/unpackdev/fdb/types"
+)
+
+// startNode starts an instance serving a fresh database over TCP.
+func startNode(t *testing.T) (*db.Db, string) {
+	server := fdbtest.Start(t, fdbtest.WithTransports(types.TCPTransportType))
+	return server.Provider(t, fdbtest.DefaultDatabase).(*db.Db), server.Addr(types.TCPTransportType)
+}
+
+func testKey(i int) []byte {
+	var key [32]byte
+	copy(key[:], fmt.Sprintf("key-%04d", i))
+	key[0] = byte(i * 37)
+	return key[:]
+}
+
+func root(t *testing.T, d *db.Db) db.MerkleHash {
+	hashes, err := d.MerkleHashes(0, []uint32{0})
+	require.NoError(t, err)
+	return hashes[0]
+}
+
+// diverge fills both databases with the same keys, then makes them differ in a few of them.
+func diverge(t *testing.T, local, peer *db.Db) {
+	for i := 0; i < 200; i++ {
+		require.NoError(t, local.Set(testKey(i), []byte{'v', byte(i)}))
+		require.NoError(t, peer.Set(testKey(i), []byte{'v', byte(i)}))
+	}
+	require.NoError(t, local.Set(testKey(3), []byte("stale")))
+	require.NoError(t, local.Delete(testKey(4)))
+	require.NoError(t, local.Set(testKey(500), []byte("extra")))
+	require.NotEqual(t, root(t, local), root(t, peer))
+}
+
+func TestRepairPull(t *testing.T) {
+	local, _ := startNode(t)
+	peer, addr := startNode(t)
+	diverge(t, local, peer)
+
+	client, err := remote.New(remote.Options{Transport: types.TCPTransportType, Addr: addr})
+	require.NoError(t, err)
+	defer client.Close()
+
+	result, err := antientropy.Repair(context.Background(), local, client, "fdb", config.RepairPull)
+	require.NoError(t, err)
+	assert.Equal(t, 3, result.DivergentLeaves)
+	assert.Equal(t, 2, result.Written)
+	assert.Equal(t, 1, result.Deleted)
+	assert.Equal(t, root(t, peer), root(t, local))
+
+	value, err := local.Get(testKey(3))
+	require.NoError(t, err)
+	assert.Equal(t, []byte{'v', 3}, value)
+	_, err = local.Get(testKey(500))
+	assert.ErrorIs(t, err, fdbErrors.ErrNotFound)
+
+	// Repaired replicas have nothing left to repair
+	result, err = antientropy.Repair(context.Background(), local, client, "fdb", config.RepairPull)
+	require.NoError(t, err)
+	assert.Zero(t, result.DivergentLeaves)
+}
+
+func TestRepairPush(t *testing.T) {
+	local, _ := startNode(t)
+	peer, addr := startNode(t)
+	diverge(t, local, peer)
+
+	client, err := remote.New(remote.Options{Transport: types.TCPTransportType, Addr: addr})
+	require.NoError(t, err)
+	defer client.Close()
+
+	result, err := antientropy.Repair(context.Background(), local, client, "fdb", config.RepairPush)
+	require.NoError(t, err)
+	assert.Equal(t, 2, result.Written)
+	assert.Equal(t, 1, result.Deleted)
+
+	// Transport writes are batched, the peer catches up once they are flushed
+	assert.Eventually(t, func() bool {
+		return root(t, local) == root(t, peer)
+	}, 5*time.Second, 20*time.Millisecond)
+}
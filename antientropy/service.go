@@ -0,0 +1,310 @@
+package antientropy
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/unpackdev/fdb/config"
+	"github.com/unpackdev/fdb/db"
+	"github.com/unpackdev/fdb/membership"
+	"github.com/unpackdev/fdb/remote"
+	"github.com/unpackdev/fdb/types"
+	"go.uber.org/zap"
+)
+
+// maxResults is the number of finished runs kept for the status.
+const maxResults = 64
+
+// Status describes the repair runs of a node, most recent first.
+type Status struct {
+	Runs []Result `json:"runs"`
+}
+
+// Service runs anti-entropy repairs of the node's databases, on schedule when enabled in the
+// configuration and on demand through Run.
+type Service struct {
+	// manager owns the repaired databases.
+	manager *db.Manager
+
+	// cnf holds the anti-entropy configuration.
+	cnf config.AntiEntropy
+
+	// members picks the peers when none are configured, nil without membership.
+	members *membership.Node
+
+	// mu guards nextID, runs and repairing.
+	mu     sync.Mutex
+	nextID uint64
+	runs   []*Result
+
+	// repairing holds the databases being repaired, a database is repaired by one run at a time.
+	repairing map[string]bool
+
+	// ctx bounds every repair, cancelled by Stop.
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewService creates the anti-entropy service of a node. Call Start to begin scheduled repairs.
+//
+// Example usage:
+//
+//	service := antientropy.NewService(manager, cnf.AntiEntropy, membershipNode)
+//	service.Start(ctx)
+//	defer service.Stop()
+//
+// Parameters:
+//
+//	manager (*db.Manager): The manager owning the repaired databases.
+//	cnf (config.AntiEntropy): The anti-entropy configuration.
+//	members (*membership.Node): Discovers peers when none are configured, may be nil.
+//
+// Returns:
+//
+//	*Service: A new Service instance.
+func NewService(manager *db.Manager, cnf config.AntiEntropy, members *membership.Node) *Service {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Service{
+		manager:   manager,
+		cnf:       cnf,
+		members:   members,
+		repairing: make(map[string]bool),
+		ctx:       ctx,
+		cancel:    cancel,
+	}
+}
+
+// Start begins scheduled repairs if they are enabled. Scheduled repairs stop when ctx is done.
+func (s *Service) Start(ctx context.Context) {
+	if !s.cnf.Enabled {
+		return
+	}
+
+	s.wg.Add(1)
+	go s.schedule(ctx)
+}
+
+// Stop cancels scheduled and running repairs and waits for them to return.
+func (s *Service) Stop() {
+	s.cancel()
+	s.wg.Wait()
+}
+
+// Status returns the running and the most recent finished repair runs.
+func (s *Service) Status() Status {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	status := Status{Runs: make([]Result, 0, len(s.runs))}
+	for i := len(s.runs) - 1; i >= 0; i-- {
+		status.Runs = append(status.Runs, *s.runs[i])
+	}
+	return status
+}
+
+// Run starts repairing a database against a peer in the background and returns the ID of the
+// run, whose progress is reported by Status.
+//
+// Parameters:
+//
+//	database (string): The database to repair, served under the same name by the peer.
+//	peer (config.ReplicationPeer): The node serving the other replica.
+//	direction (config.RepairDirection): Which replica is authoritative, empty for pull.
+//
+// Returns:
+//
+//	uint64: The ID of the run.
+//	error: Returns an error if the database is unknown, already being repaired, or the peer is invalid.
+func (s *Service) Run(database string, peer config.ReplicationPeer, direction config.RepairDirection) (uint64, error) {
+	if err := config.ValidatePeer(peer); err != nil {
+		return 0, err
+	}
+	if err := direction.Validate(); err != nil {
+		return 0, err
+	}
+
+	run, release, err := s.begin(database, peer, direction)
+	if err != nil {
+		return 0, err
+	}
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer release()
+		s.repair(s.ctx, run, peer)
+	}()
+	return run.ID, nil
+}
+
+// schedule repairs every configured database each interval until ctx is done.
+func (s *Service) schedule(ctx context.Context) {
+	defer s.wg.Done()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stop := context.AfterFunc(s.ctx, cancel)
+	defer stop()
+
+	ticker := time.NewTicker(s.cnf.GetInterval())
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		for _, info := range s.manager.ListDbs() {
+			if !info.Open || info.Remote || !s.cnf.Repairs(info.Name) {
+				continue
+			}
+
+			peer, ok := s.pickPeer(info.Name)
+			if !ok {
+				zap.L().Debug("No peer to repair database against", zap.String("database", info.Name))
+				continue
+			}
+
+			run, release, err := s.begin(info.Name, peer, s.cnf.Direction)
+			if err != nil {
+				zap.L().Debug("Skipping scheduled repair", zap.String("database", info.Name), zap.Error(err))
+				continue
+			}
+			s.repair(ctx, run, peer)
+			release()
+		}
+	}
+}
+
+// pickPeer returns a random configured peer, or a random alive gossip member serving the database.
+func (s *Service) pickPeer(database string) (config.ReplicationPeer, bool) {
+	if len(s.cnf.Peers) > 0 {
+		return s.cnf.Peers[rand.Intn(len(s.cnf.Peers))], true
+	}
+	if s.members == nil {
+		return config.ReplicationPeer{}, false
+	}
+
+	var candidates []config.ReplicationPeer
+	for _, member := range s.members.AliveMembers() {
+		if member.Name == s.members.Name() || !serves(member, database) {
+			continue
+		}
+		// Members do not advertise certificates, QUIC peers are not verified
+		for _, transport := range []types.TransportType{types.TCPTransportType, types.QUICTransportType} {
+			if addr := member.Endpoint(transport); addr != "" {
+				candidates = append(candidates, config.ReplicationPeer{Transport: transport, Addr: addr, Insecure: true})
+				break
+			}
+		}
+	}
+	if len(candidates) == 0 {
+		return config.ReplicationPeer{}, false
+	}
+	return candidates[rand.Intn(len(candidates))], true
+}
+
+// serves reports whether the member advertises the database.
+func serves(member membership.Member, database string) bool {
+	for _, name := range member.Databases {
+		if name == database {
+			return true
+		}
+	}
+	return false
+}
+
+// begin registers a new run of the database, failing if one is in progress. The returned
+// function ends the run.
+func (s *Service) begin(database string, peer config.ReplicationPeer, direction config.RepairDirection) (*Result, func(), error) {
+	if !s.manager.HasDb(types.DbType(database)) {
+		return nil, nil, fmt.Errorf("unknown database: %s", database)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.repairing[database] {
+		return nil, nil, fmt.Errorf("database %s is already being repaired", database)
+	}
+	s.repairing[database] = true
+
+	s.nextID++
+	run := &Result{
+		ID:        s.nextID,
+		Database:  database,
+		Peer:      peer.Addr,
+		Direction: direction.GetOrDefault(),
+		StartedAt: time.Now(),
+		Running:   true,
+	}
+	s.runs = append(s.runs, run)
+	if len(s.runs) > maxResults {
+		s.runs = s.runs[len(s.runs)-maxResults:]
+	}
+
+	return run, func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		delete(s.repairing, database)
+	}, nil
+}
+
+// repair runs a registered repair and records its outcome.
+func (s *Service) repair(ctx context.Context, run *Result, peer config.ReplicationPeer) {
+	result, err := s.repairDb(ctx, run.Database, peer, run.Direction)
+	if err != nil {
+		zap.L().Error(
+			"Anti-entropy repair failed",
+			zap.String("database", run.Database),
+			zap.String("peer", peer.Addr),
+			zap.Error(err),
+		)
+		result.Error = err.Error()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result.ID = run.ID
+	result.Peer = run.Peer
+	result.Direction = run.Direction
+	result.StartedAt = run.StartedAt
+	result.Duration = time.Since(run.StartedAt)
+	result.Running = false
+	*run = result
+}
+
+// repairDb connects to the peer and repairs the local database against it.
+func (s *Service) repairDb(ctx context.Context, database string, peer config.ReplicationPeer, direction config.RepairDirection) (Result, error) {
+	provider, release, err := s.manager.Acquire(types.DbType(database))
+	if err != nil {
+		return Result{Database: database}, err
+	}
+	defer release()
+
+	local, ok := provider.(*db.Db)
+	if !ok {
+		return Result{Database: database}, errors.Errorf("database %s is not stored on this node", database)
+	}
+
+	client, err := remote.New(remote.Options{
+		Transport: peer.Transport,
+		Addr:      peer.Addr,
+		Insecure:  peer.Insecure,
+		Timeout:   s.cnf.GetRequestTimeout(),
+	})
+	if err != nil {
+		return Result{Database: database}, err
+	}
+	defer client.Close()
+
+	return Repair(ctx, local, client, database, direction)
+}
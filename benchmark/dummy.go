@@ -49,7 +49,13 @@ func (ds *DummySuite) Start(ctx context.Context) error {
 		return fmt.Errorf("failed to cast transport to DummyServer")
 	}
 
-	db, err := ds.fdb.GetDbManager().GetDb("benchmark")
+	router, err := ds.fdb.NewRouter(types.DummyTransportType)
+	if err != nil {
+		return fmt.Errorf("failed to create database router: %w", err)
+	}
+
+	// Dummy handlers never touch the database, the default one of the transport is good enough
+	db, err := router.Resolve("")
 	if err != nil {
 		return fmt.Errorf("failed to retrieve benchmark database: %w", err)
 	}
@@ -75,7 +81,7 @@ func (ds *DummySuite) Start(ctx context.Context) error {
 // Stop stops the Dummy server and closes the client connection and stream.
 func (ds *DummySuite) Stop(ctx context.Context) error {
 	if ds.server != nil {
-		if err := ds.server.Stop(); err != nil {
+		if err := ds.fdb.Stop(types.DummyTransportType); err != nil {
 			return err
 		}
 	}
@@ -6,7 +6,6 @@ import (
 	"fmt"
 	"github.com/quic-go/quic-go"
 	"github.com/unpackdev/fdb"
-	"github.com/unpackdev/fdb/db"
 	transport_quic "github.com/unpackdev/fdb/transports/quic"
 	"github.com/unpackdev/fdb/types"
 	"golang.org/x/sync/errgroup"
@@ -51,18 +50,16 @@ func (qs *QuicSuite) Start(ctx context.Context) error {
 		return fmt.Errorf("failed to cast transport to QuicServer")
 	}
 
-	bDb, err := qs.fdb.GetDbManager().GetDb("benchmark")
+	// Route requests to the databases declared by the transport entry of the benchmark configuration
+	router, err := qs.fdb.NewRouter(types.QUICTransportType)
 	if err != nil {
-		return fmt.Errorf("failed to retrieve benchmark database: %w", err)
+		return fmt.Errorf("failed to create database router: %w", err)
 	}
 
-	// Create a new BatchWriter with a batch size of 512 and flush interval of 1 second
-	batchWriter := db.NewBatchWriter(bDb.(*db.Db), 512, 500*time.Millisecond, 15)
-
-	wHandler := transport_quic.NewQuicWriteHandler(bDb, batchWriter)
+	wHandler := transport_quic.NewQuicWriteHandler(router)
 	quicServer.RegisterHandler(types.WriteHandlerType, wHandler.HandleMessage)
 
-	rHandler := transport_quic.NewQuicReadHandler(bDb)
+	rHandler := transport_quic.NewQuicReadHandler(router)
 	quicServer.RegisterHandler(types.ReadHandlerType, rHandler.HandleMessage)
 
 	if err := quicServer.Start(ctx); err != nil {
@@ -77,7 +74,7 @@ func (qs *QuicSuite) Start(ctx context.Context) error {
 // Stop stops the QUIC server and closes the client connection and stream.
 func (qs *QuicSuite) Stop(ctx context.Context) error {
 	if qs.quicServer != nil {
-		if err := qs.quicServer.Stop(); err != nil {
+		if err := qs.fdb.Stop(types.QUICTransportType); err != nil {
 			return err
 		}
 	}
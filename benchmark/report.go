@@ -11,6 +11,8 @@ import (
 
 // Report holds the results of the benchmark.
 type Report struct {
+	Suite             string          `json:"suite,omitempty"`   // Suite the benchmark ran
+	Profile           string          `json:"profile,omitempty"` // MDBX profile the benchmark ran against
 	TotalClients      int             `json:"total_clients"`
 	MessagesPerClient int             `json:"messages_per_client"`
 	TotalMessages     int             `json:"total_messages"`
@@ -38,6 +40,12 @@ func NewReport() *Report {
 // PrintReport prints the benchmark report to the console.
 func (r *Report) PrintReport() {
 	fmt.Printf("\n--- Benchmark Report ---\n")
+	if r.Suite != "" {
+		fmt.Printf("Suite: %s\n", r.Suite)
+	}
+	if r.Profile != "" {
+		fmt.Printf("MDBX Profile: %s\n", r.Profile)
+	}
 	fmt.Printf("Total Clients: %d\n", r.TotalClients)
 	fmt.Printf("Messages per Client: %d\n", r.MessagesPerClient)
 	fmt.Printf("Total Messages: %d\n", r.TotalMessages)
@@ -115,3 +123,20 @@ func (r *Report) ExportToJSON(filename string) error {
 	fmt.Printf("Benchmark report exported to %s\n", filename)
 	return nil
 }
+
+// PrintComparison prints a side-by-side summary of reports, typically one per suite or MDBX
+// profile.
+func PrintComparison(reports []*Report) {
+	fmt.Printf("\n--- Benchmark Comparison ---\n")
+	fmt.Printf("%-10s %-12s %18s %12s %12s %12s %8s\n", "Suite", "Profile", "Throughput (msg/s)", "Avg", "P50", "P99", "Failed")
+	for _, r := range reports {
+		profile := r.Profile
+		if profile == "" {
+			profile = "(config)"
+		}
+		fmt.Printf("%-10s %-12s %18s %12s %12s %12s %8d\n",
+			r.Suite, profile, humanize.Comma(int64(r.Throughput)), r.AvgLatency, r.P50Latency, r.P99Latency, r.FailedMessages,
+		)
+	}
+	fmt.Println("")
+}
@@ -2,10 +2,10 @@ package benchmark
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
 	"github.com/pkg/errors"
 	"github.com/unpackdev/fdb"
-	"github.com/unpackdev/fdb/db"
 	transport_tcp "github.com/unpackdev/fdb/transports/tcp"
 	"github.com/unpackdev/fdb/types"
 	"go.uber.org/zap"
@@ -20,8 +20,10 @@ import (
 type TcpSuite struct {
 	fdb             *fdb.FDB
 	server          *transport_tcp.Server
-	pool            *sync.Pool // Buffer pool for reuse
-	latencySampling int        // How often to sample latencies (e.g., every 1000th message)
+	tlsConfig       *tls.Config // Client TLS configuration, nil when the transport serves plaintext
+	requireTLS      bool        // Whether the transport must serve TLS
+	pool            *sync.Pool  // Buffer pool for reuse
+	latencySampling int         // How often to sample latencies (e.g., every 1000th message)
 }
 
 // NewTcpSuite initializes the TcpSuite with buffer reuse and latency sampling settings.
@@ -38,6 +40,14 @@ func NewTcpSuite(fdb *fdb.FDB, latencySampling int) *TcpSuite {
 	}
 }
 
+// NewTcpTLSSuite initializes a TcpSuite benchmarking the TCP transport over TLS. The TLS block of
+// the transport must be enabled.
+func NewTcpTLSSuite(fdb *fdb.FDB, latencySampling int) *TcpSuite {
+	suite := NewTcpSuite(fdb, latencySampling)
+	suite.requireTLS = true
+	return suite
+}
+
 // Start starts the TCP server for benchmarking.
 func (ts *TcpSuite) Start(ctx context.Context) error {
 	tcpTransport, err := ts.fdb.GetTransportByType(types.TCPTransportType)
@@ -50,20 +60,34 @@ func (ts *TcpSuite) Start(ctx context.Context) error {
 		return fmt.Errorf("failed to cast transport to TcpServer")
 	}
 
-	bDb, err := ts.fdb.GetDbManager().GetDb("benchmark")
+	// Route requests to the databases declared by the transport entry of the benchmark configuration
+	router, err := ts.fdb.NewRouter(types.TCPTransportType)
 	if err != nil {
-		return fmt.Errorf("failed to retrieve benchmark database: %w", err)
+		return fmt.Errorf("failed to create database router: %w", err)
 	}
 
-	// Create a new BatchWriter with a batch size of 512 and flush interval of 1 second
-	batchWriter := db.NewBatchWriter(bDb.(*db.Db), 512, 500*time.Millisecond, 15)
-
-	wHandler := transport_tcp.NewTCPWriteHandler(bDb, batchWriter)
+	wHandler := transport_tcp.NewTCPWriteHandler(router)
 	tcpServer.RegisterHandler(types.WriteHandlerType, wHandler.HandleMessage)
 
-	rHandler := transport_tcp.NewTCPReadHandler(bDb)
+	rHandler := transport_tcp.NewTCPReadHandler(router)
 	tcpServer.RegisterHandler(types.ReadHandlerType, rHandler.HandleMessage)
 
+	tlsConfig, err := tcpServer.TLSConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load TCP transport TLS configuration: %w", err)
+	}
+	if tlsConfig == nil && ts.requireTLS {
+		return fmt.Errorf("TCP transport does not serve TLS, enable its tls block")
+	}
+	if tlsConfig != nil {
+		// The benchmark measures the transport, the certificate of the server is not verified
+		ts.tlsConfig = &tls.Config{
+			InsecureSkipVerify: true, // #nosec G402
+			MinVersion:         tlsConfig.MinVersion,
+			CipherSuites:       tlsConfig.CipherSuites,
+		}
+	}
+
 	if sErr := tcpServer.Start(ctx); sErr != nil {
 		zap.L().Error("failed to start TCP transport", zap.Error(sErr))
 	}
@@ -76,7 +100,7 @@ func (ts *TcpSuite) Start(ctx context.Context) error {
 // Stop stops the TCP server and closes the client connection.
 func (ts *TcpSuite) Stop(ctx context.Context) error {
 	if ts.server != nil {
-		if err := ts.server.Stop(); err != nil {
+		if err := ts.fdb.Stop(types.TCPTransportType); err != nil {
 			return err
 		}
 	}
@@ -84,8 +108,16 @@ func (ts *TcpSuite) Stop(ctx context.Context) error {
 	return nil
 }
 
-// AcquireClient creates and returns a new TCP client.
-func (ts *TcpSuite) AcquireClient() (*net.TCPConn, error) {
+// AcquireClient creates and returns a new TCP client, speaking TLS when the transport serves TLS.
+func (ts *TcpSuite) AcquireClient() (net.Conn, error) {
+	if ts.tlsConfig != nil {
+		client, err := tls.Dial("tcp", ts.server.Addr(), ts.tlsConfig)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to connect to server")
+		}
+		return client, nil
+	}
+
 	// Resolve the server address
 	serverAddr, err := net.ResolveTCPAddr("tcp", ts.server.Addr())
 	if err != nil {
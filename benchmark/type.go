@@ -9,11 +9,13 @@ import (
 type SuiteType string
 
 const (
-	QUICSuite      SuiteType = "quic"
-	UDSSuiteType   SuiteType = "uds" // Example for future transport suites
-	TCPSuiteType   SuiteType = "tcp"
-	UDPSuiteType   SuiteType = "udp"
-	DummySuiteType SuiteType = "dummy"
+	QUICSuite        SuiteType = "quic"
+	UDSSuiteType     SuiteType = "uds" // Example for future transport suites
+	TCPSuiteType     SuiteType = "tcp"
+	TCPTLSSuiteType  SuiteType = "tcp-tls" // TCP transport serving TLS
+	UDPSuiteType     SuiteType = "udp"
+	UDPDTLSSuiteType SuiteType = "udp-dtls" // UDP transport serving DTLS
+	DummySuiteType   SuiteType = "dummy"
 )
 
 // ErrInvalidSuiteType is returned when an unsupported SuiteType is provided.
@@ -3,9 +3,9 @@ package benchmark
 import (
 	"context"
 	"fmt"
+	"github.com/pion/dtls/v3"
 	"github.com/pkg/errors"
 	"github.com/unpackdev/fdb"
-	"github.com/unpackdev/fdb/db"
 	transport_udp "github.com/unpackdev/fdb/transports/udp"
 	"github.com/unpackdev/fdb/types"
 	"go.uber.org/zap"
@@ -20,8 +20,10 @@ import (
 type UdpSuite struct {
 	fdb             *fdb.FDB
 	server          *transport_udp.Server
-	pool            *sync.Pool // Buffer pool for reuse
-	latencySampling int        // How often to sample latencies (e.g., every 1000th message)
+	dtlsConfig      *dtls.Config // Client DTLS configuration, nil when the transport serves plaintext
+	requireDTLS     bool         // Whether the transport must serve DTLS
+	pool            *sync.Pool   // Buffer pool for reuse
+	latencySampling int          // How often to sample latencies (e.g., every 1000th message)
 }
 
 // NewUdpSuite initializes the UdpSuite with buffer reuse and latency sampling settings.
@@ -38,6 +40,14 @@ func NewUdpSuite(fdb *fdb.FDB, latencySampling int) *UdpSuite {
 	}
 }
 
+// NewUdpDTLSSuite initializes a UdpSuite benchmarking the UDP transport over DTLS. The DTLS
+// block of the transport must be enabled.
+func NewUdpDTLSSuite(fdb *fdb.FDB, latencySampling int) *UdpSuite {
+	suite := NewUdpSuite(fdb, latencySampling)
+	suite.requireDTLS = true
+	return suite
+}
+
 // Start starts the UDP server for benchmarking.
 func (us *UdpSuite) Start(ctx context.Context) error {
 	udpTransport, err := us.fdb.GetTransportByType(types.UDPTransportType)
@@ -50,20 +60,33 @@ func (us *UdpSuite) Start(ctx context.Context) error {
 		return fmt.Errorf("failed to cast transport to UdpServer")
 	}
 
-	bDb, err := us.fdb.GetDbManager().GetDb("benchmark")
+	// Route requests to the databases declared by the transport entry of the benchmark configuration
+	router, err := us.fdb.NewRouter(types.UDPTransportType)
 	if err != nil {
-		return fmt.Errorf("failed to retrieve benchmark database: %w", err)
+		return fmt.Errorf("failed to create database router: %w", err)
 	}
 
-	// Create a new BatchWriter with a batch size of 512 and flush interval of 1 second
-	batchWriter := db.NewBatchWriter(bDb.(*db.Db), 512, 500*time.Millisecond, 15)
-
-	wHandler := transport_udp.NewUDPWriteHandler(bDb, batchWriter)
+	wHandler := transport_udp.NewUDPWriteHandler(router)
 	udpServer.RegisterHandler(types.WriteHandlerType, wHandler.HandleMessage)
 
-	rHandler := transport_udp.NewUDPReadHandler(bDb)
+	rHandler := transport_udp.NewUDPReadHandler(router)
 	udpServer.RegisterHandler(types.ReadHandlerType, rHandler.HandleMessage)
 
+	dtlsConfig, err := udpServer.DTLSConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load UDP transport DTLS configuration: %w", err)
+	}
+	if dtlsConfig == nil && us.requireDTLS {
+		return fmt.Errorf("UDP transport does not serve DTLS, enable its dtls block")
+	}
+	if dtlsConfig != nil {
+		// The benchmark measures the transport, the certificate of the server is not verified
+		us.dtlsConfig = &dtls.Config{
+			InsecureSkipVerify:   true, // #nosec G402
+			ExtendedMasterSecret: dtls.RequireExtendedMasterSecret,
+		}
+	}
+
 	if sErr := udpServer.Start(ctx); sErr != nil {
 		zap.L().Error("failed to start UDP transport", zap.Error(sErr))
 	}
@@ -76,7 +99,7 @@ func (us *UdpSuite) Start(ctx context.Context) error {
 // Stop stops the UDP server and closes the client connection.
 func (us *UdpSuite) Stop(ctx context.Context) error {
 	if us.server != nil {
-		if err := us.server.Stop(); err != nil {
+		if err := us.fdb.Stop(types.UDPTransportType); err != nil {
 			return err
 		}
 	}
@@ -84,14 +107,27 @@ func (us *UdpSuite) Stop(ctx context.Context) error {
 	return nil
 }
 
-// AcquireClient creates and returns a new UDP client.
-func (us *UdpSuite) AcquireClient() (*net.UDPConn, error) {
+// AcquireClient creates and returns a new UDP client, speaking DTLS when the transport serves
+// DTLS.
+func (us *UdpSuite) AcquireClient() (net.Conn, error) {
 	// Resolve the server address
 	serverAddr, err := net.ResolveUDPAddr("udp", us.server.Addr())
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to resolve server address")
 	}
 
+	if us.dtlsConfig != nil {
+		client, err := dtls.Dial("udp", serverAddr, us.dtlsConfig)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to connect to server")
+		}
+		if err := client.Handshake(); err != nil {
+			_ = client.Close()
+			return nil, errors.Wrap(err, "failed to complete DTLS handshake")
+		}
+		return client, nil
+	}
+
 	// Create the UDP client
 	client, err := net.DialUDP("udp", nil, serverAddr)
 	if err != nil {
@@ -5,7 +5,6 @@ import (
 	"fmt"
 	"github.com/pkg/errors"
 	"github.com/unpackdev/fdb"
-	"github.com/unpackdev/fdb/db"
 	transport_uds "github.com/unpackdev/fdb/transports/uds"
 	"github.com/unpackdev/fdb/types"
 	"go.uber.org/zap"
@@ -50,19 +49,17 @@ func (us *UdsSuite) Start(ctx context.Context) error {
 		return fmt.Errorf("failed to cast transport to UdsServer")
 	}
 
-	bDb, err := us.fdb.GetDbManager().GetDb("benchmark")
+	// Route requests to the databases declared by the transport entry of the benchmark configuration
+	router, err := us.fdb.NewRouter(types.UDSTransportType)
 	if err != nil {
-		return fmt.Errorf("failed to retrieve benchmark database: %w", err)
+		return fmt.Errorf("failed to create database router: %w", err)
 	}
 
-	// Create a new BatchWriter with a batch size of 512 and flush interval of 1 second
-	batchWriter := db.NewBatchWriter(bDb.(*db.Db), 512, 500*time.Millisecond, 15)
-
 	// Register write and read handlers
-	wHandler := transport_uds.NewUDSWriteHandler(bDb, batchWriter)
+	wHandler := transport_uds.NewUDSWriteHandler(router)
 	udsServer.RegisterHandler(types.WriteHandlerType, wHandler.HandleMessage)
 
-	rHandler := transport_uds.NewUDSReadHandler(bDb)
+	rHandler := transport_uds.NewUDSReadHandler(router)
 	udsServer.RegisterHandler(types.ReadHandlerType, rHandler.HandleMessage)
 
 	if sErr := udsServer.Start(ctx); sErr != nil {
@@ -77,7 +74,7 @@ func (us *UdsSuite) Start(ctx context.Context) error {
 // Stop stops the UDS server and closes the client connection.
 func (us *UdsSuite) Stop(ctx context.Context) error {
 	if us.server != nil {
-		if err := us.server.Stop(); err != nil {
+		if err := us.fdb.Stop(types.UDSTransportType); err != nil {
 			return err
 		}
 	}
@@ -0,0 +1,227 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	fdbErrors "github.com/unpackdev/fdb/errors"
+	"github.com/unpackdev/fdb/messages"
+	"github.com/unpackdev/fdb/types"
+)
+
+// Get returns the value of a key. Requests are tagged so their responses are matched by ID,
+// any number of them may be in flight on the same transport.
+//
+// Example usage:
+//
+//	value, err := c.Get(ctx, key)
+//	if errors.Is(err, fdbErrors.ErrNotFound) {
+//	    log.Printf("Key %x not found", key)
+//	}
+//
+// Parameters:
+//
+//	ctx (context.Context): Bounds the request, Config.RequestTimeout applies when it has no deadline.
+//	key ([32]byte): The key to read.
+//
+// Returns:
+//
+//	[]byte: The stored value.
+//	error: Returns errors.ErrNotFound if the key is missing, or a request error.
+func (c *Client) Get(ctx context.Context, key [32]byte) ([]byte, error) {
+	resp, err := c.do(ctx, types.ReadHandlerType, key, nil)
+	if err != nil {
+		return nil, err
+	}
+	return readResponse(resp)
+}
+
+// Set stores the value of a key. The server acknowledges once the write is buffered, it becomes
+// visible to reads when the server's batch writer flushes.
+//
+// Returns:
+//
+//	error: Returns errors.ErrReadOnly if the database rejects writes, or a request error.
+func (c *Client) Set(ctx context.Context, key [32]byte, value []byte) error {
+	if len(value) == 0 {
+		return errors.New("writes require a value")
+	}
+
+	resp, err := c.do(ctx, types.WriteHandlerType, key, value)
+	if err != nil {
+		return err
+	}
+	return statusResponse(resp)
+}
+
+// Delete removes a key. Deleting a missing key succeeds.
+func (c *Client) Delete(ctx context.Context, key [32]byte) error {
+	resp, err := c.do(ctx, types.DeleteHandlerType, key, nil)
+	if err != nil {
+		return err
+	}
+	return statusResponse(resp)
+}
+
+// Exists reports whether a key has a value.
+func (c *Client) Exists(ctx context.Context, key [32]byte) (bool, error) {
+	_, err := c.Get(ctx, key)
+	if errors.Is(err, fdbErrors.ErrNotFound) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// MGet returns the values of several keys, in the order of keys. The reads are sent at once and
+// answered concurrently; missing keys have a nil value.
+//
+// Example usage:
+//
+//	values, err := c.MGet(ctx, first, second)
+//	if err != nil {
+//	    log.Fatalf("Failed to read keys: %v", err)
+//	}
+//
+// Returns:
+//
+//	[][]byte: The values, nil for missing keys.
+//	error: Returns the first request error, in the order of keys.
+func (c *Client) MGet(ctx context.Context, keys ...[32]byte) ([][]byte, error) {
+	ctx, cancel := c.cfg.requestContext(ctx)
+	defer cancel()
+
+	values := make([][]byte, len(keys))
+	errs := make([]error, len(keys))
+	done := make(chan struct{}, len(keys))
+	for i, key := range keys {
+		go func(i int, key [32]byte) {
+			defer func() { done <- struct{}{} }()
+
+			value, err := c.Get(ctx, key)
+			if errors.Is(err, fdbErrors.ErrNotFound) {
+				return
+			}
+			values[i], errs[i] = value, err
+		}(i, key)
+	}
+	for range keys {
+		<-done
+	}
+
+	for i, err := range errs {
+		if err != nil {
+			return nil, fmt.Errorf("failed to read key %x: %w", keys[i], err)
+		}
+	}
+	return values, nil
+}
+
+// do sends a key request and returns the raw response.
+func (c *Client) do(ctx context.Context, handler types.HandlerType, key [32]byte, value []byte) ([]byte, error) {
+	return c.send(ctx, handler, keyFrame(handler, key, value))
+}
+
+// keyFrame encodes a key request: handler (1 byte) | key (32 bytes) | value
+func keyFrame(handler types.HandlerType, key [32]byte, value []byte) []byte {
+	frame := make([]byte, 1+len(key)+len(value))
+	frame[0] = byte(handler)
+	copy(frame[1:], key[:])
+	copy(frame[1+len(key):], value)
+	return frame
+}
+
+// send selects the database of a request frame and sends it through the group serving its
+// handler, or the default transport, returning the raw response. Failed requests are retried
+// as allowed by Config.Retry.
+func (c *Client) send(ctx context.Context, handler types.HandlerType, frame []byte) ([]byte, error) {
+	frame, err := messages.WithDatabase(c.cfg.Database, frame)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := c.cfg.requestContext(ctx)
+	defer cancel()
+
+	return c.retry(ctx, handler, func(ctx context.Context) ([]byte, error) {
+		return c.attempt(ctx, handler, frame)
+	})
+}
+
+// attempt sends a request frame once. Requests the server was too busy to serve, reads included,
+// fail with errors.ErrBusy before their response is decoded.
+func (c *Client) attempt(ctx context.Context, handler types.HandlerType, frame []byte) ([]byte, error) {
+	var (
+		resp []byte
+		err  error
+	)
+	if group := c.group(handler); group != nil {
+		resp, err = group.roundTrip(ctx, frame)
+	} else {
+		var corr *correlator
+		if corr, err = c.defaultCorrelator(); err == nil {
+			resp, err = corr.roundTrip(ctx, frame)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if len(resp) == 1 && types.ResponseStatus(resp[0]) == types.StatusBusy {
+		return nil, fdbErrors.ErrBusy
+	}
+	return resp, nil
+}
+
+// defaultCorrelator returns the correlator of the transport serving the typed API.
+func (c *Client) defaultCorrelator() (*correlator, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	name, err := c.defaultName()
+	if err != nil {
+		return nil, err
+	}
+	corr, ok := c.correlators[name]
+	if !ok {
+		return nil, fmt.Errorf("transport %s not found", name)
+	}
+	return corr, nil
+}
+
+// defaultName returns the name of the default transport. The caller holds mu.
+func (c *Client) defaultName() (string, error) {
+	if c.cfg.Default != "" {
+		return c.cfg.Default, nil
+	}
+	if len(c.correlators) != 1 {
+		return "", fmt.Errorf("no default transport configured among %d transports", len(c.correlators))
+	}
+	for only := range c.correlators {
+		return only, nil
+	}
+	return "", nil
+}
+
+// streamer returns the named transport opening the connections of server push streams, the
+// default transport when name is empty.
+func (c *Client) streamer(name string) (Streamer, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if name == "" {
+		var err error
+		if name, err = c.defaultName(); err != nil {
+			return nil, err
+		}
+	}
+	transport, ok := c.transports[name]
+	if !ok {
+		return nil, fmt.Errorf("transport %s not found", name)
+	}
+	streamer, ok := transport.(Streamer)
+	if !ok {
+		return nil, fmt.Errorf("transport %s cannot open streams", name)
+	}
+	return streamer, nil
+}
@@ -0,0 +1,209 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultCircuitFailureThreshold is the number of consecutive failures opening a circuit.
+	DefaultCircuitFailureThreshold = 5
+
+	// DefaultCircuitOpenTimeout is how long a circuit stays open before probing the endpoint.
+	DefaultCircuitOpenTimeout = 5 * time.Second
+
+	// DefaultCircuitHalfOpenProbes is the number of requests let through a half-open circuit.
+	DefaultCircuitHalfOpenProbes = 1
+)
+
+// ErrCircuitOpen is returned for requests not sent because the circuit of their transport is
+// open
+var ErrCircuitOpen = errors.New("circuit is open")
+
+// CircuitState is the state of the circuit breaker of a transport.
+type CircuitState int
+
+const (
+	// CircuitClosed lets every request through.
+	CircuitClosed CircuitState = iota
+
+	// CircuitOpen rejects every request with ErrCircuitOpen.
+	CircuitOpen
+
+	// CircuitHalfOpen lets a few probing requests through, closing the circuit once one
+	// succeeds and opening it again once one fails.
+	CircuitHalfOpen
+)
+
+// String returns the name of the state
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+// CircuitBreaker configures the circuit breakers guarding every transport of a client. A
+// circuit opens after consecutive requests of its transport failed, i.e. could not be sent or
+// got no answer in time, so requests fail fast with ErrCircuitOpen instead of waiting for a
+// dead endpoint. Once OpenTimeout elapsed the circuit half-opens and lets probing requests
+// through.
+type CircuitBreaker struct {
+	// FailureThreshold is the number of consecutive failures opening the circuit,
+	// DefaultCircuitFailureThreshold when zero.
+	FailureThreshold int `yaml:"failureThreshold" json:"failureThreshold"`
+
+	// OpenTimeout is how long the circuit stays open, DefaultCircuitOpenTimeout when zero.
+	OpenTimeout time.Duration `yaml:"openTimeout" json:"openTimeout"`
+
+	// HalfOpenProbes is the number of requests let through at once while half-open,
+	// DefaultCircuitHalfOpenProbes when zero.
+	HalfOpenProbes int `yaml:"halfOpenProbes" json:"halfOpenProbes"`
+
+	// OnStateChange is called with the name of the transport on every state change. It must
+	// not block.
+	OnStateChange func(transport string, from, to CircuitState) `yaml:"-" json:"-"`
+}
+
+// CircuitStats describes the circuit breaker of a transport.
+type CircuitStats struct {
+	// State is the current state of the circuit.
+	State CircuitState `json:"state"`
+
+	// Failures is the number of consecutive failures.
+	Failures int `json:"failures"`
+
+	// Opened is the number of times the circuit opened.
+	Opened uint64 `json:"opened"`
+
+	// Rejected is the number of requests rejected with ErrCircuitOpen.
+	Rejected uint64 `json:"rejected"`
+}
+
+// breaker is the circuit breaker of a transport. A nil breaker lets every request through.
+type breaker struct {
+	name string
+	cfg  CircuitBreaker
+
+	// mu guards the fields below.
+	mu       sync.Mutex
+	state    CircuitState
+	openedAt time.Time
+	probes   int
+	stats    CircuitStats
+}
+
+// newBreaker creates the breaker of a transport, nil when cfg is nil.
+func newBreaker(name string, cfg *CircuitBreaker) *breaker {
+	if cfg == nil {
+		return nil
+	}
+	b := &breaker{name: name, cfg: *cfg}
+	if b.cfg.FailureThreshold <= 0 {
+		b.cfg.FailureThreshold = DefaultCircuitFailureThreshold
+	}
+	if b.cfg.OpenTimeout <= 0 {
+		b.cfg.OpenTimeout = DefaultCircuitOpenTimeout
+	}
+	if b.cfg.HalfOpenProbes <= 0 {
+		b.cfg.HalfOpenProbes = DefaultCircuitHalfOpenProbes
+	}
+	return b
+}
+
+// allow reports whether a request may be sent, returning ErrCircuitOpen otherwise, and whether
+// the request probes a half-open circuit. Every allowed request must be followed by a call to
+// done.
+func (b *breaker) allow() (bool, error) {
+	if b == nil {
+		return false, nil
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == CircuitOpen && time.Since(b.openedAt) >= b.cfg.OpenTimeout {
+		b.transition(CircuitHalfOpen)
+	}
+	switch {
+	case b.state == CircuitOpen, b.state == CircuitHalfOpen && b.probes >= b.cfg.HalfOpenProbes:
+		b.stats.Rejected++
+		return false, ErrCircuitOpen
+	case b.state == CircuitHalfOpen:
+		b.probes++
+		return true, nil
+	}
+	return false, nil
+}
+
+// done records the outcome of an allowed request. Only probes close or open again a half-open
+// circuit, and requests abandoned by their caller count neither as a success nor as a failure.
+func (b *breaker) done(probe bool, err error) {
+	if b == nil {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	probing := probe && b.state == CircuitHalfOpen
+	if probing {
+		b.probes--
+	}
+	switch {
+	case errors.Is(err, context.Canceled):
+	case err == nil:
+		b.stats.Failures = 0
+		if probing {
+			b.transition(CircuitClosed)
+		}
+	default:
+		b.stats.Failures++
+		if probing || (b.state == CircuitClosed && b.stats.Failures >= b.cfg.FailureThreshold) {
+			b.transition(CircuitOpen)
+		}
+	}
+}
+
+// open reports whether requests are rejected, without counting a rejection.
+func (b *breaker) open() bool {
+	if b == nil {
+		return false
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state == CircuitOpen && time.Since(b.openedAt) < b.cfg.OpenTimeout
+}
+
+// snapshot returns the state and counters of the breaker.
+func (b *breaker) snapshot() CircuitStats {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	stats := b.stats
+	stats.State = b.state
+	return stats
+}
+
+// transition changes the state and notifies the callback. The caller holds mu.
+func (b *breaker) transition(to CircuitState) {
+	from := b.state
+	b.state = to
+	b.probes = 0
+	if to == CircuitOpen {
+		b.openedAt = time.Now()
+		b.stats.Opened++
+	}
+	if b.cfg.OnStateChange != nil {
+		b.cfg.OnStateChange(b.name, from, to)
+	}
+}
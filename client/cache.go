@@ -0,0 +1,495 @@
+package client
+
+import (
+	"bufio"
+	"container/list"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	fdbErrors "github.com/unpackdev/fdb/errors"
+	"github.com/unpackdev/fdb/messages"
+	"github.com/unpackdev/fdb/types"
+	"go.uber.org/zap"
+)
+
+const (
+	// DefaultCacheSize is the number of values a Cache holds.
+	DefaultCacheSize = 10000
+
+	// DefaultCacheResubscribeInterval is how long a Cache waits before subscribing again to a
+	// change stream that failed.
+	DefaultCacheResubscribeInterval = time.Second
+
+	// cacheStreamTimeout is how long the change stream may stay silent before it is considered
+	// lost; the server sends a heartbeat every messages.HeartbeatInterval.
+	cacheStreamTimeout = 3 * messages.HeartbeatInterval
+)
+
+// CacheOption configures a Cache
+type CacheOption func(*Cache)
+
+// WithCacheSize sets the number of values held, DefaultCacheSize when not set. The least
+// recently read values are evicted first.
+func WithCacheSize(size int) CacheOption {
+	return func(cache *Cache) {
+		if size > 0 {
+			cache.size = size
+		}
+	}
+}
+
+// WithCacheTTL sets how long a value is served from the cache. Values never expire when not
+// set, which suits keys that never change, such as content addressed ones.
+func WithCacheTTL(ttl time.Duration) CacheOption {
+	return func(cache *Cache) {
+		if ttl > 0 {
+			cache.ttl = ttl
+		}
+	}
+}
+
+// WithCacheInvalidation subscribes to the change stream of the server behind the named transport
+// of the client, the default transport when empty, and evicts the values of the changed keys.
+// The transport must open streams, see Streamer, such as TCPTransport and TLSTransport, and the
+// database needs change data capture enabled. Values are only cached once the server
+// acknowledged the subscription: the cache is emptied when the subscription is lost.
+func WithCacheInvalidation(transport string) CacheOption {
+	return func(cache *Cache) {
+		cache.invalidation = true
+		cache.streamTransport = transport
+	}
+}
+
+// WithCacheLogger sets the logger reporting the failures of the change stream, zap.NewNop when
+// not set.
+func WithCacheLogger(logger *zap.Logger) CacheOption {
+	return func(cache *Cache) {
+		if logger != nil {
+			cache.logger = logger
+		}
+	}
+}
+
+// CacheStats describes the reads served by a Cache.
+type CacheStats struct {
+	// Hits is the number of reads served from the cache.
+	Hits uint64 `json:"hits"`
+
+	// Misses is the number of reads sent to the server.
+	Misses uint64 `json:"misses"`
+
+	// Evictions is the number of values removed to make room for others.
+	Evictions uint64 `json:"evictions"`
+
+	// Expirations is the number of values removed once their TTL elapsed.
+	Expirations uint64 `json:"expirations"`
+
+	// Invalidations is the number of values removed because their key changed.
+	Invalidations uint64 `json:"invalidations"`
+
+	// Entries is the number of values held.
+	Entries int `json:"entries"`
+
+	// Subscribed reports whether the change stream is up, always false without
+	// WithCacheInvalidation.
+	Subscribed bool `json:"subscribed"`
+}
+
+// cacheEntry is a value held by the cache.
+type cacheEntry struct {
+	key     [32]byte
+	value   []byte
+	expires time.Time
+}
+
+// pendingRead tracks the reads of a key sent to the server, so a value read before its key
+// changed is not cached after the change was seen.
+type pendingRead struct {
+	readers     int
+	invalidated bool
+}
+
+// Cache serves the reads of a Client from a bounded least recently used cache. Writes and
+// deletes made through the Cache go to the client and evict the key.
+//
+// Values may expire after a TTL, see WithCacheTTL, and stay coherent with the writes of other
+// clients when the cache subscribes to the server's change stream, see WithCacheInvalidation.
+// Without either, a value is served until evicted, so only keys that never change should be
+// read through the cache.
+type Cache struct {
+	client          *Client
+	size            int
+	ttl             time.Duration
+	invalidation    bool
+	streamTransport string
+	logger          *zap.Logger
+
+	// mu guards the fields below.
+	mu         sync.Mutex
+	entries    map[[32]byte]*list.Element
+	recent     *list.List
+	pending    map[[32]byte]*pendingRead
+	subscribed bool
+	stats      CacheStats
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewCache creates a Cache reading through the client
+//
+// Example usage:
+//
+//	cache := c.NewCache(
+//	    client.WithCacheSize(100000),
+//	    client.WithCacheInvalidation("tcp"),
+//	)
+//	defer cache.Close()
+//
+//	code, err := cache.Get(ctx, codeHash)
+//
+// Parameters:
+//
+//	opts (...CacheOption): The size of the cache, the TTL and the change stream.
+//
+// Returns:
+//
+//	*Cache: A new Cache, subscribed to the change stream until closed.
+func (c *Client) NewCache(opts ...CacheOption) *Cache {
+	cache := &Cache{
+		client:  c,
+		size:    DefaultCacheSize,
+		logger:  zap.NewNop(),
+		entries: make(map[[32]byte]*list.Element),
+		recent:  list.New(),
+		pending: make(map[[32]byte]*pendingRead),
+	}
+	for _, opt := range opts {
+		opt(cache)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cache.cancel = cancel
+	if cache.invalidation {
+		cache.wg.Add(1)
+		go cache.invalidate(ctx)
+	}
+	return cache
+}
+
+// Get returns the value of a key, from the cache when held.
+func (cache *Cache) Get(ctx context.Context, key [32]byte) ([]byte, error) {
+	if value, ok := cache.lookup(key); ok {
+		return value, nil
+	}
+
+	cache.begin(key)
+	value, err := cache.client.Get(ctx, key)
+	cache.end(key, value, err)
+	return value, err
+}
+
+// Exists reports whether a key has a value, from the cache when held.
+func (cache *Cache) Exists(ctx context.Context, key [32]byte) (bool, error) {
+	_, err := cache.Get(ctx, key)
+	if errors.Is(err, fdbErrors.ErrNotFound) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// MGet returns the values of several keys, in the order of keys, reading the keys not held by
+// the cache at once. Missing keys have a nil value.
+func (cache *Cache) MGet(ctx context.Context, keys ...[32]byte) ([][]byte, error) {
+	values := make([][]byte, len(keys))
+	var missed []int
+	for i, key := range keys {
+		if value, ok := cache.lookup(key); ok {
+			values[i] = value
+			continue
+		}
+		missed = append(missed, i)
+	}
+	if len(missed) == 0 {
+		return values, nil
+	}
+
+	missedKeys := make([][32]byte, len(missed))
+	for j, i := range missed {
+		missedKeys[j] = keys[i]
+		cache.begin(keys[i])
+	}
+	read, err := cache.client.MGet(ctx, missedKeys...)
+	for j, key := range missedKeys {
+		switch {
+		case err != nil:
+			cache.end(key, nil, err)
+		case read[j] == nil:
+			cache.end(key, nil, fdbErrors.ErrNotFound)
+		default:
+			cache.end(key, read[j], nil)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for j, i := range missed {
+		values[i] = read[j]
+	}
+	return values, nil
+}
+
+// Set stores the value of a key through the client and evicts the key.
+func (cache *Cache) Set(ctx context.Context, key [32]byte, value []byte) error {
+	defer cache.Invalidate(key)
+	return cache.client.Set(ctx, key, value)
+}
+
+// Delete removes a key through the client and evicts the key.
+func (cache *Cache) Delete(ctx context.Context, key [32]byte) error {
+	defer cache.Invalidate(key)
+	return cache.client.Delete(ctx, key)
+}
+
+// Invalidate evicts the value of a key, and keeps the reads of the key in flight from caching
+// their value.
+func (cache *Cache) Invalidate(key [32]byte) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	cache.invalidateLocked(key)
+}
+
+// Purge evicts every value.
+func (cache *Cache) Purge() {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	cache.purgeLocked()
+}
+
+// Stats returns the reads served so far.
+func (cache *Cache) Stats() CacheStats {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	stats := cache.stats
+	stats.Entries = len(cache.entries)
+	stats.Subscribed = cache.subscribed
+	return stats
+}
+
+// Close stops the change stream and evicts every value. The client is left open.
+func (cache *Cache) Close() error {
+	cache.cancel()
+	cache.wg.Wait()
+	cache.Purge()
+	return nil
+}
+
+// lookup returns a copy of the value held for a key, counting the hit or the miss.
+func (cache *Cache) lookup(key [32]byte) ([]byte, bool) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	element, ok := cache.entries[key]
+	if !ok {
+		cache.stats.Misses++
+		return nil, false
+	}
+
+	entry := element.Value.(*cacheEntry)
+	if !entry.expires.IsZero() && time.Now().After(entry.expires) {
+		cache.remove(element)
+		cache.stats.Expirations++
+		cache.stats.Misses++
+		return nil, false
+	}
+
+	cache.recent.MoveToFront(element)
+	cache.stats.Hits++
+	return append([]byte(nil), entry.value...), true
+}
+
+// begin records a read of a key sent to the server.
+func (cache *Cache) begin(key [32]byte) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	read, ok := cache.pending[key]
+	if !ok {
+		read = &pendingRead{}
+		cache.pending[key] = read
+	}
+	read.readers++
+}
+
+// end records the result of a read of a key, caching the value unless the key changed while the
+// read was in flight or the cache cannot learn about changes.
+func (cache *Cache) end(key [32]byte, value []byte, err error) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	read := cache.pending[key]
+	read.readers--
+	if read.readers == 0 {
+		delete(cache.pending, key)
+	}
+
+	if err != nil || read.invalidated || (cache.invalidation && !cache.subscribed) {
+		return
+	}
+
+	// The value is returned to the reader too, the cache keeps a copy of its own
+	entry := &cacheEntry{key: key, value: append([]byte(nil), value...)}
+	if cache.ttl > 0 {
+		entry.expires = time.Now().Add(cache.ttl)
+	}
+	if element, ok := cache.entries[key]; ok {
+		element.Value = entry
+		cache.recent.MoveToFront(element)
+		return
+	}
+
+	cache.entries[key] = cache.recent.PushFront(entry)
+	for len(cache.entries) > cache.size {
+		cache.remove(cache.recent.Back())
+		cache.stats.Evictions++
+	}
+}
+
+// invalidateLocked evicts the value of a key. The caller holds mu.
+func (cache *Cache) invalidateLocked(key [32]byte) {
+	if read, ok := cache.pending[key]; ok {
+		read.invalidated = true
+	}
+	if element, ok := cache.entries[key]; ok {
+		cache.remove(element)
+		cache.stats.Invalidations++
+	}
+}
+
+// purgeLocked evicts every value, and keeps the reads in flight from caching their value. The
+// caller holds mu.
+func (cache *Cache) purgeLocked() {
+	for _, read := range cache.pending {
+		read.invalidated = true
+	}
+	cache.entries = make(map[[32]byte]*list.Element)
+	cache.recent.Init()
+}
+
+// remove removes a value. The caller holds mu.
+func (cache *Cache) remove(element *list.Element) {
+	cache.recent.Remove(element)
+	delete(cache.entries, element.Value.(*cacheEntry).key)
+}
+
+// invalidate follows the change stream until ctx is done, subscribing again after failures.
+func (cache *Cache) invalidate(ctx context.Context) {
+	defer cache.wg.Done()
+
+	var position uint64
+	for {
+		err := cache.follow(ctx, &position)
+
+		// Changes may be missed until subscribed again, so nothing cached can be trusted
+		cache.mu.Lock()
+		cache.subscribed = false
+		cache.purgeLocked()
+		cache.mu.Unlock()
+
+		if ctx.Err() != nil {
+			return
+		}
+		if errors.Is(err, fdbErrors.ErrChangesTruncated) {
+			position = 0
+		}
+		cache.logger.Warn("Cache change stream failed", zap.String("transport", cache.streamTransport), zap.Error(err))
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(DefaultCacheResubscribeInterval):
+		}
+	}
+}
+
+// follow subscribes to the changes following position and evicts the changed keys, updating
+// position, until the stream fails or ctx is done. The first subscription replays the changes
+// retained by the server.
+func (cache *Cache) follow(ctx context.Context, position *uint64) error {
+	streamer, err := cache.client.streamer(cache.streamTransport)
+	if err != nil {
+		return err
+	}
+	conn, err := streamer.OpenStream(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	stop := context.AfterFunc(ctx, func() { _ = conn.Close() })
+	defer stop()
+
+	from := *position
+	if from > 0 {
+		from++
+	}
+	request := messages.SubscribeRequest{From: from}
+	frame, err := messages.WithDatabase(cache.client.cfg.Database, request.Encode())
+	if err != nil {
+		return err
+	}
+	if _, err := conn.Write(frame); err != nil {
+		return err
+	}
+
+	acknowledged := false
+	reader := bufio.NewReader(conn)
+	for {
+		if err := conn.SetReadDeadline(time.Now().Add(cacheStreamTimeout)); err != nil {
+			return err
+		}
+		status, body, err := messages.ReadStreamFrame(reader)
+		if err != nil {
+			return err
+		}
+
+		switch status {
+		case types.StatusOK:
+			change, err := messages.DecodeChange(body)
+			if err != nil {
+				return err
+			}
+			if len(change.Key) == 32 {
+				cache.Invalidate([32]byte(change.Key))
+			}
+			*position = change.Seq
+		case types.StatusHeartbeat:
+			heartbeat, err := messages.DecodeHeartbeat(body)
+			if err != nil {
+				return err
+			}
+			// A server behind the position lost changes, follow it from its head
+			if heartbeat.Head < *position {
+				*position = heartbeat.Head
+				return fmt.Errorf("server head %d is behind the stream position", heartbeat.Head)
+			}
+		case types.StatusTruncated:
+			return fmt.Errorf("change stream: %w: %s", fdbErrors.ErrChangesTruncated, body)
+		default:
+			return fmt.Errorf("change stream: %s", body)
+		}
+
+		// The server acknowledges the subscription with its first frame, reads sent from now on
+		// are followed by the changes of their keys
+		if !acknowledged {
+			acknowledged = true
+			cache.mu.Lock()
+			cache.subscribed = true
+			cache.mu.Unlock()
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package client_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/unpackdev/fdb/client"
+	fdbErrors "github.com/unpackdev/fdb/errors"
+	"github.com/unpackdev/fdb/fdbtest"
+	"github.com/unpackdev/fdb/types"
+)
+
+func TestCacheEvictsAndExpires(t *testing.T) {
+	server := fdbtest.Start(t, fdbtest.WithTransports(types.TCPTransportType))
+	provider := server.Provider(t, fdbtest.DefaultDatabase)
+	for i := 0; i < 3; i++ {
+		key := testKey(i)
+		require.NoError(t, provider.Set(key[:], []byte(fmt.Sprintf("value-%d", i))))
+	}
+
+	cache := server.Client(t, types.TCPTransportType).NewCache(client.WithCacheSize(2), client.WithCacheTTL(200*time.Millisecond))
+	t.Cleanup(func() { _ = cache.Close() })
+	ctx := context.Background()
+
+	for _, i := range []int{0, 1, 0, 2, 1} {
+		value, err := cache.Get(ctx, testKey(i))
+		require.NoError(t, err)
+		assert.Equal(t, fmt.Sprintf("value-%d", i), string(value))
+	}
+	// The second key was the least recently read when the third one was cached
+	stats := cache.Stats()
+	assert.Equal(t, uint64(1), stats.Hits)
+	assert.Equal(t, uint64(4), stats.Misses)
+	assert.Equal(t, uint64(2), stats.Evictions)
+	assert.Equal(t, 2, stats.Entries)
+
+	// Missing keys are not cached
+	_, err := cache.Get(ctx, testKey(9))
+	assert.ErrorIs(t, err, fdbErrors.ErrNotFound)
+	found, err := cache.Exists(ctx, testKey(9))
+	require.NoError(t, err)
+	assert.False(t, found)
+
+	values, err := cache.MGet(ctx, testKey(1), testKey(2), testKey(9))
+	require.NoError(t, err)
+	assert.Equal(t, [][]byte{[]byte("value-1"), []byte("value-2"), nil}, values)
+	assert.Equal(t, uint64(3), cache.Stats().Hits)
+
+	// Readers get values of their own, changing them leaves the cache unchanged
+	values[0][0] = 'x'
+	value, err := cache.Get(ctx, testKey(1))
+	require.NoError(t, err)
+	value[1] = 'x'
+	value, err = cache.Get(ctx, testKey(1))
+	require.NoError(t, err)
+	assert.Equal(t, "value-1", string(value))
+
+	time.Sleep(300 * time.Millisecond)
+	_, err = cache.Get(ctx, testKey(1))
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), cache.Stats().Expirations)
+}
+
+func TestCacheInvalidatesChangedKeys(t *testing.T) {
+	// The subscription goes through the transport of the client, so TLS applies to it as well
+	for name, opts := range map[string][]fdbtest.Option{
+		"tcp": {fdbtest.WithTransports(types.TCPTransportType), fdbtest.WithCDC()},
+		"tls": {fdbtest.WithTransports(types.TCPTransportType), fdbtest.WithCDC(), fdbtest.WithTLS()},
+	} {
+		t.Run(name, func(t *testing.T) {
+			server := fdbtest.Start(t, opts...)
+			testCacheInvalidatesChangedKeys(t, server)
+		})
+	}
+}
+
+func testCacheInvalidatesChangedKeys(t *testing.T, server *fdbtest.Server) {
+	writer := server.Client(t, types.TCPTransportType)
+	ctx := context.Background()
+	key := testKey(1)
+	require.NoError(t, writer.Set(ctx, key, []byte("v1")))
+
+	cache := server.Client(t, types.TCPTransportType).NewCache(client.WithCacheInvalidation(""))
+	t.Cleanup(func() { _ = cache.Close() })
+	require.Eventually(t, func() bool { return cache.Stats().Subscribed }, 5*time.Second, 10*time.Millisecond)
+
+	// The change of the first write may evict the value once more, until then reads miss
+	require.Eventually(t, func() bool {
+		hits := cache.Stats().Hits
+		value, err := cache.Get(ctx, key)
+		return err == nil && string(value) == "v1" && cache.Stats().Hits > hits
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// A write of another client reaches the cache through the change stream
+	require.NoError(t, writer.Set(ctx, key, []byte("v2")))
+	require.Eventually(t, func() bool {
+		value, err := cache.Get(ctx, key)
+		return err == nil && string(value) == "v2"
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.NotZero(t, cache.Stats().Invalidations)
+
+	require.NoError(t, writer.Delete(ctx, key))
+	require.Eventually(t, func() bool {
+		found, err := cache.Exists(ctx, key)
+		return err == nil && !found
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestCacheWaitsForSubscription(t *testing.T) {
+	// The server refuses subscriptions without change data capture, so nothing is ever cached
+	server := fdbtest.Start(t, fdbtest.WithTransports(types.TCPTransportType))
+	ctx := context.Background()
+	key := testKey(1)
+	require.NoError(t, server.Provider(t, fdbtest.DefaultDatabase).Set(key[:], []byte("value")))
+
+	cache := server.Client(t, types.TCPTransportType).NewCache(client.WithCacheInvalidation(""))
+	t.Cleanup(func() { _ = cache.Close() })
+
+	for i := 0; i < 3; i++ {
+		value, err := cache.Get(ctx, key)
+		require.NoError(t, err)
+		assert.Equal(t, []byte("value"), value)
+		time.Sleep(50 * time.Millisecond)
+	}
+	stats := cache.Stats()
+	assert.False(t, stats.Subscribed)
+	assert.Zero(t, stats.Hits)
+	assert.Zero(t, stats.Entries)
+}
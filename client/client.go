@@ -5,29 +5,52 @@ import (
 	"errors"
 	"fmt"
 	"sync"
+	"sync/atomic"
 )
 
 // Client manages multiple transports and handlers using the config
 type Client struct {
-	transports map[string]Transport
-	ctx        context.Context
-	mu         sync.RWMutex
+	cfg         *Config
+	transports  map[string]Transport
+	correlators map[string]*correlator
+	ctx         context.Context
+	mu          sync.RWMutex
+
+	// endpoints and groups route the requests of the groups, created by Start.
+	endpoints  map[string]*endpoint
+	groups     map[string]*endpointGroup
+	stopChecks context.CancelFunc
+	checks     sync.WaitGroup
+
+	// retries and exhausted count the retries of the typed API.
+	retries   atomic.Uint64
+	exhausted atomic.Uint64
 }
 
 // NewClient creates a new Client using the provided config
 func NewClient(ctx context.Context, cfg *Config) *Client {
-	return &Client{
-		ctx:        ctx,
-		transports: cfg.Transports,
+	c := &Client{
+		cfg:         cfg,
+		ctx:         ctx,
+		transports:  cfg.Transports,
+		correlators: make(map[string]*correlator, len(cfg.Transports)),
 	}
+	for name, transport := range cfg.Transports {
+		c.correlators[name] = newCorrelator(transport, newBreaker(name, cfg.CircuitBreaker))
+	}
+	return c
 }
 
 // RegisterTransport adds a transport to the config
 func (c *Client) RegisterTransport(name string, transport Transport) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
 	if _, exists := c.transports[name]; exists {
 		return fmt.Errorf("transport %s already registered", name)
 	}
 	c.transports[name] = transport
+	c.correlators[name] = newCorrelator(transport, newBreaker(name, c.cfg.CircuitBreaker))
 	return nil
 }
 
@@ -51,21 +74,39 @@ func (c *Client) SendMessage(name string, data []byte) error {
 	return transport.Send(data)
 }
 
-// Start starts all transports in the client
+// Start starts all transports in the client. Transports of the endpoint groups failing to
+// connect are marked unhealthy instead, as long as their group has another endpoint.
 func (c *Client) Start(ctx context.Context) error {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-	for _, transport := range c.transports {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	grouped := make(map[string]bool)
+	for _, group := range c.cfg.Groups {
+		for _, name := range group.Endpoints {
+			grouped[name] = true
+		}
+	}
+
+	connectErrs := make(map[string]error)
+	for name, transport := range c.transports {
 		err := transport.Connect(ctx)
 		if err != nil {
-			return err
+			if !grouped[name] {
+				return err
+			}
+			connectErrs[name] = err
 		}
 	}
-	return nil
+	return c.startGroups(ctx, connectErrs)
 }
 
 // Close shuts down all transports in the client
 func (c *Client) Close() error {
+	if c.stopChecks != nil {
+		c.stopChecks()
+		c.checks.Wait()
+	}
+
 	c.mu.RLock()
 	defer c.mu.RUnlock()
 	for _, transport := range c.transports {
@@ -9,6 +9,7 @@ import (
 
 	"github.com/panjf2000/gnet/v2"
 	"github.com/unpackdev/fdb/client"
+	"github.com/unpackdev/fdb/fdbtest"
 	"github.com/unpackdev/fdb/messages"
 	"github.com/unpackdev/fdb/types"
 	"go.uber.org/zap"
@@ -17,6 +18,7 @@ import (
 func TestTCPClientSendMessage(t *testing.T) {
 	ctx := context.Background()
 	logger, _ := zap.NewDevelopment()
+	server := fdbtest.Start(t, fdbtest.WithTransports(types.TCPTransportType))
 
 	// Create configuration
 	cfg := client.NewConfig()
@@ -25,7 +27,7 @@ func TestTCPClientSendMessage(t *testing.T) {
 	c := client.NewClient(ctx, cfg)
 
 	// Create a new TCP transport with gnet options
-	tcpTransport := client.NewTCPTransport("127.0.0.1:5011", logger,
+	tcpTransport := client.NewTCPTransport(server.Addr(types.TCPTransportType), logger,
 		gnet.WithMulticore(true),
 		gnet.WithTCPNoDelay(gnet.TCPNoDelay),
 	)
@@ -0,0 +1,133 @@
+package client_test
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/unpackdev/fdb/client"
+	fdbErrors "github.com/unpackdev/fdb/errors"
+	"github.com/unpackdev/fdb/fdbtest"
+	"github.com/unpackdev/fdb/types"
+)
+
+func testKey(i int) [32]byte {
+	var key [32]byte
+	copy(key[:], fmt.Sprintf("key-%04d", i))
+	return key
+}
+
+func TestClientTypedAPI(t *testing.T) {
+	server := fdbtest.Start(t, fdbtest.WithTransports(types.TCPTransportType))
+	c := server.Client(t, types.TCPTransportType)
+	ctx := context.Background()
+
+	for i := 0; i < 10; i++ {
+		require.NoError(t, c.Set(ctx, testKey(i), []byte(fmt.Sprintf("value-%d", i))))
+	}
+
+	// Writes are acknowledged once buffered and visible once flushed
+	require.Eventually(t, func() bool {
+		exists, err := c.Exists(ctx, testKey(9))
+		return err == nil && exists
+	}, 5*time.Second, 20*time.Millisecond)
+
+	value, err := c.Get(ctx, testKey(3))
+	require.NoError(t, err)
+	assert.Equal(t, []byte("value-3"), value)
+
+	values, err := c.MGet(ctx, testKey(1), testKey(100), testKey(2))
+	require.NoError(t, err)
+	assert.Equal(t, [][]byte{[]byte("value-1"), nil, []byte("value-2")}, values)
+
+	require.NoError(t, c.Delete(ctx, testKey(3)))
+	require.Eventually(t, func() bool {
+		exists, err := c.Exists(ctx, testKey(3))
+		return err == nil && !exists
+	}, 5*time.Second, 20*time.Millisecond)
+
+	assert.Error(t, c.Set(ctx, testKey(4), nil))
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = c.Get(cancelled, testKey(1))
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestClientCorrelatesConcurrentRequests(t *testing.T) {
+	server := fdbtest.Start(t, fdbtest.WithTransports(types.TCPTransportType))
+	provider := server.Provider(t, fdbtest.DefaultDatabase)
+	c := server.Client(t, types.TCPTransportType)
+
+	const keys = 200
+	for i := 0; i < keys; i++ {
+		key := testKey(i)
+		require.NoError(t, provider.Set(key[:], []byte(fmt.Sprintf("value-%d", i))))
+	}
+
+	// Every response reaches the goroutine that sent the request, whatever the order they arrive in
+	var wg sync.WaitGroup
+	for i := 0; i < keys; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			value, err := c.Get(context.Background(), testKey(i))
+			if assert.NoError(t, err) {
+				assert.Equal(t, fmt.Sprintf("value-%d", i), string(value))
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestClientReadsValuesShapedLikeErrors(t *testing.T) {
+	server := fdbtest.Start(t)
+	provider := server.Provider(t, fdbtest.DefaultDatabase)
+	ctx := context.Background()
+
+	// Values matching a status byte or the text errors of untagged reads are read back as values
+	values := [][]byte{
+		{byte(types.StatusError)},
+		{byte(types.StatusDatabaseNotFound)},
+		{byte(types.StatusNotFound)},
+		[]byte("Error reading from database"),
+		[]byte("No value found for key"),
+	}
+	for i, value := range values {
+		key := testKey(i)
+		require.NoError(t, provider.Set(key[:], value))
+	}
+
+	clients := make(map[types.TransportType]*client.Client, len(fdbtest.DefaultTransports))
+	for _, transport := range fdbtest.DefaultTransports {
+		clients[transport] = server.Client(t, transport)
+	}
+
+	for transport, c := range clients {
+		t.Run(transport.String(), func(t *testing.T) {
+			for i, expected := range values {
+				value, err := c.Get(ctx, testKey(i))
+				require.NoError(t, err)
+				assert.Equal(t, expected, value)
+			}
+
+			_, err := c.Get(ctx, testKey(len(values)))
+			assert.ErrorIs(t, err, fdbErrors.ErrNotFound)
+		})
+	}
+
+	// Failed reads are errors rather than missing keys
+	require.NoError(t, provider.Close())
+	for transport, c := range clients {
+		t.Run(transport.String()+" closed", func(t *testing.T) {
+			exists, err := c.Exists(ctx, testKey(0))
+			assert.Error(t, err)
+			assert.False(t, exists)
+		})
+	}
+}
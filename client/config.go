@@ -1,13 +1,168 @@
 package client
 
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultRequestTimeout bounds the requests of the typed API whose context has no deadline.
+const DefaultRequestTimeout = 5 * time.Second
+
 // Config holds the configuration for the Client, including transports
 type Config struct {
 	Transports map[string]Transport
+
+	// Default names the transport serving Get, Set, Delete, Exists and MGet. When empty, the
+	// only registered transport is used.
+	Default string
+
+	// Database selects the database of the typed API, empty for the server's default database.
+	Database string
+
+	// RequestTimeout bounds the requests of the typed API whose context has no deadline,
+	// DefaultRequestTimeout when zero.
+	RequestTimeout time.Duration
+
+	// Groups lists the endpoint groups by name, see Group.
+	Groups map[string]Group
+
+	// ReadGroup names the group serving Get, Exists and MGet, and WriteGroup the group serving
+	// Set and Delete. Requests without a group are sent through the Default transport.
+	ReadGroup  string
+	WriteGroup string
+
+	// HealthCheckInterval is how often the endpoints of the groups are pinged,
+	// DefaultHealthCheckInterval when zero.
+	HealthCheckInterval time.Duration
+
+	// Retry configures the retries of the typed API, see RetryPolicy. The zero policy sends
+	// every request once.
+	Retry RetryPolicy
+
+	// CircuitBreaker configures the circuit breakers guarding the transports, see
+	// CircuitBreaker. Nil disables them.
+	CircuitBreaker *CircuitBreaker
 }
 
 // NewConfig creates and initializes a Config instance
 func NewConfig() *Config {
 	return &Config{
 		Transports: make(map[string]Transport),
+		Groups:     make(map[string]Group),
+	}
+}
+
+// FileConfig is the configuration file of a client, its transports given as connection
+// strings, see DSN:
+//
+//	default: local
+//	database: fdb
+//	transports:
+//	  local: fdb+uds:///tmp/fdb.sock
+//	  replica: fdb+quic://10.0.0.3:4433?ca=/etc/fdb/ca.pem
+//	groups:
+//	  replicas:
+//	    endpoints: [local, replica]
+//	    balancing: leastOutstanding
+//	readGroup: replicas
+type FileConfig struct {
+	Default             string            `yaml:"default" json:"default"`
+	Database            string            `yaml:"database" json:"database"`
+	RequestTimeout      time.Duration     `yaml:"requestTimeout" json:"requestTimeout"`
+	Transports          map[string]string `yaml:"transports" json:"transports"`
+	Groups              map[string]Group  `yaml:"groups" json:"groups"`
+	ReadGroup           string            `yaml:"readGroup" json:"readGroup"`
+	WriteGroup          string            `yaml:"writeGroup" json:"writeGroup"`
+	HealthCheckInterval time.Duration     `yaml:"healthCheckInterval" json:"healthCheckInterval"`
+	Retry               RetryPolicy       `yaml:"retry" json:"retry"`
+	CircuitBreaker      *CircuitBreaker   `yaml:"circuitBreaker" json:"circuitBreaker"`
+}
+
+// LoadConfig reads a client configuration file, see FileConfig, and creates its transports
+//
+// Example usage:
+//
+//	cfg, err := client.LoadConfig("/etc/fdb/client.yaml", zap.L())
+//	if err != nil {
+//	    log.Fatalf("Failed to load client config: %v", err)
+//	}
+//	c := client.NewClient(ctx, cfg)
+//
+// Parameters:
+//
+//	path (string): The YAML file.
+//	logger (*zap.Logger): Logs the failures of the transports.
+//
+// Returns:
+//
+//	*Config: The configuration, its transports not connected yet.
+//	error: Returns an error if the file cannot be read or a connection string is invalid.
+func LoadConfig(path string, logger *zap.Logger) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read client config: %w", err)
+	}
+
+	var file FileConfig
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse client config: %w", err)
+	}
+	return file.Config(logger)
+}
+
+// Config creates the transports of the file. The db and timeout parameters of the default
+// transport apply when the file sets no database or request timeout.
+func (f FileConfig) Config(logger *zap.Logger) (*Config, error) {
+	cfg := NewConfig()
+	cfg.Default = f.Default
+	cfg.Database = f.Database
+	cfg.RequestTimeout = f.RequestTimeout
+	cfg.ReadGroup = f.ReadGroup
+	cfg.WriteGroup = f.WriteGroup
+	cfg.HealthCheckInterval = f.HealthCheckInterval
+	cfg.Retry = f.Retry
+	cfg.CircuitBreaker = f.CircuitBreaker
+	for name, group := range f.Groups {
+		cfg.Groups[name] = group
+	}
+
+	for name, raw := range f.Transports {
+		dsn, err := ParseDSN(raw)
+		if err != nil {
+			return nil, fmt.Errorf("transport %s: %w", name, err)
+		}
+		transport, err := dsn.NewTransport(logger)
+		if err != nil {
+			return nil, fmt.Errorf("transport %s: %w", name, err)
+		}
+		cfg.Transports[name] = transport
+
+		if name == f.Default || len(f.Transports) == 1 {
+			if cfg.Database == "" {
+				cfg.Database = dsn.Database
+			}
+			if cfg.RequestTimeout == 0 {
+				cfg.RequestTimeout = dsn.RequestTimeout
+			}
+		}
+	}
+	return cfg, nil
+}
+
+// requestContext applies the request timeout to contexts without a deadline.
+func (cfg *Config) requestContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if _, ok := ctx.Deadline(); ok {
+		return ctx, func() {}
+	}
+
+	timeout := cfg.RequestTimeout
+	if timeout <= 0 {
+		timeout = DefaultRequestTimeout
 	}
+	return context.WithTimeout(ctx, timeout)
 }
@@ -0,0 +1,430 @@
+package client
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/unpackdev/fdb/config"
+	"go.uber.org/zap"
+)
+
+// Schemes of the connection strings accepted by ParseDSN.
+const (
+	// SchemeAuto selects the transport when connecting: the UDS socket when the server is
+	// local, then QUIC, then TCP.
+	SchemeAuto = "fdb"
+	SchemeTCP  = "fdb+tcp"
+	SchemeTLS  = "fdb+tls"
+	SchemeQUIC = "fdb+quic"
+	SchemeUDP  = "fdb+udp"
+	SchemeDTLS = "fdb+dtls"
+	SchemeUDS  = "fdb+uds"
+)
+
+// DSN describes a server and the transport reaching it, parsed from a connection string such as
+//
+//	fdb+tcp://10.0.0.2:5011?db=fdb&timeout=2s
+//	fdb+tls://10.0.0.2:5011?ca=/etc/fdb/ca.pem&min_version=1.3
+//	fdb+quic://10.0.0.2:4433?ca=/etc/fdb/ca.pem&server_name=fdb.internal&streams=4
+//	fdb+udp://10.0.0.2:5022?retransmit=50ms&retransmits=3
+//	fdb+dtls://10.0.0.2:5022?ca=/etc/fdb/ca.pem
+//	fdb+uds:///tmp/fdb.sock?datagram=true
+//	fdb://10.0.0.2:5011?socket=/tmp/fdb.sock&quic=10.0.0.2:4433
+//
+// The last form selects the transport when connecting, see SchemeAuto.
+type DSN struct {
+	// Scheme is the scheme of the connection string, one of the Scheme constants.
+	Scheme string
+
+	// Addr is the host:port of the server, empty for SchemeUDS.
+	Addr string
+
+	// Socket is the path of the UDS socket, set by SchemeUDS or the socket parameter.
+	Socket string
+
+	// QUICAddr is the QUIC address tried by SchemeAuto, set by the quic parameter.
+	QUICAddr string
+
+	// Database is the database of the typed API, set by the db parameter.
+	Database string
+
+	// RequestTimeout bounds the requests of the typed API, set by the timeout parameter.
+	RequestTimeout time.Duration
+
+	// CAFiles, ServerName and Insecure configure the verification of the server certificate,
+	// set by the ca, server_name and insecure parameters.
+	CAFiles    []string
+	ServerName string
+	Insecure   bool
+
+	// MinVersion and CipherSuites restrict the TLS versions and the TLS 1.2 cipher suites of
+	// SchemeTLS, set by the min_version parameter, "1.2" or "1.3", and the comma separated
+	// cipher_suites parameter.
+	MinVersion   uint16
+	CipherSuites []uint16
+
+	// Streams is the number of long-lived QUIC streams, set by the streams parameter.
+	Streams int
+
+	// Datagram talks to the datagram socket of the UDS transport, set by the datagram parameter.
+	Datagram bool
+
+	// Reconnect is how often a lost UDS connection is retried, set by the reconnect parameter.
+	Reconnect time.Duration
+
+	// Retransmit, MaxBackoff and Retransmits configure the UDP retransmissions, set by the
+	// retransmit, max_backoff and retransmits parameters.
+	Retransmit  time.Duration
+	MaxBackoff  time.Duration
+	Retransmits int
+
+	// PoolSize spreads the requests over that many connections, set by the pool parameter.
+	PoolSize int
+}
+
+// ParseDSN parses a connection string
+//
+// Example usage:
+//
+//	dsn, err := client.ParseDSN("fdb+quic://10.0.0.2:4433?db=fdb&insecure=true")
+//	if err != nil {
+//	    log.Fatalf("Invalid connection string: %v", err)
+//	}
+//
+// Parameters:
+//
+//	raw (string): The connection string, see DSN.
+//
+// Returns:
+//
+//	DSN: The parsed connection string.
+//	error: Returns an error for unknown schemes and parameters or invalid values.
+func ParseDSN(raw string) (DSN, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return DSN{}, fmt.Errorf("invalid connection string: %w", err)
+	}
+
+	dsn := DSN{Scheme: u.Scheme}
+	switch u.Scheme {
+	case SchemeUDS:
+		dsn.Socket = u.Path
+		if dsn.Socket == "" {
+			return DSN{}, fmt.Errorf("%s connection string requires a socket path", u.Scheme)
+		}
+	case SchemeAuto, SchemeTCP, SchemeTLS, SchemeQUIC, SchemeUDP, SchemeDTLS:
+		dsn.Addr = u.Host
+		if _, _, err := net.SplitHostPort(dsn.Addr); err != nil {
+			return DSN{}, fmt.Errorf("%s connection string requires host:port: %w", u.Scheme, err)
+		}
+	default:
+		return DSN{}, fmt.Errorf("unsupported connection string scheme: %q", u.Scheme)
+	}
+
+	for name, values := range u.Query() {
+		value := values[len(values)-1]
+		switch name {
+		case "db":
+			dsn.Database = value
+		case "timeout":
+			dsn.RequestTimeout, err = time.ParseDuration(value)
+		case "ca":
+			dsn.CAFiles = values
+		case "server_name":
+			dsn.ServerName = value
+		case "insecure":
+			dsn.Insecure, err = strconv.ParseBool(value)
+		case "min_version":
+			dsn.MinVersion, err = config.ParseTLSVersion(value)
+		case "cipher_suites":
+			dsn.CipherSuites, err = config.ParseCipherSuites(strings.Split(value, ","))
+		case "streams":
+			dsn.Streams, err = strconv.Atoi(value)
+		case "datagram":
+			dsn.Datagram, err = strconv.ParseBool(value)
+		case "reconnect":
+			dsn.Reconnect, err = time.ParseDuration(value)
+		case "retransmit":
+			dsn.Retransmit, err = time.ParseDuration(value)
+		case "max_backoff":
+			dsn.MaxBackoff, err = time.ParseDuration(value)
+		case "retransmits":
+			dsn.Retransmits, err = strconv.Atoi(value)
+		case "pool":
+			dsn.PoolSize, err = strconv.Atoi(value)
+		case "socket":
+			dsn.Socket = value
+		case "quic":
+			dsn.QUICAddr = value
+		default:
+			return DSN{}, fmt.Errorf("unknown connection string parameter: %q", name)
+		}
+		if err != nil {
+			return DSN{}, fmt.Errorf("invalid connection string parameter %s: %w", name, err)
+		}
+	}
+	return dsn, nil
+}
+
+// NewTransport creates the transport described by the connection string, not connected yet.
+func (dsn DSN) NewTransport(logger *zap.Logger) (Transport, error) {
+	if dsn.Scheme == SchemeAuto {
+		return dsn.newAutoTransport(logger)
+	}
+
+	factory, err := dsn.factory(dsn.Scheme, dsn.Addr, logger)
+	if err != nil {
+		return nil, err
+	}
+	if dsn.PoolSize > 1 {
+		return NewPool(factory, WithPoolSize(dsn.PoolSize)), nil
+	}
+	return factory(), nil
+}
+
+// factory returns a function creating transports of a scheme reaching addr, or the socket.
+func (dsn DSN) factory(scheme string, addr string, logger *zap.Logger) (func() Transport, error) {
+	switch scheme {
+	case SchemeTCP:
+		return func() Transport { return NewTCPTransport(addr, logger) }, nil
+	case SchemeTLS:
+		opts := []TLSOption{WithTLSMinVersion(dsn.MinVersion), WithTLSCipherSuites(dsn.CipherSuites...)}
+		if len(dsn.CAFiles) > 0 {
+			pool, err := LoadRootCAs(dsn.CAFiles...)
+			if err != nil {
+				return nil, err
+			}
+			opts = append(opts, WithTLSRootCAs(pool))
+		}
+		if dsn.ServerName != "" {
+			opts = append(opts, WithTLSServerName(dsn.ServerName))
+		}
+		if dsn.Insecure {
+			opts = append(opts, WithTLSInsecureSkipVerify())
+		}
+		return func() Transport { return NewTLSTransport(addr, logger, opts...) }, nil
+	case SchemeQUIC:
+		var opts []QUICOption
+		if len(dsn.CAFiles) > 0 {
+			pool, err := LoadRootCAs(dsn.CAFiles...)
+			if err != nil {
+				return nil, err
+			}
+			opts = append(opts, WithQUICRootCAs(pool))
+		}
+		if dsn.ServerName != "" {
+			opts = append(opts, WithQUICServerName(dsn.ServerName))
+		}
+		if dsn.Insecure {
+			opts = append(opts, WithQUICInsecureSkipVerify())
+		}
+		if dsn.Streams > 0 {
+			opts = append(opts, WithQUICStreamPool(dsn.Streams))
+		}
+		return func() Transport { return NewQUICTransport(addr, logger, opts...) }, nil
+	case SchemeUDP, SchemeDTLS:
+		opts := []UDPOption{WithUDPRetransmitTimeout(dsn.Retransmit), WithUDPMaxBackoff(dsn.MaxBackoff)}
+		if dsn.Retransmits > 0 {
+			opts = append(opts, WithUDPMaxRetransmits(dsn.Retransmits))
+		}
+		if scheme == SchemeDTLS {
+			tlsConfig := &tls.Config{ServerName: dsn.ServerName, InsecureSkipVerify: dsn.Insecure} // #nosec G402
+			if len(dsn.CAFiles) > 0 {
+				pool, err := LoadRootCAs(dsn.CAFiles...)
+				if err != nil {
+					return nil, err
+				}
+				tlsConfig.RootCAs = pool
+			}
+			opts = append(opts, WithUDPDTLS(tlsConfig))
+		}
+		return func() Transport { return NewUDPTransport(addr, logger, opts...) }, nil
+	case SchemeUDS:
+		opts := []UDSOption{WithUDSReconnectInterval(dsn.Reconnect)}
+		if dsn.Datagram {
+			opts = append(opts, WithUDSDatagram())
+		}
+		return func() Transport { return NewUDSTransport(dsn.Socket, logger, opts...) }, nil
+	default:
+		return nil, fmt.Errorf("unsupported connection string scheme: %q", scheme)
+	}
+}
+
+// newAutoTransport creates a fallbackTransport over the transports reaching the server, in the
+// order they are preferred.
+func (dsn DSN) newAutoTransport(logger *zap.Logger) (Transport, error) {
+	var schemes, addrs []string
+	if dsn.Socket != "" && isLocalAddr(dsn.Addr) {
+		schemes, addrs = append(schemes, SchemeUDS), append(addrs, "")
+	}
+	if dsn.QUICAddr != "" {
+		schemes, addrs = append(schemes, SchemeQUIC), append(addrs, dsn.QUICAddr)
+	}
+	schemes, addrs = append(schemes, SchemeTCP), append(addrs, dsn.Addr)
+
+	t := &fallbackTransport{logger: logger}
+	for i, scheme := range schemes {
+		factory, err := dsn.factory(scheme, addrs[i], logger)
+		if err != nil {
+			return nil, err
+		}
+		if dsn.PoolSize > 1 {
+			t.candidates = append(t.candidates, NewPool(factory, WithPoolSize(dsn.PoolSize)))
+		} else {
+			t.candidates = append(t.candidates, factory())
+		}
+	}
+	return t, nil
+}
+
+// isLocalAddr reports whether the host of addr is an address of this host.
+func isLocalAddr(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if strings.EqualFold(host, "localhost") {
+		return true
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+	if ip.IsLoopback() {
+		return true
+	}
+	local, err := net.InterfaceAddrs()
+	if err != nil {
+		return false
+	}
+	for _, a := range local {
+		if network, ok := a.(*net.IPNet); ok && network.IP.Equal(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// Open creates a client for the server described by a connection string and connects it
+//
+// Example usage:
+//
+//	c, err := client.Open(ctx, "fdb+uds:///tmp/fdb.sock?db=fdb", zap.L())
+//	if err != nil {
+//	    log.Fatalf("Failed to connect: %v", err)
+//	}
+//	defer c.Close()
+//
+// Parameters:
+//
+//	ctx (context.Context): The lifetime of the client's transport.
+//	raw (string): The connection string, see DSN.
+//	logger (*zap.Logger): Logs the failures of the transport.
+//
+// Returns:
+//
+//	*Client: A connected client whose typed API goes through the transport.
+//	error: Returns an error if the connection string is invalid or the server is unreachable.
+func Open(ctx context.Context, raw string, logger *zap.Logger) (*Client, error) {
+	dsn, err := ParseDSN(raw)
+	if err != nil {
+		return nil, err
+	}
+	transport, err := dsn.NewTransport(logger)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := NewConfig()
+	cfg.Transports[dsn.Scheme] = transport
+	cfg.Default = dsn.Scheme
+	cfg.Database = dsn.Database
+	cfg.RequestTimeout = dsn.RequestTimeout
+
+	c := NewClient(ctx, cfg)
+	if err := c.Start(ctx); err != nil {
+		_ = transport.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// fallbackTransport implements the Transport interface over the first of several transports
+// that connects, the others being closed.
+type fallbackTransport struct {
+	candidates []Transport
+	active     Transport
+	logger     *zap.Logger
+}
+
+// Connect connects the candidates in order until one of them succeeds
+func (t *fallbackTransport) Connect(ctx context.Context) error {
+	var errs []error
+	for _, candidate := range t.candidates {
+		err := candidate.Connect(ctx)
+		if err == nil {
+			t.active = candidate
+			return nil
+		}
+		_ = candidate.Close()
+		t.logger.Debug("Falling back to the next transport", zap.Error(err))
+		errs = append(errs, err)
+	}
+	return fmt.Errorf("no transport connected: %w", errors.Join(errs...))
+}
+
+// Send sends a message over the connected transport
+func (t *fallbackTransport) Send(data []byte) error {
+	if t.active == nil {
+		return fmt.Errorf("transport is not connected")
+	}
+	return t.active.Send(data)
+}
+
+// Close closes the connected transport
+func (t *fallbackTransport) Close() error {
+	if t.active == nil {
+		return nil
+	}
+	return t.active.Close()
+}
+
+// RegisterHandler registers a handler for a specific message type on every candidate
+func (t *fallbackTransport) RegisterHandler(messageType MessageType, handler HandlerFunc) {
+	for _, candidate := range t.candidates {
+		candidate.RegisterHandler(messageType, handler)
+	}
+}
+
+// OnResponse sets the function receiving the responses to tagged requests on every candidate
+func (t *fallbackTransport) OnResponse(fn ResponseFunc) {
+	for _, candidate := range t.candidates {
+		candidate.OnResponse(fn)
+	}
+}
+
+// OnRequestFailure sets the function failing the tagged requests given up on, on every candidate
+// able to give up on a request. It implements Failer.
+func (t *fallbackTransport) OnRequestFailure(fn RequestFailureFunc) {
+	for _, candidate := range t.candidates {
+		if failer, ok := candidate.(Failer); ok {
+			failer.OnRequestFailure(fn)
+		}
+	}
+}
+
+// Cancel forwards the cancellation of a request to the connected transport. It implements
+// Canceler.
+func (t *fallbackTransport) Cancel(id uint32) {
+	if canceler, ok := t.active.(Canceler); ok {
+		canceler.Cancel(id)
+	}
+}
@@ -0,0 +1,116 @@
+package client_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/unpackdev/fdb/client"
+	"github.com/unpackdev/fdb/fdbtest"
+	"github.com/unpackdev/fdb/types"
+	"go.uber.org/zap"
+)
+
+func TestParseDSN(t *testing.T) {
+	dsn, err := client.ParseDSN("fdb+quic://10.0.0.2:4433?db=fdb&timeout=2s&ca=/a.pem&ca=/b.pem&server_name=fdb.internal&insecure=true&streams=4&pool=2")
+	require.NoError(t, err)
+	assert.Equal(t, client.DSN{
+		Scheme:         client.SchemeQUIC,
+		Addr:           "10.0.0.2:4433",
+		Database:       "fdb",
+		RequestTimeout: 2 * time.Second,
+		CAFiles:        []string{"/a.pem", "/b.pem"},
+		ServerName:     "fdb.internal",
+		Insecure:       true,
+		Streams:        4,
+		PoolSize:       2,
+	}, dsn)
+
+	dsn, err = client.ParseDSN("fdb+uds:///tmp/fdb.sock?datagram=true&reconnect=50ms")
+	require.NoError(t, err)
+	assert.Equal(t, client.DSN{Scheme: client.SchemeUDS, Socket: "/tmp/fdb.sock", Datagram: true, Reconnect: 50 * time.Millisecond}, dsn)
+
+	dsn, err = client.ParseDSN("fdb+udp://10.0.0.2:5022?retransmit=50ms&max_backoff=1s&retransmits=3")
+	require.NoError(t, err)
+	assert.Equal(t, client.DSN{Scheme: client.SchemeUDP, Addr: "10.0.0.2:5022", Retransmit: 50 * time.Millisecond, MaxBackoff: time.Second, Retransmits: 3}, dsn)
+
+	dsn, err = client.ParseDSN("fdb://10.0.0.2:5011?socket=/tmp/fdb.sock&quic=10.0.0.2:4433")
+	require.NoError(t, err)
+	assert.Equal(t, client.DSN{Scheme: client.SchemeAuto, Addr: "10.0.0.2:5011", Socket: "/tmp/fdb.sock", QUICAddr: "10.0.0.2:4433"}, dsn)
+
+	for _, invalid := range []string{
+		"postgres://10.0.0.2:5432",
+		"fdb+tcp://10.0.0.2",
+		"fdb+uds://",
+		"fdb+tcp://10.0.0.2:5011?unknown=1",
+		"fdb+tcp://10.0.0.2:5011?timeout=soon",
+	} {
+		_, err := client.ParseDSN(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestOpenPrefersLocalSocket(t *testing.T) {
+	tcpServer := fdbtest.Start(t, fdbtest.WithTransports(types.TCPTransportType))
+	udsServer := fdbtest.Start(t, fdbtest.WithTransports(types.UDSTransportType))
+	tcpProvider, tcpAddr := tcpServer.Provider(t, fdbtest.DefaultDatabase), tcpServer.Addr(types.TCPTransportType)
+	udsProvider, socket := udsServer.Provider(t, fdbtest.DefaultDatabase), udsServer.Addr(types.UDSTransportType)
+
+	// The nodes hold different values, telling which transport answered
+	key := testKey(1)
+	require.NoError(t, tcpProvider.Set(key[:], []byte("over-tcp")))
+	require.NoError(t, udsProvider.Set(key[:], []byte("over-uds")))
+	ctx := context.Background()
+
+	for dsn, expected := range map[string]string{
+		fmt.Sprintf("fdb://%s?db=fdb&socket=%s", tcpAddr, socket):            "over-uds",
+		fmt.Sprintf("fdb://%s?db=fdb&socket=%s", tcpAddr, socket+".missing"): "over-tcp",
+		fmt.Sprintf("fdb+tcp://%s?db=fdb&timeout=2s&pool=2", tcpAddr):        "over-tcp",
+		fmt.Sprintf("fdb+uds://%s?db=fdb", socket):                           "over-uds",
+	} {
+		c, err := client.Open(ctx, dsn, zap.NewNop())
+		require.NoError(t, err, dsn)
+		value, err := c.Get(ctx, key)
+		require.NoError(t, err, dsn)
+		assert.Equal(t, expected, string(value), dsn)
+		require.NoError(t, c.Close())
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	server := fdbtest.Start(t, fdbtest.WithTransports(types.TCPTransportType))
+	provider, addr := server.Provider(t, fdbtest.DefaultDatabase), server.Addr(types.TCPTransportType)
+	key := testKey(1)
+	require.NoError(t, provider.Set(key[:], []byte("value-1")))
+
+	path := filepath.Join(t.TempDir(), "client.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(fmt.Sprintf(`
+default: primary
+transports:
+  primary: fdb+tcp://%[1]s?db=fdb&timeout=2s
+  secondary: fdb+tcp://%[1]s
+groups:
+  replicas:
+    endpoints: [primary, secondary]
+    balancing: leastOutstanding
+readGroup: replicas
+`, addr)), 0o600))
+
+	cfg, err := client.LoadConfig(path, zap.NewNop())
+	require.NoError(t, err)
+	assert.Equal(t, "fdb", cfg.Database)
+	assert.Equal(t, 2*time.Second, cfg.RequestTimeout)
+	assert.Equal(t, client.LeastOutstanding, cfg.Groups["replicas"].Balancing)
+
+	c := client.NewClient(context.Background(), cfg)
+	require.NoError(t, c.Start(context.Background()))
+	t.Cleanup(func() { _ = c.Close() })
+	value, err := c.Get(context.Background(), key)
+	require.NoError(t, err)
+	assert.Equal(t, "value-1", string(value))
+}
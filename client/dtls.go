@@ -0,0 +1,63 @@
+package client
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"time"
+
+	"github.com/pion/dtls/v3"
+)
+
+// dtlsHandshakeTimeout bounds establishing a DTLS session.
+const dtlsHandshakeTimeout = 5 * time.Second
+
+// WithUDPDTLS sends the datagrams of a UDPTransport over DTLS 1.2, to a server whose UDP
+// transport enables its DTLS block. The RootCAs, ServerName, InsecureSkipVerify and Certificates
+// of config verify the server and authenticate the client; the other fields are ignored.
+//
+// Example usage:
+//
+//	pool, err := client.LoadRootCAs("/etc/fdb/ca.pem")
+//	if err != nil {
+//	    log.Fatalf("Failed to load CAs: %v", err)
+//	}
+//	transport := client.NewUDPTransport("10.0.0.2:5022", zap.L(), client.WithUDPDTLS(&tls.Config{RootCAs: pool}))
+//
+// Parameters:
+//
+//	config (*tls.Config): The certificates verifying the server and authenticating the client.
+//
+// Returns:
+//
+//	UDPOption: The option enabling DTLS, ignored when config is nil.
+func WithUDPDTLS(config *tls.Config) UDPOption {
+	return func(t *UDPTransport) {
+		if config == nil {
+			return
+		}
+		t.dtlsConfig = &dtls.Config{
+			Certificates:         config.Certificates,
+			RootCAs:              config.RootCAs,
+			ServerName:           config.ServerName,
+			InsecureSkipVerify:   config.InsecureSkipVerify, // #nosec G402
+			ExtendedMasterSecret: dtls.RequireExtendedMasterSecret,
+		}
+	}
+}
+
+// dialDTLS establishes a DTLS session with the server at addr.
+func dialDTLS(ctx context.Context, addr *net.UDPAddr, config *dtls.Config) (net.Conn, error) {
+	conn, err := dtls.Dial("udp", addr, config)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, dtlsHandshakeTimeout)
+	defer cancel()
+	if err := conn.HandshakeContext(ctx); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
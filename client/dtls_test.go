@@ -0,0 +1,83 @@
+package client_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/unpackdev/fdb/client"
+	"github.com/unpackdev/fdb/config"
+	"github.com/unpackdev/fdb/fdbtest"
+	"github.com/unpackdev/fdb/types"
+	"go.uber.org/zap"
+)
+
+func TestUDPTransportDTLS(t *testing.T) {
+	server := fdbtest.Start(t, fdbtest.WithTransports(types.UDPTransportType), fdbtest.WithDTLS())
+	c := server.Client(t, types.UDPTransportType)
+	ctx := context.Background()
+
+	var first, second, missing [32]byte
+	copy(first[:], "first")
+	copy(second[:], "second")
+	copy(missing[:], "missing")
+
+	require.NoError(t, c.Set(ctx, first, []byte("one")))
+	require.NoError(t, c.Set(ctx, second, []byte("two")))
+
+	// Writes are acknowledged once buffered and visible once flushed
+	require.Eventually(t, func() bool {
+		values, err := c.MGet(ctx, first, missing, second)
+		return err == nil && assert.ObjectsAreEqual([][]byte{[]byte("one"), nil, []byte("two")}, values)
+	}, 5*time.Second, 20*time.Millisecond)
+}
+
+func TestUDPTransportDTLSVerifiesServer(t *testing.T) {
+	server := fdbtest.Start(t, fdbtest.WithTransports(types.UDPTransportType), fdbtest.WithDTLS())
+
+	// The certificate of the instance is signed by its own CA, unknown to the system roots
+	dsn := fmt.Sprintf("%s://%s?db=%s", client.SchemeDTLS, server.Addr(types.UDPTransportType), fdbtest.DefaultDatabase)
+	_, err := client.Open(context.Background(), dsn, zap.NewNop())
+	assert.Error(t, err)
+
+	// Plaintext datagrams are not answered
+	plain := client.NewUDPTransport(server.Addr(types.UDPTransportType), zap.NewNop(), client.WithUDPMaxRetransmits(0))
+	cfg := client.NewConfig()
+	cfg.Transports["udp"] = plain
+	cfg.Default = "udp"
+	cfg.RequestTimeout = 200 * time.Millisecond
+	plainClient := client.NewClient(context.Background(), cfg)
+	require.NoError(t, plainClient.Start(context.Background()))
+	t.Cleanup(func() { _ = plainClient.Close() })
+
+	var key [32]byte
+	_, err = plainClient.Exists(context.Background(), key)
+	assert.Error(t, err)
+}
+
+func TestUDPTransportDTLSSessionTimeout(t *testing.T) {
+	server := fdbtest.Start(t,
+		fdbtest.WithTransports(types.UDPTransportType),
+		fdbtest.WithDTLS(),
+		fdbtest.WithConfig(func(cfg *config.Config) {
+			udp := cfg.GetTransportByType(types.UDPTransportType).Config.(*config.UdpTransport)
+			udp.DTLS.SessionTimeout = 100 * time.Millisecond
+		}),
+	)
+	c := server.Client(t, types.UDPTransportType)
+	ctx := context.Background()
+
+	var key [32]byte
+	copy(key[:], "key")
+	require.NoError(t, c.Set(ctx, key, []byte("value")))
+
+	// The server closes the idle session, the next request establishes a new one
+	time.Sleep(300 * time.Millisecond)
+	require.Eventually(t, func() bool {
+		value, err := c.Get(ctx, key)
+		return err == nil && string(value) == "value"
+	}, 5*time.Second, 20*time.Millisecond)
+}
@@ -0,0 +1,359 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/unpackdev/fdb/messages"
+	"github.com/unpackdev/fdb/types"
+)
+
+const (
+	// DefaultHealthCheckInterval is how often the endpoints of the groups are pinged.
+	DefaultHealthCheckInterval = time.Second
+
+	// DefaultGroupMaxRetries is how many other endpoints a request is sent to after a failure.
+	DefaultGroupMaxRetries = 2
+
+	// DefaultRetryBudget is the number of retries a group allows per request sent.
+	DefaultRetryBudget = 0.2
+
+	// retryBudgetBurst bounds the retries a group saves up while requests succeed.
+	retryBudgetBurst = 10
+)
+
+// Balancing selects the endpoint of a group serving a request.
+type Balancing int
+
+const (
+	// RoundRobin sends the requests to the healthy endpoints in turn.
+	RoundRobin Balancing = iota
+
+	// LeastOutstanding sends each request to the healthy endpoint with the fewest requests in
+	// flight.
+	LeastOutstanding
+)
+
+// UnmarshalText parses a balancing from its name, roundRobin or leastOutstanding.
+func (b *Balancing) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "", "roundRobin":
+		*b = RoundRobin
+	case "leastOutstanding":
+		*b = LeastOutstanding
+	default:
+		return fmt.Errorf("unknown balancing: %q", text)
+	}
+	return nil
+}
+
+// Group lists transports reaching nodes that serve the same data, e.g. the replicas of a
+// database. Requests go to a healthy endpoint picked by Balancing and, when the endpoint fails
+// to answer, to the next one. Failed endpoints are marked unhealthy until they answer a ping
+// again.
+type Group struct {
+	// Endpoints names the registered transports of the group.
+	Endpoints []string `yaml:"endpoints" json:"endpoints"`
+
+	// Balancing selects the endpoint of each request, RoundRobin by default.
+	Balancing Balancing `yaml:"balancing" json:"balancing"`
+
+	// MaxRetries is how many other endpoints a failed request is sent to,
+	// DefaultGroupMaxRetries when zero. Negative values disable retries.
+	MaxRetries int `yaml:"maxRetries" json:"maxRetries"`
+
+	// RetryBudget is the number of retries allowed per request sent, DefaultRetryBudget when
+	// zero, so an outage of every endpoint does not multiply the load. Negative values lift
+	// the bound.
+	RetryBudget float64 `yaml:"retryBudget" json:"retryBudget"`
+
+	// AttemptTimeout bounds each attempt, so a request fails over from an endpoint that stopped
+	// answering without losing its connection. Zero lets an attempt use the whole request
+	// timeout.
+	AttemptTimeout time.Duration `yaml:"attemptTimeout" json:"attemptTimeout"`
+}
+
+// endpoint is a transport serving groups.
+type endpoint struct {
+	name        string
+	corr        *correlator
+	healthy     atomic.Bool
+	outstanding atomic.Int64
+}
+
+// endpointGroup routes requests among the endpoints of a Group.
+type endpointGroup struct {
+	name      string
+	cfg       Group
+	endpoints []*endpoint
+	next      atomic.Uint32
+
+	// mu guards tokens.
+	mu     sync.Mutex
+	tokens float64
+}
+
+// newEndpointGroup creates the router of a group over its endpoints.
+func newEndpointGroup(name string, cfg Group, endpoints []*endpoint) *endpointGroup {
+	if cfg.MaxRetries == 0 {
+		cfg.MaxRetries = DefaultGroupMaxRetries
+	}
+	if cfg.RetryBudget == 0 {
+		cfg.RetryBudget = DefaultRetryBudget
+	}
+	return &endpointGroup{
+		name:      name,
+		cfg:       cfg,
+		endpoints: endpoints,
+		tokens:    retryBudgetBurst,
+	}
+}
+
+// roundTrip sends a request frame to an endpoint of the group, failing over to the other
+// endpoints while the retries and the retry budget allow.
+func (g *endpointGroup) roundTrip(ctx context.Context, frame []byte) ([]byte, error) {
+	g.deposit()
+
+	tried := make([]bool, len(g.endpoints))
+	var lastErr error
+	for attempt := 0; ; attempt++ {
+		i := g.pick(tried)
+		if i < 0 {
+			break
+		}
+		tried[i] = true
+
+		ep := g.endpoints[i]
+		resp, err := ep.roundTrip(ctx, frame, g.cfg.AttemptTimeout)
+		if err == nil {
+			return resp, nil
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
+		// The endpoint failed to answer, it is skipped until a ping succeeds
+		ep.healthy.Store(false)
+		lastErr = err
+		if attempt >= g.cfg.MaxRetries || !g.withdraw() {
+			break
+		}
+	}
+
+	if lastErr == nil {
+		return nil, fmt.Errorf("group %s has no endpoint", g.name)
+	}
+	return nil, fmt.Errorf("group %s: %w", g.name, lastErr)
+}
+
+// pick returns the index of the endpoint serving the next attempt, -1 when every endpoint was
+// tried. Unhealthy endpoints are only picked once no healthy endpoint is left.
+func (g *endpointGroup) pick(tried []bool) int {
+	start := int(g.next.Add(1))
+	for _, healthy := range []bool{true, false} {
+		best := -1
+		for n := range g.endpoints {
+			i := (start + n) % len(g.endpoints)
+			ep := g.endpoints[i]
+			// Endpoints whose circuit is open count as unhealthy
+			if tried[i] || (ep.healthy.Load() && !ep.corr.breaker.open()) != healthy {
+				continue
+			}
+			if g.cfg.Balancing == RoundRobin {
+				return i
+			}
+			if best < 0 || ep.outstanding.Load() < g.endpoints[best].outstanding.Load() {
+				best = i
+			}
+		}
+		if best >= 0 {
+			return best
+		}
+	}
+	return -1
+}
+
+// deposit credits the retry budget for a request.
+func (g *endpointGroup) deposit() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.tokens = min(g.tokens+g.cfg.RetryBudget, retryBudgetBurst)
+}
+
+// withdraw takes a retry from the budget, reporting false when it is exhausted.
+func (g *endpointGroup) withdraw() bool {
+	if g.cfg.RetryBudget < 0 {
+		return true
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.tokens < 1 {
+		return false
+	}
+	g.tokens--
+	return true
+}
+
+// roundTrip sends a request frame to the endpoint, bounded by timeout when positive.
+func (e *endpoint) roundTrip(ctx context.Context, frame []byte, timeout time.Duration) ([]byte, error) {
+	e.outstanding.Add(1)
+	defer e.outstanding.Add(-1)
+
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	return e.corr.roundTrip(ctx, frame)
+}
+
+// Ping checks that the node behind a transport answers requests, by reading a key no client
+// writes. Any answer, including a missing key, means the node is alive.
+//
+// Example usage:
+//
+//	if err := c.Ping(ctx, "tcp"); err != nil {
+//	    log.Printf("Node unreachable: %v", err)
+//	}
+//
+// Parameters:
+//
+//	ctx (context.Context): Bounds the ping, Config.RequestTimeout applies when it has no deadline.
+//	name (string): The name of the transport.
+//
+// Returns:
+//
+//	error: Returns errors.ErrDatabaseNotFound if the node does not serve the database, or a
+//	request error.
+func (c *Client) Ping(ctx context.Context, name string) error {
+	c.mu.RLock()
+	corr, ok := c.correlators[name]
+	c.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("transport %s not found", name)
+	}
+
+	ctx, cancel := c.cfg.requestContext(ctx)
+	defer cancel()
+	return c.ping(ctx, corr)
+}
+
+// Healthy reports whether the endpoint behind a transport answered its last request or ping.
+// Transports outside the groups are never checked and reported unhealthy.
+func (c *Client) Healthy(name string) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	ep, ok := c.endpoints[name]
+	return ok && ep.healthy.Load()
+}
+
+// ping sends a ping request through a correlator, answered without touching data.
+func (c *Client) ping(ctx context.Context, corr *correlator) error {
+	frame, err := messages.WithDatabase(c.cfg.Database, []byte{byte(types.PingHandlerType)})
+	if err != nil {
+		return err
+	}
+
+	resp, err := corr.roundTrip(ctx, frame)
+	if err != nil {
+		return err
+	}
+	return statusResponse(resp)
+}
+
+// startGroups creates the endpoint groups of the config, marking the endpoints whose transport
+// connected healthy, and starts checking their health. Starting fails when every endpoint of a
+// group failed to connect.
+func (c *Client) startGroups(ctx context.Context, connectErrs map[string]error) error {
+	c.endpoints = make(map[string]*endpoint)
+	c.groups = make(map[string]*endpointGroup, len(c.cfg.Groups))
+	for name, cfg := range c.cfg.Groups {
+		endpoints := make([]*endpoint, 0, len(cfg.Endpoints))
+		var connected bool
+		for _, transport := range cfg.Endpoints {
+			corr, ok := c.correlators[transport]
+			if !ok {
+				return fmt.Errorf("group %s: transport %s not found", name, transport)
+			}
+			ep, ok := c.endpoints[transport]
+			if !ok {
+				ep = &endpoint{name: transport, corr: corr}
+				ep.healthy.Store(connectErrs[transport] == nil)
+				c.endpoints[transport] = ep
+			}
+			connected = connected || connectErrs[transport] == nil
+			endpoints = append(endpoints, ep)
+		}
+		if len(endpoints) > 0 && !connected {
+			return fmt.Errorf("group %s: no endpoint connected: %w", name, connectErrs[cfg.Endpoints[0]])
+		}
+		c.groups[name] = newEndpointGroup(name, cfg, endpoints)
+	}
+
+	for _, name := range []string{c.cfg.ReadGroup, c.cfg.WriteGroup} {
+		if _, ok := c.groups[name]; name != "" && !ok {
+			return fmt.Errorf("group %s not found", name)
+		}
+	}
+
+	if len(c.endpoints) > 0 {
+		ctx, c.stopChecks = context.WithCancel(ctx)
+		c.checks.Add(1)
+		go c.checkHealth(ctx)
+	}
+	return nil
+}
+
+// checkHealth pings the endpoints of the groups every health check interval until ctx is done.
+func (c *Client) checkHealth(ctx context.Context) {
+	defer c.checks.Done()
+
+	interval := c.cfg.HealthCheckInterval
+	if interval <= 0 {
+		interval = DefaultHealthCheckInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		var wg sync.WaitGroup
+		for _, ep := range c.endpoints {
+			wg.Add(1)
+			go func(ep *endpoint) {
+				defer wg.Done()
+
+				pingCtx, cancel := context.WithTimeout(ctx, interval)
+				defer cancel()
+				ep.healthy.Store(c.ping(pingCtx, ep.corr) == nil)
+			}(ep)
+		}
+		wg.Wait()
+	}
+}
+
+// group returns the endpoint group serving requests of a handler, nil when they go through the
+// default transport.
+func (c *Client) group(handler types.HandlerType) *endpointGroup {
+	name := c.cfg.WriteGroup
+	if handler == types.ReadHandlerType {
+		name = c.cfg.ReadGroup
+	}
+	if name == "" {
+		return nil
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.groups[name]
+}
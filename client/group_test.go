@@ -0,0 +1,149 @@
+package client_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/unpackdev/fdb/client"
+	"github.com/unpackdev/fdb/config"
+	"github.com/unpackdev/fdb/db"
+	fdbErrors "github.com/unpackdev/fdb/errors"
+	"github.com/unpackdev/fdb/fdbtest"
+	"github.com/unpackdev/fdb/types"
+	"go.uber.org/zap"
+)
+
+func TestGroupsRouteReadsAndWrites(t *testing.T) {
+	leaderServer := fdbtest.Start(t, fdbtest.WithTransports(types.TCPTransportType))
+	replicaServer := fdbtest.Start(t, fdbtest.WithTransports(types.TCPTransportType))
+	leader, leaderAddr := leaderServer.Provider(t, fdbtest.DefaultDatabase), leaderServer.Addr(types.TCPTransportType)
+	replica, replicaAddr := replicaServer.Provider(t, fdbtest.DefaultDatabase), replicaServer.Addr(types.TCPTransportType)
+
+	cfg := client.NewConfig()
+	cfg.Database = "fdb"
+	cfg.Transports["leader"] = client.NewTCPTransport(leaderAddr, zap.NewNop())
+	cfg.Transports["replica"] = client.NewTCPTransport(replicaAddr, zap.NewNop())
+	cfg.Groups["writes"] = client.Group{Endpoints: []string{"leader"}}
+	cfg.Groups["reads"] = client.Group{Endpoints: []string{"replica"}, Balancing: client.LeastOutstanding}
+	cfg.WriteGroup, cfg.ReadGroup = "writes", "reads"
+	c := client.NewClient(context.Background(), cfg)
+	require.NoError(t, c.Start(context.Background()))
+	t.Cleanup(func() { _ = c.Close() })
+	ctx := context.Background()
+
+	key := testKey(1)
+	require.NoError(t, replica.Set(key[:], []byte("replicated")))
+	value, err := c.Get(ctx, key)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("replicated"), value)
+
+	// Writes reach the leader only, nothing replicates them in this test
+	require.NoError(t, c.Set(ctx, testKey(2), []byte("written")))
+	require.Eventually(t, func() bool {
+		key := testKey(2)
+		value, err := leader.Get(key[:])
+		return err == nil && string(value) == "written"
+	}, 5*time.Second, 20*time.Millisecond)
+	exists, err := c.Exists(ctx, testKey(2))
+	require.NoError(t, err)
+	assert.False(t, exists)
+
+	require.NoError(t, c.Ping(ctx, "leader"))
+	assert.True(t, c.Healthy("replica"))
+}
+
+// failingReader fails every read, as a database whose replicas are unreachable does.
+type failingReader struct{}
+
+func (failingReader) Read(context.Context, types.DbType, []byte) ([]byte, error) {
+	return nil, errors.New("replicas unreachable")
+}
+
+func TestPingChecksTheDatabase(t *testing.T) {
+	server := fdbtest.Start(t)
+	ctx := context.Background()
+
+	// Pings leave the data alone, a node stays healthy whatever reading it would answer
+	require.NoError(t, server.FDB.GetDbManager().SetReader(fdbtest.DefaultDatabase, failingReader{}))
+
+	for _, transport := range fdbtest.DefaultTransports {
+		t.Run(transport.String(), func(t *testing.T) {
+			dsn := server.DSN(transport)
+			scheme, _, _ := strings.Cut(dsn, "://")
+			require.NoError(t, server.Client(t, transport).Ping(ctx, scheme))
+
+			missing, err := client.Open(ctx, strings.Replace(dsn, "db="+fdbtest.DefaultDatabase, "db=missing", 1), zap.NewNop())
+			require.NoError(t, err)
+			t.Cleanup(func() { _ = missing.Close() })
+			assert.ErrorIs(t, missing.Ping(ctx, scheme), fdbErrors.ErrDatabaseNotFound)
+		})
+	}
+}
+
+func TestGroupsFailOver(t *testing.T) {
+	// The first node is restarted on the same port
+	firstPort := fdbtest.FreePort(t, "tcp")
+	onFirstPort := fdbtest.WithConfig(func(cfg *config.Config) {
+		cfg.GetTransportByType(types.TCPTransportType).Config.(*config.TcpTransport).Port = firstPort
+	})
+	firstServer := fdbtest.Start(t, fdbtest.WithTransports(types.TCPTransportType), onFirstPort)
+	secondServer := fdbtest.Start(t, fdbtest.WithTransports(types.TCPTransportType))
+	first, firstAddr := firstServer.Provider(t, fdbtest.DefaultDatabase), firstServer.Addr(types.TCPTransportType)
+	second, secondAddr := secondServer.Provider(t, fdbtest.DefaultDatabase), secondServer.Addr(types.TCPTransportType)
+
+	const keys = 20
+	fill := func(provider db.Provider) {
+		for i := 0; i < keys; i++ {
+			key := testKey(i)
+			require.NoError(t, provider.Set(key[:], []byte(fmt.Sprintf("value-%d", i))))
+		}
+	}
+	fill(first)
+	fill(second)
+
+	cfg := client.NewConfig()
+	cfg.Database = "fdb"
+	cfg.Transports["first"] = client.NewTCPTransport(firstAddr, zap.NewNop())
+	cfg.Transports["second"] = client.NewTCPTransport(secondAddr, zap.NewNop())
+	// A node that is down from the start is skipped
+	downAddr := fmt.Sprintf("127.0.0.1:%d", fdbtest.FreePort(t, "tcp"))
+	cfg.Transports["down"] = client.NewTCPTransport(downAddr, zap.NewNop())
+	cfg.Groups["replicas"] = client.Group{Endpoints: []string{"down", "first", "second"}}
+	cfg.ReadGroup = "replicas"
+	cfg.HealthCheckInterval = 50 * time.Millisecond
+	c := client.NewClient(context.Background(), cfg)
+	require.NoError(t, c.Start(context.Background()))
+	t.Cleanup(func() { _ = c.Close() })
+	assert.False(t, c.Healthy("down"))
+
+	read := func() {
+		for i := 0; i < keys; i++ {
+			value, err := c.Get(context.Background(), testKey(i))
+			require.NoError(t, err)
+			assert.Equal(t, fmt.Sprintf("value-%d", i), string(value))
+		}
+	}
+	read()
+
+	// Every read still succeeds once a node goes away
+	require.NoError(t, firstServer.Stop())
+	read()
+	require.Eventually(t, func() bool {
+		return !c.Healthy("first")
+	}, 5*time.Second, 20*time.Millisecond)
+	assert.True(t, c.Healthy("second"))
+
+	// The node comes back and passes its health check
+	restarted := fdbtest.Start(t, fdbtest.WithTransports(types.TCPTransportType), onFirstPort)
+	fill(restarted.Provider(t, fdbtest.DefaultDatabase))
+	require.Eventually(t, func() bool {
+		return c.Healthy("first")
+	}, 5*time.Second, 20*time.Millisecond)
+	read()
+}
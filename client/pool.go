@@ -0,0 +1,383 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/unpackdev/fdb/messages"
+)
+
+const (
+	// DefaultPoolSize is the number of connections of a Pool.
+	DefaultPoolSize = 4
+
+	// DefaultPoolMaxInFlight is the number of tagged requests a connection of a Pool carries at
+	// once.
+	DefaultPoolMaxInFlight = 256
+
+	// DefaultPoolRetryInterval is how long a failing connection of a Pool is skipped before
+	// requests are sent to it again.
+	DefaultPoolRetryInterval = time.Second
+
+	// poolMaxFailures is the number of requests a connection of a Pool fails in a row, without a
+	// response in between, before it is skipped.
+	poolMaxFailures = 3
+)
+
+// PoolOption configures a Pool
+type PoolOption func(*Pool)
+
+// WithPoolSize sets the number of connections, DefaultPoolSize when not set.
+func WithPoolSize(size int) PoolOption {
+	return func(p *Pool) {
+		if size > 0 {
+			p.size = size
+		}
+	}
+}
+
+// WithPoolMaxInFlight sets how many tagged requests each connection carries at once,
+// DefaultPoolMaxInFlight when not set. Further requests wait for a response, in the order they
+// were sent.
+func WithPoolMaxInFlight(limit int) PoolOption {
+	return func(p *Pool) {
+		if limit > 0 {
+			p.maxInFlight = limit
+		}
+	}
+}
+
+// WithPoolRetryInterval sets how long a failing connection is skipped before requests are sent to
+// it again, dialing it again when it was lost, DefaultPoolRetryInterval when not set.
+func WithPoolRetryInterval(interval time.Duration) PoolOption {
+	return func(p *Pool) {
+		if interval > 0 {
+			p.retryInterval = interval
+		}
+	}
+}
+
+// PoolStats describes the connections of a Pool.
+type PoolStats struct {
+	// InFlight is the number of tagged requests waiting for a response, per connection.
+	InFlight []int `json:"inFlight"`
+
+	// Sent is the number of tagged requests sent, per connection.
+	Sent []uint64 `json:"sent"`
+
+	// Healthy reports, per connection, whether requests are sent to it, see Pool.
+	Healthy []bool `json:"healthy"`
+}
+
+// Pool implements the Transport interface over several connections to the same endpoint, each
+// one a Transport of its own created by a factory. Tagged requests are pipelined: each request
+// goes to the connection with the fewest requests in flight, without waiting for the responses
+// to the previous ones, and the responses are matched to their requests by ID.
+//
+// The requests in flight are bounded, see WithPoolMaxInFlight. Once every connection carries as
+// many requests as allowed, Send blocks until a response arrives and senders proceed in the
+// order they arrived, so no goroutine is starved by the others.
+//
+// A connection failing several requests in a row, because they could not be sent, were given up
+// on or got no response in time, is skipped for a while, then tried again. Requests that could
+// not be sent are sent over another connection.
+//
+// Untagged data goes to the least busy connection as well, its responses reaching the handlers
+// registered on the pool.
+type Pool struct {
+	factory          func() Transport
+	size             int
+	maxInFlight      int
+	retryInterval    time.Duration
+	onResponse       ResponseFunc
+	onRequestFailure RequestFailureFunc
+
+	members []*poolMember
+	next    atomic.Uint32
+
+	// slots holds a token per tagged request in flight over the whole pool.
+	slots chan struct{}
+
+	// mu guards pending.
+	mu      sync.Mutex
+	pending map[uint32]*poolMember
+
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// poolMember is a connection of the pool.
+type poolMember struct {
+	transport Transport
+	inFlight  atomic.Int64
+	sent      atomic.Uint64
+
+	// failures counts the requests failed since the last response. Once it reaches
+	// poolMaxFailures the member is skipped until retryAt, in Unix nanoseconds.
+	failures      atomic.Int64
+	retryAt       atomic.Int64
+	retryInterval time.Duration
+}
+
+// healthy reports whether requests may be sent to the member at now, in Unix nanoseconds.
+func (m *poolMember) healthy(now int64) bool {
+	return m.retryAt.Load() <= now
+}
+
+// succeeded records a response received by the member.
+func (m *poolMember) succeeded() {
+	m.failures.Store(0)
+	m.retryAt.Store(0)
+}
+
+// failed records a request the member failed, skipping the member once it failed too many in a
+// row.
+func (m *poolMember) failed() {
+	if m.failures.Add(1) >= poolMaxFailures {
+		m.retryAt.Store(time.Now().Add(m.retryInterval).UnixNano())
+	}
+}
+
+// NewPool creates a Pool whose connections are created by factory
+//
+// Example usage:
+//
+//	pool := client.NewPool(func() client.Transport {
+//	    return client.NewTCPTransport("127.0.0.1:5011", zap.L())
+//	}, client.WithPoolSize(8))
+//	_ = c.RegisterTransport("tcp", pool)
+//
+// Parameters:
+//
+//	factory (func() Transport): Creates a connection, called WithPoolSize times.
+//	opts (...PoolOption): The size of the pool, the requests each connection carries and how long
+//	failing connections are skipped.
+//
+// Returns:
+//
+//	*Pool: A new Pool, connected by Connect.
+func NewPool(factory func() Transport, opts ...PoolOption) *Pool {
+	p := &Pool{
+		factory:       factory,
+		size:          DefaultPoolSize,
+		maxInFlight:   DefaultPoolMaxInFlight,
+		retryInterval: DefaultPoolRetryInterval,
+		pending:       make(map[uint32]*poolMember),
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+
+	p.slots = make(chan struct{}, p.size*p.maxInFlight)
+	p.members = make([]*poolMember, p.size)
+	for i := range p.members {
+		member := &poolMember{transport: factory(), retryInterval: p.retryInterval}
+		member.transport.OnResponse(p.deliver)
+		if failer, ok := member.transport.(Failer); ok {
+			failer.OnRequestFailure(p.fail)
+		}
+		p.members[i] = member
+	}
+	return p
+}
+
+// Connect connects every connection of the pool
+func (p *Pool) Connect(ctx context.Context) error {
+	p.ctx, p.cancel = context.WithCancel(ctx)
+
+	for _, member := range p.members {
+		if err := member.transport.Connect(ctx); err != nil {
+			_ = p.Close()
+			return err
+		}
+	}
+	return nil
+}
+
+// Send sends a message over the least busy healthy connection, waiting for a free slot first
+// when the message is a tagged request. Tagged requests that cannot be sent are sent over the
+// next connection.
+func (p *Pool) Send(data []byte) error {
+	if p.ctx == nil || p.ctx.Err() != nil {
+		return errors.New("pool is closed")
+	}
+	if len(data) < messages.TagHeaderLen || data[0] != messages.TagSelector {
+		member := p.pick()
+		err := member.transport.Send(data)
+		if err != nil {
+			member.failed()
+		}
+		return err
+	}
+
+	id, _, err := messages.DecodeTagHeader(data[:messages.TagHeaderLen])
+	if err != nil {
+		return err
+	}
+
+	// Waiting senders are served in the order they arrived
+	select {
+	case p.slots <- struct{}{}:
+	case <-p.ctx.Done():
+		return errors.New("pool is closed")
+	}
+
+	tried := make([]*poolMember, 0, 1)
+	for range p.members {
+		member := p.pick(tried...)
+		member.inFlight.Add(1)
+		member.sent.Add(1)
+		p.mu.Lock()
+		p.pending[id] = member
+		p.mu.Unlock()
+
+		if err = member.transport.Send(data); err == nil {
+			return nil
+		}
+		member.failed()
+		p.untrack(id)
+		tried = append(tried, member)
+	}
+	<-p.slots
+	return err
+}
+
+// Close closes every connection of the pool
+func (p *Pool) Close() error {
+	if p.cancel != nil {
+		p.cancel()
+	}
+
+	var errs []error
+	for _, member := range p.members {
+		if err := member.transport.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// RegisterHandler registers a handler for a specific message type on every connection
+func (p *Pool) RegisterHandler(messageType MessageType, handler HandlerFunc) {
+	for _, member := range p.members {
+		member.transport.RegisterHandler(messageType, handler)
+	}
+}
+
+// OnResponse sets the function receiving the responses to tagged requests
+func (p *Pool) OnResponse(fn ResponseFunc) {
+	p.onResponse = fn
+}
+
+// OnRequestFailure sets the function failing the tagged requests a connection gave up on. It
+// implements Failer.
+func (p *Pool) OnRequestFailure(fn RequestFailureFunc) {
+	p.onRequestFailure = fn
+}
+
+// Cancel frees the slot of a request nobody waits for anymore, counting it as failed by its
+// connection. It implements Canceler.
+func (p *Pool) Cancel(id uint32) {
+	if member := p.release(id); member != nil {
+		member.failed()
+		if canceler, ok := member.transport.(Canceler); ok {
+			canceler.Cancel(id)
+		}
+	}
+}
+
+// Stats returns the requests in flight and sent per connection.
+func (p *Pool) Stats() PoolStats {
+	stats := PoolStats{
+		InFlight: make([]int, len(p.members)),
+		Sent:     make([]uint64, len(p.members)),
+		Healthy:  make([]bool, len(p.members)),
+	}
+	now := time.Now().UnixNano()
+	for i, member := range p.members {
+		stats.InFlight[i] = int(member.inFlight.Load())
+		stats.Sent[i] = member.sent.Load()
+		stats.Healthy[i] = member.healthy(now)
+	}
+	return stats
+}
+
+// pick returns the healthy connection with the fewest requests in flight, starting the search at
+// the next connection in turn so ties are spread evenly. When every connection is failing, the
+// least busy one is tried anyway. Connections in skip are only picked when there is no other.
+func (p *Pool) pick(skip ...*poolMember) *poolMember {
+	start := int(p.next.Add(1))
+	now := time.Now().UnixNano()
+	var best, fallback *poolMember
+	for i := range p.members {
+		member := p.members[(start+i)%len(p.members)]
+		if slices.Contains(skip, member) {
+			continue
+		}
+		if fallback == nil || member.inFlight.Load() < fallback.inFlight.Load() {
+			fallback = member
+		}
+		if !member.healthy(now) {
+			continue
+		}
+		if best == nil || member.inFlight.Load() < best.inFlight.Load() {
+			best = member
+		}
+	}
+	if best == nil && fallback == nil {
+		return p.members[start%len(p.members)]
+	}
+	if best == nil {
+		return fallback
+	}
+	return best
+}
+
+// release stops tracking a request and frees its slot, returning its connection or nil when the
+// request was not tracked.
+func (p *Pool) release(id uint32) *poolMember {
+	member := p.untrack(id)
+	if member != nil {
+		<-p.slots
+	}
+	return member
+}
+
+// untrack stops tracking a request, keeping its slot, and returns its connection or nil when the
+// request was not tracked.
+func (p *Pool) untrack(id uint32) *poolMember {
+	p.mu.Lock()
+	member, ok := p.pending[id]
+	delete(p.pending, id)
+	p.mu.Unlock()
+
+	if !ok {
+		return nil
+	}
+	member.inFlight.Add(-1)
+	return member
+}
+
+// deliver receives the responses of every connection.
+func (p *Pool) deliver(id uint32, data []byte) {
+	if member := p.release(id); member != nil {
+		member.succeeded()
+	}
+	if p.onResponse != nil {
+		p.onResponse(id, data)
+	}
+}
+
+// fail receives the requests every connection gave up on.
+func (p *Pool) fail(id uint32, err error) {
+	if member := p.release(id); member != nil {
+		member.failed()
+	}
+	if p.onRequestFailure != nil {
+		p.onRequestFailure(id, err)
+	}
+}
@@ -0,0 +1,169 @@
+package client_test
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/unpackdev/fdb/client"
+	"github.com/unpackdev/fdb/fdbtest"
+	"github.com/unpackdev/fdb/types"
+	"go.uber.org/zap"
+)
+
+// newPoolClient connects a client to the server at addr over a pool of TCP connections.
+func newPoolClient(t *testing.T, addr string, opts ...client.PoolOption) (*client.Client, *client.Pool) {
+	pool := client.NewPool(func() client.Transport {
+		return client.NewTCPTransport(addr, zap.NewNop())
+	}, opts...)
+
+	cfg := client.NewConfig()
+	cfg.Database = "fdb"
+	c := client.NewClient(context.Background(), cfg)
+	require.NoError(t, c.RegisterTransport("tcp", pool))
+	require.NoError(t, c.Start(context.Background()))
+	t.Cleanup(func() { _ = c.Close() })
+	return c, pool
+}
+
+func TestPoolPipelinesAcrossConnections(t *testing.T) {
+	server := fdbtest.Start(t, fdbtest.WithTransports(types.TCPTransportType))
+	provider, addr := server.Provider(t, fdbtest.DefaultDatabase), server.Addr(types.TCPTransportType)
+
+	const keys = 500
+	for i := 0; i < keys; i++ {
+		key := testKey(i)
+		require.NoError(t, provider.Set(key[:], []byte(fmt.Sprintf("value-%d", i))))
+	}
+
+	for name, opts := range map[string][]client.PoolOption{
+		"default": {client.WithPoolSize(4)},
+		// A single request in flight per connection, the others wait for their turn
+		"bounded": {client.WithPoolSize(2), client.WithPoolMaxInFlight(1)},
+	} {
+		t.Run(name, func(t *testing.T) {
+			c, pool := newPoolClient(t, addr, opts...)
+
+			var wg sync.WaitGroup
+			for i := 0; i < keys; i++ {
+				wg.Add(1)
+				go func(i int) {
+					defer wg.Done()
+
+					value, err := c.Get(context.Background(), testKey(i))
+					if assert.NoError(t, err) {
+						assert.Equal(t, fmt.Sprintf("value-%d", i), string(value))
+					}
+				}(i)
+			}
+			wg.Wait()
+
+			stats := pool.Stats()
+			var sent uint64
+			for i := range stats.Sent {
+				assert.NotZero(t, stats.Sent[i], "connection %d unused", i)
+				assert.Zero(t, stats.InFlight[i])
+				sent += stats.Sent[i]
+			}
+			assert.Equal(t, uint64(keys), sent)
+		})
+	}
+}
+
+func TestPoolFreesCancelledRequests(t *testing.T) {
+	// The server accepts connections and never answers, requests time out and give back their slot
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			t.Cleanup(func() { _ = conn.Close() })
+		}
+	}()
+
+	c, pool := newPoolClient(t, listener.Addr().String(), client.WithPoolSize(1), client.WithPoolMaxInFlight(1))
+	for i := 0; i < 3; i++ {
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		_, err := c.Get(ctx, testKey(i))
+		cancel()
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	}
+	assert.Equal(t, []int{0}, pool.Stats().InFlight)
+}
+
+func TestPoolSkipsDeadConnections(t *testing.T) {
+	server := fdbtest.Start(t, fdbtest.WithTransports(types.TCPTransportType))
+	key := testKey(1)
+	require.NoError(t, server.Provider(t, fdbtest.DefaultDatabase).Set(key[:], []byte("value")))
+
+	// The first connection reaches a server that accepts connections and never answers
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			t.Cleanup(func() { _ = conn.Close() })
+		}
+	}()
+
+	for name, interval := range map[string]time.Duration{"skipped": time.Minute, "retried": 100 * time.Millisecond} {
+		t.Run(name, func(t *testing.T) {
+			addrs := []string{listener.Addr().String(), server.Addr(types.TCPTransportType)}
+			pool := client.NewPool(func() client.Transport {
+				addr := addrs[0]
+				addrs = addrs[1:]
+				return client.NewTCPTransport(addr, zap.NewNop())
+			}, client.WithPoolSize(2), client.WithPoolRetryInterval(interval))
+
+			cfg := client.NewConfig()
+			cfg.Database = fdbtest.DefaultDatabase
+			c := client.NewClient(context.Background(), cfg)
+			require.NoError(t, c.RegisterTransport("tcp", pool))
+			require.NoError(t, c.Start(context.Background()))
+			t.Cleanup(func() { _ = c.Close() })
+
+			// The dead connection is skipped once it failed a few requests in a row
+			var failed int
+			for i := 0; i < 20 && pool.Stats().Healthy[0]; i++ {
+				ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+				value, err := c.Get(ctx, key)
+				cancel()
+				if err != nil {
+					assert.ErrorIs(t, err, context.DeadlineExceeded)
+					failed++
+					continue
+				}
+				assert.Equal(t, []byte("value"), value)
+			}
+			assert.Equal(t, 3, failed)
+
+			if interval == time.Minute {
+				for i := 0; i < 10; i++ {
+					value, err := c.Get(context.Background(), key)
+					require.NoError(t, err)
+					assert.Equal(t, []byte("value"), value)
+				}
+				assert.Equal(t, []bool{false, true}, pool.Stats().Healthy)
+				return
+			}
+
+			// It is tried again after a while
+			require.Eventually(t, func() bool {
+				return pool.Stats().Healthy[0]
+			}, 5*time.Second, 10*time.Millisecond)
+		})
+	}
+}
@@ -0,0 +1,470 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/quic-go/quic-go"
+	"github.com/unpackdev/fdb/messages"
+	"github.com/unpackdev/fdb/types"
+	"go.uber.org/zap"
+)
+
+const (
+	// quicNextProto is the ALPN protocol negotiated by the QUIC transport of the server.
+	quicNextProto = "quic-example"
+
+	// quicDialTimeout bounds establishing a QUIC connection.
+	quicDialTimeout = 5 * time.Second
+)
+
+// QUICOption configures a QUICTransport
+type QUICOption func(*QUICTransport)
+
+// WithQUICStreamPool keeps size long-lived streams open and spreads the requests over them,
+// several requests being in flight on each stream. Without it, every request opens a stream of
+// its own, closed once answered.
+func WithQUICStreamPool(size int) QUICOption {
+	return func(t *QUICTransport) {
+		if size > 0 {
+			t.pool = make([]*quicPooledStream, size)
+			for i := range t.pool {
+				t.pool[i] = &quicPooledStream{}
+			}
+		}
+	}
+}
+
+// WithQUICRootCAs verifies the certificate of the server against the CAs of pool, see
+// LoadRootCAs, instead of the system roots.
+func WithQUICRootCAs(pool *x509.CertPool) QUICOption {
+	return func(t *QUICTransport) {
+		t.tlsConfig.RootCAs = pool
+	}
+}
+
+// WithQUICServerName sets the name the certificate of the server is verified against, the host
+// of the address when not set.
+func WithQUICServerName(name string) QUICOption {
+	return func(t *QUICTransport) {
+		t.tlsConfig.ServerName = name
+	}
+}
+
+// WithQUICInsecureSkipVerify accepts any certificate of the server. Meant for development only.
+func WithQUICInsecureSkipVerify() QUICOption {
+	return func(t *QUICTransport) {
+		t.tlsConfig.InsecureSkipVerify = true
+	}
+}
+
+// WithQUIC0RTT resumes the TLS sessions kept in cache on reconnects and sends the first requests
+// as 0-RTT data, saving a round trip when the server allows it. A nil cache keeps the sessions
+// of this transport only; sharing one lets other transports resume them. 0-RTT data can be
+// replayed by an attacker.
+func WithQUIC0RTT(cache tls.ClientSessionCache) QUICOption {
+	return func(t *QUICTransport) {
+		if cache == nil {
+			cache = tls.NewLRUClientSessionCache(0)
+		}
+		t.tlsConfig.ClientSessionCache = cache
+		t.early = true
+	}
+}
+
+// QUICTransport implements the Transport interface over QUIC. Requests share a single
+// connection, re-established when it is lost, and travel on per-request streams or on a pool
+// of long-lived streams, see WithQUICStreamPool.
+//
+// Tagged requests of the typed API are converted to the messages the QUIC transport of the
+// server expects. Untagged data is sent
+// as-is on a stream of its own, the response being dispatched to the handlers with a nil
+// gnet.Conn once the server closes the stream.
+type QUICTransport struct {
+	address    string
+	tlsConfig  *tls.Config
+	early      bool
+	handlers   map[MessageType]HandlerFunc
+	onResponse ResponseFunc
+	logger     *zap.Logger
+
+	// pool holds the long-lived streams, empty for per-request streams.
+	pool []*quicPooledStream
+	next atomic.Uint32
+
+	// mu guards conn.
+	mu   sync.Mutex
+	conn quic.Connection
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// quicPooledStream is a long-lived stream of the pool.
+type quicPooledStream struct {
+	// mu serialises the writes to the stream.
+	mu sync.Mutex
+
+	// stream is nil until opened and after it failed.
+	stream quic.Stream
+}
+
+// NewQUICTransport creates a new QUICTransport connecting to the server at address
+func NewQUICTransport(address string, logger *zap.Logger, opts ...QUICOption) *QUICTransport {
+	t := &QUICTransport{
+		address:   address,
+		tlsConfig: &tls.Config{NextProtos: []string{quicNextProto}},
+		handlers:  make(map[MessageType]HandlerFunc),
+		logger:    logger,
+	}
+	for _, opt := range opts {
+		opt(t)
+	}
+	return t
+}
+
+// Connect establishes the QUIC connection
+func (t *QUICTransport) Connect(ctx context.Context) error {
+	t.ctx, t.cancel = context.WithCancel(ctx)
+
+	if _, err := t.connection(); err != nil {
+		t.cancel()
+		return err
+	}
+	return nil
+}
+
+// Send sends a message over the QUIC connection, reconnecting first if the connection was lost
+func (t *QUICTransport) Send(data []byte) error {
+	if t.ctx == nil || t.ctx.Err() != nil {
+		return errors.New("transport is closed")
+	}
+	if len(data) == 0 {
+		return errors.New("empty message")
+	}
+
+	if data[0] != messages.TagSelector {
+		return t.sendUntagged(data)
+	}
+
+	frame, err := quicRequest(data)
+	if err != nil {
+		return err
+	}
+	if len(t.pool) > 0 {
+		slot := t.pool[int(t.next.Add(1))%len(t.pool)]
+		return t.sendPooled(slot, frame)
+	}
+	return t.sendOnStream(frame)
+}
+
+// Close closes the QUIC connection
+func (t *QUICTransport) Close() error {
+	if t.cancel == nil {
+		return nil
+	}
+	t.cancel()
+
+	t.mu.Lock()
+	var err error
+	if t.conn != nil {
+		err = t.conn.CloseWithError(0, "")
+		t.conn = nil
+	}
+	t.mu.Unlock()
+
+	t.wg.Wait()
+	return err
+}
+
+// RegisterHandler registers a handler for a specific message type
+func (t *QUICTransport) RegisterHandler(messageType MessageType, handler HandlerFunc) {
+	t.handlers[messageType] = handler
+}
+
+// OnResponse sets the function receiving the responses to tagged requests
+func (t *QUICTransport) OnResponse(fn ResponseFunc) {
+	t.onResponse = fn
+}
+
+// Used0RTT reports whether the server accepted the 0-RTT data of the current connection. It
+// waits for the handshake of the connection to complete.
+func (t *QUICTransport) Used0RTT() bool {
+	t.mu.Lock()
+	conn := t.conn
+	t.mu.Unlock()
+	if conn == nil {
+		return false
+	}
+
+	if early, ok := conn.(quic.EarlyConnection); ok {
+		select {
+		case <-early.HandshakeComplete():
+		case <-t.ctx.Done():
+			return false
+		}
+	}
+	return conn.ConnectionState().Used0RTT
+}
+
+// connection returns the current connection, dialing a new one if it was lost.
+func (t *QUICTransport) connection() (quic.Connection, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.ctx.Err() != nil {
+		return nil, errors.New("transport is closed")
+	}
+	if t.conn != nil && t.conn.Context().Err() == nil {
+		return t.conn, nil
+	}
+
+	ctx, cancel := context.WithTimeout(t.ctx, quicDialTimeout)
+	defer cancel()
+
+	var conn quic.Connection
+	var err error
+	if t.early {
+		conn, err = quic.DialAddrEarly(ctx, t.address, t.tlsConfig, nil)
+	} else {
+		conn, err = quic.DialAddr(ctx, t.address, t.tlsConfig, nil)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to %s: %w", t.address, err)
+	}
+
+	t.conn = conn
+	t.logger.Info("Connected to server", zap.String("remote", t.address))
+	return conn, nil
+}
+
+// openStream opens a stream on the current connection. When the server rejects the 0-RTT data
+// of the connection, the stream is opened once the connection completes its handshake.
+func (t *QUICTransport) openStream() (quic.Stream, error) {
+	conn, err := t.connection()
+	if err != nil {
+		return nil, err
+	}
+
+	stream, err := conn.OpenStream()
+	if errors.Is(err, quic.Err0RTTRejected) {
+		if conn, err = t.rejected0RTT(conn); err == nil {
+			stream, err = conn.OpenStream()
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open stream to %s: %w", t.address, err)
+	}
+	return stream, nil
+}
+
+// rejected0RTT replaces a connection whose 0-RTT data was rejected by the connection it
+// continues as.
+func (t *QUICTransport) rejected0RTT(conn quic.Connection) (quic.Connection, error) {
+	early, ok := conn.(quic.EarlyConnection)
+	if !ok {
+		return nil, quic.Err0RTTRejected
+	}
+
+	ctx, cancel := context.WithTimeout(t.ctx, quicDialTimeout)
+	defer cancel()
+	next, err := early.NextConnection(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	t.mu.Lock()
+	if t.conn == conn {
+		t.conn = next
+	}
+	t.mu.Unlock()
+	return next, nil
+}
+
+// sendOnStream sends a tagged request on a stream of its own, closed once answered.
+func (t *QUICTransport) sendOnStream(frame []byte) error {
+	stream, err := t.openStream()
+	if err != nil {
+		return err
+	}
+
+	if _, err := stream.Write(frame); err != nil {
+		stream.CancelRead(0)
+		stream.CancelWrite(0)
+		return fmt.Errorf("failed to send request to %s: %w", t.address, err)
+	}
+	// Closing the send side lets the server end the stream after its response
+	if err := stream.Close(); err != nil {
+		stream.CancelRead(0)
+		return err
+	}
+
+	t.wg.Add(1)
+	go func() {
+		defer t.wg.Done()
+		t.readResponses(stream)
+	}()
+	return nil
+}
+
+// sendPooled sends a tagged request on a long-lived stream of the pool, opening it if needed.
+func (t *QUICTransport) sendPooled(slot *quicPooledStream, frame []byte) error {
+	slot.mu.Lock()
+	defer slot.mu.Unlock()
+
+	if slot.stream != nil && slot.stream.Context().Err() != nil {
+		slot.stream = nil
+	}
+	if slot.stream == nil {
+		stream, err := t.openStream()
+		if err != nil {
+			return err
+		}
+		slot.stream = stream
+
+		t.wg.Add(1)
+		go func() {
+			defer t.wg.Done()
+			t.readResponses(stream)
+
+			slot.mu.Lock()
+			if slot.stream == stream {
+				slot.stream = nil
+			}
+			slot.mu.Unlock()
+		}()
+	}
+
+	if _, err := slot.stream.Write(frame); err != nil {
+		slot.stream.CancelRead(0)
+		slot.stream.CancelWrite(0)
+		slot.stream = nil
+		return fmt.Errorf("failed to send request to %s: %w", t.address, err)
+	}
+	return nil
+}
+
+// sendUntagged sends data on a stream of its own and dispatches the response to the handlers.
+func (t *QUICTransport) sendUntagged(data []byte) error {
+	stream, err := t.openStream()
+	if err != nil {
+		return err
+	}
+
+	if _, err := stream.Write(data); err != nil {
+		stream.CancelRead(0)
+		stream.CancelWrite(0)
+		return fmt.Errorf("failed to send message to %s: %w", t.address, err)
+	}
+	if err := stream.Close(); err != nil {
+		stream.CancelRead(0)
+		return err
+	}
+
+	t.wg.Add(1)
+	go func() {
+		defer t.wg.Done()
+
+		resp, err := io.ReadAll(io.LimitReader(stream, messages.MaxTaggedFrameLen))
+		if err != nil {
+			t.logger.Error("Error reading response", zap.Error(err))
+			return
+		}
+		if len(resp) < 1 {
+			t.logger.Warn("Received empty data")
+			return
+		}
+
+		messageType := MessageType(resp[0])
+		if handler, exists := t.handlers[messageType]; exists {
+			if err := handler(nil, resp[1:]); err != nil {
+				t.logger.Error("Handler error", zap.Error(err))
+			}
+		} else {
+			t.logger.Warn("No handler for message type", zap.Uint64("type", messageType.Uint64()))
+		}
+	}()
+	return nil
+}
+
+// readResponses delivers the tagged responses received on a stream until it ends.
+func (t *QUICTransport) readResponses(stream quic.Stream) {
+	defer stream.CancelRead(0)
+
+	reader := bufio.NewReader(stream)
+	header := make([]byte, messages.TagHeaderLen)
+	for {
+		if _, err := io.ReadFull(reader, header); err != nil {
+			if !errors.Is(err, io.EOF) && t.ctx.Err() == nil {
+				t.logger.Debug("Stream closed", zap.Error(err))
+			}
+			return
+		}
+		id, length, err := messages.DecodeTagHeader(header)
+		if err != nil {
+			t.logger.Error("Invalid tagged response", zap.Error(err))
+			return
+		}
+
+		resp := make([]byte, length)
+		if _, err := io.ReadFull(reader, resp); err != nil {
+			t.logger.Debug("Stream closed", zap.Error(err))
+			return
+		}
+
+		if t.onResponse != nil {
+			t.onResponse(id, resp)
+		}
+	}
+}
+
+// quicRequest converts a tagged request of the typed API into the tagged message the QUIC
+// transport of the server expects.
+func quicRequest(data []byte) ([]byte, error) {
+	id, frame, n, err := messages.SplitTagged(data)
+	if err != nil {
+		return nil, err
+	}
+	if n != len(data) {
+		return nil, errors.New("incomplete tagged request")
+	}
+
+	consistency, database, frame, err := messages.SplitHeader(frame)
+	if err != nil {
+		return nil, err
+	}
+	message := messages.Message{
+		Consistency: consistency,
+		Database:    database,
+	}
+	switch {
+	case len(frame) > 0 && types.HandlerType(frame[0]) == types.MultiSetHandlerType:
+		// Multi-sets carry no key of their own, the writes travel as data
+		message.Handler = types.MultiSetHandlerType
+		message.Data = frame[1:]
+	case len(frame) > 0 && types.HandlerType(frame[0]) == types.PingHandlerType:
+		// Pings only select the database
+		message.Handler = types.PingHandlerType
+	case len(frame) < 33:
+		return nil, fmt.Errorf("request too short: %d bytes", len(frame))
+	default:
+		message.Handler = types.HandlerType(frame[0])
+		message.Data = frame[33:]
+		copy(message.Key[:], frame[1:33])
+	}
+
+	encoded, err := message.Encode()
+	if err != nil {
+		return nil, err
+	}
+	return messages.TagFrame(id, encoded), nil
+}
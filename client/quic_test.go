@@ -0,0 +1,140 @@
+package client_test
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/unpackdev/fdb/client"
+	"github.com/unpackdev/fdb/config"
+	"github.com/unpackdev/fdb/fdbtest"
+	"github.com/unpackdev/fdb/types"
+	"go.uber.org/zap"
+)
+
+// newQUICClient connects a client to the server at addr over QUIC.
+func newQUICClient(t *testing.T, addr string, opts ...client.QUICOption) *client.Client {
+	cfg := client.NewConfig()
+	cfg.Database = "fdb"
+	c := client.NewClient(context.Background(), cfg)
+	require.NoError(t, c.RegisterTransport("quic", client.NewQUICTransport(addr, zap.NewNop(), opts...)))
+	require.NoError(t, c.Start(context.Background()))
+	t.Cleanup(func() { _ = c.Close() })
+	return c
+}
+
+func TestQUICTransportStreams(t *testing.T) {
+	server := fdbtest.Start(t, fdbtest.WithTransports(types.QUICTransportType))
+	provider, addr := server.Provider(t, fdbtest.DefaultDatabase), server.Addr(types.QUICTransportType)
+	pool, err := client.LoadRootCAs(server.Certs.CAFile)
+	require.NoError(t, err)
+
+	clients := map[string]*client.Client{
+		"per-request": newQUICClient(t, addr, client.WithQUICRootCAs(pool)),
+		"pool":        newQUICClient(t, addr, client.WithQUICRootCAs(pool), client.WithQUICStreamPool(4)),
+	}
+	ctx := context.Background()
+
+	const keys = 50
+	for i := 0; i < keys; i++ {
+		key := testKey(i)
+		require.NoError(t, provider.Set(key[:], []byte(fmt.Sprintf("value-%d", i))))
+	}
+
+	for name, c := range clients {
+		t.Run(name, func(t *testing.T) {
+			// Concurrent reads share the connection and, with a pool, the streams
+			var wg sync.WaitGroup
+			for i := 0; i < keys; i++ {
+				wg.Add(1)
+				go func(i int) {
+					defer wg.Done()
+
+					value, err := c.Get(ctx, testKey(i))
+					if assert.NoError(t, err) {
+						assert.Equal(t, fmt.Sprintf("value-%d", i), string(value))
+					}
+				}(i)
+			}
+			wg.Wait()
+
+			exists, err := c.Exists(ctx, testKey(keys+len(name)))
+			require.NoError(t, err)
+			assert.False(t, exists)
+
+			key := testKey(keys + len(name))
+			require.NoError(t, c.Set(ctx, key, []byte("value-"+name)))
+			require.Eventually(t, func() bool {
+				value, err := c.Get(ctx, key)
+				return err == nil && string(value) == "value-"+name
+			}, 5*time.Second, 20*time.Millisecond)
+
+			require.NoError(t, c.Delete(ctx, key))
+			require.Eventually(t, func() bool {
+				exists, err := c.Exists(ctx, key)
+				return err == nil && !exists
+			}, 5*time.Second, 20*time.Millisecond)
+		})
+	}
+}
+
+func TestQUICTransportVerifiesServer(t *testing.T) {
+	addr := fdbtest.Start(t, fdbtest.WithTransports(types.QUICTransportType)).Addr(types.QUICTransportType)
+
+	// A CA that did not sign the certificate of the server
+	otherCA := fdbtest.WriteCerts(t, t.TempDir()).CAFile
+	pool, err := client.LoadRootCAs(otherCA)
+	require.NoError(t, err)
+
+	transport := client.NewQUICTransport(addr, zap.NewNop(), client.WithQUICRootCAs(pool))
+	assert.Error(t, transport.Connect(context.Background()))
+	_ = transport.Close()
+}
+
+func TestQUICTransportResumesWith0RTT(t *testing.T) {
+	server := fdbtest.Start(t,
+		fdbtest.WithTransports(types.QUICTransportType),
+		fdbtest.WithConfig(func(cfg *config.Config) {
+			cfg.GetTransportByType(types.QUICTransportType).Config.(*config.QuicTransport).Allow0RTT = true
+		}),
+	)
+	provider, addr := server.Provider(t, fdbtest.DefaultDatabase), server.Addr(types.QUICTransportType)
+	pool, err := client.LoadRootCAs(server.Certs.CAFile)
+	require.NoError(t, err)
+
+	key := testKey(1)
+	require.NoError(t, provider.Set(key[:], []byte("value-1")))
+
+	// Transports sharing the session cache resume the sessions of each other
+	sessions := tls.NewLRUClientSessionCache(0)
+	connect := func() (*client.Client, *client.QUICTransport) {
+		transport := client.NewQUICTransport(addr, zap.NewNop(), client.WithQUICRootCAs(pool), client.WithQUIC0RTT(sessions))
+		cfg := client.NewConfig()
+		cfg.Database = "fdb"
+		c := client.NewClient(context.Background(), cfg)
+		require.NoError(t, c.RegisterTransport("quic", transport))
+		require.NoError(t, c.Start(context.Background()))
+		return c, transport
+	}
+
+	first, transport := connect()
+	value, err := first.Get(context.Background(), key)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("value-1"), value)
+	assert.False(t, transport.Used0RTT())
+	require.NoError(t, first.Close())
+
+	// The session ticket arrives after the handshake
+	require.Eventually(t, func() bool {
+		c, transport := connect()
+		defer c.Close()
+
+		value, err := c.Get(context.Background(), key)
+		return err == nil && string(value) == "value-1" && transport.Used0RTT()
+	}, 5*time.Second, 50*time.Millisecond)
+}
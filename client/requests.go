@@ -0,0 +1,110 @@
+package client
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+
+	"github.com/unpackdev/fdb/messages"
+)
+
+// correlator matches the responses received by a transport with the tagged requests waiting for
+// them. Responses to requests nobody waits for anymore, such as requests whose context expired,
+// are dropped.
+type correlator struct {
+	transport Transport
+
+	// breaker guards the transport, nil without Config.CircuitBreaker.
+	breaker *breaker
+
+	// nextID is the ID of the last request sent.
+	nextID atomic.Uint32
+
+	// mu guards pending.
+	mu      sync.Mutex
+	pending map[uint32]chan outcome
+}
+
+// outcome is the response to a tagged request, or the error the transport failed it with.
+type outcome struct {
+	data []byte
+	err  error
+}
+
+// newCorrelator creates the correlator of a transport and registers it as its response receiver.
+func newCorrelator(transport Transport, breaker *breaker) *correlator {
+	c := &correlator{
+		transport: transport,
+		breaker:   breaker,
+		pending:   make(map[uint32]chan outcome),
+	}
+	transport.OnResponse(c.deliver)
+	if failer, ok := transport.(Failer); ok {
+		failer.OnRequestFailure(c.fail)
+	}
+	return c
+}
+
+// roundTrip tags the request frame, sends it and waits for its response or until ctx is done,
+// recording the outcome in the circuit breaker.
+func (c *correlator) roundTrip(ctx context.Context, frame []byte) ([]byte, error) {
+	probe, err := c.breaker.allow()
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.exchange(ctx, frame)
+	c.breaker.done(probe, err)
+	return resp, err
+}
+
+// exchange tags the request frame, sends it and waits for its response or until ctx is done.
+func (c *correlator) exchange(ctx context.Context, frame []byte) ([]byte, error) {
+	id := c.nextID.Add(1)
+	resp := make(chan outcome, 1)
+
+	c.mu.Lock()
+	c.pending[id] = resp
+	c.mu.Unlock()
+
+	defer func() {
+		c.mu.Lock()
+		delete(c.pending, id)
+		c.mu.Unlock()
+	}()
+
+	if err := c.transport.Send(messages.TagFrame(id, frame)); err != nil {
+		return nil, &sendError{err: err}
+	}
+
+	select {
+	case out := <-resp:
+		return out.data, out.err
+	case <-ctx.Done():
+		if canceler, ok := c.transport.(Canceler); ok {
+			canceler.Cancel(id)
+		}
+		return nil, ctx.Err()
+	}
+}
+
+// deliver hands a response to the request waiting for it.
+func (c *correlator) deliver(id uint32, data []byte) {
+	c.complete(id, outcome{data: append([]byte(nil), data...)})
+}
+
+// fail fails the request waiting for a response the transport gave up on.
+func (c *correlator) fail(id uint32, err error) {
+	c.complete(id, outcome{err: err})
+}
+
+// complete hands the outcome of a request to the goroutine waiting for it.
+func (c *correlator) complete(id uint32, out outcome) {
+	c.mu.Lock()
+	resp, ok := c.pending[id]
+	delete(c.pending, id)
+	c.mu.Unlock()
+
+	if ok {
+		resp <- out
+	}
+}
@@ -0,0 +1,58 @@
+package client
+
+import (
+	"fmt"
+
+	fdbErrors "github.com/unpackdev/fdb/errors"
+	"github.com/unpackdev/fdb/messages"
+	"github.com/unpackdev/fdb/types"
+)
+
+// readResponse decodes the response to a tagged read, a status byte followed by the value, see
+// messages.EncodeReadResponse.
+func readResponse(resp []byte) ([]byte, error) {
+	status, value, err := messages.DecodeReadResponse(resp)
+	if err != nil {
+		return nil, err
+	}
+
+	switch status {
+	case types.StatusOK:
+		return value, nil
+	case types.StatusNotFound:
+		return nil, fdbErrors.ErrNotFound
+	case types.StatusError:
+		if len(value) > 0 {
+			return nil, fmt.Errorf("server failed the read: %s", value)
+		}
+		return nil, statusError(status)
+	default:
+		return nil, statusError(status)
+	}
+}
+
+// statusResponse decodes the single status byte answering a write or delete.
+func statusResponse(resp []byte) error {
+	if len(resp) != 1 {
+		return fmt.Errorf("unexpected response: %q", resp)
+	}
+	return statusError(types.ResponseStatus(resp[0]))
+}
+
+// statusError converts a response status to an error, nil for types.StatusOK.
+func statusError(status types.ResponseStatus) error {
+	switch status {
+	case types.StatusOK:
+		return nil
+	case types.StatusReadOnly:
+		return fdbErrors.ErrReadOnly
+	case types.StatusDatabaseNotFound:
+		return fdbErrors.ErrDatabaseNotFound
+	case types.StatusBusy:
+		return fdbErrors.ErrBusy
+	case types.StatusNotFound:
+		return fdbErrors.ErrNotFound
+	default:
+		return fmt.Errorf("server answered with status %d", status)
+	}
+}
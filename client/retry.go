@@ -0,0 +1,178 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"time"
+
+	fdbErrors "github.com/unpackdev/fdb/errors"
+	"github.com/unpackdev/fdb/types"
+)
+
+const (
+	// DefaultRetryInitialBackoff is the wait before the first retry.
+	DefaultRetryInitialBackoff = 10 * time.Millisecond
+
+	// DefaultRetryMaxBackoff caps the wait between retries.
+	DefaultRetryMaxBackoff = time.Second
+
+	// DefaultRetryMultiplier is the growth of the wait after every retry.
+	DefaultRetryMultiplier = 2.0
+
+	// DefaultRetryJitter is the fraction of each wait drawn at random.
+	DefaultRetryJitter = 0.2
+)
+
+// RetryPolicy configures the retries of the typed API. A request is retried when it failed
+// without reaching the server, i.e. it could not be sent, the circuit of its transport was open
+// or the server answered errors.ErrBusy, and when an idempotent request failed for any
+// transient reason, such as getting no answer in time. Reads are idempotent; writes and deletes
+// are only retried after an ambiguous failure with IdempotentWrites, as a retried write may
+// overwrite a newer write of another client.
+//
+// Retries wait an exponentially growing backoff, part of it drawn at random so clients failing
+// together do not retry together, and stop at MaxAttempts or once the request context is done.
+type RetryPolicy struct {
+	// MaxAttempts is the number of times a request is sent, including the first one. Zero or
+	// one disables retries.
+	MaxAttempts int `yaml:"maxAttempts" json:"maxAttempts"`
+
+	// InitialBackoff is the wait before the first retry, DefaultRetryInitialBackoff when zero.
+	InitialBackoff time.Duration `yaml:"initialBackoff" json:"initialBackoff"`
+
+	// MaxBackoff caps the wait between retries, DefaultRetryMaxBackoff when zero.
+	MaxBackoff time.Duration `yaml:"maxBackoff" json:"maxBackoff"`
+
+	// Multiplier is the growth of the wait after every retry, DefaultRetryMultiplier when zero.
+	Multiplier float64 `yaml:"multiplier" json:"multiplier"`
+
+	// Jitter is the fraction of each wait drawn at random, DefaultRetryJitter when zero.
+	// Negative values disable the jitter.
+	Jitter float64 `yaml:"jitter" json:"jitter"`
+
+	// AttemptTimeout bounds each attempt, so an attempt that got no answer is retried within
+	// the request timeout. Zero lets an attempt use the whole request timeout.
+	AttemptTimeout time.Duration `yaml:"attemptTimeout" json:"attemptTimeout"`
+
+	// IdempotentWrites retries writes and deletes after ambiguous failures too, for
+	// applications whose writes can be applied twice, e.g. content addressed keys.
+	IdempotentWrites bool `yaml:"idempotentWrites" json:"idempotentWrites"`
+}
+
+// RetryStats describes the retries of a client.
+type RetryStats struct {
+	// Retries is the number of requests sent again.
+	Retries uint64 `json:"retries"`
+
+	// Exhausted is the number of requests failing after MaxAttempts attempts.
+	Exhausted uint64 `json:"exhausted"`
+}
+
+// sendError is returned for requests the transport failed to send.
+type sendError struct {
+	err error
+}
+
+// Error returns the error of the transport
+func (e *sendError) Error() string {
+	return e.err.Error()
+}
+
+// Unwrap returns the error of the transport
+func (e *sendError) Unwrap() error {
+	return e.err
+}
+
+// retryable reports whether a request of a handler failing with err may be sent again.
+func (p RetryPolicy) retryable(handler types.HandlerType, err error) bool {
+	var notSent *sendError
+	switch {
+	case errors.As(err, &notSent), errors.Is(err, ErrCircuitOpen), errors.Is(err, fdbErrors.ErrBusy):
+		return true
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(err, ErrLost):
+		return handler == types.ReadHandlerType || p.IdempotentWrites
+	default:
+		return false
+	}
+}
+
+// backoff returns the wait before the retry following attempt, counted from one.
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	initial, limit, multiplier, jitter := p.InitialBackoff, p.MaxBackoff, p.Multiplier, p.Jitter
+	if initial <= 0 {
+		initial = DefaultRetryInitialBackoff
+	}
+	if limit <= 0 {
+		limit = DefaultRetryMaxBackoff
+	}
+	if multiplier <= 0 {
+		multiplier = DefaultRetryMultiplier
+	}
+	if jitter == 0 {
+		jitter = DefaultRetryJitter
+	}
+
+	wait := float64(initial)
+	for i := 1; i < attempt && wait < float64(limit); i++ {
+		wait *= multiplier
+	}
+	wait = min(wait, float64(limit))
+	if jitter > 0 {
+		wait -= wait * min(jitter, 1) * rand.Float64()
+	}
+	return time.Duration(wait)
+}
+
+// RetryStats returns the retries of the typed API so far.
+func (c *Client) RetryStats() RetryStats {
+	return RetryStats{Retries: c.retries.Load(), Exhausted: c.exhausted.Load()}
+}
+
+// Circuits returns the circuit breakers of the transports by name, empty without
+// Config.CircuitBreaker.
+func (c *Client) Circuits() map[string]CircuitStats {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	stats := make(map[string]CircuitStats, len(c.correlators))
+	for name, corr := range c.correlators {
+		if corr.breaker != nil {
+			stats[name] = corr.breaker.snapshot()
+		}
+	}
+	return stats
+}
+
+// retry sends a request through attempt until it succeeds, fails for good or the retries of
+// the policy are exhausted.
+func (c *Client) retry(ctx context.Context, handler types.HandlerType, attempt func(context.Context) ([]byte, error)) ([]byte, error) {
+	policy := c.cfg.Retry
+	for n := 1; ; n++ {
+		attemptCtx, cancel := ctx, context.CancelFunc(func() {})
+		if policy.AttemptTimeout > 0 {
+			attemptCtx, cancel = context.WithTimeout(ctx, policy.AttemptTimeout)
+		}
+		resp, err := attempt(attemptCtx)
+		cancel()
+
+		if err == nil || ctx.Err() != nil || !policy.retryable(handler, err) {
+			return resp, err
+		}
+		if n >= policy.MaxAttempts {
+			if policy.MaxAttempts > 1 {
+				c.exhausted.Add(1)
+			}
+			return nil, err
+		}
+
+		timer := time.NewTimer(policy.backoff(n))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, err
+		case <-timer.C:
+		}
+		c.retries.Add(1)
+	}
+}
@@ -0,0 +1,214 @@
+package client_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/unpackdev/fdb/client"
+	fdbErrors "github.com/unpackdev/fdb/errors"
+	"github.com/unpackdev/fdb/messages"
+	"github.com/unpackdev/fdb/types"
+)
+
+// scriptedTransport answers tagged requests with scripted outcomes: a response, no response at
+// all, or a failure to send.
+type scriptedTransport struct {
+	mu         sync.Mutex
+	script     func(n int) ([]byte, error)
+	requests   int
+	onResponse client.ResponseFunc
+}
+
+func (s *scriptedTransport) Connect(context.Context) error                          { return nil }
+func (s *scriptedTransport) Close() error                                           { return nil }
+func (s *scriptedTransport) RegisterHandler(client.MessageType, client.HandlerFunc) {}
+func (s *scriptedTransport) OnResponse(fn client.ResponseFunc)                      { s.onResponse = fn }
+
+func (s *scriptedTransport) Send(data []byte) error {
+	id, _, _, err := messages.SplitTagged(data)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.requests++
+	resp, err := s.script(s.requests)
+	s.mu.Unlock()
+
+	if err != nil || resp == nil {
+		return err
+	}
+	go s.onResponse(id, resp)
+	return nil
+}
+
+func (s *scriptedTransport) Requests() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests
+}
+
+// newScriptedClient starts a client whose only transport follows script.
+func newScriptedClient(t *testing.T, cfg *client.Config, script func(n int) ([]byte, error)) (*client.Client, *scriptedTransport) {
+	transport := &scriptedTransport{script: script}
+	c := client.NewClient(context.Background(), cfg)
+	require.NoError(t, c.RegisterTransport("scripted", transport))
+	require.NoError(t, c.Start(context.Background()))
+	t.Cleanup(func() { _ = c.Close() })
+	return c, transport
+}
+
+func TestRetryPolicy(t *testing.T) {
+	ctx := context.Background()
+	busy := []byte{byte(types.StatusBusy)}
+	ok := []byte{byte(types.StatusOK)}
+	policy := client.RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond, AttemptTimeout: 50 * time.Millisecond}
+
+	t.Run("busy writes are retried", func(t *testing.T) {
+		cfg := client.NewConfig()
+		cfg.Retry = policy
+		c, transport := newScriptedClient(t, cfg, func(n int) ([]byte, error) {
+			if n < 3 {
+				return busy, nil
+			}
+			return ok, nil
+		})
+
+		require.NoError(t, c.Set(ctx, testKey(1), []byte("value")))
+		assert.Equal(t, 3, transport.Requests())
+		assert.Equal(t, client.RetryStats{Retries: 2}, c.RetryStats())
+	})
+
+	t.Run("busy reads are retried", func(t *testing.T) {
+		cfg := client.NewConfig()
+		cfg.Retry = policy
+		c, transport := newScriptedClient(t, cfg, func(n int) ([]byte, error) {
+			if n < 3 {
+				return busy, nil
+			}
+			return messages.EncodeReadResponse(types.StatusOK, []byte("value")), nil
+		})
+
+		value, err := c.Get(ctx, testKey(1))
+		require.NoError(t, err)
+		assert.Equal(t, "value", string(value))
+		assert.Equal(t, 3, transport.Requests())
+		assert.Equal(t, client.RetryStats{Retries: 2}, c.RetryStats())
+	})
+
+	t.Run("busy reads fail once retries are exhausted", func(t *testing.T) {
+		cfg := client.NewConfig()
+		cfg.Retry = policy
+		c, transport := newScriptedClient(t, cfg, func(int) ([]byte, error) {
+			return busy, nil
+		})
+
+		_, err := c.Get(ctx, testKey(1))
+		assert.ErrorIs(t, err, fdbErrors.ErrBusy)
+		assert.Equal(t, 3, transport.Requests())
+		assert.Equal(t, client.RetryStats{Retries: 2, Exhausted: 1}, c.RetryStats())
+	})
+
+	t.Run("retries are bounded", func(t *testing.T) {
+		cfg := client.NewConfig()
+		cfg.Retry = policy
+		c, transport := newScriptedClient(t, cfg, func(int) ([]byte, error) {
+			return nil, errors.New("connection reset")
+		})
+
+		assert.Error(t, c.Delete(ctx, testKey(1)))
+		assert.Equal(t, 3, transport.Requests())
+		assert.Equal(t, client.RetryStats{Retries: 2, Exhausted: 1}, c.RetryStats())
+	})
+
+	t.Run("only idempotent requests are retried after timeouts", func(t *testing.T) {
+		cfg := client.NewConfig()
+		cfg.Retry = policy
+		c, transport := newScriptedClient(t, cfg, func(int) ([]byte, error) {
+			return nil, nil
+		})
+
+		assert.ErrorIs(t, c.Set(ctx, testKey(1), []byte("value")), context.DeadlineExceeded)
+		assert.Equal(t, 1, transport.Requests())
+
+		_, err := c.Get(ctx, testKey(1))
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.Equal(t, 4, transport.Requests())
+	})
+
+	t.Run("errors of the server are not retried", func(t *testing.T) {
+		cfg := client.NewConfig()
+		cfg.Retry = policy
+		c, transport := newScriptedClient(t, cfg, func(int) ([]byte, error) {
+			return []byte{byte(types.StatusReadOnly)}, nil
+		})
+
+		assert.ErrorIs(t, c.Set(ctx, testKey(1), []byte("value")), fdbErrors.ErrReadOnly)
+		assert.Equal(t, 1, transport.Requests())
+	})
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	type change struct{ from, to client.CircuitState }
+	var (
+		mu      sync.Mutex
+		changes []change
+		failing = true
+	)
+	cfg := client.NewConfig()
+	cfg.CircuitBreaker = &client.CircuitBreaker{
+		FailureThreshold: 3,
+		OpenTimeout:      100 * time.Millisecond,
+		OnStateChange: func(transport string, from, to client.CircuitState) {
+			assert.Equal(t, "scripted", transport)
+			mu.Lock()
+			changes = append(changes, change{from, to})
+			mu.Unlock()
+		},
+	}
+	c, transport := newScriptedClient(t, cfg, func(int) ([]byte, error) {
+		if failing {
+			return nil, errors.New("connection refused")
+		}
+		return messages.EncodeReadResponse(types.StatusOK, []byte("value")), nil
+	})
+	ctx := context.Background()
+
+	// Consecutive failures open the circuit, then requests fail fast
+	for i := 0; i < 3; i++ {
+		_, err := c.Get(ctx, testKey(1))
+		assert.NotErrorIs(t, err, client.ErrCircuitOpen)
+	}
+	_, err := c.Get(ctx, testKey(1))
+	assert.ErrorIs(t, err, client.ErrCircuitOpen)
+	assert.Equal(t, 3, transport.Requests())
+	assert.Equal(t, client.CircuitStats{State: client.CircuitOpen, Failures: 3, Opened: 1, Rejected: 1}, c.Circuits()["scripted"])
+
+	// A failed probe opens the circuit again, a successful one closes it
+	time.Sleep(150 * time.Millisecond)
+	_, err = c.Get(ctx, testKey(1))
+	assert.NotErrorIs(t, err, client.ErrCircuitOpen)
+	assert.Equal(t, client.CircuitOpen, c.Circuits()["scripted"].State)
+
+	time.Sleep(150 * time.Millisecond)
+	failing = false
+	value, err := c.Get(ctx, testKey(1))
+	require.NoError(t, err)
+	assert.Equal(t, "value", string(value))
+	assert.Equal(t, client.CircuitClosed, c.Circuits()["scripted"].State)
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []change{
+		{client.CircuitClosed, client.CircuitOpen},
+		{client.CircuitOpen, client.CircuitHalfOpen},
+		{client.CircuitHalfOpen, client.CircuitOpen},
+		{client.CircuitOpen, client.CircuitHalfOpen},
+		{client.CircuitHalfOpen, client.CircuitClosed},
+	}, changes)
+}
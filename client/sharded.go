@@ -0,0 +1,460 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/unpackdev/fdb/config"
+	fdbErrors "github.com/unpackdev/fdb/errors"
+	"github.com/unpackdev/fdb/messages"
+	"github.com/unpackdev/fdb/sharding"
+	"github.com/unpackdev/fdb/types"
+	"go.uber.org/zap"
+)
+
+// ShardDialer creates the transport reaching a shard. The transport is connected by the
+// ShardedClient.
+type ShardDialer func(shard config.ShardNode) (Transport, error)
+
+// ShardedOption configures a ShardedClient
+type ShardedOption func(*ShardedClient)
+
+// WithShardDialer sets the function creating the transports of the shards, the TCP and QUIC
+// transports of this package by default.
+func WithShardDialer(dialer ShardDialer) ShardedOption {
+	return func(s *ShardedClient) {
+		s.dialer = dialer
+	}
+}
+
+// shardConn is the connection to a shard.
+type shardConn struct {
+	node      config.ShardNode
+	transport Transport
+	corr      *correlator
+}
+
+// shardView is the shard map used for routing along with the connections to its shards.
+type shardView struct {
+	shardMap sharding.Map
+	ring     *sharding.Ring
+	shards   map[string]*shardConn
+}
+
+// ShardedClient routes requests to independent nodes (shards) without a proxy: every key is
+// sent to the shard owning it on the consistent-hash ring of a shard map, the same ring the
+// sharding proxies use, see sharding.NewRing. Multi-key operations are split per shard and
+// their responses merged.
+//
+// The shard map is replaced at runtime with Reload, or kept in sync with a proxy with Watch.
+type ShardedClient struct {
+	cfg    *Config
+	dialer ShardDialer
+	logger *zap.Logger
+
+	// reloadMu serializes the reloads, view is read by every request.
+	reloadMu sync.Mutex
+	view     atomic.Pointer[shardView]
+}
+
+// NewShardedClient creates a ShardedClient, routing once a shard map is loaded with Reload
+//
+// Example usage:
+//
+//	sc := client.NewShardedClient(cfg, zap.L())
+//	if err := sc.Reload(ctx, shardMap); err != nil {
+//	    log.Fatalf("Failed to connect shards: %v", err)
+//	}
+//	go sc.Watch(ctx, proxy.ShardMap, 10*time.Second)
+//
+// Parameters:
+//
+//	cfg (*Config): The database and request timeout of the requests, transports are ignored.
+//	logger (*zap.Logger): Logs the transports of the shards and failed map refreshes.
+//	opts (...ShardedOption): The dialer of the shards.
+//
+// Returns:
+//
+//	*ShardedClient: A new ShardedClient without shards.
+func NewShardedClient(cfg *Config, logger *zap.Logger, opts ...ShardedOption) *ShardedClient {
+	s := &ShardedClient{
+		cfg:    cfg,
+		logger: logger,
+	}
+	s.dialer = s.dialShard
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Reload installs a shard map. Shards whose ID, transport and address are unchanged keep their
+// connection, new shards are connected before the map is installed and removed shards are
+// disconnected after. Maps older than the installed one are rejected, reloading the installed
+// version is a no-op.
+func (s *ShardedClient) Reload(ctx context.Context, m sharding.Map) error {
+	if err := m.Validate(); err != nil {
+		return fmt.Errorf("invalid shard map: %w", err)
+	}
+
+	s.reloadMu.Lock()
+	defer s.reloadMu.Unlock()
+
+	current := s.view.Load()
+	if current != nil {
+		if m.Version == current.shardMap.Version {
+			return nil
+		}
+		if m.Version < current.shardMap.Version {
+			return fmt.Errorf("shard map version %d is older than the installed version %d", m.Version, current.shardMap.Version)
+		}
+	}
+
+	shards := make(map[string]*shardConn, len(m.Shards))
+	var opened []*shardConn
+	for _, node := range m.Shards {
+		if current != nil {
+			if conn, ok := current.shards[node.ID]; ok && conn.node == node {
+				shards[node.ID] = conn
+				continue
+			}
+		}
+
+		conn, err := s.connect(ctx, node)
+		if err != nil {
+			for _, conn := range opened {
+				_ = conn.transport.Close()
+			}
+			return err
+		}
+		opened = append(opened, conn)
+		shards[node.ID] = conn
+	}
+
+	m.Shards = append([]config.ShardNode(nil), m.Shards...)
+	s.view.Store(&shardView{shardMap: m, ring: sharding.NewRing(&m), shards: shards})
+
+	if current != nil {
+		for id, conn := range current.shards {
+			if shards[id] != conn {
+				_ = conn.transport.Close()
+			}
+		}
+	}
+	return nil
+}
+
+// Watch polls a source of shard maps, such as Client.ShardMap of a client connected to a
+// proxy, every interval and installs the maps with a higher version. Failures are logged and
+// retried at the next interval. Watch returns when ctx is done.
+func (s *ShardedClient) Watch(ctx context.Context, source func(context.Context) (sharding.Map, error), interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		m, err := source(ctx)
+		if err != nil {
+			s.logger.Warn("Failed to fetch shard map", zap.Error(err))
+			continue
+		}
+		if m.Version <= s.Map().Version {
+			continue
+		}
+		if err := s.Reload(ctx, m); err != nil {
+			s.logger.Warn("Failed to reload shard map", zap.Uint64("version", m.Version), zap.Error(err))
+		}
+	}
+}
+
+// Map returns a copy of the installed shard map, the zero map before the first Reload.
+func (s *ShardedClient) Map() sharding.Map {
+	view := s.view.Load()
+	if view == nil {
+		return sharding.Map{}
+	}
+	m := view.shardMap
+	m.Shards = append([]config.ShardNode(nil), m.Shards...)
+	return m
+}
+
+// Owner returns the ID of the shard owning a key, empty before the first Reload.
+func (s *ShardedClient) Owner(key [32]byte) string {
+	view := s.view.Load()
+	if view == nil {
+		return ""
+	}
+	return view.ring.Owner(key[:])
+}
+
+// Get returns the value of a key from the shard owning it.
+func (s *ShardedClient) Get(ctx context.Context, key [32]byte) ([]byte, error) {
+	resp, err := s.do(ctx, types.ReadHandlerType, key, nil)
+	if err != nil {
+		return nil, err
+	}
+	return readResponse(resp)
+}
+
+// Set stores the value of a key on the shard owning it.
+func (s *ShardedClient) Set(ctx context.Context, key [32]byte, value []byte) error {
+	if len(value) == 0 {
+		return errors.New("writes require a value")
+	}
+
+	resp, err := s.do(ctx, types.WriteHandlerType, key, value)
+	if err != nil {
+		return err
+	}
+	return statusResponse(resp)
+}
+
+// Delete removes a key from the shard owning it.
+func (s *ShardedClient) Delete(ctx context.Context, key [32]byte) error {
+	resp, err := s.do(ctx, types.DeleteHandlerType, key, nil)
+	if err != nil {
+		return err
+	}
+	return statusResponse(resp)
+}
+
+// Exists reports whether the shard owning a key has a value for it.
+func (s *ShardedClient) Exists(ctx context.Context, key [32]byte) (bool, error) {
+	_, err := s.Get(ctx, key)
+	if errors.Is(err, fdbErrors.ErrNotFound) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// MGet returns the values of several keys, in the order of keys. The keys are split per shard
+// and every shard answers its reads concurrently; missing keys have a nil value.
+func (s *ShardedClient) MGet(ctx context.Context, keys ...[32]byte) ([][]byte, error) {
+	view, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := s.cfg.requestContext(ctx)
+	defer cancel()
+
+	values := make([][]byte, len(keys))
+	errs := make([]error, len(keys))
+	var wg sync.WaitGroup
+	for shard, indexes := range view.split(keys) {
+		for _, i := range indexes {
+			wg.Add(1)
+			go func(shard *shardConn, i int) {
+				defer wg.Done()
+
+				resp, err := s.send(ctx, shard, keyFrame(types.ReadHandlerType, keys[i], nil))
+				if err == nil {
+					values[i], err = readResponse(resp)
+				}
+				if !errors.Is(err, fdbErrors.ErrNotFound) {
+					errs[i] = err
+				}
+			}(shard, i)
+		}
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			return nil, fmt.Errorf("failed to read key %x: %w", keys[i], err)
+		}
+	}
+	return values, nil
+}
+
+// MSet stores several values, sending a single multi-set frame to every shard owning some of
+// the keys. The shards apply their writes independently: on failure, the writes of the other
+// shards may have been applied.
+func (s *ShardedClient) MSet(ctx context.Context, entries ...messages.MultiSetEntry) error {
+	view, err := s.load()
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if len(entry.Value) == 0 {
+			return fmt.Errorf("write of key %x requires a value", entry.Key)
+		}
+	}
+
+	keys := make([][32]byte, len(entries))
+	for i, entry := range entries {
+		keys[i] = entry.Key
+	}
+
+	ctx, cancel := s.cfg.requestContext(ctx)
+	defer cancel()
+
+	var (
+		mu   sync.Mutex
+		errs []error
+		wg   sync.WaitGroup
+	)
+	for shard, indexes := range view.split(keys) {
+		batch := make([]messages.MultiSetEntry, len(indexes))
+		for n, i := range indexes {
+			batch[n] = entries[i]
+		}
+
+		wg.Add(1)
+		go func(shard *shardConn) {
+			defer wg.Done()
+
+			resp, err := s.send(ctx, shard, messages.EncodeMultiSet(batch))
+			if err == nil {
+				err = statusResponse(resp)
+			}
+			if err != nil {
+				mu.Lock()
+				errs = append(errs, fmt.Errorf("shard %s: %w", shard.node.ID, err))
+				mu.Unlock()
+			}
+		}(shard)
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// Close disconnects every shard.
+func (s *ShardedClient) Close() error {
+	s.reloadMu.Lock()
+	defer s.reloadMu.Unlock()
+
+	view := s.view.Swap(nil)
+	if view == nil {
+		return nil
+	}
+
+	var errs []error
+	for _, conn := range view.shards {
+		if err := conn.transport.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// do sends a key request to the shard owning the key and returns the raw response.
+func (s *ShardedClient) do(ctx context.Context, handler types.HandlerType, key [32]byte, value []byte) ([]byte, error) {
+	view, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := s.cfg.requestContext(ctx)
+	defer cancel()
+	return s.send(ctx, view.shards[view.ring.Owner(key[:])], keyFrame(handler, key, value))
+}
+
+// send selects the database of a request frame and sends it to a shard.
+func (s *ShardedClient) send(ctx context.Context, shard *shardConn, frame []byte) ([]byte, error) {
+	frame, err := messages.WithDatabase(s.cfg.Database, frame)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := shard.corr.roundTrip(ctx, frame)
+	if err != nil {
+		return nil, fmt.Errorf("shard %s: %w", shard.node.ID, err)
+	}
+	return resp, nil
+}
+
+// load returns the installed shard map.
+func (s *ShardedClient) load() (*shardView, error) {
+	view := s.view.Load()
+	if view == nil {
+		return nil, errors.New("no shard map loaded")
+	}
+	return view, nil
+}
+
+// connect creates and connects the transport of a shard.
+func (s *ShardedClient) connect(ctx context.Context, node config.ShardNode) (*shardConn, error) {
+	transport, err := s.dialer(node)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create transport of shard %s: %w", node.ID, err)
+	}
+
+	conn := &shardConn{node: node, transport: transport, corr: newCorrelator(transport, newBreaker(node.ID, s.cfg.CircuitBreaker))}
+	if err := transport.Connect(ctx); err != nil {
+		_ = transport.Close()
+		return nil, fmt.Errorf("failed to connect shard %s: %w", node.ID, err)
+	}
+	return conn, nil
+}
+
+// dialShard is the default ShardDialer.
+func (s *ShardedClient) dialShard(node config.ShardNode) (Transport, error) {
+	switch node.Transport {
+	case types.TCPTransportType:
+		return NewTCPTransport(node.Addr, s.logger), nil
+	case types.QUICTransportType:
+		var opts []QUICOption
+		if node.Insecure {
+			opts = append(opts, WithQUICInsecureSkipVerify())
+		}
+		return NewQUICTransport(node.Addr, s.logger, opts...), nil
+	default:
+		return nil, fmt.Errorf("unsupported shard transport %s", node.Transport)
+	}
+}
+
+// split groups the indexes of keys by the shard owning them.
+func (v *shardView) split(keys [][32]byte) map[*shardConn][]int {
+	groups := make(map[*shardConn][]int)
+	for i, key := range keys {
+		shard := v.shards[v.ring.Owner(key[:])]
+		groups[shard] = append(groups[shard], i)
+	}
+	return groups
+}
+
+// ShardMap fetches the shard map of a sharding proxy through the default transport, see
+// messages.AdminShardMap. It is the source of ShardedClient.Watch.
+func (c *Client) ShardMap(ctx context.Context) (sharding.Map, error) {
+	corr, err := c.defaultCorrelator()
+	if err != nil {
+		return sharding.Map{}, err
+	}
+
+	req := messages.AdminRequest{Op: messages.AdminShardMap}
+	frame, err := req.Encode()
+	if err != nil {
+		return sharding.Map{}, err
+	}
+
+	ctx, cancel := c.cfg.requestContext(ctx)
+	defer cancel()
+
+	resp, err := corr.roundTrip(ctx, frame)
+	if err != nil {
+		return sharding.Map{}, err
+	}
+	if len(resp) == 0 {
+		return sharding.Map{}, errors.New("empty shard map response")
+	}
+	if types.ResponseStatus(resp[0]) != types.StatusOK {
+		return sharding.Map{}, fmt.Errorf("proxy rejected the shard map request: %s", resp[1:])
+	}
+
+	var status sharding.Status
+	if err := json.Unmarshal(resp[1:], &status); err != nil {
+		return sharding.Map{}, fmt.Errorf("failed to decode shard map: %w", err)
+	}
+	return status.Map, nil
+}
@@ -0,0 +1,154 @@
+package client_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/unpackdev/fdb/client"
+	"github.com/unpackdev/fdb/config"
+	"github.com/unpackdev/fdb/db"
+	fdbErrors "github.com/unpackdev/fdb/errors"
+	"github.com/unpackdev/fdb/fdbtest"
+	"github.com/unpackdev/fdb/messages"
+	"github.com/unpackdev/fdb/sharding"
+	"github.com/unpackdev/fdb/types"
+	"go.uber.org/zap"
+)
+
+func TestShardedClientRoutesByKey(t *testing.T) {
+	providers := make(map[string]db.Provider)
+	shardMap := sharding.Map{Version: 1, VirtualNodes: 16}
+	for i := 0; i < 3; i++ {
+		server := fdbtest.Start(t, fdbtest.WithTransports(types.TCPTransportType))
+		id := fmt.Sprintf("shard-%d", i+1)
+		providers[id] = server.Provider(t, fdbtest.DefaultDatabase)
+		shardMap.Shards = append(shardMap.Shards, config.ShardNode{ID: id, Transport: types.TCPTransportType, Addr: server.Addr(types.TCPTransportType)})
+	}
+
+	// The client starts with the first two shards
+	initial := shardMap
+	initial.Shards = shardMap.Shards[:2]
+	cfg := client.NewConfig()
+	cfg.Database = "fdb"
+	sc := client.NewShardedClient(cfg, zap.NewNop())
+	require.NoError(t, sc.Reload(context.Background(), initial))
+	t.Cleanup(func() { _ = sc.Close() })
+	ctx := context.Background()
+
+	const keys = 100
+	entries := make([]messages.MultiSetEntry, keys)
+	for i := range entries {
+		entries[i] = messages.MultiSetEntry{Key: testKey(i), Value: []byte(fmt.Sprintf("value-%d", i))}
+	}
+	require.NoError(t, sc.MSet(ctx, entries[:keys/2]...))
+	for _, entry := range entries[keys/2:] {
+		require.NoError(t, sc.Set(ctx, entry.Key, entry.Value))
+	}
+
+	// Every key is stored by its owner only
+	ring := sharding.NewRing(&initial)
+	owned := make(map[string]int)
+	for _, entry := range entries {
+		owner := ring.Owner(entry.Key[:])
+		assert.Equal(t, owner, sc.Owner(entry.Key))
+		owned[owner]++
+	}
+	assert.Len(t, owned, 2)
+	require.Eventually(t, func() bool {
+		for _, entry := range entries {
+			owner := ring.Owner(entry.Key[:])
+			for id, provider := range providers {
+				if _, err := provider.Get(entry.Key[:]); (err == nil) != (id == owner) {
+					return false
+				}
+			}
+		}
+		return true
+	}, 5*time.Second, 20*time.Millisecond)
+
+	missing := testKey(keys)
+	values, err := sc.MGet(ctx, testKey(0), missing, testKey(keys-1))
+	require.NoError(t, err)
+	assert.Equal(t, [][]byte{[]byte("value-0"), nil, []byte(fmt.Sprintf("value-%d", keys-1))}, values)
+
+	require.NoError(t, sc.Delete(ctx, testKey(0)))
+	require.Eventually(t, func() bool {
+		_, err := sc.Get(ctx, testKey(0))
+		return assert.ObjectsAreEqual(fdbErrors.ErrNotFound, err)
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// A newer map adds the third shard, new keys it owns are sent to it
+	shardMap.Version = 2
+	require.NoError(t, sc.Reload(ctx, shardMap))
+	assert.Len(t, sc.Map().Shards, 3)
+	assert.Error(t, sc.Reload(ctx, initial))
+	assert.Equal(t, uint64(2), sc.Map().Version)
+
+	var moved int
+	for i := keys; i < 2*keys; i++ {
+		key := testKey(i)
+		if sc.Owner(key) != "shard-3" {
+			continue
+		}
+		moved++
+		require.NoError(t, sc.Set(ctx, key, []byte("value")))
+	}
+	assert.NotZero(t, moved)
+	require.Eventually(t, func() bool {
+		for i := keys; i < 2*keys; i++ {
+			key := testKey(i)
+			if _, err := providers["shard-3"].Get(key[:]); sc.Owner(key) == "shard-3" && err != nil {
+				return false
+			}
+		}
+		return true
+	}, 5*time.Second, 20*time.Millisecond)
+}
+
+func TestShardedClientWatchesProxy(t *testing.T) {
+	first := fdbtest.Start(t, fdbtest.WithTransports(types.TCPTransportType))
+	second := fdbtest.Start(t, fdbtest.WithTransports(types.TCPTransportType))
+
+	// The shard map is served by an instance proxying its database to the shards
+	proxyServer := fdbtest.Start(t,
+		fdbtest.WithTransports(types.TCPTransportType),
+		fdbtest.WithAdmin(),
+		fdbtest.WithConfig(func(cfg *config.Config) {
+			cfg.Mdbx.Nodes = nil
+			cfg.Sharding = config.Sharding{
+				Enabled:      true,
+				Databases:    []string{fdbtest.DefaultDatabase},
+				VirtualNodes: 8,
+				Shards: []config.ShardNode{
+					{ID: "shard-1", Transport: types.TCPTransportType, Addr: first.Addr(types.TCPTransportType)},
+				},
+			}
+		}),
+	)
+	proxy := proxyServer.Client(t, types.TCPTransportType)
+	m, err := proxy.ShardMap(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), m.Version)
+
+	sc := client.NewShardedClient(client.NewConfig(), zap.NewNop())
+	require.NoError(t, sc.Reload(context.Background(), m))
+	t.Cleanup(func() { _ = sc.Close() })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	go sc.Watch(ctx, proxy.ShardMap, 20*time.Millisecond)
+
+	next := m
+	next.Version = 2
+	next.Shards = append(next.Shards, config.ShardNode{ID: "shard-2", Transport: types.TCPTransportType, Addr: second.Addr(types.TCPTransportType)})
+	require.NoError(t, proxyServer.FDB.GetSharding().Rebalance(context.Background(), next))
+
+	require.Eventually(t, func() bool {
+		return sc.Map().Version == 2
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Len(t, sc.Map().Shards, 2)
+}
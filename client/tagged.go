@@ -0,0 +1,46 @@
+package client
+
+import "github.com/unpackdev/fdb/messages"
+
+// taggedReceiver reassembles the tagged responses of a stream, which may arrive split or
+// coalesced, by their length. See messages.TagSelector.
+type taggedReceiver struct {
+	buf []byte
+}
+
+// pending reports whether part of a tagged response has been received.
+func (r *taggedReceiver) pending() bool {
+	return len(r.buf) > 0
+}
+
+// receive appends received data to the buffered tagged responses and delivers every complete
+// one. The buffer is discarded on error.
+func (r *taggedReceiver) receive(data []byte, deliver ResponseFunc) error {
+	r.buf = append(r.buf, data...)
+
+	consumed := 0
+	for {
+		id, resp, n, err := messages.SplitTagged(r.buf[consumed:])
+		if err != nil {
+			r.reset()
+			return err
+		}
+		if n == 0 {
+			break
+		}
+		consumed += n
+
+		if deliver != nil {
+			deliver(id, resp)
+		}
+	}
+
+	// Keep the incomplete response, if any, at the start of the buffer
+	r.buf = append(r.buf[:0], r.buf[consumed:]...)
+	return nil
+}
+
+// reset discards the buffered data.
+func (r *taggedReceiver) reset() {
+	r.buf = nil
+}
@@ -3,24 +3,32 @@ package client
 import (
 	"context"
 	"errors"
+	"net"
 	"sync"
 	"time"
 
 	"github.com/panjf2000/gnet/v2"
+	"github.com/unpackdev/fdb/messages"
 	"go.uber.org/zap"
 )
 
-// TCPTransport implements the Transport interface using gnet
+// TCPTransport implements the Transport interface using gnet. gnet cannot speak TLS, servers
+// serving TLS on their TCP transport are reached through TLSTransport.
 type TCPTransport struct {
-	address  string
-	opts     []gnet.Option
-	handlers map[MessageType]HandlerFunc
-	client   *gnet.Client
-	conn     gnet.Conn
-	mu       sync.Mutex
-	ctx      context.Context
-	cancel   context.CancelFunc
-	logger   *zap.Logger
+	address    string
+	opts       []gnet.Option
+	handlers   map[MessageType]HandlerFunc
+	onResponse ResponseFunc
+	client     *gnet.Client
+	conn       gnet.Conn
+	mu         sync.Mutex
+	dialMu     sync.Mutex
+	ctx        context.Context
+	cancel     context.CancelFunc
+	logger     *zap.Logger
+
+	// tagged reassembles the tagged responses, only used by the event loop.
+	tagged taggedReceiver
 }
 
 // NewTCPTransport creates a new TCPTransport
@@ -44,36 +52,79 @@ func (t *TCPTransport) Connect(ctx context.Context) error {
 	if err != nil {
 		return err
 	}
-	t.client = client
 
-	// Start the client
-	go func() {
-		if err := t.client.Start(); err != nil {
-			t.logger.Error("Failed to start gnet client", zap.Error(err))
-		}
-	}()
+	// Start the client, the event loop runs in the background once Start returns so Close
+	// always finds it started
+	if err := client.Start(); err != nil {
+		t.cancel()
+		return err
+	}
+	t.client = client
 
 	// Dial the server
 	conn, err := t.client.Dial("tcp", t.address)
 	if err != nil {
+		t.cancel()
+		_ = t.client.Stop()
+		t.client = nil
 		return err
 	}
 
+	t.mu.Lock()
 	t.conn = conn
+	t.mu.Unlock()
 
 	return nil
 }
 
-// Send sends a message over the TCP connection
+// Send sends a message over the TCP connection, dialing the server again when the connection
+// was lost
 func (t *TCPTransport) Send(data []byte) error {
+	conn, err := t.connection()
+	if err != nil {
+		return err
+	}
+	return conn.AsyncWrite(data, nil)
+}
+
+// connection returns the current connection, dialing a new one when the previous one was lost.
+func (t *TCPTransport) connection() (gnet.Conn, error) {
 	t.mu.Lock()
-	defer t.mu.Unlock()
+	conn := t.conn
+	t.mu.Unlock()
+	if conn != nil {
+		return conn, nil
+	}
 
-	if t.conn == nil {
-		return errors.New("no active connection")
+	if t.client == nil || t.ctx.Err() != nil {
+		return nil, errors.New("no active connection")
 	}
 
-	return t.conn.AsyncWrite(data, nil)
+	// A single connection at a time, the tagged responses are reassembled from one stream
+	t.dialMu.Lock()
+	defer t.dialMu.Unlock()
+
+	t.mu.Lock()
+	conn = t.conn
+	t.mu.Unlock()
+	if conn != nil {
+		return conn, nil
+	}
+
+	conn, err := t.client.Dial("tcp", t.address)
+	if err != nil {
+		return nil, err
+	}
+	t.mu.Lock()
+	t.conn = conn
+	t.mu.Unlock()
+	return conn, nil
+}
+
+// OpenStream dials a plain TCP connection to the server, see Streamer
+func (t *TCPTransport) OpenStream(ctx context.Context) (net.Conn, error) {
+	var dialer net.Dialer
+	return dialer.DialContext(ctx, "tcp", t.address)
 }
 
 // Close closes the TCP connection
@@ -90,6 +141,11 @@ func (t *TCPTransport) RegisterHandler(messageType MessageType, handler HandlerF
 	t.handlers[messageType] = handler
 }
 
+// OnResponse sets the function receiving the responses to tagged requests
+func (t *TCPTransport) OnResponse(fn ResponseFunc) {
+	t.onResponse = fn
+}
+
 // tcpEventHandler implements gnet.EventHandler for the TCPTransport
 type tcpEventHandler struct {
 	transport *TCPTransport
@@ -109,14 +165,24 @@ func (h *tcpEventHandler) OnShutdown(eng gnet.Engine) {
 // OnOpen is called when a new connection is established
 func (h *tcpEventHandler) OnOpen(c gnet.Conn) ([]byte, gnet.Action) {
 	h.transport.logger.Info("Connected to server", zap.String("remote", c.RemoteAddr().String()))
+	h.transport.mu.Lock()
 	h.transport.conn = c // Store the connection
+	h.transport.mu.Unlock()
 	return nil, gnet.None
 }
 
 // OnClose is called when the connection is closed
 func (h *tcpEventHandler) OnClose(c gnet.Conn, err error) gnet.Action {
 	h.transport.logger.Info("Connection closed", zap.Error(err))
-	h.transport.conn = nil
+	h.transport.mu.Lock()
+	current := h.transport.conn == c
+	if current {
+		h.transport.conn = nil
+	}
+	h.transport.mu.Unlock()
+	if current {
+		h.transport.tagged.reset()
+	}
 	return gnet.None
 }
 
@@ -133,6 +199,15 @@ func (h *tcpEventHandler) OnTraffic(c gnet.Conn) gnet.Action {
 		return gnet.None
 	}
 
+	// Tagged responses may arrive split or coalesced, they are reassembled by their length
+	if h.transport.tagged.pending() || data[0] == messages.TagSelector {
+		if err := h.transport.tagged.receive(data, h.transport.onResponse); err != nil {
+			h.transport.logger.Error("Invalid tagged response", zap.Error(err))
+			return gnet.Close
+		}
+		return gnet.None
+	}
+
 	messageType := MessageType(data[0])
 	handler, exists := h.transport.handlers[messageType]
 	if exists {
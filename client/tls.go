@@ -0,0 +1,317 @@
+package client
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/unpackdev/fdb/messages"
+	"go.uber.org/zap"
+)
+
+const (
+	// tlsDialTimeout bounds establishing a TLS connection, handshake included.
+	tlsDialTimeout = 5 * time.Second
+
+	// tlsReadBufferSize is the size of the reads from a TLS connection, a TLS record at most.
+	tlsReadBufferSize = 16 * 1024
+)
+
+// LoadRootCAs reads the PEM encoded CA certificates of the given files into a pool, used to
+// verify the certificates of servers.
+//
+// Example usage:
+//
+//	pool, err := client.LoadRootCAs("/etc/fdb/ca.pem")
+//	if err != nil {
+//	    log.Fatalf("Failed to load CAs: %v", err)
+//	}
+//	transport := client.NewQUICTransport("10.0.0.2:4433", zap.L(), client.WithQUICRootCAs(pool))
+//
+// Parameters:
+//
+//	paths (...string): The files holding the CA certificates.
+//
+// Returns:
+//
+//	*x509.CertPool: The pool of CA certificates.
+//	error: Returns an error if a file cannot be read or holds no certificate.
+func LoadRootCAs(paths ...string) (*x509.CertPool, error) {
+	pool := x509.NewCertPool()
+	for _, path := range paths {
+		pem, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA file: %w", err)
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no CA certificates found in %s", path)
+		}
+	}
+	return pool, nil
+}
+
+// TLSOption configures a TLSTransport
+type TLSOption func(*TLSTransport)
+
+// WithTLSRootCAs verifies the certificate of the server against the CAs of pool, see
+// LoadRootCAs, instead of the system roots.
+func WithTLSRootCAs(pool *x509.CertPool) TLSOption {
+	return func(t *TLSTransport) {
+		t.tlsConfig.RootCAs = pool
+	}
+}
+
+// WithTLSServerName sets the name the certificate of the server is verified against, the host
+// of the address when not set.
+func WithTLSServerName(name string) TLSOption {
+	return func(t *TLSTransport) {
+		t.tlsConfig.ServerName = name
+	}
+}
+
+// WithTLSInsecureSkipVerify accepts any certificate of the server. Meant for development only.
+func WithTLSInsecureSkipVerify() TLSOption {
+	return func(t *TLSTransport) {
+		t.tlsConfig.InsecureSkipVerify = true
+	}
+}
+
+// WithTLSMinVersion sets the oldest TLS version negotiated, tls.VersionTLS12 when not set.
+func WithTLSMinVersion(version uint16) TLSOption {
+	return func(t *TLSTransport) {
+		if version != 0 {
+			t.tlsConfig.MinVersion = version
+		}
+	}
+}
+
+// WithTLSCipherSuites restricts the cipher suites of TLS 1.2 connections. The suites of TLS 1.3
+// are not configurable.
+func WithTLSCipherSuites(suites ...uint16) TLSOption {
+	return func(t *TLSTransport) {
+		if len(suites) > 0 {
+			t.tlsConfig.CipherSuites = suites
+		}
+	}
+}
+
+// WithTLSCertificate presents cert to servers requesting a client certificate.
+func WithTLSCertificate(cert tls.Certificate) TLSOption {
+	return func(t *TLSTransport) {
+		t.tlsConfig.Certificates = append(t.tlsConfig.Certificates, cert)
+	}
+}
+
+// TLSTransport implements the Transport interface over a TLS connection to the TCP transport of
+// a server serving TLS. The connection is dialed again by the first request sent after it was
+// lost.
+//
+// Handlers registered for untagged responses receive a nil gnet.Conn.
+type TLSTransport struct {
+	address    string
+	tlsConfig  *tls.Config
+	handlers   map[MessageType]HandlerFunc
+	onResponse ResponseFunc
+	logger     *zap.Logger
+
+	// mu guards the connection.
+	mu   sync.Mutex
+	conn *tls.Conn
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewTLSTransport creates a new TLSTransport connecting to the server at address
+//
+// Example usage:
+//
+//	pool, err := client.LoadRootCAs("/etc/fdb/ca.pem")
+//	if err != nil {
+//	    log.Fatalf("Failed to load CAs: %v", err)
+//	}
+//	transport := client.NewTLSTransport("10.0.0.2:5011", zap.L(), client.WithTLSRootCAs(pool))
+//
+// Parameters:
+//
+//	address (string): The address of the TCP transport of the server.
+//	logger (*zap.Logger): The logger of the transport.
+//	opts (...TLSOption): Options verifying the server or restricting the TLS versions and suites.
+//
+// Returns:
+//
+//	*TLSTransport: The transport, connected by Connect.
+func NewTLSTransport(address string, logger *zap.Logger, opts ...TLSOption) *TLSTransport {
+	t := &TLSTransport{
+		address:   address,
+		tlsConfig: &tls.Config{MinVersion: tls.VersionTLS12},
+		handlers:  make(map[MessageType]HandlerFunc),
+		logger:    logger,
+	}
+	for _, opt := range opts {
+		opt(t)
+	}
+	return t
+}
+
+// Connect establishes the TLS connection
+func (t *TLSTransport) Connect(ctx context.Context) error {
+	t.ctx, t.cancel = context.WithCancel(ctx)
+
+	t.mu.Lock()
+	err := t.dial()
+	t.mu.Unlock()
+	if err != nil {
+		t.cancel()
+		return err
+	}
+	return nil
+}
+
+// Send sends a message over the TLS connection, dialing the server again when the connection
+// was lost
+func (t *TLSTransport) Send(data []byte) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.ctx == nil || t.ctx.Err() != nil {
+		return errors.New("transport is closed")
+	}
+	if t.conn == nil {
+		if err := t.dial(); err != nil {
+			return fmt.Errorf("no active connection: %w", err)
+		}
+	}
+
+	if _, err := t.conn.Write(data); err != nil {
+		t.disconnect()
+		return err
+	}
+	return nil
+}
+
+// Close closes the TLS connection
+func (t *TLSTransport) Close() error {
+	if t.cancel == nil {
+		return nil
+	}
+	t.cancel()
+
+	t.mu.Lock()
+	t.disconnect()
+	t.mu.Unlock()
+	t.wg.Wait()
+	return nil
+}
+
+// OpenStream dials a TLS connection to the server with the settings of the transport, see
+// Streamer.
+func (t *TLSTransport) OpenStream(ctx context.Context) (net.Conn, error) {
+	ctx, cancel := context.WithTimeout(ctx, tlsDialTimeout)
+	defer cancel()
+
+	dialer := &tls.Dialer{Config: t.tlsConfig}
+	return dialer.DialContext(ctx, "tcp", t.address)
+}
+
+// RegisterHandler registers a handler for a specific message type
+func (t *TLSTransport) RegisterHandler(messageType MessageType, handler HandlerFunc) {
+	t.handlers[messageType] = handler
+}
+
+// OnResponse sets the function receiving the responses to tagged requests
+func (t *TLSTransport) OnResponse(fn ResponseFunc) {
+	t.onResponse = fn
+}
+
+// dial connects to the server and starts reading from the connection. Must be called with mu
+// held.
+func (t *TLSTransport) dial() error {
+	ctx, cancel := context.WithTimeout(t.ctx, tlsDialTimeout)
+	defer cancel()
+
+	dialer := &tls.Dialer{Config: t.tlsConfig}
+	conn, err := dialer.DialContext(ctx, "tcp", t.address)
+	if err != nil {
+		return err
+	}
+
+	t.conn = conn.(*tls.Conn)
+	state := t.conn.ConnectionState()
+	t.logger.Info("Connected to server",
+		zap.String("remote", t.address),
+		zap.String("version", tls.VersionName(state.Version)),
+		zap.String("cipherSuite", tls.CipherSuiteName(state.CipherSuite)),
+	)
+
+	t.wg.Add(1)
+	go t.read(t.conn)
+	return nil
+}
+
+// disconnect closes the connection, if any. Must be called with mu held.
+func (t *TLSTransport) disconnect() {
+	if t.conn != nil {
+		// The close notification tells the server the connection was not truncated, crypto/tls
+		// bounds sending it and skips it while a write is in flight
+		_ = t.conn.Close()
+		t.conn = nil
+	}
+}
+
+// read dispatches the responses received on the connection until it is closed.
+func (t *TLSTransport) read(conn *tls.Conn) {
+	defer t.wg.Done()
+
+	var tagged taggedReceiver
+	buf := make([]byte, tlsReadBufferSize)
+	for {
+		n, err := conn.Read(buf)
+		if err != nil {
+			t.mu.Lock()
+			if t.conn == conn {
+				if !errors.Is(err, net.ErrClosed) {
+					t.logger.Info("Connection closed", zap.Error(err))
+				}
+				t.disconnect()
+			}
+			t.mu.Unlock()
+			return
+		}
+		if n == 0 {
+			continue
+		}
+		data := buf[:n]
+
+		// Tagged responses may arrive split or coalesced, they are reassembled by their length
+		if tagged.pending() || data[0] == messages.TagSelector {
+			if err := tagged.receive(data, t.onResponse); err != nil {
+				t.logger.Error("Invalid tagged response", zap.Error(err))
+				// The stream cannot be resynchronised
+				t.mu.Lock()
+				if t.conn == conn {
+					t.disconnect()
+				}
+				t.mu.Unlock()
+				return
+			}
+			continue
+		}
+
+		messageType := MessageType(data[0])
+		if handler, exists := t.handlers[messageType]; exists {
+			if err := handler(nil, data[1:]); err != nil {
+				t.logger.Error("Handler error", zap.Error(err))
+			}
+		} else {
+			t.logger.Warn("No handler for message type", zap.Uint64("type", messageType.Uint64()))
+		}
+	}
+}
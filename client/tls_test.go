@@ -0,0 +1,75 @@
+package client_test
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/unpackdev/fdb/client"
+	"github.com/unpackdev/fdb/config"
+	"github.com/unpackdev/fdb/fdbtest"
+	"github.com/unpackdev/fdb/types"
+	"go.uber.org/zap"
+)
+
+func TestTLSTransport(t *testing.T) {
+	server := fdbtest.Start(t, fdbtest.WithTransports(types.TCPTransportType), fdbtest.WithTLS())
+	c := server.Client(t, types.TCPTransportType)
+	ctx := context.Background()
+
+	var first, second, missing [32]byte
+	copy(first[:], "first")
+	copy(second[:], "second")
+	copy(missing[:], "missing")
+
+	require.NoError(t, c.Set(ctx, first, []byte("one")))
+	require.NoError(t, c.Set(ctx, second, []byte("two")))
+
+	// Writes are acknowledged once buffered and visible once flushed
+	require.Eventually(t, func() bool {
+		values, err := c.MGet(ctx, first, missing, second)
+		return err == nil && assert.ObjectsAreEqual([][]byte{[]byte("one"), nil, []byte("two")}, values)
+	}, 5*time.Second, 20*time.Millisecond)
+}
+
+func TestTLSTransportVerifiesServer(t *testing.T) {
+	server := fdbtest.Start(t, fdbtest.WithTransports(types.TCPTransportType), fdbtest.WithTLS())
+
+	// The certificate of the instance is signed by its own CA, unknown to the system roots
+	dsn := fmt.Sprintf("%s://%s?db=%s", client.SchemeTLS, server.Addr(types.TCPTransportType), fdbtest.DefaultDatabase)
+	_, err := client.Open(context.Background(), dsn, zap.NewNop())
+	assert.Error(t, err)
+
+	c, err := client.Open(context.Background(), dsn+"&insecure=true", zap.NewNop())
+	require.NoError(t, err)
+	require.NoError(t, c.Close())
+}
+
+func TestTLSTransportMinVersion(t *testing.T) {
+	server := fdbtest.Start(t,
+		fdbtest.WithTransports(types.TCPTransportType),
+		fdbtest.WithTLS(),
+		fdbtest.WithConfig(func(cfg *config.Config) {
+			tcp := cfg.GetTransportByType(types.TCPTransportType).Config.(*config.TcpTransport)
+			tcp.TLS.MinVersion = "1.3"
+		}),
+	)
+
+	// Clients limited to TLS 1.2 are refused
+	_, err := tls.Dial("tcp", server.Addr(types.TCPTransportType), &tls.Config{
+		InsecureSkipVerify: true, // #nosec G402
+		MaxVersion:         tls.VersionTLS12,
+	})
+	assert.Error(t, err)
+
+	conn, err := tls.Dial("tcp", server.Addr(types.TCPTransportType), &tls.Config{
+		InsecureSkipVerify: true, // #nosec G402
+	})
+	require.NoError(t, err)
+	assert.Equal(t, uint16(tls.VersionTLS13), conn.ConnectionState().Version)
+	require.NoError(t, conn.Close())
+}
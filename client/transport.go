@@ -2,6 +2,7 @@ package client
 
 import (
 	"context"
+	"net"
 
 	"github.com/panjf2000/gnet/v2"
 )
@@ -9,10 +10,42 @@ import (
 // HandlerFunc defines the function signature for handlers
 type HandlerFunc func(c gnet.Conn, data []byte) error
 
+// ResponseFunc receives the response to a tagged request, see messages.TagSelector. The data is
+// only valid during the call.
+type ResponseFunc func(id uint32, data []byte)
+
 // Transport interface defines the methods that all transports must implement
 type Transport interface {
 	Connect(ctx context.Context) error
 	Send(data []byte) error
 	Close() error
 	RegisterHandler(messageType MessageType, handler HandlerFunc)
+
+	// OnResponse sets the function receiving the responses to tagged requests. Untagged
+	// responses are still dispatched to the registered handlers.
+	OnResponse(fn ResponseFunc)
+}
+
+// RequestFailureFunc receives the error failing a tagged request the transport gave up on before
+// it was answered.
+type RequestFailureFunc func(id uint32, err error)
+
+// Failer is implemented by transports that may give up on a tagged request, e.g. once its
+// retransmissions are exhausted. OnRequestFailure sets the function failing the request at once
+// rather than leaving it waiting for its deadline.
+type Failer interface {
+	OnRequestFailure(fn RequestFailureFunc)
+}
+
+// Streamer is implemented by transports able to open a connection of its own to the server, with
+// the settings of the transport, for a request the server answers with a stream taking over the
+// connection, such as a change subscription.
+type Streamer interface {
+	OpenStream(ctx context.Context) (net.Conn, error)
+}
+
+// Canceler is implemented by transports tracking tagged requests until answered. Cancel is
+// called once nobody waits for the response to a request anymore, e.g. when its context expired.
+type Canceler interface {
+	Cancel(id uint32)
 }
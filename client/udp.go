@@ -0,0 +1,419 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/pion/dtls/v3"
+	"github.com/unpackdev/fdb/messages"
+	"go.uber.org/zap"
+)
+
+const (
+	// DefaultUDPRetransmitTimeout is how long a UDPTransport waits for a response before sending
+	// the request again for the first time.
+	DefaultUDPRetransmitTimeout = 100 * time.Millisecond
+
+	// DefaultUDPMaxBackoff caps the doubling wait between retransmissions.
+	DefaultUDPMaxBackoff = 2 * time.Second
+
+	// DefaultUDPMaxRetransmits is how many times a request is sent again before it is lost.
+	DefaultUDPMaxRetransmits = 5
+
+	// udpMaxDatagramSize bounds a single datagram received from the server.
+	udpMaxDatagramSize = 64 * 1024
+)
+
+// ErrLost is returned for tagged requests a UDPTransport gave up on after their last
+// retransmission. The server may still have applied them.
+var ErrLost = errors.New("request lost")
+
+// UDPOption configures a UDPTransport
+type UDPOption func(*UDPTransport)
+
+// WithUDPRetransmitTimeout sets how long to wait for a response before the first
+// retransmission, DefaultUDPRetransmitTimeout when not set. The wait doubles with every
+// retransmission.
+func WithUDPRetransmitTimeout(timeout time.Duration) UDPOption {
+	return func(t *UDPTransport) {
+		if timeout > 0 {
+			t.retransmitTimeout = timeout
+		}
+	}
+}
+
+// WithUDPMaxBackoff caps the wait between retransmissions, DefaultUDPMaxBackoff when not set.
+func WithUDPMaxBackoff(backoff time.Duration) UDPOption {
+	return func(t *UDPTransport) {
+		if backoff > 0 {
+			t.maxBackoff = backoff
+		}
+	}
+}
+
+// WithUDPMaxRetransmits sets how many times a request is sent again before it is counted as
+// lost, DefaultUDPMaxRetransmits when not set. Zero disables retransmissions.
+func WithUDPMaxRetransmits(retransmits int) UDPOption {
+	return func(t *UDPTransport) {
+		if retransmits >= 0 {
+			t.maxRetransmits = retransmits
+		}
+	}
+}
+
+// UDPStats describes the delivery of the tagged requests sent by a UDPTransport.
+type UDPStats struct {
+	// Sent is the number of requests sent, retransmissions excluded.
+	Sent uint64 `json:"sent"`
+
+	// Retransmits is the number of retransmissions.
+	Retransmits uint64 `json:"retransmits"`
+
+	// Responses is the number of requests answered.
+	Responses uint64 `json:"responses"`
+
+	// Duplicates is the number of responses dropped because their request was already answered
+	// or given up on.
+	Duplicates uint64 `json:"duplicates"`
+
+	// Lost is the number of requests given up on after their last retransmission, failed with
+	// ErrLost. Requests cancelled before, see Canceler, are not counted.
+	Lost uint64 `json:"lost"`
+
+	// Outstanding is the number of requests waiting for a response.
+	Outstanding int `json:"outstanding"`
+}
+
+// UDPTransport implements the Transport interface over UDP. Tagged requests are tracked by ID
+// until answered: requests left unanswered are sent again with an exponential backoff and failed
+// with ErrLost after the last retransmission, and responses arriving once their request was
+// answered are dropped. Requests should therefore be
+// idempotent, as reads, writes and deletes are.
+//
+// Untagged data is sent once and its responses are dispatched to the handlers with a nil
+// gnet.Conn.
+//
+// With WithUDPDTLS the datagrams are sent over a DTLS session, established again by the first
+// request sent after the server closed it.
+type UDPTransport struct {
+	address           string
+	retransmitTimeout time.Duration
+	maxBackoff        time.Duration
+	maxRetransmits    int
+	handlers          map[MessageType]HandlerFunc
+	onResponse        ResponseFunc
+	onRequestFailure  RequestFailureFunc
+	logger            *zap.Logger
+	dtlsConfig        *dtls.Config
+
+	// connMu guards the connection, replaced when a DTLS session is established again.
+	connMu sync.Mutex
+	conn   net.Conn
+
+	// mu guards outstanding and stats.
+	mu          sync.Mutex
+	outstanding map[uint32]*udpRequest
+	stats       UDPStats
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// udpRequest is a tagged request waiting for its response.
+type udpRequest struct {
+	frame       []byte
+	retransmits int
+	backoff     time.Duration
+	timer       *time.Timer
+}
+
+// NewUDPTransport creates a new UDPTransport sending to the server at address
+func NewUDPTransport(address string, logger *zap.Logger, opts ...UDPOption) *UDPTransport {
+	t := &UDPTransport{
+		address:           address,
+		retransmitTimeout: DefaultUDPRetransmitTimeout,
+		maxBackoff:        DefaultUDPMaxBackoff,
+		maxRetransmits:    DefaultUDPMaxRetransmits,
+		handlers:          make(map[MessageType]HandlerFunc),
+		outstanding:       make(map[uint32]*udpRequest),
+		logger:            logger,
+	}
+	for _, opt := range opts {
+		opt(t)
+	}
+	return t
+}
+
+// Connect creates the UDP socket, or establishes the DTLS session, and starts receiving
+// responses
+func (t *UDPTransport) Connect(ctx context.Context) error {
+	t.ctx, t.cancel = context.WithCancel(ctx)
+
+	t.connMu.Lock()
+	_, err := t.dial()
+	t.connMu.Unlock()
+	if err != nil {
+		t.cancel()
+		return err
+	}
+	return nil
+}
+
+// Send sends a message over UDP. Tagged requests are retransmitted until answered.
+func (t *UDPTransport) Send(data []byte) error {
+	if t.ctx == nil || t.ctx.Err() != nil {
+		return errors.New("no active connection")
+	}
+	if len(data) == 0 || data[0] != messages.TagSelector {
+		return t.write(data)
+	}
+
+	id, _, n, err := messages.SplitTagged(data)
+	if err != nil {
+		return err
+	}
+	if n != len(data) {
+		return errors.New("incomplete tagged request")
+	}
+
+	req := &udpRequest{frame: append([]byte(nil), data...), backoff: t.retransmitTimeout}
+	t.mu.Lock()
+	if previous, ok := t.outstanding[id]; ok {
+		previous.timer.Stop()
+	}
+	t.outstanding[id] = req
+	t.stats.Sent++
+	req.timer = time.AfterFunc(req.backoff, func() { t.retransmit(id, req) })
+	t.mu.Unlock()
+
+	if err := t.write(req.frame); err != nil {
+		t.forget(id, req)
+		return err
+	}
+	return nil
+}
+
+// Close closes the UDP socket, or the DTLS session. Outstanding requests are no longer
+// retransmitted.
+func (t *UDPTransport) Close() error {
+	if t.cancel == nil {
+		return nil
+	}
+	t.cancel()
+
+	t.connMu.Lock()
+	var err error
+	if t.conn != nil {
+		err = t.conn.Close()
+		t.conn = nil
+	}
+	t.connMu.Unlock()
+	t.wg.Wait()
+
+	t.mu.Lock()
+	for id, req := range t.outstanding {
+		req.timer.Stop()
+		delete(t.outstanding, id)
+	}
+	t.mu.Unlock()
+	return err
+}
+
+// RegisterHandler registers a handler for a specific message type
+func (t *UDPTransport) RegisterHandler(messageType MessageType, handler HandlerFunc) {
+	t.handlers[messageType] = handler
+}
+
+// OnResponse sets the function receiving the responses to tagged requests
+func (t *UDPTransport) OnResponse(fn ResponseFunc) {
+	t.onResponse = fn
+}
+
+// OnRequestFailure sets the function failing the tagged requests lost after their last
+// retransmission with ErrLost. It implements Failer.
+func (t *UDPTransport) OnRequestFailure(fn RequestFailureFunc) {
+	t.onRequestFailure = fn
+}
+
+// Cancel stops retransmitting a request nobody waits for anymore. It implements Canceler.
+func (t *UDPTransport) Cancel(id uint32) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if req, ok := t.outstanding[id]; ok {
+		req.timer.Stop()
+		delete(t.outstanding, id)
+	}
+}
+
+// Stats returns the delivery statistics of the tagged requests.
+func (t *UDPTransport) Stats() UDPStats {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	stats := t.stats
+	stats.Outstanding = len(t.outstanding)
+	return stats
+}
+
+// retransmit sends an unanswered request again, or gives up on it after the last
+// retransmission.
+func (t *UDPTransport) retransmit(id uint32, req *udpRequest) {
+	t.mu.Lock()
+	if t.outstanding[id] != req || t.ctx.Err() != nil {
+		t.mu.Unlock()
+		return
+	}
+	if req.retransmits >= t.maxRetransmits {
+		delete(t.outstanding, id)
+		t.stats.Lost++
+		t.mu.Unlock()
+		t.logger.Debug("Request lost", zap.Uint32("id", id), zap.Int("retransmits", req.retransmits))
+		if t.onRequestFailure != nil {
+			t.onRequestFailure(id, fmt.Errorf("%w after %d retransmissions", ErrLost, req.retransmits))
+		}
+		return
+	}
+
+	req.retransmits++
+	req.backoff = min(req.backoff*2, t.maxBackoff)
+	req.timer.Reset(req.backoff)
+	t.stats.Retransmits++
+	t.mu.Unlock()
+
+	if err := t.write(req.frame); err != nil {
+		t.logger.Debug("Failed to retransmit request", zap.Uint32("id", id), zap.Error(err))
+	}
+}
+
+// forget stops tracking a request.
+func (t *UDPTransport) forget(id uint32, req *udpRequest) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.outstanding[id] == req {
+		req.timer.Stop()
+		delete(t.outstanding, id)
+	}
+}
+
+// read dispatches the datagrams received until the socket, or the DTLS session, is closed.
+func (t *UDPTransport) read(conn net.Conn) {
+	defer t.wg.Done()
+
+	buf := make([]byte, udpMaxDatagramSize)
+	for {
+		n, err := conn.Read(buf)
+		if err != nil {
+			if t.ctx.Err() != nil || errors.Is(err, net.ErrClosed) {
+				return
+			}
+			if t.dtlsConfig != nil {
+				// The session cannot be resumed, the next request establishes a new one
+				t.logger.Debug("DTLS session closed", zap.Error(err))
+				t.connMu.Lock()
+				if t.conn == conn {
+					_ = conn.Close()
+					t.conn = nil
+				}
+				t.connMu.Unlock()
+				return
+			}
+			// Errors such as refused datagrams are reported on later reads, retransmissions
+			// take care of the requests
+			t.logger.Debug("Error reading datagram", zap.Error(err))
+			continue
+		}
+		if n == 0 {
+			continue
+		}
+		data := buf[:n]
+
+		if data[0] == messages.TagSelector {
+			t.receiveTagged(data)
+			continue
+		}
+
+		messageType := MessageType(data[0])
+		if handler, exists := t.handlers[messageType]; exists {
+			if err := handler(nil, data[1:]); err != nil {
+				t.logger.Error("Handler error", zap.Error(err))
+			}
+		} else {
+			t.logger.Warn("No handler for message type", zap.Uint64("type", messageType.Uint64()))
+		}
+	}
+}
+
+// receiveTagged delivers the response to an outstanding request, dropping duplicates.
+func (t *UDPTransport) receiveTagged(data []byte) {
+	id, resp, n, err := messages.SplitTagged(data)
+	if err != nil || n != len(data) {
+		t.logger.Warn("Invalid tagged response", zap.Error(err))
+		return
+	}
+
+	t.mu.Lock()
+	req, ok := t.outstanding[id]
+	if ok {
+		req.timer.Stop()
+		delete(t.outstanding, id)
+		t.stats.Responses++
+	} else {
+		t.stats.Duplicates++
+	}
+	t.mu.Unlock()
+
+	if ok && t.onResponse != nil {
+		t.onResponse(id, resp)
+	}
+}
+
+// write sends a datagram, establishing the DTLS session again when the server closed it.
+func (t *UDPTransport) write(frame []byte) error {
+	t.connMu.Lock()
+	conn := t.conn
+	if conn == nil {
+		if t.ctx.Err() != nil {
+			t.connMu.Unlock()
+			return errors.New("no active connection")
+		}
+		var err error
+		if conn, err = t.dial(); err != nil {
+			t.connMu.Unlock()
+			return err
+		}
+	}
+	t.connMu.Unlock()
+
+	_, err := conn.Write(frame)
+	return err
+}
+
+// dial creates the UDP socket, or establishes the DTLS session, and starts reading from it.
+// Must be called with connMu held.
+func (t *UDPTransport) dial() (net.Conn, error) {
+	addr, err := net.ResolveUDPAddr("udp", t.address)
+	if err != nil {
+		return nil, err
+	}
+
+	var conn net.Conn
+	if t.dtlsConfig != nil {
+		if conn, err = dialDTLS(t.ctx, addr, t.dtlsConfig); err != nil {
+			return nil, err
+		}
+	} else if conn, err = net.DialUDP("udp", nil, addr); err != nil {
+		return nil, err
+	}
+
+	t.conn = conn
+	t.wg.Add(1)
+	go t.read(conn)
+	return conn, nil
+}
@@ -0,0 +1,155 @@
+package client_test
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/unpackdev/fdb/client"
+	"github.com/unpackdev/fdb/fdbtest"
+	"github.com/unpackdev/fdb/types"
+	"go.uber.org/zap"
+)
+
+// lossyProxy relays datagrams between a client and a server, dropping the requests selected by
+// drop and sending every response twice.
+type lossyProxy struct {
+	listener *net.UDPConn
+	upstream *net.UDPConn
+
+	mu       sync.Mutex
+	client   *net.UDPAddr
+	requests int
+}
+
+func startLossyProxy(t *testing.T, server string, drop func(request int) bool) *lossyProxy {
+	listener, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.ParseIP("127.0.0.1")})
+	require.NoError(t, err)
+	addr, err := net.ResolveUDPAddr("udp", server)
+	require.NoError(t, err)
+	upstream, err := net.DialUDP("udp", nil, addr)
+	require.NoError(t, err)
+
+	p := &lossyProxy{listener: listener, upstream: upstream}
+	t.Cleanup(func() {
+		_ = listener.Close()
+		_ = upstream.Close()
+	})
+
+	go func() {
+		buf := make([]byte, 64*1024)
+		for {
+			n, from, err := listener.ReadFromUDP(buf)
+			if err != nil {
+				return
+			}
+			p.mu.Lock()
+			p.client = from
+			p.requests++
+			dropped := drop(p.requests)
+			p.mu.Unlock()
+			if !dropped {
+				_, _ = upstream.Write(buf[:n])
+			}
+		}
+	}()
+	go func() {
+		buf := make([]byte, 64*1024)
+		for {
+			n, err := upstream.Read(buf)
+			if err != nil {
+				return
+			}
+			p.mu.Lock()
+			to := p.client
+			p.mu.Unlock()
+			for i := 0; i < 2; i++ {
+				_, _ = listener.WriteToUDP(buf[:n], to)
+			}
+		}
+	}()
+	return p
+}
+
+func (p *lossyProxy) Addr() string {
+	return p.listener.LocalAddr().String()
+}
+
+func TestUDPTransportRetransmitsAndDeduplicates(t *testing.T) {
+	server := fdbtest.Start(t, fdbtest.WithTransports(types.UDPTransportType))
+	provider, addr := server.Provider(t, fdbtest.DefaultDatabase), server.Addr(types.UDPTransportType)
+	proxy := startLossyProxy(t, addr, func(request int) bool { return request%2 == 1 })
+
+	transport := client.NewUDPTransport(proxy.Addr(), zap.NewNop(), client.WithUDPRetransmitTimeout(20*time.Millisecond))
+	cfg := client.NewConfig()
+	cfg.Database = "fdb"
+	c := client.NewClient(context.Background(), cfg)
+	require.NoError(t, c.RegisterTransport("udp", transport))
+	require.NoError(t, c.Start(context.Background()))
+	t.Cleanup(func() { _ = c.Close() })
+
+	const keys = 20
+	for i := 0; i < keys; i++ {
+		key := testKey(i)
+		require.NoError(t, provider.Set(key[:], []byte(fmt.Sprintf("value-%d", i))))
+	}
+
+	// Every other datagram is dropped on its way to the server, retransmissions get through
+	ctx := context.Background()
+	for i := 0; i < keys; i++ {
+		value, err := c.Get(ctx, testKey(i))
+		require.NoError(t, err)
+		assert.Equal(t, fmt.Sprintf("value-%d", i), string(value))
+	}
+	require.NoError(t, c.Set(ctx, testKey(keys), []byte("value")))
+	require.NoError(t, c.Delete(ctx, testKey(0)))
+
+	// The second copy of every response is dropped
+	require.Eventually(t, func() bool {
+		return transport.Stats().Duplicates >= keys+2
+	}, time.Second, 10*time.Millisecond)
+
+	stats := transport.Stats()
+	assert.Equal(t, uint64(keys+2), stats.Sent)
+	assert.Equal(t, uint64(keys+2), stats.Responses)
+	assert.GreaterOrEqual(t, stats.Retransmits, uint64(keys+2))
+	assert.Zero(t, stats.Lost)
+	assert.Zero(t, stats.Outstanding)
+}
+
+func TestUDPTransportFailsLostRequests(t *testing.T) {
+	// Every datagram is dropped on its way to the server, every request is lost
+	addr := fdbtest.Start(t, fdbtest.WithTransports(types.UDPTransportType)).Addr(types.UDPTransportType)
+	proxy := startLossyProxy(t, addr, func(int) bool { return true })
+
+	transport := client.NewUDPTransport(proxy.Addr(), zap.NewNop(),
+		client.WithUDPRetransmitTimeout(5*time.Millisecond),
+		client.WithUDPMaxBackoff(10*time.Millisecond),
+		client.WithUDPMaxRetransmits(2),
+	)
+	cfg := client.NewConfig()
+	cfg.Database = "fdb"
+	c := client.NewClient(context.Background(), cfg)
+	require.NoError(t, c.RegisterTransport("udp", transport))
+	require.NoError(t, c.Start(context.Background()))
+	t.Cleanup(func() { _ = c.Close() })
+
+	// The request fails once its last retransmission is unanswered, long before its deadline
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	started := time.Now()
+	_, err := c.Get(ctx, testKey(1))
+	assert.ErrorIs(t, err, client.ErrLost)
+	assert.Less(t, time.Since(started), time.Second)
+
+	stats := transport.Stats()
+	assert.Equal(t, uint64(1), stats.Sent)
+	assert.Equal(t, uint64(2), stats.Retransmits)
+	assert.Equal(t, uint64(1), stats.Lost)
+	assert.Zero(t, stats.Outstanding)
+}
@@ -0,0 +1,289 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/unpackdev/fdb/messages"
+	"go.uber.org/zap"
+)
+
+// DefaultUDSReconnectInterval is how often a UDSTransport checks its socket file and retries a
+// lost connection.
+const DefaultUDSReconnectInterval = 500 * time.Millisecond
+
+// udsMaxDatagramSize bounds a single datagram received from the server.
+const udsMaxDatagramSize = 64 * 1024
+
+// udsLocalSockets numbers the local sockets of datagram transports.
+var udsLocalSockets atomic.Uint64
+
+// UDSOption configures a UDSTransport
+type UDSOption func(*UDSTransport)
+
+// WithUDSDatagram makes the transport use a datagram socket, the DatagramSocket of the server.
+// Each request and response is a single datagram, received on a socket the transport binds in
+// the temporary directory.
+func WithUDSDatagram() UDSOption {
+	return func(t *UDSTransport) {
+		t.datagram = true
+	}
+}
+
+// WithUDSReconnectInterval sets how often the transport checks its socket file and retries a
+// lost connection, DefaultUDSReconnectInterval when not set.
+func WithUDSReconnectInterval(interval time.Duration) UDSOption {
+	return func(t *UDSTransport) {
+		if interval > 0 {
+			t.reconnectInterval = interval
+		}
+	}
+}
+
+// UDSTransport implements the Transport interface over a Unix Domain Socket. The connection is
+// re-established when it is lost or when the socket file is recreated, e.g. by a restarted
+// server.
+//
+// Handlers registered for untagged responses receive a nil gnet.Conn.
+type UDSTransport struct {
+	path              string
+	datagram          bool
+	reconnectInterval time.Duration
+	handlers          map[MessageType]HandlerFunc
+	onResponse        ResponseFunc
+	logger            *zap.Logger
+
+	// mu guards the connection and the socket file it was established to.
+	mu     sync.Mutex
+	conn   *net.UnixConn
+	socket os.FileInfo
+
+	// local is the path of the socket datagrams are received on, empty for streams.
+	local string
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewUDSTransport creates a new UDSTransport connecting to the socket at path
+func NewUDSTransport(path string, logger *zap.Logger, opts ...UDSOption) *UDSTransport {
+	t := &UDSTransport{
+		path:              path,
+		reconnectInterval: DefaultUDSReconnectInterval,
+		handlers:          make(map[MessageType]HandlerFunc),
+		logger:            logger,
+	}
+	for _, opt := range opts {
+		opt(t)
+	}
+	return t
+}
+
+// Connect establishes the UDS connection and starts watching the socket file
+func (t *UDSTransport) Connect(ctx context.Context) error {
+	t.ctx, t.cancel = context.WithCancel(ctx)
+
+	if t.datagram {
+		t.local = filepath.Join(os.TempDir(), fmt.Sprintf("fdb-client-%d-%d.sock", os.Getpid(), udsLocalSockets.Add(1)))
+	}
+
+	t.mu.Lock()
+	err := t.dial()
+	t.mu.Unlock()
+	if err != nil {
+		t.cancel()
+		return err
+	}
+
+	t.wg.Add(1)
+	go t.watch()
+	return nil
+}
+
+// Send sends a message over the UDS connection, reconnecting first if the connection was lost
+func (t *UDSTransport) Send(data []byte) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.ctx == nil || t.ctx.Err() != nil {
+		return errors.New("transport is closed")
+	}
+	if t.conn == nil {
+		if err := t.dial(); err != nil {
+			return fmt.Errorf("no active connection: %w", err)
+		}
+	}
+
+	if _, err := t.conn.Write(data); err != nil {
+		t.disconnect()
+		return err
+	}
+	return nil
+}
+
+// Close closes the UDS connection
+func (t *UDSTransport) Close() error {
+	if t.cancel == nil {
+		return nil
+	}
+	t.cancel()
+
+	t.mu.Lock()
+	t.disconnect()
+	t.mu.Unlock()
+	t.wg.Wait()
+
+	if t.local != "" {
+		if err := os.Remove(t.local); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// RegisterHandler registers a handler for a specific message type
+func (t *UDSTransport) RegisterHandler(messageType MessageType, handler HandlerFunc) {
+	t.handlers[messageType] = handler
+}
+
+// OnResponse sets the function receiving the responses to tagged requests
+func (t *UDSTransport) OnResponse(fn ResponseFunc) {
+	t.onResponse = fn
+}
+
+// dial connects to the socket and starts reading from the connection. Must be called with mu
+// held.
+func (t *UDSTransport) dial() error {
+	socket, err := os.Stat(t.path)
+	if err != nil {
+		return err
+	}
+
+	var conn *net.UnixConn
+	if t.datagram {
+		// The server answers to the address of the local socket, left behind by a previous
+		// connection of this transport
+		if err := os.Remove(t.local); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		conn, err = net.DialUnix("unixgram", &net.UnixAddr{Name: t.local, Net: "unixgram"}, &net.UnixAddr{Name: t.path, Net: "unixgram"})
+	} else {
+		conn, err = net.DialUnix("unix", nil, &net.UnixAddr{Name: t.path, Net: "unix"})
+	}
+	if err != nil {
+		return err
+	}
+
+	t.conn = conn
+	t.socket = socket
+	t.logger.Info("Connected to server", zap.String("socket", t.path), zap.Bool("datagram", t.datagram))
+
+	t.wg.Add(1)
+	go t.read(conn)
+	return nil
+}
+
+// disconnect closes the connection, if any. Must be called with mu held.
+func (t *UDSTransport) disconnect() {
+	if t.conn != nil {
+		_ = t.conn.Close()
+		t.conn = nil
+		t.socket = nil
+	}
+}
+
+// watch re-establishes the connection when it is lost or when the socket file is replaced,
+// until the transport is closed.
+func (t *UDSTransport) watch() {
+	defer t.wg.Done()
+
+	ticker := time.NewTicker(t.reconnectInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-t.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		t.mu.Lock()
+		if t.ctx.Err() == nil {
+			if t.conn != nil {
+				// A recreated socket file belongs to a new listener, the connection is stale
+				if current, err := os.Stat(t.path); err == nil && !os.SameFile(current, t.socket) {
+					t.logger.Info("Socket file recreated, reconnecting", zap.String("socket", t.path))
+					t.disconnect()
+				}
+			}
+			if t.conn == nil {
+				if err := t.dial(); err != nil {
+					t.logger.Debug("Failed to reconnect", zap.String("socket", t.path), zap.Error(err))
+				}
+			}
+		}
+		t.mu.Unlock()
+	}
+}
+
+// read dispatches the responses received on the connection until it is closed.
+func (t *UDSTransport) read(conn *net.UnixConn) {
+	defer t.wg.Done()
+
+	var tagged taggedReceiver
+	buf := make([]byte, udsMaxDatagramSize)
+	for {
+		n, err := conn.Read(buf)
+		if err != nil {
+			t.mu.Lock()
+			if t.conn == conn {
+				t.logger.Info("Connection closed", zap.Error(err))
+				t.disconnect()
+			}
+			t.mu.Unlock()
+			return
+		}
+
+		// Every datagram is a whole response
+		if t.datagram {
+			tagged.reset()
+		}
+		if n == 0 {
+			continue
+		}
+		data := buf[:n]
+
+		if tagged.pending() || data[0] == messages.TagSelector {
+			if err := tagged.receive(data, t.onResponse); err != nil {
+				t.logger.Error("Invalid tagged response", zap.Error(err))
+				// The stream cannot be resynchronised
+				if !t.datagram {
+					t.mu.Lock()
+					if t.conn == conn {
+						t.disconnect()
+					}
+					t.mu.Unlock()
+					return
+				}
+			}
+			continue
+		}
+
+		messageType := MessageType(data[0])
+		if handler, exists := t.handlers[messageType]; exists {
+			if err := handler(nil, data[1:]); err != nil {
+				t.logger.Error("Handler error", zap.Error(err))
+			}
+		} else {
+			t.logger.Warn("No handler for message type", zap.Uint64("type", messageType.Uint64()))
+		}
+	}
+}
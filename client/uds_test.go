@@ -0,0 +1,110 @@
+package client_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/unpackdev/fdb/client"
+	"github.com/unpackdev/fdb/config"
+	"github.com/unpackdev/fdb/fdbtest"
+	"github.com/unpackdev/fdb/types"
+	"go.uber.org/zap"
+)
+
+// socketDir returns a directory for the sockets of a test. The UDS server lowercases its socket
+// path, so t.TempDir, named after the test, cannot be used.
+func socketDir(t *testing.T) string {
+	dir, err := os.MkdirTemp("", "fdb-uds")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = os.RemoveAll(dir) })
+	return dir
+}
+
+// newUDSClient connects a client to the socket at path over UDS.
+func newUDSClient(t *testing.T, path string, opts ...client.UDSOption) *client.Client {
+	cfg := client.NewConfig()
+	cfg.Database = "fdb"
+	c := client.NewClient(context.Background(), cfg)
+	require.NoError(t, c.RegisterTransport("uds", client.NewUDSTransport(path, zap.NewNop(), opts...)))
+	require.NoError(t, c.Start(context.Background()))
+	t.Cleanup(func() { _ = c.Close() })
+	return c
+}
+
+func TestUDSTransportStreamAndDatagram(t *testing.T) {
+	datagramSocket := filepath.Join(socketDir(t), "fdb.dgram.sock")
+	server := fdbtest.Start(t,
+		fdbtest.WithTransports(types.UDSTransportType),
+		fdbtest.WithConfig(func(cfg *config.Config) {
+			cfg.GetTransportByType(types.UDSTransportType).Config.(*config.UdsTransport).DatagramSocket = datagramSocket
+		}),
+	)
+
+	clients := map[string]*client.Client{
+		"stream":   newUDSClient(t, server.Addr(types.UDSTransportType)),
+		"datagram": newUDSClient(t, datagramSocket, client.WithUDSDatagram()),
+	}
+	ctx := context.Background()
+
+	for name, c := range clients {
+		t.Run(name, func(t *testing.T) {
+			key, missing := testKey(len(name)), testKey(100+len(name))
+			require.NoError(t, c.Set(ctx, key, []byte("value-"+name)))
+
+			// Writes are acknowledged once buffered and visible once flushed
+			require.Eventually(t, func() bool {
+				value, err := c.Get(ctx, key)
+				return err == nil && string(value) == "value-"+name
+			}, 5*time.Second, 20*time.Millisecond)
+
+			values, err := c.MGet(ctx, key, missing)
+			require.NoError(t, err)
+			assert.Equal(t, [][]byte{[]byte("value-" + name), nil}, values)
+
+			require.NoError(t, c.Delete(ctx, key))
+			require.Eventually(t, func() bool {
+				exists, err := c.Exists(ctx, key)
+				return err == nil && !exists
+			}, 5*time.Second, 20*time.Millisecond)
+		})
+	}
+}
+
+func TestUDSTransportReconnects(t *testing.T) {
+	// Both instances serve the same socket
+	socket := filepath.Join(socketDir(t), "fdb.sock")
+	onSocket := fdbtest.WithConfig(func(cfg *config.Config) {
+		cfg.GetTransportByType(types.UDSTransportType).Config.(*config.UdsTransport).Socket = socket
+	})
+	first := fdbtest.Start(t, fdbtest.WithTransports(types.UDSTransportType), onSocket)
+
+	c := newUDSClient(t, socket, client.WithUDSReconnectInterval(50*time.Millisecond))
+	ctx := context.Background()
+
+	key := testKey(1)
+	require.NoError(t, first.Provider(t, fdbtest.DefaultDatabase).Set(key[:], []byte("value-1")))
+	value, err := c.Get(ctx, key)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("value-1"), value)
+
+	// A restarted server recreates the socket file, the client follows it even while the
+	// connection to the previous server is still open
+	require.NoError(t, first.Stop())
+	second := fdbtest.Start(t, fdbtest.WithTransports(types.UDSTransportType), onSocket)
+	require.NoError(t, second.Provider(t, fdbtest.DefaultDatabase).Set(key[:], []byte("value-1")))
+
+	require.Eventually(t, func() bool {
+		reqCtx, cancel := context.WithTimeout(ctx, 200*time.Millisecond)
+		defer cancel()
+		value, err := c.Get(reqCtx, key)
+		return err == nil && string(value) == "value-1"
+	}, 5*time.Second, 50*time.Millisecond)
+
+	require.NoError(t, c.Set(ctx, testKey(2), []byte(fmt.Sprintf("value-%d", 2))))
+}
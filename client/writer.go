@@ -0,0 +1,367 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/unpackdev/fdb/messages"
+	"github.com/unpackdev/fdb/types"
+)
+
+const (
+	// DefaultWriterBatchSize is the number of writes sent in a multi-set frame.
+	DefaultWriterBatchSize = 1024
+
+	// DefaultWriterBatchBytes bounds the encoded writes of a multi-set frame.
+	DefaultWriterBatchBytes = 1 << 20
+
+	// DefaultWriterFlushInterval is how long writes wait for their batch to fill up.
+	DefaultWriterFlushInterval = 10 * time.Millisecond
+
+	// DefaultWriterMaxBuffered bounds the encoded writes a Writer holds until acknowledged.
+	DefaultWriterMaxBuffered = 64 << 20
+
+	// DefaultWriterConcurrency is the number of batches in flight.
+	DefaultWriterConcurrency = 4
+)
+
+var (
+	// ErrWriterFull is reported for writes dropped because the Writer held too many writes
+	ErrWriterFull = errors.New("writer buffer is full")
+
+	// ErrWriterClosed is returned when writing to a closed Writer
+	ErrWriterClosed = errors.New("writer is closed")
+)
+
+// FailureFunc receives the writes of a batch the server did not acknowledge, or of writes
+// dropped with ErrWriterFull. The entries must not be modified.
+type FailureFunc func(entries []messages.MultiSetEntry, err error)
+
+// WriterOption configures a Writer
+type WriterOption func(*Writer)
+
+// WithWriterBatchSize sets the number of writes per batch, DefaultWriterBatchSize when not set.
+func WithWriterBatchSize(size int) WriterOption {
+	return func(w *Writer) {
+		if size > 0 {
+			w.batchSize = size
+		}
+	}
+}
+
+// WithWriterBatchBytes bounds the encoded writes of a batch, DefaultWriterBatchBytes when not
+// set. Datagram transports need batches fitting a datagram.
+func WithWriterBatchBytes(size int) WriterOption {
+	return func(w *Writer) {
+		if size > 0 {
+			w.batchBytes = size
+		}
+	}
+}
+
+// WithWriterFlushInterval sets how long writes wait for their batch to fill up,
+// DefaultWriterFlushInterval when not set.
+func WithWriterFlushInterval(interval time.Duration) WriterOption {
+	return func(w *Writer) {
+		if interval > 0 {
+			w.flushInterval = interval
+		}
+	}
+}
+
+// WithWriterMaxBuffered bounds the encoded writes held until acknowledged,
+// DefaultWriterMaxBuffered when not set. Writes beyond the bound block, or are dropped with
+// WithWriterDropWhenFull.
+func WithWriterMaxBuffered(size int) WriterOption {
+	return func(w *Writer) {
+		if size > 0 {
+			w.maxBuffered = size
+		}
+	}
+}
+
+// WithWriterConcurrency sets the number of batches in flight, DefaultWriterConcurrency when not
+// set.
+func WithWriterConcurrency(concurrency int) WriterOption {
+	return func(w *Writer) {
+		if concurrency > 0 {
+			w.concurrency = concurrency
+		}
+	}
+}
+
+// WithWriterDropWhenFull drops the writes exceeding the buffer instead of blocking, reporting
+// them to the failure handler with ErrWriterFull.
+func WithWriterDropWhenFull() WriterOption {
+	return func(w *Writer) {
+		w.dropWhenFull = true
+	}
+}
+
+// WithWriterFailureHandler sets the function receiving the failed and dropped writes.
+func WithWriterFailureHandler(fn FailureFunc) WriterOption {
+	return func(w *Writer) {
+		w.onFailure = fn
+	}
+}
+
+// WriterStats describes the writes handled by a Writer.
+type WriterStats struct {
+	// Written is the number of writes acknowledged by the server.
+	Written uint64 `json:"written"`
+
+	// Batches is the number of multi-set frames acknowledged by the server.
+	Batches uint64 `json:"batches"`
+
+	// Failed is the number of writes of the batches the server did not acknowledge.
+	Failed uint64 `json:"failed"`
+
+	// Dropped is the number of writes dropped because the buffer was full.
+	Dropped uint64 `json:"dropped"`
+
+	// Buffered is the size of the encoded writes waiting for their acknowledgement.
+	Buffered int `json:"buffered"`
+}
+
+// Writer sends writes without waiting for them: writes are gathered into multi-set frames,
+// sent once a batch fills up or the flush interval elapses, through the write group or the
+// default transport of the client. Batches the server did not acknowledge are reported to the
+// failure handler.
+//
+// The writes held until acknowledged are bounded, see WithWriterMaxBuffered, so a slow server
+// slows the writers down instead of exhausting memory.
+type Writer struct {
+	client        *Client
+	batchSize     int
+	batchBytes    int
+	flushInterval time.Duration
+	maxBuffered   int
+	concurrency   int
+	dropWhenFull  bool
+	onFailure     FailureFunc
+
+	// mu guards the fields below, cond is signalled on any change of them.
+	mu           sync.Mutex
+	cond         *sync.Cond
+	current      []messages.MultiSetEntry
+	currentBytes int
+	queue        [][]messages.MultiSetEntry
+	buffered     int
+	inFlight     int
+	closed       bool
+	stats        WriterStats
+
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewWriter creates a Writer sending its batches through the client
+//
+// Example usage:
+//
+//	w := c.NewWriter(client.WithWriterFailureHandler(func(entries []messages.MultiSetEntry, err error) {
+//	    log.Printf("Lost %d writes: %v", len(entries), err)
+//	}))
+//	defer w.Close()
+//
+//	for _, event := range events {
+//	    _ = w.Write(event.Key, event.Value)
+//	}
+//
+// Parameters:
+//
+//	opts (...WriterOption): The batching, the buffer bound and the failure handler.
+//
+// Returns:
+//
+//	*Writer: A new Writer sending batches until closed.
+func (c *Client) NewWriter(opts ...WriterOption) *Writer {
+	w := &Writer{
+		client:        c,
+		batchSize:     DefaultWriterBatchSize,
+		batchBytes:    DefaultWriterBatchBytes,
+		flushInterval: DefaultWriterFlushInterval,
+		maxBuffered:   DefaultWriterMaxBuffered,
+		concurrency:   DefaultWriterConcurrency,
+		stop:          make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(w)
+	}
+	w.cond = sync.NewCond(&w.mu)
+
+	w.wg.Add(w.concurrency + 1)
+	for i := 0; i < w.concurrency; i++ {
+		go w.send()
+	}
+	go w.tick()
+	return w
+}
+
+// Write queues a write of a key. It returns once the write is buffered, blocking while the
+// buffer is full unless WithWriterDropWhenFull is set.
+func (w *Writer) Write(key [32]byte, value []byte) error {
+	if len(value) == 0 {
+		return errors.New("writes require a value")
+	}
+	entry := messages.MultiSetEntry{Key: key, Value: append([]byte(nil), value...)}
+	size := messages.MultiSetEntryOverhead + len(value)
+
+	w.mu.Lock()
+	// A write larger than the buffer is accepted once the buffer is empty
+	for !w.closed && w.buffered > 0 && w.buffered+size > w.maxBuffered {
+		if w.dropWhenFull {
+			w.stats.Dropped++
+			w.mu.Unlock()
+			w.fail([]messages.MultiSetEntry{entry}, ErrWriterFull)
+			return nil
+		}
+		w.cond.Wait()
+	}
+	if w.closed {
+		w.mu.Unlock()
+		return ErrWriterClosed
+	}
+
+	w.current = append(w.current, entry)
+	w.currentBytes += size
+	w.buffered += size
+	if len(w.current) >= w.batchSize || w.currentBytes >= w.batchBytes {
+		w.seal()
+	}
+	w.mu.Unlock()
+	return nil
+}
+
+// Flush sends the buffered writes and waits until the server answered all of them or ctx is
+// done.
+func (w *Writer) Flush(ctx context.Context) error {
+	stop := context.AfterFunc(ctx, func() {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		w.cond.Broadcast()
+	})
+	defer stop()
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.seal()
+	for (len(w.queue) > 0 || w.inFlight > 0) && ctx.Err() == nil {
+		w.cond.Wait()
+	}
+	return ctx.Err()
+}
+
+// Close flushes the buffered writes and stops the Writer. Writes after Close fail with
+// ErrWriterClosed.
+func (w *Writer) Close() error {
+	err := w.Flush(context.Background())
+
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return err
+	}
+	// Writes that raced with the flush are sent before the senders stop
+	w.closed = true
+	w.seal()
+	w.cond.Broadcast()
+	w.mu.Unlock()
+
+	close(w.stop)
+	w.wg.Wait()
+	return err
+}
+
+// Stats returns the writes handled so far.
+func (w *Writer) Stats() WriterStats {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	stats := w.stats
+	stats.Buffered = w.buffered
+	return stats
+}
+
+// seal queues the current batch for sending. The caller holds mu.
+func (w *Writer) seal() {
+	if len(w.current) == 0 {
+		return
+	}
+	w.queue = append(w.queue, w.current)
+	w.current = nil
+	w.currentBytes = 0
+	w.cond.Broadcast()
+}
+
+// send sends the queued batches until the Writer is closed.
+func (w *Writer) send() {
+	defer w.wg.Done()
+
+	for {
+		w.mu.Lock()
+		for len(w.queue) == 0 && !w.closed {
+			w.cond.Wait()
+		}
+		if len(w.queue) == 0 {
+			w.mu.Unlock()
+			return
+		}
+		batch := w.queue[0]
+		w.queue = w.queue[1:]
+		w.inFlight++
+		w.mu.Unlock()
+
+		resp, err := w.client.send(context.Background(), types.MultiSetHandlerType, messages.EncodeMultiSet(batch))
+		if err == nil {
+			err = statusResponse(resp)
+		}
+
+		w.mu.Lock()
+		w.inFlight--
+		for _, entry := range batch {
+			w.buffered -= messages.MultiSetEntryOverhead + len(entry.Value)
+		}
+		if err != nil {
+			w.stats.Failed += uint64(len(batch))
+		} else {
+			w.stats.Written += uint64(len(batch))
+			w.stats.Batches++
+		}
+		w.cond.Broadcast()
+		w.mu.Unlock()
+
+		if err != nil {
+			w.fail(batch, err)
+		}
+	}
+}
+
+// tick seals the current batch every flush interval until the Writer is closed.
+func (w *Writer) tick() {
+	defer w.wg.Done()
+
+	ticker := time.NewTicker(w.flushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-w.stop:
+			return
+		case <-ticker.C:
+			w.mu.Lock()
+			w.seal()
+			w.mu.Unlock()
+		}
+	}
+}
+
+// fail reports writes to the failure handler.
+func (w *Writer) fail(entries []messages.MultiSetEntry, err error) {
+	if w.onFailure != nil {
+		w.onFailure(entries, err)
+	}
+}
@@ -0,0 +1,119 @@
+package client_test
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/unpackdev/fdb/client"
+	"github.com/unpackdev/fdb/fdbtest"
+	"github.com/unpackdev/fdb/messages"
+	"github.com/unpackdev/fdb/types"
+	"go.uber.org/zap"
+)
+
+func TestWriterBatchesWrites(t *testing.T) {
+	for _, transport := range []types.TransportType{types.TCPTransportType, types.QUICTransportType, types.UDSTransportType} {
+		t.Run(transport.String(), func(t *testing.T) {
+			server := fdbtest.Start(t, fdbtest.WithTransports(transport))
+			provider := server.Provider(t, fdbtest.DefaultDatabase)
+			c := server.Client(t, transport)
+
+			w := c.NewWriter(client.WithWriterBatchSize(100), client.WithWriterFailureHandler(func(entries []messages.MultiSetEntry, err error) {
+				t.Errorf("%d writes failed: %v", len(entries), err)
+			}))
+
+			const keys = 2000
+			for i := 0; i < keys; i++ {
+				require.NoError(t, w.Write(testKey(i), []byte(fmt.Sprintf("value-%d", i))))
+			}
+			require.NoError(t, w.Close())
+			assert.ErrorIs(t, w.Write(testKey(0), []byte("late")), client.ErrWriterClosed)
+
+			stats := w.Stats()
+			assert.Equal(t, uint64(keys), stats.Written)
+			assert.GreaterOrEqual(t, stats.Batches, uint64(keys/100))
+			assert.Less(t, stats.Batches, uint64(keys))
+			assert.Zero(t, stats.Buffered)
+
+			// The server buffers the writes of the batches before they become visible
+			require.Eventually(t, func() bool {
+				key := testKey(keys - 1)
+				value, err := provider.Get(key[:])
+				return err == nil && string(value) == fmt.Sprintf("value-%d", keys-1)
+			}, 5*time.Second, 20*time.Millisecond)
+			for i := 0; i < keys; i += 97 {
+				key := testKey(i)
+				value, err := provider.Get(key[:])
+				require.NoError(t, err)
+				assert.Equal(t, fmt.Sprintf("value-%d", i), string(value))
+			}
+		})
+	}
+}
+
+func TestWriterReportsFailedAndDroppedWrites(t *testing.T) {
+	// The server accepts connections and never answers, every batch times out
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			t.Cleanup(func() { _ = conn.Close() })
+		}
+	}()
+
+	cfg := client.NewConfig()
+	cfg.RequestTimeout = 100 * time.Millisecond
+	c := client.NewClient(context.Background(), cfg)
+	require.NoError(t, c.RegisterTransport("tcp", client.NewTCPTransport(listener.Addr().String(), zap.NewNop())))
+	require.NoError(t, c.Start(context.Background()))
+	t.Cleanup(func() { _ = c.Close() })
+
+	var (
+		mu      sync.Mutex
+		failed  int
+		dropped int
+	)
+	value := []byte("value")
+	entrySize := messages.MultiSetEntryOverhead + len(value)
+	w := c.NewWriter(
+		client.WithWriterBatchSize(5),
+		client.WithWriterMaxBuffered(10*entrySize),
+		client.WithWriterDropWhenFull(),
+		client.WithWriterFailureHandler(func(entries []messages.MultiSetEntry, err error) {
+			mu.Lock()
+			defer mu.Unlock()
+			if assert.Error(t, err) && err == client.ErrWriterFull {
+				dropped += len(entries)
+			} else {
+				failed += len(entries)
+			}
+		}),
+	)
+
+	const keys = 100
+	for i := 0; i < keys; i++ {
+		require.NoError(t, w.Write(testKey(i), value))
+	}
+	require.NoError(t, w.Close())
+
+	stats := w.Stats()
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, keys, failed+dropped)
+	assert.Equal(t, uint64(failed), stats.Failed)
+	assert.Equal(t, uint64(dropped), stats.Dropped)
+	assert.NotZero(t, dropped)
+	assert.Zero(t, stats.Written)
+	assert.Zero(t, stats.Buffered)
+}
@@ -8,6 +8,9 @@ import (
 	"github.com/unpackdev/fdb/benchmark"
 	"github.com/unpackdev/fdb/config"
 	"github.com/urfave/cli/v2"
+	"os"
+	"path/filepath"
+	"strings"
 	"time"
 )
 
@@ -22,10 +25,10 @@ func BenchmarkCommand() *cli.Command {
 				Usage: "Path where benchmark configuration can be found",
 				Value: "./benchmark.yaml",
 			},
-			&cli.StringFlag{
+			&cli.StringSliceFlag{
 				Name:  "suite",
-				Usage: "Specify the suite type (e.g., quic, dummy)",
-				Value: "dummy", // Default to DUMMY
+				Usage: "Specify the suite type(s) (e.g., quic, dummy, tcp, tcp-tls, udp, udp-dtls). Repeat to compare suites",
+				Value: cli.NewStringSlice("dummy"), // Default to DUMMY
 			},
 			&cli.StringFlag{
 				Name:  "type",
@@ -47,6 +50,10 @@ func BenchmarkCommand() *cli.Command {
 				Usage: "Path to save the JSON report (optional)",
 				Value: "", // Default to no export
 			},
+			&cli.StringSliceFlag{
+				Name:  "profile",
+				Usage: "MDBX profile(s) to benchmark against (e.g., durable, balanced, ingest). Repeat to compare profiles",
+			},
 			&cli.IntFlag{
 				Name:  "timeout",
 				Usage: "Specify the timeout for the benchmark in seconds",
@@ -61,64 +68,202 @@ func BenchmarkCommand() *cli.Command {
 				return errors.Wrap(err, "failed to load configuration")
 			}
 
-			// Initialize FDB
-			fdbc, err := fdb.New(c.Context, *cfg)
-			if err != nil {
-				return fmt.Errorf("failed to initialize FDB: %w", err)
+			// Without explicit profiles the benchmark runs once against the configured nodes
+			profiles := c.StringSlice("profile")
+			if len(profiles) == 0 {
+				profiles = []string{""}
 			}
 
-			// Create a new suite manager
-			suiteManager := benchmark.NewSuiteManager(fdbc)
+			suites := c.StringSlice("suite")
+			passes := len(suites) * len(profiles)
 
-			// Set up signal handling for graceful shutdown
-			handleBenchmarkSignals(c.Context, suiteManager, benchmark.SuiteType(c.String("suite-type")))
+			// Set up signal handling for graceful shutdown, stopping the running pass
+			ctx, stop := handleBenchmarkSignals(c.Context)
+			defer stop()
 
-			// Get the suite type, benchmark type, and number of clients/messages from CLI flags
-			suiteType := benchmark.SuiteType(c.String("suite"))
-			benchmarkType := c.String("type")
-			totalClients := c.Int("clients")
-			messagesPerClient := c.Int("messages")
-			timeout := time.Duration(c.Int("timeout")) * time.Second
+			reports := make([]*benchmark.Report, 0, passes)
+			for _, suite := range suites {
+				for _, profile := range profiles {
+					report, rErr := runBenchmark(ctx, c, *cfg, benchmark.SuiteType(suite), config.MdbxProfile(profile))
+					if rErr != nil {
+						return rErr
+					}
 
-			// Start the suite
-			if err := suiteManager.Start(c.Context, suiteType); err != nil {
-				return fmt.Errorf("failed to start suite: %w", err)
+					// Print report to console
+					report.PrintReport()
+
+					// Optional: export report to JSON if the report-output flag is set
+					if outputPath := c.String("output"); outputPath != "" {
+						if passes > 1 {
+							ext := filepath.Ext(outputPath)
+							outputPath = fmt.Sprintf("%s-%s%s", strings.TrimSuffix(outputPath, ext), passName(suites, profiles, suite, profile), ext)
+						}
+						if err := report.ExportToJSON(outputPath); err != nil {
+							return fmt.Errorf("failed to export report: %w", err)
+						}
+					}
+
+					reports = append(reports, report)
+				}
 			}
 
-			defer suiteManager.Stop(c.Context, suiteType)
+			if len(reports) > 1 {
+				benchmark.PrintComparison(reports)
+			}
 
-			report := benchmark.NewReport()
+			return nil
+		},
+	}
+}
 
-			// Create a context with a timeout
-			ctx, cancel := context.WithTimeout(c.Context, timeout)
-			defer cancel()
+// passName names a benchmark pass in the exported report files by the suite and profile that
+// vary between the passes.
+func passName(suites, profiles []string, suite, profile string) string {
+	switch {
+	case len(suites) == 1:
+		return profile
+	case len(profiles) == 1:
+		return suite
+	default:
+		return suite + "-" + profile
+	}
+}
 
-			// Run the appropriate benchmark based on the user's choice (write or read)
-			switch benchmarkType {
-			case "write":
-				if rErr := suiteManager.RunWriteBenchmark(ctx, suiteType, totalClients, messagesPerClient, report); rErr != nil {
-					return errors.Wrap(rErr, "failed to run write benchmark")
-				}
-			case "read":
-				if rErr := suiteManager.RunReadBenchmark(ctx, suiteType, totalClients, messagesPerClient, report); rErr != nil {
-					return errors.Wrap(rErr, "failed to run read benchmark")
-				}
+// runBenchmark runs a single benchmark pass of a suite against a fresh FDB instance, whose MDBX
+// nodes are stored in a temporary directory of the pass, removed once it is done, rather than
+// their configured paths. When profile is set it overrides the MDBX profile of every configured
+// node, so that successive passes can compare durability profiles against the same workload. The
+// tcp and tcp-tls suites turn TLS off and on on the TCP transport, and the udp and udp-dtls suites
+// DTLS on the UDP transport, so that they compare plaintext and encryption on the same
+// configuration.
+func runBenchmark(ctx context.Context, c *cli.Context, cfg config.Config, suiteType benchmark.SuiteType, profile config.MdbxProfile) (*benchmark.Report, error) {
+	if profile != "" {
+		if err := profile.Validate(); err != nil {
+			return nil, err
+		}
+	}
 
-			default:
-				return fmt.Errorf("invalid benchmark type: %s", benchmarkType)
-			}
+	dataDir, err := os.MkdirTemp("", "fdb-benchmark-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create benchmark data directory: %w", err)
+	}
+	defer os.RemoveAll(dataDir)
 
-			// Print report to console
-			report.PrintReport()
+	nodes := make([]config.MdbxNode, len(cfg.Mdbx.Nodes))
+	for i, node := range cfg.Mdbx.Nodes {
+		node.Path = filepath.Join(dataDir, node.Name)
+		if profile != "" {
+			node.Profile = profile
+		}
+		nodes[i] = node
+	}
+	cfg.Mdbx.Nodes = nodes
+	cfg.Mdbx.DataDir = filepath.Join(dataDir, "runtime")
 
-			// Optional: export report to JSON if the report-output flag is set
-			if outputPath := c.String("output"); outputPath != "" {
-				if err := report.ExportToJSON(outputPath); err != nil {
-					return fmt.Errorf("failed to export report: %w", err)
-				}
+	if suiteType == benchmark.TCPSuiteType || suiteType == benchmark.TCPTLSSuiteType {
+		transports, err := withTCPTLS(cfg.Transports, suiteType == benchmark.TCPTLSSuiteType)
+		if err != nil {
+			return nil, err
+		}
+		cfg.Transports = transports
+	}
+	if suiteType == benchmark.UDPSuiteType || suiteType == benchmark.UDPDTLSSuiteType {
+		transports, err := withUDPDTLS(cfg.Transports, suiteType == benchmark.UDPDTLSSuiteType)
+		if err != nil {
+			return nil, err
+		}
+		cfg.Transports = transports
+	}
+
+	// Initialize FDB
+	fdbc, err := fdb.New(c.Context, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize FDB: %w", err)
+	}
+	defer fdbc.GetDbManager().Close()
+
+	// Create a new suite manager
+	suiteManager := benchmark.NewSuiteManager(fdbc)
+
+	// Get the benchmark type, and number of clients/messages from CLI flags
+	benchmarkType := c.String("type")
+	totalClients := c.Int("clients")
+	messagesPerClient := c.Int("messages")
+	timeout := time.Duration(c.Int("timeout")) * time.Second
+
+	// Start the suite
+	if err := suiteManager.Start(c.Context, suiteType); err != nil {
+		return nil, fmt.Errorf("failed to start suite: %w", err)
+	}
+
+	defer suiteManager.Stop(c.Context, suiteType)
+
+	report := benchmark.NewReport()
+	report.Suite = string(suiteType)
+	report.Profile = string(profile)
+
+	// Create a context with a timeout
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	// Run the appropriate benchmark based on the user's choice (write or read)
+	switch benchmarkType {
+	case "write":
+		if rErr := suiteManager.RunWriteBenchmark(ctx, suiteType, totalClients, messagesPerClient, report); rErr != nil {
+			return nil, errors.Wrap(rErr, "failed to run write benchmark")
+		}
+	case "read":
+		if rErr := suiteManager.RunReadBenchmark(ctx, suiteType, totalClients, messagesPerClient, report); rErr != nil {
+			return nil, errors.Wrap(rErr, "failed to run read benchmark")
+		}
+
+	default:
+		return nil, fmt.Errorf("invalid benchmark type: %s", benchmarkType)
+	}
+
+	return report, nil
+}
+
+// withTCPTLS returns a copy of transports serving TLS on the TCP transport when enabled is set,
+// plaintext otherwise. Serving TLS requires the TCP transport to configure a certificate.
+func withTCPTLS(transports []config.Transport, enabled bool) ([]config.Transport, error) {
+	result := make([]config.Transport, len(transports))
+	for i, transport := range transports {
+		if tcp, ok := transport.Config.(*config.TcpTransport); ok {
+			if enabled && (tcp.TLS == nil || tcp.TLS.Cert == "" || tcp.TLS.Key == "") {
+				return nil, errors.New("the tcp-tls suite requires a tls block with a cert and key on the TCP transport")
+			}
+			tcpCopy := *tcp
+			if tcp.TLS != nil {
+				tls := *tcp.TLS
+				tls.Enabled = enabled
+				tcpCopy.TLS = &tls
 			}
+			transport.Config = &tcpCopy
+		}
+		result[i] = transport
+	}
+	return result, nil
+}
 
-			return nil
-		},
+// withUDPDTLS returns a copy of transports serving DTLS on the UDP transport when enabled is set,
+// plaintext otherwise. Serving DTLS requires the UDP transport to configure a certificate.
+func withUDPDTLS(transports []config.Transport, enabled bool) ([]config.Transport, error) {
+	result := make([]config.Transport, len(transports))
+	for i, transport := range transports {
+		if udp, ok := transport.Config.(*config.UdpTransport); ok {
+			if enabled && (udp.DTLS == nil || udp.DTLS.Cert == "" || udp.DTLS.Key == "") {
+				return nil, errors.New("the udp-dtls suite requires a dtls block with a cert and key on the UDP transport")
+			}
+			udpCopy := *udp
+			if udp.DTLS != nil {
+				dtls := *udp.DTLS
+				dtls.Enabled = enabled
+				udpCopy.DTLS = &dtls
+			}
+			transport.Config = &udpCopy
+		}
+		result[i] = transport
 	}
+	return result, nil
 }
@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/unpackdev/fdb/gateway"
+	"github.com/unpackdev/fdb/messages"
+	"github.com/urfave/cli/v2"
+)
+
+// GatewayCommand returns a cli.Command that shows the upstream node of a gateway and the requests forwarded to it
+func GatewayCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "gateway",
+		Usage: "Show the upstream node of a gateway, its read cache and the requests being forwarded",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "node",
+				Usage: "TCP address of the gateway to ask, admin requests must be enabled on it",
+				Value: "127.0.0.1:5011",
+			},
+			&cli.BoolFlag{
+				Name:  "json",
+				Usage: "Print the status as JSON",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			body, err := sendAdminRequest(c.String("node"), &messages.AdminRequest{Op: messages.AdminGatewayStatus})
+			if err != nil {
+				return err
+			}
+
+			var status gateway.Status
+			if err := json.Unmarshal(body, &status); err != nil {
+				return errors.Wrap(err, "failed to decode gateway status")
+			}
+
+			if c.Bool("json") {
+				out, err := json.MarshalIndent(status, "", "  ")
+				if err != nil {
+					return err
+				}
+				fmt.Println(string(out))
+				return nil
+			}
+
+			fmt.Printf("Upstream:  %s://%s\n", status.Upstream.Transport, status.Upstream.Addr)
+			fmt.Printf("Databases: %s\n", strings.Join(status.Databases, ","))
+			fmt.Printf("Forwarded: %d (%d failed)\n", status.Forwarded, status.Failed)
+			if status.Cache != nil {
+				fmt.Printf("Cache:     %d entries, %d hits, %d misses, %d evictions\n",
+					status.Cache.Entries, status.Cache.Hits, status.Cache.Misses, status.Cache.Evictions)
+			}
+			if len(status.InFlight) == 0 {
+				return nil
+			}
+
+			fmt.Println()
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "ID\tDATABASE\tOP\tELAPSED")
+			for _, req := range status.InFlight {
+				fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", req.ID, req.Database, req.Op, time.Since(req.Started).Round(time.Millisecond))
+			}
+			return w.Flush()
+		},
+	}
+}
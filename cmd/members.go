@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/pkg/errors"
+	"github.com/unpackdev/fdb/membership"
+	"github.com/unpackdev/fdb/messages"
+	"github.com/urfave/cli/v2"
+)
+
+// MembersCommand returns a cli.Command that lists the gossip members known to a node
+func MembersCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "members",
+		Usage: "List the cluster members known to a node and their state",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "node",
+				Usage: "TCP address of the node to ask, admin requests must be enabled on it",
+				Value: "127.0.0.1:5011",
+			},
+			&cli.BoolFlag{
+				Name:  "json",
+				Usage: "Print the member list as JSON",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			body, err := sendAdminRequest(c.String("node"), &messages.AdminRequest{Op: messages.AdminMembers})
+			if err != nil {
+				return err
+			}
+
+			var members []membership.Member
+			if err := json.Unmarshal(body, &members); err != nil {
+				return errors.Wrap(err, "failed to decode member list")
+			}
+
+			if c.Bool("json") {
+				out, err := json.MarshalIndent(members, "", "  ")
+				if err != nil {
+					return err
+				}
+				fmt.Println(string(out))
+				return nil
+			}
+
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "NAME\tADDR\tSTATE\tINCARNATION\tROLES\tTRANSPORTS\tDATABASES")
+			for _, m := range members {
+				transports := make([]string, 0, len(m.Transports))
+				for _, endpoint := range m.Transports {
+					transports = append(transports, endpoint.Type.String()+"://"+endpoint.Addr)
+				}
+				fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\t%s\t%s\n",
+					m.Name, m.Addr, m.State, m.Incarnation,
+					strings.Join(m.Roles, ","), strings.Join(transports, ","), strings.Join(m.Databases, ","),
+				)
+			}
+			return w.Flush()
+		},
+	}
+}
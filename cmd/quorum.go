@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/pkg/errors"
+	"github.com/unpackdev/fdb/messages"
+	"github.com/unpackdev/fdb/quorum"
+	"github.com/urfave/cli/v2"
+)
+
+// QuorumCommand returns a cli.Command that shows the quorum settings and pending hints of a node
+func QuorumCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "quorum",
+		Usage: "Show the quorum replication settings of a node and the writes it keeps for unreachable replicas",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "node",
+				Usage: "TCP address of the node to ask, admin requests must be enabled on it",
+				Value: "127.0.0.1:5011",
+			},
+			&cli.BoolFlag{
+				Name:  "json",
+				Usage: "Print the status as JSON",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			body, err := sendAdminRequest(c.String("node"), &messages.AdminRequest{Op: messages.AdminQuorumStatus})
+			if err != nil {
+				return err
+			}
+
+			var status quorum.Status
+			if err := json.Unmarshal(body, &status); err != nil {
+				return errors.Wrap(err, "failed to decode quorum status")
+			}
+
+			if c.Bool("json") {
+				out, err := json.MarshalIndent(status, "", "  ")
+				if err != nil {
+					return err
+				}
+				fmt.Println(string(out))
+				return nil
+			}
+
+			fmt.Printf("Node:          %s\n", status.NodeID)
+			fmt.Printf("N / R / W:     %d / %d / %d\n", status.Replicas, status.ReadQuorum, status.WriteQuorum)
+			fmt.Printf("Read repairs:  %d\n", status.ReadRepairs)
+			fmt.Printf("Dropped hints: %d\n", status.DroppedHints)
+			if len(status.Hints) == 0 {
+				return nil
+			}
+
+			nodes := make([]string, 0, len(status.Hints))
+			for node := range status.Hints {
+				nodes = append(nodes, node)
+			}
+			sort.Strings(nodes)
+
+			fmt.Println()
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "NODE\tPENDING HINTS")
+			for _, node := range nodes {
+				fmt.Fprintf(w, "%s\t%d\n", node, status.Hints[node])
+			}
+			return w.Flush()
+		},
+	}
+}
@@ -0,0 +1,97 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/pkg/errors"
+	"github.com/unpackdev/fdb/config"
+	"github.com/unpackdev/fdb/consensus"
+	"github.com/unpackdev/fdb/messages"
+	"github.com/urfave/cli/v2"
+)
+
+// RaftCommand returns a cli.Command that inspects and changes the membership of the Raft group of a running node
+func RaftCommand() *cli.Command {
+	nodeFlag := &cli.StringFlag{
+		Name:  "node",
+		Usage: "TCP address of the node to manage, admin requests must be enabled on it",
+		Value: "127.0.0.1:5011",
+	}
+	idFlag := &cli.StringFlag{
+		Name:     "id",
+		Usage:    "Identifier of the member",
+		Required: true,
+	}
+
+	return &cli.Command{
+		Name:  "raft",
+		Usage: "Manage the Raft replication group",
+		Subcommands: []*cli.Command{
+			{
+				Name:  "status",
+				Usage: "Show the Raft state of the node and the members of the group",
+				Flags: []cli.Flag{nodeFlag},
+				Action: func(c *cli.Context) error {
+					body, err := sendAdminRequest(c.String("node"), &messages.AdminRequest{Op: messages.AdminRaftStatus})
+					if err != nil {
+						return err
+					}
+
+					var status consensus.Status
+					if err := json.Unmarshal(body, &status); err != nil {
+						return errors.Wrap(err, "failed to decode raft status")
+					}
+					out, err := json.MarshalIndent(status, "", "  ")
+					if err != nil {
+						return err
+					}
+					fmt.Println(string(out))
+					return nil
+				},
+			},
+			{
+				Name:  "join",
+				Usage: "Add a voting member to the group",
+				Flags: []cli.Flag{
+					nodeFlag,
+					idFlag,
+					&cli.StringFlag{
+						Name:     "addr",
+						Usage:    "Raft address of the member",
+						Required: true,
+					},
+				},
+				Action: func(c *cli.Context) error {
+					payload, err := json.Marshal(config.RaftPeer{ID: c.String("id"), Addr: c.String("addr")})
+					if err != nil {
+						return err
+					}
+
+					if _, err := sendAdminRequest(c.String("node"), &messages.AdminRequest{Op: messages.AdminRaftJoin, Payload: payload}); err != nil {
+						return err
+					}
+					fmt.Printf("Member %s (%s) joined the group\n", c.String("id"), c.String("addr"))
+					return nil
+				},
+			},
+			{
+				Name:  "leave",
+				Usage: "Remove a member from the group",
+				Flags: []cli.Flag{nodeFlag, idFlag},
+				Action: func(c *cli.Context) error {
+					payload, err := json.Marshal(config.RaftPeer{ID: c.String("id")})
+					if err != nil {
+						return err
+					}
+
+					if _, err := sendAdminRequest(c.String("node"), &messages.AdminRequest{Op: messages.AdminRaftLeave, Payload: payload}); err != nil {
+						return err
+					}
+					fmt.Printf("Member %s left the group\n", c.String("id"))
+					return nil
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,129 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/unpackdev/fdb/antientropy"
+	"github.com/unpackdev/fdb/config"
+	"github.com/unpackdev/fdb/messages"
+	"github.com/unpackdev/fdb/types"
+	"github.com/urfave/cli/v2"
+)
+
+// RepairCommand returns a cli.Command that repairs a database of a node against a peer replica
+func RepairCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "repair",
+		Usage: "Compare a database with a peer replica and repair the divergent key ranges",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "node",
+				Usage: "TCP address of the node to repair, admin requests must be enabled on it",
+				Value: "127.0.0.1:5011",
+			},
+			&cli.StringFlag{
+				Name:     "peer",
+				Usage:    "Address of the peer transport serving the other replica",
+				Required: true,
+			},
+			&cli.StringFlag{
+				Name:  "transport",
+				Usage: "Peer transport, tcp or quic",
+				Value: "tcp",
+			},
+			&cli.BoolFlag{
+				Name:  "insecure",
+				Usage: "Skip verifying the peer certificate",
+			},
+			&cli.StringFlag{
+				Name:  "database",
+				Usage: "Database to repair",
+				Value: "fdb",
+			},
+			&cli.StringFlag{
+				Name:  "direction",
+				Usage: "pull to make the node match the peer, push to make the peer match the node",
+				Value: string(config.RepairPull),
+			},
+			&cli.BoolFlag{
+				Name:  "detach",
+				Usage: "Start the repair and return without waiting for it",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			transport, err := types.ParseTransportType(c.String("transport"))
+			if err != nil {
+				return err
+			}
+
+			payload, err := json.Marshal(antientropy.RepairRequest{
+				Database: c.String("database"),
+				Peer: config.ReplicationPeer{
+					Transport: transport,
+					Addr:      c.String("peer"),
+					Insecure:  c.Bool("insecure"),
+				},
+				Direction: config.RepairDirection(c.String("direction")),
+			})
+			if err != nil {
+				return err
+			}
+
+			body, err := sendAdminRequest(c.String("node"), &messages.AdminRequest{Op: messages.AdminRepair, Payload: payload})
+			if err != nil {
+				return err
+			}
+
+			var started antientropy.RepairResponse
+			if err := json.Unmarshal(body, &started); err != nil {
+				return errors.Wrap(err, "failed to decode repair response")
+			}
+			if c.Bool("detach") {
+				fmt.Printf("Repair %d of %s started on %s\n", started.ID, c.String("database"), c.String("node"))
+				return nil
+			}
+
+			for {
+				result, err := repairResult(c.String("node"), started.ID)
+				if err != nil {
+					return err
+				}
+				if !result.Running {
+					out, err := json.MarshalIndent(result, "", "  ")
+					if err != nil {
+						return err
+					}
+					fmt.Println(string(out))
+
+					if result.Error != "" {
+						return errors.Errorf("repair failed: %s", result.Error)
+					}
+					return nil
+				}
+				time.Sleep(time.Second)
+			}
+		},
+	}
+}
+
+// repairResult returns the repair run with the given ID from the repair status of a node.
+func repairResult(node string, id uint64) (antientropy.Result, error) {
+	body, err := sendAdminRequest(node, &messages.AdminRequest{Op: messages.AdminRepairStatus})
+	if err != nil {
+		return antientropy.Result{}, err
+	}
+
+	var status antientropy.Status
+	if err := json.Unmarshal(body, &status); err != nil {
+		return antientropy.Result{}, errors.Wrap(err, "failed to decode repair status")
+	}
+	for _, run := range status.Runs {
+		if run.ID == id {
+			return run, nil
+		}
+	}
+	return antientropy.Result{}, errors.Errorf("repair %d is no longer reported by %s", id, node)
+}
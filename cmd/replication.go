@@ -0,0 +1,152 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/unpackdev/fdb/config"
+	"github.com/unpackdev/fdb/messages"
+	"github.com/unpackdev/fdb/replication"
+	"github.com/unpackdev/fdb/types"
+	"github.com/urfave/cli/v2"
+)
+
+// adminTimeout bounds a single admin request sent by the replication commands.
+const adminTimeout = 10 * time.Second
+
+// ReplicationCommand returns a cli.Command that inspects and controls replication on a running node
+func ReplicationCommand() *cli.Command {
+	nodeFlag := &cli.StringFlag{
+		Name:  "node",
+		Usage: "TCP address of the node to manage, admin requests must be enabled on it",
+		Value: "127.0.0.1:5011",
+	}
+
+	return &cli.Command{
+		Name:  "replication",
+		Usage: "Manage leader-follower replication",
+		Subcommands: []*cli.Command{
+			{
+				Name:  "status",
+				Usage: "Show the replication role and lag of the node",
+				Flags: []cli.Flag{nodeFlag},
+				Action: func(c *cli.Context) error {
+					body, err := sendAdminRequest(c.String("node"), &messages.AdminRequest{Op: messages.AdminReplicationStatus})
+					if err != nil {
+						return err
+					}
+
+					var status replication.Status
+					if err := json.Unmarshal(body, &status); err != nil {
+						return errors.Wrap(err, "failed to decode replication status")
+					}
+					out, err := json.MarshalIndent(status, "", "  ")
+					if err != nil {
+						return err
+					}
+					fmt.Println(string(out))
+					return nil
+				},
+			},
+			{
+				Name:  "promote",
+				Usage: "Stop following the leader and accept writes",
+				Flags: []cli.Flag{nodeFlag},
+				Action: func(c *cli.Context) error {
+					if _, err := sendAdminRequest(c.String("node"), &messages.AdminRequest{Op: messages.AdminPromote}); err != nil {
+						return err
+					}
+					fmt.Printf("Node %s promoted to leader\n", c.String("node"))
+					return nil
+				},
+			},
+			{
+				Name:  "follow",
+				Usage: "Replicate from the given leader",
+				Flags: []cli.Flag{
+					nodeFlag,
+					&cli.StringFlag{
+						Name:     "leader",
+						Usage:    "Address of the leader transport",
+						Required: true,
+					},
+					&cli.StringFlag{
+						Name:  "transport",
+						Usage: "Leader transport, tcp or quic",
+						Value: "tcp",
+					},
+					&cli.BoolFlag{
+						Name:  "insecure",
+						Usage: "Skip verifying the leader certificate",
+					},
+					&cli.BoolFlag{
+						Name:  "resync",
+						Usage: "Discard local data and bootstrap from a fresh snapshot",
+					},
+				},
+				Action: func(c *cli.Context) error {
+					transport, err := types.ParseTransportType(c.String("transport"))
+					if err != nil {
+						return err
+					}
+
+					payload, err := json.Marshal(replication.FollowRequest{
+						Leader: config.ReplicationPeer{
+							Transport: transport,
+							Addr:      c.String("leader"),
+							Insecure:  c.Bool("insecure"),
+						},
+						Resync: c.Bool("resync"),
+					})
+					if err != nil {
+						return err
+					}
+
+					if _, err := sendAdminRequest(c.String("node"), &messages.AdminRequest{Op: messages.AdminFollow, Payload: payload}); err != nil {
+						return err
+					}
+					fmt.Printf("Node %s following %s\n", c.String("node"), c.String("leader"))
+					return nil
+				},
+			},
+		},
+	}
+}
+
+// sendAdminRequest sends a single admin request over TCP and returns the response body.
+func sendAdminRequest(addr string, req *messages.AdminRequest) ([]byte, error) {
+	frame, err := req.Encode()
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := net.DialTimeout("tcp", addr, adminTimeout)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to connect to node %s", addr)
+	}
+	defer conn.Close()
+
+	if err := conn.SetDeadline(time.Now().Add(adminTimeout)); err != nil {
+		return nil, err
+	}
+	if _, err := conn.Write(frame); err != nil {
+		return nil, errors.Wrap(err, "failed to send admin request")
+	}
+
+	buf := make([]byte, 64*1024)
+	n, err := conn.Read(buf)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read admin response")
+	}
+	if n < 1 {
+		return nil, errors.New("empty admin response")
+	}
+
+	if types.ResponseStatus(buf[0]) != types.StatusOK {
+		return nil, fmt.Errorf("%s failed: %s", req.Op, string(buf[1:n]))
+	}
+	return buf[1:n], nil
+}
@@ -0,0 +1,112 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/unpackdev/fdb/messages"
+	"github.com/unpackdev/fdb/sharding"
+	"github.com/urfave/cli/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// rebalancePollInterval is how often the rebalance command checks whether the new map is installed.
+const rebalancePollInterval = time.Second
+
+// ShardCommand returns a cli.Command that inspects and rebalances the shard map of a sharding proxy
+func ShardCommand() *cli.Command {
+	nodeFlag := &cli.StringFlag{
+		Name:  "node",
+		Usage: "TCP address of the proxy to manage, admin requests must be enabled on it",
+		Value: "127.0.0.1:5011",
+	}
+
+	return &cli.Command{
+		Name:  "shard",
+		Usage: "Manage the shard map of a sharding proxy",
+		Subcommands: []*cli.Command{
+			{
+				Name:  "map",
+				Usage: "Show the shard map of the proxy and the state of the last rebalance",
+				Flags: []cli.Flag{nodeFlag},
+				Action: func(c *cli.Context) error {
+					status, err := shardStatus(c.String("node"))
+					if err != nil {
+						return err
+					}
+					out, err := json.MarshalIndent(status, "", "  ")
+					if err != nil {
+						return err
+					}
+					fmt.Println(string(out))
+					return nil
+				},
+			},
+			{
+				Name:  "rebalance",
+				Usage: "Install a new shard map and move the keys changing owner",
+				Flags: []cli.Flag{
+					nodeFlag,
+					&cli.StringFlag{
+						Name:     "map",
+						Usage:    "YAML or JSON file with the new shard map, its version must be higher than the current one",
+						Required: true,
+					},
+				},
+				Action: func(c *cli.Context) error {
+					data, err := os.ReadFile(c.String("map"))
+					if err != nil {
+						return errors.Wrap(err, "failed to read shard map")
+					}
+					var next sharding.Map
+					if err := yaml.Unmarshal(data, &next); err != nil {
+						return errors.Wrap(err, "failed to decode shard map")
+					}
+					payload, err := json.Marshal(next)
+					if err != nil {
+						return err
+					}
+
+					if _, err := sendAdminRequest(c.String("node"), &messages.AdminRequest{Op: messages.AdminRebalance, Payload: payload}); err != nil {
+						return err
+					}
+					fmt.Printf("Rebalancing to shard map version %d\n", next.Version)
+
+					for {
+						time.Sleep(rebalancePollInterval)
+
+						status, err := shardStatus(c.String("node"))
+						if err != nil {
+							return err
+						}
+						if status.Rebalancing {
+							continue
+						}
+						if status.Map.Version >= next.Version {
+							fmt.Printf("Shard map version %d installed\n", status.Map.Version)
+							return nil
+						}
+						return fmt.Errorf("rebalance failed: %s", status.LastError)
+					}
+				},
+			},
+		},
+	}
+}
+
+// shardStatus fetches the shard map and rebalance state of a proxy.
+func shardStatus(node string) (*sharding.Status, error) {
+	body, err := sendAdminRequest(node, &messages.AdminRequest{Op: messages.AdminShardMap})
+	if err != nil {
+		return nil, err
+	}
+
+	var status sharding.Status
+	if err := json.Unmarshal(body, &status); err != nil {
+		return nil, errors.Wrap(err, "failed to decode shard map")
+	}
+	return &status, nil
+}
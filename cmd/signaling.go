@@ -3,21 +3,30 @@ package cmd
 import (
 	"context"
 	"fmt"
-	"github.com/unpackdev/fdb/benchmark"
 	"os"
 	"os/signal"
 	"syscall"
 )
 
-// handleBenchmarkSignals traps OS signals for graceful shutdown.
-func handleBenchmarkSignals(ctx context.Context, suiteManager *benchmark.SuiteManager, suiteType benchmark.SuiteType) {
+// handleBenchmarkSignals traps OS signals for graceful shutdown. The returned context is cancelled
+// on the first signal, so that the running pass stops its suite and removes its data before the
+// benchmark exits; the returned function stops trapping the signals.
+func handleBenchmarkSignals(ctx context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(ctx)
 	signalChan := make(chan os.Signal, 1)
 	signal.Notify(signalChan, syscall.SIGINT, syscall.SIGTERM)
 
 	go func() {
-		<-signalChan
-		fmt.Println("Received interrupt signal, stopping suite...")
-		suiteManager.Stop(ctx, suiteType)
-		os.Exit(1)
+		select {
+		case <-signalChan:
+			fmt.Println("Received interrupt signal, stopping suite...")
+			cancel()
+		case <-ctx.Done():
+		}
 	}()
+
+	return ctx, func() {
+		signal.Stop(signalChan)
+		cancel()
+	}
 }
@@ -0,0 +1,10 @@
+package config
+
+// Admin represents the configuration of administrative operations exposed over the
+// transports, such as creating, opening, closing and dropping databases at runtime.
+// Administrative operations are disabled unless explicitly enabled, as the transports
+// do not authenticate their clients.
+type Admin struct {
+	// Enabled determines whether the admin handler is registered on the transports.
+	Enabled bool `yaml:"enabled"`
+}
@@ -0,0 +1,125 @@
+package config
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/unpackdev/fdb/types"
+)
+
+// Anti-entropy defaults, applied when the corresponding setting is left empty.
+const (
+	DefaultAntiEntropyInterval       = 10 * time.Minute
+	DefaultAntiEntropyRequestTimeout = 30 * time.Second
+)
+
+// RepairDirection defines which replica is authoritative when two replicas are repaired.
+type RepairDirection string
+
+const (
+	// RepairPull makes the local database match the peer in the divergent key ranges. This is the default.
+	RepairPull RepairDirection = "pull"
+
+	// RepairPush makes the peer match the local database in the divergent key ranges.
+	RepairPush RepairDirection = "push"
+)
+
+// Validate checks that the direction is known, empty standing for RepairPull.
+func (d RepairDirection) Validate() error {
+	switch d {
+	case "", RepairPull, RepairPush:
+		return nil
+	default:
+		return fmt.Errorf("unknown repair direction: %s", d)
+	}
+}
+
+// GetOrDefault returns the direction, or RepairPull when empty.
+func (d RepairDirection) GetOrDefault() RepairDirection {
+	if d == "" {
+		return RepairPull
+	}
+	return d
+}
+
+// AntiEntropy holds the configuration of scheduled anti-entropy repair. Every interval each
+// repaired database is compared with a peer through Merkle tree summaries, and the key ranges
+// in which they differ are repaired.
+type AntiEntropy struct {
+	// Enabled turns scheduled repair on. Repairs requested through the admin API run regardless.
+	Enabled bool `yaml:"enabled" json:"enabled"`
+
+	// Interval is how often each database is repaired.
+	Interval time.Duration `yaml:"interval" json:"interval"`
+
+	// Direction decides which replica wins in divergent ranges, pull (the peer) or push (this node).
+	Direction RepairDirection `yaml:"direction" json:"direction"`
+
+	// Databases lists the databases to repair. Defaults to every configured MDBX node.
+	Databases []string `yaml:"databases" json:"databases"`
+
+	// Peers lists the nodes to repair against, one picked at random per round. When empty, a
+	// random alive gossip member serving the database is picked, which requires membership.
+	Peers []ReplicationPeer `yaml:"peers" json:"peers"`
+
+	// RequestTimeout bounds a single request to the peer. The first request of a run may hash
+	// the whole database.
+	RequestTimeout time.Duration `yaml:"requestTimeout" json:"requestTimeout"`
+}
+
+// GetInterval returns the configured interval, or DefaultAntiEntropyInterval when unset.
+func (a AntiEntropy) GetInterval() time.Duration {
+	if a.Interval <= 0 {
+		return DefaultAntiEntropyInterval
+	}
+	return a.Interval
+}
+
+// GetRequestTimeout returns the configured request timeout, or DefaultAntiEntropyRequestTimeout when unset.
+func (a AntiEntropy) GetRequestTimeout() time.Duration {
+	if a.RequestTimeout <= 0 {
+		return DefaultAntiEntropyRequestTimeout
+	}
+	return a.RequestTimeout
+}
+
+// Repairs reports whether the named database is repaired on schedule.
+func (a AntiEntropy) Repairs(name string) bool {
+	if len(a.Databases) == 0 {
+		return true
+	}
+	for _, database := range a.Databases {
+		if database == name {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidatePeer checks that a repair peer uses a stream transport and has an address.
+func ValidatePeer(p ReplicationPeer) error {
+	if p.Transport != types.TCPTransportType && p.Transport != types.QUICTransportType {
+		return fmt.Errorf("repair peer transport must be tcp or quic, got %s", p.Transport)
+	}
+	if p.Addr == "" {
+		return fmt.Errorf("repair peer addr must not be empty")
+	}
+	return nil
+}
+
+// Validate checks the repair direction and peers.
+//
+// Returns:
+//
+//	error: Returns nil if the configuration is valid, or an error if validation fails.
+func (a AntiEntropy) Validate() error {
+	if err := a.Direction.Validate(); err != nil {
+		return err
+	}
+	for _, peer := range a.Peers {
+		if err := ValidatePeer(peer); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// DefaultCdcPruneInterval is how often the CDC log retention is enforced when no interval is configured.
+const DefaultCdcPruneInterval = time.Minute
+
+// Cdc holds the change data capture configuration of an MDBX node. When enabled, every
+// mutation is recorded in an ordered log, written in the same transaction as the data,
+// that downstream consumers can tail by sequence number.
+type Cdc struct {
+	// Enabled turns the change log on for the node.
+	Enabled bool `yaml:"enabled" json:"enabled"`
+
+	// RetainCount is the maximum number of changes kept in the log. Zero keeps every change.
+	RetainCount uint64 `yaml:"retainCount" json:"retainCount"`
+
+	// RetainAge is the maximum age of changes kept in the log. Zero keeps changes regardless of age.
+	RetainAge time.Duration `yaml:"retainAge" json:"retainAge"`
+
+	// PruneInterval is how often retention is enforced. Defaults to DefaultCdcPruneInterval.
+	PruneInterval time.Duration `yaml:"pruneInterval" json:"pruneInterval"`
+}
+
+// HasRetention reports whether any retention limit is configured.
+func (c Cdc) HasRetention() bool {
+	return c.RetainCount > 0 || c.RetainAge > 0
+}
+
+// GetPruneInterval returns the configured prune interval, or DefaultCdcPruneInterval when unset.
+func (c Cdc) GetPruneInterval() time.Duration {
+	if c.PruneInterval <= 0 {
+		return DefaultCdcPruneInterval
+	}
+	return c.PruneInterval
+}
+
+// Validate checks the CDC configuration for negative durations.
+//
+// Returns:
+//
+//	error: Returns nil if the configuration is valid, or an error if validation fails.
+func (c Cdc) Validate() error {
+	if c.RetainAge < 0 {
+		return fmt.Errorf("cdc retainAge must not be negative")
+	}
+	if c.PruneInterval < 0 {
+		return fmt.Errorf("cdc pruneInterval must not be negative")
+	}
+	return nil
+}
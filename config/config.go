@@ -23,11 +23,36 @@ type Config struct {
 
 	// Pprof is a list of pprof profiling configurations, each tied to a specific service or subsystem.
 	Pprof []Pprof `yaml:"pprof"`
+
+	// Admin controls the administrative operations (database lifecycle management) exposed over the transports.
+	Admin Admin `yaml:"admin"`
+
+	// Replication configures leader-follower replication between (f)db nodes.
+	Replication Replication `yaml:"replication"`
+
+	// Raft configures the optional Raft group replicating selected databases with strong consistency.
+	Raft Raft `yaml:"raft"`
+
+	// Sharding turns the node into a proxy routing requests to the shards owning their keys.
+	Sharding Sharding `yaml:"sharding"`
+
+	// Membership configures the gossip membership and failure detection between (f)db nodes.
+	Membership Membership `yaml:"membership"`
+
+	// AntiEntropy schedules the Merkle tree repair of databases against their replicas.
+	AntiEntropy AntiEntropy `yaml:"antiEntropy"`
+
+	// Quorum replicates selected databases over several nodes with tunable read and write quorums.
+	Quorum Quorum `yaml:"quorum"`
+
+	// Gateway turns the node into a gateway forwarding requests to a remote node over another transport.
+	Gateway Gateway `yaml:"gateway"`
 }
 
-// Validate checks the integrity of the loaded configuration.
-// Currently, it returns nil, but you can extend it to perform validation on
-// the various configuration fields to ensure they are set correctly.
+// Validate checks the integrity of the loaded configuration. At the moment it
+// validates the MDBX node definitions (names, sizes, profiles and sync modes) and
+// the databases declared by each transport, the replication, Raft, sharding, membership,
+// anti-entropy, quorum and gateway settings.
 //
 // Example usage:
 //
@@ -39,6 +64,81 @@ type Config struct {
 //
 //	error: Returns nil if the configuration is valid, or an error if validation fails.
 func (c Config) Validate() error {
+	if err := c.Mdbx.Validate(); err != nil {
+		return fmt.Errorf("invalid mdbx configuration: %w", err)
+	}
+	for _, t := range c.Transports {
+		if err := t.Validate(); err != nil {
+			return fmt.Errorf("invalid transport configuration: %w", err)
+		}
+	}
+	if err := c.Replication.Validate(); err != nil {
+		return fmt.Errorf("invalid replication configuration: %w", err)
+	}
+
+	if err := c.Raft.Validate(); err != nil {
+		return fmt.Errorf("invalid raft configuration: %w", err)
+	}
+
+	if err := c.Sharding.Validate(); err != nil {
+		return fmt.Errorf("invalid sharding configuration: %w", err)
+	}
+
+	if err := c.Membership.Validate(); err != nil {
+		return fmt.Errorf("invalid membership configuration: %w", err)
+	}
+
+	if err := c.AntiEntropy.Validate(); err != nil {
+		return fmt.Errorf("invalid anti-entropy configuration: %w", err)
+	}
+	if c.AntiEntropy.Enabled && len(c.AntiEntropy.Peers) == 0 && !c.Membership.Enabled {
+		return fmt.Errorf("invalid anti-entropy configuration: peers must be listed unless membership is enabled")
+	}
+
+	if err := c.Quorum.Validate(); err != nil {
+		return fmt.Errorf("invalid quorum configuration: %w", err)
+	}
+	// Quorum databases are written by their coordinators only
+	if c.Quorum.Enabled {
+		for _, database := range c.Quorum.Databases {
+			if c.Raft.Enabled && contains(c.Raft.Databases, database) {
+				return fmt.Errorf("invalid quorum configuration: database %s is also replicated by raft", database)
+			}
+			if c.Sharding.Enabled && contains(c.Sharding.Databases, database) {
+				return fmt.Errorf("invalid quorum configuration: database %s is also served by the sharding proxy", database)
+			}
+			if c.Replication.Role == ReplicationFollower && c.Replication.Replicates(database) {
+				return fmt.Errorf("invalid quorum configuration: database %s is also replicated from a leader", database)
+			}
+		}
+	}
+
+	if err := c.Gateway.Validate(); err != nil {
+		return fmt.Errorf("invalid gateway configuration: %w", err)
+	}
+	// Gateway databases are stored upstream only
+	if c.Gateway.Enabled {
+		for _, database := range c.Gateway.Databases {
+			if c.Sharding.Enabled && contains(c.Sharding.Databases, database) {
+				return fmt.Errorf("invalid gateway configuration: database %s is also served by the sharding proxy", database)
+			}
+			if c.Quorum.Enabled && c.Quorum.Replicates(database) {
+				return fmt.Errorf("invalid gateway configuration: database %s is also replicated with quorums", database)
+			}
+			if c.Raft.Enabled && contains(c.Raft.Databases, database) {
+				return fmt.Errorf("invalid gateway configuration: database %s is also replicated by raft", database)
+			}
+		}
+	}
+
+	// Leaders serve followers from the change log
+	if c.Replication.Role == ReplicationLeader {
+		for _, node := range c.Mdbx.Nodes {
+			if c.Replication.Replicates(node.Name) && !node.Cdc.Enabled {
+				return fmt.Errorf("invalid replication configuration: mdbx node %s must enable cdc to be replicated", node.Name)
+			}
+		}
+	}
 	return nil
 }
 
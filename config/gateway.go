@@ -0,0 +1,109 @@
+package config
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/unpackdev/fdb/types"
+)
+
+// Gateway defaults, applied when the corresponding setting is left empty.
+const (
+	DefaultGatewayPoolSize        = 8
+	DefaultGatewayRequestTimeout  = 5 * time.Second
+	DefaultGatewayCacheMaxEntries = 10000
+	DefaultGatewayCacheTTL        = time.Second
+)
+
+// GatewayCache holds the configuration of the gateway read cache. Cached values are served
+// without asking the upstream node until they expire, so reads may miss writes made through
+// other nodes for up to TTL. Writes through the gateway update the cache.
+type GatewayCache struct {
+	// Enabled turns the read cache on.
+	Enabled bool `yaml:"enabled" json:"enabled"`
+
+	// MaxEntries bounds the number of cached keys, the least recently used are evicted first.
+	MaxEntries int `yaml:"maxEntries" json:"maxEntries"`
+
+	// TTL is how long a cached value is served.
+	TTL time.Duration `yaml:"ttl" json:"ttl"`
+}
+
+// GetMaxEntries returns the configured cache size, or DefaultGatewayCacheMaxEntries when unset.
+func (c GatewayCache) GetMaxEntries() int {
+	if c.MaxEntries <= 0 {
+		return DefaultGatewayCacheMaxEntries
+	}
+	return c.MaxEntries
+}
+
+// GetTTL returns the configured cache TTL, or DefaultGatewayCacheTTL when unset.
+func (c GatewayCache) GetTTL() time.Duration {
+	if c.TTL <= 0 {
+		return DefaultGatewayCacheTTL
+	}
+	return c.TTL
+}
+
+// Gateway holds the configuration of the gateway role. A gateway stores no data itself: it
+// serves the listed databases on its transports and forwards every request to the upstream
+// node, translating between the transports it serves and the upstream transport.
+type Gateway struct {
+	// Enabled turns the node into a gateway.
+	Enabled bool `yaml:"enabled" json:"enabled"`
+
+	// Databases lists the databases served through the gateway. The upstream node serves them
+	// under the same names.
+	Databases []string `yaml:"databases" json:"databases"`
+
+	// Upstream is the node requests are forwarded to, over tcp or quic.
+	Upstream ReplicationPeer `yaml:"upstream" json:"upstream"`
+
+	// PoolSize is the number of TCP connections kept to the upstream node. QUIC multiplexes
+	// every request over a single connection.
+	PoolSize int `yaml:"poolSize" json:"poolSize"`
+
+	// RequestTimeout bounds a single request forwarded to the upstream node.
+	RequestTimeout time.Duration `yaml:"requestTimeout" json:"requestTimeout"`
+
+	// Cache configures the local read cache.
+	Cache GatewayCache `yaml:"cache" json:"cache"`
+}
+
+// GetPoolSize returns the configured pool size, or DefaultGatewayPoolSize when unset.
+func (g Gateway) GetPoolSize() int {
+	if g.PoolSize <= 0 {
+		return DefaultGatewayPoolSize
+	}
+	return g.PoolSize
+}
+
+// GetRequestTimeout returns the configured request timeout, or DefaultGatewayRequestTimeout when unset.
+func (g Gateway) GetRequestTimeout() time.Duration {
+	if g.RequestTimeout <= 0 {
+		return DefaultGatewayRequestTimeout
+	}
+	return g.RequestTimeout
+}
+
+// Validate checks that an enabled gateway serves at least one database and forwards it over a
+// stream transport.
+//
+// Returns:
+//
+//	error: Returns nil if the configuration is valid, or an error if validation fails.
+func (g Gateway) Validate() error {
+	if !g.Enabled {
+		return nil
+	}
+	if len(g.Databases) == 0 {
+		return fmt.Errorf("gateway must serve at least one database")
+	}
+	if g.Upstream.Transport != types.TCPTransportType && g.Upstream.Transport != types.QUICTransportType {
+		return fmt.Errorf("gateway upstream transport must be tcp or quic, got %s", g.Upstream.Transport)
+	}
+	if g.Upstream.Addr == "" {
+		return fmt.Errorf("gateway upstream addr must not be empty")
+	}
+	return nil
+}
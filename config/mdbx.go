@@ -1,34 +1,263 @@
 package config
 
+import (
+	"fmt"
+	"time"
+)
+
+// MdbxSyncMode describes how aggressively MDBX flushes committed transactions to disk.
+// The modes map directly onto the MDBX environment durability flags and trade crash
+// safety for write throughput.
+type MdbxSyncMode string
+
+const (
+	// MdbxSyncDurable fully syncs data and meta pages on every commit (MDBX_SYNC_DURABLE).
+	MdbxSyncDurable MdbxSyncMode = "durable"
+
+	// MdbxSyncNoMetaSync syncs data pages on commit but defers the meta page (MDBX_NOMETASYNC).
+	// A system crash may roll back the last committed transaction, but never corrupts the database.
+	MdbxSyncNoMetaSync MdbxSyncMode = "nometasync"
+
+	// MdbxSyncSafeNoSync skips syncing on commit entirely (MDBX_SAFE_NOSYNC). A system crash may
+	// lose recent transactions, but the database stays consistent.
+	MdbxSyncSafeNoSync MdbxSyncMode = "safenosync"
+
+	// MdbxSyncUtterlyNoSync disables all syncing (MDBX_UTTERLY_NOSYNC). A system crash may corrupt
+	// the database. Use only for data that can be rebuilt.
+	MdbxSyncUtterlyNoSync MdbxSyncMode = "utterlynosync"
+)
+
+// IsRelaxed reports whether the sync mode does not fully persist every commit, meaning
+// that a periodic environment sync is worth running alongside it.
+func (m MdbxSyncMode) IsRelaxed() bool {
+	return m != "" && m != MdbxSyncDurable
+}
+
+// Validate checks that the sync mode is one of the supported MDBX durability modes.
+func (m MdbxSyncMode) Validate() error {
+	switch m {
+	case "", MdbxSyncDurable, MdbxSyncNoMetaSync, MdbxSyncSafeNoSync, MdbxSyncUtterlyNoSync:
+		return nil
+	default:
+		return fmt.Errorf("unknown mdbx sync mode: %s", m)
+	}
+}
+
+// MdbxProfile is a named preset of MDBX environment settings. Profiles let operators pick a
+// sensible durability/throughput trade-off without tuning every flag individually.
+type MdbxProfile string
+
+const (
+	// MdbxProfileDurable syncs every commit and favours crash safety over throughput.
+	MdbxProfileDurable MdbxProfile = "durable"
+
+	// MdbxProfileBalanced defers meta page syncs and flushes the environment every second.
+	MdbxProfileBalanced MdbxProfile = "balanced"
+
+	// MdbxProfileIngest is tuned for bulk loading: no sync on commit, writable memory map,
+	// no readahead and a periodic background sync.
+	MdbxProfileIngest MdbxProfile = "ingest"
+)
+
+// mdbxProfiles holds the settings each named profile applies to an MdbxNode.
+var mdbxProfiles = map[MdbxProfile]MdbxNode{
+	MdbxProfileDurable: {
+		SyncMode: MdbxSyncDurable,
+		Coalesce: Flag(true),
+	},
+	MdbxProfileBalanced: {
+		SyncMode:    MdbxSyncNoMetaSync,
+		Coalesce:    Flag(true),
+		LifoReclaim: Flag(true),
+		SyncPeriod:  time.Second,
+	},
+	MdbxProfileIngest: {
+		SyncMode:    MdbxSyncSafeNoSync,
+		WriteMap:    Flag(true),
+		NoReadahead: Flag(true),
+		Coalesce:    Flag(true),
+		LifoReclaim: Flag(true),
+		SyncPeriod:  5 * time.Second,
+	},
+}
+
+// Flag returns a pointer to v, to set the optional flags of an MdbxNode.
+func Flag(v bool) *bool {
+	return &v
+}
+
+// Enabled reports whether an optional flag of an MdbxNode is set to true.
+func Enabled(flag *bool) bool {
+	return flag != nil && *flag
+}
+
+// inheritFlag returns the flag of the node when set, or a copy of the flag of its profile.
+func inheritFlag(node, profile *bool) *bool {
+	if node != nil || profile == nil {
+		return node
+	}
+	return Flag(*profile)
+}
+
+// MdbxProfiles returns the names of all supported MDBX profiles.
+func MdbxProfiles() []MdbxProfile {
+	return []MdbxProfile{MdbxProfileDurable, MdbxProfileBalanced, MdbxProfileIngest}
+}
+
+// Validate checks that the profile is empty or one of the supported named profiles.
+func (p MdbxProfile) Validate() error {
+	if p == "" {
+		return nil
+	}
+	if _, ok := mdbxProfiles[p]; !ok {
+		return fmt.Errorf("unknown mdbx profile: %s", p)
+	}
+	return nil
+}
+
 // MdbxNode represents the configuration for an individual MDBX node. Each node
 // corresponds to an instance of the MDBX database, with specific configurations for
 // file path, size, and performance optimizations.
 type MdbxNode struct {
 	// Name is the identifier for the MDBX node, allowing the system to distinguish between multiple nodes.
-	Name string `yaml:"name"`
+	Name string `yaml:"name" json:"name"`
 
 	// Path specifies the file system path where the MDBX database files are stored.
-	Path string `yaml:"path"`
+	Path string `yaml:"path" json:"path"`
 
 	// MaxReaders defines the maximum number of readers allowed for the MDBX instance.
 	// This controls how many concurrent read transactions can be active at the same time.
-	MaxReaders int `yaml:"maxReaders"`
+	MaxReaders int `yaml:"maxReaders" json:"maxReaders"`
 
-	// MaxSize defines the maximum size of the MDBX database in bytes. This is the upper limit
+	// MaxSize defines the maximum size of the MDBX database in gigabytes. This is the upper limit
 	// on the size the database can grow to on disk.
-	MaxSize int64 `yaml:"maxSize"`
+	MaxSize int64 `yaml:"maxSize" json:"maxSize"`
 
-	// MinSize defines the minimum size of the MDBX database in bytes. The database will allocate
+	// MinSize defines the minimum size of the MDBX database in gigabytes. The database will allocate
 	// at least this amount of space on disk.
-	MinSize int64 `yaml:"minSize"`
+	MinSize int64 `yaml:"minSize" json:"minSize"`
 
 	// GrowthStep specifies the size in bytes by which the MDBX database will grow when it needs more space.
 	// This controls how efficiently the database expands on disk.
-	GrowthStep int64 `yaml:"growthStep"`
+	GrowthStep int64 `yaml:"growthStep" json:"growthStep"`
 
 	// FilePermissions sets the file system permissions for the MDBX database files. It defaults to 0600,
 	// which grants read and write access to the file owner only.
-	FilePermissions uint `yaml:"filePermissions"`
+	FilePermissions uint `yaml:"filePermissions" json:"filePermissions"`
+
+	// Profile selects a named preset (durable, balanced, ingest) whose settings are applied
+	// on top of this node. Explicitly configured values take precedence over the profile.
+	Profile MdbxProfile `yaml:"profile" json:"profile"`
+
+	// SyncMode controls the durability of commits (durable, nometasync, safenosync, utterlynosync).
+	// Defaults to durable when neither the node nor its profile sets it.
+	SyncMode MdbxSyncMode `yaml:"syncMode" json:"syncMode"`
+
+	// SyncPeriod is the interval at which the environment is flushed to disk when a relaxed
+	// sync mode is in use. Zero disables the periodic sync.
+	SyncPeriod time.Duration `yaml:"syncPeriod" json:"syncPeriod"`
+
+	// WriteMap enables the writable memory map (MDBX_WRITEMAP), trading safety against stray
+	// pointer writes for faster commits. The flags below are left to the profile when unset,
+	// and an explicit false turns off what the profile enables.
+	WriteMap *bool `yaml:"writeMap" json:"writeMap"`
+
+	// NoReadahead disables OS readahead (MDBX_NORDAHEAD), useful when the database is larger than RAM.
+	NoReadahead *bool `yaml:"noReadahead" json:"noReadahead"`
+
+	// Coalesce enables coalescing of freed pages in the GC (MDBX_COALESCE).
+	Coalesce *bool `yaml:"coalesce" json:"coalesce"`
+
+	// LifoReclaim reuses the most recently freed pages first (MDBX_LIFORECLAIM).
+	LifoReclaim *bool `yaml:"lifoReclaim" json:"lifoReclaim"`
+
+	// PageSize sets the database page size in bytes. It must be a power of two between 256 and 65536.
+	// Zero keeps the MDBX default (the OS page size).
+	PageSize int `yaml:"pageSize" json:"pageSize"`
+
+	// MaxDBs limits the number of named sub-databases (DBIs) that can be opened in the environment.
+	// It is raised to leave room for the internal DBIs (change log, replication state) when lower.
+	MaxDBs int `yaml:"maxDbs" json:"maxDbs"`
+
+	// Cdc configures the change data capture log of the node.
+	Cdc Cdc `yaml:"cdc" json:"cdc"`
+}
+
+// WithProfile returns a copy of the node with its profile settings applied. Values set
+// explicitly on the node win over the profile, including flags set to false.
+//
+// Example usage:
+//
+//	node, err := nodeConfig.WithProfile()
+//	if err != nil {
+//	    log.Fatalf("Invalid MDBX profile: %v", err)
+//	}
+//
+// Returns:
+//
+//	MdbxNode: The node with profile defaults applied.
+//	error: Returns an error if the profile is unknown.
+func (n MdbxNode) WithProfile() (MdbxNode, error) {
+	if n.Profile == "" {
+		return n, nil
+	}
+
+	profile, ok := mdbxProfiles[n.Profile]
+	if !ok {
+		return n, fmt.Errorf("unknown mdbx profile: %s", n.Profile)
+	}
+
+	if n.SyncMode == "" {
+		n.SyncMode = profile.SyncMode
+	}
+	if n.SyncPeriod == 0 {
+		n.SyncPeriod = profile.SyncPeriod
+	}
+	n.WriteMap = inheritFlag(n.WriteMap, profile.WriteMap)
+	n.NoReadahead = inheritFlag(n.NoReadahead, profile.NoReadahead)
+	n.Coalesce = inheritFlag(n.Coalesce, profile.Coalesce)
+	n.LifoReclaim = inheritFlag(n.LifoReclaim, profile.LifoReclaim)
+
+	return n, nil
+}
+
+// Validate checks the MDBX node configuration for missing or inconsistent values.
+//
+// Returns:
+//
+//	error: Returns nil if the node is valid, or an error describing the first problem found.
+func (n MdbxNode) Validate() error {
+	if n.Name == "" {
+		return fmt.Errorf("mdbx node name must not be empty")
+	}
+	if n.Path == "" {
+		return fmt.Errorf("mdbx node %s: path must not be empty", n.Name)
+	}
+	if err := n.Profile.Validate(); err != nil {
+		return fmt.Errorf("mdbx node %s: %w", n.Name, err)
+	}
+	if err := n.SyncMode.Validate(); err != nil {
+		return fmt.Errorf("mdbx node %s: %w", n.Name, err)
+	}
+	if n.SyncPeriod < 0 {
+		return fmt.Errorf("mdbx node %s: sync period must not be negative", n.Name)
+	}
+	if n.MinSize < 0 || n.MaxSize < 0 {
+		return fmt.Errorf("mdbx node %s: database sizes must not be negative", n.Name)
+	}
+	if n.MaxSize > 0 && n.MinSize > n.MaxSize {
+		return fmt.Errorf("mdbx node %s: minSize (%d) exceeds maxSize (%d)", n.Name, n.MinSize, n.MaxSize)
+	}
+	if n.PageSize != 0 && (n.PageSize < 256 || n.PageSize > 65536 || n.PageSize&(n.PageSize-1) != 0) {
+		return fmt.Errorf("mdbx node %s: page size must be a power of two between 256 and 65536, got %d", n.Name, n.PageSize)
+	}
+	if n.MaxDBs < 0 {
+		return fmt.Errorf("mdbx node %s: maxDbs must not be negative", n.Name)
+	}
+	if err := n.Cdc.Validate(); err != nil {
+		return fmt.Errorf("mdbx node %s: %w", n.Name, err)
+	}
+	return nil
 }
 
 // Mdbx represents the global MDBX configuration. It enables or disables MDBX functionality
@@ -40,6 +269,35 @@ type Mdbx struct {
 	// Nodes is a list of MDBX nodes. Each node contains its own configuration, allowing
 	// multiple MDBX databases to be configured with different paths, sizes, and performance settings.
 	Nodes []MdbxNode `yaml:"nodes"`
+
+	// DataDir is the directory where databases created at runtime are stored (one sub-directory
+	// per database unless an explicit path is given) together with the registry file that keeps
+	// track of them across restarts. Runtime creation is disabled when empty.
+	DataDir string `yaml:"dataDir"`
+}
+
+// Validate checks every configured MDBX node and ensures node names are unique.
+// Validation is skipped entirely when MDBX is disabled.
+//
+// Returns:
+//
+//	error: Returns nil if the configuration is valid, or an error if validation fails.
+func (m Mdbx) Validate() error {
+	if !m.Enabled {
+		return nil
+	}
+
+	seen := make(map[string]struct{}, len(m.Nodes))
+	for _, node := range m.Nodes {
+		if err := node.Validate(); err != nil {
+			return err
+		}
+		if _, exists := seen[node.Name]; exists {
+			return fmt.Errorf("duplicate mdbx node name: %s", node.Name)
+		}
+		seen[node.Name] = struct{}{}
+	}
+	return nil
 }
 
 // GetMdbxNodeByName searches for an MDBX node by its name and returns the corresponding MdbxNode configuration.
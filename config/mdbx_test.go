@@ -0,0 +1,117 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func TestMdbxNodeWithProfile(t *testing.T) {
+	tests := []struct {
+		name     string
+		node     MdbxNode
+		expected MdbxNode
+		wantErr  bool
+	}{
+		{
+			name:     "No profile keeps node untouched",
+			node:     MdbxNode{Name: "fdb", SyncMode: MdbxSyncNoMetaSync},
+			expected: MdbxNode{Name: "fdb", SyncMode: MdbxSyncNoMetaSync},
+		},
+		{
+			name: "Ingest profile fills unset values",
+			node: MdbxNode{Name: "fdb", Profile: MdbxProfileIngest},
+			expected: MdbxNode{
+				Name: "fdb", Profile: MdbxProfileIngest, SyncMode: MdbxSyncSafeNoSync, SyncPeriod: 5 * time.Second,
+				WriteMap: Flag(true), NoReadahead: Flag(true), Coalesce: Flag(true), LifoReclaim: Flag(true),
+			},
+		},
+		{
+			name: "Explicit values win over profile",
+			node: MdbxNode{Name: "fdb", Profile: MdbxProfileBalanced, SyncMode: MdbxSyncDurable, SyncPeriod: time.Minute},
+			expected: MdbxNode{
+				Name: "fdb", Profile: MdbxProfileBalanced, SyncMode: MdbxSyncDurable, SyncPeriod: time.Minute,
+				Coalesce: Flag(true), LifoReclaim: Flag(true),
+			},
+		},
+		{
+			name: "Explicit false turns off profile flags",
+			node: MdbxNode{Name: "fdb", Profile: MdbxProfileIngest, WriteMap: Flag(false), LifoReclaim: Flag(false)},
+			expected: MdbxNode{
+				Name: "fdb", Profile: MdbxProfileIngest, SyncMode: MdbxSyncSafeNoSync, SyncPeriod: 5 * time.Second,
+				WriteMap: Flag(false), NoReadahead: Flag(true), Coalesce: Flag(true), LifoReclaim: Flag(false),
+			},
+		},
+		{
+			name:    "Unknown profile",
+			node:    MdbxNode{Name: "fdb", Profile: "reckless"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			node, err := tt.node.WithProfile()
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, node)
+		})
+	}
+}
+
+func TestMdbxValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		mdbx    Mdbx
+		wantErr bool
+	}{
+		{name: "Disabled skips validation", mdbx: Mdbx{Nodes: []MdbxNode{{}}}},
+		{name: "Valid node", mdbx: Mdbx{Enabled: true, Nodes: []MdbxNode{{Name: "fdb", Path: "/tmp/fdb", MinSize: 1, MaxSize: 10, PageSize: 4096}}}},
+		{name: "Missing path", mdbx: Mdbx{Enabled: true, Nodes: []MdbxNode{{Name: "fdb"}}}, wantErr: true},
+		{name: "Unknown sync mode", mdbx: Mdbx{Enabled: true, Nodes: []MdbxNode{{Name: "fdb", Path: "/tmp", SyncMode: "sometimes"}}}, wantErr: true},
+		{name: "Min size exceeds max size", mdbx: Mdbx{Enabled: true, Nodes: []MdbxNode{{Name: "fdb", Path: "/tmp", MinSize: 2, MaxSize: 1}}}, wantErr: true},
+		{name: "Page size not power of two", mdbx: Mdbx{Enabled: true, Nodes: []MdbxNode{{Name: "fdb", Path: "/tmp", PageSize: 3000}}}, wantErr: true},
+		{name: "Duplicate names", mdbx: Mdbx{Enabled: true, Nodes: []MdbxNode{{Name: "fdb", Path: "/a"}, {Name: "fdb", Path: "/b"}}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.mdbx.Validate()
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestMdbxNodeUnmarshalYAML(t *testing.T) {
+	data := []byte(`
+name: fdb
+path: /tmp/fdb
+profile: balanced
+syncMode: safenosync
+syncPeriod: 250ms
+pageSize: 8192
+maxDbs: 16
+writeMap: false
+`)
+
+	var node MdbxNode
+	require.NoError(t, yaml.Unmarshal(data, &node))
+	assert.Equal(t, MdbxProfileBalanced, node.Profile)
+	assert.Equal(t, MdbxSyncSafeNoSync, node.SyncMode)
+	assert.Equal(t, 250*time.Millisecond, node.SyncPeriod)
+	assert.Equal(t, 8192, node.PageSize)
+	assert.Equal(t, 16, node.MaxDBs)
+	assert.Equal(t, Flag(false), node.WriteMap)
+	assert.Nil(t, node.Coalesce)
+	assert.NoError(t, node.Validate())
+}
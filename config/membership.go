@@ -0,0 +1,143 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// Membership defaults, applied when the corresponding setting is left empty.
+const (
+	DefaultMembershipProbeInterval    = time.Second
+	DefaultMembershipProbeTimeout     = 500 * time.Millisecond
+	DefaultMembershipSuspicionTimeout = 5 * time.Second
+	DefaultMembershipPushPullInterval = 30 * time.Second
+	DefaultMembershipIndirectChecks   = 3
+	DefaultMembershipRetransmitMult   = 4
+	DefaultMembershipReclaimTimeout   = time.Minute
+)
+
+// Membership holds the configuration of the gossip membership subsystem. Members find each
+// other through the seeds, advertise their transports, databases and roles, and detect failed
+// members by probing each other over UDP.
+type Membership struct {
+	// Enabled turns gossip membership on.
+	Enabled bool `yaml:"enabled" json:"enabled"`
+
+	// NodeName is the unique name of this node within the cluster.
+	NodeName string `yaml:"nodeName" json:"nodeName"`
+
+	// BindAddr is the UDP address gossip is received on (host:port).
+	BindAddr string `yaml:"bindAddr" json:"bindAddr"`
+
+	// AdvertiseAddr is the gossip address announced to the other members. Defaults to the
+	// bound address, which must then not be a wildcard address.
+	AdvertiseAddr string `yaml:"advertiseAddr" json:"advertiseAddr"`
+
+	// Seeds lists gossip addresses of existing members contacted to join the cluster.
+	Seeds []string `yaml:"seeds" json:"seeds"`
+
+	// Roles lists additional roles advertised by this node. Replication, Raft and proxy roles
+	// are derived from the configuration.
+	Roles []string `yaml:"roles" json:"roles"`
+
+	// ProbeInterval is how often a random member is probed.
+	ProbeInterval time.Duration `yaml:"probeInterval" json:"probeInterval"`
+
+	// ProbeTimeout is how long a direct probe waits for its acknowledgement before asking
+	// other members to probe indirectly.
+	ProbeTimeout time.Duration `yaml:"probeTimeout" json:"probeTimeout"`
+
+	// SuspicionTimeout is how long a member that failed a probe may refute the suspicion
+	// before it is declared failed.
+	SuspicionTimeout time.Duration `yaml:"suspicionTimeout" json:"suspicionTimeout"`
+
+	// PushPullInterval is how often the full member list is exchanged with a random member.
+	PushPullInterval time.Duration `yaml:"pushPullInterval" json:"pushPullInterval"`
+
+	// IndirectChecks is the number of members asked to probe a member that missed a direct probe.
+	IndirectChecks int `yaml:"indirectChecks" json:"indirectChecks"`
+
+	// RetransmitMult scales how many times each update is gossiped, multiplied by the
+	// logarithm of the cluster size.
+	RetransmitMult int `yaml:"retransmitMult" json:"retransmitMult"`
+
+	// ReclaimTimeout is how long failed and departed members are kept in the member list.
+	ReclaimTimeout time.Duration `yaml:"reclaimTimeout" json:"reclaimTimeout"`
+}
+
+// GetProbeInterval returns the configured probe interval, or DefaultMembershipProbeInterval when unset.
+func (m Membership) GetProbeInterval() time.Duration {
+	if m.ProbeInterval <= 0 {
+		return DefaultMembershipProbeInterval
+	}
+	return m.ProbeInterval
+}
+
+// GetProbeTimeout returns the configured probe timeout, or DefaultMembershipProbeTimeout when unset.
+func (m Membership) GetProbeTimeout() time.Duration {
+	if m.ProbeTimeout <= 0 {
+		return DefaultMembershipProbeTimeout
+	}
+	return m.ProbeTimeout
+}
+
+// GetSuspicionTimeout returns the configured suspicion timeout, or DefaultMembershipSuspicionTimeout when unset.
+func (m Membership) GetSuspicionTimeout() time.Duration {
+	if m.SuspicionTimeout <= 0 {
+		return DefaultMembershipSuspicionTimeout
+	}
+	return m.SuspicionTimeout
+}
+
+// GetPushPullInterval returns the configured push-pull interval, or DefaultMembershipPushPullInterval when unset.
+func (m Membership) GetPushPullInterval() time.Duration {
+	if m.PushPullInterval <= 0 {
+		return DefaultMembershipPushPullInterval
+	}
+	return m.PushPullInterval
+}
+
+// GetIndirectChecks returns the configured number of indirect checks, or DefaultMembershipIndirectChecks when unset.
+func (m Membership) GetIndirectChecks() int {
+	if m.IndirectChecks <= 0 {
+		return DefaultMembershipIndirectChecks
+	}
+	return m.IndirectChecks
+}
+
+// GetRetransmitMult returns the configured retransmit multiplier, or DefaultMembershipRetransmitMult when unset.
+func (m Membership) GetRetransmitMult() int {
+	if m.RetransmitMult <= 0 {
+		return DefaultMembershipRetransmitMult
+	}
+	return m.RetransmitMult
+}
+
+// GetReclaimTimeout returns the configured reclaim timeout, or DefaultMembershipReclaimTimeout when unset.
+func (m Membership) GetReclaimTimeout() time.Duration {
+	if m.ReclaimTimeout <= 0 {
+		return DefaultMembershipReclaimTimeout
+	}
+	return m.ReclaimTimeout
+}
+
+// Validate checks that enabled membership names this node and has an address to gossip on.
+//
+// Returns:
+//
+//	error: Returns nil if the configuration is valid, or an error if validation fails.
+func (m Membership) Validate() error {
+	if !m.Enabled {
+		return nil
+	}
+	if m.NodeName == "" {
+		return fmt.Errorf("membership nodeName must not be empty")
+	}
+	if m.BindAddr == "" {
+		return fmt.Errorf("membership bindAddr must not be empty")
+	}
+	if m.GetProbeTimeout() >= m.GetProbeInterval() {
+		return fmt.Errorf("membership probeTimeout must be shorter than probeInterval")
+	}
+	return nil
+}
@@ -29,6 +29,10 @@ type QuicTransport struct {
 	// TLS holds the TLS configuration for the QUIC transport, as QUIC requires
 	// TLS for secure communication.
 	TLS TLS `yaml:"tls" json:"tls" mapstructure:"tls"`
+
+	// Allow0RTT accepts 0-RTT data from clients resuming a TLS session, saving a round trip on
+	// reconnects. 0-RTT data can be replayed by an attacker, so it is disabled by default.
+	Allow0RTT bool `yaml:"allow0rtt" json:"allow0rtt" mapstructure:"allow0rtt"`
 }
 
 // Addr returns the full address (IPv4 and port) as a string for the QUIC transport.
@@ -127,6 +131,7 @@ func (q QuicTransport) GetTLSConfig() (*tls.Config, error) {
 //	  cert: "/path/to/cert.pem"
 //	  key: "/path/to/key.pem"
 //	  rootCa: "/path/to/rootCA.pem"
+//	allow0rtt: false
 //
 // Parameters:
 //
@@ -138,11 +143,12 @@ func (q QuicTransport) GetTLSConfig() (*tls.Config, error) {
 func (q *QuicTransport) UnmarshalYAML(value *yaml.Node) error {
 	// Create a temporary struct to capture the common fields
 	aux := struct {
-		Type    types.TransportType `yaml:"type"`
-		Enabled bool                `yaml:"enabled"`
-		IPv4    string              `yaml:"ipv4"`
-		Port    int                 `yaml:"port"`
-		TLS     TLS                 `yaml:"tls"`
+		Type      types.TransportType `yaml:"type"`
+		Enabled   bool                `yaml:"enabled"`
+		IPv4      string              `yaml:"ipv4"`
+		Port      int                 `yaml:"port"`
+		TLS       TLS                 `yaml:"tls"`
+		Allow0RTT bool                `yaml:"allow0rtt"`
 	}{}
 
 	// Unmarshal the common fields, including the nested TLS config
@@ -156,6 +162,7 @@ func (q *QuicTransport) UnmarshalYAML(value *yaml.Node) error {
 	q.IPv4 = aux.IPv4
 	q.Port = aux.Port
 	q.TLS = aux.TLS
+	q.Allow0RTT = aux.Allow0RTT
 
 	return nil
 }
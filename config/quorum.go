@@ -0,0 +1,178 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// Quorum defaults, applied when the corresponding setting is left empty.
+const (
+	DefaultQuorumReplicas        = 3
+	DefaultQuorumMaxHints        = 100000
+	DefaultQuorumHandoffInterval = 10 * time.Second
+)
+
+// Quorum holds the configuration of Dynamo-style quorum replication. Every key of the listed
+// databases is stored on Replicas nodes, picked by a consistent-hash ring over Nodes. Any node
+// coordinates the requests it receives: writes are acknowledged once WriteQuorum replicas
+// stored them, reads answer once ReadQuorum replicas responded with the newest version. Clients
+// override both per request through the consistency header of the transports.
+type Quorum struct {
+	// Enabled turns quorum replication on.
+	Enabled bool `yaml:"enabled" json:"enabled"`
+
+	// NodeID is the ID of this node among Nodes.
+	NodeID string `yaml:"nodeId" json:"nodeId"`
+
+	// Databases lists the databases replicated with quorums. Every node stores them in MDBX
+	// nodes of the same names.
+	Databases []string `yaml:"databases" json:"databases"`
+
+	// Nodes lists every node holding replicas, this one included. Key placement is derived
+	// from the node IDs, so every node must list the same nodes.
+	Nodes []ShardNode `yaml:"nodes" json:"nodes"`
+
+	// VirtualNodes is the number of ring positions of each node.
+	VirtualNodes int `yaml:"virtualNodes" json:"virtualNodes"`
+
+	// Replicas is the number of nodes storing each key (N), at most the number of nodes.
+	Replicas int `yaml:"replicas" json:"replicas"`
+
+	// ReadQuorum is the default number of replicas answering a read (R). Defaults to a majority.
+	ReadQuorum int `yaml:"readQuorum" json:"readQuorum"`
+
+	// WriteQuorum is the default number of replicas acknowledging a write (W). Defaults to a majority.
+	WriteQuorum int `yaml:"writeQuorum" json:"writeQuorum"`
+
+	// MaxHints bounds the writes kept for unreachable replicas. Writes beyond it are left to
+	// anti-entropy repair.
+	MaxHints int `yaml:"maxHints" json:"maxHints"`
+
+	// HandoffInterval is how often kept writes are delivered to the replicas they missed.
+	HandoffInterval time.Duration `yaml:"handoffInterval" json:"handoffInterval"`
+
+	// PoolSize is the number of connections kept to each node.
+	PoolSize int `yaml:"poolSize" json:"poolSize"`
+
+	// RequestTimeout bounds a single request to a replica.
+	RequestTimeout time.Duration `yaml:"requestTimeout" json:"requestTimeout"`
+}
+
+// GetVirtualNodes returns the configured number of virtual nodes, or DefaultShardVirtualNodes when unset.
+func (q Quorum) GetVirtualNodes() int {
+	if q.VirtualNodes <= 0 {
+		return DefaultShardVirtualNodes
+	}
+	return q.VirtualNodes
+}
+
+// GetReplicas returns the number of replicas of each key, DefaultQuorumReplicas when unset, at
+// most the number of nodes.
+func (q Quorum) GetReplicas() int {
+	n := q.Replicas
+	if n <= 0 {
+		n = DefaultQuorumReplicas
+	}
+	if n > len(q.Nodes) {
+		n = len(q.Nodes)
+	}
+	return n
+}
+
+// GetReadQuorum returns the configured read quorum, or a majority of the replicas when unset.
+func (q Quorum) GetReadQuorum() int {
+	if q.ReadQuorum <= 0 {
+		return q.GetReplicas()/2 + 1
+	}
+	return q.ReadQuorum
+}
+
+// GetWriteQuorum returns the configured write quorum, or a majority of the replicas when unset.
+func (q Quorum) GetWriteQuorum() int {
+	if q.WriteQuorum <= 0 {
+		return q.GetReplicas()/2 + 1
+	}
+	return q.WriteQuorum
+}
+
+// GetMaxHints returns the configured hint limit, or DefaultQuorumMaxHints when unset.
+func (q Quorum) GetMaxHints() int {
+	if q.MaxHints <= 0 {
+		return DefaultQuorumMaxHints
+	}
+	return q.MaxHints
+}
+
+// GetHandoffInterval returns the configured handoff interval, or DefaultQuorumHandoffInterval when unset.
+func (q Quorum) GetHandoffInterval() time.Duration {
+	if q.HandoffInterval <= 0 {
+		return DefaultQuorumHandoffInterval
+	}
+	return q.HandoffInterval
+}
+
+// GetPoolSize returns the configured pool size, or DefaultShardPoolSize when unset.
+func (q Quorum) GetPoolSize() int {
+	if q.PoolSize <= 0 {
+		return DefaultShardPoolSize
+	}
+	return q.PoolSize
+}
+
+// GetRequestTimeout returns the configured request timeout, or DefaultShardRequestTimeout when unset.
+func (q Quorum) GetRequestTimeout() time.Duration {
+	if q.RequestTimeout <= 0 {
+		return DefaultShardRequestTimeout
+	}
+	return q.RequestTimeout
+}
+
+// Replicates reports whether the named database is replicated with quorums.
+func (q Quorum) Replicates(name string) bool {
+	return contains(q.Databases, name)
+}
+
+// contains reports whether names holds name.
+func contains(names []string, name string) bool {
+	for _, other := range names {
+		if other == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate checks that enabled quorum replication lists its databases and valid, unique nodes,
+// this one included, and that the quorums fit the number of replicas.
+//
+// Returns:
+//
+//	error: Returns nil if the configuration is valid, or an error if validation fails.
+func (q Quorum) Validate() error {
+	if !q.Enabled {
+		return nil
+	}
+	if len(q.Databases) == 0 {
+		return fmt.Errorf("quorum replication must replicate at least one database")
+	}
+	if err := ValidateShards(q.Nodes); err != nil {
+		return err
+	}
+
+	found := false
+	for _, node := range q.Nodes {
+		found = found || node.ID == q.NodeID
+	}
+	if !found {
+		return fmt.Errorf("node id %q is not one of the quorum nodes", q.NodeID)
+	}
+
+	n := q.GetReplicas()
+	if r := q.GetReadQuorum(); r > n {
+		return fmt.Errorf("read quorum %d exceeds the %d replicas", r, n)
+	}
+	if w := q.GetWriteQuorum(); w > n {
+		return fmt.Errorf("write quorum %d exceeds the %d replicas", w, n)
+	}
+	return nil
+}
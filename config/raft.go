@@ -0,0 +1,169 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// Raft defaults, applied when the corresponding setting is left empty.
+const (
+	DefaultRaftApplyTimeout      = 5 * time.Second
+	DefaultRaftHeartbeatTimeout  = time.Second
+	DefaultRaftElectionTimeout   = time.Second
+	DefaultRaftSnapshotThreshold = 8192
+	DefaultRaftSnapshotInterval  = 2 * time.Minute
+	DefaultRaftTrailingLogs      = 10240
+)
+
+// RaftPeer identifies a member of the Raft group.
+type RaftPeer struct {
+	// ID is the unique, stable identifier of the node within the group.
+	ID string `yaml:"id" json:"id"`
+
+	// Addr is the Raft address of the node (host:port).
+	Addr string `yaml:"addr" json:"addr"`
+}
+
+// Raft holds the configuration of the optional Raft replication group. Databases managed by
+// the group accept writes only once they are committed by a quorum of its members; writes
+// received by a follower are forwarded to the leader.
+type Raft struct {
+	// Enabled turns the Raft group on.
+	Enabled bool `yaml:"enabled" json:"enabled"`
+
+	// NodeID is the unique, stable identifier of this node within the group.
+	NodeID string `yaml:"nodeId" json:"nodeId"`
+
+	// Addr is the address the Raft transport listens on and advertises to its peers (host:port).
+	Addr string `yaml:"addr" json:"addr"`
+
+	// DataDir holds the Raft log and snapshots of this node.
+	DataDir string `yaml:"dataDir" json:"dataDir"`
+
+	// Bootstrap forms a new group from Peers on first start. Only one node, or every initial
+	// node with the same Peers, should bootstrap; the others join through membership changes.
+	Bootstrap bool `yaml:"bootstrap" json:"bootstrap"`
+
+	// Peers lists the initial voters, including this node, used when bootstrapping.
+	// Defaults to this node alone.
+	Peers []RaftPeer `yaml:"peers" json:"peers"`
+
+	// Databases lists the databases managed by the group. They must exist on every member.
+	Databases []string `yaml:"databases" json:"databases"`
+
+	// ApplyTimeout bounds how long a write waits to be committed.
+	ApplyTimeout time.Duration `yaml:"applyTimeout" json:"applyTimeout"`
+
+	// HeartbeatTimeout is how long a follower waits without contact from the leader before
+	// starting an election.
+	HeartbeatTimeout time.Duration `yaml:"heartbeatTimeout" json:"heartbeatTimeout"`
+
+	// ElectionTimeout is how long a candidate waits before starting a new election.
+	ElectionTimeout time.Duration `yaml:"electionTimeout" json:"electionTimeout"`
+
+	// SnapshotThreshold is the number of log entries after which a snapshot is taken.
+	SnapshotThreshold uint64 `yaml:"snapshotThreshold" json:"snapshotThreshold"`
+
+	// SnapshotInterval is how often the snapshot threshold is checked.
+	SnapshotInterval time.Duration `yaml:"snapshotInterval" json:"snapshotInterval"`
+
+	// TrailingLogs is the number of log entries kept after a snapshot, so slightly lagging
+	// members catch up from the log rather than from a full snapshot.
+	TrailingLogs uint64 `yaml:"trailingLogs" json:"trailingLogs"`
+}
+
+// GetApplyTimeout returns the configured apply timeout, or DefaultRaftApplyTimeout when unset.
+func (r Raft) GetApplyTimeout() time.Duration {
+	if r.ApplyTimeout <= 0 {
+		return DefaultRaftApplyTimeout
+	}
+	return r.ApplyTimeout
+}
+
+// GetHeartbeatTimeout returns the configured heartbeat timeout, or DefaultRaftHeartbeatTimeout when unset.
+func (r Raft) GetHeartbeatTimeout() time.Duration {
+	if r.HeartbeatTimeout <= 0 {
+		return DefaultRaftHeartbeatTimeout
+	}
+	return r.HeartbeatTimeout
+}
+
+// GetElectionTimeout returns the configured election timeout, or DefaultRaftElectionTimeout when unset.
+func (r Raft) GetElectionTimeout() time.Duration {
+	if r.ElectionTimeout <= 0 {
+		return DefaultRaftElectionTimeout
+	}
+	return r.ElectionTimeout
+}
+
+// GetSnapshotThreshold returns the configured snapshot threshold, or DefaultRaftSnapshotThreshold when unset.
+func (r Raft) GetSnapshotThreshold() uint64 {
+	if r.SnapshotThreshold == 0 {
+		return DefaultRaftSnapshotThreshold
+	}
+	return r.SnapshotThreshold
+}
+
+// GetSnapshotInterval returns the configured snapshot interval, or DefaultRaftSnapshotInterval when unset.
+func (r Raft) GetSnapshotInterval() time.Duration {
+	if r.SnapshotInterval <= 0 {
+		return DefaultRaftSnapshotInterval
+	}
+	return r.SnapshotInterval
+}
+
+// GetTrailingLogs returns the configured number of trailing logs, or DefaultRaftTrailingLogs when unset.
+func (r Raft) GetTrailingLogs() uint64 {
+	if r.TrailingLogs == 0 {
+		return DefaultRaftTrailingLogs
+	}
+	return r.TrailingLogs
+}
+
+// GetPeers returns the initial voters, defaulting to this node alone.
+func (r Raft) GetPeers() []RaftPeer {
+	if len(r.Peers) == 0 {
+		return []RaftPeer{{ID: r.NodeID, Addr: r.Addr}}
+	}
+	return r.Peers
+}
+
+// Validate checks that an enabled group identifies this node and manages at least one database.
+//
+// Returns:
+//
+//	error: Returns nil if the configuration is valid, or an error if validation fails.
+func (r Raft) Validate() error {
+	if !r.Enabled {
+		return nil
+	}
+	if r.NodeID == "" {
+		return fmt.Errorf("raft nodeId must not be empty")
+	}
+	if r.Addr == "" {
+		return fmt.Errorf("raft addr must not be empty")
+	}
+	if r.DataDir == "" {
+		return fmt.Errorf("raft dataDir must not be empty")
+	}
+	if len(r.Databases) == 0 {
+		return fmt.Errorf("raft must manage at least one database")
+	}
+
+	seen := make(map[string]struct{}, len(r.Peers))
+	for _, peer := range r.Peers {
+		if peer.ID == "" || peer.Addr == "" {
+			return fmt.Errorf("raft peers need both id and addr")
+		}
+		if _, dup := seen[peer.ID]; dup {
+			return fmt.Errorf("duplicate raft peer: %s", peer.ID)
+		}
+		seen[peer.ID] = struct{}{}
+	}
+	if len(r.Peers) > 0 {
+		if _, self := seen[r.NodeID]; !self {
+			return fmt.Errorf("raft peers must include this node (%s)", r.NodeID)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package config
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/unpackdev/fdb/types"
+)
+
+// DefaultReplicationRetryInterval is how long a follower waits before reconnecting to its leader.
+const DefaultReplicationRetryInterval = 2 * time.Second
+
+// ReplicationRole defines whether a node accepts writes or replicates from a leader.
+type ReplicationRole string
+
+const (
+	// ReplicationStandalone nodes neither replicate nor expect followers. This is the default.
+	ReplicationStandalone ReplicationRole = ""
+
+	// ReplicationLeader nodes accept writes and serve snapshots and change streams to followers.
+	ReplicationLeader ReplicationRole = "leader"
+
+	// ReplicationFollower nodes replicate from a leader, serve reads and reject writes.
+	ReplicationFollower ReplicationRole = "follower"
+)
+
+// ReplicationPeer describes how a follower reaches its leader.
+type ReplicationPeer struct {
+	// Transport is the leader transport to connect through, tcp or quic.
+	Transport types.TransportType `yaml:"transport" json:"transport"`
+
+	// Addr is the leader transport address (host:port).
+	Addr string `yaml:"addr" json:"addr"`
+
+	// Insecure skips verifying the leader certificate when the transport uses TLS.
+	Insecure bool `yaml:"insecure" json:"insecure"`
+}
+
+// Validate checks that the peer uses a stream transport and has an address.
+func (p ReplicationPeer) Validate() error {
+	if p.Transport != types.TCPTransportType && p.Transport != types.QUICTransportType {
+		return fmt.Errorf("replication leader transport must be tcp or quic, got %s", p.Transport)
+	}
+	if p.Addr == "" {
+		return fmt.Errorf("replication leader addr must not be empty")
+	}
+	return nil
+}
+
+// Replication holds the leader-follower replication configuration of the node. Leaders need
+// CDC enabled on every replicated database; followers should enable it as well so they can be
+// promoted and serve followers of their own.
+type Replication struct {
+	// Role is the replication role of the node: leader, follower, or empty for standalone.
+	Role ReplicationRole `yaml:"role" json:"role"`
+
+	// Leader is the node followers replicate from. Only used by followers.
+	Leader ReplicationPeer `yaml:"leader" json:"leader"`
+
+	// Databases lists the databases to replicate. Defaults to every configured MDBX node.
+	Databases []string `yaml:"databases" json:"databases"`
+
+	// RetryInterval is how long a follower waits before reconnecting after a failure.
+	// Defaults to DefaultReplicationRetryInterval.
+	RetryInterval time.Duration `yaml:"retryInterval" json:"retryInterval"`
+}
+
+// GetRetryInterval returns the configured retry interval, or DefaultReplicationRetryInterval when unset.
+func (r Replication) GetRetryInterval() time.Duration {
+	if r.RetryInterval <= 0 {
+		return DefaultReplicationRetryInterval
+	}
+	return r.RetryInterval
+}
+
+// Replicates reports whether the named database is replicated.
+func (r Replication) Replicates(name string) bool {
+	if len(r.Databases) == 0 {
+		return true
+	}
+	for _, database := range r.Databases {
+		if database == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate checks the replication role and, for followers, the leader definition.
+//
+// Returns:
+//
+//	error: Returns nil if the configuration is valid, or an error if validation fails.
+func (r Replication) Validate() error {
+	switch r.Role {
+	case ReplicationStandalone, ReplicationLeader:
+		return nil
+	case ReplicationFollower:
+		return r.Leader.Validate()
+	default:
+		return fmt.Errorf("unknown replication role: %s", r.Role)
+	}
+}
@@ -0,0 +1,129 @@
+package config
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/unpackdev/fdb/types"
+)
+
+// Sharding defaults, applied when the corresponding setting is left empty.
+const (
+	DefaultShardVirtualNodes   = 128
+	DefaultShardPoolSize       = 8
+	DefaultShardRequestTimeout = 5 * time.Second
+)
+
+// ShardNode describes a shard of the cluster and how the proxy reaches it.
+type ShardNode struct {
+	// ID is the unique, stable identifier of the shard. Key ownership is derived from it, so
+	// renaming a shard moves its keys.
+	ID string `yaml:"id" json:"id"`
+
+	// Transport is the shard transport requests are forwarded through, tcp or quic.
+	Transport types.TransportType `yaml:"transport" json:"transport"`
+
+	// Addr is the shard transport address (host:port).
+	Addr string `yaml:"addr" json:"addr"`
+
+	// Insecure skips verifying the shard certificate when the transport uses TLS.
+	Insecure bool `yaml:"insecure" json:"insecure"`
+}
+
+// Validate checks that the shard is identified and uses a stream transport.
+func (s ShardNode) Validate() error {
+	if s.ID == "" {
+		return fmt.Errorf("shard id must not be empty")
+	}
+	if s.Transport != types.TCPTransportType && s.Transport != types.QUICTransportType {
+		return fmt.Errorf("shard %s transport must be tcp or quic, got %s", s.ID, s.Transport)
+	}
+	if s.Addr == "" {
+		return fmt.Errorf("shard %s addr must not be empty", s.ID)
+	}
+	return nil
+}
+
+// Sharding holds the configuration of the routing proxy role. A proxy stores no data itself:
+// it serves the listed databases on its transports and forwards every request to the shard
+// owning the key, as decided by a consistent-hash ring over the shards.
+type Sharding struct {
+	// Enabled turns the node into a routing proxy.
+	Enabled bool `yaml:"enabled" json:"enabled"`
+
+	// Databases lists the databases served through the proxy. Every shard serves them under the same names.
+	Databases []string `yaml:"databases" json:"databases"`
+
+	// VirtualNodes is the number of ring positions of each shard. More positions spread keys more evenly.
+	VirtualNodes int `yaml:"virtualNodes" json:"virtualNodes"`
+
+	// Shards is the initial shard map, used until a newer one is stored in MapFile.
+	Shards []ShardNode `yaml:"shards" json:"shards"`
+
+	// MapFile stores the current shard map, so the map installed by the last rebalance survives restarts.
+	MapFile string `yaml:"mapFile" json:"mapFile"`
+
+	// PoolSize is the number of connections kept to each shard.
+	PoolSize int `yaml:"poolSize" json:"poolSize"`
+
+	// RequestTimeout bounds a single request forwarded to a shard.
+	RequestTimeout time.Duration `yaml:"requestTimeout" json:"requestTimeout"`
+}
+
+// GetVirtualNodes returns the configured number of virtual nodes, or DefaultShardVirtualNodes when unset.
+func (s Sharding) GetVirtualNodes() int {
+	if s.VirtualNodes <= 0 {
+		return DefaultShardVirtualNodes
+	}
+	return s.VirtualNodes
+}
+
+// GetPoolSize returns the configured pool size, or DefaultShardPoolSize when unset.
+func (s Sharding) GetPoolSize() int {
+	if s.PoolSize <= 0 {
+		return DefaultShardPoolSize
+	}
+	return s.PoolSize
+}
+
+// GetRequestTimeout returns the configured request timeout, or DefaultShardRequestTimeout when unset.
+func (s Sharding) GetRequestTimeout() time.Duration {
+	if s.RequestTimeout <= 0 {
+		return DefaultShardRequestTimeout
+	}
+	return s.RequestTimeout
+}
+
+// Validate checks that an enabled proxy serves at least one database and knows at least one shard.
+//
+// Returns:
+//
+//	error: Returns nil if the configuration is valid, or an error if validation fails.
+func (s Sharding) Validate() error {
+	if !s.Enabled {
+		return nil
+	}
+	if len(s.Databases) == 0 {
+		return fmt.Errorf("sharding must serve at least one database")
+	}
+	return ValidateShards(s.Shards)
+}
+
+// ValidateShards checks every shard of a shard map and that their identifiers are unique.
+func ValidateShards(shards []ShardNode) error {
+	if len(shards) == 0 {
+		return fmt.Errorf("sharding needs at least one shard")
+	}
+
+	seen := make(map[string]struct{}, len(shards))
+	for _, shard := range shards {
+		if err := shard.Validate(); err != nil {
+			return err
+		}
+		if _, dup := seen[shard.ID]; dup {
+			return fmt.Errorf("duplicate shard: %s", shard.ID)
+		}
+		seen[shard.ID] = struct{}{}
+	}
+	return nil
+}
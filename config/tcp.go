@@ -56,8 +56,9 @@ func (t TcpTransport) GetTransportType() types.TransportType {
 	return t.Type
 }
 
-// GetTLSConfig loads the TLS configuration if specified. This allows the TCP transport
-// to use TLS for secure communication.
+// GetTLSConfig loads the TLS configuration if TLS is enabled. This allows the TCP transport
+// to use TLS for secure communication, accepting MinVersion and newer versions and, for TLS 1.2,
+// the CipherSuites only.
 //
 // Example usage:
 //
@@ -68,13 +69,22 @@ func (t TcpTransport) GetTransportType() types.TransportType {
 //
 // Returns:
 //
-//	*tls.Config: The TLS configuration for the TCP transport, or nil if not using TLS.
+//	*tls.Config: The TLS configuration for the TCP transport, or nil if TLS is not enabled.
 //	error: Returns an error if TLS setup fails.
 func (t TcpTransport) GetTLSConfig() (*tls.Config, error) {
-	if t.TLS == nil {
+	if t.TLS == nil || !t.TLS.Enabled {
 		return nil, nil // No TLS configuration provided
 	}
 
+	minVersion, err := ParseTLSVersion(t.TLS.MinVersion)
+	if err != nil {
+		return nil, err
+	}
+	cipherSuites, err := ParseCipherSuites(t.TLS.CipherSuites)
+	if err != nil {
+		return nil, err
+	}
+
 	// Check if the certificate file exists
 	if _, err := os.Stat(t.TLS.Cert); os.IsNotExist(err) {
 		return nil, fmt.Errorf("certificate file does not exist: %s", t.TLS.Cert)
@@ -94,6 +104,8 @@ func (t TcpTransport) GetTLSConfig() (*tls.Config, error) {
 	tlsConfig := &tls.Config{
 		InsecureSkipVerify: t.TLS.Insecure,
 		Certificates:       []tls.Certificate{cert},
+		MinVersion:         minVersion,
+		CipherSuites:       cipherSuites,
 	}
 
 	// Load the Root CA if specified
@@ -125,10 +137,12 @@ func (t TcpTransport) GetTLSConfig() (*tls.Config, error) {
 //		ipv4: "127.0.0.1"
 //		port: 4242
 //		tls:
+//	      enabled: true
 //	      insecure: true
 //		  cert: "/path/to/cert.pem"
 //		  key: "/path/to/key.pem"
 //		  rootCa: "/path/to/rootCA.pem"
+//		  minVersion: "1.3"
 //
 // Parameters:
 //
@@ -0,0 +1,69 @@
+package config
+
+import (
+	"crypto/tls"
+	"fmt"
+)
+
+// ParseTLSVersion returns the TLS version named by version, "1.2" or "1.3". An empty version
+// is TLS 1.2.
+//
+// Example usage:
+//
+//	version, err := config.ParseTLSVersion("1.3")
+//	if err != nil {
+//	    log.Fatalf("Invalid TLS version: %v", err)
+//	}
+//	tlsConfig.MinVersion = version
+//
+// Parameters:
+//
+//	version (string): The name of the version.
+//
+// Returns:
+//
+//	uint16: The TLS version, one of the tls.VersionTLS constants.
+//	error: Returns an error if the version is unknown or older than TLS 1.2.
+func ParseTLSVersion(version string) (uint16, error) {
+	switch version {
+	case "", "1.2":
+		return tls.VersionTLS12, nil
+	case "1.3":
+		return tls.VersionTLS13, nil
+	default:
+		return 0, fmt.Errorf("unsupported TLS version %q, expected 1.2 or 1.3", version)
+	}
+}
+
+// ParseCipherSuites returns the IDs of the cipher suites named by names, as named by
+// tls.CipherSuiteName. Suites with known security issues are rejected. No names return nil,
+// which selects the default suites of the Go TLS stack.
+//
+// Parameters:
+//
+//	names ([]string): The names of the cipher suites.
+//
+// Returns:
+//
+//	[]uint16: The IDs of the cipher suites, nil when names is empty.
+//	error: Returns an error if a suite is unknown or insecure.
+func ParseCipherSuites(names []string) ([]uint16, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	known := make(map[string]uint16)
+	for _, suite := range tls.CipherSuites() {
+		known[suite.Name] = suite.ID
+	}
+
+	ids := make([]uint16, 0, len(names))
+	for _, name := range names {
+		id, ok := known[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown or insecure TLS cipher suite: %s", name)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
@@ -0,0 +1,85 @@
+package config
+
+import (
+	"crypto/tls"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/unpackdev/fdb/types"
+)
+
+func TestParseTLSVersion(t *testing.T) {
+	tests := []struct {
+		version  string
+		expected uint16
+		wantErr  bool
+	}{
+		{version: "", expected: tls.VersionTLS12},
+		{version: "1.2", expected: tls.VersionTLS12},
+		{version: "1.3", expected: tls.VersionTLS13},
+		{version: "1.1", wantErr: true},
+		{version: "tls13", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.version, func(t *testing.T) {
+			version, err := ParseTLSVersion(tt.version)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, version)
+		})
+	}
+}
+
+func TestParseCipherSuites(t *testing.T) {
+	suites, err := ParseCipherSuites(nil)
+	require.NoError(t, err)
+	assert.Nil(t, suites)
+
+	suites, err = ParseCipherSuites([]string{"TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256", "TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256"})
+	require.NoError(t, err)
+	assert.Equal(t, []uint16{tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256, tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256}, suites)
+
+	// Insecure suites are rejected along with unknown ones
+	_, err = ParseCipherSuites([]string{"TLS_RSA_WITH_RC4_128_SHA"})
+	assert.Error(t, err)
+	_, err = ParseCipherSuites([]string{"TLS_NOPE"})
+	assert.Error(t, err)
+}
+
+func TestTransportValidateTLS(t *testing.T) {
+	transport := Transport{
+		Type:   types.TCPTransportType,
+		Config: &TcpTransport{TLS: &TLS{Enabled: true, MinVersion: "1.0"}},
+	}
+	assert.Error(t, transport.Validate())
+
+	// Disabled TLS blocks are not validated
+	transport.Config = &TcpTransport{TLS: &TLS{MinVersion: "1.0"}}
+	assert.NoError(t, transport.Validate())
+}
+
+func TestTransportValidateDTLS(t *testing.T) {
+	transport := Transport{
+		Type:   types.UDPTransportType,
+		Config: &UdpTransport{DTLS: &DTLS{Enabled: true, MinVersion: "1.2"}},
+	}
+	assert.NoError(t, transport.Validate())
+
+	// DTLS 1.3 is refused rather than silently served as DTLS 1.2
+	transport.Config = &UdpTransport{DTLS: &DTLS{Enabled: true, MinVersion: "1.3"}}
+	err := transport.Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "DTLS 1.3 is not supported")
+
+	transport.Config = &UdpTransport{DTLS: &DTLS{Enabled: true, MinVersion: "1.0"}}
+	assert.Error(t, transport.Validate())
+
+	// Disabled DTLS blocks are not validated
+	transport.Config = &UdpTransport{DTLS: &DTLS{MinVersion: "1.3"}}
+	assert.NoError(t, transport.Validate())
+}
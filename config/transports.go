@@ -12,6 +12,9 @@ import (
 type TransportConfig interface {
 	// GetTransportType returns the type of transport (e.g., UDS, Dummy).
 	GetTransportType() types.TransportType
+
+	// Addr returns the address the transport listens on.
+	Addr() string
 }
 
 // Transport holds the generic transport configuration for different types
@@ -26,6 +29,11 @@ type Transport struct {
 	// Enabled indicates whether the transport is enabled or disabled.
 	Enabled bool `yaml:"enabled"`
 
+	// Databases lists the MDBX nodes served by the transport. The first one is the default
+	// database for requests that carry no database selector. When empty, the transport serves
+	// every known database and defaults to the first configured MDBX node.
+	Databases []string `yaml:"databases"`
+
 	// Config holds the specific configuration for the given transport type.
 	// This is populated dynamically based on the Type field during unmarshalling.
 	Config TransportConfig `yaml:"-"`
@@ -46,10 +54,57 @@ type TLS struct {
 
 	// RootCA is the path to the Root Certificate Authority used for validating the server's TLS certificate.
 	RootCA string `json:"rootCa"`
+
+	// Enabled serves the TCP transport over TLS. The QUIC transport always uses TLS and ignores it.
+	Enabled bool `yaml:"enabled" json:"enabled"`
+
+	// MinVersion is the oldest TLS version accepted by the TCP transport, "1.2" or "1.3".
+	// Defaults to "1.2".
+	MinVersion string `yaml:"minVersion" json:"minVersion"`
+
+	// CipherSuites restricts the cipher suites of TLS 1.2 connections of the TCP transport to
+	// the named ones, e.g. "TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256". The suites of TLS 1.3 are
+	// not configurable. Defaults to the secure suites of the Go TLS stack.
+	CipherSuites []string `yaml:"cipherSuites" json:"cipherSuites"`
+}
+
+// Validate ensures the databases declared by the transport are named and unique, and that the
+// TLS settings of a TCP transport serving TLS are known. Whether the databases exist is checked
+// when the transport is started, as databases may be created at runtime.
+//
+// Returns:
+//
+//	error: Returns nil if the transport configuration is valid, or an error if validation fails.
+func (t Transport) Validate() error {
+	seen := make(map[string]struct{}, len(t.Databases))
+	for _, name := range t.Databases {
+		if name == "" {
+			return fmt.Errorf("transport %s declares an empty database name", t.Type)
+		}
+		if _, exists := seen[name]; exists {
+			return fmt.Errorf("transport %s declares database %s more than once", t.Type, name)
+		}
+		seen[name] = struct{}{}
+	}
+
+	if tcp, ok := t.Config.(*TcpTransport); ok && tcp.TLS != nil && tcp.TLS.Enabled {
+		if _, err := ParseTLSVersion(tcp.TLS.MinVersion); err != nil {
+			return fmt.Errorf("transport %s: %w", t.Type, err)
+		}
+		if _, err := ParseCipherSuites(tcp.TLS.CipherSuites); err != nil {
+			return fmt.Errorf("transport %s: %w", t.Type, err)
+		}
+	}
+	if udp, ok := t.Config.(*UdpTransport); ok && udp.DTLS != nil && udp.DTLS.Enabled {
+		if err := udp.DTLS.validateVersion(); err != nil {
+			return fmt.Errorf("transport %s: %w", t.Type, err)
+		}
+	}
+	return nil
 }
 
 // UnmarshalYAML unmarshals a YAML node into the Transport struct. It first decodes
-// the common transport fields (Type, Enabled) and then dynamically unmarshals the
+// the common transport fields (Type, Enabled, Databases) and then dynamically unmarshals the
 // specific transport configuration (DummyTransport, UdsTransport, QuicTransport)
 // based on the transport type.
 //
@@ -57,6 +112,7 @@ type TLS struct {
 //
 //	type: uds
 //	enabled: true
+//	databases: [fdb, contracts]
 //	config:
 //	  socket: /tmp/my-uds.sock
 //
@@ -70,9 +126,10 @@ type TLS struct {
 func (t *Transport) UnmarshalYAML(value *yaml.Node) error {
 	// Create a temporary struct to capture the common fields
 	aux := struct {
-		Type    types.TransportType `yaml:"type"`
-		Enabled bool                `yaml:"enabled"`
-		Config  yaml.Node           `yaml:"config"` // Capture the nested "config" as a raw YAML node
+		Type      types.TransportType `yaml:"type"`
+		Enabled   bool                `yaml:"enabled"`
+		Databases []string            `yaml:"databases"`
+		Config    yaml.Node           `yaml:"config"` // Capture the nested "config" as a raw YAML node
 	}{}
 
 	// Unmarshal the common fields, including the raw "config" node
@@ -82,6 +139,7 @@ func (t *Transport) UnmarshalYAML(value *yaml.Node) error {
 
 	t.Type = aux.Type
 	t.Enabled = aux.Enabled
+	t.Databases = aux.Databases
 
 	// Depending on the transport type, decode the "config" field into the appropriate struct
 	switch t.Type {
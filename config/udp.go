@@ -4,14 +4,24 @@ import (
 	"crypto/tls"
 	"crypto/x509"
 	"fmt"
+	"github.com/pion/dtls/v3"
 	"github.com/unpackdev/fdb/types"
 	"gopkg.in/yaml.v3"
 	"os"
+	"time"
 )
 
+// DefaultDTLSSessionTimeout is how long the UDP transport keeps the DTLS session of an idle peer.
+const DefaultDTLSSessionTimeout = 5 * time.Minute
+
 // DTLS represents the DTLS configuration used by the UDP transport.
-// It is similar to TLS, but designed for datagram-based communication.
+// It is similar to TLS, but designed for datagram-based communication. Only DTLS 1.2 is
+// supported, pion/dtls does not implement DTLS 1.3.
 type DTLS struct {
+	// Enabled determines whether the UDP transport serves DTLS. When false the block is ignored
+	// and the transport serves plaintext datagrams.
+	Enabled bool `yaml:"enabled" json:"enabled" mapstructure:"enabled"`
+
 	// Cert is the path to the certificate file used for DTLS encryption.
 	Cert string `yaml:"cert" json:"cert" mapstructure:"cert"`
 
@@ -23,6 +33,35 @@ type DTLS struct {
 
 	// Insecure determines if the DTLS should skip certificate verification.
 	Insecure bool `yaml:"insecure" json:"insecure" mapstructure:"insecure"`
+
+	// MinVersion is the oldest DTLS version accepted by the UDP transport. Only "1.2", the
+	// default, is supported; "1.3" is rejected by Transport.Validate rather than served as 1.2.
+	MinVersion string `yaml:"minVersion" json:"minVersion" mapstructure:"minVersion"`
+
+	// SessionTimeout closes the session of a peer sending nothing for that long,
+	// DefaultDTLSSessionTimeout when zero. The peer is notified and handshakes again on its next
+	// request.
+	SessionTimeout time.Duration `yaml:"sessionTimeout" json:"sessionTimeout" mapstructure:"sessionTimeout"`
+}
+
+// GetSessionTimeout returns the session timeout, DefaultDTLSSessionTimeout when not set.
+func (d DTLS) GetSessionTimeout() time.Duration {
+	if d.SessionTimeout <= 0 {
+		return DefaultDTLSSessionTimeout
+	}
+	return d.SessionTimeout
+}
+
+// validateVersion checks that MinVersion names a DTLS version the UDP transport can serve.
+func (d DTLS) validateVersion() error {
+	switch d.MinVersion {
+	case "", "1.2":
+		return nil
+	case "1.3":
+		return fmt.Errorf("DTLS 1.3 is not supported: pion/dtls implements DTLS 1.2 only, set minVersion to 1.2")
+	default:
+		return fmt.Errorf("unsupported DTLS version %q, expected 1.2", d.MinVersion)
+	}
 }
 
 // UdpTransport represents the configuration for UDP-based transport, with optional DTLS support.
@@ -70,7 +109,7 @@ func (t UdpTransport) GetTransportType() types.TransportType {
 	return t.Type
 }
 
-// GetDTLSConfig loads the DTLS configuration if specified. This allows the UDP transport
+// GetDTLSConfig loads the DTLS configuration if DTLS is enabled. This allows the UDP transport
 // to use DTLS for secure communication.
 //
 // Example usage:
@@ -82,11 +121,11 @@ func (t UdpTransport) GetTransportType() types.TransportType {
 //
 // Returns:
 //
-//	*tls.Config: The DTLS configuration for the UDP transport, or nil if not using DTLS.
+//	*dtls.Config: The DTLS configuration for the UDP transport, or nil if not using DTLS.
 //	error: Returns an error if DTLS setup fails.
-func (t UdpTransport) GetDTLSConfig() (*tls.Config, error) {
-	if t.DTLS == nil {
-		return nil, nil // No DTLS configuration provided
+func (t UdpTransport) GetDTLSConfig() (*dtls.Config, error) {
+	if t.DTLS == nil || !t.DTLS.Enabled {
+		return nil, nil // DTLS not configured or disabled
 	}
 
 	// Check if the certificate file exists
@@ -105,9 +144,10 @@ func (t UdpTransport) GetDTLSConfig() (*tls.Config, error) {
 	}
 
 	// Prepare the DTLS configuration
-	tlsConfig := &tls.Config{
-		InsecureSkipVerify: t.DTLS.Insecure,
-		Certificates:       []tls.Certificate{cert},
+	dtlsConfig := &dtls.Config{
+		Certificates:         []tls.Certificate{cert},
+		InsecureSkipVerify:   t.DTLS.Insecure,
+		ExtendedMasterSecret: dtls.RequireExtendedMasterSecret,
 	}
 
 	// Load the Root CA if specified
@@ -122,10 +162,10 @@ func (t UdpTransport) GetDTLSConfig() (*tls.Config, error) {
 		if ok := caCertPool.AppendCertsFromPEM(caCert); !ok {
 			return nil, fmt.Errorf("failed to append root CA certificates")
 		}
-		tlsConfig.RootCAs = caCertPool
+		dtlsConfig.RootCAs = caCertPool
 	}
 
-	return tlsConfig, nil
+	return dtlsConfig, nil
 }
 
 // UnmarshalYAML is a custom YAML unmarshaler for UdpTransport.
@@ -139,10 +179,12 @@ func (t UdpTransport) GetDTLSConfig() (*tls.Config, error) {
 //		ipv4: "127.0.0.1"
 //		port: 4242
 //		dtls:
+//		  enabled: true
 //	      insecure: true
 //		  cert: "/path/to/cert.pem"
 //		  key: "/path/to/key.pem"
 //		  rootCa: "/path/to/rootCA.pem"
+//		  sessionTimeout: 5m
 //
 // Parameters:
 //
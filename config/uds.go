@@ -19,6 +19,10 @@ type UdsTransport struct {
 	// Socket is the file path to the Unix Domain Socket. This field is required to establish
 	// UDS communication, representing the location where the socket is created.
 	Socket string `yaml:"socket" json:"socket" mapstructure:"socket"`
+
+	// DatagramSocket is the optional file path of a datagram (SOCK_DGRAM) socket serving the
+	// same handlers. Each request and response is a single datagram.
+	DatagramSocket string `yaml:"datagramSocket" json:"datagramSocket" mapstructure:"datagramSocket"`
 }
 
 // Addr returns the address (file path) of the UDS socket.
@@ -59,6 +63,7 @@ func (u UdsTransport) GetTransportType() types.TransportType {
 //	type: uds
 //	enabled: true
 //	socket: /tmp/my-uds.sock
+//	datagramSocket: /tmp/my-uds.dgram.sock
 //
 // Parameters:
 //
@@ -69,9 +74,10 @@ func (u UdsTransport) GetTransportType() types.TransportType {
 //	error: Returns an error if unmarshaling fails; otherwise, nil.
 func (u *UdsTransport) UnmarshalYAML(value *yaml.Node) error {
 	aux := struct {
-		Type    types.TransportType `yaml:"type"`
-		Enabled bool                `yaml:"enabled"`
-		Socket  string              `yaml:"socket"`
+		Type           types.TransportType `yaml:"type"`
+		Enabled        bool                `yaml:"enabled"`
+		Socket         string              `yaml:"socket"`
+		DatagramSocket string              `yaml:"datagramSocket"`
 	}{}
 
 	if err := value.Decode(&aux); err != nil {
@@ -81,5 +87,6 @@ func (u *UdsTransport) UnmarshalYAML(value *yaml.Node) error {
 	u.Type = aux.Type
 	u.Enabled = aux.Enabled
 	u.Socket = aux.Socket
+	u.DatagramSocket = aux.DatagramSocket
 	return nil
 }
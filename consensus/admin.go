@@ -0,0 +1,59 @@
+package consensus
+
+import (
+	"encoding/json"
+
+	"github.com/pkg/errors"
+	"github.com/unpackdev/fdb/config"
+	"github.com/unpackdev/fdb/db"
+	"github.com/unpackdev/fdb/messages"
+)
+
+// RegisterAdminOps exposes the Raft group status and membership changes of the node through
+// the admin handlers of the manager's transports. The payload of messages.AdminRaftJoin and
+// messages.AdminRaftLeave is a JSON encoded config.RaftPeer, leave only uses its ID.
+//
+// Parameters:
+//
+//	manager (*db.Manager): The manager dispatching admin requests.
+//	group (*Group): The Raft group the operations apply to.
+func RegisterAdminOps(manager *db.Manager, group *Group) {
+	manager.RegisterAdminOp(messages.AdminRaftStatus, func(_ *messages.AdminRequest) ([]byte, error) {
+		status, err := group.Status()
+		if err != nil {
+			return nil, err
+		}
+		return json.Marshal(status)
+	})
+
+	manager.RegisterAdminOp(messages.AdminRaftJoin, func(req *messages.AdminRequest) ([]byte, error) {
+		peer, err := decodePeer(req.Payload)
+		if err != nil {
+			return nil, err
+		}
+		if peer.Addr == "" {
+			return nil, errors.New("raft join requires the member address")
+		}
+		return nil, group.Join(peer.ID, peer.Addr)
+	})
+
+	manager.RegisterAdminOp(messages.AdminRaftLeave, func(req *messages.AdminRequest) ([]byte, error) {
+		peer, err := decodePeer(req.Payload)
+		if err != nil {
+			return nil, err
+		}
+		return nil, group.Leave(peer.ID)
+	})
+}
+
+// decodePeer decodes the member of a membership change request.
+func decodePeer(payload []byte) (config.RaftPeer, error) {
+	var peer config.RaftPeer
+	if err := json.Unmarshal(payload, &peer); err != nil {
+		return peer, errors.Wrap(err, "failure to decode raft member")
+	}
+	if peer.ID == "" {
+		return peer, errors.New("raft member id must not be empty")
+	}
+	return peer, nil
+}
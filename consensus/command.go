@@ -0,0 +1,66 @@
+package consensus
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/unpackdev/fdb/types"
+)
+
+const commandHeaderLen = 1 + 1 + 2
+
+// Command is a single write submitted to the Raft log:
+// op (1 byte) | database length (1 byte) | database | key length (2 bytes) | key | value
+type Command struct {
+	Op       types.ChangeOp // The mutation to apply
+	Database types.DbType   // The database the mutation applies to
+	Key      []byte         // The mutated key
+	Value    []byte         // The new value, empty for deletes
+}
+
+// Encode encodes the Command into a newly allocated byte slice.
+func (c *Command) Encode() ([]byte, error) {
+	if len(c.Database) > 0xFF {
+		return nil, fmt.Errorf("database name too long: %d bytes", len(c.Database))
+	}
+	if len(c.Key) > 0xFFFF {
+		return nil, fmt.Errorf("command key too long: %d bytes", len(c.Key))
+	}
+
+	buf := make([]byte, commandHeaderLen+len(c.Database)+len(c.Key)+len(c.Value))
+	buf[0] = byte(c.Op)
+	buf[1] = byte(len(c.Database))
+	offset := 2 + copy(buf[2:], c.Database)
+	binary.BigEndian.PutUint16(buf[offset:offset+2], uint16(len(c.Key)))
+	offset += 2
+	offset += copy(buf[offset:], c.Key)
+	copy(buf[offset:], c.Value)
+
+	return buf, nil
+}
+
+// DecodeCommand decodes a byte slice into a Command. Key and value reuse the provided slice.
+func DecodeCommand(data []byte) (*Command, error) {
+	if len(data) < commandHeaderLen {
+		return nil, fmt.Errorf("data too short, must be at least %d bytes", commandHeaderLen)
+	}
+
+	dbLen := int(data[1])
+	if len(data) < commandHeaderLen+dbLen {
+		return nil, fmt.Errorf("database length mismatch, expected %d bytes", dbLen)
+	}
+	offset := 2 + dbLen
+
+	keyLen := int(binary.BigEndian.Uint16(data[offset : offset+2]))
+	offset += 2
+	if len(data[offset:]) < keyLen {
+		return nil, fmt.Errorf("key length mismatch, expected %d bytes but got %d bytes", keyLen, len(data[offset:]))
+	}
+
+	return &Command{
+		Op:       types.ChangeOp(data[0]),
+		Database: types.DbType(data[2 : 2+dbLen]),
+		Key:      data[offset : offset+keyLen],
+		Value:    data[offset+keyLen:],
+	}, nil
+}
@@ -0,0 +1,32 @@
+// Package consensus implements an optional Raft replication group for the databases whose
+// data must never diverge between nodes, such as nonces and allocation counters.
+//
+// Every member manages the same set of databases. A write received by any transport of any
+// member is proposed as a command to the replicated log:
+//
+//   - **On the leader** the command is appended to the log and the write is acknowledged once a
+//     quorum of members stored it and the leader applied it to MDBX.
+//
+//   - **On a follower** the command is forwarded to the leader over the Raft address and the
+//     write is acknowledged with the leader's answer.
+//
+// MDBX is the state machine: each committed command is applied in its own transaction together
+// with its log index, so replaying the log after a restart never applies an entry twice. Direct
+// writes to the group's databases are rejected, they only change through the log.
+//
+// The Raft log and stable state live in a dedicated MDBX environment under the data directory.
+// Snapshots are logical copies of the databases taken from MDBX read transactions; members
+// that fall behind the compacted log are brought up to date from the leader's latest snapshot.
+// As the databases must not lose committed entries, they should use a durable sync mode.
+//
+// Membership changes (Join, Leave) are themselves log entries and are exposed as admin
+// operations, see RegisterAdminOps.
+//
+// Example usage:
+//
+//	group := consensus.NewGroup(manager, cnf.Raft)
+//	if err := group.Start(ctx); err != nil {
+//	    log.Fatalf("Failed to start raft group: %v", err)
+//	}
+//	defer group.Shutdown()
+package consensus
@@ -0,0 +1,256 @@
+package consensus
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"github.com/hashicorp/raft"
+	"github.com/pkg/errors"
+	"github.com/unpackdev/fdb/db"
+	"github.com/unpackdev/fdb/types"
+	"go.uber.org/zap"
+)
+
+// Snapshot stream markers, each database section is a sequence of entries closed by snapshotEnd.
+const (
+	snapshotEnd   byte = 0x00
+	snapshotEntry byte = 0x01
+
+	// restoreBatch is the number of snapshot pairs written per transaction.
+	restoreBatch = 1000
+)
+
+// fsm is the Raft state machine: committed commands are applied to the MDBX databases of the
+// group, each database recording the index of the last entry applied to it.
+type fsm struct {
+	manager   *db.Manager
+	databases []types.DbType
+}
+
+// database returns the MDBX database a command applies to.
+func (f *fsm) database(name types.DbType) (*db.Db, error) {
+	provider, err := f.manager.GetDb(name)
+	if err != nil {
+		return nil, err
+	}
+	bDb, ok := provider.(*db.Db)
+	if !ok {
+		return nil, fmt.Errorf("mdbx database %s does not support consensus", name)
+	}
+	return bDb, nil
+}
+
+// Apply applies a committed command. The returned value is the error of the command, nil on success.
+func (f *fsm) Apply(log *raft.Log) interface{} {
+	cmd, err := DecodeCommand(log.Data)
+	if err != nil {
+		zap.L().Error("Invalid raft command", zap.Uint64("index", log.Index), zap.Error(err))
+		return err
+	}
+
+	bDb, err := f.database(cmd.Database)
+	if err != nil {
+		zap.L().Error(
+			"Failed to apply raft command",
+			zap.Uint64("index", log.Index),
+			zap.String("database", cmd.Database.String()),
+			zap.Error(err),
+		)
+		return err
+	}
+
+	if _, err := bDb.ApplyLogEntry(log.Index, cmd.Op, cmd.Key, cmd.Value); err != nil {
+		zap.L().Error(
+			"Failed to apply raft command",
+			zap.Uint64("index", log.Index),
+			zap.String("database", cmd.Database.String()),
+			zap.Error(err),
+		)
+		return err
+	}
+	return nil
+}
+
+// Snapshot returns a snapshot of the group's databases. The copy itself is made in Persist,
+// from a read transaction per database, so applying commands is not blocked while it is written.
+// A copy may include commands committed after the snapshot index; each database records the
+// index it is consistent with, so those commands are skipped when replayed after a restore.
+func (f *fsm) Snapshot() (raft.FSMSnapshot, error) {
+	return &fsmSnapshot{fsm: f}, nil
+}
+
+// Restore replaces the contents of the group's databases with a snapshot.
+func (f *fsm) Restore(snapshot io.ReadCloser) error {
+	defer snapshot.Close()
+
+	r := bufio.NewReader(snapshot)
+	for {
+		name, err := readBytes(r, 1)
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return errors.Wrap(err, "failed to read snapshot database")
+		}
+
+		if err := f.restoreDb(r, types.DbType(name)); err != nil {
+			return errors.Wrapf(err, "failed to restore database %s", name)
+		}
+	}
+}
+
+// restoreDb restores a single database section of a snapshot.
+func (f *fsm) restoreDb(r *bufio.Reader, name types.DbType) error {
+	var header [8]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return err
+	}
+	index := binary.BigEndian.Uint64(header[:])
+
+	bDb, err := f.database(name)
+	if err != nil {
+		return err
+	}
+
+	// An interrupted restore must not look applied, clear the index before the data
+	if err := bDb.SetAppliedIndex(0); err != nil {
+		return err
+	}
+	if err := bDb.ResetReplica(); err != nil {
+		return err
+	}
+
+	batch := make([]db.KeyValue, 0, restoreBatch)
+	for {
+		marker, err := r.ReadByte()
+		if err != nil {
+			return err
+		}
+		if marker == snapshotEnd {
+			break
+		}
+		if marker != snapshotEntry {
+			return fmt.Errorf("invalid snapshot marker: %x", marker)
+		}
+
+		key, err := readBytes(r, 4)
+		if err != nil {
+			return err
+		}
+		value, err := readBytes(r, 4)
+		if err != nil {
+			return err
+		}
+
+		batch = append(batch, db.KeyValue{Key: key, Value: value})
+		if len(batch) == restoreBatch {
+			if err := bDb.RestoreSnapshot(batch); err != nil {
+				return err
+			}
+			batch = batch[:0]
+		}
+	}
+	if err := bDb.RestoreSnapshot(batch); err != nil {
+		return err
+	}
+
+	zap.L().Info("Restored raft snapshot", zap.String("database", name.String()), zap.Uint64("index", index))
+	return bDb.SetAppliedIndex(index)
+}
+
+// fsmSnapshot writes a logical copy of the group's databases:
+// for each database, name length (1 byte) | name | applied index (8 bytes), then
+// entries of marker (1 byte) | key length (4 bytes) | key | value length (4 bytes) | value,
+// closed by the end marker.
+type fsmSnapshot struct {
+	fsm *fsm
+}
+
+// Persist writes the snapshot to the sink.
+func (s *fsmSnapshot) Persist(sink raft.SnapshotSink) error {
+	w := bufio.NewWriter(sink)
+
+	for _, name := range s.fsm.databases {
+		if err := s.persistDb(w, name); err != nil {
+			_ = sink.Cancel()
+			return errors.Wrapf(err, "failed to snapshot database %s", name)
+		}
+	}
+
+	if err := w.Flush(); err != nil {
+		_ = sink.Cancel()
+		return err
+	}
+	return sink.Close()
+}
+
+// persistDb writes a single database section of the snapshot.
+func (s *fsmSnapshot) persistDb(w *bufio.Writer, name types.DbType) error {
+	bDb, err := s.fsm.database(name)
+	if err != nil {
+		return err
+	}
+
+	err = bDb.Copy(func(index uint64) error {
+		if err := writeBytes(w, 1, []byte(name)); err != nil {
+			return err
+		}
+		var header [8]byte
+		binary.BigEndian.PutUint64(header[:], index)
+		_, err := w.Write(header[:])
+		return err
+	}, func(key, value []byte) error {
+		if err := w.WriteByte(snapshotEntry); err != nil {
+			return err
+		}
+		if err := writeBytes(w, 4, key); err != nil {
+			return err
+		}
+		return writeBytes(w, 4, value)
+	})
+	if err != nil {
+		return err
+	}
+	return w.WriteByte(snapshotEnd)
+}
+
+// Release is a no-op, the snapshot holds no resources between Snapshot and Persist.
+func (s *fsmSnapshot) Release() {}
+
+// writeBytes writes b prefixed with its big-endian length of lenSize (1 or 4) bytes.
+func writeBytes(w *bufio.Writer, lenSize int, b []byte) error {
+	var header [4]byte
+	if lenSize == 1 {
+		if len(b) > 0xFF {
+			return fmt.Errorf("snapshot field too long: %d bytes", len(b))
+		}
+		header[0] = byte(len(b))
+	} else {
+		binary.BigEndian.PutUint32(header[:], uint32(len(b)))
+	}
+	if _, err := w.Write(header[:lenSize]); err != nil {
+		return err
+	}
+	_, err := w.Write(b)
+	return err
+}
+
+// readBytes reads a field written by writeBytes.
+func readBytes(r *bufio.Reader, lenSize int) ([]byte, error) {
+	var header [4]byte
+	if _, err := io.ReadFull(r, header[:lenSize]); err != nil {
+		return nil, err
+	}
+
+	size := int(header[0])
+	if lenSize == 4 {
+		size = int(binary.BigEndian.Uint32(header[:]))
+	}
+
+	b := make([]byte, size)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
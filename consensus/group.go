@@ -0,0 +1,524 @@
+package consensus
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/raft"
+	"github.com/pkg/errors"
+	"github.com/unpackdev/fdb/config"
+	"github.com/unpackdev/fdb/db"
+	fdbErrors "github.com/unpackdev/fdb/errors"
+	"github.com/unpackdev/fdb/types"
+	"go.uber.org/zap"
+)
+
+const (
+	// transportTimeout bounds a single Raft RPC between members.
+	transportTimeout = 10 * time.Second
+
+	// transportMaxPool is the number of idle connections kept per member.
+	transportMaxPool = 3
+
+	// retainSnapshots is the number of snapshots kept on disk.
+	retainSnapshots = 2
+
+	// leaderPollInterval is how often a proposal checks for a leader while the group has none.
+	leaderPollInterval = 20 * time.Millisecond
+)
+
+// Member describes a voter of the group.
+type Member struct {
+	// ID is the unique identifier of the member.
+	ID string `json:"id"`
+
+	// Addr is the Raft address of the member.
+	Addr string `json:"addr"`
+
+	// Suffrage is the voting status of the member.
+	Suffrage string `json:"suffrage"`
+}
+
+// Status describes the state of the local member and the membership of the group.
+type Status struct {
+	// ID is the identifier of this node.
+	ID string `json:"id"`
+
+	// State is the Raft state of this node: Leader, Follower, Candidate or Shutdown.
+	State string `json:"state"`
+
+	// LeaderID is the identifier of the current leader, empty if unknown.
+	LeaderID string `json:"leaderId,omitempty"`
+
+	// LeaderAddr is the Raft address of the current leader, empty if unknown.
+	LeaderAddr string `json:"leaderAddr,omitempty"`
+
+	// LastIndex is the index of the last entry in the local log.
+	LastIndex uint64 `json:"lastIndex"`
+
+	// CommitIndex is the index of the last committed entry known to this node.
+	CommitIndex uint64 `json:"commitIndex"`
+
+	// AppliedIndex is the index of the last entry applied to the databases.
+	AppliedIndex uint64 `json:"appliedIndex"`
+
+	// Databases lists the databases managed by the group.
+	Databases []string `json:"databases"`
+
+	// Members lists the members of the group.
+	Members []Member `json:"members"`
+}
+
+// Group is the local member of a Raft replication group. Writes to the databases it manages
+// are proposed as commands to the replicated log and applied to MDBX once a quorum committed
+// them, on every member, in log order. Proposals received by a follower are forwarded to the leader.
+type Group struct {
+	// manager owns the databases of the group.
+	manager *db.Manager
+
+	// cnf holds the Raft configuration.
+	cnf config.Raft
+
+	// mu guards raft, store, mux and addr.
+	mu sync.Mutex
+
+	// raft is the Raft node, set by Start.
+	raft *raft.Raft
+
+	// store holds the Raft log and stable state.
+	store *store
+
+	// mux splits the Raft address between Raft RPCs and forwarded requests.
+	mux *mux
+
+	// addr is the Raft address advertised to the other members.
+	addr net.Addr
+}
+
+// NewGroup creates a new Group member. Call Start to join the group.
+//
+// Example usage:
+//
+//	group := consensus.NewGroup(manager, cnf.Raft)
+//
+// Parameters:
+//
+//	manager (*db.Manager): The manager owning the databases of the group.
+//	cnf (config.Raft): The Raft configuration.
+//
+// Returns:
+//
+//	*Group: A new Group instance.
+func NewGroup(manager *db.Manager, cnf config.Raft) *Group {
+	return &Group{
+		manager: manager,
+		cnf:     cnf,
+	}
+}
+
+// Start opens the Raft log, starts the Raft transport and node and, when configured to
+// bootstrap a group that has no state yet, forms it from the configured peers. Writes to the
+// group's databases go through the group from then on, direct writes are rejected with
+// errors.ErrReadOnly.
+//
+// Parameters:
+//
+//	ctx (context.Context): The context of the Raft log database.
+//
+// Returns:
+//
+//	error: Returns an error if a database is unknown or the node cannot be started.
+func (g *Group) Start(ctx context.Context) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.raft != nil {
+		return nil
+	}
+
+	databases := make([]types.DbType, 0, len(g.cnf.Databases))
+	for _, name := range g.cnf.Databases {
+		if !g.manager.HasDb(types.DbType(name)) {
+			return errors.Wrapf(fdbErrors.ErrDatabaseNotFound, "raft database %s", name)
+		}
+		databases = append(databases, types.DbType(name))
+	}
+
+	logger := hclog.New(&hclog.LoggerOptions{
+		Name:   "raft",
+		Level:  hclog.Warn,
+		Output: zap.NewStdLog(zap.L()).Writer(),
+	})
+
+	logStore, err := newStore(ctx, filepath.Join(g.cnf.DataDir, "log"))
+	if err != nil {
+		return err
+	}
+
+	snapshots, err := raft.NewFileSnapshotStoreWithLogger(g.cnf.DataDir, retainSnapshots, logger)
+	if err != nil {
+		_ = logStore.Close()
+		return errors.Wrap(err, "failed to open raft snapshot store")
+	}
+
+	m, err := newMux(g.cnf.Addr, g.serveForward)
+	if err != nil {
+		_ = logStore.Close()
+		return err
+	}
+
+	advertise, err := advertiseAddr(g.cnf.Addr, m.Addr())
+	if err != nil {
+		_ = m.Close()
+		_ = logStore.Close()
+		return err
+	}
+
+	transport := raft.NewNetworkTransportWithConfig(&raft.NetworkTransportConfig{
+		Stream:  &streamLayer{mux: m, advertise: advertise},
+		MaxPool: transportMaxPool,
+		Timeout: transportTimeout,
+		Logger:  logger,
+	})
+
+	rc := raft.DefaultConfig()
+	rc.LocalID = raft.ServerID(g.cnf.NodeID)
+	rc.HeartbeatTimeout = g.cnf.GetHeartbeatTimeout()
+	rc.ElectionTimeout = g.cnf.GetElectionTimeout()
+	rc.LeaderLeaseTimeout = rc.HeartbeatTimeout / 2
+	rc.SnapshotThreshold = g.cnf.GetSnapshotThreshold()
+	rc.SnapshotInterval = g.cnf.GetSnapshotInterval()
+	rc.TrailingLogs = g.cnf.GetTrailingLogs()
+	// The databases already hold the state of every applied entry
+	rc.NoSnapshotRestoreOnStart = true
+	rc.Logger = logger
+
+	node, err := raft.NewRaft(rc, &fsm{manager: g.manager, databases: databases}, logStore, logStore, snapshots, transport)
+	if err != nil {
+		_ = transport.Close()
+		_ = logStore.Close()
+		return errors.Wrap(err, "failed to start raft node")
+	}
+
+	if g.cnf.Bootstrap {
+		hasState, err := raft.HasExistingState(logStore, logStore, snapshots)
+		if err != nil {
+			return g.abortStart(node, transport, logStore, errors.Wrap(err, "failed to inspect raft state"))
+		}
+		if !hasState {
+			var servers []raft.Server
+			for _, peer := range g.cnf.GetPeers() {
+				addr := raft.ServerAddress(peer.Addr)
+				if peer.ID == g.cnf.NodeID {
+					addr = raft.ServerAddress(advertise.String())
+				}
+				servers = append(servers, raft.Server{ID: raft.ServerID(peer.ID), Address: addr})
+			}
+			if err := node.BootstrapCluster(raft.Configuration{Servers: servers}).Error(); err != nil {
+				return g.abortStart(node, transport, logStore, errors.Wrap(err, "failed to bootstrap raft group"))
+			}
+		}
+	}
+
+	// Only committed entries may modify the databases, direct writes are rejected
+	for _, name := range databases {
+		if err := g.manager.SetProposer(name, g); err != nil {
+			return g.abortStart(node, transport, logStore, err)
+		}
+		if err := g.manager.SetReadOnly(name, true); err != nil {
+			return g.abortStart(node, transport, logStore, err)
+		}
+	}
+
+	g.raft = node
+	g.store = logStore
+	g.mux = m
+	g.addr = advertise
+
+	zap.L().Info(
+		"Raft group started",
+		zap.String("id", g.cnf.NodeID),
+		zap.String("addr", advertise.String()),
+		zap.Strings("databases", g.cnf.Databases),
+	)
+	return nil
+}
+
+// abortStart releases everything Start acquired and returns err.
+func (g *Group) abortStart(node *raft.Raft, transport *raft.NetworkTransport, logStore *store, err error) error {
+	for _, name := range g.cnf.Databases {
+		_ = g.manager.SetProposer(types.DbType(name), nil)
+		_ = g.manager.SetReadOnly(types.DbType(name), false)
+	}
+	_ = node.Shutdown().Error()
+	_ = transport.Close()
+	_ = logStore.Close()
+	return err
+}
+
+// advertiseAddr returns the address advertised to the other members: the configured host with
+// the port actually listened on, so a zero port can be used.
+func advertiseAddr(configured string, listening net.Addr) (net.Addr, error) {
+	host, _, err := net.SplitHostPort(configured)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid raft address %s", configured)
+	}
+	port := listening.(*net.TCPAddr).Port
+
+	addr, err := net.ResolveTCPAddr("tcp", net.JoinHostPort(host, fmt.Sprint(port)))
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to resolve raft address %s", configured)
+	}
+	if addr.IP == nil || addr.IP.IsUnspecified() {
+		return nil, fmt.Errorf("raft address %s must name a reachable host, not an unspecified one", configured)
+	}
+	return addr, nil
+}
+
+// node returns the Raft node, or an error if the group is not started.
+func (g *Group) node() (*raft.Raft, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.raft == nil {
+		return nil, errors.New("raft group is not started")
+	}
+	return g.raft, nil
+}
+
+// timeout returns the apply timeout, shortened to the deadline of ctx.
+func (g *Group) timeout(ctx context.Context) time.Duration {
+	timeout := g.cnf.GetApplyTimeout()
+	if deadline, ok := ctx.Deadline(); ok {
+		if remaining := time.Until(deadline); remaining < timeout {
+			timeout = remaining
+		}
+	}
+	return timeout
+}
+
+// Propose submits a write to the replicated log and waits until it is committed and applied
+// locally on the leader. On a follower the write is forwarded to the leader.
+//
+// Parameters:
+//
+//	ctx (context.Context): Bounds how long the write waits, together with the apply timeout.
+//	database (types.DbType): The database the write applies to.
+//	op (types.ChangeOp): The mutation.
+//	key ([]byte): The mutated key.
+//	value ([]byte): The new value, ignored for deletes.
+//
+// Returns:
+//
+//	error: Returns errors.ErrNoLeader if the group has no leader, or an error if the write was not applied.
+func (g *Group) Propose(ctx context.Context, database types.DbType, op types.ChangeOp, key, value []byte) error {
+	data, err := (&Command{Op: op, Database: database, Key: key, Value: value}).Encode()
+	if err != nil {
+		return err
+	}
+	return g.submit(forwardCommand, data, g.timeout(ctx))
+}
+
+// Join adds a voter to the group, forwarding the change to the leader if needed.
+//
+// Parameters:
+//
+//	id (string): The unique identifier of the new member.
+//	addr (string): The Raft address of the new member.
+//
+// Returns:
+//
+//	error: Returns an error if the membership change was not committed.
+func (g *Group) Join(id, addr string) error {
+	if len(id) > 0xFFFF {
+		return fmt.Errorf("raft member id too long: %d bytes", len(id))
+	}
+
+	payload := make([]byte, 2+len(id)+len(addr))
+	binary.BigEndian.PutUint16(payload[0:2], uint16(len(id)))
+	copy(payload[2:], id)
+	copy(payload[2+len(id):], addr)
+	return g.submit(forwardJoin, payload, g.cnf.GetApplyTimeout())
+}
+
+// Leave removes a member from the group, forwarding the change to the leader if needed.
+//
+// Parameters:
+//
+//	id (string): The identifier of the member to remove.
+//
+// Returns:
+//
+//	error: Returns an error if the membership change was not committed.
+func (g *Group) Leave(id string) error {
+	return g.submit(forwardLeave, []byte(id), g.cnf.GetApplyTimeout())
+}
+
+// submit applies a request on the leader, or forwards it to the leader from a follower.
+func (g *Group) submit(kind byte, payload []byte, timeout time.Duration) error {
+	node, err := g.node()
+	if err != nil {
+		return err
+	}
+
+	// During an election there is briefly no leader, wait for one within the timeout
+	deadline := time.Now().Add(timeout)
+	for {
+		if node.State() == raft.Leader {
+			return g.apply(node, kind, payload, time.Until(deadline))
+		}
+		if leader, _ := node.LeaderWithID(); leader != "" {
+			return forward(leader, time.Until(deadline), kind, payload)
+		}
+		if time.Now().Add(leaderPollInterval).After(deadline) {
+			return fdbErrors.ErrNoLeader
+		}
+		time.Sleep(leaderPollInterval)
+	}
+}
+
+// serveForward serves a request forwarded by a follower. Requests are not forwarded twice, a
+// node that lost leadership rejects them and the follower retries against the new leader.
+func (g *Group) serveForward(kind byte, payload []byte) error {
+	node, err := g.node()
+	if err != nil {
+		return err
+	}
+	if node.State() != raft.Leader {
+		return raft.ErrNotLeader
+	}
+	return g.apply(node, kind, payload, g.cnf.GetApplyTimeout())
+}
+
+// apply applies a request on the leader.
+func (g *Group) apply(node *raft.Raft, kind byte, payload []byte, timeout time.Duration) error {
+	switch kind {
+	case forwardCommand:
+		future := node.Apply(payload, timeout)
+		if err := future.Error(); err != nil {
+			return errors.Wrap(err, "failed to commit raft command")
+		}
+		if err, ok := future.Response().(error); ok && err != nil {
+			return err
+		}
+		return nil
+
+	case forwardJoin:
+		if len(payload) < 2 {
+			return errors.New("invalid raft join request")
+		}
+		idLen := int(binary.BigEndian.Uint16(payload[0:2]))
+		if len(payload) < 2+idLen {
+			return errors.New("invalid raft join request")
+		}
+		id, addr := payload[2:2+idLen], payload[2+idLen:]
+		future := node.AddVoter(raft.ServerID(id), raft.ServerAddress(addr), 0, timeout)
+		return errors.Wrapf(future.Error(), "failed to add raft member %s", id)
+
+	case forwardLeave:
+		future := node.RemoveServer(raft.ServerID(payload), 0, timeout)
+		return errors.Wrapf(future.Error(), "failed to remove raft member %s", payload)
+
+	default:
+		return fmt.Errorf("unknown raft request: %c", kind)
+	}
+}
+
+// Addr returns the Raft address advertised to the other members, empty if the group is not started.
+func (g *Group) Addr() string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.addr == nil {
+		return ""
+	}
+	return g.addr.String()
+}
+
+// Leader returns the identifier and Raft address of the current leader, empty if unknown.
+func (g *Group) Leader() (string, string) {
+	node, err := g.node()
+	if err != nil {
+		return "", ""
+	}
+	addr, id := node.LeaderWithID()
+	return string(id), string(addr)
+}
+
+// IsLeader reports whether this node is the leader of the group.
+func (g *Group) IsLeader() bool {
+	node, err := g.node()
+	return err == nil && node.State() == raft.Leader
+}
+
+// Status returns the state of the local member and the membership of the group.
+//
+// Returns:
+//
+//	Status: The state of the group as seen by this node.
+//	error: Returns an error if the group is not started or its configuration cannot be read.
+func (g *Group) Status() (Status, error) {
+	node, err := g.node()
+	if err != nil {
+		return Status{}, err
+	}
+
+	future := node.GetConfiguration()
+	if err := future.Error(); err != nil {
+		return Status{}, errors.Wrap(err, "failed to read raft configuration")
+	}
+
+	leaderAddr, leaderID := node.LeaderWithID()
+	status := Status{
+		ID:           g.cnf.NodeID,
+		State:        node.State().String(),
+		LeaderID:     string(leaderID),
+		LeaderAddr:   string(leaderAddr),
+		LastIndex:    node.LastIndex(),
+		CommitIndex:  node.CommitIndex(),
+		AppliedIndex: node.AppliedIndex(),
+		Databases:    g.cnf.Databases,
+	}
+	for _, server := range future.Configuration().Servers {
+		status.Members = append(status.Members, Member{
+			ID:       string(server.ID),
+			Addr:     string(server.Address),
+			Suffrage: server.Suffrage.String(),
+		})
+	}
+	return status, nil
+}
+
+// Shutdown stops the Raft node and its transport and closes the Raft log. The group's
+// databases stay read-only, so they cannot diverge from the log, until it is started again.
+//
+// Returns:
+//
+//	error: Returns an error if the node does not shut down cleanly.
+func (g *Group) Shutdown() error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.raft == nil {
+		return nil
+	}
+
+	for _, name := range g.cnf.Databases {
+		_ = g.manager.SetProposer(types.DbType(name), nil)
+	}
+
+	err := g.raft.Shutdown().Error()
+	_ = g.mux.Close()
+	if cErr := g.store.Close(); err == nil {
+		err = cErr
+	}
+
+	g.raft, g.store, g.mux, g.addr = nil, nil, nil, nil
+	return err
+}
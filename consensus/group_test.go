@@ -0,0 +1,187 @@
+package consensus
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/unpackdev/fdb/config"
+	"github.com/unpackdev/fdb/db"
+	fdbErrors "github.com/unpackdev/fdb/errors"
+	"github.com/unpackdev/fdb/types"
+)
+
+// testNode is a single in-process member of a test group.
+type testNode struct {
+	id      string
+	manager *db.Manager
+	cnf     config.Raft
+	group   *Group
+}
+
+func setupNode(t *testing.T, id string, bootstrap bool, tune func(*config.Raft)) *testNode {
+	manager, err := db.NewManager(context.Background(), config.Mdbx{
+		Enabled: true,
+		Nodes: []config.MdbxNode{{
+			Path:    t.TempDir(),
+			Name:    "fdb",
+			MaxSize: 1,
+		}},
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = manager.Close() })
+
+	cnf := config.Raft{
+		Enabled:          true,
+		NodeID:           id,
+		Addr:             "127.0.0.1:0",
+		DataDir:          t.TempDir(),
+		Bootstrap:        bootstrap,
+		Databases:        []string{"fdb"},
+		HeartbeatTimeout: 200 * time.Millisecond,
+		ElectionTimeout:  200 * time.Millisecond,
+	}
+	if tune != nil {
+		tune(&cnf)
+	}
+
+	node := &testNode{id: id, manager: manager, cnf: cnf}
+	node.start(t)
+	t.Cleanup(func() { _ = node.group.Shutdown() })
+	return node
+}
+
+func (n *testNode) start(t *testing.T) {
+	n.group = NewGroup(n.manager, n.cnf)
+	require.NoError(t, n.group.Start(context.Background()))
+}
+
+func (n *testNode) database(t *testing.T) *db.Db {
+	provider, err := n.manager.GetDb("fdb")
+	require.NoError(t, err)
+	return provider.(*db.Db)
+}
+
+func waitForLeader(t *testing.T, nodes ...*testNode) *testNode {
+	var leader *testNode
+	require.Eventually(t, func() bool {
+		for _, node := range nodes {
+			if node.group.IsLeader() {
+				leader = node
+				return true
+			}
+		}
+		return false
+	}, 10*time.Second, 20*time.Millisecond)
+	return leader
+}
+
+func waitForValue(t *testing.T, node *testNode, key, expected []byte) {
+	bDb := node.database(t)
+	require.Eventually(t, func() bool {
+		value, err := bDb.Get(key)
+		return err == nil && string(value) == string(expected)
+	}, 10*time.Second, 20*time.Millisecond)
+}
+
+func TestCommandEncoding(t *testing.T) {
+	cmd := &Command{Op: types.ChangeSet, Database: "fdb", Key: []byte("key"), Value: []byte("value")}
+	data, err := cmd.Encode()
+	require.NoError(t, err)
+
+	decoded, err := DecodeCommand(data)
+	require.NoError(t, err)
+	assert.Equal(t, cmd, decoded)
+
+	_, err = DecodeCommand(data[:5])
+	assert.Error(t, err)
+}
+
+func TestGroupReplicatesAndFailsOver(t *testing.T) {
+	node1 := setupNode(t, "node-1", true, nil)
+	require.Equal(t, node1, waitForLeader(t, node1))
+
+	node2 := setupNode(t, "node-2", false, nil)
+	node3 := setupNode(t, "node-3", false, nil)
+	require.NoError(t, node1.group.Join(node2.id, node2.group.Addr()))
+	// Membership changes are forwarded to the leader as well
+	require.NoError(t, node2.group.Join(node3.id, node3.group.Addr()))
+
+	status, err := node1.group.Status()
+	require.NoError(t, err)
+	assert.Equal(t, "Leader", status.State)
+	assert.Len(t, status.Members, 3)
+
+	// A write proposed on a follower is forwarded and applied on every member
+	require.NoError(t, node2.group.Propose(context.Background(), "fdb", types.ChangeSet, []byte("nonce"), []byte("1")))
+	for _, node := range []*testNode{node1, node2, node3} {
+		waitForValue(t, node, []byte("nonce"), []byte("1"))
+	}
+
+	// Writes bypassing the log are rejected
+	assert.ErrorIs(t, node3.database(t).Set([]byte("nonce"), []byte("2")), fdbErrors.ErrReadOnly)
+
+	// Routers send client writes to the group
+	router, err := db.NewRouter(node3.manager, nil)
+	require.NoError(t, err)
+	proposer, name := router.Proposer("")
+	require.NotNil(t, proposer)
+	require.NoError(t, proposer.Propose(context.Background(), name, types.ChangeSet, []byte("counter"), []byte("7")))
+	waitForValue(t, node1, []byte("counter"), []byte("7"))
+
+	// The remaining members elect a new leader and keep accepting writes
+	node1.cnf.Addr = node1.group.Addr()
+	require.NoError(t, node1.group.Shutdown())
+	leader := waitForLeader(t, node2, node3)
+	require.NoError(t, leader.group.Propose(context.Background(), "fdb", types.ChangeDelete, []byte("nonce"), nil))
+	require.Eventually(t, func() bool {
+		_, err := node2.database(t).Get([]byte("nonce"))
+		_, err3 := node3.database(t).Get([]byte("nonce"))
+		return errors.Is(err, fdbErrors.ErrNotFound) && errors.Is(err3, fdbErrors.ErrNotFound)
+	}, 10*time.Second, 20*time.Millisecond)
+
+	// A restarted member replays its log without applying entries twice and catches up
+	applied, err := node1.database(t).AppliedIndex()
+	require.NoError(t, err)
+	assert.NotZero(t, applied)
+	node1.start(t)
+	require.Eventually(t, func() bool {
+		_, err := node1.database(t).Get([]byte("nonce"))
+		return errors.Is(err, fdbErrors.ErrNotFound)
+	}, 10*time.Second, 20*time.Millisecond)
+}
+
+func TestGroupInstallsSnapshotOnLateJoiner(t *testing.T) {
+	tune := func(cnf *config.Raft) {
+		cnf.SnapshotThreshold = 4
+		cnf.SnapshotInterval = 50 * time.Millisecond
+		cnf.TrailingLogs = 1
+	}
+
+	node1 := setupNode(t, "node-1", true, tune)
+	waitForLeader(t, node1)
+
+	for i := byte(0); i < 20; i++ {
+		require.NoError(t, node1.group.Propose(context.Background(), "fdb", types.ChangeSet, []byte{'k', i}, []byte{i}))
+	}
+
+	// Wait until the log was compacted, so the new member cannot replay it from the start
+	require.Eventually(t, func() bool {
+		first, err := node1.group.store.FirstIndex()
+		return err == nil && first > 1
+	}, 10*time.Second, 20*time.Millisecond)
+
+	node2 := setupNode(t, "node-2", false, tune)
+	require.NoError(t, node1.group.Join(node2.id, node2.group.Addr()))
+
+	for i := byte(0); i < 20; i++ {
+		waitForValue(t, node2, []byte{'k', i}, []byte{i})
+	}
+
+	// Later writes continue from the snapshot
+	require.NoError(t, node2.group.Propose(context.Background(), "fdb", types.ChangeSet, []byte("after"), []byte("snapshot")))
+	waitForValue(t, node2, []byte("after"), []byte("snapshot"))
+}
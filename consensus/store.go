@@ -0,0 +1,253 @@
+package consensus
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/erigontech/mdbx-go/mdbx"
+	"github.com/hashicorp/raft"
+	"github.com/pkg/errors"
+	"github.com/unpackdev/fdb/config"
+	"github.com/unpackdev/fdb/db"
+)
+
+const (
+	// logPrefix prefixes the keys of Raft log entries, followed by the big-endian index.
+	logPrefix byte = 'l'
+
+	// stablePrefix prefixes the keys of Raft stable values (current term, last vote).
+	stablePrefix byte = 's'
+
+	// logHeaderLen is the fixed part of an encoded log entry:
+	// term (8 bytes) | type (1 byte) | appended at (8 bytes) | data length (4 bytes)
+	logHeaderLen = 8 + 1 + 8 + 4
+)
+
+// errKeyNotFound is returned by Get for unknown stable keys. Raft compares the message, so it
+// must read "not found".
+var errKeyNotFound = errors.New("not found")
+
+// store implements raft.LogStore and raft.StableStore on top of a dedicated MDBX environment,
+// so the Raft log is as durable as the databases it replicates.
+type store struct {
+	db  *db.Db
+	env *mdbx.Env
+	dbi mdbx.DBI
+}
+
+// newStore opens (creating if needed) the Raft log environment in dir.
+func newStore(ctx context.Context, dir string) (*store, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, errors.Wrapf(err, "failed to create raft log directory: %s", dir)
+	}
+
+	provider, err := db.NewDb(ctx, config.MdbxNode{
+		Name:    "raft",
+		Path:    dir,
+		MaxSize: 8,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to open raft log")
+	}
+
+	ldb := provider.(*db.Db)
+	return &store{db: ldb, env: ldb.GetEnv(), dbi: ldb.GetDBI()}, nil
+}
+
+// Close closes the Raft log environment.
+func (s *store) Close() error {
+	return s.db.Close()
+}
+
+// logKey returns the key of the log entry at index.
+func logKey(index uint64) []byte {
+	key := make([]byte, 9)
+	key[0] = logPrefix
+	binary.BigEndian.PutUint64(key[1:], index)
+	return key
+}
+
+// stableKey returns the key of a stable value.
+func stableKey(key []byte) []byte {
+	return append([]byte{stablePrefix}, key...)
+}
+
+// encodeLog encodes a log entry, the index is part of its key.
+func encodeLog(log *raft.Log) []byte {
+	buf := make([]byte, logHeaderLen+len(log.Data)+len(log.Extensions))
+	binary.BigEndian.PutUint64(buf[0:8], log.Term)
+	buf[8] = byte(log.Type)
+	binary.BigEndian.PutUint64(buf[9:17], uint64(log.AppendedAt.UnixNano()))
+	binary.BigEndian.PutUint32(buf[17:21], uint32(len(log.Data)))
+	copy(buf[logHeaderLen:], log.Data)
+	copy(buf[logHeaderLen+len(log.Data):], log.Extensions)
+	return buf
+}
+
+// decodeLog decodes a log entry into log, copying data out of the transaction.
+func decodeLog(index uint64, data []byte, log *raft.Log) error {
+	if len(data) < logHeaderLen {
+		return fmt.Errorf("raft log entry %d too short: %d bytes", index, len(data))
+	}
+	dataLen := int(binary.BigEndian.Uint32(data[17:21]))
+	if len(data[logHeaderLen:]) < dataLen {
+		return fmt.Errorf("raft log entry %d data length mismatch", index)
+	}
+
+	log.Index = index
+	log.Term = binary.BigEndian.Uint64(data[0:8])
+	log.Type = raft.LogType(data[8])
+	log.AppendedAt = time.Unix(0, int64(binary.BigEndian.Uint64(data[9:17])))
+	log.Data = append([]byte(nil), data[logHeaderLen:logHeaderLen+dataLen]...)
+	log.Extensions = nil
+	if extensions := data[logHeaderLen+dataLen:]; len(extensions) > 0 {
+		log.Extensions = append([]byte(nil), extensions...)
+	}
+	return nil
+}
+
+// FirstIndex returns the first index written, zero for an empty log.
+func (s *store) FirstIndex() (uint64, error) {
+	var index uint64
+	err := s.env.View(func(txn *mdbx.Txn) error {
+		cursor, err := txn.OpenCursor(s.dbi)
+		if err != nil {
+			return err
+		}
+		defer cursor.Close()
+
+		key, _, err := cursor.Get(logKey(0), nil, mdbx.SetRange)
+		if mdbx.IsNotFound(err) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if len(key) == 9 && key[0] == logPrefix {
+			index = binary.BigEndian.Uint64(key[1:])
+		}
+		return nil
+	})
+	return index, errors.Wrap(err, "failed to read first raft log index")
+}
+
+// LastIndex returns the last index written, zero for an empty log.
+func (s *store) LastIndex() (uint64, error) {
+	var index uint64
+	err := s.env.View(func(txn *mdbx.Txn) error {
+		cursor, err := txn.OpenCursor(s.dbi)
+		if err != nil {
+			return err
+		}
+		defer cursor.Close()
+
+		// Position after the last log key, then step back onto it
+		key, _, err := cursor.Get([]byte{logPrefix + 1}, nil, mdbx.SetRange)
+		if mdbx.IsNotFound(err) {
+			key, _, err = cursor.Get(nil, nil, mdbx.Last)
+		} else if err == nil {
+			key, _, err = cursor.Get(nil, nil, mdbx.Prev)
+		}
+		if mdbx.IsNotFound(err) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if len(key) == 9 && key[0] == logPrefix {
+			index = binary.BigEndian.Uint64(key[1:])
+		}
+		return nil
+	})
+	return index, errors.Wrap(err, "failed to read last raft log index")
+}
+
+// GetLog gets the log entry at index.
+func (s *store) GetLog(index uint64, log *raft.Log) error {
+	return s.env.View(func(txn *mdbx.Txn) error {
+		data, err := txn.Get(s.dbi, logKey(index))
+		if mdbx.IsNotFound(err) {
+			return raft.ErrLogNotFound
+		} else if err != nil {
+			return errors.Wrapf(err, "failed to read raft log entry %d", index)
+		}
+		return decodeLog(index, data, log)
+	})
+}
+
+// StoreLog stores a single log entry.
+func (s *store) StoreLog(log *raft.Log) error {
+	return s.StoreLogs([]*raft.Log{log})
+}
+
+// StoreLogs stores multiple log entries in a single transaction.
+func (s *store) StoreLogs(logs []*raft.Log) error {
+	return s.env.Update(func(txn *mdbx.Txn) error {
+		for _, log := range logs {
+			if err := txn.Put(s.dbi, logKey(log.Index), encodeLog(log), 0); err != nil {
+				return errors.Wrapf(err, "failed to store raft log entry %d", log.Index)
+			}
+		}
+		return nil
+	})
+}
+
+// DeleteRange deletes the log entries between min and max, inclusive.
+func (s *store) DeleteRange(min, max uint64) error {
+	return s.env.Update(func(txn *mdbx.Txn) error {
+		for index := min; index <= max; index++ {
+			if err := txn.Del(s.dbi, logKey(index), nil); err != nil && !mdbx.IsNotFound(err) {
+				return errors.Wrapf(err, "failed to delete raft log entry %d", index)
+			}
+			if index == max {
+				break
+			}
+		}
+		return nil
+	})
+}
+
+// Set stores a stable value.
+func (s *store) Set(key []byte, val []byte) error {
+	return s.env.Update(func(txn *mdbx.Txn) error {
+		return txn.Put(s.dbi, stableKey(key), val, 0)
+	})
+}
+
+// Get returns a stable value, or errKeyNotFound.
+func (s *store) Get(key []byte) ([]byte, error) {
+	var value []byte
+	err := s.env.View(func(txn *mdbx.Txn) error {
+		data, err := txn.Get(s.dbi, stableKey(key))
+		if mdbx.IsNotFound(err) {
+			return errKeyNotFound
+		} else if err != nil {
+			return err
+		}
+		value = append([]byte(nil), data...)
+		return nil
+	})
+	return value, err
+}
+
+// SetUint64 stores a stable integer value.
+func (s *store) SetUint64(key []byte, val uint64) error {
+	buf := make([]byte, 8)
+	binary.BigEndian.PutUint64(buf, val)
+	return s.Set(key, buf)
+}
+
+// GetUint64 returns a stable integer value, zero if it was never set.
+func (s *store) GetUint64(key []byte) (uint64, error) {
+	value, err := s.Get(key)
+	if errors.Is(err, errKeyNotFound) {
+		return 0, nil
+	} else if err != nil {
+		return 0, err
+	}
+	if len(value) != 8 {
+		return 0, fmt.Errorf("invalid stable value length: %d", len(value))
+	}
+	return binary.BigEndian.Uint64(value), nil
+}
@@ -0,0 +1,254 @@
+package consensus
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/raft"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// Every connection to the Raft address starts with a byte selecting its protocol.
+const (
+	protocolRaft    byte = 'R' // Raft RPCs between members
+	protocolForward byte = 'F' // Requests forwarded by a follower to the leader
+)
+
+// Forwarded request kinds.
+const (
+	forwardCommand byte = 'C' // Payload is an encoded Command
+	forwardJoin    byte = 'J' // Payload is id length (2 bytes) | id | address
+	forwardLeave   byte = 'V' // Payload is the id of the member to remove
+)
+
+// Forwarded response statuses.
+const (
+	forwardOK    byte = 0x00
+	forwardError byte = 0x01
+)
+
+const (
+	// protocolTimeout bounds reading the protocol byte of an accepted connection.
+	protocolTimeout = 5 * time.Second
+
+	// maxForwardPayload bounds the payload of a forwarded request.
+	maxForwardPayload = 64 * 1024 * 1024
+)
+
+// forwardFunc serves a forwarded request and returns its error.
+type forwardFunc func(kind byte, payload []byte) error
+
+// mux listens on the Raft address and splits connections between the Raft transport and the
+// forwarding server by their protocol byte.
+type mux struct {
+	listener net.Listener
+	forward  forwardFunc
+	raftConn chan net.Conn
+	closed   chan struct{}
+	once     sync.Once
+}
+
+// newMux starts listening on addr.
+func newMux(addr string, forward forwardFunc) (*mux, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to listen on raft address %s", addr)
+	}
+
+	m := &mux{
+		listener: listener,
+		forward:  forward,
+		raftConn: make(chan net.Conn),
+		closed:   make(chan struct{}),
+	}
+	go m.serve()
+	return m, nil
+}
+
+// serve accepts connections until the listener is closed.
+func (m *mux) serve() {
+	for {
+		conn, err := m.listener.Accept()
+		if err != nil {
+			select {
+			case <-m.closed:
+				return
+			default:
+			}
+			zap.L().Warn("Failed to accept raft connection", zap.Error(err))
+			continue
+		}
+		go m.route(conn)
+	}
+}
+
+// route reads the protocol byte of conn and hands it over to the matching protocol.
+func (m *mux) route(conn net.Conn) {
+	var protocol [1]byte
+	_ = conn.SetReadDeadline(time.Now().Add(protocolTimeout))
+	if _, err := io.ReadFull(conn, protocol[:]); err != nil {
+		_ = conn.Close()
+		return
+	}
+	_ = conn.SetReadDeadline(time.Time{})
+
+	switch protocol[0] {
+	case protocolRaft:
+		select {
+		case m.raftConn <- conn:
+		case <-m.closed:
+			_ = conn.Close()
+		}
+	case protocolForward:
+		m.serveForward(conn)
+	default:
+		zap.L().Warn("Unknown raft connection protocol", zap.Uint8("protocol", protocol[0]))
+		_ = conn.Close()
+	}
+}
+
+// serveForward serves a single forwarded request.
+func (m *mux) serveForward(conn net.Conn) {
+	defer conn.Close()
+
+	kind, payload, err := readForward(conn)
+	if err != nil {
+		zap.L().Warn("Invalid forwarded raft request", zap.Error(err))
+		return
+	}
+
+	if err := m.forward(kind, payload); err != nil {
+		_ = writeForwardResponse(conn, forwardError, err.Error())
+		return
+	}
+	_ = writeForwardResponse(conn, forwardOK, "")
+}
+
+// Close stops accepting connections.
+func (m *mux) Close() error {
+	var err error
+	m.once.Do(func() {
+		close(m.closed)
+		err = m.listener.Close()
+	})
+	return err
+}
+
+// Addr returns the address the mux listens on.
+func (m *mux) Addr() net.Addr {
+	return m.listener.Addr()
+}
+
+// streamLayer is the raft.StreamLayer of the mux, carrying Raft RPCs.
+type streamLayer struct {
+	mux       *mux
+	advertise net.Addr
+}
+
+// Accept waits for the next Raft connection.
+func (s *streamLayer) Accept() (net.Conn, error) {
+	select {
+	case conn := <-s.mux.raftConn:
+		return conn, nil
+	case <-s.mux.closed:
+		return nil, errors.New("raft transport closed")
+	}
+}
+
+// Close closes the mux.
+func (s *streamLayer) Close() error {
+	return s.mux.Close()
+}
+
+// Addr returns the address advertised to the other members.
+func (s *streamLayer) Addr() net.Addr {
+	return s.advertise
+}
+
+// Dial opens a Raft connection to a member.
+func (s *streamLayer) Dial(address raft.ServerAddress, timeout time.Duration) (net.Conn, error) {
+	conn, err := net.DialTimeout("tcp", string(address), timeout)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := conn.Write([]byte{protocolRaft}); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+// forward sends a request to the leader at address and waits for its response:
+// protocol (1 byte) | kind (1 byte) | payload length (4 bytes) | payload, answered with
+// status (1 byte) | message length (2 bytes) | message.
+func forward(address raft.ServerAddress, timeout time.Duration, kind byte, payload []byte) error {
+	conn, err := net.DialTimeout("tcp", string(address), timeout)
+	if err != nil {
+		return errors.Wrapf(err, "failed to connect to raft leader %s", address)
+	}
+	defer conn.Close()
+
+	if err := conn.SetDeadline(time.Now().Add(timeout)); err != nil {
+		return err
+	}
+
+	frame := make([]byte, 1+1+4+len(payload))
+	frame[0] = protocolForward
+	frame[1] = kind
+	binary.BigEndian.PutUint32(frame[2:6], uint32(len(payload)))
+	copy(frame[6:], payload)
+	if _, err := conn.Write(frame); err != nil {
+		return errors.Wrap(err, "failed to forward request to raft leader")
+	}
+
+	var header [3]byte
+	if _, err := io.ReadFull(conn, header[:]); err != nil {
+		return errors.Wrap(err, "failed to read raft leader response")
+	}
+	message := make([]byte, binary.BigEndian.Uint16(header[1:3]))
+	if _, err := io.ReadFull(conn, message); err != nil {
+		return errors.Wrap(err, "failed to read raft leader response")
+	}
+
+	if header[0] != forwardOK {
+		return fmt.Errorf("raft leader %s: %s", address, message)
+	}
+	return nil
+}
+
+// readForward reads a forwarded request, after its protocol byte.
+func readForward(r io.Reader) (byte, []byte, error) {
+	var header [5]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return 0, nil, err
+	}
+
+	size := binary.BigEndian.Uint32(header[1:5])
+	if size > maxForwardPayload {
+		return 0, nil, fmt.Errorf("forwarded payload too large: %d bytes", size)
+	}
+	payload := make([]byte, size)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return 0, nil, err
+	}
+	return header[0], payload, nil
+}
+
+// writeForwardResponse writes the response to a forwarded request.
+func writeForwardResponse(w io.Writer, status byte, message string) error {
+	if len(message) > 0xFFFF {
+		message = message[:0xFFFF]
+	}
+
+	buf := make([]byte, 3+len(message))
+	buf[0] = status
+	binary.BigEndian.PutUint16(buf[1:3], uint16(len(message)))
+	copy(buf[3:], message)
+	_, err := w.Write(buf)
+	return err
+}
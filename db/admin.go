@@ -0,0 +1,113 @@
+package db
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/pkg/errors"
+	"github.com/unpackdev/fdb/config"
+	"github.com/unpackdev/fdb/messages"
+	"github.com/unpackdev/fdb/types"
+	"go.uber.org/zap"
+)
+
+// AdminOpFunc executes an admin operation registered with RegisterAdminOp and returns its result.
+type AdminOpFunc func(req *messages.AdminRequest) ([]byte, error)
+
+// RegisterAdminOp adds an admin operation served by a component outside the manager, such
+// as replication. Registered operations are dispatched by HandleAdminRequest and cannot
+// replace the built-in database operations.
+//
+// Parameters:
+//
+//	op (messages.AdminOp): The admin operation byte.
+//	fn (AdminOpFunc): The function executing the operation.
+func (m *Manager) RegisterAdminOp(op messages.AdminOp, fn AdminOpFunc) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.adminOps[op] = fn
+}
+
+// HandleAdminRequest executes an administrative request against the manager. List requests
+// return the JSON encoded database list, registered operations their own result; all other
+// operations return an empty body.
+//
+// Parameters:
+//
+//	req (*messages.AdminRequest): The decoded admin request.
+//
+// Returns:
+//
+//	[]byte: The operation result, if any.
+//	error: Returns an error if the operation is unknown or fails.
+func (m *Manager) HandleAdminRequest(req *messages.AdminRequest) ([]byte, error) {
+	name := types.DbType(req.Name)
+
+	switch req.Op {
+	case messages.AdminListDbs:
+		return json.Marshal(m.ListDbs())
+	case messages.AdminCreateDb:
+		var node config.MdbxNode
+		if len(req.Payload) > 0 {
+			if err := json.Unmarshal(req.Payload, &node); err != nil {
+				return nil, errors.Wrap(err, "failure to decode database configuration")
+			}
+		}
+		node.Name = req.Name
+		return nil, m.CreateDb(node)
+	case messages.AdminOpenDb:
+		return nil, m.OpenDb(name)
+	case messages.AdminCloseDb:
+		return nil, m.CloseDb(name)
+	case messages.AdminDropDb:
+		return nil, m.DropDb(name)
+	default:
+		m.mu.RLock()
+		fn, ok := m.adminOps[req.Op]
+		m.mu.RUnlock()
+
+		if !ok {
+			return nil, fmt.Errorf("unknown admin operation: %v", req.Op)
+		}
+		return fn(req)
+	}
+}
+
+// ExecuteAdminFrame decodes a raw admin frame, executes it and returns the encoded response:
+// a status byte followed by the operation result on success or the error message on failure.
+// Transports use it to implement their admin handlers.
+//
+// Parameters:
+//
+//	frame ([]byte): The raw admin frame as received by the transport.
+//
+// Returns:
+//
+//	[]byte: The encoded response to send back to the client.
+func (m *Manager) ExecuteAdminFrame(frame []byte) []byte {
+	// Admin requests name their target database explicitly, headers in front of the frame are ignored.
+	_, _, frame, err := messages.SplitHeader(frame)
+	if err != nil {
+		return messages.EncodeStatusResponse(types.StatusError, []byte(err.Error()))
+	}
+
+	req, err := messages.DecodeAdminRequest(frame)
+	if err != nil {
+		return messages.EncodeStatusResponse(types.StatusError, []byte(err.Error()))
+	}
+
+	body, err := m.HandleAdminRequest(req)
+	if err != nil {
+		zap.L().Warn(
+			"Admin operation failed",
+			zap.String("op", req.Op.String()),
+			zap.String("name", req.Name),
+			zap.Error(err),
+		)
+		return messages.EncodeStatusResponse(types.StatusError, []byte(err.Error()))
+	}
+
+	zap.L().Info("Admin operation completed", zap.String("op", req.Op.String()), zap.String("name", req.Name))
+	return messages.EncodeStatusResponse(types.StatusOK, body)
+}
@@ -0,0 +1,419 @@
+package db
+
+import (
+	"context"
+	"encoding/binary"
+	"time"
+
+	"github.com/erigontech/mdbx-go/mdbx"
+	"github.com/pkg/errors"
+	fdbErrors "github.com/unpackdev/fdb/errors"
+	"github.com/unpackdev/fdb/messages"
+	"github.com/unpackdev/fdb/types"
+	"go.uber.org/zap"
+)
+
+const (
+	// cdcDbiName is the named DBI holding the change log, keyed by big-endian sequence number.
+	// Named DBIs are recorded in the main DBI, the name cannot clash with the 32-byte data keys.
+	cdcDbiName = "fdb_cdc"
+
+	// cdcReadBatch is the maximum number of changes read per transaction while tailing.
+	cdcReadBatch = 1024
+
+	// cdcPruneBatch is the maximum number of changes removed per retention transaction.
+	cdcPruneBatch = 10000
+)
+
+// changeAppender appends change log entries within a single write transaction. The last
+// sequence number is looked up once, on the first append, and incremented locally after.
+// MDBX serialises write transactions, so sequence numbers are gap-free and ordered by commit.
+type changeAppender struct {
+	db     *Db
+	txn    *mdbx.Txn
+	cursor *mdbx.Cursor
+	seq    uint64
+	now    int64
+}
+
+// newChangeAppender returns an appender bound to txn, or nil when the change log is disabled.
+func (db *Db) newChangeAppender(txn *mdbx.Txn) *changeAppender {
+	if !db.opts.Cdc.Enabled {
+		return nil
+	}
+	return &changeAppender{db: db, txn: txn, now: time.Now().UnixNano()}
+}
+
+// append records a single mutation. It is a no-op on a nil appender.
+func (a *changeAppender) append(op types.ChangeOp, key, value []byte) error {
+	if a == nil {
+		return nil
+	}
+
+	if a.cursor == nil {
+		cursor, err := a.txn.OpenCursor(a.db.cdcDbi)
+		if err != nil {
+			return errors.Wrap(err, "failed to open change log cursor")
+		}
+		a.cursor = cursor
+
+		lastKey, _, err := cursor.Get(nil, nil, mdbx.Last)
+		if err != nil && !mdbx.IsNotFound(err) {
+			return errors.Wrap(err, "failed to read last change sequence")
+		}
+		if err == nil {
+			a.seq = binary.BigEndian.Uint64(lastKey)
+		}
+
+		// A replica bootstrapped from a snapshot continues the leader's sequence, even with an empty log
+		position, err := a.db.replicationPosition(a.txn)
+		if err != nil {
+			return err
+		}
+		if position > a.seq {
+			a.seq = position
+		}
+	}
+
+	a.seq++
+	return a.put(&messages.Change{Seq: a.seq, Op: op, Timestamp: a.now, Key: key, Value: value})
+}
+
+// appendReplicated records a change received from the leader, keeping its sequence number
+// and timestamp. It is a no-op on a nil appender.
+func (a *changeAppender) appendReplicated(change *messages.Change) error {
+	if a == nil {
+		return nil
+	}
+
+	if a.cursor == nil {
+		cursor, err := a.txn.OpenCursor(a.db.cdcDbi)
+		if err != nil {
+			return errors.Wrap(err, "failed to open change log cursor")
+		}
+		a.cursor = cursor
+	}
+
+	a.seq = change.Seq
+	return a.put(change)
+}
+
+// put encodes the change and appends it under its sequence number.
+func (a *changeAppender) put(change *messages.Change) error {
+	encoded, err := change.Encode()
+	if err != nil {
+		return err
+	}
+	return a.cursor.Put(sequenceKey(change.Seq), encoded, mdbx.Append)
+}
+
+// close releases the appender cursor. It is a no-op on a nil appender.
+func (a *changeAppender) close() {
+	if a != nil && a.cursor != nil {
+		a.cursor.Close()
+	}
+}
+
+// committed wakes up subscribers once the transaction holding the appended changes committed.
+func (a *changeAppender) committed() {
+	if a != nil && a.seq > 0 {
+		a.db.notifyChanges()
+	}
+}
+
+// sequenceKey encodes a sequence number as a change log key.
+func sequenceKey(seq uint64) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, seq)
+	return key
+}
+
+// notifyChanges wakes up every subscriber waiting for new changes.
+func (db *Db) notifyChanges() {
+	db.cdcMu.Lock()
+	close(db.cdcNotify)
+	db.cdcNotify = make(chan struct{})
+	db.cdcMu.Unlock()
+}
+
+// changesNotify returns a channel closed on the next committed change.
+func (db *Db) changesNotify() <-chan struct{} {
+	db.cdcMu.Lock()
+	defer db.cdcMu.Unlock()
+	return db.cdcNotify
+}
+
+// ChangeLogBounds returns the first and last sequence numbers retained in the change log.
+// Both are zero while the log is empty.
+//
+// Returns:
+//
+//	uint64: The oldest retained sequence number.
+//	uint64: The newest sequence number.
+//	error: Returns errors.ErrCdcDisabled if the change log is disabled, or an error if the read fails.
+func (db *Db) ChangeLogBounds() (uint64, uint64, error) {
+	if !db.opts.Cdc.Enabled {
+		return 0, 0, fdbErrors.ErrCdcDisabled
+	}
+	if !db.Acquire() {
+		return 0, 0, fdbErrors.ErrDatabaseClosed
+	}
+	defer db.Release()
+
+	var first, last uint64
+	err := db.env.View(func(txn *mdbx.Txn) error {
+		var err error
+		first, last, err = db.changeLogBounds(txn)
+		return err
+	})
+	return first, last, err
+}
+
+// changeLogBounds returns the first and last retained sequence numbers within txn.
+func (db *Db) changeLogBounds(txn *mdbx.Txn) (uint64, uint64, error) {
+	cursor, err := txn.OpenCursor(db.cdcDbi)
+	if err != nil {
+		return 0, 0, errors.Wrap(err, "failed to open change log cursor")
+	}
+	defer cursor.Close()
+
+	firstKey, _, err := cursor.Get(nil, nil, mdbx.First)
+	if mdbx.IsNotFound(err) {
+		return 0, 0, nil
+	} else if err != nil {
+		return 0, 0, err
+	}
+	first := binary.BigEndian.Uint64(firstKey)
+
+	lastKey, _, err := cursor.Get(nil, nil, mdbx.Last)
+	if err != nil {
+		return 0, 0, err
+	}
+	return first, binary.BigEndian.Uint64(lastKey), nil
+}
+
+// ReadChanges returns up to limit changes starting at sequence from. A from of zero starts
+// at the oldest retained change.
+//
+// Example usage:
+//
+//	changes, err := db.ReadChanges(42, 100)
+//	if err != nil {
+//	    log.Fatalf("Failed to read changes: %v", err)
+//	}
+//
+// Parameters:
+//
+//	from (uint64): The first sequence number to return.
+//	limit (int): The maximum number of changes to return.
+//
+// Returns:
+//
+//	[]*messages.Change: The changes, ordered by sequence number.
+//	error: Returns errors.ErrChangesTruncated if changes starting at from were already pruned,
+//	errors.ErrCdcDisabled if the change log is disabled, or an error if the read fails.
+func (db *Db) ReadChanges(from uint64, limit int) ([]*messages.Change, error) {
+	if !db.opts.Cdc.Enabled {
+		return nil, fdbErrors.ErrCdcDisabled
+	}
+	if !db.Acquire() {
+		return nil, fdbErrors.ErrDatabaseClosed
+	}
+	defer db.Release()
+
+	var changes []*messages.Change
+	err := db.env.View(func(txn *mdbx.Txn) error {
+		first, _, err := db.changeLogBounds(txn)
+		if err != nil {
+			return err
+		}
+		if from != 0 && first != 0 && from < first {
+			return errors.Wrapf(fdbErrors.ErrChangesTruncated, "requested %d, oldest retained %d", from, first)
+		}
+
+		cursor, err := txn.OpenCursor(db.cdcDbi)
+		if err != nil {
+			return errors.Wrap(err, "failed to open change log cursor")
+		}
+		defer cursor.Close()
+
+		_, value, err := cursor.Get(sequenceKey(from), nil, mdbx.SetRange)
+		for ; err == nil && len(changes) < limit; _, value, err = cursor.Get(nil, nil, mdbx.Next) {
+			// Values are only valid within the transaction, decode from a copy
+			change, dErr := messages.DecodeChange(append([]byte(nil), value...))
+			if dErr != nil {
+				return dErr
+			}
+			changes = append(changes, change)
+		}
+		if err != nil && !mdbx.IsNotFound(err) {
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return changes, nil
+}
+
+// Subscribe tails the change log starting at sequence from, calling fn for every change in
+// order. Once caught up it waits for new commits. It returns when ctx is cancelled, fn returns
+// an error, or the database is closed. A from of zero starts at the oldest retained change; to
+// resume after a disconnect, subscribe again from the sequence following the last one handled.
+//
+// Example usage:
+//
+//	err := db.Subscribe(ctx, lastSeq+1, func(change *messages.Change) error {
+//	    lastSeq = change.Seq
+//	    return index(change)
+//	})
+//
+// Parameters:
+//
+//	ctx (context.Context): Cancels the subscription.
+//	from (uint64): The first sequence number to deliver.
+//	fn (func(*messages.Change) error): Called for every change; an error stops the subscription.
+//
+// Returns:
+//
+//	error: The reason the subscription stopped.
+func (db *Db) Subscribe(ctx context.Context, from uint64, fn func(change *messages.Change) error) error {
+	next := from
+	for {
+		// Grab the notification channel before reading so commits racing with the read are not missed
+		notify := db.changesNotify()
+
+		changes, err := db.ReadChanges(next, cdcReadBatch)
+		if err != nil {
+			return err
+		}
+
+		for _, change := range changes {
+			if err := fn(change); err != nil {
+				return err
+			}
+			next = change.Seq + 1
+		}
+
+		if len(changes) == cdcReadBatch {
+			continue
+		}
+
+		select {
+		case <-notify:
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-db.stopSync:
+			return fdbErrors.ErrDatabaseClosed
+		}
+	}
+}
+
+// runCdcRetention enforces the change log retention limits every prune interval until the
+// database is closed or its context is cancelled.
+func (db *Db) runCdcRetention() {
+	defer db.background.Done()
+
+	ticker := time.NewTicker(db.opts.Cdc.GetPruneInterval())
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			pruned, err := db.PruneChanges()
+			if err != nil {
+				zap.L().Error(
+					"failure to prune change log",
+					zap.String("name", db.opts.Name),
+					zap.Error(err),
+				)
+			} else if pruned > 0 {
+				zap.L().Debug("Change log pruned", zap.String("name", db.opts.Name), zap.Int("pruned", pruned))
+			}
+		case <-db.stopSync:
+			return
+		case <-db.ctx.Done():
+			return
+		}
+	}
+}
+
+// PruneChanges removes the changes exceeding the configured retention count or age. The newest
+// change is never removed so sequence numbers keep increasing. It is run periodically in the
+// background and exposed for callers that want to prune eagerly.
+//
+// Returns:
+//
+//	int: The number of changes removed.
+//	error: Returns an error if the change log is disabled or the prune fails.
+func (db *Db) PruneChanges() (int, error) {
+	if !db.opts.Cdc.Enabled {
+		return 0, fdbErrors.ErrCdcDisabled
+	}
+	if !db.opts.Cdc.HasRetention() {
+		return 0, nil
+	}
+	if !db.Acquire() {
+		return 0, fdbErrors.ErrDatabaseClosed
+	}
+	defer db.Release()
+
+	var cutoff int64
+	if db.opts.Cdc.RetainAge > 0 {
+		cutoff = time.Now().Add(-db.opts.Cdc.RetainAge).UnixNano()
+	}
+
+	total := 0
+	for {
+		pruned := 0
+		err := db.env.Update(func(txn *mdbx.Txn) error {
+			first, last, err := db.changeLogBounds(txn)
+			if err != nil || first == 0 {
+				return err
+			}
+
+			cursor, err := txn.OpenCursor(db.cdcDbi)
+			if err != nil {
+				return errors.Wrap(err, "failed to open change log cursor")
+			}
+			defer cursor.Close()
+
+			count := last - first + 1
+			_, value, err := cursor.Get(nil, nil, mdbx.First)
+			for ; err == nil && pruned < cdcPruneBatch; _, value, err = cursor.Get(nil, nil, mdbx.Next) {
+				overCount := db.opts.Cdc.RetainCount > 0 && count > db.opts.Cdc.RetainCount
+				overAge := false
+				if cutoff > 0 {
+					change, dErr := messages.DecodeChange(value)
+					if dErr != nil {
+						return dErr
+					}
+					overAge = change.Timestamp < cutoff
+				}
+				// The newest change is always kept, it anchors the sequence for the next append
+				if (!overCount && !overAge) || count == 1 {
+					return nil
+				}
+
+				if err := cursor.Del(0); err != nil {
+					return errors.Wrap(err, "failed to delete change")
+				}
+				pruned++
+				count--
+			}
+			if err != nil && !mdbx.IsNotFound(err) {
+				return err
+			}
+			return nil
+		})
+		if err != nil {
+			return total, err
+		}
+
+		total += pruned
+		if pruned < cdcPruneBatch {
+			return total, nil
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/unpackdev/fdb/config"
+	"github.com/unpackdev/fdb/errors"
+	"github.com/unpackdev/fdb/messages"
+	"github.com/unpackdev/fdb/types"
+)
+
+func setupCdcTestDb(t *testing.T, cdc config.Cdc) *Db {
+	cdc.Enabled = true
+	provider, err := NewDb(context.Background(), config.MdbxNode{Path: t.TempDir(), Name: "cdc", MaxSize: 1, Cdc: cdc})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = provider.Close() })
+	return provider.(*Db)
+}
+
+func TestChangeLogRecordsMutations(t *testing.T) {
+	db := setupCdcTestDb(t, config.Cdc{})
+
+	require.NoError(t, db.Set([]byte("a"), []byte("1")))
+	require.NoError(t, db.Set([]byte("b"), []byte("2")))
+	require.NoError(t, db.Delete([]byte("a")))
+
+	// Batched writes are recorded in the flush transaction
+	writer := NewBatchWriter(db, 512, 10*time.Millisecond, 1)
+	var key [32]byte
+	copy(key[:], "batched")
+	writer.BufferWrite(key, []byte("3"))
+	require.Eventually(t, func() bool {
+		_, last, err := db.ChangeLogBounds()
+		return err == nil && last == 4
+	}, 5*time.Second, 10*time.Millisecond)
+	writer.FlushAndStop()
+
+	changes, err := db.ReadChanges(0, 10)
+	require.NoError(t, err)
+	require.Len(t, changes, 4)
+
+	assert.Equal(t, uint64(1), changes[0].Seq)
+	assert.Equal(t, types.ChangeSet, changes[0].Op)
+	assert.Equal(t, []byte("a"), changes[0].Key)
+	assert.Equal(t, []byte("1"), changes[0].Value)
+	assert.Equal(t, types.ChangeDelete, changes[2].Op)
+	assert.Empty(t, changes[2].Value)
+	assert.Equal(t, key[:], changes[3].Key)
+
+	changes, err = db.ReadChanges(3, 1)
+	require.NoError(t, err)
+	require.Len(t, changes, 1)
+	assert.Equal(t, uint64(3), changes[0].Seq)
+}
+
+func TestChangeLogRetention(t *testing.T) {
+	db := setupCdcTestDb(t, config.Cdc{RetainCount: 3, PruneInterval: time.Hour})
+
+	for i := 0; i < 5; i++ {
+		require.NoError(t, db.Set([]byte{byte(i)}, []byte("value")))
+	}
+
+	pruned, err := db.PruneChanges()
+	require.NoError(t, err)
+	assert.Equal(t, 2, pruned)
+
+	first, last, err := db.ChangeLogBounds()
+	require.NoError(t, err)
+	assert.Equal(t, uint64(3), first)
+	assert.Equal(t, uint64(5), last)
+
+	// Sequence numbers keep increasing after pruning
+	require.NoError(t, db.Set([]byte("next"), []byte("value")))
+	_, last, err = db.ChangeLogBounds()
+	require.NoError(t, err)
+	assert.Equal(t, uint64(6), last)
+
+	_, err = db.ReadChanges(1, 10)
+	assert.ErrorIs(t, err, errors.ErrChangesTruncated)
+}
+
+func TestChangeLogSubscribeAndResume(t *testing.T) {
+	db := setupCdcTestDb(t, config.Cdc{})
+	require.NoError(t, db.Set([]byte("a"), []byte("1")))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	received := make(chan *messages.Change, 10)
+	done := make(chan error, 1)
+	go func() {
+		done <- db.Subscribe(ctx, 0, func(change *messages.Change) error {
+			received <- change
+			return nil
+		})
+	}()
+
+	// Existing changes are replayed, new commits are streamed as they happen
+	assert.Equal(t, uint64(1), (<-received).Seq)
+	require.NoError(t, db.Set([]byte("b"), []byte("2")))
+	assert.Equal(t, uint64(2), (<-received).Seq)
+
+	cancel()
+	assert.ErrorIs(t, <-done, context.Canceled)
+
+	// Resume after the last change seen
+	require.NoError(t, db.Set([]byte("c"), []byte("3")))
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		done <- db.Subscribe(ctx, 3, func(change *messages.Change) error {
+			received <- change
+			return nil
+		})
+	}()
+
+	change := <-received
+	assert.Equal(t, uint64(3), change.Seq)
+	assert.Equal(t, []byte("c"), change.Key)
+}
+
+func TestChangeLogDisabled(t *testing.T) {
+	manager := setupTestManager(t)
+	provider, err := manager.GetDb("test")
+	require.NoError(t, err)
+
+	_, err = provider.(*Db).ReadChanges(0, 1)
+	assert.ErrorIs(t, err, errors.ErrCdcDisabled)
+}
@@ -0,0 +1,171 @@
+package db
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/erigontech/mdbx-go/mdbx"
+	"github.com/pkg/errors"
+	fdbErrors "github.com/unpackdev/fdb/errors"
+	"github.com/unpackdev/fdb/types"
+)
+
+// consensusIndexKey is the key, within the replication DBI, of the last applied consensus log index.
+var consensusIndexKey = []byte("consensus_index")
+
+// consensusIndex returns the last applied consensus log index within txn, zero if none.
+func (db *Db) consensusIndex(txn *mdbx.Txn) (uint64, error) {
+	db.replMu.Lock()
+	open, dbi := db.replOpen, db.replDbi
+	db.replMu.Unlock()
+
+	if !open {
+		return 0, nil
+	}
+
+	value, err := txn.Get(dbi, consensusIndexKey)
+	if mdbx.IsNotFound(err) {
+		return 0, nil
+	} else if err != nil {
+		return 0, errors.Wrap(err, "failed to read consensus index")
+	}
+	return binary.BigEndian.Uint64(value), nil
+}
+
+// AppliedIndex returns the index of the last consensus log entry applied to the database.
+//
+// Returns:
+//
+//	uint64: The last applied log index, zero if none.
+//	error: Returns an error if the index cannot be read.
+func (db *Db) AppliedIndex() (uint64, error) {
+	if !db.Acquire() {
+		return 0, fdbErrors.ErrDatabaseClosed
+	}
+	defer db.Release()
+
+	var index uint64
+	err := db.env.View(func(txn *mdbx.Txn) error {
+		var err error
+		index, err = db.consensusIndex(txn)
+		return err
+	})
+	return index, err
+}
+
+// SetAppliedIndex records the consensus log index a restored snapshot is consistent with.
+//
+// Parameters:
+//
+//	index (uint64): The log index.
+//
+// Returns:
+//
+//	error: Returns an error if the index cannot be recorded.
+func (db *Db) SetAppliedIndex(index uint64) error {
+	if err := db.ensureReplicationDbi(); err != nil {
+		return err
+	}
+	if !db.Acquire() {
+		return fdbErrors.ErrDatabaseClosed
+	}
+	defer db.Release()
+
+	return db.env.Update(func(txn *mdbx.Txn) error {
+		return txn.Put(db.replDbi, consensusIndexKey, sequenceKey(index), 0)
+	})
+}
+
+// ApplyLogEntry applies a committed consensus log entry, recording its index in the same
+// transaction. Entries at or below the last applied index are skipped, so replaying the log
+// after a restart leaves the database unchanged. Like ApplyChanges it bypasses the read-only check.
+//
+// Parameters:
+//
+//	index (uint64): The log index of the entry.
+//	op (types.ChangeOp): The mutation to apply.
+//	key ([]byte): The mutated key.
+//	value ([]byte): The new value, ignored for deletes.
+//
+// Returns:
+//
+//	bool: Whether the entry was applied, false if it was applied before.
+//	error: Returns an error if the entry cannot be applied.
+func (db *Db) ApplyLogEntry(index uint64, op types.ChangeOp, key, value []byte) (bool, error) {
+	if err := db.ensureReplicationDbi(); err != nil {
+		return false, err
+	}
+	if !db.Acquire() {
+		return false, fdbErrors.ErrDatabaseClosed
+	}
+	defer db.Release()
+
+	applied := false
+	var log *changeAppender
+	err := db.env.Update(func(txn *mdbx.Txn) error {
+		last, err := db.consensusIndex(txn)
+		if err != nil || index <= last {
+			return err
+		}
+
+		log = db.newChangeAppender(txn)
+		defer log.close()
+
+		switch op {
+		case types.ChangeSet:
+			err = txn.Put(db.dbi, key, value, 0)
+		case types.ChangeDelete:
+			err = txn.Del(db.dbi, key, nil)
+			if mdbx.IsNotFound(err) {
+				err = nil
+			}
+		default:
+			err = fmt.Errorf("unknown change operation: %v", op)
+		}
+		if err != nil {
+			return errors.Wrapf(err, "failed to apply log entry %d", index)
+		}
+
+		if err := log.append(op, key, value); err != nil {
+			return err
+		}
+		applied = true
+		return txn.Put(db.replDbi, consensusIndexKey, sequenceKey(index), 0)
+	})
+	if err == nil && applied {
+		log.committed()
+		db.touchMerkle(key)
+	}
+	return applied, err
+}
+
+// Copy streams a consistent copy of the database, independent of the change log. start is
+// called first with the last applied consensus log index, then each is called for every
+// key-value pair. Both run inside a single read transaction, so keys and values must be
+// copied if retained.
+//
+// Parameters:
+//
+//	start (func(uint64) error): Receives the consensus log index the copy is consistent with.
+//	each (func(key, value []byte) error): Receives every key-value pair.
+//
+// Returns:
+//
+//	error: Returns the first callback or read error.
+func (db *Db) Copy(start func(index uint64) error, each func(key, value []byte) error) error {
+	if !db.Acquire() {
+		return fdbErrors.ErrDatabaseClosed
+	}
+	defer db.Release()
+
+	return db.env.View(func(txn *mdbx.Txn) error {
+		index, err := db.consensusIndex(txn)
+		if err != nil {
+			return err
+		}
+		if err := start(index); err != nil {
+			return err
+		}
+		return db.scan(txn, each)
+	})
+}
@@ -15,6 +15,11 @@
 //     MDBX-based databases. It handles opening, closing, and interacting with the MDBX
 //     environment.
 //
+//   - **Manager struct**: Owns every database of a node and manages their lifecycle at
+//     runtime (open, create, close, drop). Operations are reference counted so a close
+//     waits for in-flight requests, and databases created at runtime are persisted in a
+//     registry file so they are reopened after a restart.
+//
 // Example usage:
 //
 //	// Initialize a new database using MDBX
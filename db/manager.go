@@ -3,17 +3,55 @@ package db
 import (
 	"context"
 	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
 	"github.com/unpackdev/fdb/config"
+	fdbErrors "github.com/unpackdev/fdb/errors"
+	"github.com/unpackdev/fdb/messages"
 	"github.com/unpackdev/fdb/types"
+	"gopkg.in/yaml.v3"
 )
 
+// registryFile is the name of the file, stored in the MDBX data directory, that records
+// databases created at runtime so they can be reopened after a restart.
+const registryFile = "databases.yaml"
+
+// DbInfo describes a database known to the Manager.
+type DbInfo struct {
+	// Name is the database name (the MDBX node name).
+	Name string `json:"name" yaml:"name"`
+
+	// Path is the directory holding the MDBX files.
+	Path string `json:"path" yaml:"path"`
+
+	// Open reports whether the database is currently open and serving requests.
+	Open bool `json:"open" yaml:"open"`
+
+	// Dynamic reports whether the database was created at runtime rather than defined in the configuration.
+	Dynamic bool `json:"dynamic" yaml:"dynamic"`
+
+	// Remote reports whether the database is served by other nodes and was attached with Attach.
+	Remote bool `json:"remote" yaml:"remote"`
+}
+
+// registry is the on-disk representation of databases created at runtime.
+type registry struct {
+	Nodes []config.MdbxNode `yaml:"nodes"`
+}
+
 // Manager is responsible for managing multiple MDBX database instances based on the
 // provided configuration. It allows easy access to different databases by name and
-// handles the lifecycle operations such as opening, closing, and managing individual
-// database instances.
+// handles the lifecycle operations such as opening, creating, closing and dropping
+// individual database instances at runtime.
 //
 // The Manager uses the MDBX configuration to set up the databases and manages the
-// connections throughout the application's lifetime.
+// connections throughout the application's lifetime. Databases created at runtime are
+// recorded in a registry file inside the configured data directory and reopened on start.
 type Manager struct {
 	// ctx represents the context used for managing the database lifecycle, such as
 	// cancellation or timeouts.
@@ -23,13 +61,37 @@ type Manager struct {
 	// and the nodes that define the MDBX instances.
 	opts config.Mdbx
 
+	// mu guards dbs, nodes, dynamic, attached, readOnly, proposers, readers and adminOps.
+	mu sync.RWMutex
+
 	// dbs is a map that holds the active MDBX databases, indexed by their DbType (name).
 	dbs map[types.DbType]Provider
+
+	// nodes holds the configuration of every known database, open or closed.
+	nodes map[types.DbType]config.MdbxNode
+
+	// dynamic tracks which of the known databases were created at runtime.
+	dynamic map[types.DbType]bool
+
+	// attached lists the databases attached with Attach, in the order they were attached.
+	attached []types.DbType
+
+	// readOnly tracks the databases that reject client writes, applied again when they are reopened.
+	readOnly map[types.DbType]bool
+
+	// proposers holds the consensus proposer of the databases replicated through consensus.
+	proposers map[types.DbType]Proposer
+
+	// readers holds the reader of the databases whose reads are served from other nodes.
+	readers map[types.DbType]Reader
+
+	// adminOps holds the admin operations registered by other components, see RegisterAdminOp.
+	adminOps map[messages.AdminOp]AdminOpFunc
 }
 
 // NewManager creates a new Manager instance that manages multiple MDBX database instances
-// based on the configuration provided. It initializes the databases if MDBX is enabled and
-// stores them in the Manager.
+// based on the configuration provided. It initializes the databases if MDBX is enabled,
+// reopens databases previously created at runtime and stores them in the Manager.
 //
 // Example usage:
 //
@@ -48,22 +110,56 @@ type Manager struct {
 //	*Manager: A new Manager instance that manages the MDBX databases.
 //	error: Returns an error if any database initialization fails.
 func NewManager(ctx context.Context, opts config.Mdbx) (*Manager, error) {
-	dbs := make(map[types.DbType]Provider)
-	if opts.Enabled {
-		for _, node := range opts.Nodes {
-			db, err := NewDb(ctx, node)
-			if err != nil {
-				return nil, err
-			}
-			// Store the database in the manager map, indexed by DbType (name).
-			dbs[types.DbType(node.Name)] = db
+	m := &Manager{
+		ctx:       ctx,
+		opts:      opts,
+		dbs:       make(map[types.DbType]Provider),
+		nodes:     make(map[types.DbType]config.MdbxNode),
+		dynamic:   make(map[types.DbType]bool),
+		readOnly:  make(map[types.DbType]bool),
+		proposers: make(map[types.DbType]Proposer),
+		readers:   make(map[types.DbType]Reader),
+		adminOps:  make(map[messages.AdminOp]AdminOpFunc),
+	}
+
+	if !opts.Enabled {
+		return m, nil
+	}
+
+	for _, node := range opts.Nodes {
+		m.nodes[types.DbType(node.Name)] = node
+	}
+
+	persisted, err := m.loadRegistry()
+	if err != nil {
+		return nil, err
+	}
+	for _, node := range persisted {
+		name := types.DbType(node.Name)
+		if _, exists := m.nodes[name]; exists {
+			return nil, fmt.Errorf("runtime database %s conflicts with a configured mdbx node", name)
+		}
+		m.nodes[name] = node
+		m.dynamic[name] = true
+	}
+
+	for name, node := range m.nodes {
+		db, err := NewDb(ctx, node)
+		if err != nil {
+			_ = m.Close()
+			return nil, errors.Wrapf(err, "failure to open mdbx database: %s", name)
 		}
+		// Store the database in the manager map, indexed by DbType (name).
+		m.dbs[name] = db
 	}
-	return &Manager{ctx: ctx, opts: opts, dbs: dbs}, nil
+
+	return m, nil
 }
 
 // GetDb retrieves a specific database by its name (DbType) from the manager.
-// If the database is not found, an error is returned.
+// If the database is not found or is closed, an error is returned. The returned provider
+// is not reference counted; use Acquire when the database has to stay open for the
+// duration of a multi-step operation.
 //
 // Example usage:
 //
@@ -81,13 +177,478 @@ func NewManager(ctx context.Context, opts config.Mdbx) (*Manager, error) {
 //	Provider: The database provider associated with the specified name.
 //	error: Returns an error if the database is not found.
 func (m *Manager) GetDb(name types.DbType) (Provider, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
 	db, ok := m.dbs[name]
 	if !ok {
-		return nil, fmt.Errorf("mdbx database not found: %s", name)
+		return nil, fmt.Errorf("mdbx database not found: %s: %w", name, fdbErrors.ErrDatabaseNotFound)
 	}
 	return db, nil
 }
 
+// Acquire retrieves an open database and takes a reference on it. The database will not
+// be closed until the returned release function is called. Individual Provider operations
+// already hold a reference while they run; Acquire is meant for callers that work with the
+// raw MDBX environment (GetEnv/GetDBI) across several steps, which must not race with
+// CloseDb or DropDb.
+//
+// Example usage:
+//
+//	db, release, err := mdbxManager.Acquire("node1")
+//	if err != nil {
+//	    return err
+//	}
+//	defer release()
+//
+// Parameters:
+//
+//	name (types.DbType): The name of the database to acquire.
+//
+// Returns:
+//
+//	Provider: The database provider associated with the specified name.
+//	func(): Releases the reference; must be called exactly once.
+//	error: Returns an error if the database is not found or is closing.
+func (m *Manager) Acquire(name types.DbType) (Provider, func(), error) {
+	provider, err := m.GetDb(name)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	db, ok := provider.(*Db)
+	if !ok {
+		return provider, func() {}, nil
+	}
+
+	if !db.Acquire() {
+		return nil, nil, fmt.Errorf("mdbx database %s: %w", name, fdbErrors.ErrDatabaseClosed)
+	}
+	return db, db.Release, nil
+}
+
+// HasDb reports whether the database is known to the manager, whether open or closed.
+//
+// Parameters:
+//
+//	name (types.DbType): The name of the database to look up.
+//
+// Returns:
+//
+//	bool: True if the database is configured or was created at runtime.
+func (m *Manager) HasDb(name types.DbType) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.knows(name)
+}
+
+// knows reports whether the database is an MDBX node or an attached database. The caller must hold m.mu.
+func (m *Manager) knows(name types.DbType) bool {
+	if _, known := m.nodes[name]; known {
+		return true
+	}
+	return m.isAttached(name)
+}
+
+// isAttached reports whether the database was attached with Attach. The caller must hold m.mu.
+func (m *Manager) isAttached(name types.DbType) bool {
+	for _, attached := range m.attached {
+		if attached == name {
+			return true
+		}
+	}
+	return false
+}
+
+// DefaultDb returns the name of the first database defined in the configuration, or of the
+// first attached database when MDBX has no nodes. Transports that do not declare the databases
+// they serve route requests without a database selector to it.
+//
+// Returns:
+//
+//	types.DbType: The default database name, empty if there is no database.
+func (m *Manager) DefaultDb() types.DbType {
+	if m.opts.Enabled && len(m.opts.Nodes) > 0 {
+		return types.DbType(m.opts.Nodes[0].Name)
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if len(m.attached) == 0 {
+		return ""
+	}
+	return m.attached[0]
+}
+
+// Attach serves a database that is not stored in a local MDBX environment, such as one
+// forwarded to other nodes, under the given name. Attached databases are resolved and listed
+// like MDBX databases; they cannot be reopened, closed or dropped individually and are closed
+// together with the manager.
+//
+// Example usage:
+//
+//	if err := manager.Attach("fdb", proxy.Provider("fdb")); err != nil {
+//	    log.Fatalf("Failed to attach database: %v", err)
+//	}
+//
+// Parameters:
+//
+//	name (types.DbType): The name the database is served under.
+//	provider (Provider): The provider serving the database.
+//
+// Returns:
+//
+//	error: Returns an error wrapping errors.ErrDatabaseExists if the name is taken.
+func (m *Manager) Attach(name types.DbType, provider Provider) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.knows(name) {
+		return fmt.Errorf("database %s: %w", name, fdbErrors.ErrDatabaseExists)
+	}
+
+	m.attached = append(m.attached, name)
+	m.dbs[name] = provider
+	return nil
+}
+
+// SetReadOnly switches a known database between serving and rejecting client writes. The
+// setting is kept across CloseDb and OpenDb, replicas stay read-only until promoted.
+//
+// Parameters:
+//
+//	name (types.DbType): The name of the database.
+//	readOnly (bool): Whether client writes are rejected.
+//
+// Returns:
+//
+//	error: Returns an error wrapping errors.ErrDatabaseNotFound if the database is unknown.
+func (m *Manager) SetReadOnly(name types.DbType, readOnly bool) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, known := m.nodes[name]; !known {
+		return fmt.Errorf("mdbx database not found: %s: %w", name, fdbErrors.ErrDatabaseNotFound)
+	}
+
+	m.readOnly[name] = readOnly
+	if db, ok := m.dbs[name].(*Db); ok {
+		db.SetReadOnly(readOnly)
+	}
+	return nil
+}
+
+// SetProposer routes the client writes of a database through a consensus proposer, or back
+// to the batch writer when p is nil.
+//
+// Parameters:
+//
+//	name (types.DbType): The name of the database.
+//	p (Proposer): The proposer committing the writes, nil to remove it.
+//
+// Returns:
+//
+//	error: Returns an error wrapping errors.ErrDatabaseNotFound if the database is unknown.
+func (m *Manager) SetProposer(name types.DbType, p Proposer) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !m.knows(name) {
+		return fmt.Errorf("mdbx database not found: %s: %w", name, fdbErrors.ErrDatabaseNotFound)
+	}
+
+	if p == nil {
+		delete(m.proposers, name)
+	} else {
+		m.proposers[name] = p
+	}
+	return nil
+}
+
+// Proposer returns the consensus proposer of a database, nil if its writes are not replicated through consensus.
+func (m *Manager) Proposer(name types.DbType) Proposer {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.proposers[name]
+}
+
+// SetReader routes the client reads of a database through a reader, or back to the database
+// itself when r is nil.
+//
+// Parameters:
+//
+//	name (types.DbType): The name of the database.
+//	r (Reader): The reader serving the reads, nil to remove it.
+//
+// Returns:
+//
+//	error: Returns an error wrapping errors.ErrDatabaseNotFound if the database is unknown.
+func (m *Manager) SetReader(name types.DbType, r Reader) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !m.knows(name) {
+		return fmt.Errorf("mdbx database not found: %s: %w", name, fdbErrors.ErrDatabaseNotFound)
+	}
+
+	if r == nil {
+		delete(m.readers, name)
+	} else {
+		m.readers[name] = r
+	}
+	return nil
+}
+
+// Reader returns the reader of a database, nil if its reads are served by the database itself.
+func (m *Manager) Reader(name types.DbType) Reader {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.readers[name]
+}
+
+// ListDbs returns every database known to the manager, whether open or closed, sorted by name.
+//
+// Returns:
+//
+//	[]DbInfo: Information about each known database.
+func (m *Manager) ListDbs() []DbInfo {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	infos := make([]DbInfo, 0, len(m.nodes)+len(m.attached))
+	for _, name := range m.attached {
+		infos = append(infos, DbInfo{Name: name.String(), Open: true, Remote: true})
+	}
+	for name, node := range m.nodes {
+		_, open := m.dbs[name]
+		infos = append(infos, DbInfo{
+			Name:    node.Name,
+			Path:    node.Path,
+			Open:    open,
+			Dynamic: m.dynamic[name],
+		})
+	}
+
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].Name < infos[j].Name
+	})
+	return infos
+}
+
+// OpenDb opens a known database that was previously closed with CloseDb.
+//
+// Parameters:
+//
+//	name (types.DbType): The name of the database to open.
+//
+// Returns:
+//
+//	error: Returns an error if the database is unknown, already open, or fails to open.
+func (m *Manager) OpenDb(name types.DbType) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	node, known := m.nodes[name]
+	if !known {
+		return fmt.Errorf("mdbx database not found: %s: %w", name, fdbErrors.ErrDatabaseNotFound)
+	}
+	if _, open := m.dbs[name]; open {
+		return fmt.Errorf("mdbx database already open: %s", name)
+	}
+
+	db, err := NewDb(m.ctx, node)
+	if err != nil {
+		return errors.Wrapf(err, "failure to open mdbx database: %s", name)
+	}
+	if mdbxDb, ok := db.(*Db); ok {
+		mdbxDb.SetReadOnly(m.readOnly[name])
+	}
+
+	m.dbs[name] = db
+	return nil
+}
+
+// CreateDb creates and opens a new database at runtime and records it in the registry
+// so it is reopened after a restart. The database is always stored in a sub-directory of
+// the configured data directory named after the database: the node must not set a path, and
+// the name must not be "." or contain a path separator or "..", so that dropping the database
+// later only ever removes files under the data directory.
+//
+// Example usage:
+//
+//	err := mdbxManager.CreateDb(config.MdbxNode{Name: "contracts", MaxSize: 64, MaxReaders: 512})
+//	if err != nil {
+//	    log.Fatalf("Failed to create database: %v", err)
+//	}
+//
+// Parameters:
+//
+//	node (config.MdbxNode): The configuration of the database to create.
+//
+// Returns:
+//
+//	error: Returns an error if runtime creation is disabled, the name or path is invalid, the name is taken, or the database fails to open.
+func (m *Manager) CreateDb(node config.MdbxNode) error {
+	if !m.opts.Enabled {
+		return errors.New("mdbx is disabled")
+	}
+	if m.opts.DataDir == "" {
+		return errors.New("mdbx dataDir must be configured to create databases at runtime")
+	}
+
+	if err := validateDynamicName(node.Name); err != nil {
+		return err
+	}
+	if node.Path != "" {
+		return fmt.Errorf("mdbx database %s: the path of runtime databases is derived from dataDir and cannot be set", node.Name)
+	}
+	node.Path = filepath.Join(m.opts.DataDir, node.Name)
+	if node.FilePermissions == 0 {
+		node.FilePermissions = 0600
+	}
+	if err := node.Validate(); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	name := types.DbType(node.Name)
+	if _, exists := m.nodes[name]; exists {
+		return fmt.Errorf("mdbx database %s: %w", name, fdbErrors.ErrDatabaseExists)
+	}
+
+	if err := os.MkdirAll(node.Path, 0700); err != nil {
+		return errors.Wrapf(err, "failure to create mdbx database directory: %s", node.Path)
+	}
+
+	db, err := NewDb(m.ctx, node)
+	if err != nil {
+		return errors.Wrapf(err, "failure to create mdbx database: %s", name)
+	}
+
+	m.nodes[name] = node
+	m.dynamic[name] = true
+	m.dbs[name] = db
+
+	if err := m.saveRegistry(); err != nil {
+		delete(m.nodes, name)
+		delete(m.dynamic, name)
+		delete(m.dbs, name)
+		_ = db.Destroy()
+		return err
+	}
+
+	return nil
+}
+
+// CloseDb closes an open database. The database stops accepting new requests immediately,
+// while requests already in flight are allowed to finish before the environment is closed.
+// The database stays known to the manager and can be reopened with OpenDb.
+//
+// Parameters:
+//
+//	name (types.DbType): The name of the database to close.
+//
+// Returns:
+//
+//	error: Returns an error if the database is not open, is attached, or fails to close.
+func (m *Manager) CloseDb(name types.DbType) error {
+	m.mu.Lock()
+	if m.isAttached(name) {
+		m.mu.Unlock()
+		return fmt.Errorf("database %s is attached and cannot be closed", name)
+	}
+	db, open := m.dbs[name]
+	if !open {
+		m.mu.Unlock()
+		return fmt.Errorf("mdbx database not open: %s: %w", name, fdbErrors.ErrDatabaseNotFound)
+	}
+	delete(m.dbs, name)
+	m.mu.Unlock()
+
+	// Closing may wait for in-flight requests, so it happens outside the manager lock.
+	return db.Close()
+}
+
+// DropDb closes a database created at runtime, removes its files from disk and forgets
+// it. Databases defined in the configuration cannot be dropped, as they would be
+// recreated on the next start.
+//
+// Parameters:
+//
+//	name (types.DbType): The name of the database to drop.
+//
+// Returns:
+//
+//	error: Returns an error if the database is unknown, statically configured, or cannot be removed.
+func (m *Manager) DropDb(name types.DbType) error {
+	m.mu.Lock()
+	node, known := m.nodes[name]
+	if !known {
+		m.mu.Unlock()
+		return fmt.Errorf("mdbx database not found: %s: %w", name, fdbErrors.ErrDatabaseNotFound)
+	}
+	if !m.dynamic[name] {
+		m.mu.Unlock()
+		return fmt.Errorf("mdbx database %s is defined in the configuration and cannot be dropped", name)
+	}
+
+	// The files are only removed under the data directory, whatever the registry recorded
+	if !withinDir(m.opts.DataDir, node.Path) {
+		m.mu.Unlock()
+		return fmt.Errorf("mdbx database %s is stored outside of dataDir and cannot be dropped: %s", name, node.Path)
+	}
+
+	db, open := m.dbs[name]
+	delete(m.dbs, name)
+	delete(m.nodes, name)
+	delete(m.dynamic, name)
+	saveErr := m.saveRegistry()
+	m.mu.Unlock()
+
+	if saveErr != nil {
+		return saveErr
+	}
+
+	// Destroy closes the environment (waiting for in-flight requests) before removing files.
+	if open {
+		return db.Destroy()
+	}
+	if err := os.RemoveAll(node.Path); err != nil {
+		return errors.Wrap(err, "failed to remove database files")
+	}
+	return nil
+}
+
+// validateDynamicName checks that the name of a runtime database names a single directory
+// within the data directory.
+func validateDynamicName(name string) error {
+	if name == "" {
+		return errors.New("mdbx database name must not be empty")
+	}
+	if name == "." || strings.ContainsAny(name, `/\`) || strings.Contains(name, "..") {
+		return fmt.Errorf("mdbx database name %q must not be \".\" or contain a path separator or \"..\"", name)
+	}
+	return nil
+}
+
+// withinDir reports whether path is located strictly inside dir.
+func withinDir(dir, path string) bool {
+	if dir == "" {
+		return false
+	}
+	rel, err := filepath.Rel(filepath.Clean(dir), filepath.Clean(path))
+	if err != nil || rel == "." || filepath.IsAbs(rel) {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
 // Close gracefully closes all managed databases in the Manager. It iterates through all
 // the databases and calls their respective Close methods to ensure proper resource cleanup.
 //
@@ -102,10 +663,67 @@ func (m *Manager) GetDb(name types.DbType) (Provider, error) {
 //
 //	error: Returns an error if any of the databases fail to close properly.
 func (m *Manager) Close() error {
-	for _, db := range m.dbs {
+	m.mu.Lock()
+	dbs := m.dbs
+	m.dbs = make(map[types.DbType]Provider)
+	m.mu.Unlock()
+
+	for _, db := range dbs {
 		if err := db.Close(); err != nil {
 			return err
 		}
 	}
 	return nil
 }
+
+// loadRegistry reads the databases created at runtime from the data directory, if any.
+func (m *Manager) loadRegistry() ([]config.MdbxNode, error) {
+	if m.opts.DataDir == "" {
+		return nil, nil
+	}
+
+	data, err := os.ReadFile(filepath.Join(m.opts.DataDir, registryFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, errors.Wrap(err, "failure to read mdbx database registry")
+	}
+
+	var reg registry
+	if err := yaml.Unmarshal(data, &reg); err != nil {
+		return nil, errors.Wrap(err, "failure to decode mdbx database registry")
+	}
+	return reg.Nodes, nil
+}
+
+// saveRegistry atomically writes the databases created at runtime to the data directory.
+// The caller must hold m.mu.
+func (m *Manager) saveRegistry() error {
+	reg := registry{Nodes: make([]config.MdbxNode, 0, len(m.dynamic))}
+	for name := range m.dynamic {
+		reg.Nodes = append(reg.Nodes, m.nodes[name])
+	}
+	sort.Slice(reg.Nodes, func(i, j int) bool {
+		return reg.Nodes[i].Name < reg.Nodes[j].Name
+	})
+
+	data, err := yaml.Marshal(reg)
+	if err != nil {
+		return errors.Wrap(err, "failure to encode mdbx database registry")
+	}
+
+	if err := os.MkdirAll(m.opts.DataDir, 0700); err != nil {
+		return errors.Wrap(err, "failure to create mdbx data directory")
+	}
+
+	path := filepath.Join(m.opts.DataDir, registryFile)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return errors.Wrap(err, "failure to write mdbx database registry")
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return errors.Wrap(err, "failure to replace mdbx database registry")
+	}
+	return nil
+}
@@ -2,12 +2,17 @@ package db
 
 import (
 	"context"
+	"github.com/erigontech/mdbx-go/mdbx"
 	"github.com/unpackdev/fdb/config"
 	"github.com/unpackdev/fdb/errors"
+	"github.com/unpackdev/fdb/messages"
 	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 type testCase struct {
@@ -21,18 +26,17 @@ func setupTestManager(t *testing.T) *Manager {
 	ctx := context.Background()
 
 	// Create a temporary directory for the test database
-	path := "./testdb"
-	err := os.Mkdir(path, 0755)
-	assert.NoError(t, err)
+	path := t.TempDir()
 
 	// Create options for the database
-	opts := config.MdbxNodes{
-		{Path: path, Name: "test"},
+	opts := config.Mdbx{
+		Enabled: true,
+		Nodes:   []config.MdbxNode{{Path: path, Name: "test", MaxSize: 1}},
 	}
 
 	// Initialize Manager
 	manager, err := NewManager(ctx, opts)
-	assert.NoError(t, err)
+	require.NoError(t, err)
 
 	// Teardown function to clean up after the test
 	return manager
@@ -52,8 +56,9 @@ func setupBenchmarkTestManager(b *testing.B, dbPath string, dbName string) *Mana
 	assert.NoError(b, err, "Failed to create database directory")
 
 	// Create options for the database
-	opts := config.MdbxNodes{
-		{Path: dbPath, Name: dbName},
+	opts := config.Mdbx{
+		Enabled: true,
+		Nodes:   []config.MdbxNode{{Path: dbPath, Name: dbName, MaxSize: 1}},
 	}
 
 	// Initialize the Manager
@@ -114,3 +119,213 @@ func TestManagerDbOperations(t *testing.T) {
 	err = db.Destroy()
 	assert.NoError(t, err)
 }
+
+func setupLifecycleTestManager(t *testing.T, dataDir string) *Manager {
+	opts := config.Mdbx{
+		Enabled: true,
+		DataDir: dataDir,
+		Nodes:   []config.MdbxNode{{Path: t.TempDir(), Name: "static", MaxSize: 1}},
+	}
+
+	manager, err := NewManager(context.Background(), opts)
+	require.NoError(t, err)
+	return manager
+}
+
+func TestManagerLifecycle(t *testing.T) {
+	dataDir := t.TempDir()
+	manager := setupLifecycleTestManager(t, dataDir)
+
+	// Create a database at runtime and use it
+	require.NoError(t, manager.CreateDb(config.MdbxNode{Name: "dynamic", MaxSize: 1}))
+	assert.ErrorIs(t, manager.CreateDb(config.MdbxNode{Name: "dynamic", MaxSize: 1}), errors.ErrDatabaseExists)
+
+	db, err := manager.GetDb("dynamic")
+	require.NoError(t, err)
+	require.NoError(t, db.Set([]byte("key"), []byte("value")))
+
+	assert.Equal(t, []DbInfo{
+		{Name: "dynamic", Path: dataDir + "/dynamic", Open: true, Dynamic: true},
+		{Name: "static", Path: manager.nodes["static"].Path, Open: true},
+	}, manager.ListDbs())
+
+	// Close and reopen keeps the data
+	require.NoError(t, manager.CloseDb("dynamic"))
+	_, err = manager.GetDb("dynamic")
+	assert.ErrorIs(t, err, errors.ErrDatabaseNotFound)
+	_, err = db.Get([]byte("key"))
+	assert.ErrorIs(t, err, errors.ErrDatabaseClosed)
+
+	require.NoError(t, manager.OpenDb("dynamic"))
+	db, err = manager.GetDb("dynamic")
+	require.NoError(t, err)
+	value, err := db.Get([]byte("key"))
+	require.NoError(t, err)
+	assert.Equal(t, []byte("value"), value)
+
+	// Statically configured databases cannot be dropped
+	assert.Error(t, manager.DropDb("static"))
+	require.NoError(t, manager.Close())
+
+	// Runtime databases survive a restart
+	manager = setupLifecycleTestManager(t, dataDir)
+	db, err = manager.GetDb("dynamic")
+	require.NoError(t, err)
+	value, err = db.Get([]byte("key"))
+	require.NoError(t, err)
+	assert.Equal(t, []byte("value"), value)
+
+	// Dropping removes the files and the registry entry
+	require.NoError(t, manager.DropDb("dynamic"))
+	_, err = os.Stat(dataDir + "/dynamic")
+	assert.True(t, os.IsNotExist(err))
+	require.NoError(t, manager.Close())
+
+	manager = setupLifecycleTestManager(t, dataDir)
+	defer manager.Close()
+	_, err = manager.GetDb("dynamic")
+	assert.ErrorIs(t, err, errors.ErrDatabaseNotFound)
+}
+
+func TestManagerCreateDbStaysInDataDir(t *testing.T) {
+	dataDir := t.TempDir()
+	manager := setupLifecycleTestManager(t, dataDir)
+	defer manager.Close()
+
+	// Names that would escape the data directory are rejected
+	for _, name := range []string{"", ".", "..", "../escaped", "nested/db", `nested\db`, "/abs"} {
+		assert.Error(t, manager.CreateDb(config.MdbxNode{Name: name, MaxSize: 1}), name)
+	}
+	_, err := os.Stat(filepath.Join(filepath.Dir(dataDir), "escaped"))
+	assert.True(t, os.IsNotExist(err))
+
+	// A client cannot choose where the files are stored
+	_, err = manager.HandleAdminRequest(&messages.AdminRequest{
+		Op:      messages.AdminCreateDb,
+		Name:    "evil",
+		Payload: []byte(`{"path":"/","maxSize":1}`),
+	})
+	assert.Error(t, err)
+	assert.False(t, manager.HasDb("evil"))
+
+	_, err = manager.HandleAdminRequest(&messages.AdminRequest{
+		Op:      messages.AdminCreateDb,
+		Name:    "../evil",
+		Payload: []byte(`{"maxSize":1}`),
+	})
+	assert.Error(t, err)
+	assert.False(t, manager.HasDb("../evil"))
+}
+
+func TestManagerDropDbStaysInDataDir(t *testing.T) {
+	dataDir := t.TempDir()
+	manager := setupLifecycleTestManager(t, dataDir)
+	defer manager.Close()
+
+	// A registry entry pointing outside of the data directory is never removed
+	outside := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(outside, "keep"), nil, 0600))
+
+	manager.mu.Lock()
+	manager.nodes["tampered"] = config.MdbxNode{Name: "tampered", Path: outside, MaxSize: 1}
+	manager.dynamic["tampered"] = true
+	manager.mu.Unlock()
+
+	assert.Error(t, manager.DropDb("tampered"))
+	_, err := os.Stat(filepath.Join(outside, "keep"))
+	assert.NoError(t, err)
+}
+
+func TestManagerCloseWaitsForAcquiredReferences(t *testing.T) {
+	manager := setupLifecycleTestManager(t, t.TempDir())
+	defer manager.Close()
+
+	db, release, err := manager.Acquire("static")
+	require.NoError(t, err)
+
+	closed := make(chan error, 1)
+	go func() {
+		closed <- manager.CloseDb("static")
+	}()
+
+	// The holder can keep using the raw environment while the close is pending
+	select {
+	case <-closed:
+		t.Fatal("database closed while a reference was still held")
+	case <-time.After(50 * time.Millisecond):
+	}
+	mdbxDb := db.(*Db)
+	require.NoError(t, mdbxDb.GetEnv().Update(func(txn *mdbx.Txn) error {
+		return txn.Put(mdbxDb.GetDBI(), []byte("key"), []byte("value"), 0)
+	}))
+
+	// Regular operations are rejected once the close has started
+	assert.ErrorIs(t, db.Set([]byte("key"), []byte("value")), errors.ErrDatabaseClosed)
+
+	// New acquisitions are rejected once the close has started
+	_, _, err = manager.Acquire("static")
+	assert.ErrorIs(t, err, errors.ErrDatabaseNotFound)
+
+	release()
+	select {
+	case err := <-closed:
+		assert.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("database was not closed after the reference was released")
+	}
+}
+
+func TestManagerAttach(t *testing.T) {
+	manager, err := NewManager(context.Background(), config.Mdbx{})
+	require.NoError(t, err)
+	assert.Empty(t, manager.DefaultDb())
+
+	// Any provider can be attached, an MDBX database stands in for a remote one here
+	remote := setupTestManager(t)
+	provider, err := remote.GetDb("test")
+	require.NoError(t, err)
+	defer remote.Close()
+
+	require.NoError(t, manager.Attach("remote", provider))
+	assert.ErrorIs(t, manager.Attach("remote", provider), errors.ErrDatabaseExists)
+
+	assert.True(t, manager.HasDb("remote"))
+	assert.Equal(t, "remote", manager.DefaultDb().String())
+	assert.Equal(t, []DbInfo{{Name: "remote", Open: true, Remote: true}}, manager.ListDbs())
+
+	router, err := NewRouter(manager, []string{"remote"})
+	require.NoError(t, err)
+	resolved, err := router.Resolve("")
+	require.NoError(t, err)
+	assert.Equal(t, provider, resolved)
+
+	assert.Error(t, manager.CloseDb("remote"))
+}
+
+func TestCloseWaitsForBackgroundGoroutines(t *testing.T) {
+	// The periodic sync and change log retention run as often as possible while the database closes
+	provider, err := NewDb(context.Background(), config.MdbxNode{
+		Path:       t.TempDir(),
+		Name:       "background",
+		MaxSize:    1,
+		SyncMode:   config.MdbxSyncSafeNoSync,
+		SyncPeriod: time.Microsecond,
+		Cdc:        config.Cdc{Enabled: true, RetainCount: 1, PruneInterval: time.Microsecond},
+	})
+	require.NoError(t, err)
+	db := provider.(*Db)
+
+	require.NoError(t, db.Set([]byte("key"), []byte("value")))
+	time.Sleep(10 * time.Millisecond)
+
+	closed := make(chan error, 1)
+	go func() {
+		closed <- db.Close()
+	}()
+	select {
+	case err := <-closed:
+		assert.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("database was not closed")
+	}
+}
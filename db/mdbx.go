@@ -5,7 +5,13 @@ import (
 	"github.com/erigontech/mdbx-go/mdbx"
 	"github.com/pkg/errors"
 	"github.com/unpackdev/fdb/config"
+	fdbErrors "github.com/unpackdev/fdb/errors"
+	"github.com/unpackdev/fdb/types"
+	"go.uber.org/zap"
 	"os"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
 // Db represents a wrapper around an MDBX database environment. It manages
@@ -26,12 +32,69 @@ type Db struct {
 
 	// dbi is the MDBX database instance handle used for interacting with the database.
 	dbi mdbx.DBI
+
+	// cdcDbi is the named DBI holding the change log, only opened when CDC is enabled.
+	cdcDbi mdbx.DBI
+
+	// replDbi is the named DBI holding the replication position, opened once the database replicates.
+	replDbi mdbx.DBI
+
+	// replMu guards replOpen.
+	replMu sync.Mutex
+
+	// replOpen reports whether replDbi has been opened.
+	replOpen bool
+
+	// versionsDbi is the named DBI holding the versions of quorum-replicated keys, opened once
+	// the database stores a versioned value.
+	versionsDbi mdbx.DBI
+
+	// versionsMu guards versionsOpen.
+	versionsMu sync.Mutex
+
+	// versionsOpen reports whether versionsDbi has been opened.
+	versionsOpen bool
+
+	// readOnly rejects client writes while the database replicates from a leader.
+	readOnly atomic.Bool
+
+	// cdcMu guards cdcNotify.
+	cdcMu sync.Mutex
+
+	// cdcNotify is closed and replaced after every commit that appended to the change log.
+	cdcNotify chan struct{}
+
+	// stopSync signals the background goroutines (periodic sync, change log retention) to exit.
+	stopSync chan struct{}
+
+	// background tracks the background goroutines; Close waits for them before closing the
+	// environment, as they use it without a reference.
+	background sync.WaitGroup
+
+	// closeOnce guards against closing the environment more than once.
+	closeOnce sync.Once
+
+	// refs counts in-flight operations and handles acquired through Acquire. Close waits
+	// for it to drop to zero before closing the environment.
+	refs atomic.Int64
+
+	// closing is set once Close has been called; new acquisitions are rejected from then on.
+	closing atomic.Bool
+
+	// drained is signalled when the last reference is released while the database is closing.
+	drained chan struct{}
+
+	// tree summarises the data for anti-entropy repair, see MerkleHashes.
+	tree *merkleTree
 }
 
 // NewDb creates a new MDBX database environment based on the provided configuration.
-// It sets the database geometry (min size, max size, growth step), maximum readers,
-// and file permissions. The function returns a Provider interface to allow for interaction
-// with the database.
+// It applies the node profile, sets the database geometry (min size, max size, growth step,
+// page size), maximum readers and DBIs, durability flags and file permissions. When a relaxed
+// sync mode is configured together with a sync period, a background goroutine periodically
+// flushes the environment to disk. When CDC is enabled the change log DBI is opened as well
+// and, if retention is configured, pruned in the background. The function returns a Provider interface to allow for
+// interaction with the database.
 //
 // Example usage:
 //
@@ -50,36 +113,87 @@ type Db struct {
 //	Provider: A new MDBX database provider for interacting with the database.
 //	error: Returns an error if the environment or database creation fails.
 func NewDb(ctx context.Context, opts config.MdbxNode) (Provider, error) {
+	opts, err := opts.WithProfile()
+	if err != nil {
+		return nil, err
+	}
+
 	env, err := mdbx.NewEnv()
 	if err != nil {
 		return nil, err
 	}
 
-	// Set database geometry (size limits and growth step)
-	maxSize := int(opts.MaxSize * 1024 * 1024 * 1024) // Convert from GB to bytes * MaxSize
-	minSize := int(opts.MinSize * 1024 * 1024 * 1024) // Convert from MB to bytes * MinSize
-	growthStep := int(opts.GrowthStep)                // Growth step in bytes
+	// Set database geometry (size limits, growth step and page size).
+	// Unset (zero) values are passed as -1 so MDBX keeps its defaults.
+	maxSize := geometryValue(opts.MaxSize * 1024 * 1024 * 1024) // Convert from GB to bytes * MaxSize
+	minSize := geometryValue(opts.MinSize * 1024 * 1024 * 1024) // Convert from GB to bytes * MinSize
+	growthStep := geometryValue(opts.GrowthStep)                // Growth step in bytes
+	pageSize := geometryValue(int64(opts.PageSize))             // Page size in bytes
 
-	err = env.SetGeometry(minSize, -1, maxSize, -1, -1, growthStep)
+	err = env.SetGeometry(minSize, -1, maxSize, growthStep, -1, pageSize)
 	if err != nil {
+		env.Close()
 		return nil, err
 	}
 
 	// Set the maximum number of readers
-	if soErr := env.SetOption(mdbx.OptMaxReaders, uint64(opts.MaxReaders)); soErr != nil {
-		return nil, soErr
+	if opts.MaxReaders > 0 {
+		if soErr := env.SetOption(mdbx.OptMaxReaders, uint64(opts.MaxReaders)); soErr != nil {
+			env.Close()
+			return nil, soErr
+		}
 	}
 
-	// Open the environment with the specified file permissions
+	// Set the maximum number of named databases, leaving room for the internal ones (change log, replication)
+	maxDBs := opts.MaxDBs
+	if maxDBs < internalDbis {
+		maxDBs = internalDbis
+	}
+	if soErr := env.SetOption(mdbx.OptMaxDB, uint64(maxDBs)); soErr != nil {
+		env.Close()
+		return nil, soErr
+	}
 
-	if eoErr := env.Open(opts.Path, mdbx.Create, os.FileMode(opts.FilePermissions)); eoErr != nil {
+	// Open the environment with the specified durability flags and file permissions.
+	// A zero mode would make MDBX refuse to create the files, so fall back to the documented 0600.
+	fileMode := os.FileMode(opts.FilePermissions)
+	if fileMode == 0 {
+		fileMode = 0600
+	}
+	if eoErr := env.Open(opts.Path, mdbx.Create|envFlags(opts), fileMode); eoErr != nil {
+		env.Close()
 		return nil, eoErr
 	}
 
-	// Open the database within the environment
-	var dbi mdbx.DBI
+	// Open the database, and the change log if enabled, within the environment
+	var dbi, cdcDbi, replDbi, versionsDbi mdbx.DBI
+	var replOpen, versionsOpen bool
 	err = env.Update(func(txn *mdbx.Txn) error {
 		dbi, err = txn.OpenRoot(mdbx.Create)
+		if err != nil {
+			return err
+		}
+
+		// The replication position only exists once the database replicated from a leader
+		replDbi, err = txn.OpenDBISimple(replicationDbiName, 0)
+		if err == nil {
+			replOpen = true
+		} else if !mdbx.IsNotFound(err) {
+			return err
+		}
+
+		// Versions only exist once the database stored a quorum-replicated value
+		versionsDbi, err = txn.OpenDBISimple(versionsDbiName, 0)
+		if err == nil {
+			versionsOpen = true
+		} else if !mdbx.IsNotFound(err) {
+			return err
+		}
+
+		if !opts.Cdc.Enabled {
+			return nil
+		}
+		cdcDbi, err = txn.OpenDBISimple(cdcDbiName, mdbx.Create)
 		return err
 	})
 	if err != nil {
@@ -87,7 +201,142 @@ func NewDb(ctx context.Context, opts config.MdbxNode) (Provider, error) {
 		return nil, err
 	}
 
-	return &Db{ctx: ctx, opts: opts, env: env, dbi: dbi}, nil
+	db := &Db{
+		ctx:          ctx,
+		opts:         opts,
+		env:          env,
+		dbi:          dbi,
+		cdcDbi:       cdcDbi,
+		replDbi:      replDbi,
+		replOpen:     replOpen,
+		versionsDbi:  versionsDbi,
+		versionsOpen: versionsOpen,
+		cdcNotify:    make(chan struct{}),
+		stopSync:     make(chan struct{}),
+		drained:      make(chan struct{}, 1),
+		tree:         newMerkleTree(),
+	}
+
+	if opts.SyncMode.IsRelaxed() && opts.SyncPeriod > 0 {
+		db.background.Add(1)
+		go db.runPeriodicSync(opts.SyncPeriod)
+	}
+
+	if opts.Cdc.Enabled && opts.Cdc.HasRetention() {
+		db.background.Add(1)
+		go db.runCdcRetention()
+	}
+
+	return db, nil
+}
+
+// geometryValue converts a configured geometry size into the value expected by
+// SetGeometry, where -1 keeps the MDBX default.
+func geometryValue(v int64) int {
+	if v <= 0 {
+		return -1
+	}
+	return int(v)
+}
+
+// envFlags translates the node durability and tuning options into MDBX environment flags.
+func envFlags(opts config.MdbxNode) uint {
+	var flags uint
+
+	switch opts.SyncMode {
+	case config.MdbxSyncNoMetaSync:
+		flags |= mdbx.NoMetaSync
+	case config.MdbxSyncSafeNoSync:
+		flags |= mdbx.SafeNoSync
+	case config.MdbxSyncUtterlyNoSync:
+		flags |= mdbx.UtterlyNoSync
+	default:
+		flags |= mdbx.Durable
+	}
+
+	if config.Enabled(opts.WriteMap) {
+		flags |= mdbx.WriteMap
+	}
+	if config.Enabled(opts.NoReadahead) {
+		flags |= mdbx.NoReadahead
+	}
+	if config.Enabled(opts.Coalesce) {
+		flags |= mdbx.Coalesce
+	}
+	if config.Enabled(opts.LifoReclaim) {
+		flags |= mdbx.LifoReclaim
+	}
+
+	return flags
+}
+
+// runPeriodicSync flushes the environment to disk every period until the database is
+// closed or its context is cancelled. It is only started for relaxed sync modes.
+func (db *Db) runPeriodicSync(period time.Duration) {
+	defer db.background.Done()
+
+	ticker := time.NewTicker(period)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := db.env.Sync(true, false); err != nil {
+				zap.L().Error(
+					"failure to sync mdbx environment",
+					zap.String("name", db.opts.Name),
+					zap.Error(err),
+				)
+			}
+		case <-db.stopSync:
+			return
+		case <-db.ctx.Done():
+			return
+		}
+	}
+}
+
+// Acquire takes a reference on the database, preventing the environment from being closed
+// until the matching Release call. It returns false if the database is closed or closing, in
+// which case Release must not be called.
+//
+// Example usage:
+//
+//	if !db.Acquire() {
+//	    return errors.ErrDatabaseClosed
+//	}
+//	defer db.Release()
+//
+// Returns:
+//
+//	bool: True if the reference was taken, false if the database is no longer usable.
+func (db *Db) Acquire() bool {
+	db.refs.Add(1)
+	if db.closing.Load() {
+		db.Release()
+		return false
+	}
+	return true
+}
+
+// Release drops a reference previously taken with Acquire.
+func (db *Db) Release() {
+	if db.refs.Add(-1) == 0 && db.closing.Load() {
+		select {
+		case db.drained <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// GetName returns the name of the MDBX node backing this database.
+func (db *Db) GetName() string {
+	return db.opts.Name
+}
+
+// GetOptions returns the resolved (profile applied) node configuration of this database.
+func (db *Db) GetOptions() config.MdbxNode {
+	return db.opts
 }
 
 // Destroy removes the MDBX database files and cleans up the environment. This method
@@ -161,17 +410,37 @@ func (db *Db) GetDBI() mdbx.DBI {
 //
 // Returns:
 //
-//	error: Returns an error if the key-value pair cannot be stored.
+//	error: Returns errors.ErrReadOnly on a replica, or an error if the key-value pair cannot be stored.
 func (db *Db) Set(key, value []byte) error {
-	return db.env.Update(func(txn *mdbx.Txn) error {
+	if db.IsReadOnly() {
+		return fdbErrors.ErrReadOnly
+	}
+	if !db.Acquire() {
+		return fdbErrors.ErrDatabaseClosed
+	}
+	defer db.Release()
+
+	var changes *changeAppender
+	err := db.env.Update(func(txn *mdbx.Txn) error {
 		cursor, err := txn.OpenCursor(db.GetDBI())
 		if err != nil {
 			return errors.Wrap(err, "failed to open cursor")
 		}
 		defer cursor.Close()
 
-		return cursor.Put(key, value, 0)
+		if err := cursor.Put(key, value, 0); err != nil {
+			return err
+		}
+
+		changes = db.newChangeAppender(txn)
+		defer changes.close()
+		return changes.append(types.ChangeSet, key, value)
 	})
+	if err == nil {
+		changes.committed()
+		db.touchMerkle(key)
+	}
+	return err
 }
 
 // Get retrieves the value associated with the given key from the MDBX database.
@@ -190,14 +459,22 @@ func (db *Db) Set(key, value []byte) error {
 // Returns:
 //
 //	[]byte: The value associated with the key.
-//	error: Returns an error if the key is not found or the retrieval fails.
+//	error: Returns errors.ErrNotFound if the key does not exist, or an error if the retrieval fails.
 func (db *Db) Get(key []byte) ([]byte, error) {
+	if !db.Acquire() {
+		return nil, fdbErrors.ErrDatabaseClosed
+	}
+	defer db.Release()
+
 	var value []byte
 	err := db.env.View(func(txn *mdbx.Txn) error {
 		var err error
 		value, err = txn.Get(db.dbi, key)
 		return err
 	})
+	if errors.Is(err, mdbx.ErrNotFound) {
+		return nil, fdbErrors.ErrNotFound
+	}
 	return value, err
 }
 
@@ -219,6 +496,11 @@ func (db *Db) Get(key []byte) ([]byte, error) {
 //	bool: True if the key exists, false otherwise.
 //	error: Returns an error if the existence check fails.
 func (db *Db) Exists(key []byte) (bool, error) {
+	if !db.Acquire() {
+		return false, fdbErrors.ErrDatabaseClosed
+	}
+	defer db.Release()
+
 	err := db.env.View(func(txn *mdbx.Txn) error {
 		_, err := txn.Get(db.dbi, key)
 		return err
@@ -248,14 +530,37 @@ func (db *Db) Exists(key []byte) (bool, error) {
 //
 // Returns:
 //
-//	error: Returns an error if the key cannot be deleted.
+//	error: Returns errors.ErrReadOnly on a replica, or an error if the key cannot be deleted.
 func (db *Db) Delete(key []byte) error {
-	return db.env.Update(func(txn *mdbx.Txn) error {
-		return txn.Del(db.dbi, key, nil)
+	if db.IsReadOnly() {
+		return fdbErrors.ErrReadOnly
+	}
+	if !db.Acquire() {
+		return fdbErrors.ErrDatabaseClosed
+	}
+	defer db.Release()
+
+	var changes *changeAppender
+	err := db.env.Update(func(txn *mdbx.Txn) error {
+		if err := txn.Del(db.dbi, key, nil); err != nil {
+			return err
+		}
+
+		changes = db.newChangeAppender(txn)
+		defer changes.close()
+		return changes.append(types.ChangeDelete, key, nil)
 	})
+	if err == nil {
+		changes.committed()
+		db.touchMerkle(key)
+	}
+	return err
 }
 
-// Close closes the MDBX environment and releases any resources held by the database.
+// Close stops the periodic sync and change log retention (if running) and waits for them to
+// return, waits for in-flight operations and acquired references to be released, then closes the MDBX environment. New operations fail with
+// errors.ErrDatabaseClosed as soon as Close is called. Calling Close more than once is a no-op.
+// Close must not be called while the caller itself holds a reference from Acquire.
 //
 // Example usage:
 //
@@ -268,6 +573,19 @@ func (db *Db) Delete(key []byte) error {
 //
 //	error: Returns an error if the environment cannot be closed.
 func (db *Db) Close() error {
-	db.env.Close()
+	db.closeOnce.Do(func() {
+		db.closing.Store(true)
+		close(db.stopSync)
+		db.background.Wait()
+
+		for db.refs.Load() > 0 {
+			select {
+			case <-db.drained:
+			case <-time.After(10 * time.Millisecond):
+			}
+		}
+
+		db.env.Close()
+	})
 	return nil
 }
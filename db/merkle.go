@@ -0,0 +1,351 @@
+package db
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"sync"
+
+	"github.com/erigontech/mdbx-go/mdbx"
+	"github.com/pkg/errors"
+	fdbErrors "github.com/unpackdev/fdb/errors"
+	"github.com/unpackdev/fdb/types"
+)
+
+const (
+	// MerkleDepth is the depth of the Merkle tree summarising a database. Level 0 holds the root,
+	// level MerkleDepth the leaves. Every node of the cluster must use the same depth.
+	MerkleDepth = 12
+
+	// MerkleLeaves is the number of leaves, each covering the keys sharing a MerkleDepth-bit prefix.
+	MerkleLeaves = 1 << MerkleDepth
+)
+
+// MerkleHash is the hash of a Merkle tree node. Nodes covering no key hash to the zero value.
+type MerkleHash [sha256.Size]byte
+
+// merkleTree summarises the main DBI of a database as a binary hash tree over key ranges. The
+// key space is split into MerkleLeaves ranges by the leading bits of the keys; a leaf hashes the
+// ordered key-value pairs of its range, an inner node the hashes of its two children.
+//
+// The write path only marks the leaves of the keys it committed as dirty. Dirty leaves are
+// rehashed from the database when the tree is next read, so maintaining the tree costs the
+// writers nothing but a bit per key, and reading it costs a scan of the changed ranges only.
+type merkleTree struct {
+	// dirtyMu guards dirty.
+	dirtyMu sync.Mutex
+
+	// dirty marks the leaves whose keys changed since they were last hashed.
+	dirty []bool
+
+	// mu serialises refreshes and guards nodes.
+	mu sync.Mutex
+
+	// nodes holds the tree in heap order: the root at 1, the children of n at 2n and 2n+1.
+	nodes []MerkleHash
+}
+
+// newMerkleTree returns a tree whose leaves all need hashing.
+func newMerkleTree() *merkleTree {
+	t := &merkleTree{
+		dirty: make([]bool, MerkleLeaves),
+		nodes: make([]MerkleHash, 2*MerkleLeaves),
+	}
+	t.touchAll()
+	return t
+}
+
+// merkleLeaf returns the leaf covering key. Keys shorter than the prefix are padded with zeros.
+func merkleLeaf(key []byte) uint32 {
+	var prefix [2]byte
+	copy(prefix[:], key)
+	return uint32(binary.BigEndian.Uint16(prefix[:])) >> (16 - MerkleDepth)
+}
+
+// touch marks the leaves of the given keys dirty. It must be called after the transaction
+// writing the keys committed, so a refresh never misses a committed write.
+func (t *merkleTree) touch(keys ...[]byte) {
+	t.dirtyMu.Lock()
+	defer t.dirtyMu.Unlock()
+
+	for _, key := range keys {
+		t.dirty[merkleLeaf(key)] = true
+	}
+}
+
+// touchAll marks every leaf dirty.
+func (t *merkleTree) touchAll() {
+	t.dirtyMu.Lock()
+	defer t.dirtyMu.Unlock()
+
+	for i := range t.dirty {
+		t.dirty[i] = true
+	}
+}
+
+// markDirty marks the given leaves dirty.
+func (t *merkleTree) markDirty(leaves []uint32) {
+	t.dirtyMu.Lock()
+	defer t.dirtyMu.Unlock()
+
+	for _, leaf := range leaves {
+		t.dirty[leaf] = true
+	}
+}
+
+// takeDirty returns the dirty leaves and marks them clean.
+func (t *merkleTree) takeDirty() []uint32 {
+	t.dirtyMu.Lock()
+	defer t.dirtyMu.Unlock()
+
+	var leaves []uint32
+	for i, dirty := range t.dirty {
+		if dirty {
+			leaves = append(leaves, uint32(i))
+			t.dirty[i] = false
+		}
+	}
+	return leaves
+}
+
+// hashPair returns the hash of an inner node, zero if both children cover no key.
+func hashPair(left, right MerkleHash) MerkleHash {
+	if left == (MerkleHash{}) && right == (MerkleHash{}) {
+		return MerkleHash{}
+	}
+	var buf [2 * sha256.Size]byte
+	copy(buf[:sha256.Size], left[:])
+	copy(buf[sha256.Size:], right[:])
+	return sha256.Sum256(buf[:])
+}
+
+// touchMerkle marks the keys written by a committed transaction in the Merkle tree.
+func (db *Db) touchMerkle(keys ...[]byte) {
+	db.tree.touch(keys...)
+}
+
+// refreshMerkle rehashes the dirty leaves from the database and the inner nodes above them.
+// The caller must hold tree.mu.
+func (db *Db) refreshMerkle(txn *mdbx.Txn) error {
+	leaves := db.tree.takeDirty()
+	if len(leaves) == 0 {
+		return nil
+	}
+
+	err := db.scanLeaves(txn, leaves, func(leaf uint32, pairs func(each func(key, value []byte) error) error) error {
+		hash := sha256.New()
+		empty := true
+		var lengths [8]byte
+		err := pairs(func(key, value []byte) error {
+			empty = false
+			binary.BigEndian.PutUint32(lengths[0:4], uint32(len(key)))
+			binary.BigEndian.PutUint32(lengths[4:8], uint32(len(value)))
+			hash.Write(lengths[:])
+			hash.Write(key)
+			hash.Write(value)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		node := &db.tree.nodes[MerkleLeaves+int(leaf)]
+		if empty {
+			*node = MerkleHash{}
+		} else {
+			copy(node[:], hash.Sum(nil))
+		}
+		return nil
+	})
+	if err != nil {
+		// The leaves must be hashed again by the next refresh
+		db.tree.markDirty(leaves)
+		return err
+	}
+
+	// Rehash the ancestors of the refreshed leaves, level by level up to the root
+	changed := make(map[int]struct{}, len(leaves))
+	for _, leaf := range leaves {
+		changed[(MerkleLeaves+int(leaf))/2] = struct{}{}
+	}
+	for len(changed) > 0 {
+		parents := make(map[int]struct{}, len(changed)/2+1)
+		for n := range changed {
+			db.tree.nodes[n] = hashPair(db.tree.nodes[2*n], db.tree.nodes[2*n+1])
+			if n > 1 {
+				parents[n/2] = struct{}{}
+			}
+		}
+		changed = parents
+	}
+	return nil
+}
+
+// scanLeaves calls fn for every given leaf, in ascending order, with a function iterating the
+// key-value pairs of the leaf's range within txn. Internal keys are skipped.
+func (db *Db) scanLeaves(txn *mdbx.Txn, leaves []uint32, fn func(leaf uint32, pairs func(each func(key, value []byte) error) error) error) error {
+	cursor, err := txn.OpenCursor(db.dbi)
+	if err != nil {
+		return errors.Wrap(err, "failed to open cursor")
+	}
+	defer cursor.Close()
+
+	for _, leaf := range leaves {
+		pairs := func(each func(key, value []byte) error) error {
+			var start [2]byte
+			binary.BigEndian.PutUint16(start[:], uint16(leaf<<(16-MerkleDepth)))
+
+			key, value, err := cursor.Get(start[:], nil, mdbx.SetRange)
+			for ; err == nil && merkleLeaf(key) == leaf; key, value, err = cursor.Get(nil, nil, mdbx.Next) {
+				if isInternalKey(key) {
+					continue
+				}
+				if err := each(key, value); err != nil {
+					return err
+				}
+			}
+			if err != nil && !mdbx.IsNotFound(err) {
+				return err
+			}
+			return nil
+		}
+		if err := fn(leaf, pairs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MerkleHashes returns the hashes of the given nodes of one level of the database's Merkle
+// tree, hashing the leaves written since the last call first. Two databases holding the same
+// data have the same tree, so comparing the trees top-down from the root finds the key ranges
+// in which they differ.
+//
+// Example usage:
+//
+//	root, err := db.MerkleHashes(0, []uint32{0})
+//	if err != nil {
+//	    log.Fatalf("Failed to read Merkle tree: %v", err)
+//	}
+//
+// Parameters:
+//
+//	level (int): The tree level, 0 for the root up to MerkleDepth for the leaves.
+//	indices ([]uint32): The positions of the nodes within the level.
+//
+// Returns:
+//
+//	[]MerkleHash: The node hashes, in the order of indices.
+//	error: Returns an error if a node does not exist or the tree cannot be refreshed.
+func (db *Db) MerkleHashes(level int, indices []uint32) ([]MerkleHash, error) {
+	if level < 0 || level > MerkleDepth {
+		return nil, fmt.Errorf("invalid merkle level: %d", level)
+	}
+	for _, index := range indices {
+		if index >= 1<<level {
+			return nil, fmt.Errorf("invalid merkle index %d at level %d", index, level)
+		}
+	}
+	if !db.Acquire() {
+		return nil, fdbErrors.ErrDatabaseClosed
+	}
+	defer db.Release()
+
+	db.tree.mu.Lock()
+	defer db.tree.mu.Unlock()
+
+	if err := db.env.View(db.refreshMerkle); err != nil {
+		return nil, errors.Wrap(err, "failed to refresh merkle tree")
+	}
+
+	hashes := make([]MerkleHash, len(indices))
+	for i, index := range indices {
+		hashes[i] = db.tree.nodes[(1<<level)+int(index)]
+	}
+	return hashes, nil
+}
+
+// MerkleRange streams the key-value pairs covered by the given Merkle tree leaves, in key
+// order, within a single read transaction. Keys and values must be copied if retained.
+//
+// Parameters:
+//
+//	leaves ([]uint32): The leaves to stream, in ascending order.
+//	each (func(key, value []byte) error): Receives every key-value pair.
+//
+// Returns:
+//
+//	error: Returns the first callback or read error.
+func (db *Db) MerkleRange(leaves []uint32, each func(key, value []byte) error) error {
+	for i, leaf := range leaves {
+		if leaf >= MerkleLeaves || (i > 0 && leaf <= leaves[i-1]) {
+			return fmt.Errorf("merkle leaves must be ascending and below %d", MerkleLeaves)
+		}
+	}
+	if !db.Acquire() {
+		return fdbErrors.ErrDatabaseClosed
+	}
+	defer db.Release()
+
+	return db.env.View(func(txn *mdbx.Txn) error {
+		return db.scanLeaves(txn, leaves, func(_ uint32, pairs func(each func(key, value []byte) error) error) error {
+			return pairs(each)
+		})
+	})
+}
+
+// Repair writes and deletes keys to converge with another replica, recording the changes in
+// the change log so subscribers and followers see them. Like ApplyChanges it bypasses the
+// read-only check, a replica being the usual target of a repair.
+//
+// Parameters:
+//
+//	set ([]KeyValue): The pairs to write.
+//	deleted ([][]byte): The keys to remove.
+//
+// Returns:
+//
+//	error: Returns an error if the changes cannot be written.
+func (db *Db) Repair(set []KeyValue, deleted [][]byte) error {
+	if len(set) == 0 && len(deleted) == 0 {
+		return nil
+	}
+	if !db.Acquire() {
+		return fdbErrors.ErrDatabaseClosed
+	}
+	defer db.Release()
+
+	var changes *changeAppender
+	err := db.env.Update(func(txn *mdbx.Txn) error {
+		changes = db.newChangeAppender(txn)
+		defer changes.close()
+
+		for _, pair := range set {
+			if err := txn.Put(db.dbi, pair.Key, pair.Value, 0); err != nil {
+				return errors.Wrapf(err, "failed to repair key: %x", pair.Key)
+			}
+			if err := changes.append(types.ChangeSet, pair.Key, pair.Value); err != nil {
+				return err
+			}
+		}
+		for _, key := range deleted {
+			if err := txn.Del(db.dbi, key, nil); err != nil && !mdbx.IsNotFound(err) {
+				return errors.Wrapf(err, "failed to repair key: %x", key)
+			}
+			if err := changes.append(types.ChangeDelete, key, nil); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	changes.committed()
+	for _, pair := range set {
+		db.touchMerkle(pair.Key)
+	}
+	db.touchMerkle(deleted...)
+	return nil
+}
@@ -0,0 +1,85 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/unpackdev/fdb/config"
+)
+
+func setupMerkleTestDb(t *testing.T, name string) *Db {
+	provider, err := NewDb(context.Background(), config.MdbxNode{Path: t.TempDir(), Name: name, MaxSize: 1})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = provider.Close() })
+	return provider.(*Db)
+}
+
+func merkleRoot(t *testing.T, db *Db) MerkleHash {
+	hashes, err := db.MerkleHashes(0, []uint32{0})
+	require.NoError(t, err)
+	return hashes[0]
+}
+
+func TestMerkleTreeTracksWrites(t *testing.T) {
+	a := setupMerkleTestDb(t, "a")
+	b := setupMerkleTestDb(t, "b")
+
+	assert.Equal(t, MerkleHash{}, merkleRoot(t, a), "empty database hashes to zero")
+
+	// Same data written in a different order hashes the same
+	require.NoError(t, a.Set([]byte("alpha"), []byte("1")))
+	require.NoError(t, a.Set([]byte("omega"), []byte("2")))
+	require.NoError(t, b.Set([]byte("omega"), []byte("2")))
+	require.NoError(t, b.Set([]byte("alpha"), []byte("1")))
+	assert.Equal(t, merkleRoot(t, a), merkleRoot(t, b))
+
+	// A diverging value changes the root and the leaf of its key only
+	require.NoError(t, b.Set([]byte("omega"), []byte("3")))
+	assert.NotEqual(t, merkleRoot(t, a), merkleRoot(t, b))
+
+	leaves := []uint32{merkleLeaf([]byte("alpha")), merkleLeaf([]byte("omega"))}
+	hashesA, err := a.MerkleHashes(MerkleDepth, leaves)
+	require.NoError(t, err)
+	hashesB, err := b.MerkleHashes(MerkleDepth, leaves)
+	require.NoError(t, err)
+	assert.Equal(t, hashesA[0], hashesB[0])
+	assert.NotEqual(t, hashesA[1], hashesB[1])
+
+	// Deleting every key returns the tree to zero
+	require.NoError(t, b.Delete([]byte("alpha")))
+	require.NoError(t, b.Delete([]byte("omega")))
+	assert.Equal(t, MerkleHash{}, merkleRoot(t, b))
+
+	_, err = a.MerkleHashes(MerkleDepth+1, []uint32{0})
+	assert.Error(t, err)
+	_, err = a.MerkleHashes(1, []uint32{2})
+	assert.Error(t, err)
+}
+
+func TestMerkleRangeAndRepair(t *testing.T) {
+	a := setupMerkleTestDb(t, "a")
+	b := setupMerkleTestDb(t, "b")
+
+	require.NoError(t, a.Set([]byte("alpha"), []byte("1")))
+	require.NoError(t, a.Set([]byte("beta"), []byte("2")))
+	require.NoError(t, b.Set([]byte("alpha"), []byte("old")))
+	require.NoError(t, b.Set([]byte("gamma"), []byte("3")))
+
+	// Repairs are applied to read-only replicas
+	b.SetReadOnly(true)
+	require.NoError(t, b.Repair(
+		[]KeyValue{{Key: []byte("alpha"), Value: []byte("1")}, {Key: []byte("beta"), Value: []byte("2")}},
+		[][]byte{[]byte("gamma")},
+	))
+	assert.Equal(t, merkleRoot(t, a), merkleRoot(t, b))
+
+	leaf := merkleLeaf([]byte("alpha"))
+	var keys []string
+	require.NoError(t, b.MerkleRange([]uint32{leaf}, func(key, value []byte) error {
+		keys = append(keys, string(key))
+		return nil
+	}))
+	assert.Equal(t, []string{"alpha"}, keys)
+}
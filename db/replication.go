@@ -0,0 +1,420 @@
+package db
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/erigontech/mdbx-go/mdbx"
+	"github.com/pkg/errors"
+	fdbErrors "github.com/unpackdev/fdb/errors"
+	"github.com/unpackdev/fdb/messages"
+	"github.com/unpackdev/fdb/types"
+)
+
+const (
+	// replicationDbiName is the named DBI holding the replication state (follower position, consensus index).
+	replicationDbiName = "fdb_replication"
+
+	// internalDbis is the number of named DBIs reserved for internal use (change log, replication, versions).
+	internalDbis = 4
+
+	// snapshotRestoreBatch is the maximum number of keys removed per transaction when a replica is reset.
+	snapshotRestoreBatch = 10000
+)
+
+// replicationPositionKey is the key, within the replication DBI, of the last applied sequence number.
+var replicationPositionKey = []byte("position")
+
+// KeyValue is a single key-value pair, as streamed in a snapshot.
+type KeyValue struct {
+	Key   []byte
+	Value []byte
+}
+
+// isInternalKey reports whether a main DBI key is the record of an internal named DBI rather than data.
+func isInternalKey(key []byte) bool {
+	name := string(key)
+	return name == cdcDbiName || name == replicationDbiName || name == versionsDbiName
+}
+
+// SetReadOnly switches the database between serving writes and rejecting them with
+// errors.ErrReadOnly. Replicas are read-only; changes from the leader are applied with
+// ApplyChanges and RestoreSnapshot, which bypass the check.
+func (db *Db) SetReadOnly(readOnly bool) {
+	db.readOnly.Store(readOnly)
+}
+
+// IsReadOnly reports whether the database rejects client writes.
+func (db *Db) IsReadOnly() bool {
+	return db.readOnly.Load()
+}
+
+// ensureReplicationDbi opens (creating if needed) the replication DBI.
+func (db *Db) ensureReplicationDbi() error {
+	db.replMu.Lock()
+	defer db.replMu.Unlock()
+
+	if db.replOpen {
+		return nil
+	}
+
+	var dbi mdbx.DBI
+	err := db.env.Update(func(txn *mdbx.Txn) error {
+		var err error
+		dbi, err = txn.OpenDBISimple(replicationDbiName, mdbx.Create)
+		return err
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to open replication state")
+	}
+
+	db.replDbi = dbi
+	db.replOpen = true
+	return nil
+}
+
+// replicationPosition returns the last applied sequence number within txn, zero if the
+// database never replicated.
+func (db *Db) replicationPosition(txn *mdbx.Txn) (uint64, error) {
+	db.replMu.Lock()
+	open, dbi := db.replOpen, db.replDbi
+	db.replMu.Unlock()
+
+	if !open {
+		return 0, nil
+	}
+
+	value, err := txn.Get(dbi, replicationPositionKey)
+	if mdbx.IsNotFound(err) {
+		return 0, nil
+	} else if err != nil {
+		return 0, errors.Wrap(err, "failed to read replication position")
+	}
+	return binary.BigEndian.Uint64(value), nil
+}
+
+// setReplicationPosition records the last applied sequence number within txn.
+func (db *Db) setReplicationPosition(txn *mdbx.Txn, seq uint64) error {
+	return txn.Put(db.replDbi, replicationPositionKey, sequenceKey(seq), 0)
+}
+
+// ReplicationPosition returns the sequence number of the last change applied from the leader.
+// Zero means the database has not been bootstrapped from a snapshot yet.
+//
+// Returns:
+//
+//	uint64: The last applied sequence number.
+//	error: Returns an error if the position cannot be read.
+func (db *Db) ReplicationPosition() (uint64, error) {
+	if !db.Acquire() {
+		return 0, fdbErrors.ErrDatabaseClosed
+	}
+	defer db.Release()
+
+	var position uint64
+	err := db.env.View(func(txn *mdbx.Txn) error {
+		var err error
+		position, err = db.replicationPosition(txn)
+		return err
+	})
+	return position, err
+}
+
+// HeadSequence returns the sequence number of the newest change known to the database: the
+// last change log entry, or the replication position when that is further ahead.
+//
+// Returns:
+//
+//	uint64: The newest sequence number, zero if there is none.
+//	error: Returns an error if the sequence cannot be read.
+func (db *Db) HeadSequence() (uint64, error) {
+	if !db.Acquire() {
+		return 0, fdbErrors.ErrDatabaseClosed
+	}
+	defer db.Release()
+
+	var head uint64
+	err := db.env.View(func(txn *mdbx.Txn) error {
+		var err error
+		if db.opts.Cdc.Enabled {
+			if _, head, err = db.changeLogBounds(txn); err != nil {
+				return err
+			}
+		}
+
+		position, err := db.replicationPosition(txn)
+		if position > head {
+			head = position
+		}
+		return err
+	})
+	return head, err
+}
+
+// Snapshot streams a consistent copy of the database. start is called first with the change
+// log head at the time of the snapshot, then each is called for every key-value pair. Both run
+// inside a single read transaction, so keys and values must be copied if retained. Applying the
+// snapshot and then every change after the head reproduces the database. Without a change log
+// the head is the replication position, which is zero unless the database is a replica.
+//
+// Example usage:
+//
+//	err := db.Snapshot(func(seq uint64) error {
+//	    return sendHeader(seq)
+//	}, func(key, value []byte) error {
+//	    return sendPair(key, value)
+//	})
+//
+// Parameters:
+//
+//	start (func(uint64) error): Receives the change log head the snapshot is consistent with.
+//	each (func(key, value []byte) error): Receives every key-value pair.
+//
+// Returns:
+//
+//	error: Returns the first callback or read error.
+func (db *Db) Snapshot(start func(seq uint64) error, each func(key, value []byte) error) error {
+	if !db.Acquire() {
+		return fdbErrors.ErrDatabaseClosed
+	}
+	defer db.Release()
+
+	return db.env.View(func(txn *mdbx.Txn) error {
+		var head uint64
+		if db.opts.Cdc.Enabled {
+			var err error
+			if _, head, err = db.changeLogBounds(txn); err != nil {
+				return err
+			}
+		}
+		if position, err := db.replicationPosition(txn); err != nil {
+			return err
+		} else if position > head {
+			head = position
+		}
+
+		if err := start(head); err != nil {
+			return err
+		}
+		return db.scan(txn, each)
+	})
+}
+
+// scan calls each for every key-value pair of the main DBI within txn, skipping internal keys.
+func (db *Db) scan(txn *mdbx.Txn, each func(key, value []byte) error) error {
+	cursor, err := txn.OpenCursor(db.dbi)
+	if err != nil {
+		return errors.Wrap(err, "failed to open cursor")
+	}
+	defer cursor.Close()
+
+	key, value, err := cursor.Get(nil, nil, mdbx.First)
+	for ; err == nil; key, value, err = cursor.Get(nil, nil, mdbx.Next) {
+		if isInternalKey(key) {
+			continue
+		}
+		if err := each(key, value); err != nil {
+			return err
+		}
+	}
+	if !mdbx.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+// ResetReplica prepares the database for a snapshot restore: the replication position is
+// cleared, then every key and change log entry is removed. A restore interrupted at any point
+// leaves the position at zero, so the follower starts over from a fresh snapshot.
+//
+// Returns:
+//
+//	error: Returns an error if the database cannot be cleared.
+func (db *Db) ResetReplica() error {
+	if err := db.ensureReplicationDbi(); err != nil {
+		return err
+	}
+	if !db.Acquire() {
+		return fdbErrors.ErrDatabaseClosed
+	}
+	defer db.Release()
+
+	err := db.env.Update(func(txn *mdbx.Txn) error {
+		if err := txn.Del(db.replDbi, replicationPositionKey, nil); err != nil && !mdbx.IsNotFound(err) {
+			return err
+		}
+		if db.opts.Cdc.Enabled {
+			return txn.Drop(db.cdcDbi, false)
+		}
+		return nil
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to reset replication state")
+	}
+
+	// Every range is about to change, the tree is rehashed from scratch
+	defer db.tree.touchAll()
+
+	for {
+		removed := 0
+		err := db.env.Update(func(txn *mdbx.Txn) error {
+			cursor, err := txn.OpenCursor(db.dbi)
+			if err != nil {
+				return errors.Wrap(err, "failed to open cursor")
+			}
+			defer cursor.Close()
+
+			var keys [][]byte
+			key, _, err := cursor.Get(nil, nil, mdbx.First)
+			for ; err == nil && len(keys) < snapshotRestoreBatch; key, _, err = cursor.Get(nil, nil, mdbx.Next) {
+				if !isInternalKey(key) {
+					keys = append(keys, append([]byte(nil), key...))
+				}
+			}
+			if err != nil && !mdbx.IsNotFound(err) {
+				return err
+			}
+
+			for _, key := range keys {
+				if err := txn.Del(db.dbi, key, nil); err != nil {
+					return err
+				}
+			}
+			removed = len(keys)
+			return nil
+		})
+		if err != nil {
+			return errors.Wrap(err, "failed to clear replica")
+		}
+		if removed < snapshotRestoreBatch {
+			return nil
+		}
+	}
+}
+
+// RestoreSnapshot writes a batch of snapshot pairs. Call ResetReplica before the first batch
+// and CompleteSnapshot after the last one.
+//
+// Parameters:
+//
+//	pairs ([]KeyValue): The key-value pairs to write.
+//
+// Returns:
+//
+//	error: Returns an error if the pairs cannot be written.
+func (db *Db) RestoreSnapshot(pairs []KeyValue) error {
+	if !db.Acquire() {
+		return fdbErrors.ErrDatabaseClosed
+	}
+	defer db.Release()
+
+	err := db.env.Update(func(txn *mdbx.Txn) error {
+		for _, pair := range pairs {
+			if err := txn.Put(db.dbi, pair.Key, pair.Value, 0); err != nil {
+				return errors.Wrapf(err, "failed to restore key: %x", pair.Key)
+			}
+		}
+		return nil
+	})
+	if err == nil {
+		for _, pair := range pairs {
+			db.touchMerkle(pair.Key)
+		}
+	}
+	return err
+}
+
+// CompleteSnapshot records the change log head the restored snapshot is consistent with.
+// Replication continues with the change that follows it.
+//
+// Parameters:
+//
+//	seq (uint64): The snapshot head sequence number.
+//
+// Returns:
+//
+//	error: Returns an error if the position cannot be recorded.
+func (db *Db) CompleteSnapshot(seq uint64) error {
+	if err := db.ensureReplicationDbi(); err != nil {
+		return err
+	}
+	if !db.Acquire() {
+		return fdbErrors.ErrDatabaseClosed
+	}
+	defer db.Release()
+
+	return db.env.Update(func(txn *mdbx.Txn) error {
+		return db.setReplicationPosition(txn, seq)
+	})
+}
+
+// ApplyChanges applies changes received from the leader in a single transaction, together
+// with their change log entries (keeping the leader's sequence numbers) and the new replication
+// position. The first change must directly follow the current position.
+//
+// Parameters:
+//
+//	changes ([]*messages.Change): Consecutive changes, ordered by sequence number.
+//
+// Returns:
+//
+//	error: Returns errors.ErrReplicationGap if the changes do not follow the position, or an
+//	error if they cannot be applied.
+func (db *Db) ApplyChanges(changes []*messages.Change) error {
+	if len(changes) == 0 {
+		return nil
+	}
+	if err := db.ensureReplicationDbi(); err != nil {
+		return err
+	}
+	if !db.Acquire() {
+		return fdbErrors.ErrDatabaseClosed
+	}
+	defer db.Release()
+
+	var log *changeAppender
+	err := db.env.Update(func(txn *mdbx.Txn) error {
+		position, err := db.replicationPosition(txn)
+		if err != nil {
+			return err
+		}
+
+		log = db.newChangeAppender(txn)
+		defer log.close()
+
+		for _, change := range changes {
+			if change.Seq != position+1 {
+				return errors.Wrapf(fdbErrors.ErrReplicationGap, "expected %d, got %d", position+1, change.Seq)
+			}
+
+			switch change.Op {
+			case types.ChangeSet:
+				err = txn.Put(db.dbi, change.Key, change.Value, 0)
+			case types.ChangeDelete:
+				err = txn.Del(db.dbi, change.Key, nil)
+				if mdbx.IsNotFound(err) {
+					err = nil
+				}
+			default:
+				err = fmt.Errorf("unknown change operation: %v", change.Op)
+			}
+			if err != nil {
+				return errors.Wrapf(err, "failed to apply change %d", change.Seq)
+			}
+
+			if err := log.appendReplicated(change); err != nil {
+				return err
+			}
+			position = change.Seq
+		}
+
+		return db.setReplicationPosition(txn, position)
+	})
+	if err == nil {
+		log.committed()
+		for _, change := range changes {
+			db.touchMerkle(change.Key)
+		}
+	}
+	return err
+}
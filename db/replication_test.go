@@ -0,0 +1,104 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/unpackdev/fdb/config"
+	"github.com/unpackdev/fdb/errors"
+	"github.com/unpackdev/fdb/messages"
+	"github.com/unpackdev/fdb/types"
+)
+
+func TestSnapshotRestoreAndApplyChanges(t *testing.T) {
+	leader := setupCdcTestDb(t, config.Cdc{})
+	follower := setupCdcTestDb(t, config.Cdc{})
+
+	require.NoError(t, leader.Set([]byte("a"), []byte("1")))
+	require.NoError(t, leader.Set([]byte("b"), []byte("2")))
+	require.NoError(t, follower.Set([]byte("stale"), []byte("x")))
+
+	// Copy the leader into the follower
+	var head uint64
+	var pairs []KeyValue
+	require.NoError(t, leader.Snapshot(func(seq uint64) error {
+		head = seq
+		return nil
+	}, func(key, value []byte) error {
+		pairs = append(pairs, KeyValue{Key: append([]byte(nil), key...), Value: append([]byte(nil), value...)})
+		return nil
+	}))
+	assert.Equal(t, uint64(2), head)
+	require.Len(t, pairs, 2)
+
+	require.NoError(t, follower.ResetReplica())
+	require.NoError(t, follower.RestoreSnapshot(pairs))
+	require.NoError(t, follower.CompleteSnapshot(head))
+
+	_, err := follower.Get([]byte("stale"))
+	assert.ErrorIs(t, err, errors.ErrNotFound)
+	value, err := follower.Get([]byte("b"))
+	require.NoError(t, err)
+	assert.Equal(t, []byte("2"), value)
+
+	// Stream the changes that follow the snapshot
+	require.NoError(t, leader.Delete([]byte("a")))
+	require.NoError(t, leader.Set([]byte("c"), []byte("3")))
+	changes, err := leader.ReadChanges(head+1, 10)
+	require.NoError(t, err)
+	require.NoError(t, follower.ApplyChanges(changes))
+
+	position, err := follower.ReplicationPosition()
+	require.NoError(t, err)
+	assert.Equal(t, uint64(4), position)
+
+	_, err = follower.Get([]byte("a"))
+	assert.ErrorIs(t, err, errors.ErrNotFound)
+	value, err = follower.Get([]byte("c"))
+	require.NoError(t, err)
+	assert.Equal(t, []byte("3"), value)
+
+	// The follower's change log keeps the leader's sequence numbers
+	replicated, err := follower.ReadChanges(0, 10)
+	require.NoError(t, err)
+	require.Len(t, replicated, 2)
+	assert.Equal(t, uint64(3), replicated[0].Seq)
+	assert.Equal(t, types.ChangeDelete, replicated[0].Op)
+
+	// Changes that skip a sequence are rejected
+	err = follower.ApplyChanges([]*messages.Change{{Seq: 6, Op: types.ChangeSet, Key: []byte("d"), Value: []byte("4")}})
+	assert.ErrorIs(t, err, errors.ErrReplicationGap)
+
+	// Once promoted, local writes continue the leader's sequence
+	require.NoError(t, follower.Set([]byte("d"), []byte("4")))
+	head, err = follower.HeadSequence()
+	require.NoError(t, err)
+	assert.Equal(t, uint64(5), head)
+}
+
+func TestReadOnlyRejectsWrites(t *testing.T) {
+	manager := setupTestManager(t)
+	require.NoError(t, manager.SetReadOnly("test", true))
+
+	provider, err := manager.GetDb("test")
+	require.NoError(t, err)
+	assert.ErrorIs(t, provider.Set([]byte("key"), []byte("value")), errors.ErrReadOnly)
+	assert.ErrorIs(t, provider.Delete([]byte("key")), errors.ErrReadOnly)
+
+	router, err := NewRouter(manager, nil)
+	require.NoError(t, err)
+	_, err = router.Writer("")
+	assert.ErrorIs(t, err, errors.ErrReadOnly)
+	assert.Equal(t, types.StatusReadOnly, WriteStatus(err))
+
+	// The setting survives a reopen
+	require.NoError(t, manager.CloseDb("test"))
+	require.NoError(t, manager.OpenDb("test"))
+	provider, err = manager.GetDb("test")
+	require.NoError(t, err)
+	assert.ErrorIs(t, provider.Set([]byte("key"), []byte("value")), errors.ErrReadOnly)
+
+	require.NoError(t, manager.SetReadOnly("test", false))
+	assert.NoError(t, provider.Set([]byte("key"), []byte("value")))
+}
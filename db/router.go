@@ -0,0 +1,285 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	fdbErrors "github.com/unpackdev/fdb/errors"
+	"github.com/unpackdev/fdb/messages"
+	"github.com/unpackdev/fdb/types"
+)
+
+// Batch writer settings used for every database a Router writes to.
+const (
+	routerBatchSize     = 512
+	routerFlushInterval = 500 * time.Millisecond
+	routerWriterWorkers = 15
+)
+
+// Proposer replicates writes through a consensus log. Databases with a proposer are only
+// modified once a write is committed by the group, see Manager.SetProposer.
+type Proposer interface {
+	// Propose submits the mutation and returns once it is committed and applied locally.
+	Propose(ctx context.Context, database types.DbType, op types.ChangeOp, key, value []byte) error
+}
+
+// Reader serves the reads of a database from other nodes, such as the replicas of a database
+// replicated with quorums, see Manager.SetReader.
+type Reader interface {
+	// Read returns the value of the key, or errors.ErrNotFound.
+	Read(ctx context.Context, database types.DbType, key []byte) ([]byte, error)
+}
+
+// consistencyKey is the context key of the consistency requested by a client.
+type consistencyKey struct{}
+
+// WithConsistency returns a context carrying the consistency requested by a client, passed by
+// transports to proposers and readers.
+func WithConsistency(ctx context.Context, c messages.Consistency) context.Context {
+	if c.IsZero() {
+		return ctx
+	}
+	return context.WithValue(ctx, consistencyKey{}, c)
+}
+
+// ConsistencyFromContext returns the consistency carried by ctx, zero when the client requested none.
+func ConsistencyFromContext(ctx context.Context) messages.Consistency {
+	c, _ := ctx.Value(consistencyKey{}).(messages.Consistency)
+	return c
+}
+
+// Router resolves the database a transport request is addressed to. Every transport owns
+// a Router bound to the databases it serves; requests carrying a database selector are
+// routed to the selected database, requests without one to the transport's default database.
+//
+// Batch writers are created lazily, one per database, the first time a write is routed to
+// it, and are recreated when a database is closed and reopened through the Manager.
+type Router struct {
+	// manager resolves database names to open providers.
+	manager *Manager
+
+	// allowed holds the databases served by the transport; nil means every database known to the manager.
+	allowed map[types.DbType]struct{}
+
+	// defaultDb receives requests that carry no database selector.
+	defaultDb types.DbType
+
+	// mu guards writers.
+	mu sync.Mutex
+
+	// writers holds the batch writer of each database a write was routed to.
+	writers map[types.DbType]*BatchWriter
+}
+
+// NewRouter creates a new Router serving the given databases. The first database becomes
+// the default one. When no databases are given the Router serves every database known to
+// the manager, including those created at runtime, and defaults to the first configured node.
+//
+// Example usage:
+//
+//	router, err := db.NewRouter(manager, []string{"fdb", "contracts"})
+//	if err != nil {
+//	    log.Fatalf("Failed to create router: %v", err)
+//	}
+//
+// Parameters:
+//
+//	manager (*Manager): The database manager used to resolve databases.
+//	databases ([]string): The databases served by the transport, may be empty.
+//
+// Returns:
+//
+//	*Router: A new Router instance.
+//	error: Returns an error if any of the databases is unknown to the manager.
+func NewRouter(manager *Manager, databases []string) (*Router, error) {
+	r := &Router{
+		manager:   manager,
+		defaultDb: manager.DefaultDb(),
+		writers:   make(map[types.DbType]*BatchWriter),
+	}
+
+	if len(databases) == 0 {
+		return r, nil
+	}
+
+	r.allowed = make(map[types.DbType]struct{}, len(databases))
+	for _, name := range databases {
+		dbName := types.DbType(name)
+		if !manager.HasDb(dbName) {
+			return nil, fmt.Errorf("mdbx database %s: %w", name, fdbErrors.ErrDatabaseNotFound)
+		}
+		r.allowed[dbName] = struct{}{}
+	}
+	r.defaultDb = types.DbType(databases[0])
+
+	return r, nil
+}
+
+// DefaultDb returns the database that receives requests without a database selector.
+func (r *Router) DefaultDb() types.DbType {
+	return r.defaultDb
+}
+
+// Resolve returns the open database the request is addressed to. An empty name resolves
+// to the default database.
+//
+// Parameters:
+//
+//	name (types.DbType): The selected database, empty when the request carries no selector.
+//
+// Returns:
+//
+//	Provider: The database provider.
+//	error: Returns an error wrapping errors.ErrDatabaseNotFound if the database is unknown,
+//	closed, or not served by the transport.
+func (r *Router) Resolve(name types.DbType) (Provider, error) {
+	if name == "" {
+		name = r.defaultDb
+	}
+	if name == "" {
+		return nil, fmt.Errorf("no default mdbx database: %w", fdbErrors.ErrDatabaseNotFound)
+	}
+
+	if r.allowed != nil {
+		if _, ok := r.allowed[name]; !ok {
+			return nil, fmt.Errorf("mdbx database %s is not served by this transport: %w", name, fdbErrors.ErrDatabaseNotFound)
+		}
+	}
+
+	return r.manager.GetDb(name)
+}
+
+// Writer returns the batch writer of the database the request is addressed to, creating
+// it on first use.
+//
+// Parameters:
+//
+//	name (types.DbType): The selected database, empty when the request carries no selector.
+//
+// Returns:
+//
+//	*BatchWriter: The batch writer bound to the database.
+//	error: Returns an error if the database cannot be resolved, or errors.ErrReadOnly if it is a replica.
+func (r *Router) Writer(name types.DbType) (*BatchWriter, error) {
+	provider, err := r.Resolve(name)
+	if err != nil {
+		return nil, err
+	}
+
+	db, ok := provider.(*Db)
+	if !ok {
+		return nil, fmt.Errorf("mdbx database %s does not support batch writes", name)
+	}
+	if db.IsReadOnly() {
+		return nil, fmt.Errorf("mdbx database %s: %w", db.GetName(), fdbErrors.ErrReadOnly)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	dbName := types.DbType(db.GetName())
+	writer, exists := r.writers[dbName]
+	if exists && writer.db == db {
+		return writer, nil
+	}
+
+	// The database was reopened since the writer was created, the old one points at a closed environment.
+	if exists {
+		writer.FlushAndStop()
+	}
+
+	writer = NewBatchWriter(db, routerBatchSize, routerFlushInterval, routerWriterWorkers)
+	r.writers[dbName] = writer
+	return writer, nil
+}
+
+// Proposer returns the consensus proposer of the database the request is addressed to, and
+// the resolved database name. The proposer is nil when writes go through the batch writer.
+//
+// Parameters:
+//
+//	name (types.DbType): The selected database, empty when the request carries no selector.
+//
+// Returns:
+//
+//	Proposer: The proposer of the database, nil if it is not replicated through consensus.
+//	types.DbType: The resolved database name.
+func (r *Router) Proposer(name types.DbType) (Proposer, types.DbType) {
+	if name == "" {
+		name = r.defaultDb
+	}
+	if r.allowed != nil {
+		if _, ok := r.allowed[name]; !ok {
+			return nil, name
+		}
+	}
+	return r.manager.Proposer(name), name
+}
+
+// Reader returns the reader of the database the request is addressed to, nil when its reads are
+// served by the database itself, and the resolved name.
+//
+// Parameters:
+//
+//	name (types.DbType): The selected database, empty when the request carries no selector.
+//
+// Returns:
+//
+//	Reader: The reader of the database, nil if the database serves its own reads.
+//	types.DbType: The resolved database name.
+func (r *Router) Reader(name types.DbType) (Reader, types.DbType) {
+	if name == "" {
+		name = r.defaultDb
+	}
+	if r.allowed != nil {
+		if _, ok := r.allowed[name]; !ok {
+			return nil, name
+		}
+	}
+	return r.manager.Reader(name), name
+}
+
+// WriteStatus returns the response status for an error returned by Writer or a Proposer.
+func WriteStatus(err error) types.ResponseStatus {
+	switch {
+	case errors.Is(err, fdbErrors.ErrReadOnly):
+		return types.StatusReadOnly
+	case errors.Is(err, fdbErrors.ErrDatabaseNotFound):
+		return types.StatusDatabaseNotFound
+	case errors.Is(err, fdbErrors.ErrNoLeader):
+		return types.StatusBusy
+	default:
+		return types.StatusError
+	}
+}
+
+// ReadStatus returns the response status for an error returned by Get or a Reader, nil reading
+// a value.
+func ReadStatus(err error) types.ResponseStatus {
+	switch {
+	case err == nil:
+		return types.StatusOK
+	case errors.Is(err, fdbErrors.ErrNotFound):
+		return types.StatusNotFound
+	case errors.Is(err, fdbErrors.ErrDatabaseNotFound), errors.Is(err, fdbErrors.ErrDatabaseClosed):
+		return types.StatusDatabaseNotFound
+	case errors.Is(err, fdbErrors.ErrQuorumNotReached), errors.Is(err, fdbErrors.ErrNoLeader):
+		return types.StatusBusy
+	default:
+		return types.StatusError
+	}
+}
+
+// Close flushes and stops every batch writer created by the Router.
+func (r *Router) Close() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for name, writer := range r.writers {
+		writer.FlushAndStop()
+		delete(r.writers, name)
+	}
+}
@@ -0,0 +1,79 @@
+package db
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/unpackdev/fdb/config"
+	"github.com/unpackdev/fdb/errors"
+	"github.com/unpackdev/fdb/types"
+)
+
+func TestRouterResolve(t *testing.T) {
+	manager := setupLifecycleTestManager(t, t.TempDir())
+	defer manager.Close()
+	require.NoError(t, manager.CreateDb(config.MdbxNode{Name: "dynamic", MaxSize: 1}))
+
+	// Without declared databases every database is served and the first configured node is the default
+	router, err := NewRouter(manager, nil)
+	require.NoError(t, err)
+	defer router.Close()
+
+	assert.Equal(t, types.DbType("static"), router.DefaultDb())
+	for _, name := range []types.DbType{"", "static", "dynamic"} {
+		_, err := router.Resolve(name)
+		assert.NoError(t, err, name)
+	}
+	_, err = router.Resolve("missing")
+	assert.ErrorIs(t, err, errors.ErrDatabaseNotFound)
+
+	// Declared databases restrict the router and the first one becomes the default
+	bound, err := NewRouter(manager, []string{"dynamic"})
+	require.NoError(t, err)
+	defer bound.Close()
+
+	assert.Equal(t, types.DbType("dynamic"), bound.DefaultDb())
+	_, err = bound.Resolve("static")
+	assert.ErrorIs(t, err, errors.ErrDatabaseNotFound)
+
+	_, err = NewRouter(manager, []string{"missing"})
+	assert.ErrorIs(t, err, errors.ErrDatabaseNotFound)
+}
+
+func TestRouterWriterFollowsReopenedDatabase(t *testing.T) {
+	manager := setupLifecycleTestManager(t, t.TempDir())
+	defer manager.Close()
+
+	router, err := NewRouter(manager, []string{"static"})
+	require.NoError(t, err)
+	defer router.Close()
+
+	writer, err := router.Writer("")
+	require.NoError(t, err)
+	same, err := router.Writer("static")
+	require.NoError(t, err)
+	assert.Same(t, writer, same)
+
+	// A closed database cannot be written to, a reopened one gets a fresh writer
+	require.NoError(t, manager.CloseDb("static"))
+	_, err = router.Writer("static")
+	assert.ErrorIs(t, err, errors.ErrDatabaseNotFound)
+
+	require.NoError(t, manager.OpenDb("static"))
+	reopened, err := router.Writer("static")
+	require.NoError(t, err)
+	assert.NotSame(t, writer, reopened)
+
+	var key [32]byte
+	copy(key[:], "routed")
+	reopened.BufferWrite(key, []byte("value"))
+
+	bDb, err := router.Resolve("static")
+	require.NoError(t, err)
+	assert.Eventually(t, func() bool {
+		value, err := bDb.Get(key[:])
+		return err == nil && string(value) == "value"
+	}, 5*time.Second, 50*time.Millisecond)
+}
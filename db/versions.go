@@ -0,0 +1,216 @@
+package db
+
+import (
+	"github.com/erigontech/mdbx-go/mdbx"
+	"github.com/pkg/errors"
+	fdbErrors "github.com/unpackdev/fdb/errors"
+	"github.com/unpackdev/fdb/messages"
+	"github.com/unpackdev/fdb/types"
+)
+
+// versionsDbiName is the named DBI holding the version of every quorum-replicated key. The value
+// of a key stays in the main DBI, so the database reads and repairs like any other; deleted keys
+// keep their version as a tombstone, so an older write cannot bring them back.
+const versionsDbiName = "fdb_versions"
+
+// ensureVersionsDbi opens (creating if needed) the versions DBI.
+func (db *Db) ensureVersionsDbi() error {
+	db.versionsMu.Lock()
+	defer db.versionsMu.Unlock()
+
+	if db.versionsOpen {
+		return nil
+	}
+
+	var dbi mdbx.DBI
+	err := db.env.Update(func(txn *mdbx.Txn) error {
+		var err error
+		dbi, err = txn.OpenDBISimple(versionsDbiName, mdbx.Create)
+		return err
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to open versions")
+	}
+
+	db.versionsDbi = dbi
+	db.versionsOpen = true
+	return nil
+}
+
+// versionOf returns the version of key within txn, zero if the key was never written with one.
+func (db *Db) versionOf(txn *mdbx.Txn, key []byte) (messages.Version, error) {
+	db.versionsMu.Lock()
+	open, dbi := db.versionsOpen, db.versionsDbi
+	db.versionsMu.Unlock()
+
+	if !open {
+		return messages.Version{}, nil
+	}
+
+	encoded, err := txn.Get(dbi, key)
+	if mdbx.IsNotFound(err) {
+		return messages.Version{}, nil
+	} else if err != nil {
+		return messages.Version{}, errors.Wrapf(err, "failed to read version of key: %x", key)
+	}
+	return messages.DecodeVersion(encoded)
+}
+
+// GetVersioned returns the value of a key together with its version. Keys written without a
+// version, such as data stored before the database was replicated with quorums, have the zero
+// version. Tombstones return their version and no value.
+//
+// Example usage:
+//
+//	version, value, err := db.GetVersioned(key)
+//	if err != nil {
+//	    log.Fatalf("Failed to read key: %v", err)
+//	}
+//	if version.Tombstone {
+//	    log.Printf("Key deleted at %d", version.Timestamp)
+//	}
+//
+// Parameters:
+//
+//	key ([]byte): The key to read.
+//
+// Returns:
+//
+//	messages.Version: The version of the value, zero if the key has none.
+//	[]byte: The value, nil if the key is missing or deleted.
+//	error: Returns an error if the key cannot be read.
+func (db *Db) GetVersioned(key []byte) (messages.Version, []byte, error) {
+	if !db.Acquire() {
+		return messages.Version{}, nil, fdbErrors.ErrDatabaseClosed
+	}
+	defer db.Release()
+
+	var version messages.Version
+	var value []byte
+	err := db.env.View(func(txn *mdbx.Txn) error {
+		var err error
+		if version, err = db.versionOf(txn, key); err != nil {
+			return err
+		}
+
+		stored, err := txn.Get(db.dbi, key)
+		if mdbx.IsNotFound(err) {
+			return nil
+		} else if err != nil {
+			return errors.Wrapf(err, "failed to read key: %x", key)
+		}
+		value = append([]byte(nil), stored...)
+		return nil
+	})
+	return version, value, err
+}
+
+// PutVersioned stores a versioned value, or removes the key when the version is a tombstone,
+// unless the database already holds the same or a newer version of the key. Like ApplyChanges
+// it bypasses the read-only check, and the change is recorded in the change log.
+//
+// Example usage:
+//
+//	applied, err := db.PutVersioned(key, messages.Version{Timestamp: uint64(time.Now().UnixNano())}, value)
+//	if err != nil {
+//	    log.Fatalf("Failed to store key: %v", err)
+//	}
+//
+// Parameters:
+//
+//	key ([]byte): The key to write.
+//	version (messages.Version): The version of the write, must not be zero.
+//	value ([]byte): The value to store, ignored for tombstones.
+//
+// Returns:
+//
+//	bool: True if the write was applied, false if the database holds the same or a newer version.
+//	error: Returns an error if the key cannot be written.
+func (db *Db) PutVersioned(key []byte, version messages.Version, value []byte) (bool, error) {
+	if version.IsZero() {
+		return false, errors.New("versioned writes need a non-zero version")
+	}
+	if err := db.ensureVersionsDbi(); err != nil {
+		return false, err
+	}
+	if !db.Acquire() {
+		return false, fdbErrors.ErrDatabaseClosed
+	}
+	defer db.Release()
+
+	applied := false
+	var changes *changeAppender
+	err := db.env.Update(func(txn *mdbx.Txn) error {
+		current, err := db.versionOf(txn, key)
+		if err != nil {
+			return err
+		}
+		if !version.Newer(current) {
+			return nil
+		}
+
+		changes = db.newChangeAppender(txn)
+		defer changes.close()
+
+		if err := txn.Put(db.versionsDbi, key, version.Encode(), 0); err != nil {
+			return errors.Wrapf(err, "failed to write version of key: %x", key)
+		}
+		if version.Tombstone {
+			if err := txn.Del(db.dbi, key, nil); err != nil && !mdbx.IsNotFound(err) {
+				return errors.Wrapf(err, "failed to delete key: %x", key)
+			}
+			err = changes.append(types.ChangeDelete, key, nil)
+		} else {
+			if err := txn.Put(db.dbi, key, value, 0); err != nil {
+				return errors.Wrapf(err, "failed to write key: %x", key)
+			}
+			err = changes.append(types.ChangeSet, key, value)
+		}
+		applied = err == nil
+		return err
+	})
+	if err != nil {
+		return false, err
+	}
+
+	if applied {
+		changes.committed()
+		db.touchMerkle(key)
+	}
+	return applied, nil
+}
+
+// ExecuteReplicaRequest serves a request sent by the coordinator of a quorum read or write to a
+// replica of the key, and returns the response described by messages.ReplicaRequest.
+//
+// Parameters:
+//
+//	provider (Provider): The local replica, which must be stored in MDBX.
+//	req (*messages.ReplicaRequest): The decoded request.
+//
+// Returns:
+//
+//	[]byte: The encoded response to send back to the coordinator.
+func ExecuteReplicaRequest(provider Provider, req *messages.ReplicaRequest) []byte {
+	db, ok := provider.(*Db)
+	if !ok {
+		return []byte{byte(types.StatusError)}
+	}
+
+	switch req.Op {
+	case messages.ReplicaGet:
+		version, value, err := db.GetVersioned(req.Key[:])
+		if err != nil {
+			return []byte{byte(types.StatusError)}
+		}
+		return messages.EncodeReplicaValue(version, value)
+	case messages.ReplicaPut:
+		// A replica holding a newer version acknowledges, the write is superseded rather than lost
+		if _, err := db.PutVersioned(req.Key[:], req.Version, req.Value); err != nil {
+			return []byte{byte(WriteStatus(err))}
+		}
+		return []byte{byte(types.StatusOK)}
+	default:
+		return []byte{byte(types.StatusError)}
+	}
+}
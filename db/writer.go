@@ -3,6 +3,7 @@ package db
 import (
 	"github.com/erigontech/mdbx-go/mdbx"
 	"github.com/pkg/errors"
+	"github.com/unpackdev/fdb/types"
 	"go.uber.org/zap"
 	"sync"
 	"time"
@@ -11,14 +12,14 @@ import (
 // WriteRequest represents a key-value pair to be written to the database.
 type WriteRequest struct {
 	Key   [32]byte // Fixed-size byte array for keys
-	Value []byte   // Value as byte slice
+	Value []byte   // Value as byte slice, nil deletes the key
 }
 
 // BatchWriter handles batch writes with concurrency support and multiple workers.
 type BatchWriter struct {
 	db             *Db
 	workerChannels []chan WriteRequest   // Dedicated channel for each worker
-	workerBuffers  []map[[32]byte][]byte // Separate buffer for each worker using fixed-size byte arrays for keys
+	workerBuffers  []map[[32]byte][]byte // Separate buffer for each worker using fixed-size byte arrays for keys, nil values are deletes
 	workerMutexes  []sync.Mutex          // Separate mutex for each worker
 	maxBatchSize   int                   // Max size of the batch before flush
 	flushInterval  time.Duration         // Time interval for auto-flush
@@ -87,15 +88,49 @@ func (bw *BatchWriter) runWorker(workerID int) {
 func (bw *BatchWriter) BufferWrite(key [32]byte, value []byte) {
 	// Determine which worker to assign the write to (for simplicity, we can use modulo)
 	workerID := int(key[0]) % bw.workers
+	if value == nil {
+		value = []byte{} // nil is reserved for deletes
+	}
 	bw.workerChannels[workerID] <- WriteRequest{Key: key, Value: value}
 }
 
+// BufferDelete adds a delete of the key to the batch. Writes and deletes of the same key are
+// handled by the same worker, so the last one buffered wins.
+func (bw *BatchWriter) BufferDelete(key [32]byte) {
+	workerID := int(key[0]) % bw.workers
+	bw.workerChannels[workerID] <- WriteRequest{Key: key}
+}
+
 // flush writes the buffered key-value pairs to the MDBX database in a single transaction for a given worker.
 func (bw *BatchWriter) flush(workerID int) {
 	if len(bw.workerBuffers[workerID]) == 0 {
 		return
 	}
 
+	// Hold a reference for the duration of the flush so the database cannot be closed underneath us
+	if !bw.db.Acquire() {
+		zap.L().Error(
+			"failure to flush messages, database is closed",
+			zap.String("name", bw.db.GetName()),
+			zap.Int("dropped", len(bw.workerBuffers[workerID])),
+		)
+		bw.workerBuffers[workerID] = make(map[[32]byte][]byte)
+		return
+	}
+	defer bw.db.Release()
+
+	// Writes buffered before the database turned into a replica would diverge from the leader
+	if bw.db.IsReadOnly() {
+		zap.L().Error(
+			"failure to flush messages, database is read-only",
+			zap.String("name", bw.db.GetName()),
+			zap.Int("dropped", len(bw.workerBuffers[workerID])),
+		)
+		bw.workerBuffers[workerID] = make(map[[32]byte][]byte)
+		return
+	}
+
+	var changes *changeAppender
 	err := bw.db.env.Update(func(txn *mdbx.Txn) error {
 		cursor, err := txn.OpenCursor(bw.db.GetDBI())
 		if err != nil {
@@ -103,14 +138,33 @@ func (bw *BatchWriter) flush(workerID int) {
 		}
 		defer cursor.Close()
 
+		// Changes are recorded in the same transaction, so the log never diverges from the data
+		changes = bw.db.newChangeAppender(txn)
+		defer changes.close()
+
 		// Write all buffered key-value pairs for this worker to the database
 		for key, value := range bw.workerBuffers[workerID] {
-			if err := cursor.Put(key[:], value, 0); err != nil {
+			op := types.ChangeSet
+			if value == nil {
+				op = types.ChangeDelete
+				if err := txn.Del(bw.db.GetDBI(), key[:], nil); err != nil && !mdbx.IsNotFound(err) {
+					return errors.Wrapf(err, "failed to delete key: %x", key)
+				}
+			} else if err := cursor.Put(key[:], value, 0); err != nil {
 				return errors.Wrapf(err, "failed to write key: %x", key)
 			}
+			if err := changes.append(op, key[:], value); err != nil {
+				return errors.Wrapf(err, "failed to record change for key: %x", key)
+			}
 		}
 		return nil
 	})
+	if err == nil {
+		changes.committed()
+		for key := range bw.workerBuffers[workerID] {
+			bw.db.touchMerkle(key[:])
+		}
+	}
 
 	if err != nil {
 		zap.L().Error(
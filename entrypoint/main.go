@@ -12,10 +12,17 @@ func main() {
 		Name:  "(f)db",
 		Usage: "Fast Database Transports",
 		Commands: []*cli.Command{
-			cmd.CertsCommand(),     // Command for handling certificates
-			cmd.BenchmarkCommand(), // Command for running benchmarks
-			cmd.EbpfCommands(),     // Command for running eBPF specific workload
-			cmd.ServeCommand(),     // Command to start the server
+			cmd.CertsCommand(),       // Command for handling certificates
+			cmd.BenchmarkCommand(),   // Command for running benchmarks
+			cmd.EbpfCommands(),       // Command for running eBPF specific workload
+			cmd.ServeCommand(),       // Command to start the server
+			cmd.ReplicationCommand(), // Command for managing replication
+			cmd.RaftCommand(),        // Command for managing the Raft group
+			cmd.ShardCommand(),       // Command for managing the shard map of a sharding proxy
+			cmd.MembersCommand(),     // Command for listing the gossip cluster members
+			cmd.RepairCommand(),      // Command for anti-entropy repair against a peer replica
+			cmd.QuorumCommand(),      // Command for showing the quorum replication status
+			cmd.GatewayCommand(),     // Command for showing the gateway upstream and forwarded requests
 		},
 	}
 
@@ -5,4 +5,34 @@ import "errors"
 var (
 	// ErrNotFound is returned when a key is not found in the database
 	ErrNotFound = errors.New("key not found")
+
+	// ErrDatabaseNotFound is returned when the requested database is not managed by this node
+	ErrDatabaseNotFound = errors.New("database not found")
+
+	// ErrDatabaseExists is returned when creating a database whose name is already taken
+	ErrDatabaseExists = errors.New("database already exists")
+
+	// ErrDatabaseClosed is returned when an operation is attempted on a closed (or closing) database
+	ErrDatabaseClosed = errors.New("database is closed")
+
+	// ErrCdcDisabled is returned when the change log is accessed on a database without CDC enabled
+	ErrCdcDisabled = errors.New("change data capture is disabled")
+
+	// ErrChangesTruncated is returned when the requested changes were already removed by retention
+	ErrChangesTruncated = errors.New("changes were pruned from the change log")
+
+	// ErrReadOnly is returned when writing to a database that replicates from a leader
+	ErrReadOnly = errors.New("database is read-only")
+
+	// ErrReplicationGap is returned when replicated changes do not follow the local replication position
+	ErrReplicationGap = errors.New("replicated changes do not follow the local position")
+
+	// ErrNoLeader is returned when a consensus write cannot be committed because the group has no leader
+	ErrNoLeader = errors.New("raft group has no leader")
+
+	// ErrQuorumNotReached is returned when too few replicas answered a quorum read or write
+	ErrQuorumNotReached = errors.New("quorum not reached")
+
+	// ErrBusy is returned when the server did not apply a request because it cannot serve it for now, the request may be retried
+	ErrBusy = errors.New("server is busy")
 )
@@ -4,10 +4,17 @@ import (
 	"context"
 	"fmt"
 	"github.com/pkg/errors"
+	"github.com/unpackdev/fdb/antientropy"
 	"github.com/unpackdev/fdb/config"
+	"github.com/unpackdev/fdb/consensus"
 	"github.com/unpackdev/fdb/db"
+	"github.com/unpackdev/fdb/gateway"
 	"github.com/unpackdev/fdb/logger"
+	"github.com/unpackdev/fdb/membership"
 	"github.com/unpackdev/fdb/pprof"
+	"github.com/unpackdev/fdb/quorum"
+	"github.com/unpackdev/fdb/replication"
+	"github.com/unpackdev/fdb/sharding"
 	"github.com/unpackdev/fdb/transports"
 	transport_dummy "github.com/unpackdev/fdb/transports/dummy"
 	transport_quic "github.com/unpackdev/fdb/transports/quic"
@@ -17,6 +24,7 @@ import (
 	"github.com/unpackdev/fdb/types"
 	"go.uber.org/zap"
 	"golang.org/x/sync/errgroup"
+	"sync"
 )
 
 type FDB struct {
@@ -24,6 +32,15 @@ type FDB struct {
 	config    config.Config
 	tm        *transports.Manager
 	dbManager *db.Manager
+	routersMu sync.Mutex
+	routers   map[types.TransportType]*db.Router
+	repl      *replication.Node
+	raft      *consensus.Group
+	proxy     *sharding.Proxy
+	members   *membership.Node
+	repair    *antientropy.Service
+	quorum    *quorum.Coordinator
+	gateway   *gateway.Gateway
 }
 
 func New(ctx context.Context, cnf config.Config) (*FDB, error) {
@@ -56,6 +73,108 @@ func New(ctx context.Context, cnf config.Config) (*FDB, error) {
 		config:    cnf,
 		tm:        transportManager,
 		dbManager: dbM,
+		routers:   make(map[types.TransportType]*db.Router),
+		repl:      replication.NewNode(dbM, cnf.Replication),
+	}
+
+	// Replication status, promotion and follow are served through the admin handlers
+	if cnf.Admin.Enabled {
+		replication.RegisterAdminOps(dbM, fdbInstance.repl)
+	}
+
+	// Databases that must never diverge are replicated through the Raft group
+	if cnf.Raft.Enabled {
+		fdbInstance.raft = consensus.NewGroup(dbM, cnf.Raft)
+		if cnf.Admin.Enabled {
+			consensus.RegisterAdminOps(dbM, fdbInstance.raft)
+		}
+	}
+
+	// Proxies serve the sharded databases by forwarding every request to the owning shard
+	if cnf.Sharding.Enabled {
+		proxy, err := sharding.NewProxy(cnf.Sharding)
+		if err != nil {
+			return nil, errors.Wrap(err, "failure to create sharding proxy")
+		}
+		for _, name := range cnf.Sharding.Databases {
+			dbName := types.DbType(name)
+			if err := dbM.Attach(dbName, proxy.Provider(dbName)); err != nil {
+				_ = proxy.Close()
+				return nil, errors.Wrapf(err, "failure to attach sharded database: %s", name)
+			}
+			if err := dbM.SetProposer(dbName, proxy); err != nil {
+				_ = proxy.Close()
+				return nil, errors.Wrapf(err, "failure to route writes of sharded database: %s", name)
+			}
+		}
+		if cnf.Admin.Enabled {
+			sharding.RegisterAdminOps(dbM, proxy)
+		}
+		fdbInstance.proxy = proxy
+	}
+
+	// Gateways serve their databases by forwarding every request to the upstream node
+	if cnf.Gateway.Enabled {
+		gw, err := gateway.NewGateway(cnf.Gateway)
+		if err != nil {
+			return nil, errors.Wrap(err, "failure to create gateway")
+		}
+		for _, name := range cnf.Gateway.Databases {
+			dbName := types.DbType(name)
+			if err := dbM.Attach(dbName, gw.Provider(dbName)); err != nil {
+				_ = gw.Close()
+				return nil, errors.Wrapf(err, "failure to attach gateway database: %s", name)
+			}
+			if err := dbM.SetProposer(dbName, gw); err != nil {
+				_ = gw.Close()
+				return nil, errors.Wrapf(err, "failure to route writes of gateway database: %s", name)
+			}
+			if err := dbM.SetReader(dbName, gw); err != nil {
+				_ = gw.Close()
+				return nil, errors.Wrapf(err, "failure to route reads of gateway database: %s", name)
+			}
+		}
+		if cnf.Admin.Enabled {
+			gateway.RegisterAdminOps(dbM, gw)
+		}
+		fdbInstance.gateway = gw
+	}
+
+	// Every node coordinates the quorum reads and writes it receives over the replicas of the key
+	if cnf.Quorum.Enabled {
+		coordinator, err := quorum.NewCoordinator(dbM, cnf.Quorum)
+		if err != nil {
+			return nil, errors.Wrap(err, "failure to create quorum coordinator")
+		}
+		for _, name := range cnf.Quorum.Databases {
+			dbName := types.DbType(name)
+			if err := dbM.SetProposer(dbName, coordinator); err != nil {
+				_ = coordinator.Close()
+				return nil, errors.Wrapf(err, "failure to route writes of quorum database: %s", name)
+			}
+			if err := dbM.SetReader(dbName, coordinator); err != nil {
+				_ = coordinator.Close()
+				return nil, errors.Wrapf(err, "failure to route reads of quorum database: %s", name)
+			}
+		}
+		if cnf.Admin.Enabled {
+			quorum.RegisterAdminOps(dbM, coordinator)
+		}
+		fdbInstance.quorum = coordinator
+	}
+
+	// Members advertise what they serve so peers and clients can discover them
+	if cnf.Membership.Enabled {
+		fdbInstance.members = membership.NewNode(cnf.Membership, fdbInstance.membershipMeta())
+		if cnf.Admin.Enabled {
+			membership.RegisterAdminOps(dbM, fdbInstance.members)
+		}
+	}
+
+	// Replicas are compared and repaired on schedule, or on demand through the admin handlers
+	fdbInstance.repair = antientropy.NewService(dbM, cnf.AntiEntropy, fdbInstance.members)
+	if cnf.Admin.Enabled {
+		antientropy.RegisterAdminOps(dbM, fdbInstance.repair)
 	}
 
 	for _, transport := range cnf.Transports {
@@ -112,11 +231,6 @@ func New(ctx context.Context, cnf config.Config) (*FDB, error) {
 func (fdb *FDB) Start(ctx context.Context, transports ...types.TransportType) error {
 	g, gCtx := errgroup.WithContext(ctx)
 
-	bDb, err := fdb.GetDbManager().GetDb("fdb")
-	if err != nil {
-		return fmt.Errorf("failed to retrieve fdb database: %w", err)
-	}
-
 	pCfg, pcErr := fdb.config.GetPprofByServiceTag("fdb")
 	if pcErr != nil {
 		return errors.Wrapf(pcErr, "failed to retrieve fdb pprof config for service tag: %s", "fdb")
@@ -128,13 +242,42 @@ func (fdb *FDB) Start(ctx context.Context, transports ...types.TransportType) er
 		})
 	}
 
+	// Followers turn their databases read-only before any transport serves them
+	if rErr := fdb.repl.Start(ctx); rErr != nil {
+		return errors.Wrap(rErr, "failure to start replication")
+	}
+
+	// Writes to the Raft databases must go through the log before any transport serves them
+	if fdb.raft != nil {
+		if rErr := fdb.raft.Start(ctx); rErr != nil {
+			return errors.Wrap(rErr, "failure to start raft group")
+		}
+	}
+
+	if fdb.members != nil {
+		if mErr := fdb.members.Start(ctx); mErr != nil {
+			return errors.Wrap(mErr, "failure to start membership")
+		}
+	}
+
+	fdb.repair.Start(ctx)
+
+	if fdb.quorum != nil {
+		fdb.quorum.Start(ctx)
+	}
+
 	for _, transport := range transports {
 		transportFn, tnOk := tRegistry[transport]
 		if !tnOk {
 			return fmt.Errorf("unknown transport type provided: %v - rejecting serving transports", transport)
 		}
 
-		iTransport, itErr := transportFn(fdb, bDb)
+		router, rErr := fdb.NewRouter(transport)
+		if rErr != nil {
+			return errors.Wrapf(rErr, "failure to create database router for transport: %s", transport)
+		}
+
+		iTransport, itErr := transportFn(fdb, router)
 		if itErr != nil {
 			return errors.Wrapf(itErr, "failure to create transport: %s", transport)
 		}
@@ -164,6 +307,41 @@ func (fdb *FDB) Stop(transports ...types.TransportType) error {
 		if err := t.Stop(); err != nil {
 			return err
 		}
+
+		fdb.closeRouter(transport)
+	}
+
+	fdb.repair.Stop()
+
+	if fdb.quorum != nil {
+		if err := fdb.quorum.Close(); err != nil {
+			return errors.Wrap(err, "failure to close quorum coordinator")
+		}
+	}
+
+	if fdb.members != nil {
+		fdb.members.Leave()
+		if err := fdb.members.Shutdown(); err != nil {
+			return errors.Wrap(err, "failure to shut down membership")
+		}
+	}
+
+	if fdb.raft != nil {
+		if err := fdb.raft.Shutdown(); err != nil {
+			return errors.Wrap(err, "failure to shut down raft group")
+		}
+	}
+
+	if fdb.proxy != nil {
+		if err := fdb.proxy.Close(); err != nil {
+			return errors.Wrap(err, "failure to close sharding proxy")
+		}
+	}
+
+	if fdb.gateway != nil {
+		if err := fdb.gateway.Close(); err != nil {
+			return errors.Wrap(err, "failure to close gateway")
+		}
 	}
 
 	zap.L().Info("All transports successfully stopped")
@@ -182,6 +360,117 @@ func (fdb *FDB) GetTransportManager() *transports.Manager {
 	return fdb.tm
 }
 
+// GetReplication returns the replication node, which manages the leader-follower role of this node.
+func (fdb *FDB) GetReplication() *replication.Node {
+	return fdb.repl
+}
+
+// GetRaft returns the Raft group of this node, nil if Raft replication is disabled.
+func (fdb *FDB) GetRaft() *consensus.Group {
+	return fdb.raft
+}
+
+// GetSharding returns the sharding proxy of this node, nil if the node is not a proxy.
+func (fdb *FDB) GetSharding() *sharding.Proxy {
+	return fdb.proxy
+}
+
+// GetAntiEntropy returns the anti-entropy service repairing the databases of this node against their replicas.
+func (fdb *FDB) GetAntiEntropy() *antientropy.Service {
+	return fdb.repair
+}
+
+// GetQuorum returns the quorum coordinator of this node, nil if quorum replication is disabled.
+func (fdb *FDB) GetQuorum() *quorum.Coordinator {
+	return fdb.quorum
+}
+
+// GetGateway returns the gateway of this node, nil if the node is not a gateway.
+func (fdb *FDB) GetGateway() *gateway.Gateway {
+	return fdb.gateway
+}
+
+// GetMembership returns the gossip membership node, nil if membership is disabled.
+func (fdb *FDB) GetMembership() *membership.Node {
+	return fdb.members
+}
+
+// membershipMeta describes this node to the other members: the enabled transports, the known
+// databases and the roles derived from the replication, Raft, sharding, quorum and gateway configuration,
+// followed by the configured roles.
+func (fdb *FDB) membershipMeta() membership.Meta {
+	var meta membership.Meta
+	for _, transport := range fdb.config.Transports {
+		if transport.Enabled && transport.Config != nil {
+			meta.Transports = append(meta.Transports, membership.Endpoint{Type: transport.Type, Addr: transport.Config.Addr()})
+		}
+	}
+
+	for _, info := range fdb.dbManager.ListDbs() {
+		meta.Databases = append(meta.Databases, info.Name)
+	}
+
+	if role := fdb.repl.Role(); role != config.ReplicationStandalone {
+		meta.Roles = append(meta.Roles, string(role))
+	}
+	if fdb.config.Raft.Enabled {
+		meta.Roles = append(meta.Roles, "raft")
+	}
+	if fdb.config.Sharding.Enabled {
+		meta.Roles = append(meta.Roles, "proxy")
+	}
+	if fdb.config.Quorum.Enabled {
+		meta.Roles = append(meta.Roles, "quorum")
+	}
+	if fdb.config.Gateway.Enabled {
+		meta.Roles = append(meta.Roles, "gateway")
+	}
+	meta.Roles = append(meta.Roles, fdb.config.Membership.Roles...)
+	return meta
+}
+
+// NewRouter creates the database router of the given transport, bound to the databases declared
+// in its configuration entry. The router is closed, flushing its batch writers, when the
+// transport is stopped.
+func (fdb *FDB) NewRouter(tType types.TransportType) (*db.Router, error) {
+	var databases []string
+	if tCfg := fdb.config.GetTransportByType(tType); tCfg != nil {
+		databases = tCfg.Databases
+	}
+
+	router, err := db.NewRouter(fdb.dbManager, databases)
+	if err != nil {
+		return nil, err
+	}
+
+	fdb.routersMu.Lock()
+	defer fdb.routersMu.Unlock()
+
+	if previous, ok := fdb.routers[tType]; ok {
+		previous.Close()
+	}
+	fdb.routers[tType] = router
+
+	zap.L().Info(
+		"Database router created",
+		zap.String("transport", tType.String()),
+		zap.Strings("databases", databases),
+		zap.String("default", router.DefaultDb().String()),
+	)
+	return router, nil
+}
+
+// closeRouter closes the database router of the given transport, if any.
+func (fdb *FDB) closeRouter(tType types.TransportType) {
+	fdb.routersMu.Lock()
+	defer fdb.routersMu.Unlock()
+
+	if router, ok := fdb.routers[tType]; ok {
+		router.Close()
+		delete(fdb.routers, tType)
+	}
+}
+
 // GetTransportByType allows retrieval of specific transport from the manager
 func (fdb *FDB) GetTransportByType(tType types.TransportType) (transports.Transport, error) {
 	return fdb.tm.GetTransport(tType)
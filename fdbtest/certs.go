@@ -0,0 +1,105 @@
+package fdbtest
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// Certs are the paths of a self-signed CA and of a server certificate it signed, valid for
+// localhost and 127.0.0.1.
+type Certs struct {
+	// CAFile is the PEM encoded CA certificate clients trust.
+	CAFile string
+
+	// CertFile is the PEM encoded server certificate.
+	CertFile string
+
+	// KeyFile is the PEM encoded private key of the server certificate.
+	KeyFile string
+}
+
+// WriteCerts generates a CA and a server certificate valid for an hour, writing them to dir as
+// ca.pem, cert.pem and key.pem. It fails the test on error.
+//
+// Example usage:
+//
+//	certs := fdbtest.WriteCerts(t, t.TempDir())
+//	server, err := transport_quic.NewServer(ctx, config.QuicTransport{
+//	    IPv4: "127.0.0.1",
+//	    Port: fdbtest.FreePort(t, "udp"),
+//	    TLS:  config.TLS{Cert: certs.CertFile, Key: certs.KeyFile},
+//	})
+func WriteCerts(t testing.TB, dir string) Certs {
+	t.Helper()
+
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("fdbtest: failure to generate CA key: %v", err)
+	}
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "fdb test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatalf("fdbtest: failure to create CA certificate: %v", err)
+	}
+	ca, err := x509.ParseCertificate(caDER)
+	if err != nil {
+		t.Fatalf("fdbtest: failure to parse CA certificate: %v", err)
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("fdbtest: failure to generate server key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "fdb"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	certDER, err := x509.CreateCertificate(rand.Reader, template, ca, &key.PublicKey, caKey)
+	if err != nil {
+		t.Fatalf("fdbtest: failure to create server certificate: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("fdbtest: failure to encode server key: %v", err)
+	}
+
+	certs := Certs{
+		CAFile:   filepath.Join(dir, "ca.pem"),
+		CertFile: filepath.Join(dir, "cert.pem"),
+		KeyFile:  filepath.Join(dir, "key.pem"),
+	}
+	for path, block := range map[string]*pem.Block{
+		certs.CAFile:   {Type: "CERTIFICATE", Bytes: caDER},
+		certs.CertFile: {Type: "CERTIFICATE", Bytes: certDER},
+		certs.KeyFile:  {Type: "EC PRIVATE KEY", Bytes: keyDER},
+	} {
+		if err := os.WriteFile(path, pem.EncodeToMemory(block), 0o600); err != nil {
+			t.Fatalf("fdbtest: failure to write %s: %v", path, err)
+		}
+	}
+	return certs
+}
@@ -0,0 +1,435 @@
+// Package fdbtest runs complete (f)db instances inside tests. Every instance gets its own
+// databases in a temporary directory, its transports on free ports and a self-signed
+// certificate, and is stopped and removed once the test finishes, so tests can run in parallel
+// without fixed ports or paths.
+//
+// Example usage:
+//
+//	func TestSomething(t *testing.T) {
+//	    server := fdbtest.Start(t)
+//	    c := server.Client(t, types.TCPTransportType)
+//	    if err := c.Set(ctx, key, []byte("value")); err != nil {
+//	        t.Fatal(err)
+//	    }
+//	}
+package fdbtest
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/unpackdev/fdb"
+	"github.com/unpackdev/fdb/client"
+	"github.com/unpackdev/fdb/config"
+	"github.com/unpackdev/fdb/db"
+	"github.com/unpackdev/fdb/types"
+	"go.uber.org/zap"
+)
+
+const (
+	// DefaultDatabase is the database of an instance started without WithDatabases.
+	DefaultDatabase = "fdb"
+
+	// DefaultStartTimeout bounds the wait for the transports of an instance to answer.
+	DefaultStartTimeout = 10 * time.Second
+)
+
+// DefaultTransports are the transports served by an instance started without WithTransports.
+var DefaultTransports = []types.TransportType{
+	types.TCPTransportType,
+	types.QUICTransportType,
+	types.UDSTransportType,
+	types.UDPTransportType,
+}
+
+// Option configures an instance started by Start.
+type Option func(*options)
+
+type options struct {
+	transports   []types.TransportType
+	databases    []string
+	cdc          bool
+	admin        bool
+	tls          bool
+	dtls         bool
+	logger       *zap.Logger
+	startTimeout time.Duration
+	configure    []func(*config.Config)
+}
+
+// WithTransports sets the transports served by the instance, DefaultTransports by default.
+func WithTransports(transports ...types.TransportType) Option {
+	return func(o *options) {
+		if len(transports) > 0 {
+			o.transports = transports
+		}
+	}
+}
+
+// WithDatabases sets the databases of the instance, DefaultDatabase by default. The first one
+// is the database of the clients returned by Server.Client.
+func WithDatabases(names ...string) Option {
+	return func(o *options) {
+		if len(names) > 0 {
+			o.databases = names
+		}
+	}
+}
+
+// WithCDC records the changes of every database, so they can be subscribed to.
+func WithCDC() Option {
+	return func(o *options) {
+		o.cdc = true
+	}
+}
+
+// WithTLS serves the TCP transport over TLS, with the certificate of the instance.
+func WithTLS() Option {
+	return func(o *options) {
+		o.tls = true
+	}
+}
+
+// WithDTLS serves the UDP transport over DTLS, with the certificate of the instance.
+func WithDTLS() Option {
+	return func(o *options) {
+		o.dtls = true
+	}
+}
+
+// WithAdmin serves the administrative operations over every transport.
+func WithAdmin() Option {
+	return func(o *options) {
+		o.admin = true
+	}
+}
+
+// WithLogger sets the logger of the clients returned by Server.Client, a no-op logger by
+// default.
+func WithLogger(logger *zap.Logger) Option {
+	return func(o *options) {
+		if logger != nil {
+			o.logger = logger
+		}
+	}
+}
+
+// WithStartTimeout bounds the wait for the transports to answer, DefaultStartTimeout by
+// default.
+func WithStartTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		if timeout > 0 {
+			o.startTimeout = timeout
+		}
+	}
+}
+
+// WithConfig lets fn change the generated configuration before the instance is created, e.g.
+// to enable replication or tune a database.
+func WithConfig(fn func(*config.Config)) Option {
+	return func(o *options) {
+		if fn != nil {
+			o.configure = append(o.configure, fn)
+		}
+	}
+}
+
+// Server is a running (f)db instance.
+type Server struct {
+	// FDB is the instance, giving access to its database manager and services.
+	FDB *fdb.FDB
+
+	// Dir is the temporary directory holding the databases, sockets and certificates.
+	Dir string
+
+	// Certs is the self-signed certificate of the encrypted transports.
+	Certs Certs
+
+	cfg        config.Config
+	transports []types.TransportType
+	databases  []string
+	logger     *zap.Logger
+
+	stop     func() error
+	stopOnce sync.Once
+	stopErr  error
+}
+
+// Start creates and starts an instance serving the transports of the options, waiting until
+// every one of them answers requests. The instance is stopped and its directory removed once
+// the test finishes. Start fails the test if the instance cannot be started.
+//
+// Example usage:
+//
+//	server := fdbtest.Start(t, fdbtest.WithTransports(types.TCPTransportType), fdbtest.WithCDC())
+//	provider := server.Provider(t, fdbtest.DefaultDatabase)
+//
+// Parameters:
+//
+//	t (testing.TB): The test owning the instance.
+//	opts (...Option): Options changing the transports, databases or configuration.
+//
+// Returns:
+//
+//	*Server: The running instance.
+func Start(t testing.TB, opts ...Option) *Server {
+	t.Helper()
+
+	o := options{
+		transports:   DefaultTransports,
+		databases:    []string{DefaultDatabase},
+		logger:       zap.NewNop(),
+		startTimeout: DefaultStartTimeout,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	// The UDS server lowercases its socket path, so t.TempDir, named after the test, cannot
+	// hold the socket
+	dir, err := os.MkdirTemp("", "fdbtest")
+	if err != nil {
+		t.Fatalf("fdbtest: failure to create directory: %v", err)
+	}
+	t.Cleanup(func() { _ = os.RemoveAll(dir) })
+
+	s := &Server{
+		Dir:        dir,
+		Certs:      WriteCerts(t, dir),
+		transports: o.transports,
+		databases:  o.databases,
+		logger:     o.logger,
+	}
+	s.cfg = s.config(t, o)
+	for _, fn := range o.configure {
+		fn(&s.cfg)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	instance, err := fdb.New(ctx, s.cfg)
+	if err != nil {
+		cancel()
+		t.Fatalf("fdbtest: failure to create instance: %v", err)
+	}
+	s.FDB = instance
+
+	done := make(chan error, 1)
+	go func() {
+		done <- instance.Start(ctx, s.transports...)
+	}()
+	s.stop = func() error {
+		// The transports stop with the context they were created with, so it is cancelled last
+		err := instance.Stop(s.transports...)
+		cancel()
+		<-done
+		if err != nil {
+			return fmt.Errorf("failure to stop instance: %w", err)
+		}
+		if err := instance.GetDbManager().Close(); err != nil {
+			return fmt.Errorf("failure to close databases: %w", err)
+		}
+		return nil
+	}
+	t.Cleanup(func() {
+		if err := s.Stop(); err != nil {
+			t.Errorf("fdbtest: %v", err)
+		}
+	})
+
+	if err := s.waitReady(done, o.startTimeout); err != nil {
+		t.Fatalf("fdbtest: %v", err)
+	}
+	return s
+}
+
+// config generates the configuration of the instance: a database per name in the directory
+// and every transport on a free port or socket.
+func (s *Server) config(t testing.TB, o options) config.Config {
+	cfg := config.Config{
+		Mdbx:  config.Mdbx{Enabled: true},
+		Pprof: []config.Pprof{{Name: "fdb"}},
+		Admin: config.Admin{Enabled: o.admin},
+	}
+	for _, name := range o.databases {
+		path := filepath.Join(s.Dir, "db", name)
+		if err := os.MkdirAll(path, 0o700); err != nil {
+			t.Fatalf("fdbtest: failure to create directory of database %s: %v", name, err)
+		}
+		cfg.Mdbx.Nodes = append(cfg.Mdbx.Nodes, config.MdbxNode{
+			Name:    name,
+			Path:    path,
+			MaxSize: 1,
+			Cdc:     config.Cdc{Enabled: o.cdc},
+		})
+	}
+
+	tls := config.TLS{Cert: s.Certs.CertFile, Key: s.Certs.KeyFile, RootCA: s.Certs.CAFile}
+	tcpTLS := tls
+	tcpTLS.Enabled = true
+	for _, transport := range o.transports {
+		var tc config.TransportConfig
+		switch transport {
+		case types.TCPTransportType:
+			tcp := &config.TcpTransport{Type: transport, Enabled: true, IPv4: "127.0.0.1", Port: FreePort(t, "tcp")}
+			if o.tls {
+				tcp.TLS = &tcpTLS
+			}
+			tc = tcp
+		case types.QUICTransportType:
+			tc = &config.QuicTransport{Type: transport, Enabled: true, IPv4: "127.0.0.1", Port: FreePort(t, "udp"), TLS: tls}
+		case types.UDPTransportType:
+			udp := &config.UdpTransport{Type: transport, Enabled: true, IPv4: "127.0.0.1", Port: FreePort(t, "udp")}
+			if o.dtls {
+				udp.DTLS = &config.DTLS{Enabled: true, Cert: s.Certs.CertFile, Key: s.Certs.KeyFile, RootCA: s.Certs.CAFile}
+			}
+			tc = udp
+		case types.UDSTransportType:
+			tc = &config.UdsTransport{Type: transport, Enabled: true, Socket: filepath.Join(s.Dir, "fdb.sock")}
+		default:
+			t.Fatalf("fdbtest: unsupported transport: %s", transport)
+		}
+		cfg.Transports = append(cfg.Transports, config.Transport{Type: transport, Enabled: true, Config: tc})
+	}
+	return cfg
+}
+
+// waitReady waits until a client of every transport gets an answer, or the instance failed.
+func (s *Server) waitReady(done <-chan error, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	var key [32]byte
+
+	for _, transport := range s.transports {
+		for {
+			select {
+			case err := <-done:
+				return fmt.Errorf("instance stopped while starting: %w", err)
+			default:
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			c, err := client.Open(ctx, s.DSN(transport), s.logger)
+			if err == nil {
+				_, err = c.Exists(ctx, key)
+				_ = c.Close()
+			}
+			cancel()
+			if err == nil {
+				break
+			}
+			if time.Now().After(deadline) {
+				return fmt.Errorf("transport %s not ready after %s: %w", transport, timeout, err)
+			}
+			time.Sleep(20 * time.Millisecond)
+		}
+	}
+	return nil
+}
+
+// Stop stops the instance and closes its databases before the test finishes, e.g. to test how
+// clients fail over. Its directory is still removed once the test finishes; later calls return
+// the result of the first one.
+func (s *Server) Stop() error {
+	s.stopOnce.Do(func() {
+		s.stopErr = s.stop()
+	})
+	return s.stopErr
+}
+
+// Config returns the configuration the instance was created with.
+func (s *Server) Config() config.Config {
+	return s.cfg
+}
+
+// Addr returns the address a transport listens on, or the path of the UDS socket. It is empty
+// for transports the instance does not serve.
+func (s *Server) Addr(transport types.TransportType) string {
+	if tc := s.cfg.GetTransportByType(transport); tc != nil {
+		return tc.Config.Addr()
+	}
+	return ""
+}
+
+// DSN returns the connection string of a transport, for client.Open or a client configuration
+// file. It selects the first database of the instance and trusts its certificate.
+func (s *Server) DSN(transport types.TransportType) string {
+	query := url.Values{"db": {s.databases[0]}}
+	switch transport {
+	case types.TCPTransportType:
+		if tc := s.cfg.GetTransportByType(transport); tc != nil {
+			if tcp, ok := tc.Config.(*config.TcpTransport); ok && tcp.TLS != nil && tcp.TLS.Enabled {
+				query.Set("ca", s.Certs.CAFile)
+				return fmt.Sprintf("%s://%s?%s", client.SchemeTLS, s.Addr(transport), query.Encode())
+			}
+		}
+		return fmt.Sprintf("%s://%s?%s", client.SchemeTCP, s.Addr(transport), query.Encode())
+	case types.QUICTransportType:
+		query.Set("ca", s.Certs.CAFile)
+		return fmt.Sprintf("%s://%s?%s", client.SchemeQUIC, s.Addr(transport), query.Encode())
+	case types.UDPTransportType:
+		if tc := s.cfg.GetTransportByType(transport); tc != nil {
+			if udp, ok := tc.Config.(*config.UdpTransport); ok && udp.DTLS != nil && udp.DTLS.Enabled {
+				query.Set("ca", s.Certs.CAFile)
+				return fmt.Sprintf("%s://%s?%s", client.SchemeDTLS, s.Addr(transport), query.Encode())
+			}
+		}
+		return fmt.Sprintf("%s://%s?%s", client.SchemeUDP, s.Addr(transport), query.Encode())
+	case types.UDSTransportType:
+		return fmt.Sprintf("%s://%s?%s", client.SchemeUDS, s.Addr(transport), query.Encode())
+	default:
+		return ""
+	}
+}
+
+// Client returns a client connected to the instance over a transport, closed once the test
+// finishes. It fails the test if the client cannot connect.
+func (s *Server) Client(t testing.TB, transport types.TransportType) *client.Client {
+	t.Helper()
+
+	c, err := client.Open(context.Background(), s.DSN(transport), s.logger)
+	if err != nil {
+		t.Fatalf("fdbtest: failure to connect over %s: %v", transport, err)
+	}
+	t.Cleanup(func() { _ = c.Close() })
+	return c
+}
+
+// Provider returns a database of the instance, to prepare or check its content directly.
+func (s *Server) Provider(t testing.TB, name string) db.Provider {
+	t.Helper()
+
+	provider, err := s.FDB.GetDbManager().GetDb(types.DbType(name))
+	if err != nil {
+		t.Fatalf("fdbtest: failure to get database %s: %v", name, err)
+	}
+	return provider
+}
+
+// FreePort returns a port of 127.0.0.1 free on network, "tcp" or "udp", at the time of the
+// call.
+func FreePort(t testing.TB, network string) int {
+	t.Helper()
+
+	switch network {
+	case "udp":
+		conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("fdbtest: failure to find a free udp port: %v", err)
+		}
+		defer conn.Close()
+		return conn.LocalAddr().(*net.UDPAddr).Port
+	default:
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("fdbtest: failure to find a free tcp port: %v", err)
+		}
+		defer listener.Close()
+		return listener.Addr().(*net.TCPAddr).Port
+	}
+}
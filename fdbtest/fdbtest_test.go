@@ -0,0 +1,62 @@
+package fdbtest_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/unpackdev/fdb/fdbtest"
+	"github.com/unpackdev/fdb/types"
+)
+
+func TestStartServesEveryTransport(t *testing.T) {
+	server := fdbtest.Start(t)
+	provider := server.Provider(t, fdbtest.DefaultDatabase)
+	ctx := context.Background()
+
+	for i, transport := range fdbtest.DefaultTransports {
+		t.Run(transport.String(), func(t *testing.T) {
+			var key [32]byte
+			copy(key[:], fmt.Sprintf("key-%d", i))
+
+			c := server.Client(t, transport)
+			require.NoError(t, c.Set(ctx, key, []byte(fmt.Sprintf("value-%d", i))))
+
+			// Writes are acknowledged once buffered and visible once flushed
+			require.Eventually(t, func() bool {
+				value, err := c.Get(ctx, key)
+				return err == nil && string(value) == fmt.Sprintf("value-%d", i)
+			}, 5*time.Second, 20*time.Millisecond)
+
+			stored, err := provider.Get(key[:])
+			require.NoError(t, err)
+			assert.Equal(t, fmt.Sprintf("value-%d", i), string(stored))
+		})
+	}
+}
+
+func TestStartIsolatesInstances(t *testing.T) {
+	first := fdbtest.Start(t, fdbtest.WithTransports(types.TCPTransportType), fdbtest.WithDatabases("a", "b"))
+	second := fdbtest.Start(t, fdbtest.WithTransports(types.TCPTransportType), fdbtest.WithCDC())
+	assert.NotEqual(t, first.Addr(types.TCPTransportType), second.Addr(types.TCPTransportType))
+	assert.NotEqual(t, first.Dir, second.Dir)
+	assert.Empty(t, first.Addr(types.UDPTransportType))
+
+	var key [32]byte
+	ctx := context.Background()
+	require.NoError(t, first.Client(t, types.TCPTransportType).Set(ctx, key, []byte("value")))
+	exists, err := second.Client(t, types.TCPTransportType).Exists(ctx, key)
+	require.NoError(t, err)
+	assert.False(t, exists)
+
+	// Clients use the first database
+	require.Eventually(t, func() bool {
+		_, err := first.Provider(t, "a").Get(key[:])
+		return err == nil
+	}, 5*time.Second, 20*time.Millisecond)
+	_, err = first.Provider(t, "b").Get(key[:])
+	assert.Error(t, err)
+}
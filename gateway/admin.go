@@ -0,0 +1,21 @@
+package gateway
+
+import (
+	"encoding/json"
+
+	"github.com/unpackdev/fdb/db"
+	"github.com/unpackdev/fdb/messages"
+)
+
+// RegisterAdminOps exposes the gateway status through the admin handlers of the manager's
+// transports. messages.AdminGatewayStatus returns the JSON encoded Status.
+//
+// Parameters:
+//
+//	manager (*db.Manager): The manager dispatching admin requests.
+//	gateway (*Gateway): The gateway of the node.
+func RegisterAdminOps(manager *db.Manager, gateway *Gateway) {
+	manager.RegisterAdminOp(messages.AdminGatewayStatus, func(_ *messages.AdminRequest) ([]byte, error) {
+		return json.Marshal(gateway.Status())
+	})
+}
@@ -0,0 +1,129 @@
+package gateway
+
+import (
+	"container/list"
+	"sync"
+	"time"
+
+	"github.com/unpackdev/fdb/types"
+)
+
+// cacheKey identifies a cached value.
+type cacheKey struct {
+	database types.DbType
+	key      [32]byte
+}
+
+// cacheEntry is a cached value and when it stops being served.
+type cacheEntry struct {
+	key     cacheKey
+	value   []byte
+	expires time.Time
+}
+
+// CacheStatus describes the read cache of a gateway.
+type CacheStatus struct {
+	// Entries is the number of cached keys, expired ones included until they are evicted.
+	Entries int `json:"entries"`
+
+	// Hits is the number of reads served from the cache.
+	Hits uint64 `json:"hits"`
+
+	// Misses is the number of reads forwarded because the key was missing or expired.
+	Misses uint64 `json:"misses"`
+
+	// Evictions is the number of keys evicted to make room for others.
+	Evictions uint64 `json:"evictions"`
+}
+
+// cache is a size-bounded read cache evicting the least recently used keys. Values expire ttl
+// after they were stored.
+type cache struct {
+	// mu guards every field below.
+	mu sync.Mutex
+
+	max     int
+	ttl     time.Duration
+	entries map[cacheKey]*list.Element
+
+	// order holds the entries, most recently used first.
+	order *list.List
+
+	hits, misses, evictions uint64
+}
+
+// newCache creates a cache of at most max keys, each served for ttl.
+func newCache(max int, ttl time.Duration) *cache {
+	return &cache{
+		max:     max,
+		ttl:     ttl,
+		entries: make(map[cacheKey]*list.Element, max),
+		order:   list.New(),
+	}
+}
+
+// get returns the cached value of a key, false if it is missing or expired.
+func (c *cache) get(k cacheKey) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[k]
+	if !ok {
+		c.misses++
+		return nil, false
+	}
+
+	entry := elem.Value.(*cacheEntry)
+	if time.Now().After(entry.expires) {
+		c.order.Remove(elem)
+		delete(c.entries, k)
+		c.misses++
+		return nil, false
+	}
+
+	c.order.MoveToFront(elem)
+	c.hits++
+	return entry.value, true
+}
+
+// put caches the value of a key, evicting the least recently used key when the cache is full.
+// The value must not be modified afterwards.
+func (c *cache) put(k cacheKey, value []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	expires := time.Now().Add(c.ttl)
+	if elem, ok := c.entries[k]; ok {
+		entry := elem.Value.(*cacheEntry)
+		entry.value, entry.expires = value, expires
+		c.order.MoveToFront(elem)
+		return
+	}
+
+	if c.order.Len() >= c.max {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry).key)
+		c.evictions++
+	}
+	c.entries[k] = c.order.PushFront(&cacheEntry{key: k, value: value, expires: expires})
+}
+
+// remove drops the cached value of a key.
+func (c *cache) remove(k cacheKey) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.entries[k]; ok {
+		c.order.Remove(elem)
+		delete(c.entries, k)
+	}
+}
+
+// status returns the size and counters of the cache.
+func (c *cache) status() CacheStatus {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return CacheStatus{Entries: c.order.Len(), Hits: c.hits, Misses: c.misses, Evictions: c.evictions}
+}
@@ -0,0 +1,26 @@
+// Package gateway implements the gateway role of `fdb serve`: a sidecar that stores no data and
+// forwards every request it receives to a remote fdb node, translating between transports. Edge
+// services may, for instance, talk to a local gateway over UDS while the database is reachable
+// only over QUIC.
+//
+// The gateway serves the configured databases on its transports like any node. Reads, writes and
+// deletes are forwarded to the upstream node over TCP or QUIC through a pool of connections,
+// together with the consistency requested by the client. Each forwarded request is given a
+// gateway request ID and tracked until the upstream answers (see Status).
+//
+// An optional read cache serves repeated reads locally. Cached values expire after a TTL, and
+// writes through the gateway update the cache, so reads through the same gateway observe its
+// writes; writes made through other nodes are observed once the cached value expires.
+//
+// Example usage:
+//
+//	gw, err := gateway.NewGateway(cnf.Gateway)
+//	if err != nil {
+//	    log.Fatalf("Failed to create gateway: %v", err)
+//	}
+//	for _, name := range cnf.Gateway.Databases {
+//	    _ = manager.Attach(types.DbType(name), gw.Provider(types.DbType(name)))
+//	    _ = manager.SetProposer(types.DbType(name), gw)
+//	    _ = manager.SetReader(types.DbType(name), gw)
+//	}
+package gateway
@@ -0,0 +1,303 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/unpackdev/fdb/config"
+	"github.com/unpackdev/fdb/db"
+	fdbErrors "github.com/unpackdev/fdb/errors"
+	"github.com/unpackdev/fdb/remote"
+	"github.com/unpackdev/fdb/types"
+	"go.uber.org/zap"
+)
+
+// Request describes a request being forwarded to the upstream node.
+type Request struct {
+	// ID identifies the request within the gateway.
+	ID uint64 `json:"id"`
+
+	// Database is the database the request targets.
+	Database string `json:"database"`
+
+	// Op is the forwarded operation: get, set or delete.
+	Op string `json:"op"`
+
+	// Started is when the request was forwarded.
+	Started time.Time `json:"started"`
+}
+
+// Status describes the upstream node of a gateway and the requests forwarded to it.
+type Status struct {
+	// Upstream is the node requests are forwarded to.
+	Upstream config.ReplicationPeer `json:"upstream"`
+
+	// Databases lists the databases served through the gateway.
+	Databases []string `json:"databases"`
+
+	// Forwarded is the number of requests forwarded to the upstream node.
+	Forwarded uint64 `json:"forwarded"`
+
+	// Failed is the number of forwarded requests that failed, missing keys excluded.
+	Failed uint64 `json:"failed"`
+
+	// InFlight lists the requests waiting for the upstream node, oldest first.
+	InFlight []Request `json:"inFlight"`
+
+	// Cache describes the read cache, nil when it is disabled.
+	Cache *CacheStatus `json:"cache,omitempty"`
+}
+
+// Gateway forwards the requests of the databases it serves to an upstream node. It acts as the
+// db.Provider of every served database, see Provider, and as their db.Proposer and db.Reader,
+// so requests received by any transport, including the consistency they ask for, are forwarded
+// over the upstream transport.
+//
+// The wire protocol carries no request IDs: responses are matched to requests by connection on
+// the inbound side and by connection or QUIC stream on the upstream side. The gateway gives every
+// forwarded request an ID of its own and tracks it until the upstream answers, which ties the
+// inbound request to its upstream exchange in the status and logs.
+type Gateway struct {
+	cnf config.Gateway
+
+	// client holds the pooled connections to the upstream node.
+	client *remote.Client
+
+	// cache serves repeated reads locally, nil when disabled.
+	cache *cache
+
+	// nextID is the ID of the last forwarded request.
+	nextID atomic.Uint64
+
+	// mu guards inFlight.
+	mu       sync.Mutex
+	inFlight map[uint64]Request
+
+	forwarded atomic.Uint64
+	failed    atomic.Uint64
+}
+
+// NewGateway creates a Gateway forwarding to the upstream node of the configuration.
+// Connections are established by the first requests that need them.
+//
+// Example usage:
+//
+//	gw, err := gateway.NewGateway(cnf.Gateway)
+//	if err != nil {
+//	    log.Fatalf("Failed to create gateway: %v", err)
+//	}
+//	defer gw.Close()
+//
+// Parameters:
+//
+//	cnf (config.Gateway): The gateway configuration.
+//
+// Returns:
+//
+//	*Gateway: A new Gateway instance.
+//	error: Returns an error if the configuration is invalid.
+func NewGateway(cnf config.Gateway) (*Gateway, error) {
+	if err := cnf.Validate(); err != nil {
+		return nil, err
+	}
+
+	client, err := remote.New(remote.Options{
+		Transport: cnf.Upstream.Transport,
+		Addr:      cnf.Upstream.Addr,
+		Insecure:  cnf.Upstream.Insecure,
+		PoolSize:  cnf.GetPoolSize(),
+		Timeout:   cnf.GetRequestTimeout(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create client of gateway upstream: %w", err)
+	}
+
+	g := &Gateway{
+		cnf:      cnf,
+		client:   client,
+		inFlight: make(map[uint64]Request),
+	}
+	if cnf.Cache.Enabled {
+		g.cache = newCache(cnf.Cache.GetMaxEntries(), cnf.Cache.GetTTL())
+	}
+	return g, nil
+}
+
+// Provider returns the db.Provider forwarding the requests of the named database upstream.
+func (g *Gateway) Provider(database types.DbType) db.Provider {
+	return &gatewayDb{gateway: g, name: database}
+}
+
+// Status returns the upstream node, the request counters and the requests in flight.
+func (g *Gateway) Status() Status {
+	g.mu.Lock()
+	inFlight := make([]Request, 0, len(g.inFlight))
+	for _, req := range g.inFlight {
+		inFlight = append(inFlight, req)
+	}
+	g.mu.Unlock()
+
+	sort.Slice(inFlight, func(i, j int) bool { return inFlight[i].ID < inFlight[j].ID })
+
+	status := Status{
+		Upstream:  g.cnf.Upstream,
+		Databases: g.cnf.Databases,
+		Forwarded: g.forwarded.Load(),
+		Failed:    g.failed.Load(),
+		InFlight:  inFlight,
+	}
+	if g.cache != nil {
+		cache := g.cache.status()
+		status.Cache = &cache
+	}
+	return status
+}
+
+// Propose forwards a write or delete to the upstream node. It implements db.Proposer; the
+// consistency carried by ctx is forwarded along. Successful writes update the read cache.
+func (g *Gateway) Propose(ctx context.Context, database types.DbType, op types.ChangeOp, key, value []byte) error {
+	k, err := gatewayKey(key)
+	if err != nil {
+		return err
+	}
+
+	id := g.begin(database, op.String())
+	switch op {
+	case types.ChangeSet:
+		err = g.client.Set(ctx, database.String(), k, value)
+	case types.ChangeDelete:
+		err = g.client.Delete(ctx, database.String(), k)
+	default:
+		err = fmt.Errorf("unsupported change operation: %s", op)
+	}
+	g.end(id, err)
+
+	if g.cache != nil {
+		ck := cacheKey{database: database, key: k}
+		if err == nil && op == types.ChangeSet {
+			g.cache.put(ck, append([]byte(nil), value...))
+		} else {
+			// Failed writes may still have been applied upstream
+			g.cache.remove(ck)
+		}
+	}
+	return err
+}
+
+// Read returns the value of a key from the read cache, or from the upstream node. It implements
+// db.Reader; reads asking for a read quorum always go upstream, with the consistency carried by
+// ctx.
+func (g *Gateway) Read(ctx context.Context, database types.DbType, key []byte) ([]byte, error) {
+	k, err := gatewayKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	ck := cacheKey{database: database, key: k}
+	cached := g.cache != nil && db.ConsistencyFromContext(ctx).R == 0
+	if cached {
+		if value, ok := g.cache.get(ck); ok {
+			return value, nil
+		}
+	}
+
+	id := g.begin(database, "get")
+	value, err := g.client.Get(ctx, database.String(), k)
+	g.end(id, err)
+
+	if cached && err == nil {
+		g.cache.put(ck, value)
+	}
+	return value, err
+}
+
+// begin records a request forwarded upstream and returns its ID.
+func (g *Gateway) begin(database types.DbType, op string) uint64 {
+	id := g.nextID.Add(1)
+	g.forwarded.Add(1)
+
+	g.mu.Lock()
+	g.inFlight[id] = Request{ID: id, Database: database.String(), Op: op, Started: time.Now()}
+	g.mu.Unlock()
+	return id
+}
+
+// end records the completion of a forwarded request.
+func (g *Gateway) end(id uint64, err error) {
+	g.mu.Lock()
+	req := g.inFlight[id]
+	delete(g.inFlight, id)
+	g.mu.Unlock()
+
+	if err != nil && !errors.Is(err, fdbErrors.ErrNotFound) {
+		g.failed.Add(1)
+		zap.L().Debug(
+			"Forwarded request failed",
+			zap.Uint64("id", id),
+			zap.String("database", req.Database),
+			zap.String("op", req.Op),
+			zap.Duration("elapsed", time.Since(req.Started)),
+			zap.Error(err),
+		)
+	}
+}
+
+// Close closes the connections to the upstream node.
+func (g *Gateway) Close() error {
+	return g.client.Close()
+}
+
+// gatewayDb is the db.Provider of a database served through the gateway.
+type gatewayDb struct {
+	gateway *Gateway
+	name    types.DbType
+}
+
+// Set forwards the write to the upstream node.
+func (d *gatewayDb) Set(key, value []byte) error {
+	return d.gateway.Propose(context.Background(), d.name, types.ChangeSet, key, value)
+}
+
+// Get reads the key from the read cache or the upstream node.
+func (d *gatewayDb) Get(key []byte) ([]byte, error) {
+	return d.gateway.Read(context.Background(), d.name, key)
+}
+
+// Exists reports whether the upstream node has a value for the key.
+func (d *gatewayDb) Exists(key []byte) (bool, error) {
+	_, err := d.Get(key)
+	if errors.Is(err, fdbErrors.ErrNotFound) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// Delete forwards the delete to the upstream node.
+func (d *gatewayDb) Delete(key []byte) error {
+	return d.gateway.Propose(context.Background(), d.name, types.ChangeDelete, key, nil)
+}
+
+// Close is a no-op, the upstream connections are closed with the gateway.
+func (d *gatewayDb) Close() error {
+	return nil
+}
+
+// Destroy is not supported, databases are managed on the upstream node.
+func (d *gatewayDb) Destroy() error {
+	return fmt.Errorf("database %s cannot be destroyed through the gateway", d.name)
+}
+
+// gatewayKey converts a key to the fixed-size key of the wire protocol.
+func gatewayKey(key []byte) ([32]byte, error) {
+	var k [32]byte
+	if len(key) != len(k) {
+		return k, fmt.Errorf("gateway keys must be %d bytes, got %d", len(k), len(key))
+	}
+	copy(k[:], key)
+	return k, nil
+}
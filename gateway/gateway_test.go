@@ -0,0 +1,142 @@
+package gateway_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/unpackdev/fdb/config"
+	"github.com/unpackdev/fdb/db"
+	fdbErrors "github.com/unpackdev/fdb/errors"
+	"github.com/unpackdev/fdb/fdbtest"
+	"github.com/unpackdev/fdb/gateway"
+	"github.com/unpackdev/fdb/messages"
+	"github.com/unpackdev/fdb/remote"
+	"github.com/unpackdev/fdb/types"
+)
+
+// startUpstream starts an fdb node storing the database served through the gateway.
+func startUpstream(t *testing.T) (db.Provider, string) {
+	server := fdbtest.Start(t, fdbtest.WithTransports(types.TCPTransportType))
+	return server.Provider(t, fdbtest.DefaultDatabase), server.Addr(types.TCPTransportType)
+}
+
+// startGateway creates a gateway of the upstream node, without local MDBX.
+func startGateway(t *testing.T, upstream string, cache config.GatewayCache) *gateway.Gateway {
+	gw, err := gateway.NewGateway(config.Gateway{
+		Enabled:   true,
+		Databases: []string{"fdb"},
+		Upstream:  config.ReplicationPeer{Transport: types.TCPTransportType, Addr: upstream},
+		Cache:     cache,
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = gw.Close() })
+	return gw
+}
+
+func testKey(i int) [32]byte {
+	var key [32]byte
+	copy(key[:], fmt.Sprintf("key-%04d", i))
+	return key
+}
+
+func TestGatewayForwardsRequests(t *testing.T) {
+	upstream, upstreamAddr := startUpstream(t)
+	server := fdbtest.Start(t,
+		fdbtest.WithTransports(types.TCPTransportType),
+		fdbtest.WithConfig(func(cfg *config.Config) {
+			cfg.Mdbx.Nodes = nil
+			cfg.Gateway = config.Gateway{
+				Enabled:   true,
+				Databases: []string{fdbtest.DefaultDatabase},
+				Upstream:  config.ReplicationPeer{Transport: types.TCPTransportType, Addr: upstreamAddr},
+			}
+		}),
+	)
+	gw := server.FDB.GetGateway()
+
+	// The reads probing the instance while it starts are forwarded as well
+	probes := gw.Status().Forwarded
+
+	// Clients talk to the gateway, which forwards to the upstream node
+	client, err := remote.New(remote.Options{Transport: types.TCPTransportType, Addr: server.Addr(types.TCPTransportType)})
+	require.NoError(t, err)
+	defer client.Close()
+
+	key := testKey(1)
+	require.NoError(t, client.Set(context.Background(), "fdb", key, []byte("value")))
+	require.Eventually(t, func() bool {
+		value, err := upstream.Get(key[:])
+		return err == nil && string(value) == "value"
+	}, 5*time.Second, 20*time.Millisecond)
+
+	value, err := client.Get(context.Background(), "fdb", key)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("value"), value)
+
+	require.NoError(t, client.Delete(context.Background(), "fdb", key))
+	require.Eventually(t, func() bool {
+		_, err := upstream.Get(key[:])
+		return err != nil
+	}, 5*time.Second, 20*time.Millisecond)
+
+	missing := testKey(2)
+	_, err = gw.Provider("fdb").Get(missing[:])
+	assert.ErrorIs(t, err, fdbErrors.ErrNotFound)
+
+	status := gw.Status()
+	assert.Equal(t, upstreamAddr, status.Upstream.Addr)
+	assert.Equal(t, probes+4, status.Forwarded)
+	assert.Zero(t, status.Failed)
+	assert.Empty(t, status.InFlight)
+	assert.Nil(t, status.Cache)
+}
+
+func TestGatewayReadCache(t *testing.T) {
+	upstream, upstreamAddr := startUpstream(t)
+	gw := startGateway(t, upstreamAddr, config.GatewayCache{Enabled: true, MaxEntries: 2, TTL: time.Minute})
+	provider := gw.Provider("fdb")
+
+	// Writes through the gateway are read back from the cache before the upstream flushes them
+	key := testKey(1)
+	require.NoError(t, provider.Set(key[:], []byte("first")))
+	value, err := provider.Get(key[:])
+	require.NoError(t, err)
+	assert.Equal(t, []byte("first"), value)
+	assert.Equal(t, uint64(1), gw.Status().Forwarded)
+
+	// Writes made elsewhere are not observed until the cached value expires or is evicted
+	require.Eventually(t, func() bool {
+		value, err := upstream.Get(key[:])
+		return err == nil && string(value) == "first"
+	}, 5*time.Second, 20*time.Millisecond)
+	require.NoError(t, upstream.Set(key[:], []byte("second")))
+	value, err = provider.Get(key[:])
+	require.NoError(t, err)
+	assert.Equal(t, []byte("first"), value)
+
+	for i := 2; i <= 3; i++ {
+		other := testKey(i)
+		require.NoError(t, provider.Set(other[:], []byte("other")))
+	}
+	value, err = provider.Get(key[:])
+	require.NoError(t, err)
+	assert.Equal(t, []byte("second"), value)
+
+	// Reads asking for a read quorum go upstream
+	ctx := db.WithConsistency(context.Background(), messages.Consistency{R: 1})
+	value, err = gw.Read(ctx, "fdb", key[:])
+	require.NoError(t, err)
+	assert.Equal(t, []byte("second"), value)
+
+	require.NoError(t, provider.Delete(key[:]))
+	cache := gw.Status().Cache
+	require.NotNil(t, cache)
+	assert.Equal(t, 1, cache.Entries)
+	assert.Equal(t, uint64(2), cache.Hits)
+	assert.Equal(t, uint64(1), cache.Misses)
+	assert.Equal(t, uint64(2), cache.Evictions)
+}
@@ -0,0 +1,34 @@
+package membership
+
+import (
+	"encoding/json"
+
+	"github.com/unpackdev/fdb/db"
+	"github.com/unpackdev/fdb/messages"
+	"github.com/unpackdev/fdb/types"
+)
+
+// RegisterAdminOps exposes the member list of the node through the admin handlers of the
+// manager's transports. messages.AdminMembers returns every known member as a JSON array,
+// including suspected, failed and departed members until they are reclaimed.
+//
+// Parameters:
+//
+//	manager (*db.Manager): The manager dispatching admin requests.
+//	node (*Node): The local member of the cluster.
+func RegisterAdminOps(manager *db.Manager, node *Node) {
+	manager.RegisterAdminOp(messages.AdminMembers, func(_ *messages.AdminRequest) ([]byte, error) {
+		return json.Marshal(node.Members())
+	})
+}
+
+// DiscoveryResponse returns the answer to a client discovery request: the alive members as a
+// JSON array, prefixed with the response status. Unlike the admin operation it is served
+// even when admin requests are disabled, so clients can find the other nodes of the cluster.
+func (n *Node) DiscoveryResponse() []byte {
+	body, err := json.Marshal(n.AliveMembers())
+	if err != nil {
+		return messages.EncodeStatusResponse(types.StatusError, []byte(err.Error()))
+	}
+	return messages.EncodeStatusResponse(types.StatusOK, body)
+}
@@ -0,0 +1,27 @@
+// Package membership implements gossip-based cluster membership and failure detection for
+// fdb nodes, following the SWIM protocol.
+//
+// Every node runs a Node that gossips over UDP on its own address. A node joins the cluster by
+// exchanging member lists with one of its seeds, and advertises the transports it serves, its
+// databases and its roles (leader, follower, raft, proxy and configured roles) as Meta.
+//
+// Failures are detected by probing: every probe interval one member is pinged, and a member
+// that does not answer directly or through the probes of a few other members is suspected. A
+// suspected member that does not refute the suspicion within the suspicion timeout is declared
+// failed. Member updates travel piggybacked on the probe packets, and the full member list is
+// periodically exchanged with a random member so that partitions heal.
+//
+// The member list is exposed through the members admin operation, and to clients through the
+// members discovery handler of the TCP and QUIC transports. Other subsystems may Subscribe to
+// membership events instead of relying on static configuration.
+//
+// Example usage:
+//
+//	node := membership.NewNode(cnf.Membership, meta)
+//	if err := node.Start(ctx); err != nil {
+//	    log.Fatalf("Failed to start membership: %v", err)
+//	}
+//	node.Subscribe(func(event membership.Event) {
+//	    log.Printf("%s %s", event.Member.Name, event.Type)
+//	})
+package membership
@@ -0,0 +1,121 @@
+package membership
+
+import (
+	"fmt"
+
+	"github.com/unpackdev/fdb/types"
+)
+
+// State is the liveness of a member as seen by the local node.
+type State uint8
+
+const (
+	// StateAlive members answer probes.
+	StateAlive State = iota
+
+	// StateSuspect members missed a probe and are declared failed unless they refute it in time.
+	StateSuspect
+
+	// StateDead members were declared failed.
+	StateDead
+
+	// StateLeft members left the cluster gracefully.
+	StateLeft
+)
+
+// String returns the name of the state.
+func (s State) String() string {
+	switch s {
+	case StateAlive:
+		return "alive"
+	case StateSuspect:
+		return "suspect"
+	case StateDead:
+		return "dead"
+	case StateLeft:
+		return "left"
+	default:
+		return "unknown"
+	}
+}
+
+// MarshalText encodes the state by name, so it reads naturally in JSON payloads.
+func (s State) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// UnmarshalText decodes a state from its name.
+func (s *State) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "alive":
+		*s = StateAlive
+	case "suspect":
+		*s = StateSuspect
+	case "dead":
+		*s = StateDead
+	case "left":
+		*s = StateLeft
+	default:
+		return fmt.Errorf("unknown member state: %s", text)
+	}
+	return nil
+}
+
+// Endpoint is a transport a member serves requests on.
+type Endpoint struct {
+	// Type is the transport type.
+	Type types.TransportType `json:"type"`
+
+	// Addr is the transport address.
+	Addr string `json:"addr"`
+}
+
+// Meta is the information a member advertises about itself.
+type Meta struct {
+	// Transports lists the transports the member serves requests on.
+	Transports []Endpoint `json:"transports"`
+
+	// Databases lists the databases the member serves.
+	Databases []string `json:"databases"`
+
+	// Roles lists the roles of the member, such as leader, follower, raft or proxy.
+	Roles []string `json:"roles"`
+}
+
+// HasRole reports whether the member advertises the given role.
+func (m Meta) HasRole(role string) bool {
+	for _, r := range m.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// Endpoint returns the address of the given transport, empty if the member does not serve it.
+func (m Meta) Endpoint(transport types.TransportType) string {
+	for _, endpoint := range m.Transports {
+		if endpoint.Type == transport {
+			return endpoint.Addr
+		}
+	}
+	return ""
+}
+
+// Member is a node of the cluster as known to the local node.
+type Member struct {
+	// Name is the unique name of the node.
+	Name string `json:"name"`
+
+	// Addr is the gossip address of the node.
+	Addr string `json:"addr"`
+
+	// Incarnation orders the claims about the member: only the member itself increases it,
+	// to refute a suspicion or to announce new metadata.
+	Incarnation uint64 `json:"incarnation"`
+
+	// State is the liveness of the member.
+	State State `json:"state"`
+
+	Meta
+}
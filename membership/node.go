@@ -0,0 +1,777 @@
+package membership
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"net"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/unpackdev/fdb/config"
+	"go.uber.org/zap"
+)
+
+const (
+	// maxPiggyback is the maximum number of updates attached to a single packet.
+	maxPiggyback = 16
+
+	// eventBufferSize is the number of events queued for the subscribers.
+	eventBufferSize = 1024
+)
+
+// EventType identifies a change of the member list.
+type EventType uint8
+
+const (
+	// EventJoin is sent when a member joins, or comes back after being declared failed.
+	EventJoin EventType = iota
+
+	// EventUpdate is sent when a member advertises new metadata.
+	EventUpdate
+
+	// EventFailed is sent when a member is declared failed.
+	EventFailed
+
+	// EventLeave is sent when a member leaves gracefully.
+	EventLeave
+)
+
+// String returns the name of the event type.
+func (e EventType) String() string {
+	switch e {
+	case EventJoin:
+		return "join"
+	case EventUpdate:
+		return "update"
+	case EventFailed:
+		return "failed"
+	case EventLeave:
+		return "leave"
+	default:
+		return "unknown"
+	}
+}
+
+// Event describes a change of the member list.
+type Event struct {
+	Type   EventType
+	Member Member
+}
+
+// memberState is a member together with the local bookkeeping about it.
+type memberState struct {
+	Member
+
+	// changed is when the state of the member last changed.
+	changed time.Time
+
+	// suspicion declares the member failed unless it refutes the suspicion in time.
+	suspicion *time.Timer
+}
+
+// broadcast is a member update waiting to be gossiped.
+type broadcast struct {
+	member    Member
+	transmits int
+}
+
+// Node is the local member of a gossip cluster. It implements the SWIM protocol over UDP:
+//
+//   - **Failure detection**: every probe interval a member is probed with a ping. Without an
+//     acknowledgement within the probe timeout, other members are asked to probe it indirectly.
+//     A member missing both is suspected, and declared failed unless it refutes the suspicion
+//     within the suspicion timeout by announcing a higher incarnation.
+//
+//   - **Dissemination**: member updates are piggybacked on the probe packets, each a number of
+//     times growing with the logarithm of the cluster size. The full member list is periodically
+//     exchanged with a random member, which also joins new nodes through their seeds.
+type Node struct {
+	cnf  config.Membership
+	name string
+	conn *net.UDPConn
+	seq  atomic.Uint64
+
+	// mu guards every field below.
+	mu          sync.Mutex
+	members     map[string]*memberState
+	probeOrder  []string
+	probeIndex  int
+	acks        map[uint64]chan struct{}
+	broadcasts  []*broadcast
+	subscribers []func(Event)
+	joined      chan struct{}
+	leaving     bool
+
+	events chan Event
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewNode creates the local member of the cluster, advertising the given metadata. It does
+// not start gossiping.
+//
+// Example usage:
+//
+//	node := membership.NewNode(cnf.Membership, membership.Meta{Databases: []string{"fdb"}})
+//	if err := node.Start(ctx); err != nil {
+//	    log.Fatalf("Failed to start membership: %v", err)
+//	}
+//	defer node.Shutdown()
+//
+// Parameters:
+//
+//	cnf (config.Membership): The membership configuration.
+//	meta (Meta): The transports, databases and roles advertised by this node.
+//
+// Returns:
+//
+//	*Node: A new Node instance.
+func NewNode(cnf config.Membership, meta Meta) *Node {
+	n := &Node{
+		cnf:     cnf,
+		name:    cnf.NodeName,
+		members: make(map[string]*memberState),
+		acks:    make(map[uint64]chan struct{}),
+		joined:  make(chan struct{}, 1),
+		events:  make(chan Event, eventBufferSize),
+	}
+	n.members[n.name] = &memberState{
+		Member:  Member{Name: n.name, Incarnation: 1, State: StateAlive, Meta: meta},
+		changed: time.Now(),
+	}
+	return n
+}
+
+// Start binds the gossip address, starts probing and contacts the configured seeds. Seeds
+// that cannot be reached are retried every push-pull interval while this node has no peers.
+//
+// Returns:
+//
+//	error: Returns an error if the gossip address cannot be bound or advertised.
+func (n *Node) Start(ctx context.Context) error {
+	bindAddr, err := net.ResolveUDPAddr("udp", n.cnf.BindAddr)
+	if err != nil {
+		return fmt.Errorf("invalid membership bindAddr: %w", err)
+	}
+	conn, err := net.ListenUDP("udp", bindAddr)
+	if err != nil {
+		return fmt.Errorf("failed to bind membership address: %w", err)
+	}
+
+	advertise := n.cnf.AdvertiseAddr
+	if advertise == "" {
+		local := conn.LocalAddr().(*net.UDPAddr)
+		if local.IP.IsUnspecified() {
+			_ = conn.Close()
+			return fmt.Errorf("membership advertiseAddr must be set when binding a wildcard address")
+		}
+		advertise = local.String()
+	}
+
+	n.mu.Lock()
+	n.conn = conn
+	n.members[n.name].Addr = advertise
+	n.mu.Unlock()
+
+	ctx, n.cancel = context.WithCancel(ctx)
+	n.wg.Add(4)
+	go n.readLoop()
+	go n.probeLoop(ctx)
+	go n.pushPullLoop(ctx)
+	go n.eventLoop(ctx)
+
+	zap.L().Info("Membership started", zap.String("node", n.name), zap.String("addr", advertise))
+	return nil
+}
+
+// Join contacts the given gossip addresses and exchanges member lists with them. It returns
+// once one of them answered.
+//
+// Parameters:
+//
+//	ctx (context.Context): Bounds how long to wait for an answer.
+//	addrs ([]string): Gossip addresses of existing members.
+//
+// Returns:
+//
+//	error: Returns an error if no address answered before ctx is done.
+func (n *Node) Join(ctx context.Context, addrs ...string) error {
+	// Answers to earlier attempts must not satisfy this one
+	select {
+	case <-n.joined:
+	default:
+	}
+
+	for _, addr := range addrs {
+		n.sendSync(addr, packetSync)
+	}
+
+	select {
+	case <-n.joined:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("no member answered the join: %w", ctx.Err())
+	}
+}
+
+// Leave announces that this node leaves the cluster, so the other members do not report it
+// as failed. The node stops answering probes; call Shutdown afterwards.
+func (n *Node) Leave() {
+	n.mu.Lock()
+	n.leaving = true
+	self := n.members[n.name]
+	self.Incarnation++
+	self.State = StateLeft
+	update := self.Member
+
+	var peers []string
+	for _, m := range n.members {
+		if m.Name != n.name && m.State == StateAlive {
+			peers = append(peers, m.Addr)
+		}
+	}
+	n.mu.Unlock()
+
+	for _, addr := range peers {
+		n.send(addr, &packet{Type: packetGossip, Members: []Member{update}})
+	}
+	zap.L().Info("Left the cluster", zap.String("node", n.name))
+}
+
+// Shutdown stops gossiping and closes the gossip address without notifying the other members.
+func (n *Node) Shutdown() error {
+	if n.cancel == nil {
+		return nil
+	}
+	n.cancel()
+	err := n.conn.Close()
+	n.wg.Wait()
+	return err
+}
+
+// Name returns the name of this node.
+func (n *Node) Name() string {
+	return n.name
+}
+
+// LocalMember returns this node as advertised to the other members.
+func (n *Node) LocalMember() Member {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	return copyMember(n.members[n.name].Member)
+}
+
+// Members returns every known member, including failed and departed ones until they are
+// reclaimed, sorted by name.
+func (n *Node) Members() []Member {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	members := make([]Member, 0, len(n.members))
+	for _, m := range n.members {
+		members = append(members, copyMember(m.Member))
+	}
+	sort.Slice(members, func(i, j int) bool {
+		return members[i].Name < members[j].Name
+	})
+	return members
+}
+
+// AliveMembers returns the members currently considered alive, sorted by name.
+func (n *Node) AliveMembers() []Member {
+	members := n.Members()
+	alive := members[:0]
+	for _, m := range members {
+		if m.State == StateAlive {
+			alive = append(alive, m)
+		}
+	}
+	return alive
+}
+
+// SetMeta changes the metadata advertised by this node and gossips it to the other members.
+func (n *Node) SetMeta(meta Meta) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	self := n.members[n.name]
+	self.Meta = meta
+	self.Incarnation++
+	n.enqueue(self.Member)
+}
+
+// Subscribe registers fn to be called for every change of the member list. Events are
+// delivered in order from a single goroutine; fn must not block.
+func (n *Node) Subscribe(fn func(Event)) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.subscribers = append(n.subscribers, fn)
+}
+
+// readLoop handles the packets received on the gossip address until it is closed.
+func (n *Node) readLoop() {
+	defer n.wg.Done()
+
+	buf := make([]byte, maxPacketSize)
+	for {
+		size, from, err := n.conn.ReadFromUDP(buf)
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			zap.L().Warn("Failed to read gossip packet", zap.Error(err))
+			continue
+		}
+
+		p, err := decodePacket(buf[:size])
+		if err != nil {
+			zap.L().Debug("Dropping invalid gossip packet", zap.String("from", from.String()), zap.Error(err))
+			continue
+		}
+		n.handle(p, from)
+	}
+}
+
+// handle processes a single packet received from the given address.
+func (n *Node) handle(p *packet, from *net.UDPAddr) {
+	n.mu.Lock()
+	for _, update := range p.Members {
+		n.apply(update)
+	}
+	leaving := n.leaving
+	n.mu.Unlock()
+
+	switch p.Type {
+	case packetPing:
+		if leaving || (p.Target != "" && p.Target != n.name) {
+			return
+		}
+		n.send(from.String(), &packet{Type: packetAck, Seq: p.Seq})
+
+	case packetPingReq:
+		go n.probeFor(p, from.String())
+
+	case packetAck:
+		n.mu.Lock()
+		if ack, ok := n.acks[p.Seq]; ok {
+			select {
+			case ack <- struct{}{}:
+			default:
+			}
+		}
+		n.mu.Unlock()
+
+	case packetSync:
+		n.sendSync(from.String(), packetSyncReply)
+
+	case packetSyncReply:
+		select {
+		case n.joined <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// probeFor probes the target of an indirect probe request and acknowledges on its behalf.
+func (n *Node) probeFor(req *packet, requester string) {
+	seq := n.seq.Add(1)
+	ack := n.expectAck(seq)
+	defer n.forgetAck(seq)
+
+	n.send(req.Addr, &packet{Type: packetPing, Seq: seq, Target: req.Target})
+
+	timer := time.NewTimer(n.cnf.GetProbeTimeout())
+	defer timer.Stop()
+
+	select {
+	case <-ack:
+		n.send(requester, &packet{Type: packetAck, Seq: req.Seq})
+	case <-timer.C:
+	}
+}
+
+// probeLoop probes a member every probe interval and reclaims departed members.
+func (n *Node) probeLoop(ctx context.Context) {
+	defer n.wg.Done()
+
+	ticker := time.NewTicker(n.cnf.GetProbeInterval())
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			n.probe(ctx)
+			n.reclaim()
+		}
+	}
+}
+
+// probe runs a single SWIM probe round against the next member.
+func (n *Node) probe(ctx context.Context) {
+	target, ok := n.nextProbeTarget()
+	if !ok {
+		return
+	}
+
+	seq := n.seq.Add(1)
+	ack := n.expectAck(seq)
+	defer n.forgetAck(seq)
+
+	n.send(target.Addr, &packet{Type: packetPing, Seq: seq, Target: target.Name})
+	if waitAck(ctx, ack, n.cnf.GetProbeTimeout()) {
+		return
+	}
+
+	// Another path to the member rules out a problem between the two nodes only
+	for _, helper := range n.randomMembers(n.cnf.GetIndirectChecks(), target.Name) {
+		n.send(helper.Addr, &packet{Type: packetPingReq, Seq: seq, Target: target.Name, Addr: target.Addr})
+	}
+	if waitAck(ctx, ack, n.cnf.GetProbeInterval()-n.cnf.GetProbeTimeout()) {
+		return
+	}
+	if ctx.Err() != nil {
+		return
+	}
+
+	zap.L().Debug("Member missed a probe", zap.String("member", target.Name))
+	n.mu.Lock()
+	n.apply(Member{Name: target.Name, Incarnation: target.Incarnation, State: StateSuspect})
+	n.mu.Unlock()
+}
+
+// nextProbeTarget returns the next member to probe. Members are probed in a random order,
+// each once per round.
+func (n *Node) nextProbeTarget() (Member, bool) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	for attempts := 0; attempts < 2; attempts++ {
+		for n.probeIndex < len(n.probeOrder) {
+			m, ok := n.members[n.probeOrder[n.probeIndex]]
+			n.probeIndex++
+			if ok && m.Name != n.name && (m.State == StateAlive || m.State == StateSuspect) {
+				return m.Member, true
+			}
+		}
+
+		// Start a new round
+		n.probeOrder = n.probeOrder[:0]
+		for name := range n.members {
+			n.probeOrder = append(n.probeOrder, name)
+		}
+		rand.Shuffle(len(n.probeOrder), func(i, j int) {
+			n.probeOrder[i], n.probeOrder[j] = n.probeOrder[j], n.probeOrder[i]
+		})
+		n.probeIndex = 0
+	}
+	return Member{}, false
+}
+
+// randomMembers returns up to k random alive members other than this node and exclude.
+func (n *Node) randomMembers(k int, exclude string) []Member {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	var candidates []Member
+	for _, m := range n.members {
+		if m.Name != n.name && m.Name != exclude && m.State == StateAlive {
+			candidates = append(candidates, m.Member)
+		}
+	}
+	rand.Shuffle(len(candidates), func(i, j int) {
+		candidates[i], candidates[j] = candidates[j], candidates[i]
+	})
+	if len(candidates) > k {
+		candidates = candidates[:k]
+	}
+	return candidates
+}
+
+// pushPullLoop periodically exchanges the full member list with a random member, or with
+// the seeds while this node has no peers.
+func (n *Node) pushPullLoop(ctx context.Context) {
+	defer n.wg.Done()
+
+	n.pushPull()
+
+	ticker := time.NewTicker(n.cnf.GetPushPullInterval())
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			n.pushPull()
+		}
+	}
+}
+
+// pushPull runs a single full member list exchange.
+func (n *Node) pushPull() {
+	peers := n.randomMembers(1, "")
+	if len(peers) > 0 {
+		n.sendSync(peers[0].Addr, packetSync)
+		return
+	}
+	for _, seed := range n.cnf.Seeds {
+		n.sendSync(seed, packetSync)
+	}
+}
+
+// reclaim forgets the failed and departed members after the reclaim timeout.
+func (n *Node) reclaim() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	for name, m := range n.members {
+		if name != n.name && m.State >= StateDead && time.Since(m.changed) > n.cnf.GetReclaimTimeout() {
+			delete(n.members, name)
+		}
+	}
+}
+
+// apply merges a member update into the member list, following the SWIM precedence rules:
+// an alive claim overrides older incarnations, a suspicion or failure overrides an alive
+// claim of the same or an older incarnation. Accepted updates are gossiped further. The
+// caller must hold mu.
+func (n *Node) apply(update Member) {
+	if update.Name == n.name {
+		n.refute(update)
+		return
+	}
+
+	local, known := n.members[update.Name]
+	switch update.State {
+	case StateAlive:
+		if known && update.Incarnation <= local.Incarnation {
+			return
+		}
+
+		event := EventUpdate
+		if !known || local.State >= StateDead {
+			event = EventJoin
+		}
+		if !known {
+			local = &memberState{}
+			n.members[update.Name] = local
+		}
+		stopSuspicion(local)
+		local.Member = copyMember(update)
+		local.changed = time.Now()
+		n.notify(event, local.Member)
+
+	case StateSuspect:
+		if !known || update.Incarnation < local.Incarnation || local.State != StateAlive {
+			return
+		}
+		local.Incarnation = update.Incarnation
+		local.State = StateSuspect
+		local.changed = time.Now()
+
+		incarnation := update.Incarnation
+		local.suspicion = time.AfterFunc(n.cnf.GetSuspicionTimeout(), func() {
+			n.mu.Lock()
+			defer n.mu.Unlock()
+
+			if m, ok := n.members[update.Name]; ok && m.State == StateSuspect && m.Incarnation == incarnation {
+				zap.L().Warn("Member failed", zap.String("member", m.Name), zap.String("addr", m.Addr))
+				n.apply(Member{Name: m.Name, Incarnation: incarnation, State: StateDead})
+			}
+		})
+
+	case StateDead, StateLeft:
+		if !known || update.Incarnation < local.Incarnation || local.State >= StateDead {
+			return
+		}
+		stopSuspicion(local)
+		local.Incarnation = update.Incarnation
+		local.State = update.State
+		local.changed = time.Now()
+
+		event := EventFailed
+		if update.State == StateLeft {
+			event = EventLeave
+		}
+		n.notify(event, local.Member)
+
+	default:
+		return
+	}
+
+	n.enqueue(local.Member)
+}
+
+// refute answers a claim about this node. Suspicions and failures are refuted by announcing a
+// higher incarnation, unless the node is leaving. The caller must hold mu.
+func (n *Node) refute(update Member) {
+	self := n.members[n.name]
+	if n.leaving || update.Incarnation < self.Incarnation {
+		return
+	}
+	if update.State == StateAlive && update.Incarnation == self.Incarnation {
+		return
+	}
+
+	self.Incarnation = update.Incarnation + 1
+	n.enqueue(self.Member)
+	zap.L().Info("Refuting member claim", zap.String("state", update.State.String()), zap.Uint64("incarnation", self.Incarnation))
+}
+
+// enqueue queues a member update for gossip, replacing an older update of the same member.
+// The caller must hold mu.
+func (n *Node) enqueue(m Member) {
+	for i, b := range n.broadcasts {
+		if b.member.Name == m.Name {
+			n.broadcasts = append(n.broadcasts[:i], n.broadcasts[i+1:]...)
+			break
+		}
+	}
+	n.broadcasts = append(n.broadcasts, &broadcast{member: copyMember(m)})
+}
+
+// piggyback takes the updates to attach to the next packet, least gossiped first.
+func (n *Node) piggyback() []Member {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if len(n.broadcasts) == 0 {
+		return nil
+	}
+
+	sort.SliceStable(n.broadcasts, func(i, j int) bool {
+		return n.broadcasts[i].transmits < n.broadcasts[j].transmits
+	})
+	limit := n.cnf.GetRetransmitMult() * int(math.Ceil(math.Log10(float64(len(n.members)+1))))
+
+	var updates []Member
+	kept := n.broadcasts[:0]
+	for i, b := range n.broadcasts {
+		if i < maxPiggyback {
+			updates = append(updates, b.member)
+			b.transmits++
+		}
+		if b.transmits < limit {
+			kept = append(kept, b)
+		}
+	}
+	n.broadcasts = kept
+	return updates
+}
+
+// sendSync sends the full member list.
+func (n *Node) sendSync(addr string, kind packetType) {
+	n.send(addr, &packet{Type: kind, Members: n.Members()})
+}
+
+// send piggybacks pending updates on the packet and sends it to addr.
+func (n *Node) send(addr string, p *packet) {
+	p.From = n.name
+	if p.Type != packetSync && p.Type != packetSyncReply {
+		p.Members = append(p.Members, n.piggyback()...)
+	}
+
+	data, err := p.encode()
+	if err != nil {
+		zap.L().Warn("Failed to encode gossip packet", zap.Error(err))
+		return
+	}
+	udpAddr, err := net.ResolveUDPAddr("udp", addr)
+	if err != nil {
+		zap.L().Warn("Invalid gossip address", zap.String("addr", addr), zap.Error(err))
+		return
+	}
+	if _, err := n.conn.WriteToUDP(data, udpAddr); err != nil && !errors.Is(err, net.ErrClosed) {
+		zap.L().Debug("Failed to send gossip packet", zap.String("addr", addr), zap.Error(err))
+	}
+}
+
+// expectAck registers a channel receiving the acknowledgement of a probe.
+func (n *Node) expectAck(seq uint64) chan struct{} {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	ack := make(chan struct{}, 1)
+	n.acks[seq] = ack
+	return ack
+}
+
+// forgetAck removes the acknowledgement channel of a finished probe.
+func (n *Node) forgetAck(seq uint64) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	delete(n.acks, seq)
+}
+
+// notify queues an event for the subscribers. The caller must hold mu.
+func (n *Node) notify(t EventType, m Member) {
+	zap.L().Info("Member "+t.String(), zap.String("member", m.Name), zap.String("addr", m.Addr))
+
+	select {
+	case n.events <- Event{Type: t, Member: copyMember(m)}:
+	default:
+		zap.L().Warn("Dropping membership event, subscribers are too slow", zap.String("member", m.Name))
+	}
+}
+
+// eventLoop delivers the events to the subscribers until the node shuts down.
+func (n *Node) eventLoop(ctx context.Context) {
+	defer n.wg.Done()
+
+	for {
+		select {
+		case event := <-n.events:
+			n.mu.Lock()
+			subscribers := append([]func(Event){}, n.subscribers...)
+			n.mu.Unlock()
+
+			for _, fn := range subscribers {
+				fn(event)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// waitAck waits up to timeout for an acknowledgement.
+func waitAck(ctx context.Context, ack chan struct{}, timeout time.Duration) bool {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case <-ack:
+		return true
+	case <-timer.C:
+		return false
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// stopSuspicion cancels the pending failure declaration of a member.
+func stopSuspicion(m *memberState) {
+	if m.suspicion != nil {
+		m.suspicion.Stop()
+		m.suspicion = nil
+	}
+}
+
+// copyMember returns a copy of m that shares no slices with it.
+func copyMember(m Member) Member {
+	c := m
+	c.Transports = append([]Endpoint(nil), m.Transports...)
+	c.Databases = append([]string(nil), m.Databases...)
+	c.Roles = append([]string(nil), m.Roles...)
+	return c
+}
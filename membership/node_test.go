@@ -0,0 +1,167 @@
+package membership
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/unpackdev/fdb/config"
+	"github.com/unpackdev/fdb/types"
+)
+
+// testEndpoint is the TCP endpoint advertised by the members. It is never dialed, so it is an
+// address of the documentation range rather than a port a test could be listening on.
+const testEndpoint = "192.0.2.1:5011"
+
+// startNode starts a member with fast probing, joining through the given seeds.
+func startNode(t *testing.T, name string, seeds ...string) *Node {
+	t.Helper()
+
+	node := NewNode(config.Membership{
+		Enabled:          true,
+		NodeName:         name,
+		BindAddr:         "127.0.0.1:0",
+		Seeds:            seeds,
+		ProbeInterval:    100 * time.Millisecond,
+		ProbeTimeout:     40 * time.Millisecond,
+		SuspicionTimeout: 500 * time.Millisecond,
+		PushPullInterval: 300 * time.Millisecond,
+	}, Meta{
+		Transports: []Endpoint{{Type: types.TCPTransportType, Addr: testEndpoint}},
+		Databases:  []string{"fdb"},
+		Roles:      []string{"test"},
+	})
+	require.NoError(t, node.Start(context.Background()))
+	return node
+}
+
+// stateOf returns the state of the named member as seen by node.
+func stateOf(node *Node, name string) (State, bool) {
+	for _, m := range node.Members() {
+		if m.Name == name {
+			return m.State, true
+		}
+	}
+	return 0, false
+}
+
+func TestNodeConvergesThroughSeed(t *testing.T) {
+	seed := startNode(t, "node-1")
+	defer seed.Shutdown()
+
+	second := startNode(t, "node-2", seed.LocalMember().Addr)
+	defer second.Shutdown()
+	third := startNode(t, "node-3", seed.LocalMember().Addr)
+	defer third.Shutdown()
+
+	for _, node := range []*Node{seed, second, third} {
+		require.Eventually(t, func() bool {
+			return len(node.AliveMembers()) == 3
+		}, 5*time.Second, 20*time.Millisecond, "%s did not see every member", node.Name())
+	}
+
+	for _, m := range third.Members() {
+		assert.Equal(t, []string{"fdb"}, m.Databases)
+		assert.True(t, m.HasRole("test"))
+		assert.Equal(t, testEndpoint, m.Endpoint(types.TCPTransportType))
+	}
+
+	var members []Member
+	response := second.DiscoveryResponse()
+	require.Equal(t, byte(types.StatusOK), response[0])
+	require.NoError(t, json.Unmarshal(response[1:], &members))
+	assert.Len(t, members, 3)
+}
+
+func TestNodeDetectsFailure(t *testing.T) {
+	seed := startNode(t, "node-1")
+	defer seed.Shutdown()
+	second := startNode(t, "node-2", seed.LocalMember().Addr)
+	defer second.Shutdown()
+	third := startNode(t, "node-3", seed.LocalMember().Addr)
+
+	var mu sync.Mutex
+	var failed []string
+	seed.Subscribe(func(event Event) {
+		if event.Type == EventFailed {
+			mu.Lock()
+			failed = append(failed, event.Member.Name)
+			mu.Unlock()
+		}
+	})
+
+	require.Eventually(t, func() bool {
+		return len(seed.AliveMembers()) == 3 && len(second.AliveMembers()) == 3
+	}, 5*time.Second, 20*time.Millisecond)
+
+	// Stopping without leaving looks like a crash
+	require.NoError(t, third.Shutdown())
+
+	for _, node := range []*Node{seed, second} {
+		require.Eventually(t, func() bool {
+			state, _ := stateOf(node, "node-3")
+			return state == StateDead
+		}, 5*time.Second, 20*time.Millisecond, "%s did not declare node-3 failed", node.Name())
+	}
+
+	require.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(failed) == 1 && failed[0] == "node-3"
+	}, time.Second, 20*time.Millisecond)
+
+	state, _ := stateOf(seed, "node-2")
+	assert.Equal(t, StateAlive, state)
+}
+
+func TestNodeLeave(t *testing.T) {
+	seed := startNode(t, "node-1")
+	defer seed.Shutdown()
+	second := startNode(t, "node-2", seed.LocalMember().Addr)
+
+	require.Eventually(t, func() bool {
+		return len(seed.AliveMembers()) == 2
+	}, 5*time.Second, 20*time.Millisecond)
+
+	second.Leave()
+	require.NoError(t, second.Shutdown())
+
+	require.Eventually(t, func() bool {
+		state, _ := stateOf(seed, "node-2")
+		return state == StateLeft
+	}, 2*time.Second, 20*time.Millisecond)
+}
+
+func TestNodeRefutesSuspicion(t *testing.T) {
+	seed := startNode(t, "node-1")
+	defer seed.Shutdown()
+	second := startNode(t, "node-2", seed.LocalMember().Addr)
+	defer second.Shutdown()
+
+	require.Eventually(t, func() bool {
+		return len(seed.AliveMembers()) == 2 && len(second.AliveMembers()) == 2
+	}, 5*time.Second, 20*time.Millisecond)
+
+	incarnation := second.LocalMember().Incarnation
+	seed.mu.Lock()
+	seed.apply(Member{Name: "node-2", Incarnation: incarnation, State: StateSuspect})
+	seed.mu.Unlock()
+
+	require.Eventually(t, func() bool {
+		return second.LocalMember().Incarnation > incarnation
+	}, 2*time.Second, 20*time.Millisecond)
+
+	require.Eventually(t, func() bool {
+		state, _ := stateOf(seed, "node-2")
+		return state == StateAlive
+	}, 2*time.Second, 20*time.Millisecond)
+
+	// The suspicion must not turn into a failure once refuted
+	time.Sleep(700 * time.Millisecond)
+	state, _ := stateOf(seed, "node-2")
+	assert.Equal(t, StateAlive, state)
+}
@@ -0,0 +1,77 @@
+package membership
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// maxPacketSize bounds a single gossip packet. The full member list is exchanged in one
+// packet, which limits clusters to a few hundred members.
+const maxPacketSize = 64 * 1024
+
+// packetType identifies the purpose of a gossip packet.
+type packetType string
+
+const (
+	// packetPing probes the target, which answers with packetAck.
+	packetPing packetType = "ping"
+
+	// packetPingReq asks the receiver to probe the target on behalf of the sender.
+	packetPingReq packetType = "ping-req"
+
+	// packetAck acknowledges a probe, on behalf of the target for indirect probes.
+	packetAck packetType = "ack"
+
+	// packetSync carries the sender's full member list, answered with packetSyncReply.
+	packetSync packetType = "sync"
+
+	// packetSyncReply carries the receiver's full member list in answer to packetSync.
+	packetSyncReply packetType = "sync-reply"
+
+	// packetGossip only carries updates and is not answered.
+	packetGossip packetType = "gossip"
+)
+
+// packet is a single gossip message. Every packet piggybacks the latest member updates.
+type packet struct {
+	Type packetType `json:"type"`
+
+	// Seq correlates probes with their acknowledgements.
+	Seq uint64 `json:"seq,omitempty"`
+
+	// From is the name of the sender, or of the probed member for indirect acknowledgements.
+	From string `json:"from"`
+
+	// Target is the name of the probed member, so a node reusing the address does not answer.
+	Target string `json:"target,omitempty"`
+
+	// Addr is the gossip address of the member to probe indirectly.
+	Addr string `json:"addr,omitempty"`
+
+	// Members holds member updates, or the full member list for sync packets.
+	Members []Member `json:"members,omitempty"`
+}
+
+// encode encodes the packet, failing if it does not fit a single datagram.
+func (p *packet) encode() ([]byte, error) {
+	data, err := json.Marshal(p)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxPacketSize {
+		return nil, fmt.Errorf("gossip packet too large: %d bytes", len(data))
+	}
+	return data, nil
+}
+
+// decodePacket decodes a gossip packet.
+func decodePacket(data []byte) (*packet, error) {
+	var p packet
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, err
+	}
+	if p.From == "" {
+		return nil, fmt.Errorf("gossip packet without sender")
+	}
+	return &p, nil
+}
@@ -0,0 +1,139 @@
+package messages
+
+import (
+	"encoding/binary"
+	"fmt"
+	"github.com/unpackdev/fdb/types"
+)
+
+// AdminOp identifies the administrative operation carried by an AdminRequest
+type AdminOp byte
+
+// Define the admin operations as 1-byte constants
+const (
+	AdminListDbs  AdminOp = 'L' // List known databases
+	AdminCreateDb AdminOp = 'C' // Create a database, payload is the JSON encoded config.MdbxNode
+	AdminOpenDb   AdminOp = 'O' // Open a previously closed database
+	AdminCloseDb  AdminOp = 'X' // Close an open database
+	AdminDropDb   AdminOp = 'D' // Close a runtime database and remove its files
+
+	AdminReplicationStatus AdminOp = 'R' // Replication status of the node, returned as JSON
+	AdminPromote           AdminOp = 'P' // Stop following the leader and accept writes
+	AdminFollow            AdminOp = 'F' // Follow a leader, payload is the JSON encoded follow request
+
+	AdminRaftStatus AdminOp = 'T' // Raft group status of the node, returned as JSON
+	AdminRaftJoin   AdminOp = 'J' // Add a member to the Raft group, payload is the JSON encoded member
+	AdminRaftLeave  AdminOp = 'V' // Remove a member from the Raft group, payload is the JSON encoded member
+
+	AdminShardMap  AdminOp = 'M' // Shard map of a sharding proxy and its rebalance state, returned as JSON
+	AdminRebalance AdminOp = 'B' // Install a new shard map and move keys, payload is the JSON encoded map
+
+	AdminMembers AdminOp = 'G' // Gossip member list of the node, returned as JSON
+
+	AdminRepair       AdminOp = 'E' // Repair a database against a peer, payload is the JSON encoded repair request
+	AdminRepairStatus AdminOp = 'Q' // Anti-entropy repair runs of the node, returned as JSON
+
+	AdminQuorumStatus AdminOp = 'U' // Quorum replication settings and pending hints of the node, returned as JSON
+
+	AdminGatewayStatus AdminOp = 'W' // Gateway upstream and forwarded requests of the node, returned as JSON
+
+	adminHeaderLen = 1 + 1 + 2
+)
+
+// String returns a human-readable name of the admin operation
+func (o AdminOp) String() string {
+	switch o {
+	case AdminListDbs:
+		return "list"
+	case AdminCreateDb:
+		return "create"
+	case AdminOpenDb:
+		return "open"
+	case AdminCloseDb:
+		return "close"
+	case AdminDropDb:
+		return "drop"
+	case AdminReplicationStatus:
+		return "replication-status"
+	case AdminPromote:
+		return "promote"
+	case AdminFollow:
+		return "follow"
+	case AdminRaftStatus:
+		return "raft-status"
+	case AdminRaftJoin:
+		return "raft-join"
+	case AdminRaftLeave:
+		return "raft-leave"
+	case AdminShardMap:
+		return "shard-map"
+	case AdminRebalance:
+		return "rebalance"
+	case AdminMembers:
+		return "members"
+	case AdminRepair:
+		return "repair"
+	case AdminRepairStatus:
+		return "repair-status"
+	case AdminQuorumStatus:
+		return "quorum-status"
+	case AdminGatewayStatus:
+		return "gateway-status"
+	default:
+		return "unknown"
+	}
+}
+
+// AdminRequest represents an administrative request frame:
+// handler (1 byte) | op (1 byte) | name length (2 bytes) | name | payload
+type AdminRequest struct {
+	Op      AdminOp // The admin operation (1 byte)
+	Name    string  // The database the operation applies to
+	Payload []byte  // Operation specific payload
+}
+
+// Encode encodes the AdminRequest into a newly allocated byte slice.
+func (r *AdminRequest) Encode() ([]byte, error) {
+	if len(r.Name) > 0xFFFF {
+		return nil, fmt.Errorf("database name too long: %d bytes", len(r.Name))
+	}
+
+	buf := make([]byte, adminHeaderLen+len(r.Name)+len(r.Payload))
+	buf[0] = byte(types.AdminHandlerType)
+	buf[1] = byte(r.Op)
+	binary.BigEndian.PutUint16(buf[2:4], uint16(len(r.Name)))
+	copy(buf[adminHeaderLen:], r.Name)
+	copy(buf[adminHeaderLen+len(r.Name):], r.Payload)
+
+	return buf, nil
+}
+
+// DecodeAdminRequest decodes a byte slice into an AdminRequest. The payload reuses the
+// provided slice instead of allocating.
+func DecodeAdminRequest(data []byte) (*AdminRequest, error) {
+	if len(data) < adminHeaderLen {
+		return nil, fmt.Errorf("data too short, must be at least %d bytes", adminHeaderLen)
+	}
+	if types.HandlerType(data[0]) != types.AdminHandlerType {
+		return nil, fmt.Errorf("invalid admin handler byte: %v", data[0])
+	}
+
+	nameLen := int(binary.BigEndian.Uint16(data[2:4]))
+	if len(data[adminHeaderLen:]) < nameLen {
+		return nil, fmt.Errorf("name length mismatch, expected %d bytes but got %d bytes", nameLen, len(data[adminHeaderLen:]))
+	}
+
+	return &AdminRequest{
+		Op:      AdminOp(data[1]),
+		Name:    string(data[adminHeaderLen : adminHeaderLen+nameLen]),
+		Payload: data[adminHeaderLen+nameLen:],
+	}, nil
+}
+
+// EncodeStatusResponse encodes a response frame: status (1 byte) | body
+func EncodeStatusResponse(status types.ResponseStatus, body []byte) []byte {
+	buf := make([]byte, 1+len(body))
+	buf[0] = byte(status)
+	copy(buf[1:], body)
+	return buf
+}
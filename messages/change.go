@@ -0,0 +1,107 @@
+package messages
+
+import (
+	"encoding/binary"
+	"fmt"
+	"github.com/unpackdev/fdb/types"
+)
+
+const (
+	changeHeaderLen    = 8 + 1 + 8 + 2
+	subscribeLen       = 1 + 8
+	streamFrameHeadLen = 4 + 1
+)
+
+// Change represents a single change data capture entry. The encoded form is both the
+// value stored in the change log and the body of a subscription stream frame:
+// seq (8 bytes) | op (1 byte) | timestamp (8 bytes) | key length (2 bytes) | key | value
+type Change struct {
+	Seq       uint64         // Monotonically increasing sequence number
+	Op        types.ChangeOp // The recorded mutation
+	Timestamp int64          // Commit time in Unix nanoseconds
+	Key       []byte         // The mutated key
+	Value     []byte         // The new value, empty for deletes
+}
+
+// Encode encodes the Change into a newly allocated byte slice.
+func (c *Change) Encode() ([]byte, error) {
+	if len(c.Key) > 0xFFFF {
+		return nil, fmt.Errorf("change key too long: %d bytes", len(c.Key))
+	}
+
+	buf := make([]byte, changeHeaderLen+len(c.Key)+len(c.Value))
+	binary.BigEndian.PutUint64(buf[0:8], c.Seq)
+	buf[8] = byte(c.Op)
+	binary.BigEndian.PutUint64(buf[9:17], uint64(c.Timestamp))
+	binary.BigEndian.PutUint16(buf[17:19], uint16(len(c.Key)))
+	copy(buf[changeHeaderLen:], c.Key)
+	copy(buf[changeHeaderLen+len(c.Key):], c.Value)
+
+	return buf, nil
+}
+
+// DecodeChange decodes a byte slice into a Change. Key and value reuse the provided slice.
+func DecodeChange(data []byte) (*Change, error) {
+	if len(data) < changeHeaderLen {
+		return nil, fmt.Errorf("data too short, must be at least %d bytes", changeHeaderLen)
+	}
+
+	keyLen := int(binary.BigEndian.Uint16(data[17:19]))
+	if len(data[changeHeaderLen:]) < keyLen {
+		return nil, fmt.Errorf("key length mismatch, expected %d bytes but got %d bytes", keyLen, len(data[changeHeaderLen:]))
+	}
+
+	return &Change{
+		Seq:       binary.BigEndian.Uint64(data[0:8]),
+		Op:        types.ChangeOp(data[8]),
+		Timestamp: int64(binary.BigEndian.Uint64(data[9:17])),
+		Key:       data[changeHeaderLen : changeHeaderLen+keyLen],
+		Value:     data[changeHeaderLen+keyLen:],
+	}, nil
+}
+
+// SubscribeRequest asks the server to stream the change log starting at the given sequence:
+// handler (1 byte) | from (8 bytes)
+//
+// From zero starts at the oldest retained change. To resume after a disconnect, subscribe
+// again from the sequence following the last change received.
+type SubscribeRequest struct {
+	From uint64 // First sequence number to deliver
+}
+
+// Encode encodes the SubscribeRequest into a newly allocated byte slice.
+func (r *SubscribeRequest) Encode() []byte {
+	buf := make([]byte, subscribeLen)
+	buf[0] = byte(types.SubscribeHandlerType)
+	binary.BigEndian.PutUint64(buf[1:9], r.From)
+	return buf
+}
+
+// DecodeSubscribeRequest decodes a byte slice into a SubscribeRequest.
+func DecodeSubscribeRequest(data []byte) (*SubscribeRequest, error) {
+	if len(data) < subscribeLen {
+		return nil, fmt.Errorf("data too short, must be at least %d bytes", subscribeLen)
+	}
+	if types.HandlerType(data[0]) != types.SubscribeHandlerType {
+		return nil, fmt.Errorf("invalid subscribe handler byte: %v", data[0])
+	}
+
+	return &SubscribeRequest{
+		From: binary.BigEndian.Uint64(data[1:9]),
+	}, nil
+}
+
+// EncodeStreamFrame encodes a single frame of a server push stream:
+// length (4 bytes, covering status and body) | status (1 byte) | body
+//
+// Subscriptions send one StatusOK frame per change and a StatusHeartbeat frame every
+// HeartbeatInterval, the first one acknowledging the subscription; a frame with any other status
+// carries an error message and terminates the stream, StatusTruncated when the requested changes
+// were already pruned.
+func EncodeStreamFrame(status types.ResponseStatus, body []byte) []byte {
+	buf := make([]byte, streamFrameHeadLen+len(body))
+	binary.BigEndian.PutUint32(buf[0:4], uint32(1+len(body)))
+	buf[4] = byte(status)
+	copy(buf[streamFrameHeadLen:], body)
+	return buf
+}
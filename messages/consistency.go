@@ -0,0 +1,53 @@
+package messages
+
+import "fmt"
+
+// ConsistencySelector is the marker byte of the optional consistency header that may prefix any
+// frame, in front of the database selector:
+// selector (1 byte) | R (1 byte) | W (1 byte) | frame
+//
+// It is honoured by databases replicated with quorums and ignored by every other database.
+const (
+	ConsistencySelector byte = '%'
+	consistencyLen           = 1 + 1 + 1
+)
+
+// Consistency is the number of replicas that must answer a read (R) or acknowledge a write
+// (W) before the coordinating node answers the client. Zero selects the default of the database.
+type Consistency struct {
+	R uint8 // Replicas answering a read
+	W uint8 // Replicas acknowledging a write or delete
+}
+
+// IsZero reports whether the consistency selects the defaults of the database.
+func (c Consistency) IsZero() bool {
+	return c.R == 0 && c.W == 0
+}
+
+// WithConsistency prefixes the frame with a consistency header. A zero consistency returns the frame unchanged.
+func WithConsistency(c Consistency, frame []byte) []byte {
+	if c.IsZero() {
+		return frame
+	}
+
+	buf := make([]byte, consistencyLen+len(frame))
+	buf[0] = ConsistencySelector
+	buf[1] = c.R
+	buf[2] = c.W
+	copy(buf[consistencyLen:], frame)
+
+	return buf
+}
+
+// SplitConsistency strips the optional consistency header from the frame. It returns the requested
+// consistency, zero when the frame carries no header, and the remaining frame without allocating.
+func SplitConsistency(frame []byte) (Consistency, []byte, error) {
+	if len(frame) == 0 || frame[0] != ConsistencySelector {
+		return Consistency{}, frame, nil
+	}
+	if len(frame) < consistencyLen {
+		return Consistency{}, nil, fmt.Errorf("consistency header too short")
+	}
+
+	return Consistency{R: frame[1], W: frame[2]}, frame[consistencyLen:], nil
+}
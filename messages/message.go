@@ -10,34 +10,31 @@ import (
 
 // Message struct represents a UDP message
 type Message struct {
-	Handler types.HandlerType // The handler type (1 byte)
-	Key     [32]byte          // Fixed 32-byte key (e.g., Ethereum hash)
-	Data    []byte            // The remaining data after the key
+	Consistency Consistency       // Optional consistency header, zero selects the defaults of the database
+	Database    string            // Optional database selector, empty routes to the transport's default database
+	Handler     types.HandlerType // The handler type (1 byte)
+	Key         [32]byte          // Fixed 32-byte key (e.g., Ethereum hash)
+	Data        []byte            // The remaining data after the key
 }
 
 // EncodeWithBuffer encodes the Message struct into a provided byte slice (buffer).
 // Assumes the buffer is large enough and avoids allocating new buffers.
 // Designed to be used with sync.Pool
 func (m *Message) EncodeWithBuffer(buf []byte) ([]byte, error) {
-	// Calculate the total message length (1 byte for handler + 32 bytes for key + 4 bytes for data length + actual data)
-	msgLen := 1 + 32 + 4 + len(m.Data)
+	selectorLen, err := m.selectorLen()
+	if err != nil {
+		return nil, err
+	}
+
+	// Calculate the total message length (selector + 1 byte for handler + 32 bytes for key + 4 bytes for data length + actual data)
+	msgLen := selectorLen + 1 + 32 + 4 + len(m.Data)
 
 	// Ensure the buffer is large enough (zero-allocation requires that the buffer be managed externally)
 	if len(buf) < msgLen {
 		return nil, fmt.Errorf("buffer too small, need at least %d bytes", msgLen)
 	}
 
-	// Set handler type
-	buf[0] = byte(m.Handler)
-
-	// Copy the 32-byte key
-	copy(buf[1:33], m.Key[:])
-
-	// Set the length of the data (4 bytes)
-	binary.BigEndian.PutUint32(buf[33:37], uint32(len(m.Data)))
-
-	// Copy the data
-	copy(buf[37:], m.Data)
+	m.encodeInto(buf[:msgLen], selectorLen)
 
 	// Return the portion of the buffer that was actually used
 	return buf[:msgLen], nil
@@ -46,11 +43,48 @@ func (m *Message) EncodeWithBuffer(buf []byte) ([]byte, error) {
 // Encode encodes the Message struct into a byte slice.
 // This method allocates a new buffer for every call, unlike EncodeWithBuffer which reuses a buffer.
 func (m *Message) Encode() ([]byte, error) {
-	// Calculate the total message length (1 byte for handler + 32 bytes for key + 4 bytes for data length + actual data)
-	msgLen := 1 + 32 + 4 + len(m.Data)
+	selectorLen, err := m.selectorLen()
+	if err != nil {
+		return nil, err
+	}
+
+	// Allocate a new buffer (selector + 1 byte for handler + 32 bytes for key + 4 bytes for data length + actual data)
+	buf := make([]byte, selectorLen+1+32+4+len(m.Data))
+	m.encodeInto(buf, selectorLen)
 
-	// Allocate a new buffer
-	buf := make([]byte, msgLen)
+	return buf, nil
+}
+
+// selectorLen returns the number of bytes the optional consistency header and database selector occupy.
+func (m *Message) selectorLen() (int, error) {
+	n := 0
+	if !m.Consistency.IsZero() {
+		n += consistencyLen
+	}
+	if m.Database == "" {
+		return n, nil
+	}
+	if len(m.Database) > maxDatabaseNameLen {
+		return 0, fmt.Errorf("database name too long: %d bytes", len(m.Database))
+	}
+	return n + databaseSelectorBase + len(m.Database), nil
+}
+
+// encodeInto writes the message into buf, which must be exactly the encoded size.
+func (m *Message) encodeInto(buf []byte, selectorLen int) {
+	if !m.Consistency.IsZero() {
+		buf[0] = ConsistencySelector
+		buf[1] = m.Consistency.R
+		buf[2] = m.Consistency.W
+		buf = buf[consistencyLen:]
+		selectorLen -= consistencyLen
+	}
+	if selectorLen > 0 {
+		buf[0] = DatabaseSelector
+		buf[1] = byte(len(m.Database))
+		copy(buf[databaseSelectorBase:selectorLen], m.Database)
+		buf = buf[selectorLen:]
+	}
 
 	// Set handler type
 	buf[0] = byte(m.Handler)
@@ -63,18 +97,29 @@ func (m *Message) Encode() ([]byte, error) {
 
 	// Copy the data
 	copy(buf[37:], m.Data)
-
-	return buf, nil
 }
 
 // Decode decodes a byte slice into a Message struct without allocating new memory for data.
+// An optional consistency header and database selector in front of the message are decoded
+// into Consistency and Database.
 func Decode(data []byte) (*Message, error) {
+	consistency, data, err := SplitConsistency(data)
+	if err != nil {
+		return nil, err
+	}
+	database, data, err := SplitDatabaseSelector(data)
+	if err != nil {
+		return nil, err
+	}
+
 	if len(data) < 37 { // 1 byte for handler + 32 bytes for key + 4 bytes for data length
 		return nil, fmt.Errorf("data too short, must be at least 37 bytes")
 	}
 
 	msg := &Message{
-		Handler: types.HandlerType(data[0]),
+		Consistency: consistency,
+		Database:    database,
+		Handler:     types.HandlerType(data[0]),
 	}
 
 	// Copy the 32-byte key
@@ -0,0 +1,86 @@
+package messages
+
+import (
+	"encoding/binary"
+	"fmt"
+	"github.com/unpackdev/fdb/types"
+)
+
+const (
+	// multiSetHeaderLen is the length of the handler and entry count of a multi-set frame.
+	multiSetHeaderLen = 1 + 4
+
+	// MultiSetEntryOverhead is the length of an entry of a multi-set frame besides its value.
+	MultiSetEntryOverhead = 32 + 4
+)
+
+// MultiSetEntry is a write carried by a multi-set frame
+type MultiSetEntry struct {
+	Key   [32]byte // The written key
+	Value []byte   // The written value, never empty
+}
+
+// EncodeMultiSet encodes writes into a multi-set frame, applied by the server as a whole:
+// handler (1 byte) | count (4 bytes) | { key (32 bytes) | value length (4 bytes) | value } * count
+//
+// The frame is answered with a single status byte.
+func EncodeMultiSet(entries []MultiSetEntry) []byte {
+	size := multiSetHeaderLen
+	for _, entry := range entries {
+		size += MultiSetEntryOverhead + len(entry.Value)
+	}
+
+	buf := make([]byte, size)
+	buf[0] = byte(types.MultiSetHandlerType)
+	binary.BigEndian.PutUint32(buf[1:5], uint32(len(entries)))
+
+	offset := multiSetHeaderLen
+	for _, entry := range entries {
+		copy(buf[offset:offset+32], entry.Key[:])
+		binary.BigEndian.PutUint32(buf[offset+32:offset+MultiSetEntryOverhead], uint32(len(entry.Value)))
+		offset += MultiSetEntryOverhead
+		offset += copy(buf[offset:], entry.Value)
+	}
+	return buf
+}
+
+// DecodeMultiSet decodes a multi-set frame into its writes. The values reuse the provided slice
+// instead of allocating. Frames with empty values are rejected, so no write of a malformed frame
+// is applied.
+func DecodeMultiSet(data []byte) ([]MultiSetEntry, error) {
+	if len(data) < multiSetHeaderLen {
+		return nil, fmt.Errorf("data too short, must be at least %d bytes", multiSetHeaderLen)
+	}
+	if types.HandlerType(data[0]) != types.MultiSetHandlerType {
+		return nil, fmt.Errorf("invalid multi-set handler byte: %v", data[0])
+	}
+
+	count := binary.BigEndian.Uint32(data[1:5])
+	data = data[multiSetHeaderLen:]
+	if uint64(count)*MultiSetEntryOverhead > uint64(len(data)) {
+		return nil, fmt.Errorf("multi-set of %d entries exceeds the frame of %d bytes", count, len(data))
+	}
+
+	entries := make([]MultiSetEntry, count)
+	for i := range entries {
+		if len(data) < MultiSetEntryOverhead {
+			return nil, fmt.Errorf("entry %d truncated", i)
+		}
+		copy(entries[i].Key[:], data[:32])
+		valueLen := binary.BigEndian.Uint32(data[32:MultiSetEntryOverhead])
+		data = data[MultiSetEntryOverhead:]
+
+		if valueLen == 0 {
+			return nil, fmt.Errorf("entry %d has an empty value", i)
+		}
+		if uint64(valueLen) > uint64(len(data)) {
+			return nil, fmt.Errorf("entry %d value length mismatch, expected %d bytes but got %d bytes", i, valueLen, len(data))
+		}
+		entries[i].Value = data[:valueLen]
+		data = data[valueLen:]
+	}
+	if len(data) != 0 {
+		return nil, fmt.Errorf("%d trailing bytes after the multi-set entries", len(data))
+	}
+	return entries, nil
+}
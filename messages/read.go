@@ -0,0 +1,28 @@
+package messages
+
+import (
+	"errors"
+
+	"github.com/unpackdev/fdb/types"
+)
+
+// EncodeReadResponse encodes the response to a tagged read:
+// status (1 byte) | value
+//
+// The value follows types.StatusOK only; types.StatusError may be followed by an error message
+// instead. Untagged reads are still answered with the raw value or a text error, see README.
+func EncodeReadResponse(status types.ResponseStatus, value []byte) []byte {
+	buf := make([]byte, 1+len(value))
+	buf[0] = byte(status)
+	copy(buf[1:], value)
+	return buf
+}
+
+// DecodeReadResponse decodes the response to a tagged read into its status and the bytes that
+// follow it, without allocating.
+func DecodeReadResponse(data []byte) (types.ResponseStatus, []byte, error) {
+	if len(data) < 1 {
+		return 0, nil, errors.New("empty read response")
+	}
+	return types.ResponseStatus(data[0]), data[1:], nil
+}
@@ -0,0 +1,155 @@
+package messages
+
+import (
+	"encoding/binary"
+	"fmt"
+	"github.com/unpackdev/fdb/types"
+)
+
+const (
+	// VersionLen is the encoded length of a Version.
+	VersionLen = 8 + 4 + 1
+
+	replicaHeaderLen = 1 + 1 + 32
+)
+
+// Version orders the writes of a key replicated with quorums. The write with the highest
+// timestamp wins, the origin of the coordinator breaks ties. The encoded form is:
+// timestamp (8 bytes) | origin (4 bytes) | tombstone (1 byte)
+type Version struct {
+	Timestamp uint64 // Unix nanoseconds assigned by the coordinator of the write
+	Origin    uint32 // Identifies the coordinator of the write
+	Tombstone bool   // The write deleted the key
+}
+
+// IsZero reports whether the version is the zero version, older than any write.
+func (v Version) IsZero() bool {
+	return v == Version{}
+}
+
+// Newer reports whether v orders after o.
+func (v Version) Newer(o Version) bool {
+	if v.Timestamp != o.Timestamp {
+		return v.Timestamp > o.Timestamp
+	}
+	return v.Origin > o.Origin
+}
+
+// Encode encodes the Version into a newly allocated byte slice.
+func (v Version) Encode() []byte {
+	buf := make([]byte, VersionLen)
+	v.encodeInto(buf)
+	return buf
+}
+
+// encodeInto writes the version into buf, which must hold at least VersionLen bytes.
+func (v Version) encodeInto(buf []byte) {
+	binary.BigEndian.PutUint64(buf[0:8], v.Timestamp)
+	binary.BigEndian.PutUint32(buf[8:12], v.Origin)
+	buf[12] = 0
+	if v.Tombstone {
+		buf[12] = 1
+	}
+}
+
+// DecodeVersion decodes a byte slice into a Version.
+func DecodeVersion(data []byte) (Version, error) {
+	if len(data) < VersionLen {
+		return Version{}, fmt.Errorf("data too short, must be at least %d bytes", VersionLen)
+	}
+	return Version{
+		Timestamp: binary.BigEndian.Uint64(data[0:8]),
+		Origin:    binary.BigEndian.Uint32(data[8:12]),
+		Tombstone: data[12] != 0,
+	}, nil
+}
+
+// ReplicaOp identifies the operation carried by a ReplicaRequest
+type ReplicaOp byte
+
+const (
+	ReplicaGet ReplicaOp = 'G' // Read the versioned value of a key
+	ReplicaPut ReplicaOp = 'P' // Store a versioned value unless the replica holds a newer one
+)
+
+// ReplicaRequest is sent by the coordinator of a quorum read or write to the replicas of a key:
+// handler (1 byte) | op (1 byte) | key (32 bytes) | version (13 bytes, put only) | value (put only)
+//
+// A get is answered with: status (1 byte) | version (13 bytes) | value, the zero version and no
+// value when the replica has never seen the key. A put is answered with a single status byte.
+type ReplicaRequest struct {
+	Op      ReplicaOp // The replica operation
+	Key     [32]byte  // The replicated key
+	Version Version   // Version of the written value
+	Value   []byte    // The written value, empty for tombstones
+}
+
+// Encode encodes the ReplicaRequest into a newly allocated byte slice.
+func (r *ReplicaRequest) Encode() []byte {
+	if r.Op != ReplicaPut {
+		buf := make([]byte, replicaHeaderLen)
+		r.encodeHeader(buf)
+		return buf
+	}
+
+	buf := make([]byte, replicaHeaderLen+VersionLen+len(r.Value))
+	r.encodeHeader(buf)
+	r.Version.encodeInto(buf[replicaHeaderLen:])
+	copy(buf[replicaHeaderLen+VersionLen:], r.Value)
+	return buf
+}
+
+// encodeHeader writes the handler, operation and key into buf.
+func (r *ReplicaRequest) encodeHeader(buf []byte) {
+	buf[0] = byte(types.ReplicaHandlerType)
+	buf[1] = byte(r.Op)
+	copy(buf[2:replicaHeaderLen], r.Key[:])
+}
+
+// DecodeReplicaRequest decodes a byte slice into a ReplicaRequest. The value reuses the
+// provided slice instead of allocating.
+func DecodeReplicaRequest(data []byte) (*ReplicaRequest, error) {
+	if len(data) < replicaHeaderLen {
+		return nil, fmt.Errorf("data too short, must be at least %d bytes", replicaHeaderLen)
+	}
+	if types.HandlerType(data[0]) != types.ReplicaHandlerType {
+		return nil, fmt.Errorf("invalid replica handler byte: %v", data[0])
+	}
+
+	req := &ReplicaRequest{Op: ReplicaOp(data[1])}
+	copy(req.Key[:], data[2:replicaHeaderLen])
+
+	switch req.Op {
+	case ReplicaGet:
+		return req, nil
+	case ReplicaPut:
+		version, err := DecodeVersion(data[replicaHeaderLen:])
+		if err != nil {
+			return nil, err
+		}
+		req.Version = version
+		req.Value = data[replicaHeaderLen+VersionLen:]
+		return req, nil
+	default:
+		return nil, fmt.Errorf("unknown replica operation: %v", data[1])
+	}
+}
+
+// EncodeReplicaValue encodes the answer to a ReplicaGet: status (1 byte) | version (13 bytes) | value
+func EncodeReplicaValue(version Version, value []byte) []byte {
+	buf := make([]byte, 1+VersionLen+len(value))
+	buf[0] = byte(types.StatusOK)
+	version.encodeInto(buf[1:])
+	copy(buf[1+VersionLen:], value)
+	return buf
+}
+
+// DecodeReplicaValue decodes the body of a successful ReplicaGet answer, without its status
+// byte. The value reuses the provided slice.
+func DecodeReplicaValue(data []byte) (Version, []byte, error) {
+	version, err := DecodeVersion(data)
+	if err != nil {
+		return Version{}, nil, err
+	}
+	return version, data[VersionLen:], nil
+}
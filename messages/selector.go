@@ -0,0 +1,67 @@
+package messages
+
+import "fmt"
+
+// DatabaseSelector is the marker byte of the optional database selector that may prefix any frame:
+// selector (1 byte) | name length (1 byte) | name | frame
+//
+// Frames without the selector are routed to the default database of the transport they arrive on.
+const (
+	DatabaseSelector     byte = '@'
+	maxDatabaseNameLen        = 0xFF
+	databaseSelectorBase      = 1 + 1
+)
+
+// WithDatabase prefixes the frame with a database selector. An empty name returns the frame unchanged.
+func WithDatabase(name string, frame []byte) ([]byte, error) {
+	if name == "" {
+		return frame, nil
+	}
+	if len(name) > maxDatabaseNameLen {
+		return nil, fmt.Errorf("database name too long: %d bytes", len(name))
+	}
+
+	buf := make([]byte, databaseSelectorBase+len(name)+len(frame))
+	buf[0] = DatabaseSelector
+	buf[1] = byte(len(name))
+	copy(buf[databaseSelectorBase:], name)
+	copy(buf[databaseSelectorBase+len(name):], frame)
+
+	return buf, nil
+}
+
+// SplitDatabaseSelector strips the optional database selector from the frame. It returns the selected
+// database name, empty when the frame carries no selector, and the remaining frame without allocating.
+func SplitDatabaseSelector(frame []byte) (string, []byte, error) {
+	if len(frame) == 0 || frame[0] != DatabaseSelector {
+		return "", frame, nil
+	}
+	if len(frame) < databaseSelectorBase {
+		return "", nil, fmt.Errorf("database selector too short")
+	}
+
+	nameLen := int(frame[1])
+	if nameLen == 0 {
+		return "", nil, fmt.Errorf("database selector carries an empty name")
+	}
+	if len(frame[databaseSelectorBase:]) < nameLen {
+		return "", nil, fmt.Errorf("database name length mismatch, expected %d bytes but got %d bytes", nameLen, len(frame[databaseSelectorBase:]))
+	}
+
+	return string(frame[databaseSelectorBase : databaseSelectorBase+nameLen]), frame[databaseSelectorBase+nameLen:], nil
+}
+
+// SplitHeader strips the optional consistency header and database selector from the frame, in
+// that order. It returns the requested consistency, the selected database name and the remaining
+// frame without allocating.
+func SplitHeader(frame []byte) (Consistency, string, []byte, error) {
+	consistency, frame, err := SplitConsistency(frame)
+	if err != nil {
+		return Consistency{}, "", nil, err
+	}
+	database, frame, err := SplitDatabaseSelector(frame)
+	if err != nil {
+		return Consistency{}, "", nil, err
+	}
+	return consistency, database, frame, nil
+}
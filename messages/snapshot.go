@@ -0,0 +1,137 @@
+package messages
+
+import (
+	"encoding/binary"
+	"fmt"
+	"github.com/unpackdev/fdb/types"
+	"io"
+	"time"
+)
+
+const (
+	snapshotHeadLen  = 8
+	snapshotEntryLen = 2
+	heartbeatLen     = 8 + 8
+
+	// maxStreamFrameLen bounds the frames accepted by ReadStreamFrame.
+	maxStreamFrameLen = 64 << 20
+)
+
+// HeartbeatInterval is how often subscription streams send a heartbeat frame.
+const HeartbeatInterval = time.Second
+
+// SnapshotRequest asks the server to stream a consistent copy of the selected database:
+// handler (1 byte)
+//
+// The response is a sequence of stream frames. The first StatusOK frame carries the change
+// log head the snapshot is consistent with (8 bytes), every following StatusOK frame carries
+// one entry (see EncodeSnapshotEntry) and a StatusOK frame with an empty body ends the
+// snapshot. A frame with any other status carries an error message and terminates the stream.
+type SnapshotRequest struct{}
+
+// Encode encodes the SnapshotRequest into a newly allocated byte slice.
+func (r *SnapshotRequest) Encode() []byte {
+	return []byte{byte(types.SnapshotHandlerType)}
+}
+
+// DecodeSnapshotRequest decodes a byte slice into a SnapshotRequest.
+func DecodeSnapshotRequest(data []byte) (*SnapshotRequest, error) {
+	if len(data) < 1 {
+		return nil, fmt.Errorf("data too short, must be at least 1 byte")
+	}
+	if types.HandlerType(data[0]) != types.SnapshotHandlerType {
+		return nil, fmt.Errorf("invalid snapshot handler byte: %v", data[0])
+	}
+	return &SnapshotRequest{}, nil
+}
+
+// EncodeSnapshotHead encodes the body of the first snapshot frame.
+func EncodeSnapshotHead(seq uint64) []byte {
+	buf := make([]byte, snapshotHeadLen)
+	binary.BigEndian.PutUint64(buf, seq)
+	return buf
+}
+
+// DecodeSnapshotHead decodes the body of the first snapshot frame.
+func DecodeSnapshotHead(data []byte) (uint64, error) {
+	if len(data) != snapshotHeadLen {
+		return 0, fmt.Errorf("invalid snapshot head length: %d bytes", len(data))
+	}
+	return binary.BigEndian.Uint64(data), nil
+}
+
+// EncodeSnapshotEntry encodes a single snapshot key-value pair:
+// key length (2 bytes) | key | value
+func EncodeSnapshotEntry(key, value []byte) ([]byte, error) {
+	if len(key) > 0xFFFF {
+		return nil, fmt.Errorf("snapshot key too long: %d bytes", len(key))
+	}
+
+	buf := make([]byte, snapshotEntryLen+len(key)+len(value))
+	binary.BigEndian.PutUint16(buf[0:2], uint16(len(key)))
+	copy(buf[snapshotEntryLen:], key)
+	copy(buf[snapshotEntryLen+len(key):], value)
+	return buf, nil
+}
+
+// DecodeSnapshotEntry decodes a snapshot key-value pair. Key and value reuse the provided slice.
+func DecodeSnapshotEntry(data []byte) (key, value []byte, err error) {
+	if len(data) < snapshotEntryLen {
+		return nil, nil, fmt.Errorf("data too short, must be at least %d bytes", snapshotEntryLen)
+	}
+
+	keyLen := int(binary.BigEndian.Uint16(data[0:2]))
+	if len(data[snapshotEntryLen:]) < keyLen {
+		return nil, nil, fmt.Errorf("key length mismatch, expected %d bytes but got %d bytes", keyLen, len(data[snapshotEntryLen:]))
+	}
+
+	return data[snapshotEntryLen : snapshotEntryLen+keyLen], data[snapshotEntryLen+keyLen:], nil
+}
+
+// Heartbeat is sent periodically on subscription streams as a StatusHeartbeat frame:
+// head (8 bytes) | timestamp (8 bytes)
+//
+// It keeps idle streams alive and tells the subscriber how far behind the server it is.
+type Heartbeat struct {
+	Head      uint64 // Newest sequence number known to the server
+	Timestamp int64  // Server time in Unix nanoseconds
+}
+
+// Encode encodes the Heartbeat into a newly allocated byte slice.
+func (h *Heartbeat) Encode() []byte {
+	buf := make([]byte, heartbeatLen)
+	binary.BigEndian.PutUint64(buf[0:8], h.Head)
+	binary.BigEndian.PutUint64(buf[8:16], uint64(h.Timestamp))
+	return buf
+}
+
+// DecodeHeartbeat decodes a byte slice into a Heartbeat.
+func DecodeHeartbeat(data []byte) (*Heartbeat, error) {
+	if len(data) < heartbeatLen {
+		return nil, fmt.Errorf("data too short, must be at least %d bytes", heartbeatLen)
+	}
+
+	return &Heartbeat{
+		Head:      binary.BigEndian.Uint64(data[0:8]),
+		Timestamp: int64(binary.BigEndian.Uint64(data[8:16])),
+	}, nil
+}
+
+// ReadStreamFrame reads a single frame written with EncodeStreamFrame.
+func ReadStreamFrame(r io.Reader) (types.ResponseStatus, []byte, error) {
+	var head [streamFrameHeadLen]byte
+	if _, err := io.ReadFull(r, head[:]); err != nil {
+		return 0, nil, err
+	}
+
+	frameLen := binary.BigEndian.Uint32(head[0:4])
+	if frameLen < 1 || frameLen > maxStreamFrameLen {
+		return 0, nil, fmt.Errorf("invalid stream frame length: %d", frameLen)
+	}
+
+	body := make([]byte, frameLen-1)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return 0, nil, err
+	}
+	return types.ResponseStatus(head[4]), body, nil
+}
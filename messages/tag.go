@@ -0,0 +1,64 @@
+package messages
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// TagSelector is the marker byte of a tagged frame. A client tags a request to correlate its
+// response, and the server answers a tagged request with tagged responses carrying the same ID:
+// selector (1 byte) | id (4 bytes) | length (4 bytes) | frame (length bytes)
+//
+// The length delimits frames on stream transports, so tagged requests may be pipelined on a
+// connection and answered out of order. The tagged frame wraps a complete request, headers
+// included, or a complete response.
+const (
+	TagSelector  byte = '#'
+	TagHeaderLen      = 1 + 4 + 4
+
+	// MaxTaggedFrameLen bounds the frame carried by a tagged frame.
+	MaxTaggedFrameLen = 64 << 20
+)
+
+// TagFrame wraps a frame into a tagged frame with the given request ID.
+func TagFrame(id uint32, frame []byte) []byte {
+	buf := make([]byte, TagHeaderLen+len(frame))
+	buf[0] = TagSelector
+	binary.BigEndian.PutUint32(buf[1:5], id)
+	binary.BigEndian.PutUint32(buf[5:9], uint32(len(frame)))
+	copy(buf[TagHeaderLen:], frame)
+
+	return buf
+}
+
+// DecodeTagHeader decodes the header of a tagged frame, returning the request ID and the length
+// of the frame that follows it.
+func DecodeTagHeader(header []byte) (uint32, int, error) {
+	if len(header) < TagHeaderLen || header[0] != TagSelector {
+		return 0, 0, fmt.Errorf("invalid tagged frame header")
+	}
+
+	length := binary.BigEndian.Uint32(header[5:9])
+	if length > MaxTaggedFrameLen {
+		return 0, 0, fmt.Errorf("tagged frame too large: %d bytes", length)
+	}
+	return binary.BigEndian.Uint32(header[1:5]), int(length), nil
+}
+
+// SplitTagged splits the first tagged frame off data, which may hold several frames or only part
+// of one. It returns the request ID, the wrapped frame without allocating and the number of bytes
+// consumed, zero when data does not hold a complete frame yet.
+func SplitTagged(data []byte) (uint32, []byte, int, error) {
+	if len(data) < TagHeaderLen {
+		return 0, nil, 0, nil
+	}
+
+	id, length, err := DecodeTagHeader(data)
+	if err != nil {
+		return 0, nil, 0, err
+	}
+	if len(data) < TagHeaderLen+length {
+		return 0, nil, 0, nil
+	}
+	return id, data[TagHeaderLen : TagHeaderLen+length], TagHeaderLen + length, nil
+}
@@ -0,0 +1,83 @@
+package messages
+
+import (
+	"encoding/binary"
+	"fmt"
+	"github.com/unpackdev/fdb/types"
+)
+
+const (
+	treeHeaderLen = 1 + 1 + 1 + 2
+
+	// MaxTreeIndices bounds the number of nodes or leaves named by a single TreeRequest.
+	MaxTreeIndices = 1024
+)
+
+// TreeOp identifies the part of the Merkle tree a TreeRequest asks for
+type TreeOp byte
+
+const (
+	TreeHashes TreeOp = 'H' // Hashes of the nodes at the given positions of a tree level
+	TreeRange  TreeOp = 'K' // Key-value pairs covered by the given leaves
+)
+
+// TreeRequest asks the server for part of the Merkle tree summarising the selected database,
+// used to find and repair the key ranges in which two replicas differ:
+// handler (1 byte) | op (1 byte) | level (1 byte) | count (2 bytes) | indices (4 bytes each)
+//
+// The response is a sequence of stream frames. TreeHashes is answered with a single StatusOK
+// frame holding the 32-byte hashes of the requested nodes, in request order. TreeRange is
+// answered with one StatusOK frame per key-value pair (see EncodeSnapshotEntry), in key order,
+// and a StatusOK frame with an empty body ends the range. A frame with any other status
+// carries an error message and terminates the stream.
+type TreeRequest struct {
+	Op      TreeOp   // The requested part of the tree
+	Level   uint8    // Tree level of the nodes, ignored by TreeRange which names leaves
+	Indices []uint32 // Positions of the nodes within the level, or leaves in ascending order
+}
+
+// Encode encodes the TreeRequest into a newly allocated byte slice.
+func (r *TreeRequest) Encode() ([]byte, error) {
+	if len(r.Indices) > MaxTreeIndices {
+		return nil, fmt.Errorf("too many tree indices: %d, at most %d", len(r.Indices), MaxTreeIndices)
+	}
+
+	buf := make([]byte, treeHeaderLen+4*len(r.Indices))
+	buf[0] = byte(types.TreeHandlerType)
+	buf[1] = byte(r.Op)
+	buf[2] = r.Level
+	binary.BigEndian.PutUint16(buf[3:5], uint16(len(r.Indices)))
+	for i, index := range r.Indices {
+		binary.BigEndian.PutUint32(buf[treeHeaderLen+4*i:], index)
+	}
+	return buf, nil
+}
+
+// DecodeTreeRequest decodes a byte slice into a TreeRequest.
+func DecodeTreeRequest(data []byte) (*TreeRequest, error) {
+	if len(data) < treeHeaderLen {
+		return nil, fmt.Errorf("data too short, must be at least %d bytes", treeHeaderLen)
+	}
+	if types.HandlerType(data[0]) != types.TreeHandlerType {
+		return nil, fmt.Errorf("invalid tree handler byte: %v", data[0])
+	}
+
+	op := TreeOp(data[1])
+	if op != TreeHashes && op != TreeRange {
+		return nil, fmt.Errorf("unknown tree operation: %v", data[1])
+	}
+
+	count := int(binary.BigEndian.Uint16(data[3:5]))
+	if count > MaxTreeIndices {
+		return nil, fmt.Errorf("too many tree indices: %d, at most %d", count, MaxTreeIndices)
+	}
+	if len(data[treeHeaderLen:]) < 4*count {
+		return nil, fmt.Errorf("indices length mismatch, expected %d bytes but got %d bytes", 4*count, len(data[treeHeaderLen:]))
+	}
+
+	req := &TreeRequest{Op: op, Level: data[2], Indices: make([]uint32, count)}
+	for i := range req.Indices {
+		req.Indices[i] = binary.BigEndian.Uint32(data[treeHeaderLen+4*i:])
+	}
+	return req, nil
+}
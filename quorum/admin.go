@@ -0,0 +1,21 @@
+package quorum
+
+import (
+	"encoding/json"
+
+	"github.com/unpackdev/fdb/db"
+	"github.com/unpackdev/fdb/messages"
+)
+
+// RegisterAdminOps exposes the quorum status of the node through the admin handlers of the
+// manager's transports. messages.AdminQuorumStatus returns the JSON encoded Status.
+//
+// Parameters:
+//
+//	manager (*db.Manager): The manager dispatching admin requests.
+//	coordinator (*Coordinator): The quorum coordinator of the node.
+func RegisterAdminOps(manager *db.Manager, coordinator *Coordinator) {
+	manager.RegisterAdminOp(messages.AdminQuorumStatus, func(_ *messages.AdminRequest) ([]byte, error) {
+		return json.Marshal(coordinator.Status())
+	})
+}
@@ -0,0 +1,499 @@
+package quorum
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/unpackdev/fdb/config"
+	"github.com/unpackdev/fdb/db"
+	fdbErrors "github.com/unpackdev/fdb/errors"
+	"github.com/unpackdev/fdb/messages"
+	"github.com/unpackdev/fdb/remote"
+	"github.com/unpackdev/fdb/sharding"
+	"github.com/unpackdev/fdb/types"
+	"go.uber.org/zap"
+)
+
+// Status describes the quorum settings of a node and the state of its hinted handoff.
+type Status struct {
+	// NodeID is the ID of this node among the quorum nodes.
+	NodeID string `json:"nodeId"`
+
+	// Replicas is the number of replicas of each key (N).
+	Replicas int `json:"replicas"`
+
+	// ReadQuorum is the default read quorum (R).
+	ReadQuorum int `json:"readQuorum"`
+
+	// WriteQuorum is the default write quorum (W).
+	WriteQuorum int `json:"writeQuorum"`
+
+	// Hints is the number of writes kept for each unreachable node.
+	Hints map[string]int `json:"hints"`
+
+	// DroppedHints is the number of writes not kept because the hint limit was reached.
+	DroppedHints uint64 `json:"droppedHints"`
+
+	// ReadRepairs is the number of stale replicas updated by reads.
+	ReadRepairs uint64 `json:"readRepairs"`
+}
+
+// Coordinator serves the requests of databases replicated with quorums. It is the db.Proposer
+// and db.Reader of every replicated database: requests received by any transport are fanned out
+// to the replicas of the key and answered once the requested quorum is reached.
+//
+// Every write is versioned with the coordinator's clock and origin. Replicas keep the newest
+// version of a key (see db.Db.PutVersioned), so concurrent writes resolve to the same value on
+// every replica, last writer wins. Reads return the newest version among the replicas that
+// answered and update the stale ones. Writes a replica missed are kept as hints and delivered
+// once it is reachable again.
+type Coordinator struct {
+	cnf config.Quorum
+
+	// manager owns the local replicas.
+	manager *db.Manager
+
+	// ring places the replicas of each key.
+	ring *sharding.Ring
+
+	// origin identifies the writes of this coordinator in their versions.
+	origin uint32
+
+	// clients holds the client of every other node.
+	clients map[string]*remote.Client
+
+	// clockMu guards last.
+	clockMu sync.Mutex
+
+	// last is the timestamp of the last version issued, versions are strictly increasing.
+	last uint64
+
+	hints       *hintStore
+	readRepairs atomic.Uint64
+
+	// ctx bounds the handoff loop and background repairs, cancelled by Close.
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewCoordinator creates the quorum coordinator of a node. Call Start to begin delivering hints.
+//
+// Example usage:
+//
+//	coordinator, err := quorum.NewCoordinator(manager, cnf.Quorum)
+//	if err != nil {
+//	    log.Fatalf("Failed to create quorum coordinator: %v", err)
+//	}
+//	for _, name := range cnf.Quorum.Databases {
+//	    _ = manager.SetProposer(types.DbType(name), coordinator)
+//	    _ = manager.SetReader(types.DbType(name), coordinator)
+//	}
+//	coordinator.Start(ctx)
+//	defer coordinator.Close()
+//
+// Parameters:
+//
+//	manager (*db.Manager): The manager owning the local replicas.
+//	cnf (config.Quorum): The quorum configuration.
+//
+// Returns:
+//
+//	*Coordinator: A new Coordinator instance.
+//	error: Returns an error if the configuration is invalid or a node client cannot be created.
+func NewCoordinator(manager *db.Manager, cnf config.Quorum) (*Coordinator, error) {
+	if err := cnf.Validate(); err != nil {
+		return nil, err
+	}
+
+	origin := fnv.New32a()
+	_, _ = origin.Write([]byte(cnf.NodeID))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	c := &Coordinator{
+		cnf:     cnf,
+		manager: manager,
+		ring:    sharding.NewRing(&sharding.Map{VirtualNodes: cnf.GetVirtualNodes(), Shards: cnf.Nodes}),
+		origin:  origin.Sum32(),
+		clients: make(map[string]*remote.Client, len(cnf.Nodes)),
+		hints:   newHintStore(cnf.GetMaxHints()),
+		ctx:     ctx,
+		cancel:  cancel,
+	}
+
+	for _, node := range cnf.Nodes {
+		if node.ID == cnf.NodeID {
+			continue
+		}
+		client, err := remote.New(remote.Options{
+			Transport: node.Transport,
+			Addr:      node.Addr,
+			Insecure:  node.Insecure,
+			PoolSize:  cnf.GetPoolSize(),
+			Timeout:   cnf.GetRequestTimeout(),
+		})
+		if err != nil {
+			_ = c.Close()
+			return nil, fmt.Errorf("failed to create client of quorum node %s: %w", node.ID, err)
+		}
+		c.clients[node.ID] = client
+	}
+	return c, nil
+}
+
+// Start begins delivering hints every handoff interval, until ctx is done or Close is called.
+func (c *Coordinator) Start(ctx context.Context) {
+	c.wg.Add(1)
+	go c.handoff(ctx)
+}
+
+// Close stops the hint delivery and background repairs and closes the connections to the
+// other nodes. Pending hints are dropped.
+func (c *Coordinator) Close() error {
+	c.cancel()
+	c.wg.Wait()
+
+	var errs []error
+	for _, client := range c.clients {
+		errs = append(errs, client.Close())
+	}
+	return errors.Join(errs...)
+}
+
+// Status returns the quorum settings and the hinted handoff state of the node.
+func (c *Coordinator) Status() Status {
+	hints, dropped := c.hints.counts()
+	return Status{
+		NodeID:       c.cnf.NodeID,
+		Replicas:     c.cnf.GetReplicas(),
+		ReadQuorum:   c.cnf.GetReadQuorum(),
+		WriteQuorum:  c.cnf.GetWriteQuorum(),
+		Hints:        hints,
+		DroppedHints: dropped,
+		ReadRepairs:  c.readRepairs.Load(),
+	}
+}
+
+// Propose writes or deletes a key on its replicas and returns once the write quorum
+// acknowledged it. It implements db.Proposer; the quorum is taken from the consistency carried
+// by ctx (see db.WithConsistency), or the configured default. Replicas that have not answered
+// yet keep receiving the write after Propose returns, and writes that fail are kept as hints.
+//
+// Returns:
+//
+//	error: Returns an error wrapping errors.ErrQuorumNotReached if too few replicas acknowledged.
+func (c *Coordinator) Propose(ctx context.Context, database types.DbType, op types.ChangeOp, key, value []byte) error {
+	k, err := requestKey(key)
+	if err != nil {
+		return err
+	}
+	if op != types.ChangeSet && op != types.ChangeDelete {
+		return fmt.Errorf("unsupported change operation: %s", op)
+	}
+
+	replicas := c.ring.Replicas(key, c.cnf.GetReplicas())
+	w, err := quorumOf(db.ConsistencyFromContext(ctx).W, c.cnf.GetWriteQuorum(), len(replicas), "write")
+	if err != nil {
+		return err
+	}
+
+	version := c.nextVersion(op == types.ChangeDelete)
+	if version.Tombstone {
+		value = nil
+	} else {
+		// Replicas still writing after the quorum is reached outlive the caller's buffer
+		value = append([]byte(nil), value...)
+	}
+
+	// Replica writes do not stop with the request, so every replica gets the chance to store it
+	writeCtx := context.WithoutCancel(ctx)
+	acks := make(chan error, len(replicas))
+	for _, id := range replicas {
+		go func(id string) {
+			err := c.put(writeCtx, database, id, k, version, value)
+			if err != nil && id != c.cnf.NodeID {
+				c.keepHint(id, hint{database: database, key: k, version: version, value: value}, err)
+			}
+			acks <- err
+		}(id)
+	}
+
+	var acked, failed int
+	var lastErr error
+	for acked < w {
+		select {
+		case err := <-acks:
+			if err == nil {
+				acked++
+				continue
+			}
+			failed++
+			lastErr = err
+			if failed > len(replicas)-w {
+				return fmt.Errorf("%w: %d of %d replicas acknowledged the write, %d required: %v", fdbErrors.ErrQuorumNotReached, acked, len(replicas), w, lastErr)
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+// reply is the answer of a single replica to a quorum read.
+type reply struct {
+	id      string
+	version messages.Version
+	value   []byte
+	err     error
+}
+
+// Read returns the newest value of a key among its replicas once the read quorum answered. It
+// implements db.Reader; the quorum is taken from the consistency carried by ctx (see
+// db.WithConsistency), or the configured default. Replicas answering with an older version,
+// including those answering after Read returned, are updated with the newest one.
+//
+// Returns:
+//
+//	[]byte: The newest value.
+//	error: Returns errors.ErrNotFound if the key is missing or deleted, or an error wrapping
+//	errors.ErrQuorumNotReached if too few replicas answered.
+func (c *Coordinator) Read(ctx context.Context, database types.DbType, key []byte) ([]byte, error) {
+	k, err := requestKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	replicas := c.ring.Replicas(key, c.cnf.GetReplicas())
+	r, err := quorumOf(db.ConsistencyFromContext(ctx).R, c.cnf.GetReadQuorum(), len(replicas), "read")
+	if err != nil {
+		return nil, err
+	}
+
+	readCtx := context.WithoutCancel(ctx)
+	replies := make(chan reply, len(replicas))
+	for _, id := range replicas {
+		go func(id string) {
+			version, value, err := c.get(readCtx, database, id, k)
+			replies <- reply{id: id, version: version, value: value, err: err}
+		}(id)
+	}
+
+	var answered []reply
+	var failed int
+	var lastErr error
+	for len(answered) < r {
+		select {
+		case rep := <-replies:
+			if rep.err == nil {
+				answered = append(answered, rep)
+				continue
+			}
+			failed++
+			lastErr = rep.err
+			if failed > len(replicas)-r {
+				c.repairLater(database, k, answered, replies, len(replicas)-len(answered)-failed)
+				return nil, fmt.Errorf("%w: %d of %d replicas answered the read, %d required: %v", fdbErrors.ErrQuorumNotReached, len(answered), len(replicas), r, lastErr)
+			}
+		case <-ctx.Done():
+			c.repairLater(database, k, answered, replies, len(replicas)-len(answered)-failed)
+			return nil, ctx.Err()
+		}
+	}
+
+	newest := newestOf(answered)
+	c.repairLater(database, k, answered, replies, len(replicas)-len(answered)-failed)
+
+	if newest.version.Tombstone || len(newest.value) == 0 {
+		return nil, fdbErrors.ErrNotFound
+	}
+	return newest.value, nil
+}
+
+// repairLater waits in the background for the replicas that have not answered a read yet,
+// then updates every replica that answered with an older version than the newest one.
+func (c *Coordinator) repairLater(database types.DbType, key [32]byte, answered []reply, replies <-chan reply, outstanding int) {
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+
+		for ; outstanding > 0; outstanding-- {
+			select {
+			case rep := <-replies:
+				if rep.err == nil {
+					answered = append(answered, rep)
+				}
+			case <-c.ctx.Done():
+				return
+			}
+		}
+		if len(answered) == 0 {
+			return
+		}
+
+		// Values stored without a version cannot be written back, anti-entropy repairs them
+		newest := newestOf(answered)
+		if newest.version.IsZero() {
+			return
+		}
+
+		for _, rep := range answered {
+			if rep.version == newest.version {
+				continue
+			}
+			if err := c.put(c.ctx, database, rep.id, key, newest.version, newest.value); err != nil {
+				zap.L().Debug("Read repair failed", zap.String("node", rep.id), zap.Binary("key", key[:]), zap.Error(err))
+				continue
+			}
+			c.readRepairs.Add(1)
+		}
+	}()
+}
+
+// newestOf returns the reply holding the newest version, replies must not be empty.
+func newestOf(replies []reply) reply {
+	newest := replies[0]
+	for _, rep := range replies[1:] {
+		if rep.version.Newer(newest.version) {
+			newest = rep
+		}
+	}
+	return newest
+}
+
+// get reads the versioned value of a key from a replica, the local one directly.
+func (c *Coordinator) get(ctx context.Context, database types.DbType, id string, key [32]byte) (messages.Version, []byte, error) {
+	if id != c.cnf.NodeID {
+		return c.clients[id].ReplicaGet(ctx, database.String(), key)
+	}
+
+	local, release, err := c.local(database)
+	if err != nil {
+		return messages.Version{}, nil, err
+	}
+	defer release()
+
+	return local.GetVersioned(key[:])
+}
+
+// put writes a versioned value to a replica, the local one directly.
+func (c *Coordinator) put(ctx context.Context, database types.DbType, id string, key [32]byte, version messages.Version, value []byte) error {
+	if id != c.cnf.NodeID {
+		return c.clients[id].ReplicaPut(ctx, database.String(), key, version, value)
+	}
+
+	local, release, err := c.local(database)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	_, err = local.PutVersioned(key[:], version, value)
+	return err
+}
+
+// local returns the local replica of a database.
+func (c *Coordinator) local(database types.DbType) (*db.Db, func(), error) {
+	provider, release, err := c.manager.Acquire(database)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	local, ok := provider.(*db.Db)
+	if !ok {
+		release()
+		return nil, nil, fmt.Errorf("database %s is not stored on this node", database)
+	}
+	return local, release, nil
+}
+
+// nextVersion returns a version newer than every version issued by this coordinator.
+func (c *Coordinator) nextVersion(tombstone bool) messages.Version {
+	c.clockMu.Lock()
+	defer c.clockMu.Unlock()
+
+	now := uint64(time.Now().UnixNano())
+	if now <= c.last {
+		now = c.last + 1
+	}
+	c.last = now
+	return messages.Version{Timestamp: now, Origin: c.origin, Tombstone: tombstone}
+}
+
+// keepHint keeps a write missed by a replica for later delivery.
+func (c *Coordinator) keepHint(id string, h hint, cause error) {
+	if !c.hints.add(id, h) {
+		zap.L().Warn("Hint limit reached, write left to anti-entropy repair", zap.String("node", id), zap.Error(cause))
+		return
+	}
+	zap.L().Debug("Keeping hint for unreachable replica", zap.String("node", id), zap.Error(cause))
+}
+
+// handoff delivers the pending hints every handoff interval until ctx is done.
+func (c *Coordinator) handoff(ctx context.Context) {
+	defer c.wg.Done()
+
+	ticker := time.NewTicker(c.cnf.GetHandoffInterval())
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-c.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		for _, id := range c.hints.nodes() {
+			c.deliver(id)
+		}
+	}
+}
+
+// deliver sends the pending hints of a node in order, stopping at the first failure.
+func (c *Coordinator) deliver(id string) {
+	hints := c.hints.pending(id)
+
+	delivered := 0
+	for _, h := range hints {
+		if err := c.put(c.ctx, h.database, id, h.key, h.version, h.value); err != nil {
+			zap.L().Debug("Replica still unreachable, keeping hints", zap.String("node", id), zap.Int("hints", len(hints)-delivered), zap.Error(err))
+			break
+		}
+		delivered++
+	}
+	if delivered == 0 {
+		return
+	}
+
+	c.hints.delivered(id, delivered)
+	zap.L().Info("Delivered hints to replica", zap.String("node", id), zap.Int("hints", delivered))
+}
+
+// quorumOf returns the requested quorum, or the default one when none was requested, checking
+// that it can be reached with the replicas of the key.
+func quorumOf(requested uint8, fallback, replicas int, kind string) (int, error) {
+	q := int(requested)
+	if q == 0 {
+		q = fallback
+	}
+	if q > replicas {
+		return 0, fmt.Errorf("%s quorum %d exceeds the %d replicas of the key", kind, q, replicas)
+	}
+	return q, nil
+}
+
+// requestKey converts a key to the fixed-size key of replica requests.
+func requestKey(key []byte) ([32]byte, error) {
+	var k [32]byte
+	if len(key) != len(k) {
+		return k, fmt.Errorf("quorum-replicated keys must be %d bytes, got %d", len(k), len(key))
+	}
+	copy(k[:], key)
+	return k, nil
+}
@@ -0,0 +1,178 @@
+package quorum_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/unpackdev/fdb/config"
+	"github.com/unpackdev/fdb/db"
+	fdbErrors "github.com/unpackdev/fdb/errors"
+	"github.com/unpackdev/fdb/fdbtest"
+	"github.com/unpackdev/fdb/messages"
+	"github.com/unpackdev/fdb/quorum"
+	"github.com/unpackdev/fdb/types"
+)
+
+// replicated is the database replicated with quorums. The instances are probed through their
+// default database, readable before the other replicas are up.
+const replicated = "replicated"
+
+type testNode struct {
+	id          string
+	local       *db.Db
+	coordinator *quorum.Coordinator
+}
+
+// cluster is a quorum cluster whose nodes serve replica requests over TCP on free ports, each
+// coordinating writes replicated to all of them.
+type cluster struct {
+	cnf   config.Quorum
+	ports []int
+}
+
+// newCluster reserves a port for each of size nodes; no node is started yet.
+func newCluster(t *testing.T, size int) *cluster {
+	c := &cluster{
+		cnf: config.Quorum{
+			Enabled:         true,
+			Databases:       []string{replicated},
+			Replicas:        size,
+			HandoffInterval: 50 * time.Millisecond,
+			RequestTimeout:  time.Second,
+		},
+	}
+	for i := 0; i < size; i++ {
+		port := fdbtest.FreePort(t, "tcp")
+		c.ports = append(c.ports, port)
+		c.cnf.Nodes = append(c.cnf.Nodes, config.ShardNode{
+			ID:        fmt.Sprintf("node-%d", i+1),
+			Transport: types.TCPTransportType,
+			Addr:      fmt.Sprintf("127.0.0.1:%d", port),
+		})
+	}
+	return c
+}
+
+// start starts the i-th node of the cluster.
+func (c *cluster) start(t *testing.T, i int) *testNode {
+	cnf := c.cnf
+	cnf.NodeID = cnf.Nodes[i].ID
+	server := fdbtest.Start(t,
+		fdbtest.WithTransports(types.TCPTransportType),
+		fdbtest.WithDatabases(fdbtest.DefaultDatabase, replicated),
+		fdbtest.WithConfig(func(cfg *config.Config) {
+			cfg.GetTransportByType(types.TCPTransportType).Config.(*config.TcpTransport).Port = c.ports[i]
+			cfg.Quorum = cnf
+		}),
+	)
+	return &testNode{
+		id:          cnf.NodeID,
+		local:       server.Provider(t, replicated).(*db.Db),
+		coordinator: server.FDB.GetQuorum(),
+	}
+}
+
+// startCluster starts a cluster of size nodes.
+func startCluster(t *testing.T, size int) []*testNode {
+	c := newCluster(t, size)
+	nodes := make([]*testNode, size)
+	for i := range nodes {
+		nodes[i] = c.start(t, i)
+	}
+	return nodes
+}
+
+func testKey(i int) []byte {
+	var key [32]byte
+	copy(key[:], fmt.Sprintf("key-%04d", i))
+	return key[:]
+}
+
+func localValue(t *testing.T, node *testNode, key []byte) []byte {
+	_, value, err := node.local.GetVersioned(key)
+	require.NoError(t, err)
+	return value
+}
+
+func TestQuorumWriteReadDelete(t *testing.T) {
+	nodes := startCluster(t, 3)
+	all := db.WithConsistency(context.Background(), messages.Consistency{R: 3, W: 3})
+
+	require.NoError(t, nodes[0].coordinator.Propose(all, replicated, types.ChangeSet, testKey(1), []byte("first")))
+	for _, node := range nodes {
+		assert.Equal(t, []byte("first"), localValue(t, node, testKey(1)), node.id)
+	}
+
+	// A later write through another coordinator wins
+	require.NoError(t, nodes[1].coordinator.Propose(context.Background(), replicated, types.ChangeSet, testKey(1), []byte("second")))
+	value, err := nodes[2].coordinator.Read(all, replicated, testKey(1))
+	require.NoError(t, err)
+	assert.Equal(t, []byte("second"), value)
+
+	require.NoError(t, nodes[2].coordinator.Propose(all, replicated, types.ChangeDelete, testKey(1), nil))
+	_, err = nodes[0].coordinator.Read(context.Background(), replicated, testKey(1))
+	assert.ErrorIs(t, err, fdbErrors.ErrNotFound)
+
+	// Quorums beyond the replicas of the key are rejected
+	tooMany := db.WithConsistency(context.Background(), messages.Consistency{W: 4})
+	assert.Error(t, nodes[0].coordinator.Propose(tooMany, replicated, types.ChangeSet, testKey(2), []byte("v")))
+}
+
+func TestQuorumReadRepair(t *testing.T) {
+	nodes := startCluster(t, 3)
+	all := db.WithConsistency(context.Background(), messages.Consistency{R: 3, W: 3})
+
+	require.NoError(t, nodes[0].coordinator.Propose(all, replicated, types.ChangeSet, testKey(1), []byte("old")))
+
+	// A single replica receives a newer write, as when the others missed it
+	old, _, err := nodes[2].local.GetVersioned(testKey(1))
+	require.NoError(t, err)
+	newer := messages.Version{Timestamp: old.Timestamp + 1, Origin: old.Origin}
+	_, err = nodes[2].local.PutVersioned(testKey(1), newer, []byte("new"))
+	require.NoError(t, err)
+
+	value, err := nodes[1].coordinator.Read(all, replicated, testKey(1))
+	require.NoError(t, err)
+	assert.Equal(t, []byte("new"), value)
+
+	assert.Eventually(t, func() bool {
+		for _, node := range nodes {
+			version, _, err := node.local.GetVersioned(testKey(1))
+			if err != nil || version != newer {
+				return false
+			}
+		}
+		return true
+	}, 5*time.Second, 20*time.Millisecond)
+	assert.Equal(t, uint64(2), nodes[1].coordinator.Status().ReadRepairs)
+}
+
+func TestQuorumHintedHandoff(t *testing.T) {
+	// The third replica is down until the writes it missed are kept as hints
+	c := newCluster(t, 3)
+	nodes := []*testNode{c.start(t, 0), c.start(t, 1)}
+	downID := c.cnf.Nodes[2].ID
+
+	ctx := db.WithConsistency(context.Background(), messages.Consistency{W: 2})
+	require.NoError(t, nodes[0].coordinator.Propose(ctx, replicated, types.ChangeSet, testKey(1), []byte("value")))
+
+	// Without the third replica a write to all of them cannot succeed
+	all := db.WithConsistency(context.Background(), messages.Consistency{W: 3})
+	err := nodes[0].coordinator.Propose(all, replicated, types.ChangeSet, testKey(2), []byte("value"))
+	assert.ErrorIs(t, err, fdbErrors.ErrQuorumNotReached)
+
+	assert.Eventually(t, func() bool {
+		return nodes[0].coordinator.Status().Hints[downID] == 2
+	}, 5*time.Second, 20*time.Millisecond)
+
+	down := c.start(t, 2)
+	assert.Eventually(t, func() bool {
+		return len(nodes[0].coordinator.Status().Hints) == 0
+	}, 5*time.Second, 20*time.Millisecond)
+	assert.Equal(t, []byte("value"), localValue(t, down, testKey(1)))
+	assert.Equal(t, []byte("value"), localValue(t, down, testKey(2)))
+}
@@ -0,0 +1,29 @@
+// Package quorum replicates databases Dynamo-style: every key is stored on N nodes, picked
+// clockwise on a consistent-hash ring over the configured nodes, and any node coordinates the
+// requests it receives.
+//
+// A write is versioned with the coordinator's clock, sent to the N replicas of the key and
+// acknowledged once W of them stored it; a delete writes a tombstone version. A read asks the N
+// replicas for their versioned value and answers with the newest one once R of them responded.
+// Replicas always keep the newest version of a key (timestamp, then origin), so concurrent
+// writes converge to the same value on every replica.
+//
+// Reads repair the replicas that answered with an older version in the background. Writes a
+// replica missed while unreachable are kept by the coordinator as hints and delivered once it is
+// back (hinted handoff). Hints are bounded and kept in memory; what they miss is left to
+// anti-entropy repair.
+//
+// Clients choose R and W per request through the consistency header of the transports (see
+// messages.WithConsistency); requests without one use the configured quorums. Replicas talk to
+// each other with replica requests (types.ReplicaHandlerType) over TCP or QUIC.
+//
+// Example usage:
+//
+//	coordinator, err := quorum.NewCoordinator(manager, cnf.Quorum)
+//	if err != nil {
+//	    log.Fatalf("Failed to create quorum coordinator: %v", err)
+//	}
+//	coordinator.Start(ctx)
+//	ctx = db.WithConsistency(ctx, messages.Consistency{R: 1, W: 3})
+//	err = coordinator.Propose(ctx, "fdb", types.ChangeSet, key, value)
+package quorum
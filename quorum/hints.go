@@ -0,0 +1,96 @@
+package quorum
+
+import (
+	"sync"
+
+	"github.com/unpackdev/fdb/messages"
+	"github.com/unpackdev/fdb/types"
+)
+
+// hint is a write kept for a replica that could not be reached, delivered once it is back.
+type hint struct {
+	database types.DbType
+	key      [32]byte
+	version  messages.Version
+	value    []byte
+}
+
+// hintStore keeps the hints of every unreachable node in memory, in the order they were
+// written. Hints are bounded; writes missed beyond the bound, or lost with a restart of the
+// coordinator, are left to anti-entropy repair.
+type hintStore struct {
+	// mu guards byNode, total and dropped.
+	mu sync.Mutex
+
+	// max bounds the number of hints kept for all nodes.
+	max int
+
+	byNode  map[string][]hint
+	total   int
+	dropped uint64
+}
+
+// newHintStore creates a hint store keeping at most max hints.
+func newHintStore(max int) *hintStore {
+	return &hintStore{max: max, byNode: make(map[string][]hint)}
+}
+
+// add keeps a hint for a node, reporting false when the store is full and the hint was dropped.
+func (s *hintStore) add(node string, h hint) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.total >= s.max {
+		s.dropped++
+		return false
+	}
+	s.byNode[node] = append(s.byNode[node], h)
+	s.total++
+	return true
+}
+
+// nodes returns the nodes with pending hints.
+func (s *hintStore) nodes() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	nodes := make([]string, 0, len(s.byNode))
+	for node := range s.byNode {
+		nodes = append(nodes, node)
+	}
+	return nodes
+}
+
+// pending returns a copy of the hints of a node, oldest first.
+func (s *hintStore) pending(node string) []hint {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]hint(nil), s.byNode[node]...)
+}
+
+// delivered removes the n oldest hints of a node. Hints added meanwhile are kept.
+func (s *hintStore) delivered(node string, n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	remaining := s.byNode[node][n:]
+	s.total -= n
+	if len(remaining) == 0 {
+		delete(s.byNode, node)
+		return
+	}
+	s.byNode[node] = remaining
+}
+
+// counts returns the number of pending hints per node and the number of dropped hints.
+func (s *hintStore) counts() (map[string]int, uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	counts := make(map[string]int, len(s.byNode))
+	for node, hints := range s.byNode {
+		counts[node] = len(hints)
+	}
+	return counts, s.dropped
+}
@@ -9,14 +9,13 @@ import (
 	transport_udp "github.com/unpackdev/fdb/transports/udp"
 	transport_uds "github.com/unpackdev/fdb/transports/uds"
 	"github.com/unpackdev/fdb/types"
-	"time"
 )
 
 // tRegistry is a transport registry mapping transport types (e.g., QUIC, TCP, UDP, UDS) to their initialization functions.
-// Each function initializes the transport, registers appropriate handlers (write, read),
-// and returns the instantiated transport or an error if initialization fails.
-var tRegistry = map[types.TransportType]func(fdb *FDB, dbP db.Provider) (transports.Transport, error){
-	types.QUICTransportType: func(fdb *FDB, dbP db.Provider) (transports.Transport, error) {
+// Each function initializes the transport, registers appropriate handlers (write, read, delete, discovery) routed
+// through the transport's database router, and returns the instantiated transport or an error if initialization fails.
+var tRegistry = map[types.TransportType]func(fdb *FDB, router *db.Router) (transports.Transport, error){
+	types.QUICTransportType: func(fdb *FDB, router *db.Router) (transports.Transport, error) {
 		quicTransport, err := fdb.GetTransportByType(types.QUICTransportType)
 		if err != nil {
 			return nil, fmt.Errorf("failed to retrieve QUIC transport: %w", err)
@@ -27,18 +26,48 @@ var tRegistry = map[types.TransportType]func(fdb *FDB, dbP db.Provider) (transpo
 			return nil, fmt.Errorf("failed to cast transport to QuicServer")
 		}
 
-		// Create a new BatchWriter with a batch size of 512 and flush interval of 1 second
-		batchWriter := db.NewBatchWriter(dbP.(*db.Db), 512, 500*time.Millisecond, 15)
-
-		wHandler := transport_quic.NewQuicWriteHandler(dbP, batchWriter)
+		wHandler := transport_quic.NewQuicWriteHandler(router)
 		quicServer.RegisterHandler(types.WriteHandlerType, wHandler.HandleMessage)
 
-		rHandler := transport_quic.NewQuicReadHandler(dbP)
+		rHandler := transport_quic.NewQuicReadHandler(router)
 		quicServer.RegisterHandler(types.ReadHandlerType, rHandler.HandleMessage)
 
+		dHandler := transport_quic.NewQuicDeleteHandler(router)
+		quicServer.RegisterHandler(types.DeleteHandlerType, dHandler.HandleMessage)
+
+		pHandler := transport_quic.NewQuicPingHandler(router)
+		quicServer.RegisterHandler(types.PingHandlerType, pHandler.HandleMessage)
+
+		bHandler := transport_quic.NewQuicMultiSetHandler(router)
+		quicServer.RegisterHandler(types.MultiSetHandlerType, bHandler.HandleMessage)
+
+		sHandler := transport_quic.NewQuicSubscribeHandler(router)
+		quicServer.RegisterHandler(types.SubscribeHandlerType, sHandler.HandleMessage)
+
+		nHandler := transport_quic.NewQuicSnapshotHandler(router)
+		quicServer.RegisterHandler(types.SnapshotHandlerType, nHandler.HandleMessage)
+
+		hHandler := transport_quic.NewQuicTreeHandler(router)
+		quicServer.RegisterHandler(types.TreeHandlerType, hHandler.HandleMessage)
+
+		if fdb.GetQuorum() != nil {
+			vHandler := transport_quic.NewQuicReplicaHandler(router)
+			quicServer.RegisterHandler(types.ReplicaHandlerType, vHandler.HandleMessage)
+		}
+
+		if fdb.config.Admin.Enabled {
+			aHandler := transport_quic.NewQuicAdminHandler(fdb.GetDbManager())
+			quicServer.RegisterHandler(types.AdminHandlerType, aHandler.HandleMessage)
+		}
+
+		if members := fdb.GetMembership(); members != nil {
+			mHandler := transport_quic.NewQuicMembersHandler(members.DiscoveryResponse)
+			quicServer.RegisterHandler(types.MembersHandlerType, mHandler.HandleMessage)
+		}
+
 		return quicTransport, nil
 	},
-	types.TCPTransportType: func(fdb *FDB, dbP db.Provider) (transports.Transport, error) {
+	types.TCPTransportType: func(fdb *FDB, router *db.Router) (transports.Transport, error) {
 		tcpTransport, err := fdb.GetTransportByType(types.TCPTransportType)
 		if err != nil {
 			return nil, fmt.Errorf("failed to retrieve TCP transport: %w", err)
@@ -49,18 +78,48 @@ var tRegistry = map[types.TransportType]func(fdb *FDB, dbP db.Provider) (transpo
 			return nil, fmt.Errorf("failed to cast transport to TcpServer")
 		}
 
-		// Create a new BatchWriter with a batch size of 512 and flush interval of 1 second
-		batchWriter := db.NewBatchWriter(dbP.(*db.Db), 512, 500*time.Millisecond, 15)
-
-		wHandler := transport_tcp.NewTCPWriteHandler(dbP, batchWriter)
+		wHandler := transport_tcp.NewTCPWriteHandler(router)
 		tcpServer.RegisterHandler(types.WriteHandlerType, wHandler.HandleMessage)
 
-		rHandler := transport_tcp.NewTCPReadHandler(dbP)
+		rHandler := transport_tcp.NewTCPReadHandler(router)
 		tcpServer.RegisterHandler(types.ReadHandlerType, rHandler.HandleMessage)
 
+		dHandler := transport_tcp.NewTCPDeleteHandler(router)
+		tcpServer.RegisterHandler(types.DeleteHandlerType, dHandler.HandleMessage)
+
+		pHandler := transport_tcp.NewTCPPingHandler(router)
+		tcpServer.RegisterHandler(types.PingHandlerType, pHandler.HandleMessage)
+
+		bHandler := transport_tcp.NewTCPMultiSetHandler(router)
+		tcpServer.RegisterHandler(types.MultiSetHandlerType, bHandler.HandleMessage)
+
+		sHandler := transport_tcp.NewTCPSubscribeHandler(router)
+		tcpServer.RegisterHandler(types.SubscribeHandlerType, sHandler.HandleMessage)
+
+		nHandler := transport_tcp.NewTCPSnapshotHandler(router)
+		tcpServer.RegisterHandler(types.SnapshotHandlerType, nHandler.HandleMessage)
+
+		hHandler := transport_tcp.NewTCPTreeHandler(router)
+		tcpServer.RegisterHandler(types.TreeHandlerType, hHandler.HandleMessage)
+
+		if fdb.GetQuorum() != nil {
+			vHandler := transport_tcp.NewTCPReplicaHandler(router)
+			tcpServer.RegisterHandler(types.ReplicaHandlerType, vHandler.HandleMessage)
+		}
+
+		if fdb.config.Admin.Enabled {
+			aHandler := transport_tcp.NewTCPAdminHandler(fdb.GetDbManager())
+			tcpServer.RegisterHandler(types.AdminHandlerType, aHandler.HandleMessage)
+		}
+
+		if members := fdb.GetMembership(); members != nil {
+			mHandler := transport_tcp.NewTCPMembersHandler(members.DiscoveryResponse)
+			tcpServer.RegisterHandler(types.MembersHandlerType, mHandler.HandleMessage)
+		}
+
 		return tcpTransport, nil
 	},
-	types.UDSTransportType: func(fdb *FDB, dbP db.Provider) (transports.Transport, error) {
+	types.UDSTransportType: func(fdb *FDB, router *db.Router) (transports.Transport, error) {
 		udsTransport, err := fdb.GetTransportByType(types.UDSTransportType)
 		if err != nil {
 			return nil, fmt.Errorf("failed to retrieve UDS transport: %w", err)
@@ -71,19 +130,30 @@ var tRegistry = map[types.TransportType]func(fdb *FDB, dbP db.Provider) (transpo
 			return nil, fmt.Errorf("failed to cast transport to UdsServer")
 		}
 
-		// Create a new BatchWriter with a batch size of 512 and flush interval of 1 second
-		batchWriter := db.NewBatchWriter(dbP.(*db.Db), 512, 500*time.Millisecond, 15)
-
 		// Register write and read handlers
-		wHandler := transport_uds.NewUDSWriteHandler(dbP, batchWriter)
+		wHandler := transport_uds.NewUDSWriteHandler(router)
 		udsServer.RegisterHandler(types.WriteHandlerType, wHandler.HandleMessage)
 
-		rHandler := transport_uds.NewUDSReadHandler(dbP)
+		rHandler := transport_uds.NewUDSReadHandler(router)
 		udsServer.RegisterHandler(types.ReadHandlerType, rHandler.HandleMessage)
 
+		dHandler := transport_uds.NewUDSDeleteHandler(router)
+		udsServer.RegisterHandler(types.DeleteHandlerType, dHandler.HandleMessage)
+
+		pHandler := transport_uds.NewUDSPingHandler(router)
+		udsServer.RegisterHandler(types.PingHandlerType, pHandler.HandleMessage)
+
+		bHandler := transport_uds.NewUDSMultiSetHandler(router)
+		udsServer.RegisterHandler(types.MultiSetHandlerType, bHandler.HandleMessage)
+
+		if fdb.config.Admin.Enabled {
+			aHandler := transport_uds.NewUDSAdminHandler(fdb.GetDbManager())
+			udsServer.RegisterHandler(types.AdminHandlerType, aHandler.HandleMessage)
+		}
+
 		return udsTransport, nil
 	},
-	types.UDPTransportType: func(fdb *FDB, dbP db.Provider) (transports.Transport, error) {
+	types.UDPTransportType: func(fdb *FDB, router *db.Router) (transports.Transport, error) {
 		udpTransport, err := fdb.GetTransportByType(types.UDPTransportType)
 		if err != nil {
 			return nil, fmt.Errorf("failed to retrieve UDP transport: %w", err)
@@ -94,15 +164,23 @@ var tRegistry = map[types.TransportType]func(fdb *FDB, dbP db.Provider) (transpo
 			return nil, fmt.Errorf("failed to cast transport to UdpServer")
 		}
 
-		// Create a new BatchWriter with a batch size of 512 and flush interval of 1 second
-		batchWriter := db.NewBatchWriter(dbP.(*db.Db), 512, 500*time.Millisecond, 15)
-
-		wHandler := transport_udp.NewUDPWriteHandler(dbP, batchWriter)
+		wHandler := transport_udp.NewUDPWriteHandler(router)
 		udpServer.RegisterHandler(types.WriteHandlerType, wHandler.HandleMessage)
 
-		rHandler := transport_udp.NewUDPReadHandler(dbP)
+		rHandler := transport_udp.NewUDPReadHandler(router)
 		udpServer.RegisterHandler(types.ReadHandlerType, rHandler.HandleMessage)
 
+		dHandler := transport_udp.NewUDPDeleteHandler(router)
+		udpServer.RegisterHandler(types.DeleteHandlerType, dHandler.HandleMessage)
+
+		pHandler := transport_udp.NewUDPPingHandler(router)
+		udpServer.RegisterHandler(types.PingHandlerType, pHandler.HandleMessage)
+
+		if fdb.config.Admin.Enabled {
+			aHandler := transport_udp.NewUDPAdminHandler(fdb.GetDbManager())
+			udpServer.RegisterHandler(types.AdminHandlerType, aHandler.HandleMessage)
+		}
+
 		return udpTransport, nil
 	},
 }
@@ -0,0 +1,622 @@
+package remote
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/quic-go/quic-go"
+	"github.com/unpackdev/fdb/db"
+	fdbErrors "github.com/unpackdev/fdb/errors"
+	"github.com/unpackdev/fdb/messages"
+	"github.com/unpackdev/fdb/types"
+)
+
+const (
+	// quicNextProto is the ALPN protocol negotiated by the QUIC transport.
+	quicNextProto = "quic-example"
+
+	// tcpReadBufferSize bounds the response of a single TCP request, see Client.
+	tcpReadBufferSize = 64 * 1024
+
+	// maxResponseLen bounds the value returned by a single QUIC read.
+	maxResponseLen = 64 << 20
+)
+
+// Text responses of the read handlers. Missing keys and failed reads are answered alike.
+var (
+	notFoundResponses = [][]byte{
+		[]byte("Error reading from database"),
+		[]byte("No value found for key"),
+	}
+	invalidMessageResponse = []byte("Invalid message format")
+)
+
+// Options describes the node a Client talks to.
+type Options struct {
+	// Transport is the node transport requests are sent through, tcp or quic.
+	Transport types.TransportType
+
+	// Addr is the node transport address (host:port).
+	Addr string
+
+	// Insecure skips verifying the node certificate when the transport uses TLS.
+	Insecure bool
+
+	// PoolSize is the maximum number of TCP connections, each carrying one request at a time.
+	// QUIC multiplexes every request over a single connection.
+	PoolSize int
+
+	// Timeout bounds a single request unless the context expires earlier.
+	Timeout time.Duration
+}
+
+// Client sends key-value requests to a single fdb node over its TCP or QUIC transport.
+//
+// TCP responses are not framed: a read is answered with the raw value, which must arrive in
+// a single read of at most 64KB, and the 1-byte values 0x01 and 0x02 cannot be told apart
+// from the error statuses. QUIC responses are length-prefixed and have neither limitation.
+type Client struct {
+	opts Options
+
+	// slots limits the number of TCP connections in use.
+	slots chan struct{}
+
+	// idle holds the TCP connections ready for the next request.
+	idle chan net.Conn
+
+	// mu guards conn and closed.
+	mu sync.Mutex
+
+	// conn is the QUIC connection shared by every request, dialed on first use.
+	conn quic.Connection
+
+	closed bool
+}
+
+// New creates a Client of the node described by opts. Connections are established lazily,
+// by the first request that needs one.
+//
+// Example usage:
+//
+//	client, err := remote.New(remote.Options{Transport: types.TCPTransportType, Addr: "10.0.0.2:5011"})
+//	if err != nil {
+//	    log.Fatalf("Failed to create client: %v", err)
+//	}
+//	defer client.Close()
+//
+// Parameters:
+//
+//	opts (Options): The node to talk to and the connection settings.
+//
+// Returns:
+//
+//	*Client: A new Client instance.
+//	error: Returns an error if the transport is not supported or the address is empty.
+func New(opts Options) (*Client, error) {
+	if opts.Transport != types.TCPTransportType && opts.Transport != types.QUICTransportType {
+		return nil, fmt.Errorf("unsupported remote transport: %s", opts.Transport)
+	}
+	if opts.Addr == "" {
+		return nil, errors.New("remote addr must not be empty")
+	}
+	if opts.PoolSize <= 0 {
+		opts.PoolSize = 1
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = 5 * time.Second
+	}
+
+	return &Client{
+		opts:  opts,
+		slots: make(chan struct{}, opts.PoolSize),
+		idle:  make(chan net.Conn, opts.PoolSize),
+	}, nil
+}
+
+// Addr returns the address of the node.
+func (c *Client) Addr() string {
+	return c.opts.Addr
+}
+
+// Get returns the value of key in the given database, empty selecting the node's default database.
+//
+// Returns:
+//
+//	[]byte: The stored value.
+//	error: Returns errors.ErrNotFound if the node has no value for the key, or a request error.
+func (c *Client) Get(ctx context.Context, database string, key [32]byte) ([]byte, error) {
+	resp, err := c.do(ctx, database, types.ReadHandlerType, key, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.opts.Transport == types.QUICTransportType && len(resp) >= 4 && int(binary.BigEndian.Uint32(resp)) == len(resp)-4 {
+		return resp[4:], nil
+	}
+	if len(resp) == 1 {
+		if status := types.ResponseStatus(resp[0]); status == types.StatusError || status == types.StatusDatabaseNotFound {
+			return nil, statusError(status)
+		}
+	}
+	for _, notFound := range notFoundResponses {
+		if bytes.Equal(resp, notFound) {
+			return nil, fdbErrors.ErrNotFound
+		}
+	}
+	if bytes.Equal(resp, invalidMessageResponse) {
+		return nil, errors.New("node rejected the read request")
+	}
+	if c.opts.Transport == types.QUICTransportType {
+		return nil, fmt.Errorf("unexpected read response: %q", resp)
+	}
+	return resp, nil
+}
+
+// Set stores the value of key in the given database. The node acknowledges once the write
+// is buffered, it becomes visible when the node's batch writer flushes.
+func (c *Client) Set(ctx context.Context, database string, key [32]byte, value []byte) error {
+	if len(value) == 0 {
+		return errors.New("remote writes require a value")
+	}
+	return c.exec(ctx, database, types.WriteHandlerType, key, value)
+}
+
+// Delete removes key from the given database.
+func (c *Client) Delete(ctx context.Context, database string, key [32]byte) error {
+	return c.exec(ctx, database, types.DeleteHandlerType, key, nil)
+}
+
+// Snapshot streams a consistent copy of the given database from the node, calling each for
+// every key-value pair. Keys and values must be copied if retained.
+//
+// Returns:
+//
+//	error: Returns the first callback error, or an error if the node fails the snapshot.
+func (c *Client) Snapshot(ctx context.Context, database string, each func(key, value []byte) error) error {
+	request := messages.SnapshotRequest{}
+	stream, err := c.openStream(ctx, database, request.Encode())
+	if err != nil {
+		return err
+	}
+	defer stream.Close()
+
+	// Unblock pending reads once the context is done
+	stop := context.AfterFunc(ctx, func() { _ = stream.Close() })
+	defer stop()
+
+	reader := bufio.NewReader(stream)
+	body, err := readFrame(reader)
+	if err != nil {
+		return err
+	}
+	if _, err := messages.DecodeSnapshotHead(body); err != nil {
+		return err
+	}
+
+	for {
+		body, err := readFrame(reader)
+		if err != nil {
+			return err
+		}
+		if len(body) == 0 {
+			return nil
+		}
+
+		key, value, err := messages.DecodeSnapshotEntry(body)
+		if err != nil {
+			return err
+		}
+		if err := each(key, value); err != nil {
+			return err
+		}
+	}
+}
+
+// TreeHashes returns the hashes of the given nodes of one level of the Merkle tree summarising
+// the given database on the node, see db.Db.MerkleHashes.
+//
+// Returns:
+//
+//	[]db.MerkleHash: The node hashes, in the order of indices.
+//	error: Returns an error if the node fails the request.
+func (c *Client) TreeHashes(ctx context.Context, database string, level int, indices []uint32) ([]db.MerkleHash, error) {
+	request := messages.TreeRequest{Op: messages.TreeHashes, Level: uint8(level), Indices: indices}
+	frame, err := request.Encode()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.opts.Timeout)
+	defer cancel()
+
+	stream, err := c.openStream(ctx, database, frame)
+	if err != nil {
+		return nil, err
+	}
+	defer stream.Close()
+
+	stop := context.AfterFunc(ctx, func() { _ = stream.Close() })
+	defer stop()
+
+	body, err := readFrame(bufio.NewReader(stream))
+	if err != nil {
+		return nil, err
+	}
+	if len(body) != len(indices)*len(db.MerkleHash{}) {
+		return nil, fmt.Errorf("unexpected tree hashes length: %d bytes for %d nodes", len(body), len(indices))
+	}
+
+	hashes := make([]db.MerkleHash, len(indices))
+	for i := range hashes {
+		copy(hashes[i][:], body[i*len(db.MerkleHash{}):])
+	}
+	return hashes, nil
+}
+
+// TreeRange streams the key-value pairs of the given database covered by the given Merkle
+// tree leaves, in key order, calling each for every pair. Keys and values must be copied if
+// retained.
+//
+// Returns:
+//
+//	error: Returns the first callback error, or an error if the node fails the request.
+func (c *Client) TreeRange(ctx context.Context, database string, leaves []uint32, each func(key, value []byte) error) error {
+	request := messages.TreeRequest{Op: messages.TreeRange, Indices: leaves}
+	frame, err := request.Encode()
+	if err != nil {
+		return err
+	}
+
+	stream, err := c.openStream(ctx, database, frame)
+	if err != nil {
+		return err
+	}
+	defer stream.Close()
+
+	stop := context.AfterFunc(ctx, func() { _ = stream.Close() })
+	defer stop()
+
+	reader := bufio.NewReader(stream)
+	for {
+		body, err := readFrame(reader)
+		if err != nil {
+			return err
+		}
+		if len(body) == 0 {
+			return nil
+		}
+
+		key, value, err := messages.DecodeSnapshotEntry(body)
+		if err != nil {
+			return err
+		}
+		if err := each(key, value); err != nil {
+			return err
+		}
+	}
+}
+
+// ReplicaGet reads the versioned value of key from the node's replica of the given database,
+// see messages.ReplicaRequest.
+//
+// Returns:
+//
+//	messages.Version: The version held by the replica, zero if it never saw the key.
+//	[]byte: The value, empty if the key is missing or deleted.
+//	error: Returns an error if the node fails the request.
+func (c *Client) ReplicaGet(ctx context.Context, database string, key [32]byte) (messages.Version, []byte, error) {
+	request := messages.ReplicaRequest{Op: messages.ReplicaGet, Key: key}
+	resp, err := c.replicaRoundTrip(ctx, database, request.Encode())
+	if err != nil {
+		return messages.Version{}, nil, err
+	}
+	if len(resp) == 1 {
+		return messages.Version{}, nil, statusError(types.ResponseStatus(resp[0]))
+	}
+	if len(resp) == 0 || types.ResponseStatus(resp[0]) != types.StatusOK {
+		return messages.Version{}, nil, fmt.Errorf("unexpected replica response: %q", resp)
+	}
+	return messages.DecodeReplicaValue(resp[1:])
+}
+
+// ReplicaPut writes a versioned value, or a tombstone, to the node's replica of the given
+// database. Replicas holding a newer version keep it and acknowledge the write.
+func (c *Client) ReplicaPut(ctx context.Context, database string, key [32]byte, version messages.Version, value []byte) error {
+	request := messages.ReplicaRequest{Op: messages.ReplicaPut, Key: key, Version: version, Value: value}
+	resp, err := c.replicaRoundTrip(ctx, database, request.Encode())
+	if err != nil {
+		return err
+	}
+	if len(resp) != 1 {
+		return fmt.Errorf("unexpected replica response: %q", resp)
+	}
+	return statusError(types.ResponseStatus(resp[0]))
+}
+
+// replicaRoundTrip sends a replica request and returns the raw response.
+func (c *Client) replicaRoundTrip(ctx context.Context, database string, request []byte) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.opts.Timeout)
+	defer cancel()
+
+	if c.opts.Transport == types.QUICTransportType {
+		message := messages.Message{Database: database, Handler: types.ReplicaHandlerType, Data: request}
+		frame, err := message.Encode()
+		if err != nil {
+			return nil, err
+		}
+		return c.quicRoundTrip(ctx, frame)
+	}
+
+	frame, err := messages.WithDatabase(database, request)
+	if err != nil {
+		return nil, err
+	}
+	return c.tcpRoundTrip(ctx, frame)
+}
+
+// Close closes every connection to the node. Requests in flight fail.
+func (c *Client) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return nil
+	}
+	c.closed = true
+
+	for {
+		select {
+		case conn := <-c.idle:
+			_ = conn.Close()
+		default:
+			if c.conn != nil {
+				return c.conn.CloseWithError(0, "")
+			}
+			return nil
+		}
+	}
+}
+
+// exec sends a request answered with a single status byte.
+func (c *Client) exec(ctx context.Context, database string, handler types.HandlerType, key [32]byte, value []byte) error {
+	resp, err := c.do(ctx, database, handler, key, value)
+	if err != nil {
+		return err
+	}
+	if len(resp) != 1 {
+		return fmt.Errorf("unexpected response to handler %c: %q", handler, resp)
+	}
+	return statusError(types.ResponseStatus(resp[0]))
+}
+
+// do sends a single request and returns the raw response.
+func (c *Client) do(ctx context.Context, database string, handler types.HandlerType, key [32]byte, value []byte) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.opts.Timeout)
+	defer cancel()
+
+	// A consistency requested by the client is forwarded to the node
+	consistency := db.ConsistencyFromContext(ctx)
+
+	if c.opts.Transport == types.QUICTransportType {
+		message := messages.Message{Consistency: consistency, Database: database, Handler: handler, Key: key, Data: value}
+		frame, err := message.Encode()
+		if err != nil {
+			return nil, err
+		}
+		return c.quicRoundTrip(ctx, frame)
+	}
+
+	frame := make([]byte, 1+32+len(value))
+	frame[0] = byte(handler)
+	copy(frame[1:33], key[:])
+	copy(frame[33:], value)
+	frame, err := messages.WithDatabase(database, frame)
+	if err != nil {
+		return nil, err
+	}
+	return c.tcpRoundTrip(ctx, messages.WithConsistency(consistency, frame))
+}
+
+// tcpRoundTrip sends the frame over a pooled connection and reads the response.
+func (c *Client) tcpRoundTrip(ctx context.Context, frame []byte) ([]byte, error) {
+	conn, err := c.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	deadline, _ := ctx.Deadline()
+	if err := conn.SetDeadline(deadline); err != nil {
+		c.release(conn, false)
+		return nil, err
+	}
+	if _, err := conn.Write(frame); err != nil {
+		c.release(conn, false)
+		return nil, errors.Wrapf(err, "failed to send request to %s", c.opts.Addr)
+	}
+
+	buf := make([]byte, tcpReadBufferSize)
+	n, err := conn.Read(buf)
+	if err != nil {
+		c.release(conn, false)
+		return nil, errors.Wrapf(err, "failed to read response from %s", c.opts.Addr)
+	}
+
+	c.release(conn, true)
+	return buf[:n], nil
+}
+
+// acquire returns an idle TCP connection, or dials a new one while the pool has room.
+func (c *Client) acquire(ctx context.Context) (net.Conn, error) {
+	select {
+	case c.slots <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	select {
+	case conn := <-c.idle:
+		return conn, nil
+	default:
+	}
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", c.opts.Addr)
+	if err != nil {
+		<-c.slots
+		return nil, errors.Wrapf(err, "failed to connect to %s", c.opts.Addr)
+	}
+	return conn, nil
+}
+
+// release returns a connection to the pool, or closes it when it is no longer usable.
+func (c *Client) release(conn net.Conn, reuse bool) {
+	c.mu.Lock()
+	closed := c.closed
+	c.mu.Unlock()
+
+	if reuse && !closed {
+		c.idle <- conn
+	} else {
+		_ = conn.Close()
+	}
+	<-c.slots
+}
+
+// quicRoundTrip sends the frame on a new stream and reads the response until the node closes it.
+func (c *Client) quicRoundTrip(ctx context.Context, frame []byte) ([]byte, error) {
+	stream, err := c.openQuicStream(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer stream.CancelRead(0)
+
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = stream.SetDeadline(deadline)
+	}
+	if _, err := stream.Write(frame); err != nil {
+		return nil, errors.Wrapf(err, "failed to send request to %s", c.opts.Addr)
+	}
+	// Closing the send side lets the node end the stream after its response
+	if err := stream.Close(); err != nil {
+		return nil, err
+	}
+
+	resp, err := io.ReadAll(io.LimitReader(stream, 4+maxResponseLen))
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read response from %s", c.opts.Addr)
+	}
+	return resp, nil
+}
+
+// openQuicStream opens a stream on the shared QUIC connection, dialing it if needed.
+func (c *Client) openQuicStream(ctx context.Context) (quic.Stream, error) {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return nil, errors.New("remote client is closed")
+	}
+	if c.conn == nil || c.conn.Context().Err() != nil {
+		tlsConfig := &tls.Config{
+			InsecureSkipVerify: c.opts.Insecure,
+			NextProtos:         []string{quicNextProto},
+		}
+		conn, err := quic.DialAddr(ctx, c.opts.Addr, tlsConfig, nil)
+		if err != nil {
+			c.mu.Unlock()
+			return nil, errors.Wrapf(err, "failed to connect to %s", c.opts.Addr)
+		}
+		c.conn = conn
+	}
+	conn := c.conn
+	c.mu.Unlock()
+
+	stream, err := conn.OpenStreamSync(ctx)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to open stream to %s", c.opts.Addr)
+	}
+	return stream, nil
+}
+
+// openStream sends a streaming request on a dedicated TCP connection or QUIC stream.
+func (c *Client) openStream(ctx context.Context, database string, request []byte) (io.ReadCloser, error) {
+	if c.opts.Transport == types.QUICTransportType {
+		stream, err := c.openQuicStream(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		// The QUIC transport expects every request wrapped in a message
+		message := messages.Message{Database: database, Handler: types.HandlerType(request[0]), Data: request}
+		frame, err := message.Encode()
+		if err == nil {
+			_, err = stream.Write(frame)
+		}
+		if err != nil {
+			stream.CancelRead(0)
+			_ = stream.Close()
+			return nil, errors.Wrapf(err, "failed to send request to %s", c.opts.Addr)
+		}
+		return &quicStream{Stream: stream}, nil
+	}
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", c.opts.Addr)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to connect to %s", c.opts.Addr)
+	}
+	frame, err := messages.WithDatabase(database, request)
+	if err == nil {
+		_, err = conn.Write(frame)
+	}
+	if err != nil {
+		_ = conn.Close()
+		return nil, errors.Wrapf(err, "failed to send request to %s", c.opts.Addr)
+	}
+	return conn, nil
+}
+
+// quicStream stops reading the stream when it is closed.
+type quicStream struct {
+	quic.Stream
+}
+
+// Close closes both directions of the stream.
+func (s *quicStream) Close() error {
+	s.Stream.CancelRead(0)
+	return s.Stream.Close()
+}
+
+// readFrame reads the next stream frame, failing on any status other than StatusOK.
+func readFrame(reader *bufio.Reader) ([]byte, error) {
+	status, body, err := messages.ReadStreamFrame(reader)
+	if err != nil {
+		return nil, err
+	}
+	if status != types.StatusOK {
+		return nil, fmt.Errorf("node failed the stream (status %d): %s", status, body)
+	}
+	return body, nil
+}
+
+// statusError returns the error a response status stands for, nil for StatusOK.
+func statusError(status types.ResponseStatus) error {
+	switch status {
+	case types.StatusOK:
+		return nil
+	case types.StatusReadOnly:
+		return fdbErrors.ErrReadOnly
+	case types.StatusDatabaseNotFound:
+		return fdbErrors.ErrDatabaseNotFound
+	default:
+		return fmt.Errorf("node answered with status %d", status)
+	}
+}
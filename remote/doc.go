@@ -0,0 +1,15 @@
+// Package remote implements a minimal client of a single fdb node, used by the components that
+// forward requests to other nodes, such as the sharding proxy.
+//
+// A Client speaks the wire protocol of the TCP and QUIC transports: reads, writes and deletes
+// of single keys, each answered by the node before the next request on the same connection is
+// sent, and snapshot streams on dedicated connections.
+//
+// Example usage:
+//
+//	client, err := remote.New(remote.Options{Transport: types.QUICTransportType, Addr: "10.0.0.2:4433", Insecure: true})
+//	if err != nil {
+//	    log.Fatalf("Failed to create client: %v", err)
+//	}
+//	value, err := client.Get(ctx, "fdb", key)
+package remote
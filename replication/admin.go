@@ -0,0 +1,44 @@
+package replication
+
+import (
+	"encoding/json"
+
+	"github.com/pkg/errors"
+	"github.com/unpackdev/fdb/config"
+	"github.com/unpackdev/fdb/db"
+	"github.com/unpackdev/fdb/messages"
+)
+
+// FollowRequest is the JSON payload of a messages.AdminFollow request.
+type FollowRequest struct {
+	// Leader is the node to replicate from.
+	Leader config.ReplicationPeer `json:"leader"`
+
+	// Resync discards the local data and bootstraps from a fresh snapshot.
+	Resync bool `json:"resync"`
+}
+
+// RegisterAdminOps exposes the replication status, promotion and follow operations of the
+// node through the admin handlers of the manager's transports.
+//
+// Parameters:
+//
+//	manager (*db.Manager): The manager dispatching admin requests.
+//	node (*Node): The replication node the operations apply to.
+func RegisterAdminOps(manager *db.Manager, node *Node) {
+	manager.RegisterAdminOp(messages.AdminReplicationStatus, func(_ *messages.AdminRequest) ([]byte, error) {
+		return json.Marshal(node.Status())
+	})
+
+	manager.RegisterAdminOp(messages.AdminPromote, func(_ *messages.AdminRequest) ([]byte, error) {
+		return nil, node.Promote()
+	})
+
+	manager.RegisterAdminOp(messages.AdminFollow, func(req *messages.AdminRequest) ([]byte, error) {
+		var follow FollowRequest
+		if err := json.Unmarshal(req.Payload, &follow); err != nil {
+			return nil, errors.Wrap(err, "failure to decode follow request")
+		}
+		return nil, node.Follow(follow.Leader, follow.Resync)
+	})
+}
@@ -0,0 +1,29 @@
+// Package replication implements asynchronous leader-follower replication between fdb nodes.
+//
+// A leader is an ordinary node with change data capture enabled on the replicated databases.
+// It serves snapshots and change log subscriptions through its TCP and QUIC transports.
+//
+// A follower connects to the leader through one of those transports and, for every replicated
+// database:
+//
+//   - **Bootstraps** from a consistent snapshot when it has never replicated, when its data
+//     diverged from the leader (local writes, a leader that is behind) or when the changes it
+//     needs were already pruned from the leader's change log.
+//
+//   - **Streams** the leader's change log from the change following its replication position
+//     and applies every change in order, in the same transaction as the new position, so a
+//     restart resumes exactly where it stopped.
+//
+// Replicated databases reject client writes while the node follows a leader and keep serving
+// reads. Replication lag is exported as OpenTelemetry gauges. Failover is manual: promoting a
+// follower stops replication and makes its databases writable, other followers are then
+// pointed at it with Follow. Both are exposed as admin operations, see RegisterAdminOps.
+//
+// Example usage:
+//
+//	node := replication.NewNode(manager, cnf.Replication)
+//	if err := node.Start(ctx); err != nil {
+//	    log.Fatalf("Failed to start replication: %v", err)
+//	}
+//	defer node.Stop()
+package replication
@@ -0,0 +1,375 @@
+package replication
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/unpackdev/fdb/config"
+	"github.com/unpackdev/fdb/db"
+	fdbErrors "github.com/unpackdev/fdb/errors"
+	"github.com/unpackdev/fdb/messages"
+	"github.com/unpackdev/fdb/types"
+	"go.uber.org/zap"
+)
+
+const (
+	// applyBatchSize is the maximum number of changes applied in a single transaction.
+	applyBatchSize = 512
+
+	// restoreBatchSize is the maximum number of snapshot pairs written in a single transaction.
+	restoreBatchSize = 1024
+)
+
+// errResync is returned by a sync pass when the database has to be bootstrapped again from a snapshot.
+var errResync = errors.New("replica diverged from the leader")
+
+// dbState tracks the replication progress of a single database.
+type dbState struct {
+	mu sync.Mutex
+
+	// position is the last applied sequence number.
+	position uint64
+
+	// leaderHead is the newest sequence number reported by the leader.
+	leaderHead uint64
+
+	// appliedAt is the leader commit time of the last applied change, in Unix nanoseconds.
+	appliedAt int64
+
+	// connected reports whether the change stream is established.
+	connected bool
+
+	// lastError is the error that ended the last sync pass, if any.
+	lastError string
+}
+
+// status returns a copy of the state as a DbStatus.
+func (s *dbState) status(name types.DbType) DbStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	status := DbStatus{
+		Name:       name.String(),
+		Position:   s.position,
+		LeaderHead: s.leaderHead,
+		Connected:  s.connected,
+		LastError:  s.lastError,
+	}
+
+	// Once caught up there is no lag, however old the last change is
+	if s.leaderHead > s.position {
+		status.LagSequences = s.leaderHead - s.position
+		if s.appliedAt > 0 {
+			status.LagSeconds = time.Since(time.Unix(0, s.appliedAt)).Seconds()
+		}
+	}
+	return status
+}
+
+// follower replicates a set of databases from a single leader, one goroutine per database.
+type follower struct {
+	manager   *db.Manager
+	leader    config.ReplicationPeer
+	retry     time.Duration
+	databases []types.DbType
+	states    map[types.DbType]*dbState
+	cancel    context.CancelFunc
+	wg        sync.WaitGroup
+}
+
+// newFollower creates a follower of the given leader. It does not start replicating.
+func newFollower(manager *db.Manager, leader config.ReplicationPeer, retry time.Duration, databases []types.DbType) *follower {
+	f := &follower{
+		manager:   manager,
+		leader:    leader,
+		retry:     retry,
+		databases: databases,
+		states:    make(map[types.DbType]*dbState, len(databases)),
+	}
+	for _, name := range databases {
+		f.states[name] = &dbState{}
+	}
+	return f
+}
+
+// start launches the replication loop of every database.
+func (f *follower) start(ctx context.Context) {
+	ctx, f.cancel = context.WithCancel(ctx)
+
+	for _, name := range f.databases {
+		f.wg.Add(1)
+		go func(name types.DbType) {
+			defer f.wg.Done()
+			f.run(ctx, name)
+		}(name)
+	}
+}
+
+// stop cancels the replication loops and waits for them to exit.
+func (f *follower) stop() {
+	if f.cancel != nil {
+		f.cancel()
+	}
+	f.wg.Wait()
+}
+
+// run keeps the database in sync with the leader until ctx is done, reconnecting after failures.
+func (f *follower) run(ctx context.Context, name types.DbType) {
+	state := f.states[name]
+	resync := false
+
+	for {
+		err := f.sync(ctx, name, state, resync)
+		if ctx.Err() != nil {
+			return
+		}
+
+		resync = errors.Is(err, errResync)
+		state.mu.Lock()
+		state.connected = false
+		if err != nil {
+			state.lastError = err.Error()
+		}
+		state.mu.Unlock()
+
+		zap.L().Warn(
+			"Replication interrupted",
+			zap.String("database", name.String()),
+			zap.String("leader", f.leader.Addr),
+			zap.Bool("resync", resync),
+			zap.Error(err),
+		)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(f.retry):
+		}
+	}
+}
+
+// sync performs a single replication pass: bootstrap from a snapshot when needed, then apply
+// the change stream until it fails.
+func (f *follower) sync(ctx context.Context, name types.DbType, state *dbState, resync bool) error {
+	provider, err := f.manager.GetDb(name)
+	if err != nil {
+		return err
+	}
+	bDb, ok := provider.(*db.Db)
+	if !ok {
+		return fmt.Errorf("mdbx database %s does not support replication", name)
+	}
+
+	position, err := bDb.ReplicationPosition()
+	if err != nil {
+		return err
+	}
+	head, err := bDb.HeadSequence()
+	if err != nil {
+		return err
+	}
+
+	// Changes past the replication position were not received from the leader, the data diverged
+	if resync || position == 0 || head != position {
+		if position, err = f.snapshot(ctx, name, bDb, state); err != nil {
+			return err
+		}
+	}
+
+	state.mu.Lock()
+	state.position = position
+	state.lastError = ""
+	state.mu.Unlock()
+
+	return f.stream(ctx, name, bDb, state, position)
+}
+
+// snapshot replaces the content of the database with a snapshot of the leader and returns
+// the sequence number replication continues after.
+func (f *follower) snapshot(ctx context.Context, name types.DbType, bDb *db.Db, state *dbState) (uint64, error) {
+	zap.L().Info("Bootstrapping replica from snapshot", zap.String("database", name.String()), zap.String("leader", f.leader.Addr))
+
+	request := messages.SnapshotRequest{}
+	stream, err := openStream(ctx, f.leader, name.String(), request.Encode())
+	if err != nil {
+		return 0, err
+	}
+	defer stream.Close()
+	reader := bufio.NewReader(stream)
+
+	body, err := readFrame(reader)
+	if err != nil {
+		return 0, err
+	}
+	head, err := messages.DecodeSnapshotHead(body)
+	if err != nil {
+		return 0, err
+	}
+
+	// The position stays at zero until the snapshot completes, an interrupted restore starts over
+	if err := bDb.ResetReplica(); err != nil {
+		return 0, err
+	}
+
+	var (
+		pairs = make([]db.KeyValue, 0, restoreBatchSize)
+		total int
+	)
+	for {
+		body, err := readFrame(reader)
+		if err != nil {
+			return 0, err
+		}
+		if len(body) > 0 {
+			key, value, err := messages.DecodeSnapshotEntry(body)
+			if err != nil {
+				return 0, err
+			}
+			pairs = append(pairs, db.KeyValue{Key: key, Value: value})
+		}
+
+		if len(pairs) == restoreBatchSize || (len(body) == 0 && len(pairs) > 0) {
+			if err := bDb.RestoreSnapshot(pairs); err != nil {
+				return 0, err
+			}
+			total += len(pairs)
+			pairs = pairs[:0]
+		}
+		if len(body) == 0 {
+			break
+		}
+	}
+
+	if err := bDb.CompleteSnapshot(head); err != nil {
+		return 0, err
+	}
+
+	state.mu.Lock()
+	state.leaderHead = head
+	state.mu.Unlock()
+
+	zap.L().Info(
+		"Replica bootstrapped from snapshot",
+		zap.String("database", name.String()),
+		zap.Uint64("sequence", head),
+		zap.Int("keys", total),
+	)
+	return head, nil
+}
+
+// stream subscribes to the leader's change log after position and applies the changes in order.
+func (f *follower) stream(ctx context.Context, name types.DbType, bDb *db.Db, state *dbState, position uint64) error {
+	request := messages.SubscribeRequest{From: position + 1}
+	stream, err := openStream(ctx, f.leader, name.String(), request.Encode())
+	if err != nil {
+		return err
+	}
+	defer stream.Close()
+	reader := bufio.NewReader(stream)
+
+	state.mu.Lock()
+	state.connected = true
+	state.mu.Unlock()
+
+	zap.L().Info("Streaming changes from leader", zap.String("database", name.String()), zap.Uint64("from", position+1))
+
+	batch := make([]*messages.Change, 0, applyBatchSize)
+	for {
+		status, body, err := messages.ReadStreamFrame(reader)
+		if err != nil {
+			return errors.Wrap(err, "failed to read change stream")
+		}
+
+		switch status {
+		case types.StatusOK:
+			change, err := messages.DecodeChange(body)
+			if err != nil {
+				return err
+			}
+			batch = append(batch, change)
+		case types.StatusHeartbeat:
+			heartbeat, err := messages.DecodeHeartbeat(body)
+			if err != nil {
+				return err
+			}
+			// A leader behind the replica lost changes the replica applied
+			if heartbeat.Head < position {
+				return errors.Wrapf(errResync, "leader head %d is behind the local position %d", heartbeat.Head, position)
+			}
+			state.mu.Lock()
+			state.leaderHead = heartbeat.Head
+			state.mu.Unlock()
+		default:
+			return leaderError(status, body)
+		}
+
+		// Apply once the buffered frames are consumed, so a burst of changes shares a transaction
+		if len(batch) == 0 || (len(batch) < applyBatchSize && frameBuffered(reader)) {
+			continue
+		}
+
+		if err := bDb.ApplyChanges(batch); err != nil {
+			if errors.Is(err, fdbErrors.ErrReplicationGap) {
+				return errors.Wrap(errResync, err.Error())
+			}
+			return err
+		}
+
+		last := batch[len(batch)-1]
+		position = last.Seq
+
+		state.mu.Lock()
+		state.position = position
+		state.appliedAt = last.Timestamp
+		if position > state.leaderHead {
+			state.leaderHead = position
+		}
+		state.mu.Unlock()
+
+		batch = batch[:0]
+	}
+}
+
+// frameBuffered reports whether the next stream frame is already fully buffered.
+func frameBuffered(reader *bufio.Reader) bool {
+	// Peek blocks until enough bytes arrive, so only peek at what is already buffered
+	if reader.Buffered() < 4 {
+		return false
+	}
+	head, err := reader.Peek(4)
+	if err != nil {
+		return false
+	}
+	return reader.Buffered() >= 4+int(binary.BigEndian.Uint32(head))
+}
+
+// readFrame reads a snapshot frame and returns its body, or the error sent by the leader.
+func readFrame(reader *bufio.Reader) ([]byte, error) {
+	status, body, err := messages.ReadStreamFrame(reader)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read snapshot stream")
+	}
+	if status != types.StatusOK {
+		return nil, leaderError(status, body)
+	}
+	return body, nil
+}
+
+// leaderError converts an error frame sent by the leader into an error. Pruned changes
+// require a new snapshot.
+func leaderError(status types.ResponseStatus, body []byte) error {
+	message := string(body)
+	if strings.Contains(message, fdbErrors.ErrChangesTruncated.Error()) {
+		return errors.Wrap(errResync, message)
+	}
+	if status == types.StatusDatabaseNotFound {
+		return fmt.Errorf("leader: %s: %w", message, fdbErrors.ErrDatabaseNotFound)
+	}
+	return fmt.Errorf("leader: %s", message)
+}
@@ -0,0 +1,129 @@
+package replication
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/unpackdev/fdb/config"
+	"github.com/unpackdev/fdb/db"
+	fdbErrors "github.com/unpackdev/fdb/errors"
+	transport_tcp "github.com/unpackdev/fdb/transports/tcp"
+	"github.com/unpackdev/fdb/types"
+)
+
+func setupReplicationManager(t *testing.T) *db.Manager {
+	manager, err := db.NewManager(context.Background(), config.Mdbx{
+		Enabled: true,
+		Nodes: []config.MdbxNode{{
+			Path:    t.TempDir(),
+			Name:    "fdb",
+			MaxSize: 1,
+			Cdc:     config.Cdc{Enabled: true},
+		}},
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = manager.Close() })
+	return manager
+}
+
+func startLeader(t *testing.T, manager *db.Manager, port int) config.ReplicationPeer {
+	router, err := db.NewRouter(manager, nil)
+	require.NoError(t, err)
+
+	server, err := transport_tcp.NewServer(context.Background(), config.TcpTransport{IPv4: "127.0.0.1", Port: port})
+	require.NoError(t, err)
+	server.RegisterHandler(types.SubscribeHandlerType, transport_tcp.NewTCPSubscribeHandler(router).HandleMessage)
+	server.RegisterHandler(types.SnapshotHandlerType, transport_tcp.NewTCPSnapshotHandler(router).HandleMessage)
+	require.NoError(t, server.Start(context.Background()))
+	t.Cleanup(func() { _ = server.Stop() })
+
+	return config.ReplicationPeer{Transport: types.TCPTransportType, Addr: server.Addr()}
+}
+
+func waitForValue(t *testing.T, provider db.Provider, key, expected []byte) {
+	require.Eventually(t, func() bool {
+		value, err := provider.Get(key)
+		return err == nil && string(value) == string(expected)
+	}, 10*time.Second, 20*time.Millisecond)
+}
+
+func TestFollowerReplicatesFromLeader(t *testing.T) {
+	leaderManager := setupReplicationManager(t)
+	leaderDb, err := leaderManager.GetDb("fdb")
+	require.NoError(t, err)
+	require.NoError(t, leaderDb.Set([]byte("before"), []byte("snapshot")))
+
+	leader := startLeader(t, leaderManager, 18791)
+
+	followerManager := setupReplicationManager(t)
+	node := NewNode(followerManager, config.Replication{
+		Role:          config.ReplicationFollower,
+		Leader:        leader,
+		RetryInterval: 50 * time.Millisecond,
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	require.NoError(t, node.Start(ctx))
+	defer node.Stop()
+
+	followerDb, err := followerManager.GetDb("fdb")
+	require.NoError(t, err)
+
+	// Bootstrapped from the snapshot, then streamed
+	waitForValue(t, followerDb, []byte("before"), []byte("snapshot"))
+	require.NoError(t, leaderDb.Set([]byte("after"), []byte("stream")))
+	waitForValue(t, followerDb, []byte("after"), []byte("stream"))
+
+	require.NoError(t, leaderDb.Delete([]byte("before")))
+	require.Eventually(t, func() bool {
+		_, err := followerDb.Get([]byte("before"))
+		return errors.Is(err, fdbErrors.ErrNotFound)
+	}, 10*time.Second, 20*time.Millisecond)
+
+	// Replicas reject client writes
+	assert.ErrorIs(t, followerDb.Set([]byte("local"), []byte("write")), fdbErrors.ErrReadOnly)
+
+	status := node.Status()
+	assert.Equal(t, config.ReplicationFollower, status.Role)
+	require.Len(t, status.Databases, 1)
+	assert.Equal(t, uint64(3), status.Databases[0].Position)
+
+	// Manual failover
+	require.NoError(t, node.Promote())
+	assert.Equal(t, config.ReplicationLeader, node.Role())
+	require.NoError(t, followerDb.Set([]byte("local"), []byte("write")))
+	head, err := followerDb.(*db.Db).HeadSequence()
+	require.NoError(t, err)
+	assert.Equal(t, uint64(4), head)
+}
+
+func TestFollowerResyncsAfterDivergence(t *testing.T) {
+	leaderManager := setupReplicationManager(t)
+	leaderDb, err := leaderManager.GetDb("fdb")
+	require.NoError(t, err)
+	require.NoError(t, leaderDb.Set([]byte("leader"), []byte("value")))
+
+	leader := startLeader(t, leaderManager, 18792)
+
+	// The follower holds writes the leader never saw
+	followerManager := setupReplicationManager(t)
+	followerDb, err := followerManager.GetDb("fdb")
+	require.NoError(t, err)
+	require.NoError(t, followerDb.Set([]byte("diverged"), []byte("value")))
+
+	node := NewNode(followerManager, config.Replication{RetryInterval: 50 * time.Millisecond})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	require.NoError(t, node.Start(ctx))
+	defer node.Stop()
+
+	require.NoError(t, node.Follow(leader, false))
+	waitForValue(t, followerDb, []byte("leader"), []byte("value"))
+
+	_, err = followerDb.Get([]byte("diverged"))
+	assert.ErrorIs(t, err, fdbErrors.ErrNotFound)
+}
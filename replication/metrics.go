@@ -0,0 +1,46 @@
+package replication
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// meterName is the instrumentation scope of the replication metrics.
+const meterName = "github.com/unpackdev/fdb/replication"
+
+// registerMetrics registers the replication lag gauges of the node with the global meter
+// provider. The gauges report every replicated database while the node follows a leader.
+func registerMetrics(n *Node) error {
+	meter := otel.Meter(meterName)
+
+	lagSequences, err := meter.Int64ObservableGauge(
+		"fdb.replication.lag.sequences",
+		metric.WithDescription("Number of leader changes not yet applied by the follower"),
+		metric.WithUnit("{change}"),
+	)
+	if err != nil {
+		return err
+	}
+
+	lagSeconds, err := meter.Float64ObservableGauge(
+		"fdb.replication.lag.seconds",
+		metric.WithDescription("Age of the last change applied by the follower while it is behind the leader"),
+		metric.WithUnit("s"),
+	)
+	if err != nil {
+		return err
+	}
+
+	_, err = meter.RegisterCallback(func(_ context.Context, o metric.Observer) error {
+		for _, status := range n.Status().Databases {
+			attrs := metric.WithAttributes(attribute.String("database", status.Name))
+			o.ObserveInt64(lagSequences, int64(status.LagSequences), attrs)
+			o.ObserveFloat64(lagSeconds, status.LagSeconds, attrs)
+		}
+		return nil
+	}, lagSequences, lagSeconds)
+	return err
+}
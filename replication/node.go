@@ -0,0 +1,279 @@
+package replication
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/unpackdev/fdb/config"
+	"github.com/unpackdev/fdb/db"
+	"github.com/unpackdev/fdb/types"
+	"go.uber.org/zap"
+)
+
+// DbStatus describes the replication progress of a single database.
+type DbStatus struct {
+	// Name is the database name.
+	Name string `json:"name"`
+
+	// Position is the sequence number of the last change applied from the leader.
+	Position uint64 `json:"position"`
+
+	// LeaderHead is the newest sequence number reported by the leader.
+	LeaderHead uint64 `json:"leaderHead"`
+
+	// LagSequences is the number of leader changes not applied yet.
+	LagSequences uint64 `json:"lagSequences"`
+
+	// LagSeconds is the age of the last applied change while the replica is behind, zero once caught up.
+	LagSeconds float64 `json:"lagSeconds"`
+
+	// Connected reports whether the change stream from the leader is established.
+	Connected bool `json:"connected"`
+
+	// LastError is the error that interrupted replication last, if any.
+	LastError string `json:"lastError,omitempty"`
+}
+
+// Status describes the replication role of a node and, on followers, the progress of each database.
+type Status struct {
+	// Role is the current replication role of the node.
+	Role config.ReplicationRole `json:"role"`
+
+	// Leader is the node replicated from, only set on followers.
+	Leader *config.ReplicationPeer `json:"leader,omitempty"`
+
+	// Databases holds the progress of each replicated database, only set on followers.
+	Databases []DbStatus `json:"databases,omitempty"`
+}
+
+// Node manages the replication role of an fdb node. It starts in the configured role and can
+// be switched at runtime: Promote turns a follower into a leader, Follow points the node at a
+// (new) leader.
+type Node struct {
+	// manager owns the replicated databases.
+	manager *db.Manager
+
+	// cnf holds the replication configuration the node started with.
+	cnf config.Replication
+
+	// mu guards ctx, role, leader and follower.
+	mu sync.Mutex
+
+	// ctx bounds the lifetime of the follower, set by Start.
+	ctx context.Context
+
+	// role is the current replication role.
+	role config.ReplicationRole
+
+	// leader is the node followed while role is follower.
+	leader config.ReplicationPeer
+
+	// follower replicates the databases while role is follower.
+	follower *follower
+}
+
+// NewNode creates a new replication Node in the configured role. Call Start to begin replicating.
+//
+// Example usage:
+//
+//	node := replication.NewNode(manager, cnf.Replication)
+//
+// Parameters:
+//
+//	manager (*db.Manager): The manager owning the replicated databases.
+//	cnf (config.Replication): The replication configuration.
+//
+// Returns:
+//
+//	*Node: A new Node instance.
+func NewNode(manager *db.Manager, cnf config.Replication) *Node {
+	return &Node{
+		manager: manager,
+		cnf:     cnf,
+		role:    cnf.Role,
+		leader:  cnf.Leader,
+	}
+}
+
+// Start registers the replication metrics and, on followers, marks the replicated databases
+// read-only and starts replicating them from the leader. Replication stops when ctx is done.
+//
+// Parameters:
+//
+//	ctx (context.Context): The context bounding the lifetime of replication.
+//
+// Returns:
+//
+//	error: Returns an error if a replicated database is unknown or the metrics cannot be registered.
+func (n *Node) Start(ctx context.Context) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.ctx = ctx
+	if err := registerMetrics(n); err != nil {
+		return errors.Wrap(err, "failure to register replication metrics")
+	}
+
+	if n.role != config.ReplicationFollower {
+		return nil
+	}
+	return n.startFollower(n.leader)
+}
+
+// Stop stops replicating. The databases stay read-only until the node is promoted.
+func (n *Node) Stop() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.stopFollower()
+}
+
+// Role returns the current replication role of the node.
+func (n *Node) Role() config.ReplicationRole {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	return n.role
+}
+
+// Status returns the replication role of the node and the progress of every replicated database.
+func (n *Node) Status() Status {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	status := Status{Role: n.role}
+	if n.follower == nil {
+		return status
+	}
+
+	leader := n.leader
+	status.Leader = &leader
+	for _, name := range n.follower.databases {
+		status.Databases = append(status.Databases, n.follower.states[name].status(name))
+	}
+	return status
+}
+
+// Promote stops following the leader and makes the replicated databases writable. The change
+// logs keep the leader's sequence numbers, so other followers can switch to the promoted node
+// and continue from their position.
+//
+// Returns:
+//
+//	error: Returns an error if a database cannot be made writable.
+func (n *Node) Promote() error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	databases := n.databases()
+	if n.follower != nil {
+		databases = n.follower.databases
+	}
+	n.stopFollower()
+
+	for _, name := range databases {
+		if err := n.manager.SetReadOnly(name, false); err != nil {
+			return err
+		}
+	}
+
+	n.role = config.ReplicationLeader
+	zap.L().Info("Node promoted to replication leader")
+	return nil
+}
+
+// Follow makes the node replicate from the given leader, replacing the current one. The
+// replicated databases become read-only; with resync they are bootstrapped again from a
+// snapshot even when their position could be continued.
+//
+// Parameters:
+//
+//	leader (config.ReplicationPeer): The leader to replicate from.
+//	resync (bool): Whether to discard the local data and start from a fresh snapshot.
+//
+// Returns:
+//
+//	error: Returns an error if the leader definition is invalid or replication cannot start.
+func (n *Node) Follow(leader config.ReplicationPeer, resync bool) error {
+	if err := leader.Validate(); err != nil {
+		return err
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.ctx == nil {
+		return errors.New("replication node is not started")
+	}
+
+	n.stopFollower()
+
+	if resync {
+		for _, name := range n.databases() {
+			if err := n.manager.SetReadOnly(name, true); err != nil {
+				return err
+			}
+			provider, err := n.manager.GetDb(name)
+			if err != nil {
+				return err
+			}
+			if bDb, ok := provider.(*db.Db); ok {
+				if err := bDb.ResetReplica(); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	if err := n.startFollower(leader); err != nil {
+		return err
+	}
+
+	n.role = config.ReplicationFollower
+	n.leader = leader
+	zap.L().Info("Node following replication leader", zap.String("transport", leader.Transport.String()), zap.String("addr", leader.Addr))
+	return nil
+}
+
+// databases returns the replicated databases: the configured ones, or every database known
+// to the manager. Must be called with mu held.
+func (n *Node) databases() []types.DbType {
+	var names []types.DbType
+	if len(n.cnf.Databases) > 0 {
+		for _, name := range n.cnf.Databases {
+			names = append(names, types.DbType(name))
+		}
+		return names
+	}
+
+	for _, info := range n.manager.ListDbs() {
+		names = append(names, types.DbType(info.Name))
+	}
+	sort.Slice(names, func(i, j int) bool { return names[i] < names[j] })
+	return names
+}
+
+// startFollower marks the databases read-only and starts replicating them. Must be called with mu held.
+func (n *Node) startFollower(leader config.ReplicationPeer) error {
+	databases := n.databases()
+	for _, name := range databases {
+		if err := n.manager.SetReadOnly(name, true); err != nil {
+			return errors.Wrapf(err, "failure to replicate database: %s", name)
+		}
+	}
+
+	n.follower = newFollower(n.manager, leader, n.cnf.GetRetryInterval(), databases)
+	n.follower.start(n.ctx)
+	return nil
+}
+
+// stopFollower stops the follower, if any. Must be called with mu held.
+func (n *Node) stopFollower() {
+	if n.follower == nil {
+		return
+	}
+	n.follower.stop()
+	n.follower = nil
+}
@@ -0,0 +1,95 @@
+package replication
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+
+	"github.com/pkg/errors"
+	"github.com/quic-go/quic-go"
+	"github.com/unpackdev/fdb/config"
+	"github.com/unpackdev/fdb/messages"
+	"github.com/unpackdev/fdb/types"
+)
+
+// quicNextProto is the ALPN protocol negotiated by the QUIC transport.
+const quicNextProto = "quic-example"
+
+// quicStream closes the QUIC connection together with its only stream.
+type quicStream struct {
+	quic.Stream
+	conn quic.Connection
+}
+
+// Close closes the stream and its connection.
+func (s *quicStream) Close() error {
+	_ = s.Stream.Close()
+	return s.conn.CloseWithError(0, "")
+}
+
+// openStream connects to the leader and sends a single request addressed to the given
+// database. The returned stream carries the leader's stream frames and is closed when ctx is done.
+func openStream(ctx context.Context, peer config.ReplicationPeer, database string, request []byte) (io.ReadCloser, error) {
+	var (
+		stream io.ReadWriteCloser
+		frame  []byte
+		err    error
+	)
+
+	switch peer.Transport {
+	case types.TCPTransportType:
+		var dialer net.Dialer
+		stream, err = dialer.DialContext(ctx, "tcp", peer.Addr)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to connect to leader %s", peer.Addr)
+		}
+		frame, err = messages.WithDatabase(database, request)
+	case types.QUICTransportType:
+		tlsConfig := &tls.Config{
+			InsecureSkipVerify: peer.Insecure,
+			NextProtos:         []string{quicNextProto},
+		}
+		conn, dErr := quic.DialAddr(ctx, peer.Addr, tlsConfig, nil)
+		if dErr != nil {
+			return nil, errors.Wrapf(dErr, "failed to connect to leader %s", peer.Addr)
+		}
+		qStream, sErr := conn.OpenStreamSync(ctx)
+		if sErr != nil {
+			_ = conn.CloseWithError(0, "")
+			return nil, errors.Wrap(sErr, "failed to open stream to leader")
+		}
+		stream = &quicStream{Stream: qStream, conn: conn}
+
+		// The QUIC transport expects every request wrapped in a message
+		message := messages.Message{Database: database, Handler: types.HandlerType(request[0]), Data: request}
+		frame, err = message.Encode()
+	default:
+		return nil, fmt.Errorf("unsupported replication transport: %s", peer.Transport)
+	}
+
+	if err == nil {
+		_, err = stream.Write(frame)
+	}
+	if err != nil {
+		_ = stream.Close()
+		return nil, errors.Wrap(err, "failed to send request to leader")
+	}
+
+	// Unblock pending reads once the follower stops
+	stop := context.AfterFunc(ctx, func() { _ = stream.Close() })
+	return &stoppableStream{ReadWriteCloser: stream, stop: stop}, nil
+}
+
+// stoppableStream releases the context hook when the stream is closed.
+type stoppableStream struct {
+	io.ReadWriteCloser
+	stop func() bool
+}
+
+// Close releases the context hook and closes the stream.
+func (s *stoppableStream) Close() error {
+	s.stop()
+	return s.ReadWriteCloser.Close()
+}
@@ -0,0 +1,45 @@
+package sharding
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/pkg/errors"
+	"github.com/unpackdev/fdb/db"
+	"github.com/unpackdev/fdb/messages"
+	"go.uber.org/zap"
+)
+
+// RegisterAdminOps exposes the shard map of the proxy and rebalancing through the admin
+// handlers of the manager's transports. messages.AdminShardMap returns the JSON encoded Status,
+// which clients routing requests directly poll for newer map versions. The payload of
+// messages.AdminRebalance is the JSON encoded Map to install; the map is validated before
+// the request is answered, the keys are moved in the background.
+//
+// Parameters:
+//
+//	manager (*db.Manager): The manager dispatching admin requests.
+//	proxy (*Proxy): The proxy the operations apply to.
+func RegisterAdminOps(manager *db.Manager, proxy *Proxy) {
+	manager.RegisterAdminOp(messages.AdminShardMap, func(_ *messages.AdminRequest) ([]byte, error) {
+		return json.Marshal(proxy.Status())
+	})
+
+	manager.RegisterAdminOp(messages.AdminRebalance, func(req *messages.AdminRequest) ([]byte, error) {
+		var next Map
+		if err := json.Unmarshal(req.Payload, &next); err != nil {
+			return nil, errors.Wrap(err, "failure to decode shard map")
+		}
+		if err := proxy.validateNext(&next); err != nil {
+			return nil, err
+		}
+
+		// Moving keys may take long, it must not hold up the transport
+		go func() {
+			if err := proxy.Rebalance(context.Background(), next); err != nil {
+				zap.L().Error("Failed to rebalance shards", zap.Uint64("version", next.Version), zap.Error(err))
+			}
+		}()
+		return nil, nil
+	})
+}
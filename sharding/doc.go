@@ -0,0 +1,28 @@
+// Package sharding spreads the keys of a database over several fdb nodes (shards) with
+// consistent hashing, and implements the routing proxy role of `fdb serve`.
+//
+// The cluster is described by a versioned Map. Every shard is placed on a hash ring at a number
+// of virtual positions derived from its ID, and each key belongs to the shard at the first
+// position following the key's hash (see Ring and Hash). Adding or removing a shard moves only
+// the keys adjacent to its positions.
+//
+// A proxy stores no data. It serves the sharded databases on its transports like any node and
+// forwards every read, write and delete to the shard owning the key. Shards are plain fdb nodes
+// serving the same databases; clients may also fetch the shard map from a proxy and route
+// requests to the shards directly.
+//
+// Rebalancing installs a new map: each shard streams a snapshot, keys changing owner are copied
+// to their new shard, the new map is switched in and the copies are removed from their previous
+// shards. Only writes to the moving keys wait while this happens.
+//
+// Example usage:
+//
+//	proxy, err := sharding.NewProxy(cnf.Sharding)
+//	if err != nil {
+//	    log.Fatalf("Failed to create sharding proxy: %v", err)
+//	}
+//	for _, name := range cnf.Sharding.Databases {
+//	    _ = manager.Attach(types.DbType(name), proxy.Provider(types.DbType(name)))
+//	    _ = manager.SetProposer(types.DbType(name), proxy)
+//	}
+package sharding
@@ -0,0 +1,14 @@
+package sharding
+
+import (
+	"testing"
+	"time"
+)
+
+// SetFlushSettleDelay shortens the wait of the rebalances for the shards to flush their writes
+// for the duration of the test.
+func SetFlushSettleDelay(t *testing.T, delay time.Duration) {
+	previous := flushSettleDelay
+	flushSettleDelay = delay
+	t.Cleanup(func() { flushSettleDelay = previous })
+}
@@ -0,0 +1,84 @@
+package sharding
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+	"github.com/unpackdev/fdb/config"
+)
+
+// Map is a versioned description of the cluster: the shards and how keys are spread over
+// them. Every rebalance installs a map with a higher version, so clients caching a map can
+// tell whether it is still current.
+type Map struct {
+	// Version increases with every change of the map.
+	Version uint64 `json:"version" yaml:"version"`
+
+	// VirtualNodes is the number of ring positions of each shard.
+	VirtualNodes int `json:"virtualNodes" yaml:"virtualNodes"`
+
+	// Shards lists the shards of the cluster.
+	Shards []config.ShardNode `json:"shards" yaml:"shards"`
+}
+
+// Validate checks that the map has a positive number of virtual nodes and valid, unique shards.
+func (m *Map) Validate() error {
+	if m.VirtualNodes <= 0 {
+		return fmt.Errorf("shard map needs a positive number of virtual nodes")
+	}
+	return config.ValidateShards(m.Shards)
+}
+
+// Shard returns the shard with the given ID.
+func (m *Map) Shard(id string) (config.ShardNode, bool) {
+	for _, shard := range m.Shards {
+		if shard.ID == id {
+			return shard, true
+		}
+	}
+	return config.ShardNode{}, false
+}
+
+// loadMap reads the shard map stored in path, nil if the file does not exist.
+func loadMap(path string) (*Map, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, errors.Wrap(err, "failure to read shard map")
+	}
+
+	var m Map
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, errors.Wrap(err, "failure to decode shard map")
+	}
+	if err := m.Validate(); err != nil {
+		return nil, errors.Wrap(err, "invalid stored shard map")
+	}
+	return &m, nil
+}
+
+// saveMap atomically writes the shard map to path.
+func saveMap(path string, m *Map) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "failure to encode shard map")
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return errors.Wrap(err, "failure to create shard map directory")
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return errors.Wrap(err, "failure to write shard map")
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return errors.Wrap(err, "failure to replace shard map")
+	}
+	return nil
+}
@@ -0,0 +1,499 @@
+package sharding
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/unpackdev/fdb/config"
+	"github.com/unpackdev/fdb/db"
+	fdbErrors "github.com/unpackdev/fdb/errors"
+	"github.com/unpackdev/fdb/remote"
+	"github.com/unpackdev/fdb/types"
+	"go.uber.org/zap"
+)
+
+// flushSettleDelay is how long a rebalance waits for writes acknowledged by the shards to be
+// applied. Shards acknowledge writes once buffered, their batch writers flush every 500ms.
+var flushSettleDelay = time.Second
+
+// migration is the state of a rebalance in progress.
+type migration struct {
+	// next is the ring being installed.
+	next *Ring
+
+	// done is closed once the rebalance completes or fails, releasing the paused writes.
+	done chan struct{}
+}
+
+// Status describes the shard map of a proxy and its last rebalance.
+type Status struct {
+	// Map is the shard map currently used for routing.
+	Map Map `json:"map"`
+
+	// Rebalancing reports whether a rebalance is in progress.
+	Rebalancing bool `json:"rebalancing"`
+
+	// LastError is the error that ended the last rebalance, if any.
+	LastError string `json:"lastError,omitempty"`
+}
+
+// Proxy routes the requests of the databases it serves to the shards owning their keys. It
+// acts as the db.Provider of every served database, see Provider, and as their db.Proposer,
+// so writes received by any transport are forwarded with the same semantics as reads.
+//
+// Rebalance installs a new shard map while requests are served: only writes to keys changing
+// owner wait for the rebalance to complete.
+type Proxy struct {
+	opts config.Sharding
+
+	// rebalanceMu serializes rebalances.
+	rebalanceMu sync.Mutex
+
+	// mu guards shardMap, ring, clients, migration and lastError. Forwarded writes hold it
+	// for reading, so installing a migration waits for the writes in flight.
+	mu sync.RWMutex
+
+	shardMap  *Map
+	ring      *Ring
+	clients   map[string]*remote.Client
+	migration *migration
+	lastError string
+}
+
+// NewProxy creates a Proxy routing to the shard map stored in cnf.MapFile, or to the shards
+// of the configuration when no map was stored yet.
+//
+// Example usage:
+//
+//	proxy, err := sharding.NewProxy(cnf.Sharding)
+//	if err != nil {
+//	    log.Fatalf("Failed to create sharding proxy: %v", err)
+//	}
+//	defer proxy.Close()
+//
+// Parameters:
+//
+//	cnf (config.Sharding): The sharding configuration.
+//
+// Returns:
+//
+//	*Proxy: A new Proxy instance.
+//	error: Returns an error if the stored map cannot be read or a shard client cannot be created.
+func NewProxy(cnf config.Sharding) (*Proxy, error) {
+	var shardMap *Map
+	if cnf.MapFile != "" {
+		stored, err := loadMap(cnf.MapFile)
+		if err != nil {
+			return nil, err
+		}
+		shardMap = stored
+	}
+	if shardMap == nil {
+		shardMap = &Map{Version: 1, VirtualNodes: cnf.GetVirtualNodes(), Shards: cnf.Shards}
+		if err := shardMap.Validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	p := &Proxy{
+		opts:     cnf,
+		shardMap: shardMap,
+		ring:     NewRing(shardMap),
+		clients:  make(map[string]*remote.Client, len(shardMap.Shards)),
+	}
+	if err := p.connect(shardMap); err != nil {
+		_ = p.Close()
+		return nil, err
+	}
+	return p, nil
+}
+
+// Map returns a copy of the shard map currently used for routing.
+func (p *Proxy) Map() Map {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	return copyMap(p.shardMap)
+}
+
+// Status returns the shard map and the state of the last rebalance.
+func (p *Proxy) Status() Status {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	return Status{
+		Map:         copyMap(p.shardMap),
+		Rebalancing: p.migration != nil,
+		LastError:   p.lastError,
+	}
+}
+
+// Provider returns the db.Provider forwarding the requests of the named database to the shards.
+func (p *Proxy) Provider(database types.DbType) db.Provider {
+	return &shardedDb{proxy: p, name: database}
+}
+
+// Propose forwards a write or delete to the shard owning the key. It implements db.Proposer,
+// writes to keys moved by a rebalance in progress wait for it to complete.
+func (p *Proxy) Propose(ctx context.Context, database types.DbType, op types.ChangeOp, key, value []byte) error {
+	k, err := shardKey(key)
+	if err != nil {
+		return err
+	}
+
+	for {
+		p.mu.RLock()
+		if p.migration != nil && p.migration.next.Owner(key) != p.ring.Owner(key) {
+			done := p.migration.done
+			p.mu.RUnlock()
+
+			select {
+			case <-done:
+				continue
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+
+		client := p.clients[p.ring.Owner(key)]
+		switch op {
+		case types.ChangeSet:
+			err = client.Set(ctx, database.String(), k, value)
+		case types.ChangeDelete:
+			err = client.Delete(ctx, database.String(), k)
+		default:
+			err = fmt.Errorf("unsupported change operation: %s", op)
+		}
+		p.mu.RUnlock()
+		return err
+	}
+}
+
+// get reads a key from the shard owning it. During a rebalance keys are read from their
+// current owner, which keeps them until the new map is installed.
+func (p *Proxy) get(ctx context.Context, database types.DbType, key []byte) ([]byte, error) {
+	k, err := shardKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.RLock()
+	client := p.clients[p.ring.Owner(key)]
+	p.mu.RUnlock()
+
+	return client.Get(ctx, database.String(), k)
+}
+
+// Rebalance installs a new shard map and moves the keys whose owner changes. For every
+// database served by the proxy, each shard of the current map streams a snapshot and the keys
+// now owned by another shard are copied to it. Once every key is copied the new map is stored
+// and used for routing, then the copied keys are deleted from their previous owners.
+//
+// Reads are served by the current owners until the new map is installed. Writes to keys that
+// change owner wait for the rebalance to complete; on failure the current map stays in use.
+//
+// Example usage:
+//
+//	next := proxy.Map()
+//	next.Version++
+//	next.Shards = append(next.Shards, config.ShardNode{ID: "shard-3", Transport: types.TCPTransportType, Addr: "10.0.0.3:5011"})
+//	if err := proxy.Rebalance(ctx, next); err != nil {
+//	    log.Fatalf("Failed to rebalance: %v", err)
+//	}
+//
+// Parameters:
+//
+//	ctx (context.Context): Cancels the rebalance.
+//	next (Map): The new shard map, with a higher version than the current one.
+//
+// Returns:
+//
+//	error: Returns an error if the map is invalid or the keys cannot be moved.
+func (p *Proxy) Rebalance(ctx context.Context, next Map) error {
+	p.rebalanceMu.Lock()
+	defer p.rebalanceMu.Unlock()
+
+	err := p.rebalance(ctx, next)
+
+	p.mu.Lock()
+	p.lastError = ""
+	if err != nil {
+		p.lastError = err.Error()
+	}
+	p.mu.Unlock()
+	return err
+}
+
+// rebalance runs a single rebalance, the caller must hold rebalanceMu.
+func (p *Proxy) rebalance(ctx context.Context, next Map) error {
+	if err := p.validateNext(&next); err != nil {
+		return err
+	}
+	if err := p.connect(&next); err != nil {
+		return err
+	}
+
+	nextRing := NewRing(&next)
+	mig := &migration{next: nextRing, done: make(chan struct{})}
+
+	// Waits for the writes in flight, writes to moving keys pause from here on
+	p.mu.Lock()
+	current, currentMap := p.ring, p.shardMap
+	p.migration = mig
+	p.mu.Unlock()
+
+	abort := func(err error) error {
+		p.mu.Lock()
+		p.migration = nil
+		p.mu.Unlock()
+		close(mig.done)
+		p.disconnect()
+		return err
+	}
+
+	zap.L().Info(
+		"Rebalancing shards",
+		zap.Uint64("from_version", currentMap.Version),
+		zap.Uint64("to_version", next.Version),
+		zap.Int("shards", len(next.Shards)),
+	)
+
+	// Writes acknowledged before the pause must be applied before the shards are scanned
+	if err := sleep(ctx, flushSettleDelay); err != nil {
+		return abort(err)
+	}
+
+	moved, err := p.copyMoved(ctx, currentMap, current, nextRing)
+	if err != nil {
+		return abort(err)
+	}
+
+	// The copies must be applied before reads are routed to the new owners
+	if err := sleep(ctx, flushSettleDelay); err != nil {
+		return abort(err)
+	}
+
+	if p.opts.MapFile != "" {
+		if err := saveMap(p.opts.MapFile, &next); err != nil {
+			return abort(err)
+		}
+	}
+
+	p.mu.Lock()
+	p.shardMap = &next
+	p.ring = nextRing
+	p.migration = nil
+	p.mu.Unlock()
+	close(mig.done)
+
+	// The previous owners no longer serve the moved keys
+	var deleted int
+	for _, m := range moved {
+		if err := p.client(m.source).Delete(ctx, m.database.String(), m.key); err != nil {
+			zap.L().Warn("Failed to remove moved key from its previous shard", zap.String("shard", m.source), zap.Error(err))
+			continue
+		}
+		deleted++
+	}
+	p.disconnect()
+
+	zap.L().Info(
+		"Shards rebalanced",
+		zap.Uint64("version", next.Version),
+		zap.Int("moved_keys", len(moved)),
+		zap.Int("removed_keys", deleted),
+	)
+	return nil
+}
+
+// movedKey is a key copied to its new owner during a rebalance.
+type movedKey struct {
+	database types.DbType
+	source   string
+	key      [32]byte
+}
+
+// copyMoved copies every key whose owner changes from current to next to its new owner.
+func (p *Proxy) copyMoved(ctx context.Context, currentMap *Map, current, next *Ring) ([]movedKey, error) {
+	var moved []movedKey
+	for _, shard := range currentMap.Shards {
+		source := p.client(shard.ID)
+		for _, name := range p.opts.Databases {
+			database := types.DbType(name)
+			err := source.Snapshot(ctx, name, func(key, value []byte) error {
+				if current.Owner(key) != shard.ID {
+					return nil
+				}
+				owner := next.Owner(key)
+				if owner == shard.ID {
+					return nil
+				}
+
+				k, err := shardKey(key)
+				if err != nil {
+					zap.L().Warn("Skipping key that cannot be forwarded", zap.String("shard", shard.ID), zap.Binary("key", key))
+					return nil
+				}
+				if err := p.client(owner).Set(ctx, name, k, value); err != nil {
+					return fmt.Errorf("failed to copy key to shard %s: %w", owner, err)
+				}
+				moved = append(moved, movedKey{database: database, source: shard.ID, key: k})
+				return nil
+			})
+			if err != nil {
+				return nil, fmt.Errorf("failed to move keys of %s from shard %s: %w", name, shard.ID, err)
+			}
+		}
+	}
+	return moved, nil
+}
+
+// validateNext checks a map proposed for a rebalance, defaulting its virtual nodes to the current ones.
+func (p *Proxy) validateNext(next *Map) error {
+	p.mu.RLock()
+	current := p.shardMap
+	p.mu.RUnlock()
+
+	if next.VirtualNodes == 0 {
+		next.VirtualNodes = current.VirtualNodes
+	}
+	if next.Version <= current.Version {
+		return fmt.Errorf("shard map version %d is not newer than the current version %d", next.Version, current.Version)
+	}
+	return next.Validate()
+}
+
+// connect creates the clients of the shards of m that have none yet.
+func (p *Proxy) connect(m *Map) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, shard := range m.Shards {
+		if client, ok := p.clients[shard.ID]; ok && client.Addr() == shard.Addr {
+			continue
+		}
+		if _, ok := p.clients[shard.ID]; ok {
+			return fmt.Errorf("shard %s cannot change its address during a rebalance", shard.ID)
+		}
+
+		client, err := remote.New(remote.Options{
+			Transport: shard.Transport,
+			Addr:      shard.Addr,
+			Insecure:  shard.Insecure,
+			PoolSize:  p.opts.GetPoolSize(),
+			Timeout:   p.opts.GetRequestTimeout(),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to create client of shard %s: %w", shard.ID, err)
+		}
+		p.clients[shard.ID] = client
+	}
+	return nil
+}
+
+// disconnect closes the clients of the shards that are not part of the current map.
+func (p *Proxy) disconnect() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for id, client := range p.clients {
+		if _, ok := p.shardMap.Shard(id); !ok {
+			_ = client.Close()
+			delete(p.clients, id)
+		}
+	}
+}
+
+// client returns the client of a shard known to the proxy.
+func (p *Proxy) client(id string) *remote.Client {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	return p.clients[id]
+}
+
+// Close closes the connections to every shard.
+func (p *Proxy) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var errs []error
+	for id, client := range p.clients {
+		errs = append(errs, client.Close())
+		delete(p.clients, id)
+	}
+	return errors.Join(errs...)
+}
+
+// shardedDb is the db.Provider of a database served through the proxy.
+type shardedDb struct {
+	proxy *Proxy
+	name  types.DbType
+}
+
+// Set forwards the write to the shard owning the key.
+func (s *shardedDb) Set(key, value []byte) error {
+	return s.proxy.Propose(context.Background(), s.name, types.ChangeSet, key, value)
+}
+
+// Get reads the key from the shard owning it.
+func (s *shardedDb) Get(key []byte) ([]byte, error) {
+	return s.proxy.get(context.Background(), s.name, key)
+}
+
+// Exists reports whether the shard owning the key has a value for it.
+func (s *shardedDb) Exists(key []byte) (bool, error) {
+	_, err := s.Get(key)
+	if errors.Is(err, fdbErrors.ErrNotFound) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// Delete forwards the delete to the shard owning the key.
+func (s *shardedDb) Delete(key []byte) error {
+	return s.proxy.Propose(context.Background(), s.name, types.ChangeDelete, key, nil)
+}
+
+// Close is a no-op, the shard connections are closed with the proxy.
+func (s *shardedDb) Close() error {
+	return nil
+}
+
+// Destroy is not supported, databases are managed on the shards.
+func (s *shardedDb) Destroy() error {
+	return fmt.Errorf("sharded database %s cannot be destroyed through the proxy", s.name)
+}
+
+// shardKey converts a key to the fixed-size key of the wire protocol.
+func shardKey(key []byte) ([32]byte, error) {
+	var k [32]byte
+	if len(key) != len(k) {
+		return k, fmt.Errorf("sharded keys must be %d bytes, got %d", len(k), len(key))
+	}
+	copy(k[:], key)
+	return k, nil
+}
+
+// copyMap returns a deep copy of m.
+func copyMap(m *Map) Map {
+	c := *m
+	c.Shards = append([]config.ShardNode(nil), m.Shards...)
+	return c
+}
+
+// sleep waits for d or until ctx is done.
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
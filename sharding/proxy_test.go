@@ -0,0 +1,131 @@
+package sharding_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/unpackdev/fdb/config"
+	"github.com/unpackdev/fdb/db"
+	fdbErrors "github.com/unpackdev/fdb/errors"
+	"github.com/unpackdev/fdb/fdbtest"
+	"github.com/unpackdev/fdb/sharding"
+	"github.com/unpackdev/fdb/types"
+)
+
+// settleDelay is the wait of the rebalances for the shards to flush their writes.
+const settleDelay = 700 * time.Millisecond
+
+// testShard is a single fdb instance serving the sharded database over TCP.
+type testShard struct {
+	node config.ShardNode
+	db   db.Provider
+}
+
+func startShard(t *testing.T, id string) *testShard {
+	server := fdbtest.Start(t, fdbtest.WithTransports(types.TCPTransportType))
+	return &testShard{
+		node: config.ShardNode{ID: id, Transport: types.TCPTransportType, Addr: server.Addr(types.TCPTransportType)},
+		db:   server.Provider(t, fdbtest.DefaultDatabase),
+	}
+}
+
+func testKey(i int) []byte {
+	var seed [8]byte
+	binary.BigEndian.PutUint64(seed[:], uint64(i))
+	key := sha256.Sum256(seed[:])
+	return key[:]
+}
+
+// assertPlacement checks that every key is stored on its owner only.
+func assertPlacement(t *testing.T, ring *sharding.Ring, shards []*testShard, keys int) {
+	for _, shard := range shards {
+		for i := 0; i < keys; i++ {
+			value, err := shard.db.Get(testKey(i))
+			if ring.Owner(testKey(i)) == shard.node.ID {
+				require.NoError(t, err, "key %d missing on shard %s", i, shard.node.ID)
+				assert.Equal(t, []byte{'v', byte(i)}, value)
+			} else {
+				assert.ErrorIs(t, err, fdbErrors.ErrNotFound, "key %d left on shard %s", i, shard.node.ID)
+			}
+		}
+	}
+}
+
+func TestProxyRoutesAndRebalances(t *testing.T) {
+	sharding.SetFlushSettleDelay(t, settleDelay)
+
+	shards := []*testShard{startShard(t, "shard-1"), startShard(t, "shard-2")}
+	opts := config.Sharding{
+		Enabled:   true,
+		Databases: []string{fdbtest.DefaultDatabase},
+		Shards:    []config.ShardNode{shards[0].node, shards[1].node},
+		MapFile:   t.TempDir() + "/shards.json",
+	}
+	proxy, err := sharding.NewProxy(opts)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = proxy.Close() })
+
+	provider := proxy.Provider(fdbtest.DefaultDatabase)
+	const keys = 200
+	for i := 0; i < keys; i++ {
+		require.NoError(t, provider.Set(testKey(i), []byte{'v', byte(i)}))
+	}
+
+	// Writes are forwarded to the owner of each key and read back through it, every shard
+	// flushes its batches on its own
+	require.Eventually(t, func() bool {
+		for i := 0; i < keys; i++ {
+			if value, err := provider.Get(testKey(i)); err != nil || value[1] != byte(i) {
+				return false
+			}
+		}
+		return true
+	}, 5*time.Second, 20*time.Millisecond)
+	assertPlacement(t, sharding.NewRing(&sharding.Map{VirtualNodes: config.DefaultShardVirtualNodes, Shards: []config.ShardNode{shards[0].node, shards[1].node}}), shards, keys)
+
+	exists, err := provider.Exists(testKey(keys))
+	require.NoError(t, err)
+	assert.False(t, exists)
+
+	// Maps must move forward
+	stale := proxy.Map()
+	assert.Error(t, proxy.Rebalance(context.Background(), stale))
+
+	// A third shard takes over part of the keys
+	shards = append(shards, startShard(t, "shard-3"))
+	next := proxy.Map()
+	next.Version++
+	next.Shards = append(next.Shards, shards[2].node)
+	require.NoError(t, proxy.Rebalance(context.Background(), next))
+
+	status := proxy.Status()
+	assert.Equal(t, uint64(2), status.Map.Version)
+	assert.False(t, status.Rebalancing)
+	assert.Empty(t, status.LastError)
+
+	// Deletes of the moved keys on the previous owners are buffered as well
+	time.Sleep(settleDelay)
+	assertPlacement(t, sharding.NewRing(&next), shards, keys)
+	for i := 0; i < keys; i++ {
+		value, err := provider.Get(testKey(i))
+		require.NoError(t, err)
+		assert.Equal(t, []byte{'v', byte(i)}, value)
+	}
+
+	// The installed map is restored on restart
+	restarted, err := sharding.NewProxy(opts)
+	require.NoError(t, err)
+	defer restarted.Close()
+	assert.Equal(t, next, restarted.Map())
+
+	require.NoError(t, provider.Delete(testKey(0)))
+	require.Eventually(t, func() bool {
+		_, err := provider.Get(testKey(0))
+		return err != nil
+	}, 5*time.Second, 20*time.Millisecond)
+}
@@ -0,0 +1,123 @@
+package sharding
+
+import (
+	"hash/fnv"
+	"sort"
+	"strconv"
+)
+
+// point is a single position of a shard on the ring.
+type point struct {
+	hash  uint64
+	shard string
+}
+
+// Ring is a consistent-hash ring over the shards of a Map. Every shard is placed on the ring
+// at VirtualNodes positions derived from its ID; a key belongs to the shard at the first
+// position at or after the key's hash, wrapping around. Adding or removing a shard only moves
+// the keys between its positions and the preceding ones.
+//
+// A Ring is immutable and safe for concurrent use.
+type Ring struct {
+	points []point
+}
+
+// NewRing builds the ring of the given shard map.
+//
+// Example usage:
+//
+//	ring := sharding.NewRing(shardMap)
+//	shardID := ring.Owner(key)
+//
+// Parameters:
+//
+//	m (*Map): The shard map, VirtualNodes must be positive.
+//
+// Returns:
+//
+//	*Ring: The ring placing every shard of the map.
+func NewRing(m *Map) *Ring {
+	r := &Ring{points: make([]point, 0, len(m.Shards)*m.VirtualNodes)}
+	for _, shard := range m.Shards {
+		for i := 0; i < m.VirtualNodes; i++ {
+			r.points = append(r.points, point{
+				hash:  Hash([]byte(shard.ID + "#" + strconv.Itoa(i))),
+				shard: shard.ID,
+			})
+		}
+	}
+
+	// Ties are broken by shard ID so every node builds the same ring
+	sort.Slice(r.points, func(i, j int) bool {
+		if r.points[i].hash != r.points[j].hash {
+			return r.points[i].hash < r.points[j].hash
+		}
+		return r.points[i].shard < r.points[j].shard
+	})
+	return r
+}
+
+// Owner returns the ID of the shard owning key, empty if the ring has no shards.
+func (r *Ring) Owner(key []byte) string {
+	if len(r.points) == 0 {
+		return ""
+	}
+	return r.points[r.search(key)].shard
+}
+
+// Replicas returns the IDs of the n distinct shards following the key's hash on the ring, the
+// owner first. Databases replicated with quorums store each key on these shards, its preference
+// list. Fewer IDs are returned when the ring has fewer than n shards.
+func (r *Ring) Replicas(key []byte, n int) []string {
+	if len(r.points) == 0 || n <= 0 {
+		return nil
+	}
+
+	replicas := make([]string, 0, n)
+	start := r.search(key)
+	for i := 0; i < len(r.points) && len(replicas) < n; i++ {
+		shard := r.points[(start+i)%len(r.points)].shard
+		if !contains(replicas, shard) {
+			replicas = append(replicas, shard)
+		}
+	}
+	return replicas
+}
+
+// search returns the index of the first position at or after the key's hash, wrapping around.
+func (r *Ring) search(key []byte) int {
+	h := Hash(key)
+	i := sort.Search(len(r.points), func(i int) bool {
+		return r.points[i].hash >= h
+	})
+	if i == len(r.points) {
+		i = 0
+	}
+	return i
+}
+
+// contains reports whether ids holds id.
+func contains(ids []string, id string) bool {
+	for _, other := range ids {
+		if other == id {
+			return true
+		}
+	}
+	return false
+}
+
+// Hash returns the ring position of a key. Clients routing requests directly must place keys
+// with the same function: the FNV-1a 64-bit hash of the key bytes, finalized with the
+// MurmurHash3 64-bit mix so that similar keys and shard IDs spread over the whole ring.
+func Hash(key []byte) uint64 {
+	h := fnv.New64a()
+	_, _ = h.Write(key)
+
+	x := h.Sum64()
+	x ^= x >> 33
+	x *= 0xff51afd7ed558ccd
+	x ^= x >> 33
+	x *= 0xc4ceb9fe1a85ec53
+	x ^= x >> 33
+	return x
+}
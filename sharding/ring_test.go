@@ -0,0 +1,84 @@
+package sharding
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/unpackdev/fdb/config"
+	"github.com/unpackdev/fdb/types"
+)
+
+func testMap(ids ...string) *Map {
+	m := &Map{Version: 1, VirtualNodes: config.DefaultShardVirtualNodes}
+	for _, id := range ids {
+		m.Shards = append(m.Shards, config.ShardNode{ID: id, Transport: types.TCPTransportType, Addr: id + ":5011"})
+	}
+	return m
+}
+
+func testKey(i int) []byte {
+	var seed [8]byte
+	binary.BigEndian.PutUint64(seed[:], uint64(i))
+	key := sha256.Sum256(seed[:])
+	return key[:]
+}
+
+func TestRingSpreadsKeys(t *testing.T) {
+	ring := NewRing(testMap("shard-1", "shard-2", "shard-3", "shard-4"))
+
+	const keys = 40000
+	counts := make(map[string]int)
+	for i := 0; i < keys; i++ {
+		counts[ring.Owner(testKey(i))]++
+	}
+
+	assert.Len(t, counts, 4)
+	for shard, count := range counts {
+		assert.InDelta(t, keys/4, count, keys/4*0.25, "shard %s owns %d keys", shard, count)
+	}
+
+	// Every node builds the same ring from the same map
+	other := NewRing(testMap("shard-4", "shard-3", "shard-2", "shard-1"))
+	for i := 0; i < 1000; i++ {
+		assert.Equal(t, ring.Owner(testKey(i)), other.Owner(testKey(i)))
+	}
+}
+
+func TestRingMovesOnlyKeysOfNewShard(t *testing.T) {
+	before := NewRing(testMap("shard-1", "shard-2", "shard-3"))
+	after := NewRing(testMap("shard-1", "shard-2", "shard-3", "shard-4"))
+
+	const keys = 20000
+	moved := 0
+	for i := 0; i < keys; i++ {
+		from, to := before.Owner(testKey(i)), after.Owner(testKey(i))
+		if from != to {
+			assert.Equal(t, "shard-4", to)
+			moved++
+		}
+	}
+	assert.InDelta(t, keys/4, moved, keys/4*0.25)
+
+	assert.Empty(t, NewRing(&Map{VirtualNodes: 1}).Owner(testKey(0)))
+}
+
+func TestRingReplicas(t *testing.T) {
+	ring := NewRing(testMap("shard-1", "shard-2", "shard-3", "shard-4"))
+
+	for i := 0; i < 1000; i++ {
+		replicas := ring.Replicas(testKey(i), 3)
+		assert.Len(t, replicas, 3)
+		assert.Equal(t, ring.Owner(testKey(i)), replicas[0])
+		assert.NotEqual(t, replicas[0], replicas[1])
+		assert.NotEqual(t, replicas[0], replicas[2])
+		assert.NotEqual(t, replicas[1], replicas[2])
+
+		// Preference lists of different lengths agree on their common prefix
+		assert.Equal(t, replicas[:2], ring.Replicas(testKey(i), 2))
+	}
+
+	assert.Len(t, ring.Replicas(testKey(0), 10), 4)
+	assert.Empty(t, NewRing(&Map{VirtualNodes: 1}).Replicas(testKey(0), 3))
+}
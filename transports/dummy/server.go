@@ -5,6 +5,7 @@ import (
 	"github.com/panjf2000/gnet"
 	"github.com/pkg/errors"
 	"github.com/unpackdev/fdb/config"
+	"github.com/unpackdev/fdb/messages"
 	"github.com/unpackdev/fdb/types"
 	"go.uber.org/zap"
 	"time"
@@ -138,12 +139,18 @@ func (s *Server) React(frame []byte, c gnet.Conn) (out []byte, action gnet.Actio
 
 // parseActionType parses the action type from the frame
 func (s *Server) parseActionType(frame []byte) (types.HandlerType, error) {
+	// The action byte follows the optional consistency header and database selector
+	_, _, frame, err := messages.SplitHeader(frame)
+	if err != nil {
+		return 0, err
+	}
+
 	if len(frame) < 1 {
 		return 0, errors.New("invalid action: frame too short")
 	}
 
 	var actionType types.HandlerType
-	err := actionType.FromByte(frame[0])
+	err = actionType.FromByte(frame[0])
 	if err != nil {
 		return 0, err
 	}
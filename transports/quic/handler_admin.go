@@ -0,0 +1,38 @@
+package transport_quic
+
+import (
+	"encoding/binary"
+	"github.com/quic-go/quic-go"
+	"github.com/unpackdev/fdb/db"
+	"github.com/unpackdev/fdb/messages"
+	"log"
+)
+
+// QuicAdminHandler struct with the database manager passed in
+type QuicAdminHandler struct {
+	manager *db.Manager // Database manager executing the admin operations
+}
+
+// NewQuicAdminHandler creates a new QuicAdminHandler with a database manager
+func NewQuicAdminHandler(manager *db.Manager) *QuicAdminHandler {
+	return &QuicAdminHandler{
+		manager: manager,
+	}
+}
+
+// HandleMessage executes the admin operation carried in the message data and sends back
+// the status-prefixed response, length-prefixed in the same way as read responses.
+func (ah *QuicAdminHandler) HandleMessage(conn quic.Connection, stream quic.Stream, message *messages.Message) {
+	response := ah.manager.ExecuteAdminFrame(message.Data)
+
+	lengthBuffer := make([]byte, 4)
+	binary.BigEndian.PutUint32(lengthBuffer, uint32(len(response)))
+	if _, err := stream.Write(lengthBuffer); err != nil {
+		log.Printf("Error writing admin response length: %v", err)
+		return
+	}
+
+	if _, err := stream.Write(response); err != nil {
+		log.Printf("Error writing admin response: %v", err)
+	}
+}
@@ -0,0 +1,44 @@
+package transport_quic
+
+import (
+	"context"
+	"github.com/quic-go/quic-go"
+	"github.com/unpackdev/fdb/db"
+	"github.com/unpackdev/fdb/messages"
+	"github.com/unpackdev/fdb/types"
+	"log"
+)
+
+// QuicDeleteHandler struct with the database router passed in
+type QuicDeleteHandler struct {
+	router *db.Router // Resolves the database and batch writer of each request
+}
+
+// NewQuicDeleteHandler creates a new QuicDeleteHandler with a database router
+func NewQuicDeleteHandler(router *db.Router) *QuicDeleteHandler {
+	return &QuicDeleteHandler{
+		router: router,
+	}
+}
+
+// HandleMessage deletes the key of the message and answers with a status byte
+func (dh *QuicDeleteHandler) HandleMessage(conn quic.Connection, stream quic.Stream, message *messages.Message) {
+	status := byte(types.StatusOK)
+
+	// Databases replicated through consensus acknowledge once the delete is committed
+	if proposer, name := dh.router.Proposer(types.DbType(message.Database)); proposer != nil {
+		if err := proposer.Propose(db.WithConsistency(context.Background(), message.Consistency), name, types.ChangeDelete, message.Key[:], nil); err != nil {
+			log.Printf("Error committing delete: %v", err)
+			status = byte(db.WriteStatus(err))
+		}
+	} else if writer, err := dh.router.Writer(types.DbType(message.Database)); err != nil {
+		log.Printf("Error resolving database: %v", err)
+		status = byte(db.WriteStatus(err))
+	} else {
+		writer.BufferDelete(message.Key)
+	}
+
+	if _, err := stream.Write([]byte{status}); err != nil {
+		log.Printf("Error sending response: %v", err)
+	}
+}
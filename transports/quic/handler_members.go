@@ -0,0 +1,37 @@
+package transport_quic
+
+import (
+	"encoding/binary"
+	"github.com/quic-go/quic-go"
+	"github.com/unpackdev/fdb/messages"
+	"log"
+)
+
+// QuicMembersHandler struct with the source of the cluster member list passed in
+type QuicMembersHandler struct {
+	members func() []byte // Returns the status-prefixed member list
+}
+
+// NewQuicMembersHandler creates a new QuicMembersHandler answering with the given member list
+func NewQuicMembersHandler(members func() []byte) *QuicMembersHandler {
+	return &QuicMembersHandler{
+		members: members,
+	}
+}
+
+// HandleMessage sends back the status-prefixed JSON list of the alive cluster members,
+// length-prefixed in the same way as read responses.
+func (mh *QuicMembersHandler) HandleMessage(conn quic.Connection, stream quic.Stream, message *messages.Message) {
+	response := mh.members()
+
+	lengthBuffer := make([]byte, 4)
+	binary.BigEndian.PutUint32(lengthBuffer, uint32(len(response)))
+	if _, err := stream.Write(lengthBuffer); err != nil {
+		log.Printf("Error writing members response length: %v", err)
+		return
+	}
+
+	if _, err := stream.Write(response); err != nil {
+		log.Printf("Error writing members response: %v", err)
+	}
+}
@@ -0,0 +1,66 @@
+package transport_quic
+
+import (
+	"context"
+	"github.com/quic-go/quic-go"
+	"github.com/unpackdev/fdb/db"
+	"github.com/unpackdev/fdb/messages"
+	"github.com/unpackdev/fdb/types"
+	"log"
+)
+
+// QuicMultiSetHandler struct with the database router passed in
+type QuicMultiSetHandler struct {
+	router *db.Router // Resolves the database and batch writer of each request
+}
+
+// NewQuicMultiSetHandler creates a new QuicMultiSetHandler with a database router
+func NewQuicMultiSetHandler(router *db.Router) *QuicMultiSetHandler {
+	return &QuicMultiSetHandler{
+		router: router,
+	}
+}
+
+// HandleMessage processes a multi-set request. The message carries the multi-set frame without
+// its handler byte as data, see messages.EncodeMultiSet, and is acknowledged with a single status
+// byte once all writes are buffered.
+func (mh *QuicMultiSetHandler) HandleMessage(conn quic.Connection, stream quic.Stream, message *messages.Message) {
+	frame := append([]byte{byte(types.MultiSetHandlerType)}, message.Data...)
+	entries, err := messages.DecodeMultiSet(frame)
+	if err != nil {
+		log.Printf("Invalid multi-set: %v", err)
+		_, _ = stream.Write([]byte{byte(types.StatusError)})
+		return
+	}
+
+	// Databases replicated through consensus acknowledge once every write is committed
+	if proposer, name := mh.router.Proposer(types.DbType(message.Database)); proposer != nil {
+		status := byte(types.StatusOK)
+		ctx := db.WithConsistency(context.Background(), message.Consistency)
+		for _, entry := range entries {
+			if err := proposer.Propose(ctx, name, types.ChangeSet, entry.Key[:], entry.Value); err != nil {
+				log.Printf("Error committing multi-set: %v", err)
+				status = byte(db.WriteStatus(err))
+				break
+			}
+		}
+		if _, err := stream.Write([]byte{status}); err != nil {
+			log.Printf("Error sending response: %v", err)
+		}
+		return
+	}
+
+	writer, err := mh.router.Writer(types.DbType(message.Database))
+	if err != nil {
+		log.Printf("Error resolving database: %v", err)
+		_, _ = stream.Write([]byte{byte(db.WriteStatus(err))})
+		return
+	}
+
+	for _, entry := range entries {
+		writer.BufferWrite(entry.Key, entry.Value)
+	}
+	if _, err := stream.Write([]byte{byte(types.StatusOK)}); err != nil {
+		log.Printf("Error sending response: %v", err)
+	}
+}
@@ -0,0 +1,34 @@
+package transport_quic
+
+import (
+	"github.com/quic-go/quic-go"
+	"github.com/unpackdev/fdb/db"
+	"github.com/unpackdev/fdb/messages"
+	"github.com/unpackdev/fdb/types"
+	"log"
+)
+
+// QuicPingHandler struct with the database router passed in
+type QuicPingHandler struct {
+	router *db.Router // Resolves the database of each request
+}
+
+// NewQuicPingHandler creates a new QuicPingHandler with a database router
+func NewQuicPingHandler(router *db.Router) *QuicPingHandler {
+	return &QuicPingHandler{
+		router: router,
+	}
+}
+
+// HandleMessage answers a health check with a status byte, types.StatusDatabaseNotFound when
+// the node does not serve the selected database. Pings touch no data.
+func (ph *QuicPingHandler) HandleMessage(conn quic.Connection, stream quic.Stream, message *messages.Message) {
+	status := byte(types.StatusOK)
+	if _, err := ph.router.Resolve(types.DbType(message.Database)); err != nil {
+		status = byte(types.StatusDatabaseNotFound)
+	}
+
+	if _, err := stream.Write([]byte{status}); err != nil {
+		log.Printf("Error sending response: %v", err)
+	}
+}
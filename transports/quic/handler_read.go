@@ -1,22 +1,31 @@
 package transport_quic
 
 import (
+	"context"
 	"encoding/binary"
+	"errors"
 	"github.com/quic-go/quic-go"
 	"github.com/unpackdev/fdb/db"
+	fdbErrors "github.com/unpackdev/fdb/errors"
 	"github.com/unpackdev/fdb/messages"
+	"github.com/unpackdev/fdb/types"
 	"log"
+	"time"
 )
 
-// QuicReadHandler struct with MDBX database passed in
+// readTimeout bounds a read served by other nodes, such as the replicas of a database
+// replicated with quorums.
+const readTimeout = 10 * time.Second
+
+// QuicReadHandler struct with the database router passed in
 type QuicReadHandler struct {
-	db db.Provider // MDBX database instance
+	router *db.Router // Resolves the database of each request
 }
 
-// NewQuicReadHandler creates a new QuicReadHandler with an MDBX database
-func NewQuicReadHandler(db db.Provider) *QuicReadHandler {
+// NewQuicReadHandler creates a new QuicReadHandler with a database router
+func NewQuicReadHandler(router *db.Router) *QuicReadHandler {
 	return &QuicReadHandler{
-		db: db,
+		router: router,
 	}
 }
 
@@ -24,8 +33,26 @@ func NewQuicReadHandler(db db.Provider) *QuicReadHandler {
 func (rh *QuicReadHandler) HandleMessage(conn quic.Connection, stream quic.Stream, message *messages.Message) {
 	//log.Printf("Processing read request: Handler=%d, Key=%x", message.Handler, message.Key)
 
-	// Query the database using the key from the Message struct
-	value, err := rh.db.Get(message.Key[:])
+	bDb, err := rh.router.Resolve(types.DbType(message.Database))
+	if err != nil {
+		log.Printf("Error resolving database: %v", err)
+		_, _ = stream.Write([]byte{byte(types.StatusDatabaseNotFound)})
+		return
+	}
+
+	// Query the database using the key from the Message struct, or the nodes serving its reads
+	var value []byte
+	if reader, name := rh.router.Reader(types.DbType(message.Database)); reader != nil {
+		ctx, cancel := context.WithTimeout(db.WithConsistency(context.Background(), message.Consistency), readTimeout)
+		value, err = reader.Read(ctx, name, message.Key[:])
+		cancel()
+	} else {
+		value, err = bDb.Get(message.Key[:])
+	}
+	if _, tagged := stream.(*taggedStream); tagged {
+		writeTaggedReadResponse(stream, value, err)
+		return
+	}
 	if err != nil {
 		log.Printf("Error reading from database: %v", err)
 		_, _ = stream.Write([]byte("Error reading from database"))
@@ -56,3 +83,22 @@ func (rh *QuicReadHandler) HandleMessage(conn quic.Connection, stream quic.Strea
 
 	//log.Printf("Successfully sent response for key: %x", message.Key)
 }
+
+// writeTaggedReadResponse answers a tagged read with a status byte followed by the value, see
+// messages.EncodeReadResponse, rather than the length-prefixed value or text error of untagged reads.
+func writeTaggedReadResponse(stream quic.Stream, value []byte, err error) {
+	if err == nil && len(value) == 0 {
+		err = fdbErrors.ErrNotFound
+	}
+	if err != nil && !errors.Is(err, fdbErrors.ErrNotFound) {
+		log.Printf("Error reading from database: %v", err)
+	}
+
+	status := db.ReadStatus(err)
+	if status == types.StatusError {
+		value = []byte(err.Error())
+	}
+	if _, err := stream.Write(messages.EncodeReadResponse(status, value)); err != nil {
+		log.Printf("Error writing response: %v", err)
+	}
+}
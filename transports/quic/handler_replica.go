@@ -0,0 +1,39 @@
+package transport_quic
+
+import (
+	"github.com/quic-go/quic-go"
+	"github.com/unpackdev/fdb/db"
+	"github.com/unpackdev/fdb/messages"
+	"github.com/unpackdev/fdb/types"
+	"log"
+)
+
+// QuicReplicaHandler struct with the database router passed in
+type QuicReplicaHandler struct {
+	router *db.Router // Resolves the local replica of each request
+}
+
+// NewQuicReplicaHandler creates a new QuicReplicaHandler with a database router
+func NewQuicReplicaHandler(router *db.Router) *QuicReplicaHandler {
+	return &QuicReplicaHandler{
+		router: router,
+	}
+}
+
+// HandleMessage serves a quorum coordinator reading or writing the local replica of a key. The
+// message data holds the messages.ReplicaRequest, the response is written unframed.
+func (rh *QuicReplicaHandler) HandleMessage(conn quic.Connection, stream quic.Stream, message *messages.Message) {
+	response := []byte{byte(types.StatusError)}
+	if req, err := messages.DecodeReplicaRequest(message.Data); err != nil {
+		log.Printf("Invalid replica request: %v", err)
+	} else if bDb, err := rh.router.Resolve(types.DbType(message.Database)); err != nil {
+		log.Printf("Error resolving database: %v", err)
+		response = []byte{byte(types.StatusDatabaseNotFound)}
+	} else {
+		response = db.ExecuteReplicaRequest(bDb, req)
+	}
+
+	if _, err := stream.Write(response); err != nil {
+		log.Printf("Error sending response: %v", err)
+	}
+}
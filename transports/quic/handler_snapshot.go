@@ -0,0 +1,68 @@
+package transport_quic
+
+import (
+	"bufio"
+	"github.com/quic-go/quic-go"
+	"github.com/unpackdev/fdb/db"
+	fdbErrors "github.com/unpackdev/fdb/errors"
+	"github.com/unpackdev/fdb/messages"
+	"github.com/unpackdev/fdb/types"
+	"log"
+)
+
+// QuicSnapshotHandler struct with the database router passed in
+type QuicSnapshotHandler struct {
+	router *db.Router // Resolves the database of each request
+}
+
+// NewQuicSnapshotHandler creates a new QuicSnapshotHandler with a database router
+func NewQuicSnapshotHandler(router *db.Router) *QuicSnapshotHandler {
+	return &QuicSnapshotHandler{
+		router: router,
+	}
+}
+
+// HandleMessage streams a consistent snapshot of the selected database to the stream, as
+// described by messages.SnapshotRequest. The message data carries the encoded snapshot request.
+func (sh *QuicSnapshotHandler) HandleMessage(conn quic.Connection, stream quic.Stream, message *messages.Message) {
+	if _, err := messages.DecodeSnapshotRequest(message.Data); err != nil {
+		_, _ = stream.Write(messages.EncodeStreamFrame(types.StatusError, []byte(err.Error())))
+		return
+	}
+
+	provider, err := sh.router.Resolve(types.DbType(message.Database))
+	if err != nil {
+		_, _ = stream.Write(messages.EncodeStreamFrame(types.StatusDatabaseNotFound, []byte(err.Error())))
+		return
+	}
+
+	bDb, ok := provider.(*db.Db)
+	if !ok {
+		_, _ = stream.Write(messages.EncodeStreamFrame(types.StatusError, []byte(fdbErrors.ErrCdcDisabled.Error())))
+		return
+	}
+
+	// Buffer the frames so a large snapshot is not written one small frame at a time
+	w := bufio.NewWriterSize(stream, 256*1024)
+	err = bDb.Snapshot(func(seq uint64) error {
+		_, err := w.Write(messages.EncodeStreamFrame(types.StatusOK, messages.EncodeSnapshotHead(seq)))
+		return err
+	}, func(key, value []byte) error {
+		entry, err := messages.EncodeSnapshotEntry(key, value)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(messages.EncodeStreamFrame(types.StatusOK, entry))
+		return err
+	})
+	if err == nil {
+		if _, err = w.Write(messages.EncodeStreamFrame(types.StatusOK, nil)); err == nil {
+			err = w.Flush()
+		}
+	}
+	if err != nil {
+		log.Printf("Snapshot stopped: %v", err)
+		_ = w.Flush()
+		_, _ = stream.Write(messages.EncodeStreamFrame(types.StatusError, []byte(err.Error())))
+	}
+}
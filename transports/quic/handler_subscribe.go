@@ -0,0 +1,131 @@
+package transport_quic
+
+import (
+	"context"
+	"errors"
+	"github.com/quic-go/quic-go"
+	"github.com/unpackdev/fdb/db"
+	fdbErrors "github.com/unpackdev/fdb/errors"
+	"github.com/unpackdev/fdb/messages"
+	"github.com/unpackdev/fdb/types"
+	"log"
+	"sync"
+	"time"
+)
+
+// QuicSubscribeHandler struct with the database router passed in
+type QuicSubscribeHandler struct {
+	router *db.Router // Resolves the database of each request
+}
+
+// NewQuicSubscribeHandler creates a new QuicSubscribeHandler with a database router
+func NewQuicSubscribeHandler(router *db.Router) *QuicSubscribeHandler {
+	return &QuicSubscribeHandler{
+		router: router,
+	}
+}
+
+// HandleMessage streams the change log of the selected database to the stream. The message
+// data carries the encoded subscribe request. The stream is dedicated to the subscription; a
+// StatusHeartbeat frame carrying the change log head acknowledges the subscription and is sent
+// again every messages.HeartbeatInterval, every change is sent as a StatusOK stream frame and a
+// final error frame is sent if the subscription fails, StatusTruncated when the requested
+// changes were already pruned. The subscription stops when the stream is closed.
+func (sh *QuicSubscribeHandler) HandleMessage(conn quic.Connection, stream quic.Stream, message *messages.Message) {
+	req, err := messages.DecodeSubscribeRequest(message.Data)
+	if err != nil {
+		_, _ = stream.Write(messages.EncodeStreamFrame(types.StatusError, []byte(err.Error())))
+		return
+	}
+
+	provider, err := sh.router.Resolve(types.DbType(message.Database))
+	if err != nil {
+		_, _ = stream.Write(messages.EncodeStreamFrame(types.StatusDatabaseNotFound, []byte(err.Error())))
+		return
+	}
+
+	bDb, ok := provider.(*db.Db)
+	if !ok {
+		_, _ = stream.Write(messages.EncodeStreamFrame(types.StatusError, []byte(fdbErrors.ErrCdcDisabled.Error())))
+		return
+	}
+
+	// Pruned changes or a disabled change log fail the subscription before it is acknowledged,
+	// ahead of every change
+	if _, err := bDb.ReadChanges(req.From, 0); err != nil {
+		_, _ = stream.Write(messages.EncodeStreamFrame(streamStatus(err), []byte(err.Error())))
+		return
+	}
+	ack, err := heartbeatFrame(bDb)
+	if err != nil {
+		_, _ = stream.Write(messages.EncodeStreamFrame(types.StatusError, []byte(err.Error())))
+		return
+	}
+	if _, err := stream.Write(ack); err != nil {
+		return
+	}
+
+	// Changes and heartbeats are written from different goroutines
+	var mu sync.Mutex
+	write := func(frame []byte) error {
+		mu.Lock()
+		defer mu.Unlock()
+		_, err := stream.Write(frame)
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(stream.Context())
+	defer cancel()
+	go sendHeartbeats(ctx, bDb, write)
+
+	err = bDb.Subscribe(ctx, req.From, func(change *messages.Change) error {
+		encoded, err := change.Encode()
+		if err != nil {
+			return err
+		}
+		return write(messages.EncodeStreamFrame(types.StatusOK, encoded))
+	})
+	if err != nil && !errors.Is(err, context.Canceled) {
+		log.Printf("Subscription from sequence %d stopped: %v", req.From, err)
+		_ = write(messages.EncodeStreamFrame(streamStatus(err), []byte(err.Error())))
+	}
+}
+
+// sendHeartbeats writes a heartbeat frame every messages.HeartbeatInterval until ctx is done.
+func sendHeartbeats(ctx context.Context, bDb *db.Db, write func([]byte) error) {
+	ticker := time.NewTicker(messages.HeartbeatInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			frame, err := heartbeatFrame(bDb)
+			if err != nil {
+				return
+			}
+			if err := write(frame); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// heartbeatFrame returns a heartbeat frame carrying the change log head of the database.
+func heartbeatFrame(bDb *db.Db) ([]byte, error) {
+	head, err := bDb.HeadSequence()
+	if err != nil {
+		return nil, err
+	}
+	heartbeat := messages.Heartbeat{Head: head, Timestamp: time.Now().UnixNano()}
+	return messages.EncodeStreamFrame(types.StatusHeartbeat, heartbeat.Encode()), nil
+}
+
+// streamStatus returns the status of the frame failing a subscription with err.
+func streamStatus(err error) types.ResponseStatus {
+	if errors.Is(err, fdbErrors.ErrChangesTruncated) {
+		return types.StatusTruncated
+	}
+	return types.StatusError
+}
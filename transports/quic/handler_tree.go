@@ -0,0 +1,77 @@
+package transport_quic
+
+import (
+	"bufio"
+	"github.com/quic-go/quic-go"
+	"github.com/unpackdev/fdb/db"
+	"github.com/unpackdev/fdb/messages"
+	"github.com/unpackdev/fdb/types"
+	"log"
+)
+
+// QuicTreeHandler struct with the database router passed in
+type QuicTreeHandler struct {
+	router *db.Router // Resolves the database of each request
+}
+
+// NewQuicTreeHandler creates a new QuicTreeHandler with a database router
+func NewQuicTreeHandler(router *db.Router) *QuicTreeHandler {
+	return &QuicTreeHandler{
+		router: router,
+	}
+}
+
+// HandleMessage answers a Merkle tree request for the selected database, as described by
+// messages.TreeRequest. The message data carries the encoded tree request.
+func (th *QuicTreeHandler) HandleMessage(conn quic.Connection, stream quic.Stream, message *messages.Message) {
+	req, err := messages.DecodeTreeRequest(message.Data)
+	if err != nil {
+		_, _ = stream.Write(messages.EncodeStreamFrame(types.StatusError, []byte(err.Error())))
+		return
+	}
+
+	provider, err := th.router.Resolve(types.DbType(message.Database))
+	if err != nil {
+		_, _ = stream.Write(messages.EncodeStreamFrame(types.StatusDatabaseNotFound, []byte(err.Error())))
+		return
+	}
+
+	bDb, ok := provider.(*db.Db)
+	if !ok {
+		_, _ = stream.Write(messages.EncodeStreamFrame(types.StatusError, []byte("database keeps no merkle tree")))
+		return
+	}
+
+	// Buffer the frames so a large range is not written one small frame at a time
+	w := bufio.NewWriterSize(stream, 256*1024)
+	if req.Op == messages.TreeHashes {
+		var hashes []db.MerkleHash
+		if hashes, err = bDb.MerkleHashes(int(req.Level), req.Indices); err == nil {
+			body := make([]byte, 0, len(hashes)*len(db.MerkleHash{}))
+			for _, hash := range hashes {
+				body = append(body, hash[:]...)
+			}
+			_, err = w.Write(messages.EncodeStreamFrame(types.StatusOK, body))
+		}
+	} else {
+		err = bDb.MerkleRange(req.Indices, func(key, value []byte) error {
+			entry, err := messages.EncodeSnapshotEntry(key, value)
+			if err != nil {
+				return err
+			}
+			_, err = w.Write(messages.EncodeStreamFrame(types.StatusOK, entry))
+			return err
+		})
+		if err == nil {
+			_, err = w.Write(messages.EncodeStreamFrame(types.StatusOK, nil))
+		}
+	}
+	if err == nil {
+		err = w.Flush()
+	}
+	if err != nil {
+		log.Printf("Tree request stopped: %v", err)
+		_ = w.Flush()
+		_, _ = stream.Write(messages.EncodeStreamFrame(types.StatusError, []byte(err.Error())))
+	}
+}
@@ -1,23 +1,23 @@
 package transport_quic
 
 import (
+	"context"
 	"github.com/quic-go/quic-go"
 	"github.com/unpackdev/fdb/db"
 	"github.com/unpackdev/fdb/messages"
+	"github.com/unpackdev/fdb/types"
 	"log"
 )
 
-// QuicWriteHandler struct with MDBX database passed in
+// QuicWriteHandler struct with the database router passed in
 type QuicWriteHandler struct {
-	db     db.Provider     // MDBX database instance
-	writer *db.BatchWriter // Batch writer instance
+	router *db.Router // Resolves the database and batch writer of each request
 }
 
-// NewQuicWriteHandler creates a new QuicWriteHandler with an MDBX database
-func NewQuicWriteHandler(db db.Provider, batchWriter *db.BatchWriter) *QuicWriteHandler {
+// NewQuicWriteHandler creates a new QuicWriteHandler with a database router
+func NewQuicWriteHandler(router *db.Router) *QuicWriteHandler {
 	return &QuicWriteHandler{
-		db:     db,
-		writer: batchWriter,
+		router: router,
 	}
 }
 
@@ -26,8 +26,28 @@ func (wh *QuicWriteHandler) HandleMessage(conn quic.Connection, stream quic.Stre
 	// Log the message for debugging purposes
 	//log.Printf("Processing write request: Handler=%d, Key=%x, Data=%s", message.Handler, message.Key, string(message.Data))
 
+	// Databases replicated through consensus acknowledge once the write is committed
+	if proposer, name := wh.router.Proposer(types.DbType(message.Database)); proposer != nil {
+		status := byte(types.StatusOK)
+		if err := proposer.Propose(db.WithConsistency(context.Background(), message.Consistency), name, types.ChangeSet, message.Key[:], message.Data); err != nil {
+			log.Printf("Error committing write: %v", err)
+			status = byte(db.WriteStatus(err))
+		}
+		if _, err := stream.Write([]byte{status}); err != nil {
+			log.Printf("Error sending response: %v", err)
+		}
+		return
+	}
+
+	writer, err := wh.router.Writer(types.DbType(message.Database))
+	if err != nil {
+		log.Printf("Error resolving database: %v", err)
+		_, _ = stream.Write([]byte{byte(db.WriteStatus(err))})
+		return
+	}
+
 	// Buffer the write request with the key as [32]byte
-	wh.writer.BufferWrite(message.Key, message.Data)
+	writer.BufferWrite(message.Key, message.Data)
 
 	// Send success response
 
@@ -1,6 +1,7 @@
 package transport_quic
 
 import (
+	"bufio"
 	"context"
 	"crypto/tls"
 	"fmt"
@@ -27,7 +28,11 @@ type Server struct {
 	stopChan        chan struct{}
 	started         chan struct{}
 	wg              sync.WaitGroup
-	listener        *quic.Listener
+	listener        *quic.EarlyListener
+
+	// conns tracks the open connections, closed when the server stops.
+	connsMu sync.Mutex
+	conns   map[quic.Connection]struct{}
 }
 
 // NewServer creates a new QuicServer instance
@@ -44,6 +49,7 @@ func NewServer(ctx context.Context, cnf config.QuicTransport) (*Server, error) {
 		tlsConfig:       tlsConfig,
 		stopChan:        make(chan struct{}),
 		started:         make(chan struct{}),
+		conns:           make(map[quic.Connection]struct{}),
 	}
 
 	return server, nil
@@ -57,7 +63,7 @@ func (s *Server) Addr() string {
 // Start starts the QUIC server
 func (s *Server) Start(ctx context.Context) error {
 	var err error
-	s.listener, err = quic.ListenAddr(s.cnf.Addr(), s.tlsConfig, nil)
+	s.listener, err = quic.ListenAddrEarly(s.cnf.Addr(), s.tlsConfig, &quic.Config{Allow0RTT: s.cnf.Allow0RTT})
 	if err != nil {
 		return fmt.Errorf("failed to start QUIC server: %w", err)
 	}
@@ -110,6 +116,15 @@ func (s *Server) acceptConnections() {
 func (s *Server) handleConnection(conn quic.Connection) {
 	defer s.wg.Done()
 
+	s.connsMu.Lock()
+	s.conns[conn] = struct{}{}
+	s.connsMu.Unlock()
+	defer func() {
+		s.connsMu.Lock()
+		delete(s.conns, conn)
+		s.connsMu.Unlock()
+	}()
+
 	// Continuously accept and handle new streams on the connection
 	for {
 		stream, err := conn.AcceptStream(context.Background())
@@ -150,27 +165,32 @@ func isClosedNetworkConnectionError(err error) bool {
 func (s *Server) handleStream(conn quic.Connection, stream quic.Stream) {
 	defer s.wg.Done()
 
+	// Closing the send side once the peer is done tells it no more responses follow
+	defer stream.Close()
+
+	// Reads are buffered to delimit tagged requests, untagged requests still take a single read
+	reader := bufio.NewReader(stream)
+
 	// Continuously read from the stream until it's closed
 	for {
+		// Tagged requests carry their length and are answered with tagged responses, see
+		// messages.TagSelector
+		if head, err := reader.Peek(1); err == nil && head[0] == messages.TagSelector {
+			if err := s.handleTagged(conn, stream, reader); err != nil {
+				if !isStreamEndError(err) {
+					log.Printf("Error handling tagged request: %v", err)
+				}
+				return
+			}
+			continue
+		}
+
 		// Step 1: Read from the stream into a buffer
 		// Assuming max message size is known or stream EOF will signify message end
 		buffer := make([]byte, 4096) // Adjust the buffer size based on your requirements
-		n, err := stream.Read(buffer)
+		n, err := reader.Read(buffer)
 		if err != nil {
-			// Check if the error is a QUIC ApplicationError with code 0x0 (connection closed normally)
-			var appErr *quic.ApplicationError
-			if errors.As(err, &appErr) && appErr.ErrorCode == 0x0 {
-				// Suppress logging for this specific error
-				return
-			}
-
-			// Handle specific "use of closed network connection" error
-			if isClosedNetworkConnectionError(err) {
-				return
-			}
-
-			// Handle other EOF or connection close errors
-			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, quic.ErrServerClosed) {
+			if isStreamEndError(err) {
 				return
 			}
 
@@ -201,6 +221,57 @@ func (s *Server) handleStream(conn quic.Connection, stream quic.Stream) {
 	}
 }
 
+// handleTagged reads a tagged request from the stream and answers it with a single tagged
+// response carrying everything the handler wrote.
+func (s *Server) handleTagged(conn quic.Connection, stream quic.Stream, reader *bufio.Reader) error {
+	header := make([]byte, messages.TagHeaderLen)
+	if _, err := io.ReadFull(reader, header); err != nil {
+		return err
+	}
+	id, length, err := messages.DecodeTagHeader(header)
+	if err != nil {
+		return err
+	}
+
+	frame := make([]byte, length)
+	if _, err := io.ReadFull(reader, frame); err != nil {
+		return err
+	}
+	message, err := messages.Decode(frame)
+	if err != nil {
+		return fmt.Errorf("failed to decode tagged request: %w", err)
+	}
+
+	handler, exists := s.handlerRegistry[message.Handler]
+	if !exists {
+		return fmt.Errorf("no handler found for action type %d", message.Handler)
+	}
+
+	tagged := &taggedStream{Stream: stream}
+	handler(conn, tagged, message)
+
+	_, err = stream.Write(messages.TagFrame(id, tagged.response))
+	return err
+}
+
+// isStreamEndError reports whether a stream read failed because the peer or the server closed
+// the stream or its connection.
+func isStreamEndError(err error) bool {
+	// Check if the error is a QUIC ApplicationError with code 0x0 (connection closed normally)
+	var appErr *quic.ApplicationError
+	if errors.As(err, &appErr) && appErr.ErrorCode == 0x0 {
+		return true
+	}
+
+	// Handle specific "use of closed network connection" error
+	if isClosedNetworkConnectionError(err) {
+		return true
+	}
+
+	// Handle other EOF or connection close errors
+	return errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, quic.ErrServerClosed)
+}
+
 // Stop stops the QUIC server
 func (s *Server) Stop() error {
 	close(s.stopChan)
@@ -208,6 +279,13 @@ func (s *Server) Stop() error {
 		return err
 	}
 
+	// Established connections outlive the listener, they are closed so their handlers return
+	s.connsMu.Lock()
+	for conn := range s.conns {
+		_ = conn.CloseWithError(0, "server stopped")
+	}
+	s.connsMu.Unlock()
+
 	s.wg.Wait()
 	return nil
 }
@@ -1,175 +1,87 @@
-package transport_quic
+package transport_quic_test
 
 import (
 	"context"
-	"crypto/rand"
 	"crypto/tls"
-	"encoding/binary"
-	"github.com/quic-go/quic-go"
-	"github.com/stretchr/testify/assert"
-	"github.com/stretchr/testify/require"
-	"github.com/unpackdev/fdb/db"
+	"crypto/x509"
 	"io"
-	"log"
+	"os"
 	"testing"
 	"time"
-)
-
-func startQuicServer(ctx context.Context, db db.Provider) (*QuicServer, error) {
-	port, err := getRandomPort()
-	if err != nil {
-		return nil, err
-	}
-	log.Printf("Starting QUIC server on port: %d", port)
-
-	tlsConfig := GenerateTLSConfig()
-
-	server, err := NewQuicServer("127.0.0.1", port, tlsConfig)
-	if err != nil {
-		return nil, err
-	}
-
-	wHandler := NewQuicWriteHandler(db)
-	server.RegisterHandler(WriteHandlerType, wHandler.HandleMessage)
-
-	rHandler := NewQuicReadHandler(db)
-	server.RegisterHandler(ReadHandlerType, rHandler.HandleMessage)
 
-	go func() {
-		log.Println("Starting QUIC server...")
-		err := server.Start()
-		if err != nil {
-			log.Fatalf("Failed to start QUIC server: %v", err)
-		}
-		log.Println("QUIC server started.")
-	}()
+	"github.com/quic-go/quic-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/unpackdev/fdb/fdbtest"
+	"github.com/unpackdev/fdb/messages"
+	"github.com/unpackdev/fdb/types"
+)
 
-	log.Println("Awaiting for started closure...")
-	<-server.WaitStarted()
-	log.Println("Started closure detected for port:", port)
+// encode encodes a request in the framing of QUIC streams, which carries the length of data.
+func encode(t *testing.T, handler types.HandlerType, key [32]byte, data []byte) []byte {
+	t.Helper()
 
-	return server, nil
+	frame, err := (&messages.Message{Handler: handler, Key: key, Data: data}).Encode()
+	require.NoError(t, err)
+	return frame
 }
 
-// TestQUICServer tests the QUIC server with various scenarios
-func TestQUICServer(t *testing.T) {
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
-
-	// Setup the MDBX manager and database
-	manager := setupTestManager(t)
-
-	// Get the database from the manager
-	db, err := manager.GetDb("test")
-	require.NoError(t, err) // Use require instead of t.Fatal
-	defer db.Destroy()
-
-	server, sErr := startQuicServer(ctx, db)
-	require.NoError(t, sErr) // Use require instead of t.Fatal
-
-	clientTLSConfig := &tls.Config{
-		InsecureSkipVerify: true,
-		NextProtos:         []string{"quic-example"},
-	}
+// request sends frame on a stream of its own and returns everything the server answers on it.
+func request(t *testing.T, conn quic.Connection, frame []byte) []byte {
+	t.Helper()
 
-	// Create the QUIC client
-	client, err := quic.DialAddr(context.Background(), server.Addr(), clientTLSConfig, nil)
-	require.NoError(t, err) // Use require instead of t.Fatal
-	defer client.CloseWithError(0, "closing connection")
-
-	// Table of test cases
-	tests := []struct {
-		name          string
-		message       Message
-		shouldFail    bool
-		modifyMessage func([]byte) []byte // Function to modify the encoded message for testing
-	}{
-		{
-			name: "Valid Write and Read",
-			message: Message{
-				Handler: WriteHandlerType,
-				Key:     [32]byte{}, // Will be set to random key
-				Data:    []byte("test value"),
-			},
-			shouldFail:    false,
-			modifyMessage: nil,
-		},
-	}
-
-	for _, tt := range tests {
-		tt := tt // capture tt for the closure
-		t.Run(tt.name, func(t *testing.T) {
-			// Generate a random 32-byte key
-			var keyBytes [32]byte
-			_, err := rand.Read(keyBytes[:])
-			require.NoError(t, err) // Use require instead of t.Fatal
-			tt.message.Key = keyBytes
-
-			// Encode the message
-			encodedMessage, err := tt.message.Encode()
-			require.NoError(t, err) // Use require instead of t.Fatal
-
-			// Modify the encoded message if needed
-			if tt.modifyMessage != nil {
-				encodedMessage = tt.modifyMessage(encodedMessage)
-			}
-
-			// Open a single stream for both write and read operations
-			stream, err := client.OpenStreamSync(context.Background())
-			require.NoError(t, err) // Use require instead of t.Fatal
-			defer stream.Close()
-
-			// Send the write message to the server
-			_, err = stream.Write(encodedMessage)
-			require.NoError(t, err) // Use require instead of t.Error
-
-			// Prepare buffer for reading response
-			buffer := make([]byte, 1024)
-
-			// Set a read deadline to prevent hanging
-			stream.SetReadDeadline(time.Now().Add(5 * time.Second))
-
-			// Read response after write
-			n, err := stream.Read(buffer)
-			require.NoError(t, err) // Use require instead of t.Error
-
-			// Log the response received from the server after write
-			response := string(buffer[:n])
-			t.Logf("Response from server after write: %s", response)
-
-			// Ensure the response is correct
-			assert.Equal(t, "Message written to database", response)
-
-			// Prepare to read the value back
-			readMessage := Message{
-				Handler: ReadHandlerType,
-				Key:     tt.message.Key,
-			}
-			encodedReadMessage, err := readMessage.Encode()
-			require.NoError(t, err) // Use require instead of t.Fatal
-
-			// Send the read message to the server on the same stream
-			_, err = stream.Write(encodedReadMessage)
-			require.NoError(t, err) // Use require instead of t.Error
-
-			// Set a read deadline to prevent hanging
-			stream.SetReadDeadline(time.Now().Add(10 * time.Second))
-
-			// Read response length (first 4 bytes)
-			_, err = io.ReadFull(stream, buffer[:4])
-			require.NoError(t, err)
-
-			// Extract length and read value
-			valueLength := binary.BigEndian.Uint32(buffer[:4])
-			readBuffer := make([]byte, valueLength)
-			_, err = io.ReadFull(stream, readBuffer)
-			require.NoError(t, err)
-
-			// Log the response received from the server after read
-			t.Logf("Response from server after read: %s", string(readBuffer))
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	stream, err := conn.OpenStreamSync(ctx)
+	require.NoError(t, err)
+	require.NoError(t, stream.SetDeadline(time.Now().Add(time.Second)))
+
+	_, err = stream.Write(frame)
+	require.NoError(t, err)
+	// Closing the send side ends the stream once the server answered
+	require.NoError(t, stream.Close())
+	response, err := io.ReadAll(stream)
+	require.NoError(t, err)
+	return response
+}
 
-			// Compare the written value with the read value
-			assert.Equal(t, tt.message.Data, readBuffer, "The read value should match the written value")
-		})
+func TestServerAnswersStreams(t *testing.T) {
+	server := fdbtest.Start(t, fdbtest.WithTransports(types.QUICTransportType))
+
+	ca, err := os.ReadFile(server.Certs.CAFile)
+	require.NoError(t, err)
+	roots := x509.NewCertPool()
+	require.True(t, roots.AppendCertsFromPEM(ca))
+	conn, err := quic.DialAddr(context.Background(), server.Addr(types.QUICTransportType), &tls.Config{
+		RootCAs:    roots,
+		NextProtos: []string{"quic-example"},
+	}, nil)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.CloseWithError(0, "") })
+
+	var key, missing [32]byte
+	copy(key[:], "key")
+	copy(missing[:], "missing")
+	read := encode(t, types.ReadHandlerType, key, nil)
+
+	// A write is acknowledged once buffered and visible once flushed, untagged reads answer the
+	// length of the value first
+	write := encode(t, types.WriteHandlerType, key, []byte("value"))
+	assert.Equal(t, []byte{byte(types.StatusOK)}, request(t, conn, write))
+	require.Eventually(t, func() bool {
+		return assert.ObjectsAreEqual([]byte("\x00\x00\x00\x05value"), request(t, conn, read))
+	}, 5*time.Second, 20*time.Millisecond)
+
+	// Tagged reads are answered with a status followed by the value
+	for k, expected := range map[[32]byte][]byte{
+		key:     messages.EncodeReadResponse(types.StatusOK, []byte("value")),
+		missing: {byte(types.StatusNotFound)},
+	} {
+		tagged := request(t, conn, messages.TagFrame(7, encode(t, types.ReadHandlerType, k, nil)))
+		id, response, n, err := messages.SplitTagged(tagged)
+		require.NoError(t, err)
+		assert.Equal(t, len(tagged), n)
+		assert.Equal(t, uint32(7), id)
+		assert.Equal(t, expected, response)
 	}
 }
@@ -0,0 +1,17 @@
+package transport_quic
+
+import "github.com/quic-go/quic-go"
+
+// taggedStream is the stream handed to the handler of a tagged request. The handler's writes
+// are collected and sent as a single tagged response once it returns, so tagged requests suit
+// the handlers answering with a single response rather than a stream.
+type taggedStream struct {
+	quic.Stream
+	response []byte
+}
+
+// Write collects a part of the response.
+func (s *taggedStream) Write(p []byte) (int, error) {
+	s.response = append(s.response, p...)
+	return len(p), nil
+}
@@ -0,0 +1,23 @@
+package transport_tcp
+
+import (
+	"github.com/panjf2000/gnet/v2"
+	"github.com/unpackdev/fdb/db"
+)
+
+// TCPAdminHandler struct with the database manager passed in
+type TCPAdminHandler struct {
+	manager *db.Manager // Database manager executing the admin operations
+}
+
+// NewTCPAdminHandler creates a new TCPAdminHandler with a database manager
+func NewTCPAdminHandler(manager *db.Manager) *TCPAdminHandler {
+	return &TCPAdminHandler{
+		manager: manager,
+	}
+}
+
+// HandleMessage executes the admin operation and sends back the status-prefixed response
+func (ah *TCPAdminHandler) HandleMessage(c gnet.Conn, frame []byte) {
+	c.AsyncWrite(ah.manager.ExecuteAdminFrame(frame), nil)
+}
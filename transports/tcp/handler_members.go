@@ -0,0 +1,22 @@
+package transport_tcp
+
+import (
+	"github.com/panjf2000/gnet/v2"
+)
+
+// TCPMembersHandler struct with the source of the cluster member list passed in
+type TCPMembersHandler struct {
+	members func() []byte // Returns the status-prefixed member list
+}
+
+// NewTCPMembersHandler creates a new TCPMembersHandler answering with the given member list
+func NewTCPMembersHandler(members func() []byte) *TCPMembersHandler {
+	return &TCPMembersHandler{
+		members: members,
+	}
+}
+
+// HandleMessage sends back the status-prefixed JSON list of the alive cluster members
+func (mh *TCPMembersHandler) HandleMessage(c gnet.Conn, frame []byte) {
+	c.AsyncWrite(mh.members(), nil)
+}
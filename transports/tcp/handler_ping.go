@@ -0,0 +1,38 @@
+package transport_tcp
+
+import (
+	"github.com/panjf2000/gnet/v2"
+	"github.com/unpackdev/fdb/db"
+	"github.com/unpackdev/fdb/messages"
+	"github.com/unpackdev/fdb/types"
+	"log"
+)
+
+// TCPPingHandler struct with the database router passed in
+type TCPPingHandler struct {
+	router *db.Router // Resolves the database of each request
+}
+
+// NewTCPPingHandler creates a new TCPPingHandler with a database router
+func NewTCPPingHandler(router *db.Router) *TCPPingHandler {
+	return &TCPPingHandler{
+		router: router,
+	}
+}
+
+// HandleMessage answers a health check with a status byte, types.StatusDatabaseNotFound when
+// the node does not serve the selected database. Pings touch no data.
+func (ph *TCPPingHandler) HandleMessage(c gnet.Conn, frame []byte) {
+	_, database, _, err := messages.SplitHeader(frame)
+	if err != nil {
+		log.Printf("Invalid database selector: %v", err)
+		c.AsyncWrite([]byte{byte(types.StatusError)}, nil)
+		return
+	}
+
+	if _, err := ph.router.Resolve(types.DbType(database)); err != nil {
+		c.AsyncWrite([]byte{byte(types.StatusDatabaseNotFound)}, nil)
+		return
+	}
+	c.AsyncWrite([]byte{byte(types.StatusOK)}, nil)
+}
@@ -1,27 +1,56 @@
 package transport_tcp
 
 import (
+	"context"
+	"errors"
 	"github.com/panjf2000/gnet/v2"
 	"github.com/unpackdev/fdb/db"
+	fdbErrors "github.com/unpackdev/fdb/errors"
+	"github.com/unpackdev/fdb/messages"
+	"github.com/unpackdev/fdb/types"
 	"log"
+	"time"
 )
 
-// TCPReadHandler struct with MDBX database passed in
+// readTimeout bounds a read served by other nodes, such as the replicas of a database
+// replicated with quorums.
+const readTimeout = 10 * time.Second
+
+// TCPReadHandler struct with the database router passed in
 type TCPReadHandler struct {
-	db db.Provider // MDBX database instance
+	router *db.Router // Resolves the database of each request
 }
 
-// NewTCPReadHandler creates a new TCPReadHandler with an MDBX database
-func NewTCPReadHandler(db db.Provider) *TCPReadHandler {
+// NewTCPReadHandler creates a new TCPReadHandler with a database router
+func NewTCPReadHandler(router *db.Router) *TCPReadHandler {
 	return &TCPReadHandler{
-		db: db,
+		router: router,
 	}
 }
 
 // HandleMessage processes the incoming message using the TCPReadHandler
 func (rh *TCPReadHandler) HandleMessage(c gnet.Conn, frame []byte) {
+	// Strip the optional consistency header and database selector and resolve the selected database
+	consistency, database, frame, err := messages.SplitHeader(frame)
+	if err != nil {
+		log.Printf("Invalid database selector: %v", err)
+		c.AsyncWrite([]byte{byte(types.StatusError)}, nil)
+		return
+	}
+
+	bDb, err := rh.router.Resolve(types.DbType(database))
+	if err != nil {
+		log.Printf("Error resolving database: %v", err)
+		c.AsyncWrite([]byte{byte(types.StatusDatabaseNotFound)}, nil)
+		return
+	}
+
 	if len(frame) < 33 { // 1 byte action + 32-byte key
 		log.Printf("Invalid message length: %d, expected at least 33 bytes", len(frame))
+		if _, tagged := c.(*taggedConn); tagged {
+			c.AsyncWrite(messages.EncodeReadResponse(types.StatusError, []byte("Invalid message format")), nil)
+			return
+		}
 		c.AsyncWrite([]byte("Invalid message format"), nil)
 		return
 	}
@@ -29,20 +58,52 @@ func (rh *TCPReadHandler) HandleMessage(c gnet.Conn, frame []byte) {
 	// Extract the key (32 bytes starting from the second byte)
 	key := frame[1:33]
 
+	// Databases read from other nodes answer once enough replicas did, which must not block
+	// the event loop
+	if reader, name := rh.router.Reader(types.DbType(database)); reader != nil {
+		key = append([]byte(nil), key...)
+		go func() {
+			ctx, cancel := context.WithTimeout(db.WithConsistency(context.Background(), consistency), readTimeout)
+			defer cancel()
+			value, err := reader.Read(ctx, name, key)
+			writeReadResponse(c, key, value, err)
+		}()
+		return
+	}
+
 	// Read from the database using the key
-	value, err := rh.db.Get(key)
-	if err != nil {
+	value, err := bDb.Get(key)
+	writeReadResponse(c, key, value, err)
+}
+
+// writeReadResponse answers a read with its value or the error reading it. Tagged reads are
+// answered with a status byte followed by the value, see messages.EncodeReadResponse; untagged
+// reads with the raw value or a text error.
+func writeReadResponse(c gnet.Conn, key, value []byte, err error) {
+	if err == nil && len(value) == 0 {
+		err = fdbErrors.ErrNotFound
+	}
+	if err != nil && !errors.Is(err, fdbErrors.ErrNotFound) {
 		log.Printf("Error reading from database: %v", err)
-		c.AsyncWrite([]byte("Error reading from database"), nil)
+	}
+
+	if _, tagged := c.(*taggedConn); tagged {
+		status := db.ReadStatus(err)
+		if status == types.StatusError {
+			value = []byte(err.Error())
+		}
+		c.AsyncWrite(messages.EncodeReadResponse(status, value), nil)
 		return
 	}
 
-	if len(value) == 0 {
+	switch {
+	case errors.Is(err, fdbErrors.ErrNotFound):
 		log.Printf("No value found for key: %x", key)
 		c.AsyncWrite([]byte("No value found for key"), nil)
-		return
+	case err != nil:
+		c.AsyncWrite([]byte("Error reading from database"), nil)
+	default:
+		// Send the value back to the client
+		c.AsyncWrite(value, nil)
 	}
-
-	// Send the value back to the client
-	c.AsyncWrite(value, nil)
 }
@@ -0,0 +1,53 @@
+package transport_tcp
+
+import (
+	"github.com/panjf2000/gnet/v2"
+	"github.com/unpackdev/fdb/db"
+	"github.com/unpackdev/fdb/messages"
+	"github.com/unpackdev/fdb/types"
+	"log"
+)
+
+// TCPReplicaHandler struct with the database router passed in
+type TCPReplicaHandler struct {
+	router *db.Router // Resolves the local replica of each request
+}
+
+// NewTCPReplicaHandler creates a new TCPReplicaHandler with a database router
+func NewTCPReplicaHandler(router *db.Router) *TCPReplicaHandler {
+	return &TCPReplicaHandler{
+		router: router,
+	}
+}
+
+// HandleMessage serves a quorum coordinator reading or writing the local replica of a key, as
+// described by messages.ReplicaRequest.
+func (rh *TCPReplicaHandler) HandleMessage(c gnet.Conn, frame []byte) {
+	_, database, frame, err := messages.SplitHeader(frame)
+	if err != nil {
+		log.Printf("Invalid database selector: %v", err)
+		c.AsyncWrite([]byte{byte(types.StatusError)}, nil)
+		return
+	}
+
+	req, err := messages.DecodeReplicaRequest(frame)
+	if err != nil {
+		log.Printf("Invalid replica request: %v", err)
+		c.AsyncWrite([]byte{byte(types.StatusError)}, nil)
+		return
+	}
+
+	bDb, err := rh.router.Resolve(types.DbType(database))
+	if err != nil {
+		log.Printf("Error resolving database: %v", err)
+		c.AsyncWrite([]byte{byte(types.StatusDatabaseNotFound)}, nil)
+		return
+	}
+
+	// Versioned writes commit synchronously, which must not block the event loop. The frame
+	// is reused by the event loop, so the value must be a copy
+	req.Value = append([]byte(nil), req.Value...)
+	go func() {
+		c.AsyncWrite(db.ExecuteReplicaRequest(bDb, req), nil)
+	}()
+}
@@ -0,0 +1,100 @@
+package transport_tcp
+
+import (
+	"context"
+	"errors"
+	"github.com/panjf2000/gnet/v2"
+	"github.com/unpackdev/fdb/db"
+	fdbErrors "github.com/unpackdev/fdb/errors"
+	"github.com/unpackdev/fdb/messages"
+	"github.com/unpackdev/fdb/types"
+	"log"
+)
+
+// snapshotChunkSize is the number of bytes of snapshot frames queued per write.
+const snapshotChunkSize = 256 * 1024
+
+// TCPSnapshotHandler struct with the database router passed in
+type TCPSnapshotHandler struct {
+	router *db.Router // Resolves the database of each request
+}
+
+// NewTCPSnapshotHandler creates a new TCPSnapshotHandler with a database router
+func NewTCPSnapshotHandler(router *db.Router) *TCPSnapshotHandler {
+	return &TCPSnapshotHandler{
+		router: router,
+	}
+}
+
+// HandleMessage streams a consistent snapshot of the selected database to the connection,
+// as described by messages.SnapshotRequest. The snapshot stops when the connection is closed.
+func (sh *TCPSnapshotHandler) HandleMessage(c gnet.Conn, frame []byte) {
+	_, database, frame, err := messages.SplitHeader(frame)
+	if err != nil {
+		c.AsyncWrite(messages.EncodeStreamFrame(types.StatusError, []byte(err.Error())), nil)
+		return
+	}
+
+	if _, err := messages.DecodeSnapshotRequest(frame); err != nil {
+		c.AsyncWrite(messages.EncodeStreamFrame(types.StatusError, []byte(err.Error())), nil)
+		return
+	}
+
+	provider, err := sh.router.Resolve(types.DbType(database))
+	if err != nil {
+		c.AsyncWrite(messages.EncodeStreamFrame(types.StatusDatabaseNotFound, []byte(err.Error())), nil)
+		return
+	}
+
+	bDb, ok := provider.(*db.Db)
+	if !ok {
+		c.AsyncWrite(messages.EncodeStreamFrame(types.StatusError, []byte(fdbErrors.ErrCdcDisabled.Error())), nil)
+		return
+	}
+
+	// The server cancels the snapshot when the connection closes
+	ctx, cancel := context.WithCancel(context.Background())
+	c.SetContext(cancel)
+
+	// Scanning the database blocks, so it must not run on the event loop
+	go func() {
+		defer cancel()
+
+		// Frames are batched into chunks so a large snapshot does not pay one round trip per key
+		var chunk []byte
+		flush := func() error {
+			if len(chunk) == 0 {
+				return nil
+			}
+			err := writeAndWait(ctx, c, chunk)
+			chunk = nil
+			return err
+		}
+		queue := func(body []byte) error {
+			chunk = append(chunk, messages.EncodeStreamFrame(types.StatusOK, body)...)
+			if len(chunk) >= snapshotChunkSize {
+				return flush()
+			}
+			return nil
+		}
+
+		err := bDb.Snapshot(func(seq uint64) error {
+			return queue(messages.EncodeSnapshotHead(seq))
+		}, func(key, value []byte) error {
+			entry, err := messages.EncodeSnapshotEntry(key, value)
+			if err != nil {
+				return err
+			}
+			return queue(entry)
+		})
+		if err == nil {
+			if err = queue(nil); err == nil {
+				err = flush()
+			}
+		}
+		if err != nil && !errors.Is(err, context.Canceled) {
+			log.Printf("Snapshot stopped: %v", err)
+			c.AsyncWrite(messages.EncodeStreamFrame(types.StatusError, []byte(err.Error())), nil)
+		}
+	}()
+}
@@ -0,0 +1,151 @@
+package transport_tcp
+
+import (
+	"context"
+	"errors"
+	"github.com/panjf2000/gnet/v2"
+	"github.com/unpackdev/fdb/db"
+	fdbErrors "github.com/unpackdev/fdb/errors"
+	"github.com/unpackdev/fdb/messages"
+	"github.com/unpackdev/fdb/types"
+	"log"
+	"time"
+)
+
+// TCPSubscribeHandler struct with the database router passed in
+type TCPSubscribeHandler struct {
+	router *db.Router // Resolves the database of each request
+}
+
+// NewTCPSubscribeHandler creates a new TCPSubscribeHandler with a database router
+func NewTCPSubscribeHandler(router *db.Router) *TCPSubscribeHandler {
+	return &TCPSubscribeHandler{
+		router: router,
+	}
+}
+
+// HandleMessage starts streaming the change log of the selected database to the connection.
+// The connection is dedicated to the subscription from then on; a StatusHeartbeat frame carrying
+// the change log head acknowledges the subscription and is sent again every
+// messages.HeartbeatInterval, every change is sent as a StatusOK stream frame and a final error
+// frame is sent if the subscription fails, StatusTruncated when the requested changes were
+// already pruned. The subscription stops when the connection is closed.
+func (sh *TCPSubscribeHandler) HandleMessage(c gnet.Conn, frame []byte) {
+	_, database, frame, err := messages.SplitHeader(frame)
+	if err != nil {
+		c.AsyncWrite(messages.EncodeStreamFrame(types.StatusError, []byte(err.Error())), nil)
+		return
+	}
+
+	req, err := messages.DecodeSubscribeRequest(frame)
+	if err != nil {
+		c.AsyncWrite(messages.EncodeStreamFrame(types.StatusError, []byte(err.Error())), nil)
+		return
+	}
+
+	provider, err := sh.router.Resolve(types.DbType(database))
+	if err != nil {
+		c.AsyncWrite(messages.EncodeStreamFrame(types.StatusDatabaseNotFound, []byte(err.Error())), nil)
+		return
+	}
+
+	bDb, ok := provider.(*db.Db)
+	if !ok {
+		c.AsyncWrite(messages.EncodeStreamFrame(types.StatusError, []byte(fdbErrors.ErrCdcDisabled.Error())), nil)
+		return
+	}
+
+	// Pruned changes or a disabled change log fail the subscription before it is acknowledged,
+	// ahead of every change
+	if _, err := bDb.ReadChanges(req.From, 0); err != nil {
+		c.AsyncWrite(messages.EncodeStreamFrame(streamStatus(err), []byte(err.Error())), nil)
+		return
+	}
+	ack, err := heartbeatFrame(bDb)
+	if err != nil {
+		c.AsyncWrite(messages.EncodeStreamFrame(types.StatusError, []byte(err.Error())), nil)
+		return
+	}
+	c.AsyncWrite(ack, nil)
+
+	// The server cancels the subscription when the connection closes
+	ctx, cancel := context.WithCancel(context.Background())
+	c.SetContext(cancel)
+
+	// Tailing blocks, so it must not run on the event loop
+	go sendHeartbeats(ctx, c, bDb)
+	go func() {
+		defer cancel()
+
+		err := bDb.Subscribe(ctx, req.From, func(change *messages.Change) error {
+			encoded, err := change.Encode()
+			if err != nil {
+				return err
+			}
+			return writeAndWait(ctx, c, messages.EncodeStreamFrame(types.StatusOK, encoded))
+		})
+		if err != nil && !errors.Is(err, context.Canceled) {
+			log.Printf("Subscription from sequence %d stopped: %v", req.From, err)
+			c.AsyncWrite(messages.EncodeStreamFrame(streamStatus(err), []byte(err.Error())), nil)
+		}
+	}()
+}
+
+// writeAndWait queues the frame and waits until it was written, so a slow subscriber applies
+// backpressure instead of growing the outbound buffer without bound.
+func writeAndWait(ctx context.Context, c gnet.Conn, frame []byte) error {
+	done := make(chan error, 1)
+	err := c.AsyncWrite(frame, func(_ gnet.Conn, err error) error {
+		done <- err
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// sendHeartbeats writes a heartbeat frame every messages.HeartbeatInterval until ctx is done.
+func sendHeartbeats(ctx context.Context, c gnet.Conn, bDb *db.Db) {
+	ticker := time.NewTicker(messages.HeartbeatInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			frame, err := heartbeatFrame(bDb)
+			if err != nil {
+				return
+			}
+			if err := writeAndWait(ctx, c, frame); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// heartbeatFrame returns a heartbeat frame carrying the change log head of the database.
+func heartbeatFrame(bDb *db.Db) ([]byte, error) {
+	head, err := bDb.HeadSequence()
+	if err != nil {
+		return nil, err
+	}
+	heartbeat := messages.Heartbeat{Head: head, Timestamp: time.Now().UnixNano()}
+	return messages.EncodeStreamFrame(types.StatusHeartbeat, heartbeat.Encode()), nil
+}
+
+// streamStatus returns the status of the frame failing a subscription with err.
+func streamStatus(err error) types.ResponseStatus {
+	if errors.Is(err, fdbErrors.ErrChangesTruncated) {
+		return types.StatusTruncated
+	}
+	return types.StatusError
+}
@@ -0,0 +1,70 @@
+package transport_tcp_test
+
+import (
+	"bufio"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/unpackdev/fdb/config"
+	"github.com/unpackdev/fdb/db"
+	"github.com/unpackdev/fdb/fdbtest"
+	"github.com/unpackdev/fdb/messages"
+	"github.com/unpackdev/fdb/types"
+)
+
+// subscribe sends a subscribe request on a connection of its own and returns the reader of the
+// stream answering it.
+func subscribe(t *testing.T, addr string, from uint64) *bufio.Reader {
+	t.Helper()
+
+	conn, err := net.Dial("tcp", addr)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+	require.NoError(t, conn.SetDeadline(time.Now().Add(5*time.Second)))
+
+	request := messages.SubscribeRequest{From: from}
+	_, err = conn.Write(request.Encode())
+	require.NoError(t, err)
+	return bufio.NewReader(conn)
+}
+
+func TestSubscribeAcknowledgesAndReportsTruncation(t *testing.T) {
+	server := fdbtest.Start(t,
+		fdbtest.WithTransports(types.TCPTransportType),
+		fdbtest.WithCDC(),
+		fdbtest.WithConfig(func(cfg *config.Config) {
+			cfg.Mdbx.Nodes[0].Cdc.RetainCount = 1
+		}),
+	)
+	provider := server.Provider(t, fdbtest.DefaultDatabase)
+	for _, key := range []string{"a", "b", "c"} {
+		require.NoError(t, provider.Set([]byte(key), []byte("value")))
+	}
+	_, err := provider.(*db.Db).PruneChanges()
+	require.NoError(t, err)
+	addr := server.Addr(types.TCPTransportType)
+
+	// An accepted subscription is acknowledged with a heartbeat before the changes
+	reader := subscribe(t, addr, 0)
+	status, body, err := messages.ReadStreamFrame(reader)
+	require.NoError(t, err)
+	require.Equal(t, types.StatusHeartbeat, status)
+	heartbeat, err := messages.DecodeHeartbeat(body)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(3), heartbeat.Head)
+
+	status, body, err = messages.ReadStreamFrame(reader)
+	require.NoError(t, err)
+	require.Equal(t, types.StatusOK, status)
+	change, err := messages.DecodeChange(body)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("c"), change.Key)
+
+	// Changes already pruned fail the subscription with a status of their own
+	status, _, err = messages.ReadStreamFrame(subscribe(t, addr, 1))
+	require.NoError(t, err)
+	assert.Equal(t, types.StatusTruncated, status)
+}
@@ -0,0 +1,94 @@
+package transport_tcp
+
+import (
+	"context"
+	"errors"
+	"github.com/panjf2000/gnet/v2"
+	"github.com/unpackdev/fdb/db"
+	"github.com/unpackdev/fdb/messages"
+	"github.com/unpackdev/fdb/types"
+	"log"
+)
+
+// TCPTreeHandler struct with the database router passed in
+type TCPTreeHandler struct {
+	router *db.Router // Resolves the database of each request
+}
+
+// NewTCPTreeHandler creates a new TCPTreeHandler with a database router
+func NewTCPTreeHandler(router *db.Router) *TCPTreeHandler {
+	return &TCPTreeHandler{
+		router: router,
+	}
+}
+
+// HandleMessage answers a Merkle tree request for the selected database, as described by
+// messages.TreeRequest.
+func (th *TCPTreeHandler) HandleMessage(c gnet.Conn, frame []byte) {
+	_, database, frame, err := messages.SplitHeader(frame)
+	if err != nil {
+		c.AsyncWrite(messages.EncodeStreamFrame(types.StatusError, []byte(err.Error())), nil)
+		return
+	}
+
+	req, err := messages.DecodeTreeRequest(frame)
+	if err != nil {
+		c.AsyncWrite(messages.EncodeStreamFrame(types.StatusError, []byte(err.Error())), nil)
+		return
+	}
+
+	provider, err := th.router.Resolve(types.DbType(database))
+	if err != nil {
+		c.AsyncWrite(messages.EncodeStreamFrame(types.StatusDatabaseNotFound, []byte(err.Error())), nil)
+		return
+	}
+
+	bDb, ok := provider.(*db.Db)
+	if !ok {
+		c.AsyncWrite(messages.EncodeStreamFrame(types.StatusError, []byte("database keeps no merkle tree")), nil)
+		return
+	}
+
+	// The server cancels the request when the connection closes
+	ctx, cancel := context.WithCancel(context.Background())
+	c.SetContext(cancel)
+
+	// Hashing and scanning the database blocks, so it must not run on the event loop
+	go func() {
+		defer cancel()
+
+		var err error
+		if req.Op == messages.TreeHashes {
+			var hashes []db.MerkleHash
+			if hashes, err = bDb.MerkleHashes(int(req.Level), req.Indices); err == nil {
+				body := make([]byte, 0, len(hashes)*len(db.MerkleHash{}))
+				for _, hash := range hashes {
+					body = append(body, hash[:]...)
+				}
+				err = writeAndWait(ctx, c, messages.EncodeStreamFrame(types.StatusOK, body))
+			}
+		} else {
+			// Frames are batched into chunks so a large range does not pay one round trip per key
+			var chunk []byte
+			err = bDb.MerkleRange(req.Indices, func(key, value []byte) error {
+				entry, err := messages.EncodeSnapshotEntry(key, value)
+				if err != nil {
+					return err
+				}
+				chunk = append(chunk, messages.EncodeStreamFrame(types.StatusOK, entry)...)
+				if len(chunk) >= snapshotChunkSize {
+					err = writeAndWait(ctx, c, chunk)
+					chunk = nil
+				}
+				return err
+			})
+			if err == nil {
+				err = writeAndWait(ctx, c, append(chunk, messages.EncodeStreamFrame(types.StatusOK, nil)...))
+			}
+		}
+		if err != nil && !errors.Is(err, context.Canceled) {
+			log.Printf("Tree request stopped: %v", err)
+			c.AsyncWrite(messages.EncodeStreamFrame(types.StatusError, []byte(err.Error())), nil)
+		}
+	}()
+}
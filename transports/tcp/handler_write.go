@@ -1,27 +1,36 @@
 package transport_tcp
 
 import (
+	"context"
 	"github.com/panjf2000/gnet/v2"
 	"github.com/unpackdev/fdb/db"
+	"github.com/unpackdev/fdb/messages"
+	"github.com/unpackdev/fdb/types"
 	"log"
 )
 
-// TCPWriteHandler struct with MDBX database passed in
+// TCPWriteHandler struct with the database router passed in
 type TCPWriteHandler struct {
-	db     db.Provider     // MDBX database instance
-	writer *db.BatchWriter // Batch writer instance
+	router *db.Router // Resolves the database and batch writer of each request
 }
 
-// NewTCPWriteHandler creates a new TCPWriteHandler with an MDBX database
-func NewTCPWriteHandler(db db.Provider, batchWriter *db.BatchWriter) *TCPWriteHandler {
+// NewTCPWriteHandler creates a new TCPWriteHandler with a database router
+func NewTCPWriteHandler(router *db.Router) *TCPWriteHandler {
 	return &TCPWriteHandler{
-		db:     db,
-		writer: batchWriter,
+		router: router,
 	}
 }
 
 // HandleMessage processes the incoming message using the TCPWriteHandler
 func (wh *TCPWriteHandler) HandleMessage(c gnet.Conn, frame []byte) {
+	// Strip the optional consistency header and database selector and resolve the batch writer of the selected database
+	consistency, database, frame, err := messages.SplitHeader(frame)
+	if err != nil {
+		log.Printf("Invalid database selector: %v", err)
+		c.AsyncWrite([]byte{byte(types.StatusError)}, nil)
+		return
+	}
+
 	// Check if the message is at least 34 bytes (1 byte for action, 32 bytes for key, and at least 1 byte for value)
 	if len(frame) < 34 {
 		log.Printf("Invalid message length: %d, expected at least 34 bytes", len(frame))
@@ -36,8 +45,31 @@ func (wh *TCPWriteHandler) HandleMessage(c gnet.Conn, frame []byte) {
 	// The remaining part is the value (from byte 33 onwards)
 	value := frame[33:]
 
-	// Buffer the write request with the key as [32]byte
-	wh.writer.BufferWrite(key, value)
+	// Databases replicated through consensus acknowledge once the write is committed, which
+	// must not block the event loop
+	if proposer, name := wh.router.Proposer(types.DbType(database)); proposer != nil {
+		value = append([]byte(nil), value...)
+		go func() {
+			if err := proposer.Propose(db.WithConsistency(context.Background(), consistency), name, types.ChangeSet, key[:], value); err != nil {
+				log.Printf("Error committing write: %v", err)
+				c.AsyncWrite([]byte{byte(db.WriteStatus(err))}, nil)
+				return
+			}
+			c.AsyncWrite([]byte{0x00}, nil)
+		}()
+		return
+	}
+
+	writer, err := wh.router.Writer(types.DbType(database))
+	if err != nil {
+		log.Printf("Error resolving database: %v", err)
+		c.AsyncWrite([]byte{byte(db.WriteStatus(err))}, nil)
+		return
+	}
+
+	// Buffer the write request with the key as [32]byte. The frame is reused by the event
+	// loop, so the buffered value must be a copy
+	writer.BufferWrite(key, append([]byte(nil), value...))
 
 	// Send success response
 	c.AsyncWrite([]byte{0x00}, nil) // Success code
@@ -3,11 +3,14 @@ package transport_tcp
 import (
 	"context"
 	"io"
+	"net"
+	"sync"
 	"time"
 
 	"github.com/panjf2000/gnet/v2"
 	"github.com/pkg/errors"
 	"github.com/unpackdev/fdb/config"
+	"github.com/unpackdev/fdb/messages"
 	"github.com/unpackdev/fdb/types"
 	"go.uber.org/zap"
 )
@@ -23,6 +26,13 @@ type Server struct {
 	stopChan        chan struct{}
 	started         chan struct{}
 	eng             gnet.Engine
+
+	// listener accepts the TLS connections, nil when serving plaintext. tlsMu guards it and the
+	// connections being served.
+	tlsMu    sync.Mutex
+	listener net.Listener
+	tlsConns map[*tlsConn]struct{}
+	tlsWg    sync.WaitGroup
 }
 
 // NewServer creates a new TCP Server instance using the provided configuration
@@ -43,10 +53,23 @@ func (s *Server) Addr() string {
 	return s.cnf.Addr()
 }
 
-// Start starts the TCP server using the provided configuration
+// Start starts the TCP server using the provided configuration. The server terminates TLS when
+// the TLS block of the configuration is enabled.
 func (s *Server) Start(ctx context.Context) error {
 	s.stopChan = make(chan struct{})
 	s.started = make(chan struct{}) // Initialize the started channel
+
+	tlsConfig, err := s.cnf.GetTLSConfig()
+	if err != nil {
+		return errors.Wrap(err, "failed to load TCP server TLS configuration")
+	}
+	if tlsConfig != nil {
+		if err := s.startTLS(tlsConfig); err != nil {
+			return errors.Wrap(err, "failed to start TCP server")
+		}
+		return nil
+	}
+
 	listenAddr := "tcp://" + s.cnf.Addr()
 	zap.L().Info("Starting TCP Server", zap.String("addr", listenAddr))
 
@@ -109,6 +132,11 @@ func (s *Server) OnOpen(c gnet.Conn) (out []byte, action gnet.Action) {
 
 // OnClose is called when a connection is closed
 func (s *Server) OnClose(c gnet.Conn, err error) (action gnet.Action) {
+	// Stop any subscription streaming to this connection
+	if cancel, ok := c.Context().(context.CancelFunc); ok {
+		cancel()
+	}
+
 	if err != nil && !errors.Is(err, io.EOF) {
 		zap.L().Error(
 			"Connection closed",
@@ -121,6 +149,11 @@ func (s *Server) OnClose(c gnet.Conn, err error) (action gnet.Action) {
 
 // OnTraffic handles incoming data
 func (s *Server) OnTraffic(c gnet.Conn) (action gnet.Action) {
+	// Tagged requests are length-delimited and may be pipelined
+	if head, err := c.Peek(1); err == nil && head[0] == messages.TagSelector {
+		return s.onTagged(c)
+	}
+
 	// Read all available data from the connection buffer
 	frame, err := c.Next(-1)
 	if err != nil {
@@ -128,17 +161,52 @@ func (s *Server) OnTraffic(c gnet.Conn) (action gnet.Action) {
 		return gnet.Close
 	}
 
+	s.dispatch(c, frame)
+	return gnet.None
+}
+
+// onTagged dispatches every complete tagged request buffered on the connection. Incomplete
+// requests stay buffered until the rest arrives.
+func (s *Server) onTagged(c gnet.Conn) gnet.Action {
+	for c.InboundBuffered() >= messages.TagHeaderLen {
+		header, err := c.Peek(messages.TagHeaderLen)
+		if err != nil {
+			zap.L().Error("Error reading data", zap.Error(err))
+			return gnet.Close
+		}
+
+		id, length, err := messages.DecodeTagHeader(header)
+		if err != nil {
+			zap.L().Warn("Invalid tagged request", zap.Error(err), zap.String("addr", c.RemoteAddr().String()))
+			return gnet.Close
+		}
+		if c.InboundBuffered() < messages.TagHeaderLen+length {
+			return gnet.None
+		}
+
+		data, err := c.Next(messages.TagHeaderLen + length)
+		if err != nil {
+			zap.L().Error("Error reading data", zap.Error(err))
+			return gnet.Close
+		}
+		s.dispatch(&taggedConn{Conn: c, id: id}, data[messages.TagHeaderLen:])
+	}
+	return gnet.None
+}
+
+// dispatch calls the handler of a request frame.
+func (s *Server) dispatch(c gnet.Conn, frame []byte) {
 	if len(frame) < 1 {
 		zap.L().Warn("Invalid action received", zap.String("addr", c.RemoteAddr().String()))
 		c.AsyncWrite([]byte("ERROR: Invalid action"), nil)
-		return gnet.None
+		return
 	}
 
 	// Parse the action type
 	actionType, err := s.parseActionType(frame)
 	if err != nil {
 		c.AsyncWrite([]byte("ERROR: Invalid action"), nil)
-		return gnet.None
+		return
 	}
 
 	// Check if the handler exists
@@ -146,12 +214,11 @@ func (s *Server) OnTraffic(c gnet.Conn) (action gnet.Action) {
 	if !exists {
 		zap.L().Warn("Unknown action type", zap.Int("action_type", int(actionType)), zap.String("addr", c.RemoteAddr().String()))
 		c.AsyncWrite([]byte("ERROR: Unknown action"), nil)
-		return gnet.None
+		return
 	}
 
 	// Call the handler
 	handler(c, frame)
-	return gnet.None
 }
 
 // OnTick is called periodically by gnet
@@ -168,7 +235,16 @@ func (s *Server) OnTick() (delay time.Duration, action gnet.Action) {
 func (s *Server) Stop() error {
 	zap.L().Info("Stopping TCP Server", zap.String("addr", s.cnf.Addr()))
 
-	err := s.eng.Stop(s.ctx)
+	s.tlsMu.Lock()
+	serveTLS := s.listener != nil
+	s.tlsMu.Unlock()
+
+	var err error
+	if serveTLS {
+		err = s.stopTLS()
+	} else {
+		err = s.eng.Stop(s.ctx)
+	}
 	if err != nil {
 		zap.L().Error("Error stopping TCP server", zap.Error(err))
 		return err
@@ -185,12 +261,18 @@ func (s *Server) WaitStarted() <-chan struct{} {
 
 // parseActionType parses the action type from the frame
 func (s *Server) parseActionType(frame []byte) (types.HandlerType, error) {
+	// The action byte follows the optional consistency header and database selector
+	_, _, frame, err := messages.SplitHeader(frame)
+	if err != nil {
+		return 0, err
+	}
+
 	if len(frame) < 1 {
 		return 0, errors.New("invalid action: frame too short")
 	}
 
 	var actionType types.HandlerType
-	err := actionType.FromByte(frame[0])
+	err = actionType.FromByte(frame[0])
 	if err != nil {
 		return 0, err
 	}
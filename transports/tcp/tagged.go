@@ -0,0 +1,38 @@
+package transport_tcp
+
+import (
+	"bytes"
+
+	"github.com/panjf2000/gnet/v2"
+	"github.com/unpackdev/fdb/messages"
+)
+
+// taggedConn is the connection handed to the handler of a tagged request. Every response the
+// handler writes is tagged with the ID of the request, see messages.TagSelector.
+type taggedConn struct {
+	gnet.Conn
+	id uint32
+}
+
+// Write tags and writes a response synchronously.
+func (c *taggedConn) Write(p []byte) (int, error) {
+	if _, err := c.Conn.Write(messages.TagFrame(c.id, p)); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Writev tags and writes a response made of several parts synchronously.
+func (c *taggedConn) Writev(bs [][]byte) (int, error) {
+	return c.Write(bytes.Join(bs, nil))
+}
+
+// AsyncWrite tags and writes a response asynchronously.
+func (c *taggedConn) AsyncWrite(p []byte, callback gnet.AsyncCallback) error {
+	return c.Conn.AsyncWrite(messages.TagFrame(c.id, p), callback)
+}
+
+// AsyncWritev tags and writes a response made of several parts asynchronously.
+func (c *taggedConn) AsyncWritev(bs [][]byte, callback gnet.AsyncCallback) error {
+	return c.AsyncWrite(bytes.Join(bs, nil), callback)
+}
@@ -0,0 +1,384 @@
+package transport_tcp
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/panjf2000/gnet/v2"
+	"go.uber.org/zap"
+)
+
+const (
+	// tlsHandshakeTimeout bounds the TLS handshake of a new connection.
+	tlsHandshakeTimeout = 10 * time.Second
+
+	// tlsReadBufferSize is the size of the reads from a TLS connection, a TLS record at most.
+	tlsReadBufferSize = 16 * 1024
+)
+
+// tlsConn must serve the handlers of plaintext connections
+var _ gnet.Conn = (*tlsConn)(nil)
+
+// errTLSConnUnsupported is returned by the gnet.Conn methods a TLS connection cannot provide.
+var errTLSConnUnsupported = errors.New("not supported by TLS connections")
+
+// TLSConfig returns the TLS configuration the server terminates TLS with, nil when it serves
+// plaintext.
+func (s *Server) TLSConfig() (*tls.Config, error) {
+	return s.cnf.GetTLSConfig()
+}
+
+// startTLS serves the handlers over TLS. gnet cannot terminate TLS, so TLS connections are
+// accepted by a crypto/tls listener and served by a goroutine each, through the same OnOpen,
+// OnTraffic and OnClose callbacks as the plaintext connections of the event loops.
+func (s *Server) startTLS(tlsConfig *tls.Config) error {
+	listener, err := tls.Listen("tcp", s.cnf.Addr(), tlsConfig)
+	if err != nil {
+		return err
+	}
+
+	s.tlsMu.Lock()
+	s.listener = listener
+	s.tlsConns = make(map[*tlsConn]struct{})
+	s.tlsMu.Unlock()
+
+	s.tlsWg.Add(1)
+	go s.acceptTLS(listener)
+
+	close(s.started)
+	zap.L().Info("TCP Server successfully started with TLS", zap.String("addr", s.cnf.Addr()))
+	return nil
+}
+
+// acceptTLS serves the connections accepted by listener until it is closed.
+func (s *Server) acceptTLS(listener net.Listener) {
+	defer s.tlsWg.Done()
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				zap.L().Error("Error accepting TLS connection", zap.Error(err))
+			}
+			return
+		}
+
+		c := &tlsConn{conn: conn.(*tls.Conn)}
+		s.tlsMu.Lock()
+		if s.listener == nil {
+			s.tlsMu.Unlock()
+			_ = conn.Close()
+			return
+		}
+		s.tlsConns[c] = struct{}{}
+		s.tlsMu.Unlock()
+
+		s.tlsWg.Add(1)
+		go s.serveTLS(c)
+	}
+}
+
+// serveTLS completes the handshake of a connection, then hands the data received to OnTraffic
+// until the connection is closed.
+func (s *Server) serveTLS(c *tlsConn) {
+	defer s.tlsWg.Done()
+	defer func() {
+		s.tlsMu.Lock()
+		delete(s.tlsConns, c)
+		s.tlsMu.Unlock()
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), tlsHandshakeTimeout)
+	err := c.conn.HandshakeContext(ctx)
+	cancel()
+	if err != nil {
+		zap.L().Warn("TLS handshake failed", zap.Error(err), zap.String("addr", c.RemoteAddr().String()))
+		_ = c.conn.Close()
+		return
+	}
+
+	if _, action := s.OnOpen(c); action == gnet.Close {
+		_ = c.Close()
+	}
+
+	buf := make([]byte, tlsReadBufferSize)
+	for {
+		n, err := c.conn.Read(buf)
+		if n > 0 {
+			c.inbound = append(c.inbound, buf[:n]...)
+			if s.OnTraffic(c) == gnet.Close {
+				err = c.Close()
+			}
+		}
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				err = nil
+			}
+			s.OnClose(c, err)
+			_ = c.conn.Close()
+			return
+		}
+	}
+}
+
+// stopTLS closes the listener and every TLS connection, waiting for them to be served.
+func (s *Server) stopTLS() error {
+	s.tlsMu.Lock()
+	listener := s.listener
+	s.listener = nil
+	conns := make([]*tlsConn, 0, len(s.tlsConns))
+	for c := range s.tlsConns {
+		conns = append(conns, c)
+	}
+	s.tlsMu.Unlock()
+
+	if listener == nil {
+		return nil
+	}
+	err := listener.Close()
+	for _, c := range conns {
+		_ = c.Close()
+	}
+	s.tlsWg.Wait()
+	return err
+}
+
+// tlsConn implements gnet.Conn over a TLS connection, so the handlers serve TLS and plaintext
+// connections alike. The inbound buffer is only used by the goroutine serving the connection;
+// writes are synchronous, AsyncWrite returning once the data was written.
+type tlsConn struct {
+	conn *tls.Conn
+
+	// inbound holds the data received and not consumed yet.
+	inbound []byte
+
+	// writeMu serializes the writes.
+	writeMu sync.Mutex
+
+	// ctxMu guards the user context.
+	ctxMu sync.Mutex
+	ctx   interface{}
+}
+
+// Read reads buffered data, implements io.Reader.
+func (c *tlsConn) Read(p []byte) (int, error) {
+	if len(c.inbound) == 0 {
+		return 0, io.EOF
+	}
+	n := copy(p, c.inbound)
+	c.inbound = c.inbound[n:]
+	return n, nil
+}
+
+// WriteTo writes the buffered data to w, implements io.WriterTo.
+func (c *tlsConn) WriteTo(w io.Writer) (int64, error) {
+	n, err := w.Write(c.inbound)
+	c.inbound = c.inbound[n:]
+	return int64(n), err
+}
+
+// Next returns the next n buffered bytes, or all of them when n is negative, and consumes them.
+func (c *tlsConn) Next(n int) ([]byte, error) {
+	buf, err := c.Peek(n)
+	if err != nil {
+		return nil, err
+	}
+	c.inbound = c.inbound[len(buf):]
+	return buf, nil
+}
+
+// Peek returns the next n buffered bytes, or all of them when n is negative, without consuming
+// them.
+func (c *tlsConn) Peek(n int) ([]byte, error) {
+	if n < 0 {
+		n = len(c.inbound)
+	}
+	if n > len(c.inbound) {
+		return nil, io.ErrShortBuffer
+	}
+	return c.inbound[:n], nil
+}
+
+// Discard skips the next n buffered bytes, or all of them when n is negative.
+func (c *tlsConn) Discard(n int) (int, error) {
+	if n < 0 || n > len(c.inbound) {
+		n = len(c.inbound)
+	}
+	c.inbound = c.inbound[n:]
+	return n, nil
+}
+
+// InboundBuffered returns the number of buffered bytes.
+func (c *tlsConn) InboundBuffered() int {
+	return len(c.inbound)
+}
+
+// Write writes p to the connection.
+func (c *tlsConn) Write(p []byte) (int, error) {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	return c.conn.Write(p)
+}
+
+// ReadFrom writes the data read from r to the connection, implements io.ReaderFrom.
+func (c *tlsConn) ReadFrom(r io.Reader) (int64, error) {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	return io.Copy(c.conn, r)
+}
+
+// Writev writes the parts of bs to the connection.
+func (c *tlsConn) Writev(bs [][]byte) (int, error) {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	buffers := net.Buffers(bs)
+	n, err := buffers.WriteTo(c.conn)
+	return int(n), err
+}
+
+// Flush does nothing, writes are not buffered.
+func (c *tlsConn) Flush() error {
+	return nil
+}
+
+// OutboundBuffered returns 0, writes are not buffered.
+func (c *tlsConn) OutboundBuffered() int {
+	return 0
+}
+
+// AsyncWrite writes buf to the connection, then calls callback.
+func (c *tlsConn) AsyncWrite(buf []byte, callback gnet.AsyncCallback) error {
+	_, err := c.Write(buf)
+	if callback != nil {
+		return callback(c, err)
+	}
+	return err
+}
+
+// AsyncWritev writes the parts of bs to the connection, then calls callback.
+func (c *tlsConn) AsyncWritev(bs [][]byte, callback gnet.AsyncCallback) error {
+	_, err := c.Writev(bs)
+	if callback != nil {
+		return callback(c, err)
+	}
+	return err
+}
+
+// Fd returns -1, the socket of a TLS connection is not exposed.
+func (c *tlsConn) Fd() int {
+	return -1
+}
+
+// Dup is not supported by TLS connections.
+func (c *tlsConn) Dup() (int, error) {
+	return -1, errTLSConnUnsupported
+}
+
+// SetReadBuffer sets the size of the receive buffer of the socket.
+func (c *tlsConn) SetReadBuffer(bytes int) error {
+	if tcp, ok := c.conn.NetConn().(*net.TCPConn); ok {
+		return tcp.SetReadBuffer(bytes)
+	}
+	return errTLSConnUnsupported
+}
+
+// SetWriteBuffer sets the size of the send buffer of the socket.
+func (c *tlsConn) SetWriteBuffer(bytes int) error {
+	if tcp, ok := c.conn.NetConn().(*net.TCPConn); ok {
+		return tcp.SetWriteBuffer(bytes)
+	}
+	return errTLSConnUnsupported
+}
+
+// SetLinger sets the behavior of Close on a connection with unsent data.
+func (c *tlsConn) SetLinger(sec int) error {
+	if tcp, ok := c.conn.NetConn().(*net.TCPConn); ok {
+		return tcp.SetLinger(sec)
+	}
+	return errTLSConnUnsupported
+}
+
+// SetKeepAlivePeriod enables the keep-alive probes of the socket with the given period.
+func (c *tlsConn) SetKeepAlivePeriod(d time.Duration) error {
+	if tcp, ok := c.conn.NetConn().(*net.TCPConn); ok {
+		if err := tcp.SetKeepAlive(true); err != nil {
+			return err
+		}
+		return tcp.SetKeepAlivePeriod(d)
+	}
+	return errTLSConnUnsupported
+}
+
+// SetNoDelay controls Nagle's algorithm on the socket.
+func (c *tlsConn) SetNoDelay(noDelay bool) error {
+	if tcp, ok := c.conn.NetConn().(*net.TCPConn); ok {
+		return tcp.SetNoDelay(noDelay)
+	}
+	return errTLSConnUnsupported
+}
+
+// Context returns the user context of the connection.
+func (c *tlsConn) Context() interface{} {
+	c.ctxMu.Lock()
+	defer c.ctxMu.Unlock()
+	return c.ctx
+}
+
+// SetContext sets the user context of the connection.
+func (c *tlsConn) SetContext(ctx interface{}) {
+	c.ctxMu.Lock()
+	defer c.ctxMu.Unlock()
+	c.ctx = ctx
+}
+
+// LocalAddr returns the local address of the connection.
+func (c *tlsConn) LocalAddr() net.Addr {
+	return c.conn.LocalAddr()
+}
+
+// RemoteAddr returns the remote address of the connection.
+func (c *tlsConn) RemoteAddr() net.Addr {
+	return c.conn.RemoteAddr()
+}
+
+// Wake is not supported by TLS connections, which are served by a goroutine of their own.
+func (c *tlsConn) Wake(gnet.AsyncCallback) error {
+	return errTLSConnUnsupported
+}
+
+// CloseWithCallback closes the connection, then calls callback.
+func (c *tlsConn) CloseWithCallback(callback gnet.AsyncCallback) error {
+	err := c.Close()
+	if callback != nil {
+		return callback(c, err)
+	}
+	return err
+}
+
+// Close sends a close_notify alert and closes the connection, so the peer can tell a closed
+// connection from a truncated one. Sending the alert is bounded by crypto/tls, and skipped while
+// a write is in flight. The goroutine serving the connection calls OnClose.
+func (c *tlsConn) Close() error {
+	return c.conn.Close()
+}
+
+// SetDeadline sets the read and write deadlines of the connection.
+func (c *tlsConn) SetDeadline(t time.Time) error {
+	return c.conn.SetDeadline(t)
+}
+
+// SetReadDeadline sets the read deadline of the connection.
+func (c *tlsConn) SetReadDeadline(t time.Time) error {
+	return c.conn.SetReadDeadline(t)
+}
+
+// SetWriteDeadline sets the write deadline of the connection.
+func (c *tlsConn) SetWriteDeadline(t time.Time) error {
+	return c.conn.SetWriteDeadline(t)
+}
@@ -0,0 +1,75 @@
+package transport_tcp_test
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"os"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/unpackdev/fdb/config"
+	"github.com/unpackdev/fdb/fdbtest"
+	transport_tcp "github.com/unpackdev/fdb/transports/tcp"
+)
+
+// recordTypeAlert is the content type of TLS 1.2 records carrying an alert.
+const recordTypeAlert = 21
+
+func TestStopClosesTLSConnections(t *testing.T) {
+	certs := fdbtest.WriteCerts(t, t.TempDir())
+	ca, err := os.ReadFile(certs.CAFile)
+	require.NoError(t, err)
+	roots := x509.NewCertPool()
+	require.True(t, roots.AppendCertsFromPEM(ca))
+
+	goroutines := runtime.NumGoroutine()
+	server, err := transport_tcp.NewServer(context.Background(), config.TcpTransport{
+		IPv4: "127.0.0.1",
+		Port: fdbtest.FreePort(t, "tcp"),
+		TLS:  &config.TLS{Enabled: true, Cert: certs.CertFile, Key: certs.KeyFile},
+	})
+	require.NoError(t, err)
+	require.NoError(t, server.Start(context.Background()))
+
+	// Idle connections, each served by a goroutine blocked reading. TLS 1.2 keeps the record
+	// types of alerts in the clear.
+	raws := make([]net.Conn, 3)
+	for i := range raws {
+		raw, err := net.Dial("tcp", server.Addr())
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = raw.Close() })
+		conn := tls.Client(raw, &tls.Config{RootCAs: roots, ServerName: "127.0.0.1", MaxVersion: tls.VersionTLS12})
+		require.NoError(t, conn.Handshake())
+		raws[i] = raw
+	}
+
+	stopped := make(chan error, 1)
+	go func() { stopped <- server.Stop() }()
+	select {
+	case err := <-stopped:
+		require.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("Stop did not return with open TLS connections")
+	}
+
+	// Every connection was closed with a close_notify alert rather than a bare FIN
+	for _, raw := range raws {
+		require.NoError(t, raw.SetReadDeadline(time.Now().Add(time.Second)))
+		header := make([]byte, 1)
+		_, err := raw.Read(header)
+		require.NoError(t, err)
+		assert.Equal(t, byte(recordTypeAlert), header[0])
+	}
+
+	// Polled without assert.Eventually, which runs the condition in a goroutine of its own
+	deadline := time.Now().Add(5 * time.Second)
+	for runtime.NumGoroutine() > goroutines && time.Now().Before(deadline) {
+		time.Sleep(20 * time.Millisecond)
+	}
+	assert.LessOrEqual(t, runtime.NumGoroutine(), goroutines, "goroutines serving the connections leaked")
+}
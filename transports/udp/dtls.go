@@ -0,0 +1,266 @@
+package transport_udp
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/panjf2000/gnet"
+	"github.com/pion/dtls/v3"
+	"go.uber.org/zap"
+)
+
+const (
+	// dtlsHandshakeTimeout bounds the DTLS handshake of a new peer.
+	dtlsHandshakeTimeout = 10 * time.Second
+
+	// dtlsMaxRecordSize bounds a single record received from a peer, a datagram at most.
+	dtlsMaxRecordSize = 64 * 1024
+)
+
+// dtlsConn must serve the handlers of plaintext datagrams
+var _ gnet.Conn = (*dtlsConn)(nil)
+
+// errDTLSConnUnsupported is returned by the gnet.Conn methods a DTLS session cannot provide.
+var errDTLSConnUnsupported = errors.New("not supported by DTLS sessions")
+
+// DTLSConfig returns the DTLS configuration the server serves DTLS with, nil when it serves
+// plaintext datagrams.
+func (s *Server) DTLSConfig() (*dtls.Config, error) {
+	return s.cnf.GetDTLSConfig()
+}
+
+// startDTLS serves the handlers over DTLS. gnet cannot terminate DTLS, so the datagrams are
+// received by a pion/dtls listener that keeps a session per peer, each served by a goroutine
+// through the same React callback as the plaintext datagrams of the event loops.
+func (s *Server) startDTLS(dtlsConfig *dtls.Config) error {
+	addr, err := net.ResolveUDPAddr("udp", s.cnf.Addr())
+	if err != nil {
+		return err
+	}
+	listener, err := dtls.Listen("udp", addr, dtlsConfig)
+	if err != nil {
+		return err
+	}
+
+	s.dtlsMu.Lock()
+	s.listener = listener
+	s.sessions = make(map[*dtlsConn]struct{})
+	s.dtlsMu.Unlock()
+
+	s.dtlsWg.Add(1)
+	go s.acceptDTLS(listener)
+
+	close(s.started)
+	zap.L().Info("UDP Server successfully started with DTLS", zap.String("addr", s.cnf.Addr()))
+	return nil
+}
+
+// acceptDTLS serves the sessions of the peers accepted by listener until it is closed.
+func (s *Server) acceptDTLS(listener net.Listener) {
+	defer s.dtlsWg.Done()
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			// The listener is unset before being closed by stopDTLS
+			s.dtlsMu.Lock()
+			stopped := s.listener == nil
+			s.dtlsMu.Unlock()
+			if !stopped {
+				zap.L().Error("Error accepting DTLS session", zap.Error(err))
+			}
+			return
+		}
+
+		c := &dtlsConn{conn: conn.(*dtls.Conn)}
+		s.dtlsMu.Lock()
+		if s.listener == nil {
+			s.dtlsMu.Unlock()
+			_ = conn.Close()
+			return
+		}
+		s.sessions[c] = struct{}{}
+		s.dtlsMu.Unlock()
+
+		s.dtlsWg.Add(1)
+		go s.serveDTLS(c)
+	}
+}
+
+// serveDTLS completes the handshake of a peer, then answers its requests until the session is
+// closed by the peer, or by the server once the peer is idle for the session timeout.
+func (s *Server) serveDTLS(c *dtlsConn) {
+	defer s.dtlsWg.Done()
+	defer func() {
+		s.dtlsMu.Lock()
+		delete(s.sessions, c)
+		s.dtlsMu.Unlock()
+		_ = c.Close()
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), dtlsHandshakeTimeout)
+	err := c.conn.HandshakeContext(ctx)
+	cancel()
+	if err != nil {
+		zap.L().Warn("DTLS handshake failed", zap.Error(err), zap.String("addr", c.RemoteAddr().String()))
+		return
+	}
+
+	timeout := s.cnf.DTLS.GetSessionTimeout()
+	buf := make([]byte, dtlsMaxRecordSize)
+	for {
+		if err := c.conn.SetReadDeadline(time.Now().Add(timeout)); err != nil {
+			return
+		}
+		n, err := c.conn.Read(buf)
+		if err != nil {
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				zap.L().Debug("DTLS session expired", zap.String("addr", c.RemoteAddr().String()))
+			}
+			return
+		}
+
+		// Each record carries a whole request, as each datagram does
+		c.frame = buf[:n]
+		out, action := s.React(c.frame, c)
+		if out != nil {
+			if err := c.SendTo(out); err != nil {
+				return
+			}
+		}
+		if action == gnet.Close || action == gnet.Shutdown {
+			return
+		}
+	}
+}
+
+// stopDTLS closes the listener and every DTLS session, waiting for them to be served.
+func (s *Server) stopDTLS() error {
+	s.dtlsMu.Lock()
+	listener := s.listener
+	s.listener = nil
+	sessions := make([]*dtlsConn, 0, len(s.sessions))
+	for c := range s.sessions {
+		sessions = append(sessions, c)
+	}
+	s.dtlsMu.Unlock()
+
+	if listener == nil {
+		return nil
+	}
+	err := listener.Close()
+	for _, c := range sessions {
+		_ = c.Close()
+	}
+	s.dtlsWg.Wait()
+	return err
+}
+
+// dtlsConn implements gnet.Conn over the DTLS session of a peer, so the handlers serve DTLS and
+// plaintext datagrams alike. The frame is only used by the goroutine serving the session;
+// writes are synchronous, AsyncWrite returning once the record was sent.
+type dtlsConn struct {
+	conn *dtls.Conn
+
+	// frame is the request being served.
+	frame []byte
+
+	// writeMu serializes the writes.
+	writeMu sync.Mutex
+
+	// ctxMu guards the user context.
+	ctxMu sync.Mutex
+	ctx   interface{}
+}
+
+// Context returns the user context of the session.
+func (c *dtlsConn) Context() interface{} {
+	c.ctxMu.Lock()
+	defer c.ctxMu.Unlock()
+	return c.ctx
+}
+
+// SetContext sets the user context of the session.
+func (c *dtlsConn) SetContext(ctx interface{}) {
+	c.ctxMu.Lock()
+	defer c.ctxMu.Unlock()
+	c.ctx = ctx
+}
+
+// LocalAddr returns the local address of the session.
+func (c *dtlsConn) LocalAddr() net.Addr {
+	return c.conn.LocalAddr()
+}
+
+// RemoteAddr returns the address of the peer.
+func (c *dtlsConn) RemoteAddr() net.Addr {
+	return c.conn.RemoteAddr()
+}
+
+// Read returns the request being served.
+func (c *dtlsConn) Read() []byte {
+	return c.frame
+}
+
+// ResetBuffer discards the request being served.
+func (c *dtlsConn) ResetBuffer() {
+	c.frame = nil
+}
+
+// ReadN returns the next n bytes of the request being served, without consuming them.
+func (c *dtlsConn) ReadN(n int) (int, []byte) {
+	if n > len(c.frame) {
+		n = len(c.frame)
+	}
+	return n, c.frame[:n]
+}
+
+// ShiftN consumes the next n bytes of the request being served.
+func (c *dtlsConn) ShiftN(n int) int {
+	if n > len(c.frame) {
+		n = len(c.frame)
+	}
+	c.frame = c.frame[n:]
+	return n
+}
+
+// BufferLength returns the number of bytes of the request being served.
+func (c *dtlsConn) BufferLength() int {
+	return len(c.frame)
+}
+
+// SendTo sends buf to the peer in a record of its own.
+func (c *dtlsConn) SendTo(buf []byte) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	_, err := c.conn.Write(buf)
+	return err
+}
+
+// AsyncWrite sends buf to the peer.
+func (c *dtlsConn) AsyncWrite(buf []byte) error {
+	return c.SendTo(buf)
+}
+
+// AsyncWritev sends the parts of bs to the peer in a single record.
+func (c *dtlsConn) AsyncWritev(bs [][]byte) error {
+	var buf []byte
+	for _, b := range bs {
+		buf = append(buf, b...)
+	}
+	return c.SendTo(buf)
+}
+
+// Wake is not supported by DTLS sessions, which are served by a goroutine of their own.
+func (c *dtlsConn) Wake() error {
+	return errDTLSConnUnsupported
+}
+
+// Close closes the session, notifying the peer.
+func (c *dtlsConn) Close() error {
+	return c.conn.Close()
+}
@@ -0,0 +1,23 @@
+package transport_udp
+
+import (
+	"github.com/panjf2000/gnet"
+	"github.com/unpackdev/fdb/db"
+)
+
+// UDPAdminHandler struct with the database manager passed in
+type UDPAdminHandler struct {
+	manager *db.Manager // Database manager executing the admin operations
+}
+
+// NewUDPAdminHandler creates a new UDPAdminHandler with a database manager
+func NewUDPAdminHandler(manager *db.Manager) *UDPAdminHandler {
+	return &UDPAdminHandler{
+		manager: manager,
+	}
+}
+
+// HandleMessage executes the admin operation and sends back the status-prefixed response
+func (ah *UDPAdminHandler) HandleMessage(c gnet.Conn, frame []byte) {
+	c.SendTo(ah.manager.ExecuteAdminFrame(frame))
+}
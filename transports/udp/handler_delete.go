@@ -0,0 +1,67 @@
+package transport_udp
+
+import (
+	"context"
+	"github.com/panjf2000/gnet"
+	"github.com/unpackdev/fdb/db"
+	"github.com/unpackdev/fdb/messages"
+	"github.com/unpackdev/fdb/types"
+	"log"
+)
+
+// UDPDeleteHandler struct with the database router passed in
+type UDPDeleteHandler struct {
+	router *db.Router // Resolves the database and batch writer of each request
+}
+
+// NewUDPDeleteHandler creates a new UDPDeleteHandler with a database router
+func NewUDPDeleteHandler(router *db.Router) *UDPDeleteHandler {
+	return &UDPDeleteHandler{
+		router: router,
+	}
+}
+
+// HandleMessage processes a delete request: action (1 byte) | key (32 bytes)
+func (dh *UDPDeleteHandler) HandleMessage(c gnet.Conn, frame []byte) {
+	// Strip the optional consistency header and database selector
+	consistency, database, frame, err := messages.SplitHeader(frame)
+	if err != nil {
+		log.Printf("Invalid database selector: %v", err)
+		c.SendTo([]byte{byte(types.StatusError)})
+		return
+	}
+
+	if len(frame) < 33 { // 1 byte action + 32-byte key
+		log.Printf("Invalid message length: %d, expected at least 33 bytes", len(frame))
+		c.SendTo([]byte{byte(types.StatusError)})
+		return
+	}
+
+	var key [32]byte
+	copy(key[:], frame[1:33])
+
+	// Databases replicated through consensus acknowledge once the delete is committed, which
+	// must not block the event loop
+	if proposer, name := dh.router.Proposer(types.DbType(database)); proposer != nil {
+		go func() {
+			if err := proposer.Propose(db.WithConsistency(context.Background(), consistency), name, types.ChangeDelete, key[:], nil); err != nil {
+				log.Printf("Error committing delete: %v", err)
+				c.SendTo([]byte{byte(db.WriteStatus(err))})
+				return
+			}
+			c.SendTo([]byte{byte(types.StatusOK)})
+		}()
+		return
+	}
+
+	writer, err := dh.router.Writer(types.DbType(database))
+	if err != nil {
+		log.Printf("Error resolving database: %v", err)
+		c.SendTo([]byte{byte(db.WriteStatus(err))})
+		return
+	}
+
+	// Deletes are buffered with the writes, so they apply in the order they were received
+	writer.BufferDelete(key)
+	c.SendTo([]byte{byte(types.StatusOK)})
+}
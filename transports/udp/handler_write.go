@@ -1,27 +1,36 @@
 package transport_udp
 
 import (
+	"context"
 	"github.com/panjf2000/gnet"
 	"github.com/unpackdev/fdb/db"
+	"github.com/unpackdev/fdb/messages"
+	"github.com/unpackdev/fdb/types"
 	"log"
 )
 
-// UDPWriteHandler struct with MDBX database passed in
+// UDPWriteHandler struct with the database router passed in
 type UDPWriteHandler struct {
-	db     db.Provider     // MDBX database instance
-	writer *db.BatchWriter // Batch writer instance
+	router *db.Router // Resolves the database and batch writer of each request
 }
 
-// NewUDPWriteHandler creates a new UDPWriteHandler with an MDBX database
-func NewUDPWriteHandler(db db.Provider, batchWriter *db.BatchWriter) *UDPWriteHandler {
+// NewUDPWriteHandler creates a new UDPWriteHandler with a database router
+func NewUDPWriteHandler(router *db.Router) *UDPWriteHandler {
 	return &UDPWriteHandler{
-		db:     db,
-		writer: batchWriter,
+		router: router,
 	}
 }
 
 // HandleMessage processes the incoming message using the UDPWriteHandler
 func (wh *UDPWriteHandler) HandleMessage(c gnet.Conn, frame []byte) {
+	// Strip the optional consistency header and database selector and resolve the batch writer of the selected database
+	consistency, database, frame, err := messages.SplitHeader(frame)
+	if err != nil {
+		log.Printf("Invalid database selector: %v", err)
+		c.SendTo([]byte{byte(types.StatusError)})
+		return
+	}
+
 	// Check if the message is at least 34 bytes (1 byte for action, 32 bytes for key, and at least 1 byte for value)
 	if len(frame) < 34 {
 		log.Printf("Invalid message length: %d, expected at least 34 bytes", len(frame))
@@ -36,8 +45,31 @@ func (wh *UDPWriteHandler) HandleMessage(c gnet.Conn, frame []byte) {
 	// The remaining part is the value (from byte 33 onwards)
 	value := frame[33:]
 
-	// Buffer the write request with the key as [32]byte
-	wh.writer.BufferWrite(key, value)
+	// Databases replicated through consensus acknowledge once the write is committed, which
+	// must not block the event loop. The frame is reused by the event loop, so the value is copied
+	if proposer, name := wh.router.Proposer(types.DbType(database)); proposer != nil {
+		value = append([]byte(nil), value...)
+		go func() {
+			if err := proposer.Propose(db.WithConsistency(context.Background(), consistency), name, types.ChangeSet, key[:], value); err != nil {
+				log.Printf("Error committing write: %v", err)
+				c.SendTo([]byte{byte(db.WriteStatus(err))})
+				return
+			}
+			c.SendTo([]byte{0x00})
+		}()
+		return
+	}
+
+	writer, err := wh.router.Writer(types.DbType(database))
+	if err != nil {
+		log.Printf("Error resolving database: %v", err)
+		c.SendTo([]byte{byte(db.WriteStatus(err))})
+		return
+	}
+
+	// Buffer the write request with the key as [32]byte. The frame is reused by the event
+	// loop, so the buffered value must be a copy
+	writer.BufferWrite(key, append([]byte(nil), value...))
 
 	// Send success response
 	c.SendTo([]byte{0x00})
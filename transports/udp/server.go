@@ -5,8 +5,11 @@ import (
 	"github.com/panjf2000/gnet"
 	"github.com/pkg/errors"
 	"github.com/unpackdev/fdb/config"
+	"github.com/unpackdev/fdb/messages"
 	"github.com/unpackdev/fdb/types"
 	"go.uber.org/zap"
+	"net"
+	"sync"
 	"time"
 )
 
@@ -21,6 +24,18 @@ type Server struct {
 	cnf             config.UdpTransport
 	stopChan        chan struct{}
 	started         chan struct{}
+
+	// mu guards stopped and stopping, Start and Stop may be called from different goroutines.
+	mu       sync.Mutex
+	stopped  chan struct{} // Closed once the event loops returned, nil until they are started
+	stopping bool
+
+	// listener accepts the DTLS sessions, nil when serving plaintext. dtlsMu guards it and the
+	// sessions being served.
+	dtlsMu   sync.Mutex
+	listener net.Listener
+	sessions map[*dtlsConn]struct{}
+	dtlsWg   sync.WaitGroup
 }
 
 // NewServer creates a new UDP Server instance using the provided configuration
@@ -41,18 +56,33 @@ func (s *Server) Addr() string {
 	return s.cnf.Addr()
 }
 
-// Start starts the UDP server using the provided configuration
+// Start starts the UDP server using the provided configuration. The server serves DTLS when the
+// DTLS block of the configuration is enabled.
 func (s *Server) Start(ctx context.Context) error {
-	s.stopChan = make(chan struct{})
-	s.started = make(chan struct{}) // Initialize the started channel
+	dtlsConfig, err := s.cnf.GetDTLSConfig()
+	if err != nil {
+		return errors.Wrap(err, "failed to load UDP server DTLS configuration")
+	}
+	if dtlsConfig != nil {
+		if err := s.startDTLS(dtlsConfig); err != nil {
+			return errors.Wrap(err, "failed to start UDP server")
+		}
+		return nil
+	}
+
 	listenAddr := "udp://" + s.cnf.Addr()
 	zap.L().Info("Starting UDP Server", zap.String("addr", listenAddr))
 
 	// Create an error channel to capture errors from the goroutine
 	errChan := make(chan error, 1)
+	stopped := make(chan struct{})
+	s.mu.Lock()
+	s.stopped = stopped
+	s.mu.Unlock()
 
 	// Start the server asynchronously
 	go func() {
+		defer close(stopped)
 		err := gnet.Serve(
 			s, listenAddr,
 			gnet.WithMulticore(true),
@@ -94,10 +124,36 @@ func (s *Server) Tick() (delay time.Duration, action gnet.Action) {
 	}
 }
 
-// Stop stops the UDP server
+// Stop stops the UDP server. Stopping a server that was never started does nothing.
 func (s *Server) Stop() error {
 	zap.L().Info("Stopping UDP Server", zap.String("addr", s.cnf.Addr()))
-	close(s.stopChan)
+
+	s.dtlsMu.Lock()
+	serving := s.listener != nil
+	s.dtlsMu.Unlock()
+	if serving {
+		if err := s.stopDTLS(); err != nil {
+			return errors.Wrap(err, "failed to stop UDP server")
+		}
+	} else {
+		s.mu.Lock()
+		stopped, stopping := s.stopped, s.stopping
+		s.stopping = true
+		s.mu.Unlock()
+		if stopped == nil {
+			return nil
+		}
+		if !stopping {
+			close(s.stopChan)
+		}
+
+		// The event loops shut down on their next tick, the socket is released once they return
+		select {
+		case <-stopped:
+		case <-time.After(5 * time.Second):
+			return errors.New("UDP server did not stop in time")
+		}
+	}
 
 	zap.L().Info("UDP Server stopped successfully", zap.String("addr", s.cnf.Addr()))
 	return nil
@@ -117,38 +173,63 @@ func (s *Server) OnInitComplete(server gnet.Server) (action gnet.Action) {
 
 // React handles incoming data
 func (s *Server) React(frame []byte, c gnet.Conn) (out []byte, action gnet.Action) {
+	// Tagged requests are answered with responses tagged with the same ID, see
+	// messages.TagSelector. Each datagram carries a whole request.
+	if len(frame) > 0 && frame[0] == messages.TagSelector {
+		id, request, n, err := messages.SplitTagged(frame)
+		if err != nil || n != len(frame) {
+			zap.L().Warn("Invalid tagged request", zap.Error(err), zap.String("addr", c.RemoteAddr().String()))
+			return nil, gnet.None
+		}
+		if out := s.dispatch(&taggedConn{Conn: c, id: id}, request); out != nil {
+			return messages.TagFrame(id, out), gnet.None
+		}
+		return nil, gnet.None
+	}
+
+	return s.dispatch(c, frame), gnet.None
+}
+
+// dispatch calls the handler of a request frame, returning the error response if it cannot.
+func (s *Server) dispatch(c gnet.Conn, frame []byte) []byte {
 	if len(frame) < 1 {
 		zap.L().Warn("Invalid action received", zap.String("addr", c.RemoteAddr().String()))
-		return []byte("ERROR: Invalid action"), gnet.None
+		return []byte("ERROR: Invalid action")
 	}
 
 	// Parse the action type
 	actionType, err := s.parseActionType(frame)
 	if err != nil {
 		//zap.L().Warn("Failed to parse action type", zap.Error(err), zap.String("addr", c.RemoteAddr().String()))
-		return []byte("ERROR: Invalid action"), gnet.None
+		return []byte("ERROR: Invalid action")
 	}
 
 	// Check if the handler exists
 	handler, exists := s.handlerRegistry[actionType]
 	if !exists {
 		zap.L().Warn("Unknown action type", zap.Int("action_type", int(actionType)), zap.String("addr", c.RemoteAddr().String()))
-		return []byte("ERROR: Unknown action"), gnet.None
+		return []byte("ERROR: Unknown action")
 	}
 
 	// Call the handler
 	handler(c, frame)
-	return nil, gnet.None
+	return nil
 }
 
 // parseActionType parses the action type from the frame
 func (s *Server) parseActionType(frame []byte) (types.HandlerType, error) {
+	// The action byte follows the optional consistency header and database selector
+	_, _, frame, err := messages.SplitHeader(frame)
+	if err != nil {
+		return 0, err
+	}
+
 	if len(frame) < 1 {
 		return 0, errors.New("invalid action: frame too short")
 	}
 
 	var actionType types.HandlerType
-	err := actionType.FromByte(frame[0])
+	err = actionType.FromByte(frame[0])
 	if err != nil {
 		return 0, err
 	}
@@ -1,246 +1,161 @@
-package transport_udp
+package transport_udp_test
 
 import (
 	"context"
-	"crypto/rand"
-	"github.com/stretchr/testify/assert"
-	"github.com/unpackdev/fdb/db"
-	"log"
-	"math/big"
 	"net"
 	"testing"
 	"time"
-)
 
-// Generate a random port in the range 1024 to 65535
-func getRandomPort() (int, error) {
-	// The valid port range is 1024–65535
-	minPort := 1024
-	maxPort := 65535
-	portRange := big.NewInt(int64(maxPort - minPort + 1))
-
-	// Generate a random number in the port range
-	randPort, err := rand.Int(rand.Reader, portRange)
-	if err != nil {
-		return 0, err
-	}
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/unpackdev/fdb/config"
+	"github.com/unpackdev/fdb/fdbtest"
+	"github.com/unpackdev/fdb/messages"
+	transport_udp "github.com/unpackdev/fdb/transports/udp"
+	"github.com/unpackdev/fdb/types"
+)
 
-	// Return the port number within the valid range
-	return minPort + int(randPort.Int64()), nil
+// request sends frame in a datagram of its own and returns the datagram answering it.
+func request(t *testing.T, conn net.Conn, frame []byte) []byte {
+	t.Helper()
+
+	_, err := conn.Write(frame)
+	require.NoError(t, err)
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(time.Second)))
+	buf := make([]byte, 64*1024)
+	n, err := conn.Read(buf)
+	require.NoError(t, err)
+	return buf[:n]
 }
 
-// Start UDP server function with handlers for write and read
-func startUDPServer(ctx context.Context, db db.Provider) (*UdpServer, error) {
-	port, err := getRandomPort()
-	if err != nil {
-		return nil, err
-	}
-	log.Printf("Starting UDP server on port: %d", port)
-
-	server, err := NewUdpServer("127.0.0.1", port)
-	if err != nil {
-		return nil, err
-	}
-
-	wHandler := NewWriteHandler(db)
-	server.RegisterHandler(WriteHandlerType, wHandler.HandleMessage)
-
-	rHandler := NewReadHandler(db)
-	server.RegisterHandler(ReadHandlerType, rHandler.HandleMessage)
-
-	go func() {
-		log.Println("Starting gnet server...")
-		err := server.Start()
-		if err != nil {
-			log.Fatalf("Failed to start server: %v", err)
-		}
-		log.Println("gnet server started.")
-	}()
-
-	log.Println("Awaiting for started closure...")
-	// Wait for the server to signal that it has started
-	//<-server.WaitStarted()
-
-	log.Println("Started closure detected for port:", port)
-
-	return server, nil
+func TestServerAnswersDatagrams(t *testing.T) {
+	server := fdbtest.Start(t, fdbtest.WithTransports(types.UDPTransportType))
+	conn, err := net.Dial("udp", server.Addr(types.UDPTransportType))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+
+	var key, missing [32]byte
+	copy(key[:], "key")
+	copy(missing[:], "missing")
+	read := append([]byte{byte(types.ReadHandlerType)}, key[:]...)
+
+	// A write is acknowledged once buffered and visible once flushed
+	write := append(append([]byte{byte(types.WriteHandlerType)}, key[:]...), "value"...)
+	assert.Equal(t, []byte{byte(types.StatusOK)}, request(t, conn, write))
+	require.Eventually(t, func() bool {
+		return string(request(t, conn, read)) == "value"
+	}, 5*time.Second, 20*time.Millisecond)
+
+	// Tagged reads are answered with a status, untagged ones with the text of the error
+	assert.Equal(t, []byte("No value found for key"), request(t, conn, append([]byte{byte(types.ReadHandlerType)}, missing[:]...)))
+	tagged := request(t, conn, messages.TagFrame(7, read))
+	id, response, n, err := messages.SplitTagged(tagged)
+	require.NoError(t, err)
+	assert.Equal(t, len(tagged), n)
+	assert.Equal(t, uint32(7), id)
+	assert.Equal(t, messages.EncodeReadResponse(types.StatusOK, []byte("value")), response)
+
+	// Frames without a known handler are refused
+	assert.Equal(t, []byte("ERROR: Invalid action"), request(t, conn, []byte{'?'}))
 }
 
-// TestUDPServer tests the gnet-based UDP server with various scenarios
-func TestUDPServer(t *testing.T) {
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
+func TestServerStop(t *testing.T) {
+	// A server that was never started has nothing to stop
+	idle, err := transport_udp.NewServer(context.Background(), config.UdpTransport{Enabled: true, IPv4: "127.0.0.1", Port: 1})
+	require.NoError(t, err)
+	assert.NoError(t, idle.Stop())
+
+	// A started server releases its port, and can be stopped twice
+	port := fdbtest.FreePort(t, "udp")
+	server, err := transport_udp.NewServer(context.Background(), config.UdpTransport{Enabled: true, IPv4: "127.0.0.1", Port: port})
+	require.NoError(t, err)
+	require.NoError(t, server.Start(context.Background()))
+	require.NoError(t, server.Stop())
+	assert.NoError(t, server.Stop())
+}
 
-	// Setup the MDBX manager and database
-	manager := setupTestManager(t)
+// pendingProposer holds every proposal until release is closed.
+type pendingProposer struct {
+	release chan struct{}
+}
 
-	// Get the database from the manager
-	db, err := manager.GetDb("test")
-	assert.NoError(t, err)
-	defer db.Destroy()
+func (p *pendingProposer) Propose(ctx context.Context, _ types.DbType, _ types.ChangeOp, _, _ []byte) error {
+	select {
+	case <-p.release:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
 
-	server, sErr := startUDPServer(ctx, db)
-	assert.NoError(t, sErr)
+func TestServerAnswersWhileProposing(t *testing.T) {
+	server := fdbtest.Start(t, fdbtest.WithTransports(types.UDPTransportType))
+	proposer := &pendingProposer{release: make(chan struct{})}
+	require.NoError(t, server.FDB.GetDbManager().SetProposer(fdbtest.DefaultDatabase, proposer))
+
+	conn, err := net.Dial("udp", server.Addr(types.UDPTransportType))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+
+	var key [32]byte
+	copy(key[:], "key")
+
+	// The event loop keeps answering while a write waits to be committed
+	_, err = conn.Write(append(append([]byte{byte(types.WriteHandlerType)}, key[:]...), "value"...))
+	require.NoError(t, err)
+	assert.Equal(t, []byte("No value found for key"), request(t, conn, append([]byte{byte(types.ReadHandlerType)}, key[:]...)))
+
+	// The write is acknowledged once committed
+	close(proposer.release)
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(time.Second)))
+	buf := make([]byte, 16)
+	n, err := conn.Read(buf)
+	require.NoError(t, err)
+	assert.Equal(t, []byte{byte(types.StatusOK)}, buf[:n])
+}
 
-	serverAddr, err := net.ResolveUDPAddr("udp", server.Addr().String())
-	if err != nil {
-		t.Fatalf("Failed to resolve server address: %v", err)
-	}
+// pendingReader holds every read until release is closed, then answers with value.
+type pendingReader struct {
+	release  chan struct{}
+	value    []byte
+	deadline chan bool // Reports whether each read is bounded by a deadline
+}
 
-	// Create the UDP client
-	client, err := net.DialUDP("udp", nil, serverAddr)
-	if err != nil {
-		t.Fatalf("Failed to create UDP client: %v", err)
-	}
-	defer client.Close()
-
-	// Table of test cases
-	tests := []struct {
-		name          string
-		message       Message
-		shouldFail    bool
-		modifyMessage func([]byte) []byte // Function to modify the encoded message for testing
-	}{
-		{
-			name: "Valid Write and Read",
-			message: Message{
-				Handler: WriteHandlerType,
-				Key:     [32]byte{}, // Will be set to random key
-				Data:    []byte("test value"),
-			},
-			shouldFail:    false,
-			modifyMessage: nil,
-		},
-		{
-			name: "Invalid Key Length (Too Short)",
-			message: Message{
-				Handler: WriteHandlerType,
-				Key:     [32]byte{}, // Will be set to random key
-				Data:    []byte("test value"),
-			},
-			shouldFail: true,
-			modifyMessage: func(encodedMsg []byte) []byte {
-				// Truncate the key to 31 bytes to simulate an invalid key length
-				return append(encodedMsg[:1], encodedMsg[1:32]...) // Handler + 31-byte key
-			},
-		},
-		{
-			name: "Invalid Handler Type",
-			message: Message{
-				Handler: 99,         // Invalid handler type
-				Key:     [32]byte{}, // Will be set to random key
-				Data:    []byte("test value"),
-			},
-			shouldFail:    true,
-			modifyMessage: nil,
-		},
-		{
-			name: "Empty Data",
-			message: Message{
-				Handler: WriteHandlerType,
-				Key:     [32]byte{}, // Will be set to random key
-				Data:    []byte(""), // No data
-			},
-			shouldFail:    true, // Adjusted to reflect the protocol requirement
-			modifyMessage: nil,
-		},
+func (r *pendingReader) Read(ctx context.Context, _ types.DbType, _ []byte) ([]byte, error) {
+	_, bounded := ctx.Deadline()
+	r.deadline <- bounded
+	select {
+	case <-r.release:
+		return r.value, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
 	}
+}
 
-	for _, tt := range tests {
-		tt := tt // capture tt for the closure
-		t.Run(tt.name, func(t *testing.T) {
-			// Generate a random 32-byte key
-			var keyBytes [32]byte
-			_, err := rand.Read(keyBytes[:])
-			if err != nil {
-				t.Fatalf("Failed to generate random key: %v", err)
-			}
-			tt.message.Key = keyBytes
-
-			// Encode the message
-			encodedMessage, err := tt.message.Encode()
-			if err != nil {
-				t.Fatalf("Failed to encode message: %v", err)
-			}
-
-			// Modify the encoded message if needed
-			if tt.modifyMessage != nil {
-				encodedMessage = tt.modifyMessage(encodedMessage)
-			}
-
-			// Send the message to the server
-			_, err = client.Write(encodedMessage)
-			if err != nil {
-				t.Errorf("Failed to write to UDP server: %v", err)
-				return
-			}
-
-			// Prepare buffer for reading response
-			buffer := make([]byte, 1024)
-
-			// Set read deadline to prevent blocking indefinitely
-			client.SetReadDeadline(time.Now().Add(1 * time.Second))
-
-			// Read response after write
-			n, _, err := client.ReadFromUDP(buffer)
-			if err != nil {
-				if tt.shouldFail {
-					// Expected failure due to read timeout or error
-					t.Logf("Expected failure occurred: %v", err)
-					return
-				}
-				t.Errorf("Failed to read from UDP server: %v", err)
-				return
-			}
-
-			// Log the response received from the server after write
-			response := string(buffer[:n])
-			t.Logf("Response from server after write: %s", response)
-
-			if tt.shouldFail {
-				// If we expected a failure but received a response, check if it's an error message
-				if response == "Message written to database" {
-					t.Errorf("Expected failure, but write succeeded")
-				} else {
-					t.Logf("Received expected error response: %s", response)
-				}
-				return
-			}
-
-			// For valid write operations, proceed to read the value back
-			// Prepare a Read message using the same key
-			readMessage := Message{
-				Handler: ReadHandlerType,
-				Key:     tt.message.Key,
-			}
-			encodedReadMessage, err := readMessage.Encode()
-			if err != nil {
-				t.Fatalf("Failed to encode read message: %v", err)
-			}
-
-			// Send the read request to the server
-			_, err = client.Write(encodedReadMessage)
-			if err != nil {
-				t.Errorf("Failed to write read request to UDP server: %v", err)
-				return
-			}
-
-			// Read response after read
-			rdErr := client.SetReadDeadline(time.Now().Add(1 * time.Second))
-			assert.NoError(t, rdErr)
-
-			n, _, err = client.ReadFromUDP(buffer)
-			if err != nil {
-				t.Errorf("Failed to read from UDP server: %v", err)
-				return
-			}
-
-			// Log the response received from the server after read
-			readValue := buffer[:n]
-			t.Logf("Response from server after read: %s", string(readValue))
-
-			// Compare the written value with the read value
-			assert.Equal(t, tt.message.Data, readValue, "The read value should match the written value")
-		})
-	}
+func TestServerAnswersWhileReadingRemotely(t *testing.T) {
+	server := fdbtest.Start(t, fdbtest.WithTransports(types.UDPTransportType))
+	reader := &pendingReader{release: make(chan struct{}), value: []byte("remote"), deadline: make(chan bool, 1)}
+	require.NoError(t, server.FDB.GetDbManager().SetReader(fdbtest.DefaultDatabase, reader))
+
+	conn, err := net.Dial("udp", server.Addr(types.UDPTransportType))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+
+	var key [32]byte
+	copy(key[:], "key")
+
+	// The event loop keeps answering while a read waits for the other nodes, which is bounded
+	_, err = conn.Write(append([]byte{byte(types.ReadHandlerType)}, key[:]...))
+	require.NoError(t, err)
+	assert.True(t, <-reader.deadline)
+	write := append(append([]byte{byte(types.WriteHandlerType)}, key[:]...), "value"...)
+	assert.Equal(t, []byte{byte(types.StatusOK)}, request(t, conn, write))
+
+	// The read is answered once the other nodes did
+	close(reader.release)
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(time.Second)))
+	buf := make([]byte, 16)
+	n, err := conn.Read(buf)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("remote"), buf[:n])
 }
@@ -0,0 +1,30 @@
+package transport_udp
+
+import (
+	"bytes"
+
+	"github.com/panjf2000/gnet"
+	"github.com/unpackdev/fdb/messages"
+)
+
+// taggedConn is the connection handed to the handler of a tagged request. Every response the
+// handler writes is tagged with the ID of the request.
+type taggedConn struct {
+	gnet.Conn
+	id uint32
+}
+
+// SendTo tags and sends a response datagram.
+func (c *taggedConn) SendTo(buf []byte) error {
+	return c.Conn.SendTo(messages.TagFrame(c.id, buf))
+}
+
+// AsyncWrite tags and writes a response asynchronously.
+func (c *taggedConn) AsyncWrite(buf []byte) error {
+	return c.Conn.AsyncWrite(messages.TagFrame(c.id, buf))
+}
+
+// AsyncWritev tags and writes a response made of several parts asynchronously.
+func (c *taggedConn) AsyncWritev(bs [][]byte) error {
+	return c.AsyncWrite(bytes.Join(bs, nil))
+}
@@ -0,0 +1,138 @@
+package transport_uds
+
+import (
+	"net"
+	"os"
+
+	"github.com/panjf2000/gnet"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// maxDatagramSize bounds a single request received on the datagram socket.
+const maxDatagramSize = 64 * 1024
+
+// listenDatagrams opens the datagram socket and serves its requests in the background, until
+// the server is stopped.
+func (s *Server) listenDatagrams() error {
+	if _, err := os.Stat(s.cnf.DatagramSocket); err == nil {
+		if rmErr := os.Remove(s.cnf.DatagramSocket); rmErr != nil {
+			return errors.Wrap(rmErr, "failed to remove existing UDS datagram socket file")
+		}
+	}
+
+	socket, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: s.cnf.DatagramSocket, Net: "unixgram"})
+	if err != nil {
+		return errors.Wrap(err, "failed to listen on UDS datagram socket")
+	}
+	s.datagrams = socket
+
+	zap.L().Info("UDS datagram socket is listening", zap.String("addr", s.cnf.DatagramSocket))
+	go s.serveDatagrams(socket)
+	return nil
+}
+
+// serveDatagrams handles every datagram received on the socket. Each datagram carries a single
+// request, answered with datagrams sent back to the socket of the client.
+func (s *Server) serveDatagrams(socket *net.UnixConn) {
+	buf := make([]byte, maxDatagramSize)
+	for {
+		n, addr, err := socket.ReadFromUnix(buf)
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			zap.L().Error("Error reading datagram", zap.Error(err))
+			continue
+		}
+
+		// Clients without a socket of their own cannot be answered
+		if addr == nil || addr.Name == "" {
+			zap.L().Warn("Dropping datagram from unbound UDS client")
+			continue
+		}
+
+		c := &datagramConn{socket: socket, remote: addr, packet: append([]byte(nil), buf[:n]...)}
+		out, err := s.handle(c, c.packet)
+		if err != nil {
+			zap.L().Warn("Invalid tagged request", zap.Error(err), zap.String("addr", addr.Name))
+			continue
+		}
+		if out != nil {
+			_ = c.SendTo(out)
+		}
+	}
+}
+
+// datagramConn is the gnet.Conn handed to handlers for a request received on the datagram
+// socket. Every write sends a datagram to the client.
+type datagramConn struct {
+	socket *net.UnixConn
+	remote *net.UnixAddr
+	packet []byte
+	ctx    interface{}
+}
+
+var _ gnet.Conn = (*datagramConn)(nil)
+
+// Context returns the user-defined context.
+func (c *datagramConn) Context() interface{} { return c.ctx }
+
+// SetContext sets the user-defined context.
+func (c *datagramConn) SetContext(ctx interface{}) { c.ctx = ctx }
+
+// LocalAddr returns the address of the datagram socket.
+func (c *datagramConn) LocalAddr() net.Addr { return c.socket.LocalAddr() }
+
+// RemoteAddr returns the address of the client socket.
+func (c *datagramConn) RemoteAddr() net.Addr { return c.remote }
+
+// Read returns the request.
+func (c *datagramConn) Read() []byte { return c.packet }
+
+// ResetBuffer discards the request.
+func (c *datagramConn) ResetBuffer() { c.packet = nil }
+
+// ReadN returns the first n bytes of the request.
+func (c *datagramConn) ReadN(n int) (int, []byte) {
+	if n <= 0 || n > len(c.packet) {
+		n = len(c.packet)
+	}
+	return n, c.packet[:n]
+}
+
+// ShiftN discards the first n bytes of the request.
+func (c *datagramConn) ShiftN(n int) int {
+	if n <= 0 || n > len(c.packet) {
+		n = len(c.packet)
+	}
+	c.packet = c.packet[n:]
+	return n
+}
+
+// BufferLength returns the length of the request.
+func (c *datagramConn) BufferLength() int { return len(c.packet) }
+
+// SendTo sends a datagram to the client.
+func (c *datagramConn) SendTo(buf []byte) error {
+	_, err := c.socket.WriteToUnix(buf, c.remote)
+	return err
+}
+
+// AsyncWrite sends a datagram to the client.
+func (c *datagramConn) AsyncWrite(buf []byte) error { return c.SendTo(buf) }
+
+// AsyncWritev sends the parts as a single datagram to the client.
+func (c *datagramConn) AsyncWritev(bs [][]byte) error {
+	var buf []byte
+	for _, b := range bs {
+		buf = append(buf, b...)
+	}
+	return c.SendTo(buf)
+}
+
+// Wake is a no-op, datagrams have no connection to wake.
+func (c *datagramConn) Wake() error { return nil }
+
+// Close is a no-op, datagrams have no connection to close.
+func (c *datagramConn) Close() error { return nil }
@@ -0,0 +1,23 @@
+package transport_uds
+
+import (
+	"github.com/panjf2000/gnet"
+	"github.com/unpackdev/fdb/db"
+)
+
+// UDSAdminHandler struct with the database manager passed in
+type UDSAdminHandler struct {
+	manager *db.Manager // Database manager executing the admin operations
+}
+
+// NewUDSAdminHandler creates a new UDSAdminHandler with a database manager
+func NewUDSAdminHandler(manager *db.Manager) *UDSAdminHandler {
+	return &UDSAdminHandler{
+		manager: manager,
+	}
+}
+
+// HandleMessage executes the admin operation and sends back the status-prefixed response
+func (ah *UDSAdminHandler) HandleMessage(c gnet.Conn, frame []byte) {
+	c.SendTo(ah.manager.ExecuteAdminFrame(frame))
+}
@@ -0,0 +1,75 @@
+package transport_uds
+
+import (
+	"context"
+	"github.com/panjf2000/gnet"
+	"github.com/unpackdev/fdb/db"
+	"github.com/unpackdev/fdb/messages"
+	"github.com/unpackdev/fdb/types"
+	"log"
+)
+
+// UDSMultiSetHandler struct with the database router passed in
+type UDSMultiSetHandler struct {
+	router *db.Router // Resolves the database and batch writer of each request
+}
+
+// NewUDSMultiSetHandler creates a new UDSMultiSetHandler with a database router
+func NewUDSMultiSetHandler(router *db.Router) *UDSMultiSetHandler {
+	return &UDSMultiSetHandler{
+		router: router,
+	}
+}
+
+// HandleMessage processes a multi-set request, see messages.EncodeMultiSet. The writes are
+// acknowledged with a single status byte once all of them are buffered.
+func (mh *UDSMultiSetHandler) HandleMessage(c gnet.Conn, frame []byte) {
+	// Strip the optional consistency header and database selector
+	consistency, database, frame, err := messages.SplitHeader(frame)
+	if err != nil {
+		log.Printf("Invalid database selector: %v", err)
+		c.SendTo([]byte{byte(types.StatusError)})
+		return
+	}
+
+	entries, err := messages.DecodeMultiSet(frame)
+	if err != nil {
+		log.Printf("Invalid multi-set: %v", err)
+		c.SendTo([]byte{byte(types.StatusError)})
+		return
+	}
+
+	// The frame is reused by the event loop, so the buffered values must be copies
+	for i := range entries {
+		entries[i].Value = append([]byte(nil), entries[i].Value...)
+	}
+
+	// Databases replicated through consensus acknowledge once every write is committed, which
+	// must not block the event loop
+	if proposer, name := mh.router.Proposer(types.DbType(database)); proposer != nil {
+		go func() {
+			ctx := db.WithConsistency(context.Background(), consistency)
+			for _, entry := range entries {
+				if err := proposer.Propose(ctx, name, types.ChangeSet, entry.Key[:], entry.Value); err != nil {
+					log.Printf("Error committing multi-set: %v", err)
+					c.SendTo([]byte{byte(db.WriteStatus(err))})
+					return
+				}
+			}
+			c.SendTo([]byte{byte(types.StatusOK)})
+		}()
+		return
+	}
+
+	writer, err := mh.router.Writer(types.DbType(database))
+	if err != nil {
+		log.Printf("Error resolving database: %v", err)
+		c.SendTo([]byte{byte(db.WriteStatus(err))})
+		return
+	}
+
+	for _, entry := range entries {
+		writer.BufferWrite(entry.Key, entry.Value)
+	}
+	c.SendTo([]byte{byte(types.StatusOK)})
+}
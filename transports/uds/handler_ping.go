@@ -0,0 +1,38 @@
+package transport_uds
+
+import (
+	"github.com/panjf2000/gnet"
+	"github.com/unpackdev/fdb/db"
+	"github.com/unpackdev/fdb/messages"
+	"github.com/unpackdev/fdb/types"
+	"log"
+)
+
+// UDSPingHandler struct with the database router passed in
+type UDSPingHandler struct {
+	router *db.Router // Resolves the database of each request
+}
+
+// NewUDSPingHandler creates a new UDSPingHandler with a database router
+func NewUDSPingHandler(router *db.Router) *UDSPingHandler {
+	return &UDSPingHandler{
+		router: router,
+	}
+}
+
+// HandleMessage answers a health check with a status byte, types.StatusDatabaseNotFound when
+// the node does not serve the selected database. Pings touch no data.
+func (ph *UDSPingHandler) HandleMessage(c gnet.Conn, frame []byte) {
+	_, database, _, err := messages.SplitHeader(frame)
+	if err != nil {
+		log.Printf("Invalid database selector: %v", err)
+		c.SendTo([]byte{byte(types.StatusError)})
+		return
+	}
+
+	if _, err := ph.router.Resolve(types.DbType(database)); err != nil {
+		c.SendTo([]byte{byte(types.StatusDatabaseNotFound)})
+		return
+	}
+	c.SendTo([]byte{byte(types.StatusOK)})
+}
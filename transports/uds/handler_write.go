@@ -1,28 +1,37 @@
 package transport_uds
 
 import (
+	"context"
 	"fmt"
 	"github.com/panjf2000/gnet"
 	"github.com/unpackdev/fdb/db"
+	"github.com/unpackdev/fdb/messages"
+	"github.com/unpackdev/fdb/types"
 	"log"
 )
 
-// UDSWriteHandler struct with MDBX database and BatchWriter passed in
+// UDSWriteHandler struct with the database router passed in
 type UDSWriteHandler struct {
-	db     db.Provider     // MDBX database instance
-	writer *db.BatchWriter // Batch writer instance
+	router *db.Router // Resolves the database and batch writer of each request
 }
 
-// NewUDSWriteHandler creates a new UDSWriteHandler with an MDBX database and BatchWriter
-func NewUDSWriteHandler(db db.Provider, batchWriter *db.BatchWriter) *UDSWriteHandler {
+// NewUDSWriteHandler creates a new UDSWriteHandler with a database router
+func NewUDSWriteHandler(router *db.Router) *UDSWriteHandler {
 	return &UDSWriteHandler{
-		db:     db,
-		writer: batchWriter,
+		router: router,
 	}
 }
 
 // HandleMessage processes the incoming message using the UDSWriteHandler
 func (wh *UDSWriteHandler) HandleMessage(c gnet.Conn, frame []byte) {
+	// Strip the optional consistency header and database selector and resolve the batch writer of the selected database
+	consistency, database, frame, err := messages.SplitHeader(frame)
+	if err != nil {
+		log.Printf("Invalid database selector: %v", err)
+		c.SendTo([]byte{byte(types.StatusError)})
+		return
+	}
+
 	// Check if the message is at least 34 bytes (1 byte for action, 32 bytes for key, and at least 1 byte for value)
 	if len(frame) < 34 {
 		log.Printf("Invalid message length: %d, expected at least 34 bytes", len(frame))
@@ -37,8 +46,31 @@ func (wh *UDSWriteHandler) HandleMessage(c gnet.Conn, frame []byte) {
 	// The remaining part is the value (from byte 33 onwards)
 	value := frame[33:]
 
-	// Buffer the write request with the key as [32]byte
-	wh.writer.BufferWrite(key, value)
+	// Databases replicated through consensus acknowledge once the write is committed, which
+	// must not block the event loop. The frame is reused by the event loop, so the value is copied
+	if proposer, name := wh.router.Proposer(types.DbType(database)); proposer != nil {
+		value = append([]byte(nil), value...)
+		go func() {
+			if err := proposer.Propose(db.WithConsistency(context.Background(), consistency), name, types.ChangeSet, key[:], value); err != nil {
+				log.Printf("Error committing write: %v", err)
+				c.SendTo([]byte{byte(db.WriteStatus(err))})
+				return
+			}
+			c.SendTo([]byte{0x00})
+		}()
+		return
+	}
+
+	writer, err := wh.router.Writer(types.DbType(database))
+	if err != nil {
+		log.Printf("Error resolving database: %v", err)
+		c.SendTo([]byte{byte(db.WriteStatus(err))})
+		return
+	}
+
+	// Buffer the write request with the key as [32]byte. The frame is reused by the event
+	// loop, so the buffered value must be a copy
+	writer.BufferWrite(key, append([]byte(nil), value...))
 
 	fmt.Println("WRITTEN TO BUFFER")
 	// Send success response
@@ -5,8 +5,10 @@ import (
 	"github.com/panjf2000/gnet"
 	"github.com/pkg/errors"
 	"github.com/unpackdev/fdb/config"
+	"github.com/unpackdev/fdb/messages"
 	"github.com/unpackdev/fdb/types"
 	"go.uber.org/zap"
+	"net"
 	"os"
 	"time"
 )
@@ -22,6 +24,9 @@ type Server struct {
 	cnf             config.UdsTransport
 	stopChan        chan struct{}
 	started         chan struct{}
+
+	// datagrams is the datagram socket, nil unless configured.
+	datagrams *net.UnixConn
 }
 
 // NewServer creates a new UDS Server instance using the provided configuration
@@ -64,10 +69,10 @@ func (s *Server) Start(ctx context.Context) error {
 		err := gnet.Serve(
 			s, listenAddr,
 			gnet.WithMulticore(true),
-			gnet.WithReusePort(true),
 			gnet.WithSocketRecvBuffer(1024*64),
 			gnet.WithLockOSThread(true),
 			gnet.WithTicker(true),
+			gnet.WithCodec(frameCodec{}),
 		)
 		if err != nil {
 			errChan <- err
@@ -81,6 +86,9 @@ func (s *Server) Start(ctx context.Context) error {
 	case <-s.started:
 		close(s.started)
 		zap.L().Info("UDS Server successfully started", zap.String("addr", listenAddr))
+		if s.cnf.DatagramSocket != "" {
+			return s.listenDatagrams()
+		}
 		return nil
 	case err := <-errChan:
 		if err != nil {
@@ -107,7 +115,23 @@ func (s *Server) Stop() error {
 	zap.L().Info("Stopping UDS Server", zap.String("addr", s.cnf.Addr()))
 	close(s.stopChan)
 
-	if err := os.Remove(s.cnf.Addr()); err != nil {
+	// Wait for the event loops to close the listener and the connections, so a server started
+	// next on the same socket does not lose its socket file to this one
+	ctx, cancel := context.WithTimeout(s.ctx, 5*time.Second)
+	defer cancel()
+	if err := gnet.Stop(ctx, "unix://"+s.cnf.Addr()); err != nil {
+		zap.L().Warn("UDS Server did not shut down cleanly", zap.Error(err))
+	}
+
+	if s.datagrams != nil {
+		_ = s.datagrams.Close()
+		if err := os.Remove(s.cnf.DatagramSocket); err != nil && !os.IsNotExist(err) {
+			zap.L().Error("Failed to remove UDS datagram socket file", zap.Error(err))
+		}
+	}
+
+	// The listener usually removes the socket file itself
+	if err := os.Remove(s.cnf.Addr()); err != nil && !os.IsNotExist(err) {
 		zap.L().Error("Failed to remove UDS socket file", zap.Error(err))
 		return err
 	}
@@ -130,38 +154,75 @@ func (s *Server) OnInitComplete(server gnet.Server) (action gnet.Action) {
 
 // React handles incoming data
 func (s *Server) React(frame []byte, c gnet.Conn) (out []byte, action gnet.Action) {
+	out, err := s.handle(&streamConn{Conn: c}, frame)
+	if err != nil {
+		zap.L().Warn("Invalid tagged request", zap.Error(err), zap.String("addr", c.RemoteAddr().String()))
+		return nil, gnet.Close
+	}
+	return out, gnet.None
+}
+
+// handle dispatches a request, returning the response to write when no handler answers it.
+// Tagged requests are answered with responses tagged with the same ID.
+func (s *Server) handle(c gnet.Conn, frame []byte) ([]byte, error) {
+	if len(frame) == 0 || frame[0] != messages.TagSelector {
+		return s.dispatch(c, frame), nil
+	}
+
+	id, request, n, err := messages.SplitTagged(frame)
+	if err != nil {
+		return nil, err
+	}
+	if n != len(frame) {
+		return nil, errors.New("incomplete tagged request")
+	}
+
+	if out := s.dispatch(&taggedConn{Conn: c, id: id}, request); out != nil {
+		return messages.TagFrame(id, out), nil
+	}
+	return nil, nil
+}
+
+// dispatch calls the handler of a request frame, returning the error response if it cannot.
+func (s *Server) dispatch(c gnet.Conn, frame []byte) []byte {
 	if len(frame) < 1 {
 		zap.L().Warn("Invalid action received", zap.String("addr", c.RemoteAddr().String()))
-		return []byte("ERROR: Invalid action"), gnet.None
+		return []byte("ERROR: Invalid action")
 	}
 
 	// Parse the action type
 	actionType, err := s.parseActionType(frame)
 	if err != nil {
 		zap.L().Warn("Failed to parse action type", zap.Error(err), zap.String("addr", c.RemoteAddr().String()))
-		return []byte("ERROR: Invalid action"), gnet.None
+		return []byte("ERROR: Invalid action")
 	}
 
 	// Check if the handler exists
 	handler, exists := s.handlerRegistry[actionType]
 	if !exists {
 		zap.L().Warn("Unknown action type", zap.Int("action_type", int(actionType)), zap.String("addr", c.RemoteAddr().String()))
-		return []byte("ERROR: Unknown action"), gnet.None
+		return []byte("ERROR: Unknown action")
 	}
 
 	// Call the handler
 	handler(c, frame)
-	return nil, gnet.None
+	return nil
 }
 
 // parseActionType parses the action type from the frame
 func (s *Server) parseActionType(frame []byte) (types.HandlerType, error) {
+	// The action byte follows the optional consistency header and database selector
+	_, _, frame, err := messages.SplitHeader(frame)
+	if err != nil {
+		return 0, err
+	}
+
 	if len(frame) < 1 {
 		return 0, errors.New("invalid action: frame too short")
 	}
 
 	var actionType types.HandlerType
-	err := actionType.FromByte(frame[0])
+	err = actionType.FromByte(frame[0])
 	if err != nil {
 		return 0, err
 	}
@@ -180,3 +241,14 @@ func (s *Server) DeregisterHandler(actionType types.HandlerType) {
 	zap.L().Debug("Deregistering handler", zap.Int("action_type", int(actionType)))
 	delete(s.handlerRegistry, actionType)
 }
+
+// streamConn is the connection handed to the handlers of a stream socket. Sending to the unnamed
+// peer of an accepted UDS connection fails, so responses are written to the connection instead.
+type streamConn struct {
+	gnet.Conn
+}
+
+// SendTo writes a response asynchronously.
+func (c *streamConn) SendTo(buf []byte) error {
+	return c.Conn.AsyncWrite(buf)
+}
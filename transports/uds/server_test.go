@@ -1,222 +1,200 @@
-package transport_uds
+package transport_uds_test
 
 import (
 	"context"
-	"crypto/rand"
-	"github.com/stretchr/testify/assert"
-	"github.com/unpackdev/fdb/db"
-	"log"
 	"net"
 	"os"
+	"path/filepath"
 	"testing"
 	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/unpackdev/fdb/config"
+	"github.com/unpackdev/fdb/fdbtest"
+	"github.com/unpackdev/fdb/messages"
+	"github.com/unpackdev/fdb/types"
 )
 
-// Start UDS server function with handlers for write and read
-func startUDSServer(ctx context.Context, db db.Provider) (*UDSServer, error) {
-	socketPath := "/tmp/fdb_test.sock"
-	log.Printf("Starting UDS server on socket: %s", socketPath)
+// request sends frame and returns the response answering it. Requests are sent one at a time,
+// so a single read returns the whole response.
+func request(t *testing.T, conn net.Conn, frame []byte) []byte {
+	t.Helper()
+
+	_, err := conn.Write(frame)
+	require.NoError(t, err)
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(time.Second)))
+	buf := make([]byte, 64*1024)
+	n, err := conn.Read(buf)
+	require.NoError(t, err)
+	return buf[:n]
+}
 
-	server, err := NewUDSServer(socketPath)
-	if err != nil {
-		return nil, err
+func TestServerAnswersStreamsAndDatagrams(t *testing.T) {
+	// The UDS server lowercases its socket paths, so t.TempDir, named after the test, cannot
+	// hold them
+	dir, err := os.MkdirTemp("", "fdb-uds")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = os.RemoveAll(dir) })
+	datagramSocket := filepath.Join(dir, "fdb.dgram.sock")
+
+	server := fdbtest.Start(t,
+		fdbtest.WithTransports(types.UDSTransportType),
+		fdbtest.WithConfig(func(cfg *config.Config) {
+			cfg.GetTransportByType(types.UDSTransportType).Config.(*config.UdsTransport).DatagramSocket = datagramSocket
+		}),
+	)
+
+	stream, err := net.Dial("unix", server.Addr(types.UDSTransportType))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = stream.Close() })
+
+	// Datagrams are answered to the socket they were sent from
+	datagram, err := net.DialUnix("unixgram",
+		&net.UnixAddr{Name: filepath.Join(dir, "client.sock"), Net: "unixgram"},
+		&net.UnixAddr{Name: datagramSocket, Net: "unixgram"},
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = datagram.Close() })
+
+	for name, conn := range map[string]net.Conn{"stream": stream, "datagram": datagram} {
+		t.Run(name, func(t *testing.T) {
+			var key, missing [32]byte
+			copy(key[:], name)
+			copy(missing[:], "missing")
+			read := append([]byte{byte(types.ReadHandlerType)}, key[:]...)
+
+			// A write is acknowledged once buffered and visible once flushed
+			write := append(append([]byte{byte(types.WriteHandlerType)}, key[:]...), "value"...)
+			assert.Equal(t, []byte{byte(types.StatusOK)}, request(t, conn, write))
+			require.Eventually(t, func() bool {
+				return string(request(t, conn, read)) == "value"
+			}, 5*time.Second, 20*time.Millisecond)
+
+			// Tagged reads are answered with a status, untagged ones with the text of the error
+			assert.Equal(t, []byte("No value found for key"), request(t, conn, append([]byte{byte(types.ReadHandlerType)}, missing[:]...)))
+			tagged := request(t, conn, messages.TagFrame(7, read))
+			id, response, n, err := messages.SplitTagged(tagged)
+			require.NoError(t, err)
+			assert.Equal(t, len(tagged), n)
+			assert.Equal(t, uint32(7), id)
+			assert.Equal(t, messages.EncodeReadResponse(types.StatusOK, []byte("value")), response)
+
+			// Frames without a known handler are refused
+			assert.Equal(t, []byte("ERROR: Invalid action"), request(t, conn, []byte{'?'}))
+		})
 	}
-
-	wHandler := NewUDSWriteHandler(db)
-	server.RegisterHandler(WriteHandlerType, wHandler.HandleMessage)
-
-	rHandler := NewUDSReadHandler(db)
-	server.RegisterHandler(ReadHandlerType, rHandler.HandleMessage)
-
-	go func() {
-		log.Println("Starting gnet server...")
-		err := server.Start()
-		if err != nil {
-			log.Fatalf("Failed to start server: %v", err)
-		}
-		log.Println("gnet server started.")
-	}()
-
-	log.Println("Awaiting for started closure...")
-	<-server.WaitStarted() // Wait for the server to signal that it has started
-	log.Println("UDS server started.")
-
-	return server, nil
 }
 
-// TestUDSServer tests the gnet-based UDS server with various scenarios
-func TestUDSServer(t *testing.T) {
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
-
-	// Remove the test UDS socket file after the test completes
-	socketPath := "/tmp/fdb_test.sock"
-	defer os.Remove(socketPath)
-
-	// Setup the MDBX manager and database
-	manager := setupTestManager(t)
-
-	// Get the database from the manager
-	db, err := manager.GetDb("test")
-	assert.NoError(t, err)
-	defer db.Destroy()
-
-	server, sErr := startUDSServer(ctx, db)
-	assert.NoError(t, sErr)
+// pendingProposer holds every proposal until release is closed.
+type pendingProposer struct {
+	release chan struct{}
+}
 
-	// Connect to the UDS server as a client
-	conn, err := net.Dial("unix", server.Addr())
-	if err != nil {
-		t.Fatalf("Failed to create UDS client: %v", err)
-	}
-	defer conn.Close()
-
-	// Table of test cases
-	tests := []struct {
-		name          string
-		message       Message
-		shouldFail    bool
-		modifyMessage func([]byte) []byte // Function to modify the encoded message for testing
-	}{
-		{
-			name: "Valid Write and Read",
-			message: Message{
-				Handler: WriteHandlerType,
-				Key:     [32]byte{}, // Will be set to random key
-				Data:    []byte("test value"),
-			},
-			shouldFail:    false,
-			modifyMessage: nil,
-		},
-		{
-			name: "Invalid Key Length (Too Short)",
-			message: Message{
-				Handler: WriteHandlerType,
-				Key:     [32]byte{}, // Will be set to random key
-				Data:    []byte("test value"),
-			},
-			shouldFail: true,
-			modifyMessage: func(encodedMsg []byte) []byte {
-				// Truncate the key to 31 bytes to simulate an invalid key length
-				return append(encodedMsg[:1], encodedMsg[1:32]...) // Handler + 31-byte key
-			},
-		},
-		{
-			name: "Invalid Handler Type",
-			message: Message{
-				Handler: 99,         // Invalid handler type
-				Key:     [32]byte{}, // Will be set to random key
-				Data:    []byte("test value"),
-			},
-			shouldFail:    true,
-			modifyMessage: nil,
-		},
-		{
-			name: "Empty Data",
-			message: Message{
-				Handler: WriteHandlerType,
-				Key:     [32]byte{}, // Will be set to random key
-				Data:    []byte(""), // No data
-			},
-			shouldFail:    true, // Adjusted to reflect the protocol requirement
-			modifyMessage: nil,
-		},
+func (p *pendingProposer) Propose(ctx context.Context, _ types.DbType, _ types.ChangeOp, _, _ []byte) error {
+	select {
+	case <-p.release:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
 	}
+}
 
-	for _, tt := range tests {
-		tt := tt // capture tt for the closure
-		t.Run(tt.name, func(t *testing.T) {
-			// Generate a random 32-byte key
-			var keyBytes [32]byte
-			_, err := rand.Read(keyBytes[:])
-			if err != nil {
-				t.Fatalf("Failed to generate random key: %v", err)
-			}
-			tt.message.Key = keyBytes
-
-			// Encode the message
-			encodedMessage, err := tt.message.Encode()
-			if err != nil {
-				t.Fatalf("Failed to encode message: %v", err)
-			}
-
-			// Modify the encoded message if needed
-			if tt.modifyMessage != nil {
-				encodedMessage = tt.modifyMessage(encodedMessage)
-			}
-
-			// Send the message to the server
-			_, err = conn.Write(encodedMessage)
-			if err != nil {
-				t.Errorf("Failed to write to UDS server: %v", err)
-				return
-			}
-
-			// Prepare buffer for reading response
-			buffer := make([]byte, 1024)
-
-			// Set read deadline to prevent blocking indefinitely
-			conn.SetReadDeadline(time.Now().Add(1 * time.Second))
-
-			// Read response after write
-			n, err := conn.Read(buffer)
-			if err != nil {
-				if tt.shouldFail {
-					// Expected failure due to read timeout or error
-					t.Logf("Expected failure occurred: %v", err)
-					return
-				}
-				t.Errorf("Failed to read from UDS server: %v", err)
-				return
-			}
-
-			// Log the response received from the server after write
-			response := string(buffer[:n])
-			t.Logf("Response from server after write: %s", response)
-
-			if tt.shouldFail {
-				// If we expected a failure but received a response, check if it's an error message
-				if response == "Message written to database" {
-					t.Errorf("Expected failure, but write succeeded")
-				} else {
-					t.Logf("Received expected error response: %s", response)
-				}
-				return
-			}
-
-			// For valid write operations, proceed to read the value back
-			// Prepare a Read message using the same key
-			readMessage := Message{
-				Handler: ReadHandlerType,
-				Key:     tt.message.Key,
-			}
-			encodedReadMessage, err := readMessage.Encode()
-			if err != nil {
-				t.Fatalf("Failed to encode read message: %v", err)
-			}
-
-			// Send the read request to the server
-			_, err = conn.Write(encodedReadMessage)
-			if err != nil {
-				t.Errorf("Failed to write read request to UDS server: %v", err)
-				return
-			}
-
-			// Read response after read
-			conn.SetReadDeadline(time.Now().Add(1 * time.Second))
-
-			n, err = conn.Read(buffer)
-			if err != nil {
-				t.Errorf("Failed to read from UDS server: %v", err)
-				return
+func TestServerAnswersWhileProposing(t *testing.T) {
+	server := fdbtest.Start(t, fdbtest.WithTransports(types.UDSTransportType))
+	proposer := &pendingProposer{release: make(chan struct{})}
+	require.NoError(t, server.FDB.GetDbManager().SetProposer(fdbtest.DefaultDatabase, proposer))
+
+	conn, err := net.Dial("unix", server.Addr(types.UDSTransportType))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+
+	var key [32]byte
+	copy(key[:], "key")
+	write := append(append([]byte{byte(types.WriteHandlerType)}, key[:]...), "value"...)
+
+	// The event loop keeps answering while the writes wait to be committed
+	_, err = conn.Write(messages.TagFrame(1, write))
+	require.NoError(t, err)
+	_, err = conn.Write(messages.TagFrame(2, messages.EncodeMultiSet([]messages.MultiSetEntry{{Key: key, Value: []byte("value")}})))
+	require.NoError(t, err)
+	response := request(t, conn, messages.TagFrame(3, append([]byte{byte(types.ReadHandlerType)}, key[:]...)))
+	id, response, _, err := messages.SplitTagged(response)
+	require.NoError(t, err)
+	assert.Equal(t, uint32(3), id)
+	assert.Equal(t, messages.EncodeReadResponse(types.StatusNotFound, nil), response)
+
+	// The writes are acknowledged once committed
+	close(proposer.release)
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(time.Second)))
+	acks := make(map[uint32][]byte)
+	buf := make([]byte, 64)
+	var pending []byte
+	for len(acks) < 2 {
+		n, err := conn.Read(buf)
+		require.NoError(t, err)
+		pending = append(pending, buf[:n]...)
+		for {
+			id, response, n, err := messages.SplitTagged(pending)
+			require.NoError(t, err)
+			if n == 0 {
+				break
 			}
+			acks[id] = response
+			pending = pending[n:]
+		}
+	}
+	assert.Equal(t, map[uint32][]byte{1: {byte(types.StatusOK)}, 2: {byte(types.StatusOK)}}, acks)
+}
 
-			// Log the response received from the server after read
-			readValue := buffer[:n]
-			t.Logf("Response from server after read: %s", string(readValue))
+// pendingReader holds every read until release is closed, then answers with value.
+type pendingReader struct {
+	release  chan struct{}
+	value    []byte
+	deadline chan bool // Reports whether each read is bounded by a deadline
+}
 
-			// Compare the written value with the read value
-			assert.Equal(t, tt.message.Data, readValue, "The read value should match the written value")
-		})
+func (r *pendingReader) Read(ctx context.Context, _ types.DbType, _ []byte) ([]byte, error) {
+	_, bounded := ctx.Deadline()
+	r.deadline <- bounded
+	select {
+	case <-r.release:
+		return r.value, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
 	}
 }
+
+func TestServerAnswersWhileReadingRemotely(t *testing.T) {
+	server := fdbtest.Start(t, fdbtest.WithTransports(types.UDSTransportType))
+	reader := &pendingReader{release: make(chan struct{}), value: []byte("remote"), deadline: make(chan bool, 1)}
+	require.NoError(t, server.FDB.GetDbManager().SetReader(fdbtest.DefaultDatabase, reader))
+
+	conn, err := net.Dial("unix", server.Addr(types.UDSTransportType))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+
+	var key [32]byte
+	copy(key[:], "key")
+
+	// The event loop keeps answering while a read waits for the other nodes, which is bounded
+	_, err = conn.Write(messages.TagFrame(1, append([]byte{byte(types.ReadHandlerType)}, key[:]...)))
+	require.NoError(t, err)
+	assert.True(t, <-reader.deadline)
+	write := append(append([]byte{byte(types.WriteHandlerType)}, key[:]...), "value"...)
+	id, response, _, err := messages.SplitTagged(request(t, conn, messages.TagFrame(2, write)))
+	require.NoError(t, err)
+	assert.Equal(t, uint32(2), id)
+	assert.Equal(t, []byte{byte(types.StatusOK)}, response)
+
+	// The read is answered once the other nodes did
+	close(reader.release)
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(time.Second)))
+	buf := make([]byte, 64)
+	n, err := conn.Read(buf)
+	require.NoError(t, err)
+	id, response, _, err = messages.SplitTagged(buf[:n])
+	require.NoError(t, err)
+	assert.Equal(t, uint32(1), id)
+	assert.Equal(t, messages.EncodeReadResponse(types.StatusOK, []byte("remote")), response)
+}
@@ -0,0 +1,68 @@
+package transport_uds
+
+import (
+	"bytes"
+
+	"github.com/panjf2000/gnet"
+	gerrors "github.com/panjf2000/gnet/pkg/errors"
+	"github.com/unpackdev/fdb/messages"
+)
+
+// frameCodec splits the stream of a connection into requests. Untagged requests are passed on
+// with everything buffered, as the built-in codec does; tagged requests are delimited by their
+// length and passed on whole once complete, see messages.TagSelector.
+type frameCodec struct{}
+
+// Encode passes responses through unchanged.
+func (frameCodec) Encode(_ gnet.Conn, buf []byte) ([]byte, error) {
+	return buf, nil
+}
+
+// Decode returns the next request buffered on the connection.
+func (frameCodec) Decode(c gnet.Conn) ([]byte, error) {
+	buf := c.Read()
+	if len(buf) == 0 {
+		return nil, gerrors.ErrIncompletePacket
+	}
+
+	if buf[0] != messages.TagSelector {
+		c.ResetBuffer()
+		return buf, nil
+	}
+
+	_, _, n, err := messages.SplitTagged(buf)
+	if err != nil {
+		c.ResetBuffer()
+		return nil, err
+	}
+	if n == 0 {
+		return nil, gerrors.ErrIncompletePacket
+	}
+
+	// Shifting may release the buffer holding the request
+	frame := append([]byte(nil), buf[:n]...)
+	c.ShiftN(n)
+	return frame, nil
+}
+
+// taggedConn is the connection handed to the handler of a tagged request. Every response the
+// handler writes is tagged with the ID of the request.
+type taggedConn struct {
+	gnet.Conn
+	id uint32
+}
+
+// SendTo tags and writes a response synchronously.
+func (c *taggedConn) SendTo(buf []byte) error {
+	return c.Conn.SendTo(messages.TagFrame(c.id, buf))
+}
+
+// AsyncWrite tags and writes a response asynchronously.
+func (c *taggedConn) AsyncWrite(buf []byte) error {
+	return c.Conn.AsyncWrite(messages.TagFrame(c.id, buf))
+}
+
+// AsyncWritev tags and writes a response made of several parts asynchronously.
+func (c *taggedConn) AsyncWritev(bs [][]byte) error {
+	return c.AsyncWrite(bytes.Join(bs, nil))
+}
@@ -56,6 +56,22 @@ func (t *TransportType) UnmarshalYAML(unmarshal func(any) error) error {
 	return nil
 }
 
+// MarshalText encodes the TransportType by name, so it reads naturally in JSON payloads
+func (t TransportType) MarshalText() ([]byte, error) {
+	return []byte(t.String()), nil
+}
+
+// UnmarshalText decodes a TransportType from its name
+func (t *TransportType) UnmarshalText(text []byte) error {
+	tt, err := ParseTransportType(string(text))
+	if err != nil {
+		return err
+	}
+
+	*t = tt
+	return nil
+}
+
 const (
 	UDPTransportType TransportType = iota
 	DummyTransportType
@@ -84,6 +100,24 @@ func (h *HandlerType) FromByte(b byte) error {
 		*h = WriteHandlerType
 	case 'R':
 		*h = ReadHandlerType
+	case 'A':
+		*h = AdminHandlerType
+	case 'S':
+		*h = SubscribeHandlerType
+	case 'N':
+		*h = SnapshotHandlerType
+	case 'D':
+		*h = DeleteHandlerType
+	case 'M':
+		*h = MembersHandlerType
+	case 'H':
+		*h = TreeHandlerType
+	case 'V':
+		*h = ReplicaHandlerType
+	case 'B':
+		*h = MultiSetHandlerType
+	case 'P':
+		*h = PingHandlerType
 	default:
 		return fmt.Errorf("invalid action byte: %v", b)
 	}
@@ -92,6 +126,53 @@ func (h *HandlerType) FromByte(b byte) error {
 
 // Define the handlers as 1-byte constants
 const (
-	WriteHandlerType HandlerType = 'W' // 'W' for WRITE
-	ReadHandlerType  HandlerType = 'R' // 'R' for READ
+	WriteHandlerType  HandlerType = 'W' // 'W' for WRITE
+	ReadHandlerType   HandlerType = 'R' // 'R' for READ
+	AdminHandlerType  HandlerType = 'A' // 'A' for ADMIN
+	DeleteHandlerType HandlerType = 'D' // 'D' for DELETE
+
+	SubscribeHandlerType HandlerType = 'S' // 'S' for SUBSCRIBE (change data capture)
+	SnapshotHandlerType  HandlerType = 'N' // 'N' for sNAPSHOT (replication bootstrap)
+	MembersHandlerType   HandlerType = 'M' // 'M' for MEMBERS (cluster discovery)
+	TreeHandlerType      HandlerType = 'H' // 'H' for HASH tree (anti-entropy repair)
+	ReplicaHandlerType   HandlerType = 'V' // 'V' for VERSIONED replica requests (quorum replication)
+	MultiSetHandlerType  HandlerType = 'B' // 'B' for BATCH of writes (multi-set)
+	PingHandlerType      HandlerType = 'P' // 'P' for PING (health checks)
+)
+
+// ChangeOp identifies the mutation recorded by a change data capture entry
+type ChangeOp byte
+
+// Define the change operations as 1-byte constants
+const (
+	ChangeSet    ChangeOp = 'S' // Key was set
+	ChangeDelete ChangeOp = 'D' // Key was deleted
+)
+
+// String returns a human-readable name of the change operation
+func (o ChangeOp) String() string {
+	switch o {
+	case ChangeSet:
+		return "set"
+	case ChangeDelete:
+		return "delete"
+	default:
+		return "unknown"
+	}
+}
+
+// ResponseStatus is the 1-byte status code that prefixes handler responses
+type ResponseStatus byte
+
+// Define the response statuses as 1-byte constants
+const (
+	StatusOK    ResponseStatus = 0x00 // Request succeeded
+	StatusError ResponseStatus = 0x01 // Request failed, an error message may follow
+
+	StatusDatabaseNotFound ResponseStatus = 0x02 // Selected database is unknown, closed or not served by the transport
+	StatusReadOnly         ResponseStatus = 0x03 // Selected database is a replica and rejects writes
+	StatusHeartbeat        ResponseStatus = 0x04 // Stream keep-alive carrying the server's change log head
+	StatusBusy             ResponseStatus = 0x05 // Request was not applied because the server cannot serve it for now, it may be retried
+	StatusNotFound         ResponseStatus = 0x06 // Key read has no value
+	StatusTruncated        ResponseStatus = 0x07 // Requested changes were already pruned from the change log
 )